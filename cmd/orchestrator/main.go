@@ -8,14 +8,36 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/rs/zerolog"
 
+	"github.com/github-builder/container-orchestrator/internal/agent"
 	"github.com/github-builder/container-orchestrator/internal/api"
+	"github.com/github-builder/container-orchestrator/internal/autoscaler"
 	"github.com/github-builder/container-orchestrator/internal/config"
+	"github.com/github-builder/container-orchestrator/internal/daemonset"
+	"github.com/github-builder/container-orchestrator/internal/deployment"
+	"github.com/github-builder/container-orchestrator/internal/events"
+	"github.com/github-builder/container-orchestrator/internal/eviction"
+	"github.com/github-builder/container-orchestrator/internal/gc"
+	"github.com/github-builder/container-orchestrator/internal/group"
+	"github.com/github-builder/container-orchestrator/internal/job"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/namespace"
+	"github.com/github-builder/container-orchestrator/internal/node"
+	"github.com/github-builder/container-orchestrator/internal/prober"
+	"github.com/github-builder/container-orchestrator/internal/runtime"
+	"github.com/github-builder/container-orchestrator/internal/runtime/docker"
+	"github.com/github-builder/container-orchestrator/internal/runtime/process"
+	"github.com/github-builder/container-orchestrator/internal/scheduler"
+	"github.com/github-builder/container-orchestrator/internal/secret"
+	"github.com/github-builder/container-orchestrator/internal/statefulset"
 	"github.com/github-builder/container-orchestrator/internal/store"
+	"github.com/github-builder/container-orchestrator/internal/volume"
 )
 
 func main() {
@@ -54,12 +76,194 @@ func run() error {
 		}
 	}()
 
+	// Load the key secrets are encrypted with.
+	var secretKey []byte
+	if cfg.SecretEncryptionKey != "" {
+		secretKey, err = secret.ParseKey(cfg.SecretEncryptionKey)
+	} else {
+		secretKey, err = secret.LoadOrCreateKey(filepath.Join(cfg.DataDir, "secret.key"))
+	}
+	if err != nil {
+		return err
+	}
+	secrets, err := secret.NewCipher(secretKey)
+	if err != nil {
+		return err
+	}
+
+	// Create container runtime.
+	rt, err := newRuntime(cfg, logger.With().Str("component", "runtime").Logger())
+	if err != nil {
+		return fmt.Errorf("creating runtime: %w", err)
+	}
+
+	// Graceful shutdown on SIGINT/SIGTERM.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// All components record events through one recorder, so that API
+	// watchers see them all.
+	recorder := events.NewRecorder(&events.Config{
+		Store:  s,
+		Logger: logger.With().Str("component", "events").Logger(),
+		TTL:    cfg.EventTTL,
+	})
+
+	// Voluntary evictions all go through one evictor, which enforces
+	// disruption budgets.
+	evictor := eviction.New(&eviction.Config{
+		Store:  s,
+		Logger: logger.With().Str("component", "eviction").Logger(),
+		Events: recorder,
+	})
+
+	// Register this host as a node.
+	nodes := node.NewRegistry(&node.Config{
+		Store:             s,
+		Logger:            logger.With().Str("component", "nodes").Logger(),
+		HeartbeatInterval: cfg.NodeHeartbeatInterval,
+		HeartbeatTimeout:  cfg.NodeHeartbeatTimeout,
+		DrainTimeout:      cfg.NodeDrainTimeout,
+		Evictor:           evictor,
+		Events:            recorder,
+	})
+	capacity, err := node.LocalCapacity()
+	if err != nil {
+		return fmt.Errorf("reading node capacity: %w", err)
+	}
+	if _, _, err := nodes.Register(&model.Node{
+		ObjectMeta: model.ObjectMeta{Name: cfg.NodeName},
+		Spec:       model.NodeSpec{Capacity: capacity},
+	}); err != nil {
+		return fmt.Errorf("registering node %s: %w", cfg.NodeName, err)
+	}
+
+	namespaces := namespace.NewRegistry(&namespace.Config{
+		Store:    s,
+		Logger:   logger.With().Str("component", "namespaces").Logger(),
+		Interval: cfg.ReconcileInterval,
+	})
+	if err := namespaces.EnsureDefault(); err != nil {
+		return err
+	}
+
+	scorers, err := scheduler.ScorersFor(cfg.SchedulerStrategy)
+	if err != nil {
+		return err
+	}
+
+	// Start background controllers.
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	sched := scheduler.New(&scheduler.Config{
+		Store:    s,
+		Logger:   logger.With().Str("component", "scheduler").Logger(),
+		Interval: cfg.SchedulerInterval,
+		Scorers:  scorers,
+		Evictor:  evictor,
+		Events:   recorder,
+	})
+	ag := agent.New(&agent.Config{
+		Store:       s,
+		Runtime:     rt,
+		Logger:      logger.With().Str("component", "agent").Logger(),
+		NodeName:    cfg.NodeName,
+		Interval:    cfg.AgentSyncInterval,
+		StopTimeout: cfg.ContainerStopTimeout,
+		PullTimeout: cfg.ImagePullTimeout,
+
+		BackoffInitial: cfg.CrashLoopBackoffInitial,
+		BackoffMax:     cfg.CrashLoopBackoffMax,
+		BackoffReset:   cfg.CrashLoopBackoffReset,
+		Volumes:        volume.NewRegistry(volume.NewLocal(filepath.Join(cfg.DataDir, "volumes")), volume.HostPath{}),
+		Secrets:        secrets,
+		FilesDir:       filepath.Join(cfg.DataDir, "files"),
+		Events:         recorder,
+	})
+	probes := prober.NewManager(&prober.Config{
+		Store:     s,
+		Runtime:   rt,
+		Restarter: ag,
+		Logger:    logger.With().Str("component", "prober").Logger(),
+		Interval:  cfg.HealthCheckInterval,
+		Events:    recorder,
+	})
+	deployments := deployment.NewController(&deployment.Config{
+		Store:    s,
+		Logger:   logger.With().Str("component", "deployments").Logger(),
+		Interval: cfg.ReconcileInterval,
+		Evictor:  evictor,
+		Events:   recorder,
+	})
+	daemonSets := daemonset.NewController(&daemonset.Config{
+		Store:    s,
+		Logger:   logger.With().Str("component", "daemonsets").Logger(),
+		Interval: cfg.ReconcileInterval,
+		Evictor:  evictor,
+		Events:   recorder,
+	})
+	statefulSets := statefulset.NewController(&statefulset.Config{
+		Store:    s,
+		Logger:   logger.With().Str("component", "statefulsets").Logger(),
+		Interval: cfg.ReconcileInterval,
+		Evictor:  evictor,
+		Events:   recorder,
+	})
+	groups := group.NewController(&group.Config{
+		Store:    s,
+		Logger:   logger.With().Str("component", "groups").Logger(),
+		Interval: cfg.ReconcileInterval,
+		Events:   recorder,
+	})
+	jobs := job.NewController(&job.Config{
+		Store:    s,
+		Logger:   logger.With().Str("component", "jobs").Logger(),
+		Interval: cfg.ReconcileInterval,
+		Events:   recorder,
+	})
+	autoscalers := autoscaler.NewController(&autoscaler.Config{
+		Store:      s,
+		Logger:     logger.With().Str("component", "autoscalers").Logger(),
+		Interval:   cfg.AutoscalerInterval,
+		Events:     recorder,
+		Namespaces: namespaces,
+	})
+	collector := gc.NewCollector(&gc.Config{
+		Store:    s,
+		Logger:   logger.With().Str("component", "gc").Logger(),
+		Interval: cfg.ReconcileInterval,
+	})
+	runInBackground(ctx, &wg,
+		nodes.Run,
+		func(ctx context.Context) { nodes.KeepAlive(ctx, cfg.NodeName) },
+		sched.Run,
+		ag.Run,
+		probes.Run,
+		deployments.Run,
+		daemonSets.Run,
+		statefulSets.Run,
+		groups.Run,
+		jobs.Run,
+		autoscalers.Run,
+		collector.Run,
+		namespaces.Run,
+		recorder.Run,
+	)
+
 	// Create router.
 	router := api.NewRouter(&api.RouterConfig{
 		Store:        s,
+		Nodes:        nodes,
+		Namespaces:   namespaces,
+		Evictor:      evictor,
 		Logger:       logger,
 		DashboardURL: cfg.DashboardURL,
 		APIKey:       cfg.APIKey,
+
+		Secrets:         secrets,
+		SecretReaderKey: cfg.SecretReaderKey,
+		Events:          recorder,
 	})
 
 	// Start HTTP server.
@@ -69,10 +273,6 @@ func run() error {
 		ReadHeaderTimeout: 10 * time.Second,
 	}
 
-	// Graceful shutdown on SIGINT/SIGTERM.
-	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-	defer stop()
-
 	go func() {
 		logger.Info().
 			Int("port", cfg.Port).
@@ -98,3 +298,41 @@ func run() error {
 	logger.Info().Msg("server stopped")
 	return nil
 }
+
+// newRuntime creates the container runtime cfg selects. The Docker daemon
+// must answer.
+func newRuntime(cfg *config.Config, logger zerolog.Logger) (runtime.Runtime, error) {
+	if cfg.ContainerRuntime != "docker" {
+		rt, err := process.NewRuntime(&process.Config{
+			RootDir:    filepath.Join(cfg.DataDir, "runtime"),
+			CgroupRoot: cfg.CgroupRoot,
+			Logger:     logger,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return rt, nil
+	}
+
+	rt, err := docker.NewRuntime(&docker.Config{Host: cfg.DockerHost, Logger: logger})
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := rt.Ping(ctx); err != nil {
+		return nil, fmt.Errorf("reaching docker at %s: %w", cfg.DockerHost, err)
+	}
+	return rt, nil
+}
+
+// runInBackground starts each loop in its own goroutine, tracked by wg.
+func runInBackground(ctx context.Context, wg *sync.WaitGroup, loops ...func(context.Context)) {
+	for _, loop := range loops {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			loop(ctx)
+		}()
+	}
+}
@@ -0,0 +1,794 @@
+// Package agent runs the containers recorded in the store on a runtime and
+// writes their observed state back to the store. It owns the container
+// lifecycle: creating, starting, restarting according to the restart policy
+// with an exponential back-off for crash loops, and cleaning up runtime
+// containers whose records were deleted, releasing the records that wait for
+// that. Volumes are mounted through their driver, and the agent measures the
+// ones on its node and prunes the data of deleted ones. Secrets and config
+// maps are resolved when a runtime container is created; when one changes,
+// the containers using it are recreated or, for hot-updated file mounts,
+// have their files rewritten in place. Members of a group start in the order
+// the group sets, share a sandbox where the runtime has them, and sidecars
+// stop once the group's main containers have finished. Where the runtime
+// reports resource usage, the agent records it in the status of running
+// containers. Where the runtime pulls images, they are pulled in the
+// background as each container's pull policy says, with the registry
+// credentials of its image pull secrets; pulls are bounded by a timeout, and
+// failed pulls back off like crashing containers.
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/github-builder/container-orchestrator/internal/events"
+	"github.com/github-builder/container-orchestrator/internal/gc"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/runtime"
+	"github.com/github-builder/container-orchestrator/internal/secret"
+	"github.com/github-builder/container-orchestrator/internal/store"
+	"github.com/github-builder/container-orchestrator/internal/volume"
+)
+
+// errStale is returned when a status update targets a record that has been
+// replaced since it was read.
+var errStale = errors.New("container record changed")
+
+// Config holds dependencies for the agent.
+type Config struct {
+	Store   store.Store
+	Runtime runtime.Runtime
+	Logger  zerolog.Logger
+
+	// NodeName is the node this agent runs containers for. Only containers
+	// scheduled to it are started.
+	NodeName string
+
+	// Interval is how often all containers are resynced. Container exits
+	// trigger an immediate resync regardless.
+	Interval time.Duration
+
+	// StopTimeout is the grace period given to containers before they are killed.
+	StopTimeout time.Duration
+
+	// PullTimeout bounds an image pull; one that takes longer fails and
+	// backs off. Default 10m.
+	PullTimeout time.Duration
+
+	// BackoffInitial is the delay before the second consecutive restart of a
+	// crashing container; the first restart is immediate. The delay doubles
+	// with every further crash up to BackoffMax. Default 10s.
+	BackoffInitial time.Duration
+
+	// BackoffMax caps the crash-loop back-off. Default 5m.
+	BackoffMax time.Duration
+
+	// BackoffReset is how long a container must run before its crash count
+	// is reset. Default 10m.
+	BackoffReset time.Duration
+
+	// Volumes holds the volume drivers of this node. Containers with
+	// volume mounts cannot start without it.
+	Volumes *volume.Registry
+
+	// Secrets opens the secrets containers refer to. Containers referring
+	// to secrets cannot start without it.
+	Secrets *secret.Cipher
+
+	// FilesDir holds files projected from secrets and config maps, one
+	// sub-directory per container. Containers with file mounts cannot
+	// start when it is empty.
+	FilesDir string
+
+	// Events records containers starting, failing to start and backing
+	// off. Defaults to a recorder on Store.
+	Events *events.Recorder
+}
+
+// Back-off defaults.
+const (
+	DefaultBackoffInitial = 10 * time.Second
+	DefaultBackoffMax     = 5 * time.Minute
+	DefaultBackoffReset   = 10 * time.Minute
+)
+
+// DefaultPullTimeout is the default bound of an image pull.
+const DefaultPullTimeout = 10 * time.Minute
+
+// Agent reconciles container records with the runtime.
+type Agent struct {
+	store       store.Store
+	runtime     runtime.Runtime
+	logger      zerolog.Logger
+	nodeName    string
+	interval    time.Duration
+	stopTimeout time.Duration
+	pullTimeout time.Duration
+	trigger     chan struct{}
+
+	backoffInitial time.Duration
+	backoffMax     time.Duration
+	backoffReset   time.Duration
+	volumes        *volume.Registry
+	secrets        *secret.Cipher
+	filesDir       string
+	events         *events.Recorder
+
+	now func() time.Time
+
+	mu sync.Mutex
+	// tracked maps container names to the runtime containers backing them.
+	tracked map[string]string
+	// watching holds runtime IDs with an exit watcher.
+	watching map[string]bool
+	// killReasons records why the agent stopped a runtime container, so the
+	// exit is not mistaken for a crash.
+	killReasons map[string]string
+	// wakeups holds names of containers with a pending back-off timer.
+	wakeups map[string]bool
+	// sandboxes maps the keys of groups with members on this node to their
+	// runtime sandboxes.
+	sandboxes map[string]string
+	// samples holds the last usage sample of each running runtime
+	// container.
+	samples map[string]*runtime.Stats
+	// pulls maps the keys of containers whose image is being pulled to the
+	// image, and pulled those whose pull succeeded until they are created.
+	pulls  map[string]string
+	pulled map[string]string
+}
+
+// New creates an agent.
+func New(cfg *Config) *Agent {
+	recorder := cfg.Events
+	if recorder == nil {
+		recorder = events.NewRecorder(&events.Config{Store: cfg.Store, Logger: cfg.Logger})
+	}
+
+	a := &Agent{
+		store:          cfg.Store,
+		runtime:        cfg.Runtime,
+		logger:         cfg.Logger,
+		nodeName:       cfg.NodeName,
+		interval:       cfg.Interval,
+		stopTimeout:    cfg.StopTimeout,
+		pullTimeout:    cfg.PullTimeout,
+		trigger:        make(chan struct{}, 1),
+		backoffInitial: cfg.BackoffInitial,
+		backoffMax:     cfg.BackoffMax,
+		backoffReset:   cfg.BackoffReset,
+		volumes:        cfg.Volumes,
+		secrets:        cfg.Secrets,
+		filesDir:       cfg.FilesDir,
+		events:         recorder.WithSource("agent/" + cfg.NodeName),
+		now:            time.Now,
+		tracked:        make(map[string]string),
+		watching:       make(map[string]bool),
+		killReasons:    make(map[string]string),
+		wakeups:        make(map[string]bool),
+		sandboxes:      make(map[string]string),
+		samples:        make(map[string]*runtime.Stats),
+		pulls:          make(map[string]string),
+		pulled:         make(map[string]string),
+	}
+	if a.backoffInitial <= 0 {
+		a.backoffInitial = DefaultBackoffInitial
+	}
+	if a.backoffMax <= 0 {
+		a.backoffMax = DefaultBackoffMax
+	}
+	if a.backoffReset <= 0 {
+		a.backoffReset = DefaultBackoffReset
+	}
+	if a.pullTimeout <= 0 {
+		a.pullTimeout = DefaultPullTimeout
+	}
+	return a
+}
+
+// Run syncs containers until ctx is canceled.
+func (a *Agent) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := a.Sync(ctx); err != nil {
+			a.logger.Error().Err(err).Msg("container sync failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-a.trigger:
+		}
+	}
+}
+
+// Trigger requests a resync as soon as possible.
+func (a *Agent) Trigger() {
+	select {
+	case a.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Sync brings every container scheduled to this node in line with the
+// runtime once.
+func (a *Agent) Sync(ctx context.Context) error {
+	containers, err := store.ListJSON[model.Container](a.store, model.ContainersBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing containers: %w", err)
+	}
+
+	// known holds the runtime containers that records refer to, on any
+	// node. Terminating containers are left out of both, so that they are
+	// stopped while their finalizers are pending.
+	known := make(map[string]bool, len(containers))
+	local := containers[:0]
+	var terminating []*model.Container
+	for _, c := range containers {
+		if c.IsTerminating() {
+			if c.Spec.NodeName == a.nodeName && c.HasFinalizer(model.FinalizerRuntimeCleanup) {
+				terminating = append(terminating, c)
+			}
+			continue
+		}
+		if c.Status.RuntimeID != "" {
+			known[c.Status.RuntimeID] = true
+		}
+		if c.Spec.NodeName == a.nodeName {
+			local = append(local, c)
+		}
+	}
+	groups, err := a.loadGroups(local)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(local))
+	var errs []error
+	for _, c := range local {
+		seen[c.Key()] = true
+		if err := a.syncContainer(ctx, c, groups); err != nil && !errors.Is(err, errStale) {
+			errs = append(errs, fmt.Errorf("container %s: %w", c.Key(), err))
+		}
+	}
+
+	a.removeOrphans(ctx, seen)
+	a.forgetPulls(seen)
+	if err := a.removeStrays(ctx, known); err != nil {
+		errs = append(errs, err)
+	}
+	if err := a.release(ctx, terminating); err != nil {
+		errs = append(errs, err)
+	}
+	a.removeSandboxes(ctx, groups)
+	if err := a.syncVolumes(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (a *Agent) syncContainer(ctx context.Context, c *model.Container, groups *groupView) error {
+	id := c.Status.RuntimeID
+
+	// A crash-looping container waits out its back-off, even across an
+	// orchestrator restart since the deadline is kept in the store.
+	if until := c.Status.BackoffUntil; until != nil && !c.Status.IsTerminal() {
+		if wait := until.Sub(a.now()); wait > 0 {
+			if id != "" {
+				a.track(c.Key(), id)
+			}
+			a.wakeAfter(c.Key(), wait)
+			return nil
+		}
+	}
+	if id == "" {
+		if c.Status.IsTerminal() {
+			return nil
+		}
+		if why := groups.blocked(c); why != "" {
+			return a.wait(c, why)
+		}
+		return a.create(ctx, c)
+	}
+
+	info, err := a.runtime.Inspect(ctx, id)
+	if errors.Is(err, runtime.ErrNotFound) {
+		if c.Status.IsTerminal() {
+			return nil
+		}
+		// The runtime lost the container, e.g. across an orchestrator restart.
+		a.logger.Warn().Str("container", c.Key()).Str("runtime_id", id).Msg("runtime container missing, recreating")
+		return a.create(ctx, c)
+	}
+	if err != nil {
+		return fmt.Errorf("inspecting: %w", err)
+	}
+
+	a.track(c.Key(), id)
+
+	if c.Status.IsTerminal() {
+		return nil
+	}
+
+	if changed, hot := a.sourceChanges(c); changed {
+		if hot {
+			return a.hotUpdate(c)
+		}
+		// Environment values are fixed when the runtime container is
+		// created, so anything but a hot update needs a new one.
+		a.logger.Info().Str("container", c.Key()).Msg("secret or config map changed, recreating container")
+		a.destroy(ctx, c.Key(), id)
+		return a.create(ctx, c)
+	}
+
+	switch info.State {
+	case runtime.StateCreated:
+		return a.start(ctx, c, false)
+	case runtime.StateRunning:
+		if groups.finished(c) {
+			return a.finish(ctx, c)
+		}
+		a.watch(ctx, id)
+		if err := a.recordUsage(ctx, c, info); err != nil {
+			return err
+		}
+		return a.resetBackoff(c)
+	case runtime.StateExited:
+		a.forgetUsage(id)
+		if c.Status.BackoffUntil != nil {
+			// The exit was recorded and the back-off has elapsed.
+			return a.start(ctx, c, true)
+		}
+		return a.handleExit(ctx, c, info)
+	default:
+		return fmt.Errorf("unknown runtime state %q", info.State)
+	}
+}
+
+// create makes a fresh runtime container for c and starts it.
+func (a *Agent) create(ctx context.Context, c *model.Container) error {
+	// A record recreated under the same name must not collide with the
+	// runtime container of its predecessor.
+	if old, ok := a.trackedID(c.Key()); ok && old != c.Status.RuntimeID {
+		a.destroy(ctx, c.Key(), old)
+	}
+
+	ready, err := a.ensureImage(ctx, c)
+	if err != nil || !ready {
+		return err
+	}
+	mounts, err := a.mounts(c)
+	if err != nil {
+		return a.fail(c, model.ReasonVolumeUnavailable, err)
+	}
+	proj, err := a.project(c)
+	if err != nil {
+		return a.fail(c, model.ReasonConfigUnavailable, err)
+	}
+	sandbox, err := a.sandbox(ctx, c)
+	if err != nil {
+		return a.fail(c, model.ReasonStartError, err)
+	}
+	cfg := runtimeConfig(c)
+	cfg.Mounts = append(mounts, proj.mounts...)
+	cfg.Sandbox = sandbox
+	if len(proj.env) > 0 {
+		env := make(map[string]string, len(cfg.Env)+len(proj.env))
+		maps.Copy(env, cfg.Env)
+		maps.Copy(env, proj.env)
+		cfg.Env = env
+	}
+
+	id, err := a.runtime.Create(ctx, cfg)
+	if err != nil {
+		return a.fail(c, model.ReasonStartError, err)
+	}
+	a.track(c.Key(), id)
+
+	updated, err := a.updateStatus(c, func(s *model.ContainerStatus) {
+		s.RuntimeID = id
+		s.SecretVersions = proj.secretVersions
+		s.ConfigMapVersions = proj.configMapVersions
+	})
+	if err != nil {
+		return err
+	}
+	return a.start(ctx, updated, false)
+}
+
+// start runs the runtime container of c. restart marks a restart after exit.
+func (a *Agent) start(ctx context.Context, c *model.Container, restart bool) error {
+	id := c.Status.RuntimeID
+	if err := a.runtime.Start(ctx, id); err != nil {
+		return a.fail(c, model.ReasonStartError, err)
+	}
+
+	now := a.now().UTC()
+	_, err := a.updateStatus(c, func(s *model.ContainerStatus) {
+		s.Phase = model.PhaseRunning
+		s.StartedAt = &now
+		s.BackoffUntil = nil
+		s.Ready = c.Spec.ReadinessProbe == nil
+		s.Health = ""
+		if c.Spec.LivenessProbe != nil {
+			s.Health = model.HealthStarting
+		}
+		s.Liveness = nil
+		s.Readiness = nil
+		s.Reason = ""
+		s.Message = ""
+		if restart {
+			s.RestartCount++
+		}
+	})
+
+	a.watch(ctx, id)
+	a.logger.Info().Str("container", c.Key()).Str("runtime_id", id).Bool("restart", restart).Msg("container started")
+	if err == nil {
+		a.events.Eventf(model.ContainersBucket, &c.ObjectMeta, model.EventNormal, model.EventStarted,
+			"Started container on node %s", a.nodeName)
+	}
+	return err
+}
+
+// handleExit records an exit and restarts the container if its policy says so.
+func (a *Agent) handleExit(ctx context.Context, c *model.Container, info *runtime.ContainerInfo) error {
+	reason := a.takeKillReason(info.ID)
+	if reason == "" {
+		reason = model.ReasonError
+		if info.ExitCode == 0 {
+			reason = model.ReasonCompleted
+		}
+	}
+
+	// A sidecar stopped because its group finished has done its work.
+	done := reason == model.ReasonCompleted && c.Labels[model.LabelGroupRole] == string(model.RoleSidecar)
+	restart := !done && model.ShouldRestart(c.Spec.RestartPolicy, info.ExitCode)
+	var delay time.Duration
+	updated, err := a.updateStatus(c, func(s *model.ContainerStatus) {
+		s.LastTermination = &model.Termination{
+			ExitCode:   info.ExitCode,
+			Reason:     reason,
+			FinishedAt: info.FinishedAt,
+		}
+		s.Ready = false
+		if restart {
+			if s.StartedAt != nil && info.FinishedAt.Sub(*s.StartedAt) >= a.backoffReset {
+				s.ConsecutiveCrashes = 0
+			}
+			s.ConsecutiveCrashes++
+			delay = a.backoff(s.ConsecutiveCrashes)
+			if delay > 0 {
+				until := a.now().UTC().Add(delay)
+				s.Phase = model.PhasePending
+				s.BackoffUntil = &until
+				s.Health = ""
+				s.Liveness = nil
+				s.Readiness = nil
+				s.Reason = model.ReasonCrashLoopBackOff
+				s.Message = fmt.Sprintf("back-off %s restarting failed container; %d restarts, last exit code %d",
+					delay, s.RestartCount, info.ExitCode)
+			}
+			return
+		}
+		s.Phase = model.PhaseFailed
+		if info.ExitCode == 0 || done {
+			s.Phase = model.PhaseSucceeded
+		}
+		s.Reason = reason
+	})
+	if err != nil {
+		return err
+	}
+
+	a.logger.Info().Str("container", c.Key()).Int("exit_code", info.ExitCode).
+		Str("reason", reason).Bool("restart", restart).Msg("container exited")
+
+	if !restart {
+		return nil
+	}
+	if delay > 0 {
+		a.logger.Warn().Str("container", c.Key()).Int("crashes", updated.Status.ConsecutiveCrashes).
+			Dur("backoff", delay).Msg("container crash looping, backing off")
+		a.events.Event(model.ContainersBucket, &c.ObjectMeta, model.EventWarning, model.EventBackOff,
+			"Back-off restarting failed container")
+		a.wakeAfter(c.Key(), delay)
+		return nil
+	}
+	return a.start(ctx, updated, true)
+}
+
+// backoff returns the delay before restarting a container that has crashed
+// crashes times in a row.
+func (a *Agent) backoff(crashes int) time.Duration {
+	if crashes <= 1 {
+		return 0
+	}
+	delay := a.backoffInitial
+	for range crashes - 2 {
+		delay *= 2
+		if delay >= a.backoffMax {
+			break
+		}
+	}
+	return min(delay, a.backoffMax)
+}
+
+// resetBackoff clears the crash count of a container that has been running
+// for the reset period.
+func (a *Agent) resetBackoff(c *model.Container) error {
+	s := c.Status
+	if s.ConsecutiveCrashes == 0 || s.StartedAt == nil || a.now().Sub(*s.StartedAt) < a.backoffReset {
+		return nil
+	}
+	_, err := a.updateStatus(c, func(s *model.ContainerStatus) {
+		s.ConsecutiveCrashes = 0
+	})
+	return err
+}
+
+// wakeAfter triggers a resync once d has passed, for a container waiting out
+// its back-off. Only one timer is kept per container.
+func (a *Agent) wakeAfter(name string, d time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.wakeups[name] {
+		return
+	}
+	a.wakeups[name] = true
+
+	time.AfterFunc(d, func() {
+		a.mu.Lock()
+		delete(a.wakeups, name)
+		a.mu.Unlock()
+		a.Trigger()
+	})
+}
+
+// Restart stops the running container stored under key so that it is
+// restarted according to its restart policy. reason is recorded as the
+// termination reason.
+func (a *Agent) Restart(ctx context.Context, key, reason string) error {
+	c, err := store.GetJSON[model.Container](a.store, model.ContainersBucket, key)
+	if err != nil {
+		return err
+	}
+	id := c.Status.RuntimeID
+	if id == "" {
+		return fmt.Errorf("container %s has not been started", key)
+	}
+
+	a.mu.Lock()
+	a.killReasons[id] = reason
+	a.mu.Unlock()
+
+	if err := a.runtime.Stop(ctx, id, a.stopTimeout); err != nil {
+		return fmt.Errorf("stopping container %s: %w", key, err)
+	}
+
+	a.Trigger()
+	return nil
+}
+
+// removeOrphans stops and removes runtime containers whose records are gone
+// or no longer scheduled to this node.
+func (a *Agent) removeOrphans(ctx context.Context, seen map[string]bool) {
+	a.mu.Lock()
+	orphans := make(map[string]string)
+	for name, id := range a.tracked {
+		if !seen[name] {
+			orphans[name] = id
+		}
+	}
+	a.mu.Unlock()
+
+	for name, id := range orphans {
+		a.destroy(ctx, name, id)
+	}
+}
+
+// removeStrays stops and removes the runtime containers labelled as ours
+// that neither a record nor the agent knows of, such as those left behind
+// when a record was deleted while the orchestrator was down.
+func (a *Agent) removeStrays(ctx context.Context, known map[string]bool) error {
+	infos, err := a.runtime.List(ctx, map[string]string{model.LabelManaged: "true"})
+	if err != nil {
+		return fmt.Errorf("listing runtime containers: %w", err)
+	}
+
+	a.mu.Lock()
+	for _, id := range a.tracked {
+		known[id] = true
+	}
+	a.mu.Unlock()
+
+	for _, info := range infos {
+		name, ok := info.Labels[model.LabelContainer]
+		if !ok || known[info.ID] {
+			continue
+		}
+		key := model.Key(info.Labels[model.LabelNamespace], name)
+		a.logger.Warn().Str("container", key).Str("runtime_id", info.ID).Msg("runtime container has no record")
+		a.destroy(ctx, key, info.ID)
+	}
+	return nil
+}
+
+// release clears model.FinalizerRuntimeCleanup from the deleted containers
+// of this node whose runtime containers are gone. removeOrphans and
+// removeStrays stop and remove them first; one that could not be removed
+// yet keeps its finalizer until a later sync.
+func (a *Agent) release(ctx context.Context, cs []*model.Container) error {
+	var errs []error
+	for _, c := range cs {
+		a.mu.Lock()
+		_, tracked := a.tracked[c.Key()]
+		a.mu.Unlock()
+		if tracked {
+			continue
+		}
+		if id := c.Status.RuntimeID; id != "" {
+			if _, err := a.runtime.Inspect(ctx, id); err == nil {
+				continue
+			} else if !errors.Is(err, runtime.ErrNotFound) {
+				errs = append(errs, fmt.Errorf("container %s: inspecting: %w", c.Key(), err))
+				continue
+			}
+		}
+		if err := gc.RemoveFinalizer(a.store, model.ContainersBucket, &c.ObjectMeta, model.FinalizerRuntimeCleanup); err != nil {
+			errs = append(errs, fmt.Errorf("container %s: releasing: %w", c.Key(), err))
+			continue
+		}
+		a.logger.Debug().Str("container", c.Key()).Msg("runtime container gone, record released")
+	}
+	return errors.Join(errs...)
+}
+
+// destroy stops and removes a runtime container and forgets it.
+func (a *Agent) destroy(ctx context.Context, name, id string) {
+	log := a.logger.With().Str("container", name).Str("runtime_id", id).Logger()
+
+	if err := a.runtime.Stop(ctx, id, a.stopTimeout); err != nil && !errors.Is(err, runtime.ErrNotFound) {
+		log.Error().Err(err).Msg("failed to stop container")
+		return
+	}
+	if err := a.runtime.Remove(ctx, id); err != nil && !errors.Is(err, runtime.ErrNotFound) {
+		log.Error().Err(err).Msg("failed to remove container")
+		return
+	}
+
+	// The files belong to whichever container is tracked under the name.
+	a.mu.Lock()
+	current, ok := a.tracked[name]
+	a.mu.Unlock()
+	if !ok || current == id {
+		if err := a.removeFiles(name); err != nil {
+			log.Warn().Err(err).Msg("failed to remove projected files")
+		}
+	}
+
+	a.mu.Lock()
+	if a.tracked[name] == id {
+		delete(a.tracked, name)
+	}
+	delete(a.killReasons, id)
+	delete(a.samples, id)
+	a.mu.Unlock()
+
+	log.Info().Msg("container removed")
+}
+
+// fail records a start failure on the container and returns err.
+func (a *Agent) fail(c *model.Container, reason string, err error) error {
+	a.events.Eventf(model.ContainersBucket, &c.ObjectMeta, model.EventWarning, model.EventFailed,
+		"%s: %v", reason, err)
+	if _, updateErr := a.updateStatus(c, func(s *model.ContainerStatus) {
+		s.Reason = reason
+		s.Message = err.Error()
+	}); updateErr != nil {
+		return errors.Join(err, updateErr)
+	}
+	return err
+}
+
+// updateStatus applies fn to the stored status of c, provided the record
+// has not been replaced by a new container of the same name.
+func (a *Agent) updateStatus(c *model.Container, fn func(s *model.ContainerStatus)) (*model.Container, error) {
+	return store.UpdateJSON(a.store, model.ContainersBucket, c.Key(), func(cur *model.Container) error {
+		if cur.UID != c.UID {
+			return errStale
+		}
+		fn(&cur.Status)
+		return nil
+	})
+}
+
+// watch triggers a resync when the runtime container exits.
+func (a *Agent) watch(ctx context.Context, id string) {
+	a.mu.Lock()
+	if a.watching[id] {
+		a.mu.Unlock()
+		return
+	}
+	a.watching[id] = true
+	a.mu.Unlock()
+
+	go func() {
+		_, err := a.runtime.Wait(ctx, id)
+
+		a.mu.Lock()
+		delete(a.watching, id)
+		a.mu.Unlock()
+
+		if err == nil {
+			a.Trigger()
+		}
+	}()
+}
+
+func (a *Agent) track(name, id string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.tracked[name] = id
+}
+
+func (a *Agent) trackedID(name string) (string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	id, ok := a.tracked[name]
+	return id, ok
+}
+
+func (a *Agent) takeKillReason(id string) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	reason := a.killReasons[id]
+	delete(a.killReasons, id)
+	return reason
+}
+
+// runtimeName returns the name of c's runtime container. Names are only
+// unique within a namespace, so the namespace qualifies it, as it does
+// the names of group sandboxes.
+func runtimeName(c *model.Container) string {
+	return c.Namespace + "_" + c.Name
+}
+
+// runtimeConfig translates a container record into a runtime request.
+func runtimeConfig(c *model.Container) *runtime.ContainerConfig {
+	labels := make(map[string]string, len(c.Labels)+3)
+	for k, v := range c.Labels {
+		labels[k] = v
+	}
+	labels[model.LabelManaged] = "true"
+	labels[model.LabelNamespace] = c.Namespace
+	labels[model.LabelContainer] = c.Name
+
+	var rlimits []runtime.Rlimit
+	for _, l := range c.Spec.Rlimits {
+		rlimits = append(rlimits, runtime.Rlimit{Name: l.Name, Soft: l.Soft, Hard: l.Hard})
+	}
+
+	return &runtime.ContainerConfig{
+		Name:       runtimeName(c),
+		Image:      c.Spec.Image,
+		Command:    c.Spec.Command,
+		Env:        c.Spec.Env,
+		WorkingDir: c.Spec.WorkingDir,
+		Labels:     labels,
+		Resources: runtime.Resources{
+			CPUMillis:   c.Spec.Resources.CPUMillis,
+			MemoryBytes: c.Spec.Resources.MemoryBytes,
+		},
+		Rlimits: rlimits,
+	}
+}
@@ -0,0 +1,947 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/gc"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/node"
+	"github.com/github-builder/container-orchestrator/internal/runtime"
+	"github.com/github-builder/container-orchestrator/internal/runtime/fake"
+	"github.com/github-builder/container-orchestrator/internal/secret"
+	"github.com/github-builder/container-orchestrator/internal/store"
+	"github.com/github-builder/container-orchestrator/internal/volume"
+)
+
+func newTestAgent(t *testing.T) (*Agent, store.Store, *fake.Runtime) {
+	t.Helper()
+	s := store.NewMemoryStore()
+	rt := fake.New()
+	a := New(&Config{
+		Store:       s,
+		Runtime:     rt,
+		Logger:      zerolog.Nop(),
+		NodeName:    "node-1",
+		Interval:    time.Hour,
+		StopTimeout: time.Second,
+	})
+	return a, s, rt
+}
+
+func putContainer(t *testing.T, s store.Store, name string, mutate func(c *model.Container)) {
+	t.Helper()
+	c := &model.Container{
+		ObjectMeta: model.ObjectMeta{Name: name, Namespace: model.DefaultNamespace},
+		Spec:       model.ContainerSpec{Image: "app:v1", Command: []string{"app"}, NodeName: "node-1"},
+	}
+	if mutate != nil {
+		mutate(c)
+	}
+	c.SetDefaults()
+	require.NoError(t, c.Initialize())
+	require.NoError(t, store.PutJSON(s, model.ContainersBucket, c.Key(), c))
+}
+
+func getContainer(t *testing.T, s store.Store, name string) *model.Container {
+	t.Helper()
+	c, err := store.GetJSON[model.Container](s, model.ContainersBucket, model.Key(model.DefaultNamespace, name))
+	require.NoError(t, err)
+	return c
+}
+
+func TestAgent_StartsPendingContainer(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	putContainer(t, s, "web", func(c *model.Container) {
+		c.Labels = map[string]string{"app": "web"}
+		c.Spec.Rlimits = []model.Rlimit{{Name: "nofile", Soft: 1024, Hard: 4096}}
+	})
+
+	require.NoError(t, a.Sync(context.Background()))
+
+	c := getContainer(t, s, "web")
+	assert.Equal(t, model.PhaseRunning, c.Status.Phase)
+	assert.True(t, c.Status.Ready)
+	assert.NotNil(t, c.Status.StartedAt)
+	require.NotEmpty(t, c.Status.RuntimeID)
+
+	rc := rt.Get(c.Status.RuntimeID)
+	require.NotNil(t, rc)
+	assert.Equal(t, runtime.StateRunning, rc.Info.State)
+	assert.Equal(t, "web", rc.Config.Labels[model.LabelContainer])
+	assert.Equal(t, "web", rc.Config.Labels["app"])
+	assert.Equal(t, model.DefaultNamespace, rc.Config.Labels[model.LabelNamespace])
+	assert.Equal(t, []runtime.Rlimit{{Name: "nofile", Soft: 1024, Hard: 4096}}, rc.Config.Rlimits)
+}
+
+func TestAgent_SameNameInTwoNamespaces(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	putContainer(t, s, "web", nil)
+	putContainer(t, s, "web", func(c *model.Container) { c.Namespace = "team-a" })
+
+	require.NoError(t, a.Sync(context.Background()))
+
+	for _, ns := range []string{model.DefaultNamespace, "team-a"} {
+		c, err := store.GetJSON[model.Container](s, model.ContainersBucket, model.Key(ns, "web"))
+		require.NoError(t, err)
+		assert.Equal(t, model.PhaseRunning, c.Status.Phase, c.Status.Message)
+		info, err := rt.Inspect(context.Background(), c.Status.RuntimeID)
+		require.NoError(t, err)
+		assert.Equal(t, ns+"_web", info.Name)
+	}
+}
+
+func TestAgent_RecordsUsage(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	putContainer(t, s, "web", nil)
+	require.NoError(t, a.Sync(context.Background()))
+	id := getContainer(t, s, "web").Status.RuntimeID
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	rt.SetStats(id, runtime.Stats{CPUTime: time.Second, MemoryBytes: 64 << 20, Time: start})
+	require.NoError(t, a.Sync(context.Background()))
+	usage := getContainer(t, s, "web").Status.Usage
+	require.NotNil(t, usage)
+	assert.Equal(t, int64(64<<20), usage.MemoryBytes)
+	assert.Equal(t, start, usage.SampledAt)
+
+	// Samples are taken once per interval.
+	a.now = func() time.Time { return start.Add(usageInterval / 2) }
+	rt.SetStats(id, runtime.Stats{CPUTime: 2 * time.Second, MemoryBytes: 80 << 20, Time: start.Add(2 * time.Second)})
+	require.NoError(t, a.Sync(context.Background()))
+	assert.Equal(t, int64(64<<20), getContainer(t, s, "web").Status.Usage.MemoryBytes)
+
+	// Two seconds of CPU time over four seconds is half a core.
+	a.now = func() time.Time { return start.Add(usageInterval) }
+	rt.SetStats(id, runtime.Stats{
+		CPUTime: 3 * time.Second, MemoryBytes: 96 << 20, Time: start.Add(4 * time.Second),
+		Metrics: map[string]float64{"queue_depth": 7},
+	})
+	require.NoError(t, a.Sync(context.Background()))
+	usage = getContainer(t, s, "web").Status.Usage
+	require.NotNil(t, usage)
+	assert.Equal(t, int64(500), usage.CPUMillis)
+	assert.Equal(t, int64(96<<20), usage.MemoryBytes)
+	assert.Equal(t, map[string]float64{"queue_depth": 7}, usage.Metrics)
+}
+
+func TestAgent_IgnoresContainersOnOtherNodes(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	putContainer(t, s, "unscheduled", func(c *model.Container) { c.Spec.NodeName = "" })
+	putContainer(t, s, "elsewhere", func(c *model.Container) { c.Spec.NodeName = "node-2" })
+
+	require.NoError(t, a.Sync(context.Background()))
+
+	assert.Equal(t, 0, rt.Count())
+	assert.Equal(t, model.PhasePending, getContainer(t, s, "unscheduled").Status.Phase)
+	assert.Equal(t, model.PhasePending, getContainer(t, s, "elsewhere").Status.Phase)
+}
+
+func TestAgent_ProbesGateReadiness(t *testing.T) {
+	a, s, _ := newTestAgent(t)
+	putContainer(t, s, "web", func(c *model.Container) {
+		c.Spec.LivenessProbe = &model.Probe{TCPSocket: &model.TCPSocketAction{Port: 80}}
+		c.Spec.ReadinessProbe = &model.Probe{TCPSocket: &model.TCPSocketAction{Port: 80}}
+	})
+
+	require.NoError(t, a.Sync(context.Background()))
+
+	c := getContainer(t, s, "web")
+	assert.False(t, c.Status.Ready)
+	assert.Equal(t, model.HealthStarting, c.Status.Health)
+}
+
+func TestAgent_RestartPolicy(t *testing.T) {
+	tests := []struct {
+		policy    model.RestartPolicy
+		exitCode  int
+		restarted bool
+		phase     model.ContainerPhase
+	}{
+		{model.RestartAlways, 0, true, model.PhaseRunning},
+		{model.RestartAlways, 1, true, model.PhaseRunning},
+		{model.RestartOnFailure, 0, false, model.PhaseSucceeded},
+		{model.RestartOnFailure, 2, true, model.PhaseRunning},
+		{model.RestartNever, 2, false, model.PhaseFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			a, s, rt := newTestAgent(t)
+			ctx := context.Background()
+			putContainer(t, s, "job", func(c *model.Container) { c.Spec.RestartPolicy = tt.policy })
+
+			require.NoError(t, a.Sync(ctx))
+			id := getContainer(t, s, "job").Status.RuntimeID
+			rt.Exit(id, tt.exitCode)
+			require.NoError(t, a.Sync(ctx))
+
+			c := getContainer(t, s, "job")
+			assert.Equal(t, tt.phase, c.Status.Phase)
+			require.NotNil(t, c.Status.LastTermination)
+			assert.Equal(t, tt.exitCode, c.Status.LastTermination.ExitCode)
+			if tt.restarted {
+				assert.Equal(t, 1, c.Status.RestartCount)
+				assert.Equal(t, 2, rt.Get(id).Starts)
+			} else {
+				assert.Equal(t, 0, c.Status.RestartCount)
+				assert.False(t, c.Status.Ready)
+			}
+		})
+	}
+}
+
+func TestAgent_RestartRecordsReason(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	ctx := context.Background()
+	putContainer(t, s, "web", nil)
+	require.NoError(t, a.Sync(ctx))
+
+	require.NoError(t, a.Restart(ctx, model.Key(model.DefaultNamespace, "web"), model.ReasonLivenessProbeFailed))
+	require.NoError(t, a.Sync(ctx))
+
+	c := getContainer(t, s, "web")
+	assert.Equal(t, model.PhaseRunning, c.Status.Phase)
+	assert.Equal(t, 1, c.Status.RestartCount)
+	assert.Equal(t, model.ReasonLivenessProbeFailed, c.Status.LastTermination.Reason)
+	assert.Equal(t, 2, rt.Get(c.Status.RuntimeID).Starts)
+}
+
+func TestAgent_RemovesDeletedContainers(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	ctx := context.Background()
+	putContainer(t, s, "web", nil)
+	require.NoError(t, a.Sync(ctx))
+	require.Equal(t, 1, rt.Count())
+
+	require.NoError(t, s.Delete(model.ContainersBucket, model.Key(model.DefaultNamespace, "web")))
+	require.NoError(t, a.Sync(ctx))
+
+	assert.Equal(t, 0, rt.Count())
+}
+
+func TestAgent_ReleasesDeletedContainersOnceRemoved(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	ctx := context.Background()
+	putContainer(t, s, "db-0", func(c *model.Container) {
+		c.Finalizers = []string{model.FinalizerRuntimeCleanup}
+	})
+	require.NoError(t, a.Sync(ctx))
+	require.Equal(t, 1, rt.Count())
+
+	c := getContainer(t, s, "db-0")
+	gone, err := gc.Delete(s, model.ContainersBucket, &c.ObjectMeta)
+	require.NoError(t, err)
+	require.False(t, gone, "the finalizer should hold the record")
+
+	require.NoError(t, a.Sync(ctx))
+	assert.Equal(t, 0, rt.Count())
+	_, err = store.GetJSON[model.Container](s, model.ContainersBucket, c.Key())
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func TestAgent_ReleasesDeletedContainerOnDrainingNode(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	ctx := context.Background()
+	putContainer(t, s, "db-0", func(c *model.Container) {
+		c.Labels = map[string]string{model.LabelStatefulSet: "db"}
+		c.Finalizers = []string{model.FinalizerRuntimeCleanup}
+	})
+	require.NoError(t, a.Sync(ctx))
+
+	c := getContainer(t, s, "db-0")
+	_, err := gc.Delete(s, model.ContainersBucket, &c.ObjectMeta)
+	require.NoError(t, err)
+
+	// Draining does not unbind the replica, so its own agent still
+	// stops it and releases the record.
+	nodes := node.NewRegistry(&node.Config{Store: s, Logger: zerolog.Nop()})
+	_, _, err = nodes.Register(&model.Node{
+		ObjectMeta: model.ObjectMeta{Name: "node-1"},
+		Spec:       model.NodeSpec{Capacity: model.Resources{CPUMillis: 2000, MemoryBytes: 1 << 30}},
+	})
+	require.NoError(t, err)
+	n, err := nodes.Drain("node-1", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n.Status.Drain.Evicted)
+	assert.Equal(t, "node-1", getContainer(t, s, "db-0").Spec.NodeName)
+
+	require.NoError(t, a.Sync(ctx))
+	assert.Equal(t, 0, rt.Count())
+	_, err = store.GetJSON[model.Container](s, model.ContainersBucket, c.Key())
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func TestAgent_RemovesStrayRuntimeContainers(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	putContainer(t, s, "web", nil)
+	ctx := context.Background()
+
+	// Left behind by a record deleted while the orchestrator was down.
+	stray, err := rt.Create(ctx, &runtime.ContainerConfig{Name: "default_old", Labels: map[string]string{
+		model.LabelManaged: "true", model.LabelNamespace: model.DefaultNamespace, model.LabelContainer: "old",
+	}})
+	require.NoError(t, err)
+	require.NoError(t, rt.Start(ctx, stray))
+	unmanaged, err := rt.Create(ctx, &runtime.ContainerConfig{Name: "someone-elses"})
+	require.NoError(t, err)
+
+	require.NoError(t, a.Sync(ctx))
+
+	assert.Nil(t, rt.Get(stray))
+	assert.NotNil(t, rt.Get(unmanaged))
+	assert.NotNil(t, rt.Get(getContainer(t, s, "web").Status.RuntimeID))
+}
+
+func TestAgent_ReplacesRecreatedContainer(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	ctx := context.Background()
+	putContainer(t, s, "web", nil)
+	require.NoError(t, a.Sync(ctx))
+	oldID := getContainer(t, s, "web").Status.RuntimeID
+
+	// Delete and recreate under the same name between syncs.
+	putContainer(t, s, "web", nil)
+	require.NoError(t, a.Sync(ctx))
+
+	c := getContainer(t, s, "web")
+	assert.NotEqual(t, oldID, c.Status.RuntimeID)
+	assert.Nil(t, rt.Get(oldID))
+	assert.Equal(t, 1, rt.Count())
+}
+
+func TestAgent_RecreatesLostRuntimeContainer(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	putContainer(t, s, "web", func(c *model.Container) {
+		c.Status.Phase = model.PhaseRunning
+		c.Status.RuntimeID = "gone"
+	})
+
+	require.NoError(t, a.Sync(context.Background()))
+
+	c := getContainer(t, s, "web")
+	assert.NotEqual(t, "gone", c.Status.RuntimeID)
+	assert.Equal(t, 1, rt.Count())
+}
+
+func TestAgent_StartFailureIsRecorded(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	rt.StartErr = assert.AnError
+	putContainer(t, s, "web", nil)
+
+	err := a.Sync(context.Background())
+	assert.ErrorIs(t, err, assert.AnError)
+
+	c := getContainer(t, s, "web")
+	assert.Equal(t, model.PhasePending, c.Status.Phase)
+	assert.Equal(t, model.ReasonStartError, c.Status.Reason)
+}
+
+func TestAgent_RunReactsToExit(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	putContainer(t, s, "web", func(c *model.Container) { c.Spec.RestartPolicy = model.RestartNever })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		a.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	require.Eventually(t, func() bool {
+		return getContainer(t, s, "web").Status.Phase == model.PhaseRunning
+	}, time.Second, 10*time.Millisecond)
+
+	rt.Exit(getContainer(t, s, "web").Status.RuntimeID, 0)
+
+	assert.Eventually(t, func() bool {
+		return getContainer(t, s, "web").Status.Phase == model.PhaseSucceeded
+	}, time.Second, 10*time.Millisecond)
+}
+
+// crash exits the runtime container of name and syncs once.
+func crash(t *testing.T, a *Agent, s store.Store, rt *fake.Runtime, name string, code int) *model.Container {
+	t.Helper()
+	rt.Exit(getContainer(t, s, name).Status.RuntimeID, code)
+	require.NoError(t, a.Sync(context.Background()))
+	return getContainer(t, s, name)
+}
+
+func TestAgent_Backoff(t *testing.T) {
+	a, _, _ := newTestAgent(t)
+	a.backoffInitial = 10 * time.Second
+	a.backoffMax = time.Minute
+
+	assert.Zero(t, a.backoff(1))
+	assert.Equal(t, 10*time.Second, a.backoff(2))
+	assert.Equal(t, 20*time.Second, a.backoff(3))
+	assert.Equal(t, 40*time.Second, a.backoff(4))
+	assert.Equal(t, time.Minute, a.backoff(5))
+	assert.Equal(t, time.Minute, a.backoff(100))
+}
+
+func TestAgent_CrashLoopBackOff(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	now := time.Now()
+	a.now = func() time.Time { return now }
+	putContainer(t, s, "web", nil)
+	require.NoError(t, a.Sync(context.Background()))
+
+	// The first crash restarts immediately.
+	c := crash(t, a, s, rt, "web", 1)
+	assert.Equal(t, model.PhaseRunning, c.Status.Phase)
+	assert.Equal(t, 1, c.Status.RestartCount)
+	assert.Nil(t, c.Status.BackoffUntil)
+
+	// The second one backs off.
+	c = crash(t, a, s, rt, "web", 3)
+	assert.Equal(t, model.PhasePending, c.Status.Phase)
+	assert.Equal(t, model.ReasonCrashLoopBackOff, c.Status.Reason)
+	assert.Equal(t, "back-off 10s restarting failed container; 1 restarts, last exit code 3", c.Status.Message)
+	assert.Equal(t, 2, c.Status.ConsecutiveCrashes)
+	require.NotNil(t, c.Status.BackoffUntil)
+	assert.Equal(t, now.Add(10*time.Second).UTC(), *c.Status.BackoffUntil)
+	assert.False(t, c.Status.Ready)
+	id := c.Status.RuntimeID
+	assert.Equal(t, 2, rt.Get(id).Starts)
+
+	// Nothing happens until the back-off has passed, even for a fresh agent
+	// reading the state back from the store.
+	require.NoError(t, a.Sync(context.Background()))
+	assert.Equal(t, 2, rt.Get(id).Starts)
+
+	restarted := New(&Config{Store: s, Runtime: rt, Logger: zerolog.Nop(), NodeName: "node-1", Interval: time.Hour})
+	restarted.now = func() time.Time { return now }
+	require.NoError(t, restarted.Sync(context.Background()))
+	assert.Equal(t, 2, rt.Get(id).Starts)
+
+	now = now.Add(11 * time.Second)
+	restarted.now = func() time.Time { return now }
+	require.NoError(t, restarted.Sync(context.Background()))
+
+	c = getContainer(t, s, "web")
+	assert.Equal(t, model.PhaseRunning, c.Status.Phase)
+	assert.Equal(t, 2, c.Status.RestartCount)
+	assert.Nil(t, c.Status.BackoffUntil)
+	assert.Empty(t, c.Status.Reason)
+	assert.Equal(t, 3, rt.Get(id).Starts)
+
+	// The next crash doubles the delay.
+	c = crash(t, restarted, s, rt, "web", 1)
+	assert.Equal(t, now.Add(20*time.Second).UTC(), *c.Status.BackoffUntil)
+}
+
+func TestAgent_CrashLoopBackOffResets(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	now := time.Now()
+	a.now = func() time.Time { return now }
+	putContainer(t, s, "web", nil)
+	require.NoError(t, a.Sync(context.Background()))
+
+	crash(t, a, s, rt, "web", 1)
+	crash(t, a, s, rt, "web", 1)
+	now = now.Add(time.Minute)
+	require.NoError(t, a.Sync(context.Background()))
+	require.Equal(t, 2, getContainer(t, s, "web").Status.ConsecutiveCrashes)
+
+	// Running stably for the reset period clears the crash count, so the
+	// next crash restarts immediately.
+	now = now.Add(DefaultBackoffReset)
+	require.NoError(t, a.Sync(context.Background()))
+	assert.Zero(t, getContainer(t, s, "web").Status.ConsecutiveCrashes)
+
+	c := crash(t, a, s, rt, "web", 1)
+	assert.Equal(t, model.PhaseRunning, c.Status.Phase)
+	assert.Nil(t, c.Status.BackoffUntil)
+	assert.Equal(t, 1, c.Status.ConsecutiveCrashes)
+}
+
+func TestAgent_OnFailureCompletesAfterCrash(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	putContainer(t, s, "job", func(c *model.Container) { c.Spec.RestartPolicy = model.RestartOnFailure })
+	require.NoError(t, a.Sync(context.Background()))
+
+	crash(t, a, s, rt, "job", 1)
+	c := crash(t, a, s, rt, "job", 0)
+	assert.Equal(t, model.PhaseSucceeded, c.Status.Phase)
+	assert.Nil(t, c.Status.BackoffUntil)
+	assert.Equal(t, model.ReasonCompleted, c.Status.Reason)
+}
+
+func TestAgent_MountsVolumes(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	dir := t.TempDir()
+	a.volumes = volume.NewRegistry(volume.NewLocal(dir), volume.HostPath{})
+	require.NoError(t, store.PutJSON(s, model.VolumesBucket, model.Key(model.DefaultNamespace, "data"), &model.Volume{
+		ObjectMeta: model.ObjectMeta{Name: "data", Namespace: model.DefaultNamespace},
+		Spec:       model.VolumeSpec{Driver: model.VolumeDriverLocal},
+	}))
+	require.NoError(t, store.PutJSON(s, model.VolumesBucket, model.Key(model.DefaultNamespace, "remote"), &model.Volume{
+		ObjectMeta: model.ObjectMeta{Name: "remote", Namespace: model.DefaultNamespace},
+		Status:     model.VolumeStatus{NodeName: "node-2"},
+	}))
+	putContainer(t, s, "db", func(c *model.Container) {
+		c.Spec.VolumeMounts = []model.VolumeMount{{Name: "data", MountPath: "/var/lib/db"}}
+	})
+
+	require.NoError(t, a.Sync(context.Background()))
+
+	c := getContainer(t, s, "db")
+	assert.Equal(t, model.PhaseRunning, c.Status.Phase)
+	assert.Equal(t, []runtime.Mount{{Source: filepath.Join(dir, model.DefaultNamespace, "data"), Target: "/var/lib/db"}},
+		rt.Get(c.Status.RuntimeID).Config.Mounts)
+	assert.DirExists(t, filepath.Join(dir, model.DefaultNamespace, "data"))
+
+	v, err := store.GetJSON[model.Volume](s, model.VolumesBucket, model.Key(model.DefaultNamespace, "data"))
+	require.NoError(t, err)
+	assert.Equal(t, "node-1", v.Status.NodeName)
+
+	// Volumes bound elsewhere or missing keep the container pending.
+	for _, name := range []string{"remote", "missing"} {
+		putContainer(t, s, "app-"+name, func(c *model.Container) {
+			c.Spec.VolumeMounts = []model.VolumeMount{{Name: name, MountPath: "/data"}}
+		})
+	}
+	assert.Error(t, a.Sync(context.Background()))
+	for _, name := range []string{"app-remote", "app-missing"} {
+		c := getContainer(t, s, name)
+		assert.Equal(t, model.PhasePending, c.Status.Phase)
+		assert.Equal(t, model.ReasonVolumeUnavailable, c.Status.Reason)
+	}
+}
+
+func TestAgent_VolumeUsageAndPrune(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	dir := t.TempDir()
+	hostDir := t.TempDir()
+	a.volumes = volume.NewRegistry(volume.NewLocal(dir), volume.HostPath{})
+	require.NoError(t, os.WriteFile(filepath.Join(hostDir, "seed"), []byte("12345"), 0o600))
+	require.NoError(t, store.PutJSON(s, model.VolumesBucket, model.Key(model.DefaultNamespace, "data"), &model.Volume{
+		ObjectMeta: model.ObjectMeta{Name: "data", Namespace: model.DefaultNamespace},
+		Spec:       model.VolumeSpec{Driver: model.VolumeDriverLocal, CapacityBytes: 1000},
+	}))
+	require.NoError(t, store.PutJSON(s, model.VolumesBucket, model.Key(model.DefaultNamespace, "host"), &model.Volume{
+		ObjectMeta: model.ObjectMeta{Name: "host", Namespace: model.DefaultNamespace},
+		Spec:       model.VolumeSpec{Driver: model.VolumeDriverHostPath, HostPath: hostDir, NodeName: "node-1"},
+	}))
+	putContainer(t, s, "db", func(c *model.Container) {
+		c.Spec.VolumeMounts = []model.VolumeMount{
+			{Name: "data", MountPath: "/data"},
+			{Name: "host", MountPath: "/host", ReadOnly: true},
+		}
+	})
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, model.DefaultNamespace, "deleted"), 0o750))
+
+	require.NoError(t, a.Sync(context.Background()))
+	c := getContainer(t, s, "db")
+	assert.Equal(t, []runtime.Mount{
+		{Source: filepath.Join(dir, model.DefaultNamespace, "data"), Target: "/data"},
+		{Source: hostDir, Target: "/host", ReadOnly: true},
+	}, rt.Get(c.Status.RuntimeID).Config.Mounts)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, model.DefaultNamespace, "data", "db"), make([]byte, 100), 0o600))
+
+	// Usage is measured on the next pass after the mount.
+	a.now = func() time.Time { return time.Now().Add(time.Hour) }
+	require.NoError(t, a.Sync(context.Background()))
+
+	v, err := store.GetJSON[model.Volume](s, model.VolumesBucket, model.Key(model.DefaultNamespace, "data"))
+	require.NoError(t, err)
+	require.NotNil(t, v.Status.Usage)
+	assert.Equal(t, int64(100), v.Status.Usage.UsedBytes)
+	assert.Equal(t, int64(1000), v.Status.Usage.CapacityBytes)
+	assert.LessOrEqual(t, v.Status.Usage.AvailableBytes, int64(900))
+
+	v, err = store.GetJSON[model.Volume](s, model.VolumesBucket, model.Key(model.DefaultNamespace, "host"))
+	require.NoError(t, err)
+	require.NotNil(t, v.Status.Usage)
+	assert.Equal(t, int64(5), v.Status.Usage.UsedBytes)
+
+	// Data of volumes without a record is removed; host paths are left alone.
+	assert.NoDirExists(t, filepath.Join(dir, model.DefaultNamespace, "deleted"))
+	require.NoError(t, s.Delete(model.ContainersBucket, model.Key(model.DefaultNamespace, "db")))
+	require.NoError(t, s.Delete(model.VolumesBucket, model.Key(model.DefaultNamespace, "data")))
+	require.NoError(t, s.Delete(model.VolumesBucket, model.Key(model.DefaultNamespace, "host")))
+	require.NoError(t, a.Sync(context.Background()))
+	assert.NoDirExists(t, filepath.Join(dir, model.DefaultNamespace, "data"))
+	assert.FileExists(t, filepath.Join(hostDir, "seed"))
+}
+
+// putSecret stores a sealed secret.
+func putSecret(t *testing.T, a *Agent, s store.Store, name string, version int64, data map[string]string) {
+	t.Helper()
+	sec := &model.Secret{ObjectMeta: model.ObjectMeta{Name: name, Namespace: model.DefaultNamespace}, Data: data, Version: version}
+	require.NoError(t, a.secrets.Seal(sec))
+	require.NoError(t, store.PutJSON(s, model.SecretsBucket, sec.Key(), sec))
+}
+
+func TestAgent_ProjectsSecrets(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	cipher, err := secret.NewCipher(bytes.Repeat([]byte{3}, secret.KeySize))
+	require.NoError(t, err)
+	a.secrets = cipher
+	a.filesDir = t.TempDir()
+
+	putSecret(t, a, s, "db", 1, map[string]string{"password": "hunter2", "user": "app"})
+	putContainer(t, s, "web", func(c *model.Container) {
+		c.Spec.Env = map[string]string{"MODE": "prod"}
+		c.Spec.EnvFrom = []model.EnvVarSource{
+			{Name: "DB_PASSWORD", SecretKeyRef: &model.KeySelector{Name: "db", Key: "password"}},
+		}
+		c.Spec.FileMounts = []model.FileMount{{MountPath: "/etc/db", Secret: "db", Keys: []string{"user"}}}
+	})
+
+	require.NoError(t, a.Sync(context.Background()))
+
+	c := getContainer(t, s, "web")
+	assert.Equal(t, map[string]int64{"db": 1}, c.Status.SecretVersions)
+	cfg := rt.Get(c.Status.RuntimeID).Config
+	assert.Equal(t, map[string]string{"MODE": "prod", "DB_PASSWORD": "hunter2"}, cfg.Env)
+	require.Len(t, cfg.Mounts, 1)
+	assert.Equal(t, "/etc/db", cfg.Mounts[0].Target)
+	assert.True(t, cfg.Mounts[0].ReadOnly)
+	b, err := os.ReadFile(filepath.Join(cfg.Mounts[0].Source, "user"))
+	require.NoError(t, err)
+	assert.Equal(t, "app", string(b))
+	assert.NoFileExists(t, filepath.Join(cfg.Mounts[0].Source, "password"))
+
+	// A new version of the secret replaces the runtime container.
+	putSecret(t, a, s, "db", 2, map[string]string{"password": "correct-horse", "user": "app"})
+	require.NoError(t, a.Sync(context.Background()))
+
+	updated := getContainer(t, s, "web")
+	assert.NotEqual(t, c.Status.RuntimeID, updated.Status.RuntimeID)
+	assert.Equal(t, map[string]int64{"db": 2}, updated.Status.SecretVersions)
+	assert.Equal(t, "correct-horse", rt.Get(updated.Status.RuntimeID).Config.Env["DB_PASSWORD"])
+	assert.Nil(t, rt.Get(c.Status.RuntimeID))
+
+	// Removing the container removes its secret files.
+	require.NoError(t, s.Delete(model.ContainersBucket, model.Key(model.DefaultNamespace, "web")))
+	require.NoError(t, a.Sync(context.Background()))
+	assert.NoDirExists(t, filepath.Join(a.filesDir, model.DefaultNamespace, "web"))
+}
+
+func TestAgent_MissingSecretKeepsContainerPending(t *testing.T) {
+	a, s, _ := newTestAgent(t)
+	cipher, err := secret.NewCipher(bytes.Repeat([]byte{3}, secret.KeySize))
+	require.NoError(t, err)
+	a.secrets = cipher
+	a.filesDir = t.TempDir()
+
+	putSecret(t, a, s, "db", 1, map[string]string{"user": "app"})
+	putContainer(t, s, "web", func(c *model.Container) {
+		c.Spec.EnvFrom = []model.EnvVarSource{
+			{Name: "DB_PASSWORD", SecretKeyRef: &model.KeySelector{Name: "db", Key: "password"}},
+		}
+	})
+
+	assert.Error(t, a.Sync(context.Background()))
+	c := getContainer(t, s, "web")
+	assert.Equal(t, model.PhasePending, c.Status.Phase)
+	assert.Equal(t, model.ReasonConfigUnavailable, c.Status.Reason)
+	assert.Contains(t, c.Status.Message, "no key password")
+}
+
+// syncPulling syncs a, waits for the image pulls the sync started and
+// syncs again to create the containers waiting for them.
+func syncPulling(t *testing.T, a *Agent) {
+	t.Helper()
+	require.NoError(t, a.Sync(context.Background()))
+	waitForPulls(t, a)
+	require.NoError(t, a.Sync(context.Background()))
+}
+
+func waitForPulls(t *testing.T, a *Agent) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		return len(a.pulls) == 0
+	}, 5*time.Second, time.Millisecond)
+}
+
+func TestAgent_PullsImages(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	cipher, err := secret.NewCipher(bytes.Repeat([]byte{3}, secret.KeySize))
+	require.NoError(t, err)
+	a.secrets = cipher
+	rt.Registry = fake.NewRegistry()
+	rt.Registry.Push("registry.example.com/app:v1", 1000)
+	rt.Registry.RequireAuth("ci", "s3cret")
+
+	putSecret(t, a, s, "hub", 1, map[string]string{"server": "docker.io", "username": "me", "password": "x"})
+	putSecret(t, a, s, "private", 1, map[string]string{
+		"server": "https://registry.example.com", "username": "ci", "password": "s3cret",
+	})
+	pullSecrets := func(c *model.Container) {
+		c.Spec.Image = "registry.example.com/app:v1"
+		c.Spec.ImagePullSecrets = []string{"hub", "private"}
+	}
+	putContainer(t, s, "web", pullSecrets)
+	syncPulling(t, a)
+
+	c := getContainer(t, s, "web")
+	assert.Equal(t, model.PhaseRunning, c.Status.Phase)
+	assert.Nil(t, c.Status.ImagePull)
+	pulls := rt.Registry.Pulls()
+	require.Len(t, pulls, 1)
+	assert.Equal(t, "registry.example.com/app:v1", pulls[0].Image)
+	require.NotNil(t, pulls[0].Auth)
+	assert.Equal(t, "ci", pulls[0].Auth.Username)
+
+	events, err := store.ListJSON[model.Event](s, model.EventsBucket, "")
+	require.NoError(t, err)
+	var reasons []string
+	for _, e := range events {
+		reasons = append(reasons, e.Reason)
+	}
+	assert.Contains(t, reasons, model.EventPulling)
+	assert.Contains(t, reasons, model.EventPulled)
+
+	// IfNotPresent, the default for a tagged image, pulls only once;
+	// Always pulls every time.
+	putContainer(t, s, "api", pullSecrets)
+	putContainer(t, s, "worker", func(c *model.Container) {
+		pullSecrets(c)
+		c.Spec.ImagePullPolicy = model.PullAlways
+	})
+	syncPulling(t, a)
+	assert.Equal(t, model.PhaseRunning, getContainer(t, s, "api").Status.Phase)
+	assert.Equal(t, model.PhaseRunning, getContainer(t, s, "worker").Status.Phase)
+	assert.Len(t, rt.Registry.Pulls(), 2)
+}
+
+func TestAgent_ImagePullBackOff(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	now := time.Now()
+	a.now = func() time.Time { return now }
+	rt.Registry = fake.NewRegistry()
+	putContainer(t, s, "web", func(c *model.Container) { c.Spec.Image = "app:v2" })
+
+	syncPulling(t, a)
+	c := getContainer(t, s, "web")
+	assert.Equal(t, model.PhasePending, c.Status.Phase)
+	assert.Empty(t, c.Status.RuntimeID)
+	assert.Equal(t, model.ReasonImagePullBackOff, c.Status.Reason)
+	assert.Equal(t, "back-off 10s pulling image app:v2: pulling app:v2: image not found in registry", c.Status.Message)
+	require.NotNil(t, c.Status.ImagePull)
+	assert.Equal(t, 1, c.Status.ImagePull.Failures)
+	require.NotNil(t, c.Status.BackoffUntil)
+	assert.Equal(t, now.Add(10*time.Second).UTC(), *c.Status.BackoffUntil)
+
+	// Nothing is pulled until the back-off has passed.
+	require.NoError(t, a.Sync(context.Background()))
+	assert.Len(t, rt.Registry.Pulls(), 1)
+
+	now = now.Add(11 * time.Second)
+	syncPulling(t, a)
+	c = getContainer(t, s, "web")
+	assert.Equal(t, 2, c.Status.ImagePull.Failures)
+	assert.Equal(t, now.Add(20*time.Second).UTC(), *c.Status.BackoffUntil)
+
+	rt.Registry.Push("app:v2", 100)
+	now = now.Add(21 * time.Second)
+	syncPulling(t, a)
+	c = getContainer(t, s, "web")
+	assert.Equal(t, model.PhaseRunning, c.Status.Phase)
+	assert.Nil(t, c.Status.ImagePull)
+	assert.Nil(t, c.Status.BackoffUntil)
+	assert.Empty(t, c.Status.Reason)
+}
+
+func TestAgent_PullRunsInBackground(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	a.pullTimeout = 50 * time.Millisecond
+	rt.Registry = fake.NewRegistry()
+	rt.Registry.Push("app:v1", 100)
+	rt.Registry.Block = make(chan struct{})
+	putContainer(t, s, "web", nil)
+	putContainer(t, s, "api", nil)
+
+	// Neither the sync nor the other container waits for a pull.
+	require.NoError(t, a.Sync(context.Background()))
+	for _, name := range []string{"web", "api"} {
+		c := getContainer(t, s, name)
+		assert.Equal(t, model.ReasonPullingImage, c.Status.Reason)
+		require.NotNil(t, c.Status.ImagePull)
+	}
+	require.Eventually(t, func() bool { return len(rt.Registry.Pulls()) == 2 }, 5*time.Second, time.Millisecond)
+
+	// Pulls in flight are not started again.
+	require.NoError(t, a.Sync(context.Background()))
+	waitForPulls(t, a)
+	assert.Len(t, rt.Registry.Pulls(), 2)
+
+	c := getContainer(t, s, "web")
+	assert.Equal(t, model.ReasonImagePullBackOff, c.Status.Reason)
+	assert.Contains(t, c.Status.Message, "pull timed out after 50ms")
+	assert.Equal(t, 1, c.Status.ImagePull.Failures)
+}
+
+func TestAgent_PullPolicyNever(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	rt.Registry = fake.NewRegistry()
+	rt.Registry.Push("app:v1", 100)
+	putContainer(t, s, "web", func(c *model.Container) {
+		c.Spec.Image = "app:v1"
+		c.Spec.ImagePullPolicy = model.PullNever
+	})
+
+	require.NoError(t, a.Sync(context.Background()))
+	c := getContainer(t, s, "web")
+	assert.Equal(t, model.PhasePending, c.Status.Phase)
+	assert.Equal(t, model.ReasonErrImageNeverPull, c.Status.Reason)
+	assert.Empty(t, rt.Registry.Pulls())
+}
+
+func putConfigMap(t *testing.T, s store.Store, name string, version int64, data map[string]string) {
+	t.Helper()
+	m := &model.ConfigMap{ObjectMeta: model.ObjectMeta{Name: name, Namespace: model.DefaultNamespace}, Data: data, Version: version}
+	require.NoError(t, store.PutJSON(s, model.ConfigMapsBucket, m.Key(), m))
+}
+
+func TestAgent_ProjectsConfigMaps(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	a.filesDir = t.TempDir()
+
+	putConfigMap(t, s, "app", 1, map[string]string{"LOG_LEVEL": "debug", "app.conf": "port = 80\n"})
+	putContainer(t, s, "web", func(c *model.Container) {
+		c.Spec.EnvFrom = []model.EnvVarSource{
+			{Name: "LOG_LEVEL", ConfigMapKeyRef: &model.KeySelector{Name: "app", Key: "LOG_LEVEL"}},
+		}
+		c.Spec.FileMounts = []model.FileMount{{MountPath: "/etc/app", ConfigMap: "app", Keys: []string{"app.conf"}}}
+	})
+
+	require.NoError(t, a.Sync(context.Background()))
+
+	c := getContainer(t, s, "web")
+	assert.Equal(t, map[string]int64{"app": 1}, c.Status.ConfigMapVersions)
+	cfg := rt.Get(c.Status.RuntimeID).Config
+	assert.Equal(t, "debug", cfg.Env["LOG_LEVEL"])
+	require.Len(t, cfg.Mounts, 1)
+	b, err := os.ReadFile(filepath.Join(cfg.Mounts[0].Source, "app.conf"))
+	require.NoError(t, err)
+	assert.Equal(t, "port = 80\n", string(b))
+
+	// The config map is also used as an environment variable, so a change
+	// restarts the container.
+	putConfigMap(t, s, "app", 2, map[string]string{"LOG_LEVEL": "info", "app.conf": "port = 80\n"})
+	require.NoError(t, a.Sync(context.Background()))
+
+	updated := getContainer(t, s, "web")
+	assert.NotEqual(t, c.Status.RuntimeID, updated.Status.RuntimeID)
+	assert.Equal(t, map[string]int64{"app": 2}, updated.Status.ConfigMapVersions)
+	assert.Equal(t, "info", rt.Get(updated.Status.RuntimeID).Config.Env["LOG_LEVEL"])
+}
+
+func TestAgent_HotUpdatesConfigMapFiles(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	a.filesDir = t.TempDir()
+
+	putConfigMap(t, s, "app", 1, map[string]string{"app.conf": "port = 80\n", "extra.conf": "x"})
+	putContainer(t, s, "web", func(c *model.Container) {
+		c.Spec.FileMounts = []model.FileMount{
+			{MountPath: "/etc/app", ConfigMap: "app", UpdatePolicy: model.FileUpdateHot},
+		}
+	})
+
+	require.NoError(t, a.Sync(context.Background()))
+	c := getContainer(t, s, "web")
+	dir := rt.Get(c.Status.RuntimeID).Config.Mounts[0].Source
+	assert.FileExists(t, filepath.Join(dir, "extra.conf"))
+
+	putConfigMap(t, s, "app", 2, map[string]string{"app.conf": "port = 8080\n"})
+	require.NoError(t, a.Sync(context.Background()))
+
+	updated := getContainer(t, s, "web")
+	assert.Equal(t, c.Status.RuntimeID, updated.Status.RuntimeID)
+	assert.Equal(t, map[string]int64{"app": 2}, updated.Status.ConfigMapVersions)
+	b, err := os.ReadFile(filepath.Join(dir, "app.conf"))
+	require.NoError(t, err)
+	assert.Equal(t, "port = 8080\n", string(b))
+	assert.NoFileExists(t, filepath.Join(dir, "extra.conf"))
+}
+
+func TestAgent_StartsGroupMembersInOrder(t *testing.T) {
+	a, s, rt := newTestAgent(t)
+	ctx := context.Background()
+
+	g := &model.Group{
+		ObjectMeta: model.ObjectMeta{Name: "shop", Namespace: model.DefaultNamespace},
+		Spec: model.GroupSpec{
+			InitContainers: []model.GroupContainer{{Name: "migrate", Spec: model.ContainerSpec{Image: "migrate"}}},
+			Sidecars:       []model.GroupContainer{{Name: "proxy", Spec: model.ContainerSpec{Image: "envoy"}}},
+			Containers:     []model.GroupContainer{{Name: "app", Spec: model.ContainerSpec{Image: "shop"}}},
+			RestartPolicy:  model.RestartNever,
+		},
+	}
+	g.SetDefaults()
+	require.NoError(t, store.PutJSON(s, model.GroupsBucket, g.Key(), g))
+	for _, m := range g.Members() {
+		putContainer(t, s, g.MemberName(m.Name), func(c *model.Container) {
+			c.Labels = map[string]string{model.LabelGroup: g.Name, model.LabelGroupRole: string(m.Role)}
+			c.Spec.RestartPolicy = m.Spec.RestartPolicy
+		})
+	}
+
+	require.NoError(t, a.Sync(ctx))
+	migrate := getContainer(t, s, "shop-migrate")
+	assert.Equal(t, model.PhaseRunning, migrate.Status.Phase)
+	for _, name := range []string{"shop-proxy", "shop-app"} {
+		c := getContainer(t, s, name)
+		assert.Empty(t, c.Status.RuntimeID, name)
+		assert.Equal(t, model.ReasonInitializing, c.Status.Reason)
+		assert.Equal(t, "waiting for migrate", c.Status.Message)
+	}
+
+	// The sidecar starts once the init container succeeds, and the main
+	// container once the sidecar is ready.
+	rt.Exit(migrate.Status.RuntimeID, 0)
+	require.NoError(t, a.Sync(ctx))
+	assert.Equal(t, model.PhaseSucceeded, getContainer(t, s, "shop-migrate").Status.Phase)
+	require.NoError(t, a.Sync(ctx))
+	proxy := getContainer(t, s, "shop-proxy")
+	assert.Equal(t, model.PhaseRunning, proxy.Status.Phase)
+	assert.Equal(t, "waiting for proxy", getContainer(t, s, "shop-app").Status.Message)
+	require.NoError(t, a.Sync(ctx))
+	app := getContainer(t, s, "shop-app")
+	assert.Equal(t, model.PhaseRunning, app.Status.Phase)
+	assert.Empty(t, app.Status.Reason)
+
+	// Members share one sandbox.
+	sandboxes := rt.Sandboxes()
+	require.Len(t, sandboxes, 1)
+	for id := range sandboxes {
+		assert.Equal(t, id, rt.Get(proxy.Status.RuntimeID).Config.Sandbox)
+		assert.Equal(t, id, rt.Get(app.Status.RuntimeID).Config.Sandbox)
+	}
+
+	// The sidecar stops when the main container is done.
+	rt.Exit(app.Status.RuntimeID, 0)
+	require.NoError(t, a.Sync(ctx))
+	require.NoError(t, a.Sync(ctx))
+	require.NoError(t, a.Sync(ctx))
+	proxy = getContainer(t, s, "shop-proxy")
+	assert.Equal(t, model.PhaseSucceeded, proxy.Status.Phase)
+	assert.Equal(t, 0, proxy.Status.RestartCount)
+	assert.Equal(t, model.ReasonCompleted, proxy.Status.LastTermination.Reason)
+
+	// The sandbox goes with the last member.
+	for _, m := range g.Members() {
+		require.NoError(t, s.Delete(model.ContainersBucket, model.Key(g.Namespace, g.MemberName(m.Name))))
+	}
+	require.NoError(t, a.Sync(ctx))
+	assert.Empty(t, rt.Sandboxes())
+	assert.Equal(t, 0, rt.Count())
+}
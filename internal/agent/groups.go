@@ -0,0 +1,188 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/runtime"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// groupView is what one sync pass knows of the groups with members on this
+// node.
+type groupView struct {
+	groups map[string]*model.Group
+
+	// members maps the key of each group to its members on this node, by
+	// member name.
+	members map[string]map[string]*model.Container
+}
+
+// loadGroups reads the groups of the members among containers, which are
+// those scheduled to this node.
+func (a *Agent) loadGroups(containers []*model.Container) (*groupView, error) {
+	v := &groupView{
+		groups:  make(map[string]*model.Group),
+		members: make(map[string]map[string]*model.Container),
+	}
+	for _, c := range containers {
+		name, ok := c.Labels[model.LabelGroup]
+		if !ok {
+			continue
+		}
+		key := model.Key(c.Namespace, name)
+		if v.members[key] == nil {
+			v.members[key] = make(map[string]*model.Container)
+			g, err := store.GetJSON[model.Group](a.store, model.GroupsBucket, key)
+			if err != nil && !store.IsNotFound(err) {
+				return nil, fmt.Errorf("reading group %s: %w", key, err)
+			}
+			if g != nil {
+				v.groups[key] = g
+			}
+		}
+		v.members[key][strings.TrimPrefix(c.Name, name+"-")] = c
+	}
+	return v, nil
+}
+
+// groupOf returns the key of the group c is a member of, if any.
+func groupOf(c *model.Container) (string, bool) {
+	name, ok := c.Labels[model.LabelGroup]
+	if !ok {
+		return "", false
+	}
+	return model.Key(c.Namespace, name), true
+}
+
+// blocked explains why the group member c may not start yet, or returns ""
+// if it may, as may containers outside groups.
+func (v *groupView) blocked(c *model.Container) string {
+	key, ok := groupOf(c)
+	if !ok {
+		return ""
+	}
+	g, ok := v.groups[key]
+	if !ok {
+		return fmt.Sprintf("group %s not found", c.Labels[model.LabelGroup])
+	}
+	member := strings.TrimPrefix(c.Name, g.Name+"-")
+	if wait := g.WaitingFor(member, v.members[key]); wait != "" {
+		return "waiting for " + wait
+	}
+	return ""
+}
+
+// finished reports whether c is a sidecar whose group's main containers
+// have all finished, so it should stop too.
+func (v *groupView) finished(c *model.Container) bool {
+	key, ok := groupOf(c)
+	if !ok || c.Labels[model.LabelGroupRole] != string(model.RoleSidecar) {
+		return false
+	}
+	g, ok := v.groups[key]
+	if !ok {
+		return false
+	}
+	for _, m := range g.Spec.Containers {
+		if mc := v.members[key][m.Name]; mc == nil || !mc.Status.IsTerminal() {
+			return false
+		}
+	}
+	return true
+}
+
+// wait records why a group member has not started yet.
+func (a *Agent) wait(c *model.Container, why string) error {
+	if c.Status.Reason == model.ReasonInitializing && c.Status.Message == why {
+		return nil
+	}
+	_, err := a.updateStatus(c, func(s *model.ContainerStatus) {
+		s.Reason = model.ReasonInitializing
+		s.Message = why
+	})
+	return err
+}
+
+// finish stops a sidecar whose group is done. The exit is recorded as a
+// success and not restarted.
+func (a *Agent) finish(ctx context.Context, c *model.Container) error {
+	id := c.Status.RuntimeID
+	a.mu.Lock()
+	a.killReasons[id] = model.ReasonCompleted
+	a.mu.Unlock()
+
+	if err := a.runtime.Stop(ctx, id, a.stopTimeout); err != nil {
+		return fmt.Errorf("stopping sidecar: %w", err)
+	}
+	a.logger.Info().Str("container", c.Key()).Msg("group finished, sidecar stopped")
+	a.Trigger()
+	return nil
+}
+
+// sandbox returns the sandbox the members of c's group share on this node,
+// creating it on first use. It returns "" for containers outside groups and
+// when the runtime has no sandboxes.
+func (a *Agent) sandbox(ctx context.Context, c *model.Container) (string, error) {
+	sr, ok := a.runtime.(runtime.SandboxRuntime)
+	if !ok {
+		return "", nil
+	}
+	key, ok := groupOf(c)
+	if !ok {
+		return "", nil
+	}
+
+	a.mu.Lock()
+	id, ok := a.sandboxes[key]
+	a.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	id, err := sr.CreateSandbox(ctx, c.Namespace+"_"+c.Labels[model.LabelGroup], map[string]string{
+		model.LabelManaged:   "true",
+		model.LabelNamespace: c.Namespace,
+		model.LabelGroup:     c.Labels[model.LabelGroup],
+	})
+	if err != nil {
+		return "", fmt.Errorf("creating sandbox: %w", err)
+	}
+	a.mu.Lock()
+	a.sandboxes[key] = id
+	a.mu.Unlock()
+	a.logger.Info().Str("group", key).Str("sandbox", id).Msg("sandbox created")
+	return id, nil
+}
+
+// removeSandboxes removes the sandboxes of groups without members on this
+// node. Their runtime containers have been removed by then.
+func (a *Agent) removeSandboxes(ctx context.Context, v *groupView) {
+	sr, ok := a.runtime.(runtime.SandboxRuntime)
+	if !ok {
+		return
+	}
+
+	a.mu.Lock()
+	unused := make(map[string]string)
+	for key, id := range a.sandboxes {
+		if _, ok := v.members[key]; !ok {
+			unused[key] = id
+		}
+	}
+	a.mu.Unlock()
+
+	for key, id := range unused {
+		if err := sr.RemoveSandbox(ctx, id); err != nil && !errors.Is(err, runtime.ErrNotFound) {
+			a.logger.Error().Err(err).Str("group", key).Str("sandbox", id).Msg("failed to remove sandbox")
+			continue
+		}
+		a.mu.Lock()
+		delete(a.sandboxes, key)
+		a.mu.Unlock()
+		a.logger.Info().Str("group", key).Str("sandbox", id).Msg("sandbox removed")
+	}
+}
@@ -0,0 +1,240 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/runtime"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// pullProgressInterval is how often the progress of a pull is written to
+// the container's status.
+const pullProgressInterval = time.Second
+
+// ensureImage makes sure the runtime has the image of c before a runtime
+// container is created from it, pulling it as the container's pull policy
+// says. It reports whether the image is ready. Pulls run in the background
+// so that a slow registry holds up no other container: the pull is
+// recorded on the container's status, and a resync follows once it ends.
+// When a pull fails the container backs off before the next attempt.
+// Runtimes that do not pull images have every image ready.
+func (a *Agent) ensureImage(ctx context.Context, c *model.Container) (bool, error) {
+	images, ok := a.runtime.(runtime.ImageRuntime)
+	image := c.Spec.Image
+	if !ok || image == "" {
+		return true, nil
+	}
+
+	a.mu.Lock()
+	pulling := a.pulls[c.Key()] != ""
+	pulled := a.pulled[c.Key()] == image
+	delete(a.pulled, c.Key())
+	a.mu.Unlock()
+	if pulling {
+		return false, nil
+	}
+	if pulled {
+		return true, nil
+	}
+
+	policy := c.Spec.ImagePullPolicy
+	if policy == "" {
+		policy = model.DefaultPullPolicy(image)
+	}
+	if policy != model.PullAlways {
+		present, err := images.HasImage(ctx, image)
+		if err != nil {
+			return false, fmt.Errorf("looking up image %s: %w", image, err)
+		}
+		if present {
+			return true, nil
+		}
+		if policy == model.PullNever {
+			return false, a.pullFailed(c, model.ReasonErrImageNeverPull,
+				errors.New("the image is not present and the pull policy is Never"))
+		}
+	}
+
+	auth, err := a.registryAuth(c)
+	if err != nil {
+		return false, a.fail(c, model.ReasonConfigUnavailable, err)
+	}
+
+	started := a.now().UTC()
+	c, err = a.updateStatus(c, func(s *model.ContainerStatus) {
+		failures := 0
+		if s.ImagePull != nil && s.ImagePull.Image == image {
+			failures = s.ImagePull.Failures
+		}
+		s.ImagePull = &model.ImagePullStatus{Image: image, StartedAt: started, Failures: failures}
+		s.Reason = model.ReasonPullingImage
+		s.Message = "pulling image " + image
+	})
+	if err != nil {
+		return false, err
+	}
+	a.events.Eventf(model.ContainersBucket, &c.ObjectMeta, model.EventNormal, model.EventPulling,
+		"Pulling image %s", image)
+
+	a.pull(ctx, images, c, auth, started)
+	return false, nil
+}
+
+// pull pulls the image of c in the background, giving up after the pull
+// timeout, records the outcome on c's status and triggers a resync.
+func (a *Agent) pull(ctx context.Context, images runtime.ImageRuntime, c *model.Container, auth *runtime.RegistryAuth, started time.Time) {
+	key, image := c.Key(), c.Spec.Image
+	a.mu.Lock()
+	a.pulls[key] = image
+	a.mu.Unlock()
+
+	go func() {
+		pullCtx, cancel := context.WithTimeout(ctx, a.pullTimeout)
+		err := images.PullImage(pullCtx, image, auth, a.reportPull(c))
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			err = fmt.Errorf("pull timed out after %s", a.pullTimeout)
+		}
+
+		switch {
+		case err == nil:
+			a.pullDone(c, started)
+		case ctx.Err() != nil:
+		default:
+			if err := a.pullFailed(c, model.ReasonImagePullBackOff, err); err != nil {
+				a.logger.Error().Err(err).Str("container", key).Msg("failed to record image pull failure")
+			}
+		}
+
+		a.mu.Lock()
+		delete(a.pulls, key)
+		if err == nil {
+			a.pulled[key] = image
+		}
+		a.mu.Unlock()
+		a.Trigger()
+	}()
+}
+
+// pullDone records that the image of c was pulled.
+func (a *Agent) pullDone(c *model.Container, started time.Time) {
+	image := c.Spec.Image
+	took := a.now().Sub(started).Round(time.Millisecond)
+	a.logger.Info().Str("container", c.Key()).Str("image", image).Dur("took", took).Msg("image pulled")
+	a.events.Eventf(model.ContainersBucket, &c.ObjectMeta, model.EventNormal, model.EventPulled,
+		"Successfully pulled image %s in %s", image, took)
+	_, err := a.updateStatus(c, func(s *model.ContainerStatus) {
+		s.ImagePull = nil
+		s.BackoffUntil = nil
+		s.Reason = ""
+		s.Message = ""
+	})
+	if err != nil && !errors.Is(err, errStale) {
+		a.logger.Error().Err(err).Str("container", c.Key()).Msg("failed to record image pull")
+	}
+}
+
+// forgetPulls drops the pulled images of containers that are gone from
+// this node, those not in seen.
+func (a *Agent) forgetPulls(seen map[string]bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for key := range a.pulled {
+		if !seen[key] {
+			delete(a.pulled, key)
+		}
+	}
+}
+
+// reportPull returns a progress callback writing the progress of the pull
+// of c's image to its status, at most once per pullProgressInterval.
+func (a *Agent) reportPull(c *model.Container) func(runtime.PullProgress) {
+	var last time.Time
+	return func(p runtime.PullProgress) {
+		now := a.now()
+		if !last.IsZero() && now.Sub(last) < pullProgressInterval {
+			return
+		}
+		last = now
+
+		_, err := a.updateStatus(c, func(s *model.ContainerStatus) {
+			if s.ImagePull == nil {
+				return
+			}
+			s.ImagePull.Progress = p.Status
+			s.ImagePull.CurrentBytes = p.Current
+			s.ImagePull.TotalBytes = p.Total
+		})
+		if err != nil {
+			a.logger.Debug().Err(err).Str("container", c.Key()).Msg("failed to record pull progress")
+		}
+	}
+}
+
+// pullFailed records that the image of c could not be pulled and backs
+// off the next attempt, doubling the delay with every failure in a row.
+func (a *Agent) pullFailed(c *model.Container, reason string, err error) error {
+	image := c.Spec.Image
+	var delay time.Duration
+	_, updateErr := a.updateStatus(c, func(s *model.ContainerStatus) {
+		now := a.now().UTC()
+		if s.ImagePull == nil || s.ImagePull.Image != image {
+			s.ImagePull = &model.ImagePullStatus{Image: image, StartedAt: now}
+		}
+		s.ImagePull.Failures++
+		s.ImagePull.Error = err.Error()
+		delay = a.backoff(s.ImagePull.Failures + 1)
+		until := now.Add(delay)
+		s.Phase = model.PhasePending
+		s.BackoffUntil = &until
+		s.Reason = reason
+		s.Message = fmt.Sprintf("back-off %s pulling image %s: %v", delay, image, err)
+	})
+	if updateErr != nil {
+		return errors.Join(err, updateErr)
+	}
+
+	a.logger.Warn().Err(err).Str("container", c.Key()).Str("image", image).Dur("backoff", delay).Msg("image pull failed")
+	a.events.Eventf(model.ContainersBucket, &c.ObjectMeta, model.EventWarning, model.EventFailed,
+		"Failed to pull image %s: %v", image, err)
+	a.wakeAfter(c.Key(), delay)
+	return nil
+}
+
+// registryAuth returns the credentials among the image pull secrets of c
+// that are for the registry of its image, or nil if none are.
+func (a *Agent) registryAuth(c *model.Container) (*runtime.RegistryAuth, error) {
+	if len(c.Spec.ImagePullSecrets) == 0 {
+		return nil, nil
+	}
+	if a.secrets == nil {
+		return nil, errors.New("secrets are not supported on this node")
+	}
+
+	registry := model.ImageRegistry(c.Spec.Image)
+	for _, name := range c.Spec.ImagePullSecrets {
+		s, err := a.secrets.Get(a.store, model.Key(c.Namespace, name))
+		if store.IsNotFound(err) {
+			return nil, fmt.Errorf("image pull secret %s not found", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		server := s.Data[model.RegistrySecretServer]
+		if server == "" {
+			return nil, fmt.Errorf("image pull secret %s has no key %s", name, model.RegistrySecretServer)
+		}
+		if model.NormalizeRegistry(server) == registry {
+			return &runtime.RegistryAuth{
+				Server:   server,
+				Username: s.Data[model.RegistrySecretUsername],
+				Password: s.Data[model.RegistrySecretPassword],
+			}, nil
+		}
+	}
+	return nil, nil
+}
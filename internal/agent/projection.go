@@ -0,0 +1,297 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/runtime"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// sources are the secrets and config maps a container refers to, with
+// secret values opened.
+type sources struct {
+	secrets    map[string]*model.Secret
+	configMaps map[string]*model.ConfigMap
+}
+
+// projection is what a container gets from its sources.
+type projection struct {
+	env               map[string]string
+	mounts            []runtime.Mount
+	secretVersions    map[string]int64
+	configMapVersions map[string]int64
+}
+
+// loadSources reads the secrets and config maps c refers to.
+func (a *Agent) loadSources(c *model.Container) (*sources, error) {
+	src := &sources{
+		secrets:    make(map[string]*model.Secret),
+		configMaps: make(map[string]*model.ConfigMap),
+	}
+	secretNames := c.Spec.SecretNames()
+	if len(secretNames) > 0 && a.secrets == nil {
+		return nil, errors.New("secrets are not supported on this node")
+	}
+	for _, name := range secretNames {
+		s, err := a.secrets.Get(a.store, model.Key(c.Namespace, name))
+		if store.IsNotFound(err) {
+			return nil, fmt.Errorf("secret %s not found", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		src.secrets[name] = s
+	}
+	for _, name := range c.Spec.ConfigMapNames() {
+		m, err := store.GetJSON[model.ConfigMap](a.store, model.ConfigMapsBucket, model.Key(c.Namespace, name))
+		if store.IsNotFound(err) {
+			return nil, fmt.Errorf("config map %s not found", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		src.configMaps[name] = m
+	}
+	return src, nil
+}
+
+// project resolves the sources of c. Environment values are returned
+// directly; file mounts are written under the files directory and mounted
+// read-only.
+func (a *Agent) project(c *model.Container) (*projection, error) {
+	if len(c.Spec.EnvFrom) == 0 && len(c.Spec.FileMounts) == 0 {
+		return &projection{}, nil
+	}
+	if len(c.Spec.FileMounts) > 0 && a.filesDir == "" {
+		return nil, errors.New("file mounts are not supported on this node")
+	}
+	src, err := a.loadSources(c)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &projection{
+		env:               make(map[string]string, len(c.Spec.EnvFrom)),
+		secretVersions:    src.secretVersions(),
+		configMapVersions: src.configMapVersions(),
+	}
+	for _, e := range c.Spec.EnvFrom {
+		v, err := src.env(&e)
+		if err != nil {
+			return nil, err
+		}
+		p.env[e.Name] = v
+	}
+
+	if err := os.RemoveAll(a.filesDirOf(c.Key())); err != nil {
+		return nil, fmt.Errorf("clearing projected files: %w", err)
+	}
+	for i := range c.Spec.FileMounts {
+		fm := &c.Spec.FileMounts[i]
+		dir, err := a.writeFiles(c.Key(), i, fm, src)
+		if err != nil {
+			return nil, err
+		}
+		p.mounts = append(p.mounts, runtime.Mount{Source: dir, Target: fm.MountPath, ReadOnly: true})
+	}
+	return p, nil
+}
+
+// sourceChanges reports whether a source c last received has a newer
+// version, and whether every such change reaches c through hot-updated
+// file mounts only. Deleted sources do not count; the container keeps
+// what it has.
+func (a *Agent) sourceChanges(c *model.Container) (changed, hot bool) {
+	hot = true
+	for name, version := range c.Status.SecretVersions {
+		s, err := store.GetJSON[model.Secret](a.store, model.SecretsBucket, model.Key(c.Namespace, name))
+		if err == nil && s.Version != version {
+			changed = true
+			hot = hot && onlyHot(&c.Spec, name, "")
+		}
+	}
+	for name, version := range c.Status.ConfigMapVersions {
+		m, err := store.GetJSON[model.ConfigMap](a.store, model.ConfigMapsBucket, model.Key(c.Namespace, name))
+		if err == nil && m.Version != version {
+			changed = true
+			hot = hot && onlyHot(&c.Spec, "", name)
+		}
+	}
+	return changed, hot
+}
+
+// onlyHot reports whether the given secret or config map reaches the
+// container through hot-updated file mounts only.
+func onlyHot(spec *model.ContainerSpec, secret, configMap string) bool {
+	for _, e := range spec.EnvFrom {
+		if (e.SecretKeyRef != nil && e.SecretKeyRef.Name == secret) ||
+			(e.ConfigMapKeyRef != nil && e.ConfigMapKeyRef.Name == configMap) {
+			return false
+		}
+	}
+	for _, fm := range spec.FileMounts {
+		if fm.UpdatePolicy != model.FileUpdateHot &&
+			((secret != "" && fm.Secret == secret) || (configMap != "" && fm.ConfigMap == configMap)) {
+			return false
+		}
+	}
+	return true
+}
+
+// hotUpdate rewrites the hot-updated file mounts of a running container
+// and records the versions it now has.
+func (a *Agent) hotUpdate(c *model.Container) error {
+	src, err := a.loadSources(c)
+	if err != nil {
+		return err
+	}
+	for i := range c.Spec.FileMounts {
+		fm := &c.Spec.FileMounts[i]
+		if fm.UpdatePolicy != model.FileUpdateHot {
+			continue
+		}
+		if _, err := a.writeFiles(c.Key(), i, fm, src); err != nil {
+			return err
+		}
+	}
+	_, err = a.updateStatus(c, func(s *model.ContainerStatus) {
+		s.SecretVersions = src.secretVersions()
+		s.ConfigMapVersions = src.configMapVersions()
+	})
+	if err != nil {
+		return err
+	}
+	a.logger.Info().Str("container", c.Key()).Msg("projected files updated in place")
+	return nil
+}
+
+// writeFiles writes the files of a file mount of the container stored under
+// key into its directory and returns the directory. Each file is replaced atomically and files for
+// keys that are gone are removed, so a running process never reads a
+// partly written file.
+func (a *Agent) writeFiles(key string, i int, fm *model.FileMount, src *sources) (string, error) {
+	files, perm, err := src.files(fm)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(a.filesDirOf(key), strconv.Itoa(i))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("creating projected files: %w", err)
+	}
+	for k, data := range files {
+		tmp := filepath.Join(dir, "."+k+".tmp")
+		if err := os.WriteFile(tmp, data, perm); err != nil {
+			return "", fmt.Errorf("writing projected files: %w", err)
+		}
+		if err := os.Rename(tmp, filepath.Join(dir, k)); err != nil {
+			return "", fmt.Errorf("writing projected files: %w", err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("reading projected files: %w", err)
+	}
+	for _, e := range entries {
+		if _, ok := files[e.Name()]; !ok {
+			if err := os.Remove(filepath.Join(dir, e.Name())); err != nil {
+				return "", fmt.Errorf("removing projected files: %w", err)
+			}
+		}
+	}
+	return dir, nil
+}
+
+// filesDirOf returns the directory holding the projected files of the
+// container stored under key.
+func (a *Agent) filesDirOf(key string) string {
+	return filepath.Join(a.filesDir, filepath.FromSlash(key))
+}
+
+// removeFiles deletes the files projected for a container.
+func (a *Agent) removeFiles(key string) error {
+	if a.filesDir == "" {
+		return nil
+	}
+	return os.RemoveAll(a.filesDirOf(key))
+}
+
+// env returns the value of an environment variable reference.
+func (src *sources) env(e *model.EnvVarSource) (string, error) {
+	if ref := e.SecretKeyRef; ref != nil {
+		v, ok := src.secrets[ref.Name].Data[ref.Key]
+		if !ok {
+			return "", fmt.Errorf("secret %s has no key %s", ref.Name, ref.Key)
+		}
+		return v, nil
+	}
+	ref := e.ConfigMapKeyRef
+	v, ok := src.configMaps[ref.Name].Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("config map %s has no text key %s", ref.Name, ref.Key)
+	}
+	return v, nil
+}
+
+// files returns the contents of a file mount by key, and the permissions
+// to write them with.
+func (src *sources) files(fm *model.FileMount) (map[string][]byte, os.FileMode, error) {
+	var (
+		keys []string
+		get  func(string) ([]byte, bool)
+		kind string
+		perm os.FileMode
+	)
+	if fm.Secret != "" {
+		s := src.secrets[fm.Secret]
+		keys, kind, perm = s.Keys(), "secret "+s.Name, 0o600
+		get = func(k string) ([]byte, bool) {
+			v, ok := s.Data[k]
+			return []byte(v), ok
+		}
+	} else {
+		m := src.configMaps[fm.ConfigMap]
+		keys, get, kind, perm = m.Keys(), m.File, "config map "+m.Name, 0o644
+	}
+	if len(fm.Keys) > 0 {
+		keys = fm.Keys
+	}
+
+	files := make(map[string][]byte, len(keys))
+	for _, k := range keys {
+		data, ok := get(k)
+		if !ok {
+			return nil, 0, fmt.Errorf("%s has no key %s", kind, k)
+		}
+		files[k] = data
+	}
+	return files, perm, nil
+}
+
+func (src *sources) secretVersions() map[string]int64 {
+	if len(src.secrets) == 0 {
+		return nil
+	}
+	versions := make(map[string]int64, len(src.secrets))
+	for name, s := range src.secrets {
+		versions[name] = s.Version
+	}
+	return versions
+}
+
+func (src *sources) configMapVersions() map[string]int64 {
+	if len(src.configMaps) == 0 {
+		return nil
+	}
+	versions := make(map[string]int64, len(src.configMaps))
+	for name, m := range src.configMaps {
+		versions[name] = m.Version
+	}
+	return versions
+}
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/runtime"
+)
+
+// usageInterval is how often the usage of a running container is sampled.
+const usageInterval = 15 * time.Second
+
+// recordUsage samples the usage of c's running runtime container once it
+// is due and records it in c's status, for the autoscalers. CPU use is
+// averaged since the previous sample, or since the container started for
+// the first one. Runtimes that cannot report usage are skipped, and a
+// failed sample only leaves the previous one in place.
+func (a *Agent) recordUsage(ctx context.Context, c *model.Container, info *runtime.ContainerInfo) error {
+	sr, ok := a.runtime.(runtime.StatsRuntime)
+	if !ok {
+		return nil
+	}
+	if u := c.Status.Usage; u != nil && a.now().Sub(u.SampledAt) < usageInterval {
+		return nil
+	}
+	st, err := sr.Stats(ctx, info.ID)
+	if errors.Is(err, runtime.ErrNotRunning) || errors.Is(err, runtime.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		a.logger.Warn().Err(err).Str("container", c.Key()).Msg("failed to sample resource usage")
+		return nil
+	}
+
+	a.mu.Lock()
+	prev := a.samples[info.ID]
+	a.samples[info.ID] = st
+	a.mu.Unlock()
+	if prev == nil {
+		prev = &runtime.Stats{Time: info.StartedAt}
+	}
+
+	usage := &model.ResourceUsage{
+		MemoryBytes: st.MemoryBytes,
+		Metrics:     st.Metrics,
+		SampledAt:   st.Time.UTC(),
+	}
+	if elapsed := st.Time.Sub(prev.Time); elapsed > 0 && st.CPUTime >= prev.CPUTime {
+		usage.CPUMillis = int64(st.CPUTime-prev.CPUTime) * 1000 / int64(elapsed)
+	}
+	_, err = a.updateStatus(c, func(s *model.ContainerStatus) {
+		s.Usage = usage
+	})
+	return err
+}
+
+// forgetUsage drops the last sample of the runtime container id.
+func (a *Agent) forgetUsage(id string) {
+	a.mu.Lock()
+	delete(a.samples, id)
+	a.mu.Unlock()
+}
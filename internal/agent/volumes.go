@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/runtime"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// volumeUsageInterval is how often the usage of a volume is measured.
+const volumeUsageInterval = time.Minute
+
+// mounts resolves the volume mounts of c to directories on this node. A
+// volume not yet bound to a node is bound here on first use; one bound to
+// another node cannot be mounted.
+func (a *Agent) mounts(c *model.Container) ([]runtime.Mount, error) {
+	if len(c.Spec.VolumeMounts) == 0 {
+		return nil, nil
+	}
+	if a.volumes == nil {
+		return nil, errors.New("volumes are not supported on this node")
+	}
+
+	mounts := make([]runtime.Mount, 0, len(c.Spec.VolumeMounts))
+	for _, vm := range c.Spec.VolumeMounts {
+		v, err := store.UpdateJSON(a.store, model.VolumesBucket, model.Key(c.Namespace, vm.Name), func(v *model.Volume) error {
+			if v.Status.NodeName == "" && (v.Spec.NodeName == "" || v.Spec.NodeName == a.nodeName) {
+				now := a.now().UTC()
+				v.Status.NodeName = a.nodeName
+				v.Status.BoundAt = &now
+			}
+			return nil
+		})
+		if store.IsNotFound(err) {
+			return nil, fmt.Errorf("volume %s not found", vm.Name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("binding volume %s: %w", vm.Name, err)
+		}
+		if node := v.BoundNode(); node != a.nodeName {
+			return nil, fmt.Errorf("volume %s is bound to node %s", vm.Name, node)
+		}
+
+		d, err := a.volumes.Get(v.Spec.Driver)
+		if err != nil {
+			return nil, err
+		}
+		dir, err := d.Mount(v)
+		if err != nil {
+			return nil, err
+		}
+		mounts = append(mounts, runtime.Mount{Source: dir, Target: vm.MountPath, ReadOnly: vm.ReadOnly})
+	}
+	return mounts, nil
+}
+
+// syncVolumes refreshes the usage of the volumes bound to this node and
+// lets each driver remove the data of deleted volumes.
+func (a *Agent) syncVolumes() error {
+	if a.volumes == nil {
+		return nil
+	}
+	volumes, err := store.ListJSON[model.Volume](a.store, model.VolumesBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing volumes: %w", err)
+	}
+
+	// Drivers see volume keys; names repeat across namespaces.
+	keep := make(map[string]map[string]bool)
+	var errs []error
+	for _, v := range volumes {
+		if v.BoundNode() != a.nodeName {
+			continue
+		}
+		if keep[v.Spec.Driver] == nil {
+			keep[v.Spec.Driver] = make(map[string]bool)
+		}
+		keep[v.Spec.Driver][v.Key()] = true
+		if err := a.measure(v); err != nil {
+			errs = append(errs, fmt.Errorf("volume %s: %w", v.Key(), err))
+		}
+	}
+
+	for _, d := range a.volumes.Drivers() {
+		if err := d.Prune(keep[d.Name()]); err != nil {
+			errs = append(errs, fmt.Errorf("pruning %s volumes: %w", d.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// measure records the usage of a volume once it is due.
+func (a *Agent) measure(v *model.Volume) error {
+	now := a.now().UTC()
+	if u := v.Status.Usage; u != nil && now.Sub(u.UpdatedAt) < volumeUsageInterval {
+		return nil
+	}
+	if v.Status.NodeName == "" {
+		// Pinned volumes have no data until they are first mounted.
+		return nil
+	}
+	d, err := a.volumes.Get(v.Spec.Driver)
+	if err != nil {
+		return err
+	}
+	u, err := d.Usage(v)
+	if err != nil {
+		return err
+	}
+	u.UpdatedAt = now
+
+	_, err = store.UpdateJSON(a.store, model.VolumesBucket, v.Key(), func(cur *model.Volume) error {
+		if cur.UID != v.UID {
+			return errStale
+		}
+		cur.Status.Usage = u
+		return nil
+	})
+	if errors.Is(err, errStale) || store.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
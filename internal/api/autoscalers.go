@@ -0,0 +1,27 @@
+package api
+
+import (
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+// newAutoscalerHandler serves the /autoscalers endpoints.
+func newAutoscalerHandler(cfg *RouterConfig) *resourceHandler[model.Autoscaler, *model.Autoscaler] {
+	return &resourceHandler[model.Autoscaler, *model.Autoscaler]{
+		store:      cfg.Store,
+		logger:     cfg.Logger,
+		namespaces: cfg.Namespaces,
+		bucket:     model.AutoscalersBucket,
+		kind:       "autoscaler",
+		prepare: func(a *model.Autoscaler) {
+			// Status is owned by the autoscaler controller.
+			a.Status = model.AutoscalerStatus{}
+		},
+		// Status is kept, so the recommendations and decisions already made
+		// go on stabilizing and limiting the changes under the new spec.
+		apply: func(cur, in *model.Autoscaler) {
+			cur.Labels = in.Labels
+			cur.Annotations = in.Annotations
+			cur.Spec = in.Spec
+		},
+	}
+}
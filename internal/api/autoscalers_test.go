@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+func TestAutoscalers_CreateAndUpdate(t *testing.T) {
+	router := newTestRouter()
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/autoscalers", `{
+		"metadata": {"name": "web"},
+		"spec": {
+			"deployment": "web",
+			"max_replicas": 10,
+			"metrics": [{"type": "CPU", "target_utilization": 60}]
+		},
+		"status": {"desired_replicas": 7}
+	}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var as model.Autoscaler
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&as))
+	assert.Equal(t, 1, as.Spec.MinReplicas)
+	assert.Equal(t, 300, *as.Spec.ScaleDown.StabilizationWindowSeconds)
+	assert.Equal(t, 4, *as.Spec.ScaleUp.MinChange)
+	assert.Zero(t, as.Status.DesiredReplicas, "status is reset on create")
+
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/default/autoscalers/web", `{
+		"spec": {
+			"deployment": "web",
+			"min_replicas": 2,
+			"max_replicas": 10,
+			"metrics": [{"type": "Custom", "name": "queue_depth", "target_average_value": 30}]
+		}
+	}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&as))
+	assert.Equal(t, 2, as.Spec.MinReplicas)
+	assert.Equal(t, "queue_depth", as.Spec.Metrics[0].Name)
+
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/default/autoscalers/web", `{
+		"spec": {"deployment": "web", "min_replicas": 5, "max_replicas": 3, "metrics": [{"type": "CPU", "target_utilization": 60}]}
+	}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, CodeValidationFailed, decodeError(t, rec).Code)
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+// newConfigMapHandler creates the /configmaps handler.
+func newConfigMapHandler(cfg *RouterConfig) *resourceHandler[model.ConfigMap, *model.ConfigMap] {
+	return &resourceHandler[model.ConfigMap, *model.ConfigMap]{
+		store:      cfg.Store,
+		logger:     cfg.Logger,
+		namespaces: cfg.Namespaces,
+		bucket:     model.ConfigMapsBucket,
+		kind:       "config map",
+		prepare: func(m *model.ConfigMap) {
+			m.Version = 1
+		},
+		// The version only moves when the data changed, so relabeling does
+		// not restart containers.
+		apply: func(cur, in *model.ConfigMap) {
+			if !cur.SameData(in) {
+				cur.Version++
+			}
+			cur.Labels = in.Labels
+			cur.Annotations = in.Annotations
+			cur.Data = in.Data
+			cur.BinaryData = in.BinaryData
+		},
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+func TestConfigMaps_CreateAndUpdate(t *testing.T) {
+	router := newTestRouter()
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/configmaps", `{
+		"metadata": {"name": "app"},
+		"data": {"LOG_LEVEL": "debug", "app.conf": "port = 80\n"},
+		"binary_data": {"logo.png": "iVBORw=="},
+		"version": 9
+	}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var m model.ConfigMap
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&m))
+	assert.Equal(t, int64(1), m.Version)
+	assert.Equal(t, "debug", m.Data["LOG_LEVEL"])
+	assert.Equal(t, []byte{0x89, 0x50, 0x4e, 0x47}, m.BinaryData["logo.png"])
+
+	// Relabeling keeps the version.
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/default/configmaps/app", `{
+		"metadata": {"labels": {"team": "web"}},
+		"data": {"LOG_LEVEL": "debug", "app.conf": "port = 80\n"},
+		"binary_data": {"logo.png": "iVBORw=="}
+	}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&m))
+	assert.Equal(t, int64(1), m.Version)
+	assert.Equal(t, "web", m.Labels["team"])
+
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/default/configmaps/app", `{
+		"data": {"LOG_LEVEL": "info"}
+	}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var updated model.ConfigMap
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&updated))
+	assert.Equal(t, int64(2), updated.Version)
+	assert.Empty(t, updated.BinaryData)
+
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/namespaces/default/configmaps/app", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"LOG_LEVEL":"info"`)
+}
+
+func TestConfigMaps_SizeLimits(t *testing.T) {
+	router := newTestRouter()
+
+	big := strings.Repeat("x", model.MaxConfigMapSize)
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/configmaps",
+		`{"metadata": {"name": "app"}, "data": {"blob": "`+big+`"}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, CodeValidationFailed, decodeError(t, rec).Code)
+
+	huge := strings.Repeat("x", maxRequestBodySize)
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/configmaps",
+		`{"metadata": {"name": "app"}, "data": {"blob": "`+huge+`"}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	resp := decodeError(t, rec)
+	assert.Equal(t, CodeBadRequest, resp.Code)
+	assert.Contains(t, resp.Error, "request body exceeds")
+
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/configmaps",
+		`{"metadata": {"name": "app"}, "data": {"a": "x"}, "binary_data": {"a": "eA=="}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, CodeValidationFailed, decodeError(t, rec).Code)
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/github-builder/container-orchestrator/internal/eviction"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// containerHandler serves the /containers endpoints.
+type containerHandler struct {
+	*resourceHandler[model.Container, *model.Container]
+	evictor *eviction.Evictor
+}
+
+func newContainerHandler(cfg *RouterConfig) *containerHandler {
+	return &containerHandler{
+		resourceHandler: &resourceHandler[model.Container, *model.Container]{
+			store:      cfg.Store,
+			logger:     cfg.Logger,
+			namespaces: cfg.Namespaces,
+			bucket:     model.ContainersBucket,
+			kind:       "container",
+			prepare: func(c *model.Container) {
+				// Status is owned by the orchestrator.
+				c.Status = model.ContainerStatus{}
+			},
+		},
+		evictor: cfg.Evictor,
+	}
+}
+
+func (h *containerHandler) routes(r chi.Router) {
+	h.resourceHandler.routes(r)
+	r.Post("/{name}/eviction", h.evict)
+}
+
+// evict moves a container off its node to be scheduled again. It is
+// refused with 429 and DISRUPTION_BUDGET_VIOLATED when a disruption budget
+// does not allow it; the client retries later.
+func (h *containerHandler) evict(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	c, err := store.GetJSON[model.Container](h.store, h.bucket, key(r))
+	if store.IsNotFound(err) {
+		h.notFound(w, name)
+		return
+	}
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+	if c.Spec.NodeName == "" || c.Status.IsTerminal() {
+		Error(w, http.StatusConflict, fmt.Sprintf("container %s is not running on a node", name), CodeConflict)
+		return
+	}
+
+	err = h.evictor.Evict(c, "evicted through the API")
+	var berr *eviction.BudgetError
+	switch {
+	case errors.As(err, &berr):
+		Error(w, http.StatusTooManyRequests, err.Error(), CodeDisruptionBudget)
+		return
+	case errors.Is(err, eviction.ErrStale):
+		Error(w, http.StatusConflict, fmt.Sprintf("container %s changed, retry the eviction", name), CodeConflict)
+		return
+	case err != nil:
+		h.internalError(w, err)
+		return
+	}
+
+	h.get(w, r)
+}
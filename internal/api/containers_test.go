@@ -0,0 +1,188 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+// doRequest sends an authenticated request to the test router.
+func doRequest(t *testing.T, router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("X-API-Key", "test-api-key")
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) ErrorResponse {
+	t.Helper()
+	var resp ErrorResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	return resp
+}
+
+func TestContainers_CreateAndGet(t *testing.T) {
+	router := newTestRouter()
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/containers", `{
+		"metadata": {"name": "web", "labels": {"app": "web"}},
+		"spec": {
+			"image": "nginx",
+			"ports": [{"container_port": 80}],
+			"liveness_probe": {"http_get": {"path": "/healthz", "port": 80}, "period_seconds": 5}
+		},
+		"status": {"phase": "Running"}
+	}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var created model.Container
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+	assert.NotEmpty(t, created.UID)
+	assert.False(t, created.CreatedAt.IsZero())
+	assert.Equal(t, model.RestartAlways, created.Spec.RestartPolicy)
+	assert.Equal(t, model.PhasePending, created.Status.Phase, "client-supplied status is ignored")
+
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/namespaces/default/containers/web", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got model.Container
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+	assert.Equal(t, created.UID, got.UID)
+	assert.Equal(t, 5, got.Spec.LivenessProbe.PeriodSeconds)
+}
+
+func TestContainers_CreateConflict(t *testing.T) {
+	router := newTestRouter()
+	body := `{"metadata": {"name": "web"}, "spec": {"image": "nginx"}}`
+
+	require.Equal(t, http.StatusCreated, doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/containers", body).Code)
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/containers", body)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, CodeAlreadyExists, decodeError(t, rec).Code)
+}
+
+func TestContainers_CreateInvalid(t *testing.T) {
+	router := newTestRouter()
+
+	tests := []struct {
+		name string
+		body string
+		code string
+	}{
+		{"malformed", `{`, CodeBadRequest},
+		{"unknown field", `{"metadata": {"name": "web"}, "spec": {"image": "nginx", "bogus": 1}}`, CodeBadRequest},
+		{"bad name", `{"metadata": {"name": "Web"}, "spec": {"image": "nginx"}}`, CodeValidationFailed},
+		{"bad probe", `{"metadata": {"name": "web"}, "spec": {"image": "nginx", "readiness_probe": {}}}`, CodeValidationFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/containers", tt.body)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Equal(t, tt.code, decodeError(t, rec).Code)
+		})
+	}
+}
+
+func TestContainers_ListPaginated(t *testing.T) {
+	router := newTestRouter()
+	for i := range 3 {
+		body := fmt.Sprintf(`{"metadata": {"name": "c%d"}, "spec": {"image": "nginx"}}`, i)
+		require.Equal(t, http.StatusCreated, doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/containers", body).Code)
+	}
+
+	rec := doRequest(t, router, http.MethodGet, "/api/v1/namespaces/default/containers?page=2&per_page=2", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Items   []model.Container `json:"items"`
+		Total   int               `json:"total"`
+		Page    int               `json:"page"`
+		PerPage int               `json:"per_page"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, 3, resp.Total)
+	assert.Equal(t, 2, resp.Page)
+	assert.Equal(t, 2, resp.PerPage)
+	require.Len(t, resp.Items, 1)
+	assert.Equal(t, "c2", resp.Items[0].Name)
+
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/namespaces/default/containers?page=0", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestContainers_ListEmpty(t *testing.T) {
+	router := newTestRouter()
+
+	rec := doRequest(t, router, http.MethodGet, "/api/v1/namespaces/default/containers", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"items":[]`)
+}
+
+func TestContainers_Delete(t *testing.T) {
+	router := newTestRouter()
+	body := `{"metadata": {"name": "web"}, "spec": {"image": "nginx"}}`
+	require.Equal(t, http.StatusCreated, doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/containers", body).Code)
+
+	rec := doRequest(t, router, http.MethodDelete, "/api/v1/namespaces/default/containers/web", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/namespaces/default/containers/web", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, CodeNotFound, decodeError(t, rec).Code)
+
+	rec = doRequest(t, router, http.MethodDelete, "/api/v1/namespaces/default/containers/web", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestContainers_DeleteWithFinalizers(t *testing.T) {
+	router := newTestRouter()
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/containers", `{
+		"metadata": {"name": "web", "finalizers": ["dns.example.com/record"]},
+		"spec": {"image": "nginx:latest"}
+	}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	rec = doRequest(t, router, http.MethodDelete, "/api/v1/namespaces/default/containers/web", "")
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	var c model.Container
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&c))
+	require.NotNil(t, c.DeletionTimestamp)
+	assert.Equal(t, []string{"dns.example.com/record"}, c.Finalizers)
+
+	// A terminating container may lose finalizers but not gain them.
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/default/containers/web/finalizers",
+		`{"finalizers": ["dns.example.com/record", "more"]}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/default/containers/web/finalizers",
+		`{"finalizers": ["Bad"]}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, CodeValidationFailed, decodeError(t, rec).Code)
+
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/namespaces/default/containers/web", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/default/containers/web/finalizers",
+		`{"finalizers": []}`)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/namespaces/default/containers/web", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/default/containers/missing/finalizers",
+		`{"finalizers": []}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
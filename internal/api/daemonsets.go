@@ -0,0 +1,26 @@
+package api
+
+import (
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+// newDaemonSetHandler serves the /daemonsets endpoints.
+func newDaemonSetHandler(cfg *RouterConfig) *resourceHandler[model.DaemonSet, *model.DaemonSet] {
+	return &resourceHandler[model.DaemonSet, *model.DaemonSet]{
+		store:      cfg.Store,
+		logger:     cfg.Logger,
+		namespaces: cfg.Namespaces,
+		bucket:     model.DaemonSetsBucket,
+		kind:       "daemon set",
+		prepare: func(d *model.DaemonSet) {
+			// Status is owned by the daemon set controller.
+			d.Status = model.DaemonSetStatus{}
+		},
+		// A changed template is rolled out according to the update strategy.
+		apply: func(cur, in *model.DaemonSet) {
+			cur.Labels = in.Labels
+			cur.Annotations = in.Annotations
+			cur.Spec = in.Spec
+		},
+	}
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+func TestDaemonSets_CreateAndUpdate(t *testing.T) {
+	router := newTestRouter()
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/daemonsets", `{
+		"metadata": {"name": "logs"},
+		"spec": {"template": {"spec": {"image": "shipper:v1", "node_selector": {"role": "worker"}}}}
+	}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var ds model.DaemonSet
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&ds))
+	assert.Equal(t, model.DaemonSetRollingUpdate, ds.Spec.UpdateStrategy.Type)
+	assert.Equal(t, model.FromInt(1), ds.Spec.UpdateStrategy.MaxUnavailable)
+
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/default/daemonsets/logs", `{
+		"spec": {"template": {"spec": {"image": "shipper:v2"}}}
+	}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&ds))
+	assert.Equal(t, "shipper:v2", ds.Spec.Template.Spec.Image)
+
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/default/daemonsets/logs", `{
+		"spec": {"template": {"spec": {"image": "shipper:v2", "restart_policy": "Never"}}}
+	}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, CodeValidationFailed, decodeError(t, rec).Code)
+
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/default/daemonsets/metrics", `{
+		"spec": {"template": {"spec": {"image": "x"}}}
+	}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,258 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/github-builder/container-orchestrator/internal/deployment"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// deploymentHandler serves the /deployments endpoints.
+type deploymentHandler struct {
+	*resourceHandler[model.Deployment, *model.Deployment]
+}
+
+func newDeploymentHandler(cfg *RouterConfig) *deploymentHandler {
+	return &deploymentHandler{
+		resourceHandler: &resourceHandler[model.Deployment, *model.Deployment]{
+			store:      cfg.Store,
+			logger:     cfg.Logger,
+			namespaces: cfg.Namespaces,
+			bucket:     model.DeploymentsBucket,
+			kind:       "deployment",
+			prepare: func(d *model.Deployment) {
+				// Status is owned by the deployment controller.
+				d.Status = model.DeploymentStatus{}
+			},
+			// A changed template starts a new rollout.
+			apply: func(cur, in *model.Deployment) {
+				cur.Labels = in.Labels
+				cur.Annotations = in.Annotations
+				cur.Spec = in.Spec
+			},
+		},
+	}
+}
+
+func (h *deploymentHandler) routes(r chi.Router) {
+	h.resourceHandler.routes(r)
+	r.Get("/{name}/rollout", h.rollout)
+	r.Post("/{name}/rollout/pause", h.setPaused(true))
+	r.Post("/{name}/rollout/resume", h.setPaused(false))
+	r.Post("/{name}/rollout/promote", h.promote)
+	r.Post("/{name}/rollout/abort", h.abort)
+	r.Get("/{name}/revisions", h.revisions)
+	r.Post("/{name}/rollback", h.rollback)
+}
+
+// rolloutStatus reports the progress of a deployment's current revision.
+type rolloutStatus struct {
+	Revision            int64                        `json:"revision"`
+	Strategy            model.DeploymentStrategyType `json:"strategy"`
+	Phase               model.RolloutPhase           `json:"phase"`
+	Reason              string                       `json:"reason,omitempty"`
+	Message             string                       `json:"message,omitempty"`
+	Paused              bool                         `json:"paused"`
+	Done                bool                         `json:"done"`
+	DesiredReplicas     int                          `json:"desired_replicas"`
+	Replicas            int                          `json:"replicas"`
+	UpdatedReplicas     int                          `json:"updated_replicas"`
+	ReadyReplicas       int                          `json:"ready_replicas"`
+	UnavailableReplicas int                          `json:"unavailable_replicas"`
+
+	// CanaryStep and CanarySteps report the position of a canary rollout.
+	CanaryStep  int `json:"canary_step,omitempty"`
+	CanarySteps int `json:"canary_steps,omitempty"`
+
+	TemplateHash       string `json:"template_hash"`
+	StableTemplateHash string `json:"stable_template_hash,omitempty"`
+	ActiveTemplateHash string `json:"active_template_hash,omitempty"`
+
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// rollout reports rollout progress. Done is true once the current template
+// is fully rolled out, so clients can poll until done or phase is Failed.
+func (h *deploymentHandler) rollout(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	d, err := store.GetJSON[model.Deployment](h.store, h.bucket, key(r))
+	if store.IsNotFound(err) {
+		h.notFound(w, name)
+		return
+	}
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	s := d.Status
+	// A template changed since the controller last ran has not started
+	// rolling out yet.
+	observed := s.TemplateHash == d.Spec.Template.Hash()
+	phase := s.Rollout.Phase
+	if !observed {
+		phase = model.RolloutProgressing
+	}
+
+	out := &rolloutStatus{
+		Revision:            s.Revision,
+		Strategy:            d.Spec.Strategy.Type,
+		Phase:               phase,
+		Reason:              s.Rollout.Reason,
+		Message:             s.Rollout.Message,
+		Paused:              d.Spec.Paused,
+		Done:                observed && phase == model.RolloutComplete,
+		DesiredReplicas:     d.Spec.Replicas,
+		Replicas:            s.Replicas,
+		UpdatedReplicas:     s.UpdatedReplicas,
+		ReadyReplicas:       s.ReadyReplicas,
+		UnavailableReplicas: s.UnavailableReplicas,
+		TemplateHash:        s.TemplateHash,
+		StableTemplateHash:  s.StableTemplateHash,
+		ActiveTemplateHash:  s.ActiveTemplateHash,
+		StartedAt:           s.Rollout.StartedAt,
+		CompletedAt:         s.Rollout.CompletedAt,
+	}
+	if s.Canary != nil && d.Spec.Strategy.Canary != nil {
+		out.CanaryStep = s.Canary.Step + 1
+		out.CanarySteps = len(d.Spec.Strategy.Canary.Steps)
+	}
+	JSON(w, http.StatusOK, out)
+}
+
+// errNoRollout is returned by promote and abort when the deployment has
+// no canary or blue-green rollout in progress.
+var errNoRollout = errors.New("no canary or blue-green rollout in progress")
+
+// inProgress reports whether d has a canary or blue-green rollout that can
+// still be promoted or aborted.
+func inProgress(d *model.Deployment) error {
+	s := &d.Status
+	switch {
+	case d.Spec.Strategy.Type != model.StrategyCanary && d.Spec.Strategy.Type != model.StrategyBlueGreen,
+		s.TemplateHash != d.Spec.Template.Hash(),
+		s.Canary == nil && s.BlueGreen == nil,
+		s.Rollout.Phase == model.RolloutComplete,
+		s.Abort:
+		return errNoRollout
+	case s.BlueGreen != nil && s.BlueGreen.SwitchedAt != nil:
+		return errors.New("traffic has already been switched; roll back instead")
+	}
+	return nil
+}
+
+// promote advances a canary rollout to its next step, or switches traffic
+// to a blue-green preview set once it is ready.
+func (h *deploymentHandler) promote(w http.ResponseWriter, r *http.Request) {
+	h.request(w, r, func(d *model.Deployment) { d.Status.Promote = true })
+}
+
+// abort stops a canary or blue-green rollout and restores the stable
+// template's replicas. Roll back to make the stable template current again.
+func (h *deploymentHandler) abort(w http.ResponseWriter, r *http.Request) {
+	h.request(w, r, func(d *model.Deployment) { d.Status.Abort = true })
+}
+
+// request records a promote or abort request for the deployment controller.
+func (h *deploymentHandler) request(w http.ResponseWriter, r *http.Request, set func(d *model.Deployment)) {
+	name := chi.URLParam(r, "name")
+
+	var rejected error
+	updated, err := store.UpdateJSON(h.store, h.bucket, key(r), func(d *model.Deployment) error {
+		if rejected = inProgress(d); rejected != nil {
+			return rejected
+		}
+		set(d)
+		return nil
+	})
+	if rejected != nil {
+		Error(w, http.StatusBadRequest, "deployment "+name+": "+rejected.Error(), CodeBadRequest)
+		return
+	}
+	h.respond(w, name, updated, err)
+}
+
+// revisions lists the retained revisions of a deployment, oldest first.
+func (h *deploymentHandler) revisions(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	page, perPage, err := pagination(r)
+	if err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeBadRequest)
+		return
+	}
+	q, err := parseListQuery(r)
+	if err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeBadRequest)
+		return
+	}
+
+	d, err := store.GetJSON[model.Deployment](h.store, h.bucket, key(r))
+	if store.IsNotFound(err) {
+		h.notFound(w, name)
+		return
+	}
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	items, err := deployment.Revisions(h.store, d)
+	if err == nil {
+		items, err = selectItems(items, q)
+	}
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	Paginated(w, paginate(items, page, perPage), len(items), page, perPage)
+}
+
+// rollback restores the template of revision ?to=N, or of the previous
+// revision when to is omitted. The restored template is rolled out as a
+// new revision.
+func (h *deploymentHandler) rollback(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	var to int64
+	if v := r.URL.Query().Get("to"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			Error(w, http.StatusBadRequest, "to must be a positive revision number", CodeBadRequest)
+			return
+		}
+		to = n
+	}
+
+	d, err := deployment.Rollback(h.store, h.namespaces, key(r), to)
+	switch {
+	case errors.Is(err, deployment.ErrRevisionNotFound):
+		Error(w, http.StatusNotFound, err.Error(), CodeNotFound)
+	case errors.Is(err, deployment.ErrNoPreviousRevision):
+		Error(w, http.StatusBadRequest, "deployment "+name+" has no previous revision", CodeBadRequest)
+	default:
+		h.respond(w, name, d, err)
+	}
+}
+
+// setPaused returns a handler that pauses or resumes a rollout.
+func (h *deploymentHandler) setPaused(paused bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+
+		updated, err := store.UpdateJSON(h.store, h.bucket, key(r), func(d *model.Deployment) error {
+			d.Spec.Paused = paused
+			return nil
+		})
+		h.respond(w, name, updated, err)
+	}
+}
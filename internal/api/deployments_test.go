@@ -0,0 +1,251 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/deployment"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+const deploymentBody = `{
+	"metadata": {"name": "web"},
+	"spec": {
+		"replicas": 3,
+		"template": {"labels": {"app": "web"}, "spec": {"image": "nginx:1"}},
+		"strategy": {"rolling_update": {"max_surge": 1, "max_unavailable": "0%"}}
+	}
+}`
+
+func TestDeployments_CreateAndUpdate(t *testing.T) {
+	router := newTestRouter()
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/deployments", deploymentBody)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var d model.Deployment
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&d))
+	assert.Equal(t, model.StrategyRollingUpdate, d.Spec.Strategy.Type)
+	assert.Equal(t, model.FromInt(1), d.Spec.Strategy.RollingUpdate.MaxSurge)
+	assert.Equal(t, model.DefaultProgressDeadlineSeconds, d.Spec.ProgressDeadlineSeconds)
+
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/default/deployments/web", `{
+		"spec": {"replicas": 5, "template": {"spec": {"image": "nginx:2"}}}
+	}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&d))
+	assert.Equal(t, 5, d.Spec.Replicas)
+	assert.Equal(t, "nginx:2", d.Spec.Template.Spec.Image)
+
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/default/deployments/web", `{"spec": {"replicas": -1}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, CodeValidationFailed, decodeError(t, rec).Code)
+
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/default/deployments/api",
+		`{"spec": {"replicas": 1, "template": {"spec": {"image": "x"}}}}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestDeployments_Rollout(t *testing.T) {
+	router := newTestRouter()
+	require.Equal(t, http.StatusCreated, doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/deployments", deploymentBody).Code)
+
+	rec := doRequest(t, router, http.MethodGet, "/api/v1/namespaces/default/deployments/web/rollout", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var status rolloutStatus
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&status))
+	// Not yet observed by the controller.
+	assert.Equal(t, model.RolloutProgressing, status.Phase)
+	assert.False(t, status.Done)
+	assert.Equal(t, 3, status.DesiredReplicas)
+
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/deployments/web/rollout/pause", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/namespaces/default/deployments/web/rollout", "")
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&status))
+	assert.True(t, status.Paused)
+
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/deployments/web/rollout/resume", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var d model.Deployment
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&d))
+	assert.False(t, d.Spec.Paused)
+
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/namespaces/default/deployments/api/rollout", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestDeployments_RevisionsAndRollback(t *testing.T) {
+	router, s := newTestRouterWithStore()
+	controller := deployment.NewController(&deployment.Config{Store: s, Logger: zerolog.Nop(), Interval: time.Hour})
+	reconcile := func() { require.NoError(t, controller.Sync(context.Background())) }
+
+	require.Equal(t, http.StatusCreated, doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/deployments", deploymentBody).Code)
+	reconcile()
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/deployments/web/rollback", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	for _, image := range []string{"nginx:2", "nginx:3"} {
+		rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/default/deployments/web",
+			`{"spec": {"replicas": 3, "template": {"spec": {"image": "`+image+`"}}}}`)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		reconcile()
+	}
+
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/namespaces/default/deployments/web/revisions", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var page struct {
+		Items []model.Revision `json:"items"`
+		Total int              `json:"total"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&page))
+	require.Equal(t, 3, page.Total)
+	assert.Equal(t, int64(1), page.Items[0].Revision)
+	assert.Equal(t, "nginx:1", page.Items[0].Template.Spec.Image)
+
+	// Without ?to the previous revision is restored.
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/deployments/web/rollback", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var d model.Deployment
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&d))
+	assert.Equal(t, "nginx:2", d.Spec.Template.Spec.Image)
+	reconcile()
+
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/deployments/web/rollback?to=1", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&d))
+	assert.Equal(t, "nginx:1", d.Spec.Template.Spec.Image)
+	assert.Equal(t, map[string]string{"app": "web"}, d.Spec.Template.Labels)
+	reconcile()
+
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/namespaces/default/deployments/web/rollout", "")
+	var status rolloutStatus
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&status))
+	assert.Equal(t, int64(5), status.Revision)
+
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/deployments/web/rollback?to=42", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/deployments/web/rollback?to=abc", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/namespaces/default/deployments/api/revisions", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestDeployments_RollbackAdmittedByQuota(t *testing.T) {
+	router, s := newTestRouterWithStore()
+	controller := deployment.NewController(&deployment.Config{Store: s, Logger: zerolog.Nop(), Interval: time.Hour})
+	reconcile := func() { require.NoError(t, controller.Sync(context.Background())) }
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces", `{
+		"metadata": {"name": "team-a"},
+		"spec": {"quota": {"requests": {"cpu_millis": 1000}}}
+	}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	template := func(cpu int) string {
+		return fmt.Sprintf(`{"spec": {"replicas": 2, "template": {"labels": {"app": "web"},
+			"spec": {"image": "nginx", "resources": {"cpu_millis": %d}}}}}`, cpu)
+	}
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/team-a/deployments",
+		`{"metadata": {"name": "web"}, `+template(400)[1:])
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	reconcile()
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/team-a/deployments/web", template(100))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	reconcile()
+
+	// 200m of the quota is left for another workload.
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/team-a/containers",
+		`{"metadata": {"name": "batch"}, "spec": {"image": "batch", "resources": {"cpu_millis": 600}}}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	// Restoring 2 x 400m would exceed it.
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/team-a/deployments/web/rollback", "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, CodeQuotaExceeded, decodeError(t, rec).Code)
+
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/namespaces/team-a/deployments/web", "")
+	var d model.Deployment
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&d))
+	assert.Equal(t, int64(100), d.Spec.Template.Spec.Resources.CPUMillis)
+
+	// Once there is room, the rollback goes through.
+	rec = doRequest(t, router, http.MethodDelete, "/api/v1/namespaces/team-a/containers/batch", "")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/team-a/deployments/web/rollback", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&d))
+	assert.Equal(t, int64(400), d.Spec.Template.Spec.Resources.CPUMillis)
+}
+
+func TestDeployments_PromoteAndAbort(t *testing.T) {
+	router, s := newTestRouterWithStore()
+	controller := deployment.NewController(&deployment.Config{Store: s, Logger: zerolog.Nop(), Interval: time.Hour})
+	reconcile := func() {
+		require.NoError(t, controller.Sync(context.Background()))
+		// Stand in for the agent: every replica comes up ready.
+		containers, err := store.ListJSON[model.Container](s, model.ContainersBucket, "")
+		require.NoError(t, err)
+		for _, c := range containers {
+			c.Status.Phase = model.PhaseRunning
+			c.Status.Ready = true
+			require.NoError(t, store.PutJSON(s, model.ContainersBucket, c.Name, c))
+		}
+		require.NoError(t, controller.Sync(context.Background()))
+	}
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/deployments", `{
+		"metadata": {"name": "web"},
+		"spec": {
+			"replicas": 2,
+			"template": {"spec": {"image": "nginx:1"}},
+			"strategy": {"type": "Canary", "canary": {"steps": [{"replicas": "50%"}]}}
+		}
+	}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	reconcile()
+
+	// Nothing to promote until a new template is rolling out.
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/deployments/web/rollout/promote", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/default/deployments/web", `{"spec": {
+		"replicas": 2,
+		"template": {"spec": {"image": "nginx:2"}},
+		"strategy": {"type": "Canary", "canary": {"steps": [{"replicas": "50%"}]}}
+	}}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	reconcile()
+
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/namespaces/default/deployments/web/rollout", "")
+	var status rolloutStatus
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&status))
+	assert.Equal(t, model.StrategyCanary, status.Strategy)
+	assert.Equal(t, model.ReasonAwaitingPromotion, status.Reason)
+	assert.Equal(t, 1, status.CanaryStep)
+	assert.Equal(t, 1, status.CanarySteps)
+
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/deployments/web/rollout/abort", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	reconcile()
+
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/namespaces/default/deployments/web/rollout", "")
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&status))
+	assert.Equal(t, model.RolloutAborted, status.Phase)
+
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/deployments/web/rollout/promote", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/deployments/api/rollout/abort", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/github-builder/container-orchestrator/internal/eviction"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// disruptionBudgetHandler serves the /disruptionbudgets endpoints. Reads
+// report how many more disruptions each budget allows.
+type disruptionBudgetHandler struct {
+	*resourceHandler[model.DisruptionBudget, *model.DisruptionBudget]
+	evictor *eviction.Evictor
+}
+
+func newDisruptionBudgetHandler(cfg *RouterConfig) *disruptionBudgetHandler {
+	return &disruptionBudgetHandler{
+		resourceHandler: &resourceHandler[model.DisruptionBudget, *model.DisruptionBudget]{
+			store:      cfg.Store,
+			logger:     cfg.Logger,
+			namespaces: cfg.Namespaces,
+			bucket:     model.DisruptionBudgetsBucket,
+			kind:       "disruption budget",
+			prepare: func(b *model.DisruptionBudget) {
+				// Status is computed on read.
+				b.Status = model.DisruptionBudgetStatus{}
+			},
+			apply: func(cur, in *model.DisruptionBudget) {
+				cur.Labels = in.Labels
+				cur.Annotations = in.Annotations
+				cur.Spec = in.Spec
+			},
+		},
+		evictor: cfg.Evictor,
+	}
+}
+
+func (h *disruptionBudgetHandler) routes(r chi.Router) {
+	r.Get("/", h.list)
+	r.Post("/", h.create)
+	r.Get("/{name}", h.get)
+	r.Put("/{name}", h.update)
+	r.Delete("/{name}", h.delete)
+	r.Put("/{name}/finalizers", h.finalizers)
+}
+
+func (h *disruptionBudgetHandler) list(w http.ResponseWriter, r *http.Request) {
+	page, perPage, err := pagination(r)
+	if err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeBadRequest)
+		return
+	}
+	q, err := parseListQuery(r)
+	if err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeBadRequest)
+		return
+	}
+
+	items, err := listItems[model.DisruptionBudget](h.store, h.bucket, prefix(r), q)
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	out := paginate(items, page, perPage)
+	for _, b := range out {
+		if b.Status, err = h.evictor.Status(b); err != nil {
+			h.internalError(w, err)
+			return
+		}
+	}
+	Paginated(w, out, len(items), page, perPage)
+}
+
+func (h *disruptionBudgetHandler) get(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	b, err := store.GetJSON[model.DisruptionBudget](h.store, h.bucket, key(r))
+	if store.IsNotFound(err) {
+		h.notFound(w, name)
+		return
+	}
+	if err == nil {
+		b.Status, err = h.evictor.Status(b)
+	}
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, b)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+func TestDisruptionBudgets_Eviction(t *testing.T) {
+	router, s := newTestRouterWithStore()
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/disruptionbudgets",
+		`{"metadata": {"name": "web"}, "spec": {"selector": {"match_labels": {"app": "web"}}}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, CodeValidationFailed, decodeError(t, rec).Code)
+
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/disruptionbudgets",
+		`{"metadata": {"name": "web"}, "spec": {"selector": {"match_labels": {"app": "web"}}, "min_available": "50%"}}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	for _, name := range []string{"web-1", "web-2"} {
+		c := &model.Container{
+			ObjectMeta: model.ObjectMeta{Name: name, Namespace: model.DefaultNamespace, UID: name, Labels: map[string]string{"app": "web"}},
+			Spec:       model.ContainerSpec{Image: "nginx", NodeName: "node-1"},
+			Status:     model.ContainerStatus{Phase: model.PhaseRunning, Ready: true},
+		}
+		require.NoError(t, store.PutJSON(s, model.ContainersBucket, c.Key(), c))
+	}
+
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/namespaces/default/disruptionbudgets/web", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var b model.DisruptionBudget
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&b))
+	assert.Equal(t, model.DisruptionBudgetStatus{
+		ExpectedCount: 2, CurrentHealthy: 2, DesiredHealthy: 1, DisruptionsAllowed: 1,
+	}, b.Status)
+
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/containers/web-1/eviction", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var c model.Container
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&c))
+	assert.Empty(t, c.Spec.NodeName)
+	assert.Equal(t, model.ReasonEvicted, c.Status.Reason)
+
+	// The budget allows no further disruption.
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/containers/web-2/eviction", "")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	errResp := decodeError(t, rec)
+	assert.Equal(t, CodeDisruptionBudget, errResp.Code)
+	assert.Contains(t, errResp.Error, "disruption budget default/web")
+
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/namespaces/default/disruptionbudgets", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list struct {
+		Items []model.DisruptionBudget `json:"items"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+	require.Len(t, list.Items, 1)
+	assert.Equal(t, 0, list.Items[0].Status.DisruptionsAllowed)
+
+	// Only containers on a node can be evicted.
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/containers/web-1/eviction", "")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/containers/missing/eviction", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+
+	"github.com/github-builder/container-orchestrator/internal/events"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// Types of WatchEvent.
+const (
+	WatchAdded    = "ADDED"
+	WatchModified = "MODIFIED"
+)
+
+// WatchEvent is one line of a watch stream: an event recorded for the
+// first time, or one whose count went up.
+type WatchEvent struct {
+	Type   string       `json:"type"`
+	Object *model.Event `json:"object"`
+}
+
+// eventHandler serves the read-only /events endpoints, cluster-wide and
+// below /namespaces/{namespace}. Lists take the usual selectors, so the
+// events about one object are selected with e.g.
+// field_selector=involved_object.kind=containers,involved_object.name=web.
+// With watch=true the response is a stream of newline-delimited
+// WatchEvents: the matching events recorded so far, then each one as it is
+// recorded, until the client goes away.
+type eventHandler struct {
+	store    store.Store
+	logger   zerolog.Logger
+	recorder *events.Recorder
+}
+
+func newEventHandler(cfg *RouterConfig) *eventHandler {
+	return &eventHandler{store: cfg.Store, logger: cfg.Logger, recorder: cfg.Events}
+}
+
+func (h *eventHandler) routes(r chi.Router) {
+	r.Get("/", h.list)
+}
+
+func (h *eventHandler) list(w http.ResponseWriter, r *http.Request) {
+	q, err := parseListQuery(r)
+	if err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeBadRequest)
+		return
+	}
+	watch := false
+	if v := r.URL.Query().Get("watch"); v != "" {
+		if watch, err = strconv.ParseBool(v); err != nil {
+			Error(w, http.StatusBadRequest, "watch must be a boolean", CodeBadRequest)
+			return
+		}
+	}
+	if watch {
+		h.watch(w, r, q)
+		return
+	}
+
+	page, perPage, err := pagination(r)
+	if err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeBadRequest)
+		return
+	}
+	items, err := listItems[model.Event](h.store, model.EventsBucket, prefix(r), q)
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+	Paginated(w, paginate(items, page, perPage), len(items), page, perPage)
+}
+
+// watch streams the events matching q.
+func (h *eventHandler) watch(w http.ResponseWriter, r *http.Request, q *listQuery) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		Error(w, http.StatusBadRequest, "watching is not supported by this connection", CodeBadRequest)
+		return
+	}
+
+	// Subscribe before listing so that nothing recorded in between is
+	// missed; it may be sent twice instead.
+	ctx := r.Context()
+	live := h.recorder.Watch(ctx)
+	existing, err := listItems[model.Event](h.store, model.EventsBucket, prefix(r), q)
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	send := func(e *model.Event) bool {
+		typ := WatchModified
+		if e.Count == 1 {
+			typ = WatchAdded
+		}
+		if err := enc.Encode(&WatchEvent{Type: typ, Object: e}); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	for _, e := range existing {
+		if !send(e) {
+			return
+		}
+	}
+	flusher.Flush()
+
+	ns := chi.URLParam(r, "namespace")
+	for e := range live {
+		if ns != "" && e.Namespace != ns {
+			continue
+		}
+		doc, err := document(e)
+		if err != nil || !matches(doc, q) {
+			continue
+		}
+		if !send(e) {
+			return
+		}
+	}
+}
+
+func (h *eventHandler) internalError(w http.ResponseWriter, err error) {
+	h.logger.Error().Err(err).Str("kind", "event").Msg("request failed")
+	Error(w, http.StatusInternalServerError, "internal error", CodeInternal)
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/events"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/namespace"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+func newEventTestRouter(t *testing.T) (http.Handler, *events.Recorder) {
+	t.Helper()
+	s := store.NewMemoryStore()
+	namespaces := namespace.NewRegistry(&namespace.Config{Store: s, Logger: zerolog.Nop()})
+	require.NoError(t, namespaces.EnsureDefault())
+	recorder := events.NewRecorder(&events.Config{Store: s, Logger: zerolog.Nop()})
+	return NewRouter(&RouterConfig{
+		Store:        s,
+		Namespaces:   namespaces,
+		Logger:       zerolog.Nop(),
+		DashboardURL: "http://localhost:3000",
+		APIKey:       "test-api-key",
+		Events:       recorder,
+	}), recorder
+}
+
+func TestEvents_List(t *testing.T) {
+	router, recorder := newEventTestRouter(t)
+	web := &model.ObjectMeta{Name: "web", Namespace: model.DefaultNamespace, UID: "web-uid"}
+	db := &model.ObjectMeta{Name: "db", Namespace: model.DefaultNamespace, UID: "db-uid"}
+	node := &model.ObjectMeta{Name: "node-1", UID: "node-uid"}
+
+	recorder.Event(model.ContainersBucket, web, model.EventWarning, model.EventFailedScheduling, "no nodes")
+	recorder.Event(model.ContainersBucket, web, model.EventWarning, model.EventFailedScheduling, "no nodes")
+	recorder.Event(model.ContainersBucket, db, model.EventNormal, model.EventScheduled, "assigned")
+	recorder.Event(model.NodesBucket, node, model.EventWarning, model.EventNodeNotReady, "no heartbeat")
+
+	rec := doRequest(t, router, http.MethodGet, "/api/v1/events", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var all struct {
+		Items []model.Event `json:"items"`
+		Total int           `json:"total"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&all))
+	assert.Equal(t, 3, all.Total)
+
+	rec = doRequest(t, router, http.MethodGet,
+		"/api/v1/namespaces/default/events?field_selector=involved_object.name=web", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var selected struct {
+		Items []model.Event `json:"items"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&selected))
+	require.Len(t, selected.Items, 1)
+	assert.Equal(t, 2, selected.Items[0].Count)
+	assert.Equal(t, model.EventFailedScheduling, selected.Items[0].Reason)
+
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/events?watch=maybe", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestEvents_Watch(t *testing.T) {
+	router, recorder := newEventTestRouter(t)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	web := &model.ObjectMeta{Name: "web", Namespace: model.DefaultNamespace, UID: "web-uid"}
+	db := &model.ObjectMeta{Name: "db", Namespace: model.DefaultNamespace, UID: "db-uid"}
+	recorder.Event(model.ContainersBucket, web, model.EventNormal, model.EventScheduled, "assigned")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		srv.URL+"/api/v1/events?watch=true&field_selector=involved_object.name=web", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-API-Key", "test-api-key")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	lines := bufio.NewScanner(resp.Body)
+	next := func() WatchEvent {
+		t.Helper()
+		require.True(t, lines.Scan())
+		var we WatchEvent
+		require.NoError(t, json.Unmarshal(lines.Bytes(), &we))
+		return we
+	}
+
+	we := next()
+	assert.Equal(t, WatchAdded, we.Type)
+	assert.Equal(t, model.EventScheduled, we.Object.Reason)
+
+	recorder.Event(model.ContainersBucket, db, model.EventNormal, model.EventScheduled, "assigned")
+	recorder.Event(model.ContainersBucket, web, model.EventNormal, model.EventScheduled, "assigned")
+	recorder.Event(model.ContainersBucket, web, model.EventNormal, model.EventStarted, "started")
+
+	we = next()
+	assert.Equal(t, WatchModified, we.Type)
+	assert.Equal(t, 2, we.Object.Count)
+
+	we = next()
+	assert.Equal(t, WatchAdded, we.Type)
+	assert.Equal(t, model.EventStarted, we.Object.Reason)
+	assert.Equal(t, "web", we.Object.InvolvedObject.Name)
+}
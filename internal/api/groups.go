@@ -0,0 +1,25 @@
+package api
+
+import (
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+// newGroupHandler serves the /groups endpoints. A group's containers are
+// fixed once created; only its labels and annotations can be updated.
+func newGroupHandler(cfg *RouterConfig) *resourceHandler[model.Group, *model.Group] {
+	return &resourceHandler[model.Group, *model.Group]{
+		store:      cfg.Store,
+		logger:     cfg.Logger,
+		namespaces: cfg.Namespaces,
+		bucket:     model.GroupsBucket,
+		kind:       "group",
+		prepare: func(g *model.Group) {
+			// Status is owned by the group controller.
+			g.Status = model.GroupStatus{}
+		},
+		apply: func(cur, in *model.Group) {
+			cur.Labels = in.Labels
+			cur.Annotations = in.Annotations
+		},
+	}
+}
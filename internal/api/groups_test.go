@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+func TestGroups(t *testing.T) {
+	router := newTestRouter()
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/groups", `{
+		"metadata": {"name": "shop"},
+		"spec": {
+			"init_containers": [{"name": "migrate", "spec": {"image": "migrate"}}],
+			"containers": [{"name": "app", "spec": {"image": "shop", "node_name": "node-1"}}]
+		}
+	}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	verr := decodeError(t, rec)
+	assert.Equal(t, CodeValidationFailed, verr.Code)
+	assert.Contains(t, verr.Error, "spec.containers[0].spec.node_name")
+
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/groups", `{
+		"metadata": {"name": "shop"},
+		"spec": {
+			"init_containers": [{"name": "migrate", "spec": {"image": "migrate"}}],
+			"containers": [{"name": "app", "spec": {"image": "shop"}}],
+			"node_name": "node-1"
+		},
+		"status": {"phase": "Running"}
+	}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var g model.Group
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&g))
+	assert.Equal(t, model.RestartOnFailure, g.Spec.InitContainers[0].Spec.RestartPolicy)
+	assert.Empty(t, g.Status.Phase)
+
+	// Only metadata changes.
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/default/groups/shop", `{
+		"metadata": {"name": "shop", "labels": {"team": "a"}},
+		"spec": {"containers": [{"name": "other", "spec": {"image": "other"}}]}
+	}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	g = model.Group{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&g))
+	assert.Equal(t, map[string]string{"team": "a"}, g.Labels)
+	assert.Equal(t, "app", g.Spec.Containers[0].Name)
+}
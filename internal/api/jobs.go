@@ -0,0 +1,35 @@
+package api
+
+import (
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+// newJobHandler serves the /jobs endpoints.
+func newJobHandler(cfg *RouterConfig) *resourceHandler[model.Job, *model.Job] {
+	return &resourceHandler[model.Job, *model.Job]{
+		store:      cfg.Store,
+		logger:     cfg.Logger,
+		namespaces: cfg.Namespaces,
+		bucket:     model.JobsBucket,
+		kind:       "job",
+		prepare: func(j *model.Job) {
+			// Status is owned by the job controller.
+			j.Status = model.JobStatus{}
+		},
+	}
+}
+
+// newCronJobHandler serves the /cronjobs endpoints.
+func newCronJobHandler(cfg *RouterConfig) *resourceHandler[model.CronJob, *model.CronJob] {
+	return &resourceHandler[model.CronJob, *model.CronJob]{
+		store:      cfg.Store,
+		logger:     cfg.Logger,
+		namespaces: cfg.Namespaces,
+		bucket:     model.CronJobsBucket,
+		kind:       "cron job",
+		prepare: func(c *model.CronJob) {
+			// Status is owned by the job controller.
+			c.Status = model.CronJobStatus{}
+		},
+	}
+}
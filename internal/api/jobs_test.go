@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+func TestJobs_Create(t *testing.T) {
+	router := newTestRouter()
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/jobs", `{
+		"metadata": {"name": "migrate"},
+		"spec": {"completions": 3, "template": {"spec": {"image": "app:v1"}}},
+		"status": {"phase": "Complete"}
+	}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var j model.Job
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&j))
+	assert.Equal(t, 3, j.Spec.Completions)
+	assert.Equal(t, model.DefaultBackoffLimit, *j.Spec.BackoffLimit)
+	assert.Equal(t, model.RestartNever, j.Spec.Template.Spec.RestartPolicy)
+	assert.Empty(t, j.Status.Phase)
+
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/jobs", `{
+		"metadata": {"name": "serve"},
+		"spec": {"template": {"spec": {"image": "app:v1", "restart_policy": "Always"}}}
+	}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, CodeValidationFailed, decodeError(t, rec).Code)
+}
+
+func TestCronJobs_Create(t *testing.T) {
+	router := newTestRouter()
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/cronjobs", `{
+		"metadata": {"name": "nightly-report"},
+		"spec": {
+			"schedule": "0 2 * * *",
+			"concurrency_policy": "Forbid",
+			"job_template": {"spec": {"template": {"spec": {"image": "report:v1"}}}}
+		}
+	}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var cj model.CronJob
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&cj))
+	assert.Equal(t, model.ConcurrencyForbid, cj.Spec.ConcurrencyPolicy)
+	assert.Equal(t, model.DefaultSuccessfulJobsHistoryLimit, *cj.Spec.SuccessfulJobsHistoryLimit)
+
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/namespaces/default/cronjobs/nightly-report", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/cronjobs", `{
+		"metadata": {"name": "broken"},
+		"spec": {"schedule": "every night", "job_template": {"spec": {"template": {"spec": {"image": "x"}}}}}
+	}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, decodeError(t, rec).Error, "spec.schedule")
+}
+
+func TestJobs_DeletePropagation(t *testing.T) {
+	router := newTestRouter()
+	create := func(name string) {
+		t.Helper()
+		rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/jobs", `{
+			"metadata": {"name": "`+name+`", "deletion_timestamp": "2024-01-01T00:00:00Z"},
+			"spec": {"template": {"spec": {"image": "app:v1"}}}
+		}`)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var j model.Job
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&j))
+		assert.Nil(t, j.DeletionTimestamp)
+	}
+
+	create("background")
+	rec := doRequest(t, router, http.MethodDelete, "/api/v1/namespaces/default/jobs/background", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	// Foreground and orphaning deletions leave the job to the garbage
+	// collector.
+	create("foreground")
+	rec = doRequest(t, router, http.MethodDelete, "/api/v1/namespaces/default/jobs/foreground?propagation_policy=Foreground", "")
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	var j model.Job
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&j))
+	assert.NotNil(t, j.DeletionTimestamp)
+	assert.Equal(t, []string{model.FinalizerForeground}, j.Finalizers)
+
+	rec = doRequest(t, router, http.MethodDelete, "/api/v1/namespaces/default/jobs/foreground?propagation_policy=Orphan", "")
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&j))
+	assert.Equal(t, []string{model.FinalizerForeground}, j.Finalizers, "the first policy sticks")
+
+	rec = doRequest(t, router, http.MethodDelete, "/api/v1/namespaces/default/jobs/foreground?propagation_policy=Sideways", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, CodeBadRequest, decodeError(t, rec).Code)
+
+	rec = doRequest(t, router, http.MethodDelete, "/api/v1/namespaces/default/jobs/missing?propagation_policy=Orphan", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestJobs_CreateValidatesOwnerReferences(t *testing.T) {
+	router := newTestRouter()
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/jobs", `{
+		"metadata": {"name": "report", "owner_references": [{"kind": "widgets", "name": "x", "uid": "u"}]},
+		"spec": {"template": {"spec": {"image": "app:v1"}}}
+	}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, decodeError(t, rec).Error, "metadata.owner_references[0].kind")
+}
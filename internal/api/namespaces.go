@@ -0,0 +1,183 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/namespace"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// namespaceHandler serves the /namespaces endpoints. Reads report what
+// each namespace uses of its quota, and deleting a namespace deletes
+// everything in it before the namespace itself is removed.
+type namespaceHandler struct {
+	*resourceHandler[model.Namespace, *model.Namespace]
+	registry *namespace.Registry
+}
+
+// newNamespaceHandler creates the /namespaces handler.
+func newNamespaceHandler(cfg *RouterConfig) *namespaceHandler {
+	return &namespaceHandler{
+		resourceHandler: &resourceHandler[model.Namespace, *model.Namespace]{
+			store:  cfg.Store,
+			logger: cfg.Logger,
+			bucket: model.NamespacesBucket,
+			kind:   "namespace",
+			prepare: func(n *model.Namespace) {
+				// Status is computed on read.
+				n.Status = model.NamespaceStatus{}
+			},
+			apply: func(cur, in *model.Namespace) {
+				cur.Labels = in.Labels
+				cur.Annotations = in.Annotations
+				cur.Spec = in.Spec
+			},
+		},
+		registry: cfg.Namespaces,
+	}
+}
+
+// routes mounts the namespace endpoints, and has mount add those of
+// namespaced resources below /{namespace}.
+func (h *namespaceHandler) routes(r chi.Router, mount func(r chi.Router)) {
+	r.Get("/", h.list)
+	r.Post("/", h.create)
+	r.Route("/{namespace}", func(r chi.Router) {
+		r.Get("/", h.get)
+		r.Put("/", h.update)
+		r.Delete("/", h.delete)
+		r.Put("/finalizers", h.finalizers)
+		r.Group(func(r chi.Router) {
+			r.Use(h.requireNamespace)
+			mount(r)
+		})
+	})
+}
+
+func (h *namespaceHandler) list(w http.ResponseWriter, r *http.Request) {
+	page, perPage, err := pagination(r)
+	if err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeBadRequest)
+		return
+	}
+	q, err := parseListQuery(r)
+	if err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeBadRequest)
+		return
+	}
+
+	items, err := listItems[model.Namespace](h.store, h.bucket, "", q)
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	out := paginate(items, page, perPage)
+	for _, n := range out {
+		if n.Status.Used, err = h.registry.Usage(n.Name); err != nil {
+			h.internalError(w, err)
+			return
+		}
+	}
+	Paginated(w, out, len(items), page, perPage)
+}
+
+func (h *namespaceHandler) get(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "namespace")
+
+	n, err := store.GetJSON[model.Namespace](h.store, h.bucket, name)
+	if store.IsNotFound(err) {
+		h.notFound(w, name)
+		return
+	}
+	if err == nil {
+		n.Status.Used, err = h.registry.Usage(name)
+	}
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, n)
+}
+
+// update replaces a namespace's labels, annotations and quota. Lowering
+// the quota below the current usage only blocks further growth.
+func (h *namespaceHandler) update(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "namespace")
+
+	var in model.Namespace
+	if err := decodeJSON(r, &in); err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeBadRequest)
+		return
+	}
+	if in.Name == "" {
+		in.Name = name
+	}
+	if in.Name != name {
+		Error(w, http.StatusBadRequest, "metadata.name does not match the URL", CodeBadRequest)
+		return
+	}
+	in.SetDefaults()
+	if err := in.Validate(); err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeValidationFailed)
+		return
+	}
+
+	updated, err := store.UpdateJSON(h.store, h.bucket, name, func(cur *model.Namespace) error {
+		h.apply(cur, &in)
+		return nil
+	})
+	h.respond(w, name, updated, err)
+}
+
+// delete deletes the namespace and everything in it. A namespace that was
+// empty is removed at once; otherwise it is returned with 202 Accepted and
+// stays terminating until its resources, and their finalizers, are gone.
+func (h *namespaceHandler) delete(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "namespace")
+
+	n, err := h.registry.Delete(name)
+	if store.IsNotFound(err) {
+		h.notFound(w, name)
+		return
+	}
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+	if n == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	JSON(w, http.StatusAccepted, n)
+}
+
+// finalizers replaces the finalizers of a namespace, as for any other
+// resource.
+func (h *namespaceHandler) finalizers(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "namespace")
+	h.replaceFinalizers(w, r, name, name)
+}
+
+// requireNamespace rejects requests for resources in a namespace that
+// does not exist.
+func (h *namespaceHandler) requireNamespace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "namespace")
+		ok, err := h.registry.Exists(name)
+		if err != nil {
+			h.internalError(w, err)
+			return
+		}
+		if !ok {
+			h.notFound(w, name)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+func TestNamespaces_IsolateNames(t *testing.T) {
+	router := newTestRouter()
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces", `{"metadata": {"name": "team-a"}}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	// The same name can be used in each namespace.
+	for _, ns := range []string{"default", "team-a"} {
+		rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/"+ns+"/containers",
+			`{"metadata": {"name": "web"}, "spec": {"image": "nginx"}}`)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var c model.Container
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&c))
+		assert.Equal(t, ns, c.Namespace)
+	}
+
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/namespaces/team-a/containers", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list struct {
+		Items []model.Container `json:"items"`
+		Total int               `json:"total"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+	assert.Equal(t, 1, list.Total)
+	assert.Equal(t, "team-a", list.Items[0].Namespace)
+
+	rec = doRequest(t, router, http.MethodDelete, "/api/v1/namespaces/team-a/containers/web", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/namespaces/default/containers/web", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// A body naming another namespace is rejected.
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/team-a/containers",
+		`{"metadata": {"name": "api", "namespace": "default"}, "spec": {"image": "nginx"}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, CodeBadRequest, decodeError(t, rec).Code)
+
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/namespaces/missing/containers", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "namespace missing not found", decodeError(t, rec).Error)
+}
+
+func TestNamespaces_Quota(t *testing.T) {
+	router := newTestRouter()
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces", `{
+		"metadata": {"name": "team-a"},
+		"spec": {"quota": {"counts": {"containers": 2}, "requests": {"cpu_millis": 1000}}}
+	}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/team-a/containers",
+		`{"metadata": {"name": "web"}, "spec": {"image": "nginx", "resources": {"cpu_millis": 600}}}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/team-a/containers",
+		`{"metadata": {"name": "api"}, "spec": {"image": "nginx", "resources": {"cpu_millis": 600}}}`)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	resp := decodeError(t, rec)
+	assert.Equal(t, CodeQuotaExceeded, resp.Code)
+	assert.Equal(t, "quota exceeded in namespace team-a: cpu_millis requested 600, used 600, limited to 1000", resp.Error)
+
+	// Updates are checked too.
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/team-a/deployments", `{
+		"metadata": {"name": "api"},
+		"spec": {"replicas": 1, "template": {"spec": {"image": "api", "resources": {"cpu_millis": 200}}}}
+	}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/team-a/deployments/api", `{
+		"metadata": {"name": "api"},
+		"spec": {"replicas": 3, "template": {"spec": {"image": "api", "resources": {"cpu_millis": 200}}}}
+	}`)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, CodeQuotaExceeded, decodeError(t, rec).Code)
+
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/namespaces/team-a", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var ns model.Namespace
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&ns))
+	require.NotNil(t, ns.Status.Used)
+	assert.Equal(t, int64(800), ns.Status.Used.Requests.CPUMillis)
+	assert.Equal(t, 1, ns.Status.Used.Counts["containers"])
+	assert.Equal(t, 1, ns.Status.Used.Counts["deployments"])
+
+	// Raising the quota makes room.
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/team-a", `{
+		"metadata": {"name": "team-a"},
+		"spec": {"quota": {"requests": {"cpu_millis": 2000}}}
+	}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/team-a/containers",
+		`{"metadata": {"name": "api"}, "spec": {"image": "nginx", "resources": {"cpu_millis": 600}}}`)
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+}
+
+func TestNamespaces_DeleteCascades(t *testing.T) {
+	router := newTestRouter()
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces", `{"metadata": {"name": "team-a"}}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/team-a/configmaps",
+		`{"metadata": {"name": "app"}, "data": {"mode": "prod"}}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	rec = doRequest(t, router, http.MethodDelete, "/api/v1/namespaces/team-a", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	// Recreating the namespace does not bring back its resources.
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces", `{"metadata": {"name": "team-a"}}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/namespaces/team-a/configmaps/app", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = doRequest(t, router, http.MethodDelete, "/api/v1/namespaces/missing", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestNamespaces_DeleteWaitsForFinalizers(t *testing.T) {
+	router := newTestRouter()
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces", `{"metadata": {"name": "team-a"}}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/team-a/configmaps",
+		`{"metadata": {"name": "app", "finalizers": ["example.com/cleanup"]}, "data": {"mode": "prod"}}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	rec = doRequest(t, router, http.MethodDelete, "/api/v1/namespaces/team-a", "")
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), "deletion_timestamp")
+
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/team-a/configmaps",
+		`{"metadata": {"name": "other"}, "data": {}}`)
+	assert.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+
+	// The namespace cannot gain finalizers while it is terminating.
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/team-a/finalizers",
+		`{"finalizers": ["orchestrator.namespace-contents", "example.com/other"]}`)
+	assert.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/team-a/configmaps/app/finalizers", `{"finalizers": []}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = doRequest(t, router, http.MethodDelete, "/api/v1/namespaces/team-a", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/namespaces/team-a", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/node"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// nodeHandler serves the /nodes endpoints. Reads and deletes use the
+// standard resource handler; registration, heartbeats and maintenance go
+// through the node registry.
+type nodeHandler struct {
+	*resourceHandler[model.Node, *model.Node]
+	registry *node.Registry
+}
+
+func newNodeHandler(cfg *RouterConfig) *nodeHandler {
+	return &nodeHandler{
+		resourceHandler: &resourceHandler[model.Node, *model.Node]{
+			store:  cfg.Store,
+			logger: cfg.Logger,
+			bucket: model.NodesBucket,
+			kind:   "node",
+		},
+		registry: cfg.Nodes,
+	}
+}
+
+func (h *nodeHandler) routes(r chi.Router) {
+	r.Get("/", h.list)
+	r.Post("/", h.register)
+	r.Get("/{name}", h.get)
+	r.Patch("/{name}", h.update)
+	r.Delete("/{name}", h.delete)
+	r.Put("/{name}/finalizers", h.finalizers)
+	r.Post("/{name}/heartbeat", h.heartbeat)
+	r.Post("/{name}/cordon", h.cordon)
+	r.Post("/{name}/uncordon", h.uncordon)
+	r.Post("/{name}/drain", h.drain)
+	r.Get("/{name}/drain", h.drainStatus)
+}
+
+// register creates a node, or updates it if it is already registered.
+func (h *nodeHandler) register(w http.ResponseWriter, r *http.Request) {
+	var n model.Node
+	if err := decodeJSON(r, &n); err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeBadRequest)
+		return
+	}
+
+	registered, created, err := h.registry.Register(&n)
+	var verr *model.ValidationError
+	if errors.As(err, &verr) {
+		Error(w, http.StatusBadRequest, err.Error(), CodeValidationFailed)
+		return
+	}
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	JSON(w, status, registered)
+}
+
+// update changes a node's labels or taints.
+func (h *nodeHandler) update(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	var u node.Update
+	if err := decodeJSON(r, &u); err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeBadRequest)
+		return
+	}
+
+	n, err := h.registry.Update(name, &u)
+	var verr *model.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		Error(w, http.StatusBadRequest, err.Error(), CodeValidationFailed)
+		return
+	case store.IsNotFound(err):
+		h.notFound(w, name)
+		return
+	case err != nil:
+		h.internalError(w, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, n)
+}
+
+func (h *nodeHandler) heartbeat(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	n, err := h.registry.Heartbeat(name)
+	if store.IsNotFound(err) {
+		h.notFound(w, name)
+		return
+	}
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, n)
+}
+
+// cordon marks a node unschedulable.
+func (h *nodeHandler) cordon(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	n, err := h.registry.Cordon(name)
+	h.respond(w, name, n, err)
+}
+
+// uncordon makes a node schedulable again and cancels any drain.
+func (h *nodeHandler) uncordon(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	n, err := h.registry.Uncordon(name)
+	h.respond(w, name, n, err)
+}
+
+// drain cordons a node and starts evicting its containers. The optional
+// timeout query parameter is a duration such as "10m". The drain goes on
+// in the background; its progress is reported by GET /{name}/drain.
+func (h *nodeHandler) drain(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	var timeout time.Duration
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		var err error
+		if timeout, err = time.ParseDuration(v); err != nil || timeout <= 0 {
+			Error(w, http.StatusBadRequest, fmt.Sprintf("timeout must be a positive duration, got %q", v), CodeBadRequest)
+			return
+		}
+	}
+
+	n, err := h.registry.Drain(name, timeout)
+	if store.IsNotFound(err) {
+		h.notFound(w, name)
+		return
+	}
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	JSON(w, http.StatusAccepted, n)
+}
+
+// drainStatus reports the progress of the node's latest drain.
+func (h *nodeHandler) drainStatus(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	n, err := store.GetJSON[model.Node](h.store, h.bucket, name)
+	if store.IsNotFound(err) {
+		h.notFound(w, name)
+		return
+	}
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+	if n.Status.Drain == nil {
+		Error(w, http.StatusNotFound, fmt.Sprintf("node %s has not been drained", name), CodeNotFound)
+		return
+	}
+
+	JSON(w, http.StatusOK, n.Status.Drain)
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+const nodeBody = `{
+	"metadata": {"name": "node-1", "labels": {"zone": "a"}},
+	"spec": {"address": "10.0.0.1", "capacity": {"cpu_millis": 4000, "memory_bytes": 8589934592}}
+}`
+
+func TestNodes_RegisterAndGet(t *testing.T) {
+	router := newTestRouter()
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/nodes", nodeBody)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var n model.Node
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&n))
+	assert.Equal(t, model.NodeReady, n.Status.Phase)
+
+	// Registering again updates in place.
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/nodes", nodeBody)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/nodes/node-1", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&n))
+	assert.Equal(t, "10.0.0.1", n.Spec.Address)
+}
+
+func TestNodes_RegisterInvalid(t *testing.T) {
+	router := newTestRouter()
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/nodes", `{"metadata": {"name": "node-1"}, "spec": {}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, CodeValidationFailed, decodeError(t, rec).Code)
+}
+
+func TestNodes_Update(t *testing.T) {
+	router := newTestRouter()
+
+	rec := doRequest(t, router, http.MethodPatch, "/api/v1/nodes/node-1", `{"labels": {}}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	require.Equal(t, http.StatusCreated, doRequest(t, router, http.MethodPost, "/api/v1/nodes", nodeBody).Code)
+
+	rec = doRequest(t, router, http.MethodPatch, "/api/v1/nodes/node-1",
+		`{"taints": [{"key": "maintenance", "effect": "NoSchedule"}]}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var n model.Node
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&n))
+	assert.Equal(t, "a", n.Labels["zone"])
+	require.Len(t, n.Spec.Taints, 1)
+	assert.NotNil(t, n.Spec.Taints[0].AddedAt)
+
+	rec = doRequest(t, router, http.MethodPatch, "/api/v1/nodes/node-1",
+		`{"taints": [{"key": "maintenance", "effect": "Never"}]}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, CodeValidationFailed, decodeError(t, rec).Code)
+}
+
+func TestNodes_Heartbeat(t *testing.T) {
+	router := newTestRouter()
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/nodes/node-1/heartbeat", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	require.Equal(t, http.StatusCreated, doRequest(t, router, http.MethodPost, "/api/v1/nodes", nodeBody).Code)
+
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/nodes/node-1/heartbeat", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestNodes_Delete(t *testing.T) {
+	router := newTestRouter()
+	require.Equal(t, http.StatusCreated, doRequest(t, router, http.MethodPost, "/api/v1/nodes", nodeBody).Code)
+
+	rec := doRequest(t, router, http.MethodDelete, "/api/v1/nodes/node-1", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/nodes", "")
+	assert.Contains(t, rec.Body.String(), `"total":0`)
+}
+
+func TestNodes_CordonAndDrain(t *testing.T) {
+	router, s := newTestRouterWithStore()
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/nodes/node-1/cordon", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	require.Equal(t, http.StatusCreated, doRequest(t, router, http.MethodPost, "/api/v1/nodes", nodeBody).Code)
+	c := &model.Container{
+		ObjectMeta: model.ObjectMeta{Name: "web", Namespace: model.DefaultNamespace, UID: "uid-1"},
+		Spec:       model.ContainerSpec{Image: "nginx", NodeName: "node-1"},
+		Status:     model.ContainerStatus{Phase: model.PhaseRunning},
+	}
+	require.NoError(t, store.PutJSON(s, model.ContainersBucket, c.Key(), c))
+
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/nodes/node-1/cordon", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var n model.Node
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&n))
+	assert.True(t, n.Spec.Unschedulable)
+
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/nodes/node-1/drain", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/nodes/node-1/drain?timeout=soon", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/nodes/node-1/drain?timeout=10m", "")
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+
+	rec = doRequest(t, router, http.MethodGet, "/api/v1/nodes/node-1/drain", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var d model.DrainStatus
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&d))
+	assert.Equal(t, model.DrainComplete, d.Phase)
+	assert.Equal(t, 1, d.Evicted)
+	assert.Equal(t, d.StartedAt.Add(10*time.Minute), d.Deadline)
+
+	evicted, err := store.GetJSON[model.Container](s, model.ContainersBucket, c.Key())
+	require.NoError(t, err)
+	assert.Empty(t, evicted.Spec.NodeName)
+
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/nodes/node-1/uncordon", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var uncordoned model.Node
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&uncordoned))
+	assert.False(t, uncordoned.Spec.Unschedulable)
+	assert.Nil(t, uncordoned.Status.Drain)
+}
@@ -0,0 +1,23 @@
+package api
+
+import (
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+// newPriorityClassHandler serves the /priorityclasses endpoints. A class's
+// value cannot change once created; replace the class to change it.
+func newPriorityClassHandler(cfg *RouterConfig) *resourceHandler[model.PriorityClass, *model.PriorityClass] {
+	return &resourceHandler[model.PriorityClass, *model.PriorityClass]{
+		store:  cfg.Store,
+		logger: cfg.Logger,
+		bucket: model.PriorityClassesBucket,
+		kind:   "priority class",
+		apply: func(cur, in *model.PriorityClass) {
+			cur.Labels = in.Labels
+			cur.Annotations = in.Annotations
+			cur.GlobalDefault = in.GlobalDefault
+			cur.PreemptionPolicy = in.PreemptionPolicy
+			cur.Description = in.Description
+		},
+	}
+}
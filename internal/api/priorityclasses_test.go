@@ -0,0 +1,41 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+func TestPriorityClasses(t *testing.T) {
+	router := newTestRouter()
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/priorityclasses",
+		`{"metadata": {"name": "high"}, "value": 1000, "description": "user facing"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var pc model.PriorityClass
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&pc))
+	assert.Equal(t, model.PreemptLowerPriority, pc.PreemptionPolicy)
+
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/priorityclasses",
+		`{"metadata": {"name": "huge"}, "value": 2000000000}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, CodeValidationFailed, decodeError(t, rec).Code)
+
+	// The value is fixed once created.
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/priorityclasses/high",
+		`{"metadata": {"name": "high"}, "value": 5, "preemption_policy": "Never"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	pc = model.PriorityClass{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&pc))
+	assert.Equal(t, 1000, pc.Value)
+	assert.Equal(t, model.PreemptNever, pc.PreemptionPolicy)
+
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/containers",
+		`{"metadata": {"name": "web"}, "spec": {"image": "nginx", "priority_class_name": "Not_Valid"}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
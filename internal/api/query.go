@@ -0,0 +1,259 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// listQuery holds the selection and ordering parameters of a list
+// request, applied before pagination:
+//
+//	label_selector  requirements on labels, e.g. "app=web,tier in (api,db)"
+//	field_selector  requirements on fields, e.g. "status.phase=Running"
+//	sort            a field to order by; a leading '-' reverses the order
+//
+// Both selectors use the language of model.ParseSelector. Fields are
+// dotted paths into the JSON form of the resource and may be written in
+// camel case, so spec.nodeName and spec.node_name are the same field.
+// Items are in key order unless sorted.
+type listQuery struct {
+	labels []model.SelectorRequirement
+	fields []model.SelectorRequirement
+	sort   string
+	desc   bool
+}
+
+// parseListQuery reads the list parameters of a request.
+func parseListQuery(r *http.Request) (*listQuery, error) {
+	params := r.URL.Query()
+
+	labels, err := model.ParseSelector(params.Get("label_selector"))
+	if err != nil {
+		return nil, fmt.Errorf("label_selector: %w", err)
+	}
+	fields, err := model.ParseSelector(params.Get("field_selector"))
+	if err != nil {
+		return nil, fmt.Errorf("field_selector: %w", err)
+	}
+	for i := range fields {
+		fields[i].Key = fieldPath(fields[i].Key)
+	}
+
+	q := &listQuery{labels: labels, fields: fields}
+	if v := params.Get("sort"); v != "" {
+		q.desc = strings.HasPrefix(v, "-")
+		q.sort = fieldPath(strings.TrimPrefix(v, "-"))
+		if q.sort == "" {
+			return nil, fmt.Errorf("sort must name a field, got %q", v)
+		}
+	}
+	return q, nil
+}
+
+// names returns the names a field selector limits the list to, if any.
+// Those are looked up by key instead of listing the bucket.
+func (q *listQuery) names() ([]string, bool) {
+	for _, req := range q.fields {
+		if req.Key == "metadata.name" && req.Operator == model.OpIn {
+			return req.Values, true
+		}
+	}
+	return nil, false
+}
+
+// listItems returns the items of bucket under prefix that match q, in the
+// order q asks for. Namespaces are selected by the key prefix and names
+// by key, so neither needs a scan of the bucket.
+func listItems[T any](s store.Store, bucket, prefix string, q *listQuery) ([]*T, error) {
+	names, ok := q.names()
+	if !ok {
+		items, err := store.ListJSON[T](s, bucket, prefix)
+		if err != nil {
+			return nil, err
+		}
+		return selectItems(items, q)
+	}
+
+	sort.Strings(names)
+	items := make([]*T, 0, len(names))
+	for _, name := range names {
+		// Invalid names match nothing, and must not reach other keys.
+		if model.ValidateName("", name) != nil {
+			continue
+		}
+		v, err := store.GetJSON[T](s, bucket, prefix+name)
+		if store.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+	}
+	return selectItems(items, q)
+}
+
+// selectItems filters items by the selectors of q and sorts them.
+func selectItems[T any](items []*T, q *listQuery) ([]*T, error) {
+	if len(q.labels) == 0 && len(q.fields) == 0 && q.sort == "" {
+		return items, nil
+	}
+
+	type entry struct {
+		item *T
+		key  any
+	}
+	selected := make([]entry, 0, len(items))
+	for _, item := range items {
+		doc, err := document(item)
+		if err != nil {
+			return nil, err
+		}
+		if !matches(doc, q) {
+			continue
+		}
+		e := entry{item: item}
+		if q.sort != "" {
+			e.key, _ = lookup(doc, q.sort)
+		}
+		selected = append(selected, e)
+	}
+
+	if q.sort != "" {
+		sort.SliceStable(selected, func(i, j int) bool {
+			if q.desc {
+				return less(selected[j].key, selected[i].key)
+			}
+			return less(selected[i].key, selected[j].key)
+		})
+	}
+
+	out := make([]*T, len(selected))
+	for i, e := range selected {
+		out[i] = e.item
+	}
+	return out, nil
+}
+
+// document returns the JSON form of v as nested maps.
+func document(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// matches reports whether a document satisfies the selectors of q.
+func matches(doc map[string]any, q *listQuery) bool {
+	if len(q.labels) > 0 {
+		labels := make(map[string]string)
+		if meta, ok := doc["metadata"].(map[string]any); ok {
+			if l, ok := meta["labels"].(map[string]any); ok {
+				for k, v := range l {
+					labels[k], _ = v.(string)
+				}
+			}
+		}
+		for i := range q.labels {
+			if !q.labels[i].Matches(labels) {
+				return false
+			}
+		}
+	}
+
+	for i := range q.fields {
+		req := &q.fields[i]
+		fields := map[string]string{}
+		if v, ok := lookup(doc, req.Key); ok {
+			fields[req.Key] = format(v)
+		}
+		if !req.Matches(fields) {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup returns the value at a dotted path of a document. Null values
+// count as missing.
+func lookup(doc map[string]any, path string) (any, bool) {
+	var cur any = doc
+	for _, seg := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[seg]; !ok {
+			return nil, false
+		}
+	}
+	return cur, cur != nil
+}
+
+// format renders a document value for comparison with selector values.
+func format(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// less orders sort keys: missing values first, numbers numerically,
+// timestamps chronologically and everything else by its formatted value.
+func less(a, b any) bool {
+	switch {
+	case a == nil:
+		return b != nil
+	case b == nil:
+		return false
+	}
+	if x, ok := a.(float64); ok {
+		if y, ok := b.(float64); ok {
+			return x < y
+		}
+	}
+	x, y := format(a), format(b)
+	if tx, err := time.Parse(time.RFC3339Nano, x); err == nil {
+		if ty, err := time.Parse(time.RFC3339Nano, y); err == nil {
+			return tx.Before(ty)
+		}
+	}
+	return x < y
+}
+
+// fieldPath converts the camel case segments of a field path to the snake
+// case of the JSON API, e.g. spec.nodeName to spec.node_name.
+func fieldPath(path string) string {
+	var b strings.Builder
+	for i, r := range path {
+		if unicode.IsUpper(r) {
+			if i > 0 && path[i-1] != '.' {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
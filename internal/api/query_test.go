@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+func TestList_SelectorsAndSort(t *testing.T) {
+	router, s := newTestRouterWithStore()
+
+	for _, c := range []*model.Container{
+		{
+			ObjectMeta: model.ObjectMeta{Name: "api", Labels: map[string]string{"app": "shop", "tier": "api"}},
+			Spec:       model.ContainerSpec{Image: "api", NodeName: "n1", Resources: model.Resources{CPUMillis: 500}},
+			Status:     model.ContainerStatus{Phase: model.PhaseRunning},
+		},
+		{
+			ObjectMeta: model.ObjectMeta{Name: "db", Labels: map[string]string{"app": "shop", "tier": "db"}},
+			Spec:       model.ContainerSpec{Image: "db", NodeName: "n2", Resources: model.Resources{CPUMillis: 2000}},
+			Status:     model.ContainerStatus{Phase: model.PhaseRunning},
+		},
+		{
+			ObjectMeta: model.ObjectMeta{Name: "web", Labels: map[string]string{"app": "shop", "tier": "web", "canary": "true"}},
+			Spec:       model.ContainerSpec{Image: "web", NodeName: "n1", Resources: model.Resources{CPUMillis: 100}},
+			Status:     model.ContainerStatus{Phase: model.PhasePending},
+		},
+		{
+			ObjectMeta: model.ObjectMeta{Name: "batch", Labels: map[string]string{"app": "reports"}},
+			Spec:       model.ContainerSpec{Image: "batch"},
+			Status:     model.ContainerStatus{Phase: model.PhaseSucceeded},
+		},
+	} {
+		c.Namespace = model.DefaultNamespace
+		require.NoError(t, store.PutJSON(s, model.ContainersBucket, c.Key(), c))
+	}
+
+	names := func(query string) []string {
+		t.Helper()
+		rec := doRequest(t, router, http.MethodGet, "/api/v1/namespaces/default/containers?"+query, "")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var resp struct {
+			Items []model.Container `json:"items"`
+			Total int               `json:"total"`
+		}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		out := []string{}
+		for _, c := range resp.Items {
+			out = append(out, c.Name)
+		}
+		return out
+	}
+	q := func(params ...string) string {
+		v := url.Values{}
+		for i := 0; i < len(params); i += 2 {
+			v.Set(params[i], params[i+1])
+		}
+		return v.Encode()
+	}
+
+	assert.Equal(t, []string{"api", "batch", "db", "web"}, names(""))
+	assert.Equal(t, []string{"api", "db", "web"}, names(q("label_selector", "app=shop")))
+	assert.Equal(t, []string{"api", "web"}, names(q("label_selector", "app=shop,tier in (api, web)")))
+	assert.Equal(t, []string{"db"}, names(q("label_selector", "app=shop,tier notin (api,web)")))
+	assert.Equal(t, []string{"web"}, names(q("label_selector", "canary")))
+	assert.Equal(t, []string{"api", "batch", "db"}, names(q("label_selector", "!canary")))
+	assert.Equal(t, []string{"batch"}, names(q("label_selector", "app!=shop")))
+
+	// Field selectors accept JSON and camel case names.
+	assert.Equal(t, []string{"api"}, names(q("field_selector", "status.phase=Running,spec.nodeName=n1")))
+	assert.Equal(t, []string{"api", "db"}, names(q("field_selector", "spec.resources.cpu_millis in (500, 2000)")))
+	assert.Equal(t, []string{"batch", "web"}, names(q("field_selector", "status.phase!=Running")))
+
+	// Name selectors are looked up by key.
+	assert.Equal(t, []string{"db", "web"}, names(q("field_selector", "metadata.name in (web, db, missing, ../x)")))
+
+	// Sorting combines with selectors and pagination.
+	assert.Equal(t, []string{"web", "api", "db"}, names(q("label_selector", "app=shop", "sort", "spec.resources.cpuMillis")))
+	assert.Equal(t, []string{"db", "api"}, names(q("label_selector", "app=shop", "sort", "-spec.resources.cpu_millis", "per_page", "2")))
+	assert.Equal(t, []string{"web"}, names(q("label_selector", "app=shop", "sort", "-spec.resources.cpu_millis", "per_page", "2", "page", "2")))
+	assert.Equal(t, []string{"web", "db", "batch", "api"}, names(q("sort", "-metadata.name")))
+
+	rec := doRequest(t, router, http.MethodGet, "/api/v1/namespaces/default/containers?"+q("label_selector", "app=shop", "per_page", "2"), "")
+	var page PaginatedResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&page))
+	assert.Equal(t, 3, page.Total)
+
+	for _, bad := range []string{q("label_selector", "app in (a"), q("field_selector", "=x"), q("sort", "-")} {
+		rec := doRequest(t, router, http.MethodGet, "/api/v1/namespaces/default/containers?"+bad, "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code, bad)
+		assert.Equal(t, CodeBadRequest, decodeError(t, rec).Code)
+	}
+}
+
+func TestList_SelectorsOnClusterResources(t *testing.T) {
+	router := newTestRouter()
+
+	for _, body := range []string{
+		`{"metadata": {"name": "team-a", "labels": {"env": "prod"}}}`,
+		`{"metadata": {"name": "team-b", "labels": {"env": "dev"}}}`,
+	} {
+		rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces", body)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	rec := doRequest(t, router, http.MethodGet, "/api/v1/namespaces?label_selector=env%3Dprod", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp struct {
+		Items []model.Namespace `json:"items"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	require.Len(t, resp.Items, 1)
+	assert.Equal(t, "team-a", resp.Items[0].Name)
+}
+
+func TestFieldPath(t *testing.T) {
+	assert.Equal(t, "spec.node_name", fieldPath("spec.nodeName"))
+	assert.Equal(t, "spec.node_name", fieldPath("spec.node_name"))
+	assert.Equal(t, "status.restart_count", fieldPath("Status.RestartCount"))
+}
@@ -0,0 +1,430 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/namespace"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// Errors that end the store updates of delete and finalizers early.
+var (
+	errNoFinalizers   = errors.New("resource has no finalizers")
+	errFinalizerAdded = errors.New("finalizer added to a terminating resource")
+)
+
+// conflictError refuses a request that conflicts with the state of other
+// resources, with 409 Conflict.
+type conflictError struct {
+	msg string
+}
+
+func (e *conflictError) Error() string { return e.msg }
+
+// resource is satisfied by pointers to model resources.
+type resource[T any] interface {
+	*T
+	Meta() *model.ObjectMeta
+	SetDefaults()
+	Validate() error
+}
+
+// resourceHandler serves the standard list, create, get and delete
+// endpoints for a resource stored in a single bucket. Namespaced resources
+// are mounted below /namespaces/{namespace} and keyed by namespace and
+// name; cluster-wide ones are keyed by name.
+type resourceHandler[T any, P resource[T]] struct {
+	store  store.Store
+	logger zerolog.Logger
+
+	// namespaces admits namespaced resources into their namespace. It is
+	// nil for cluster-wide resources.
+	namespaces *namespace.Registry
+
+	// bucket is the store bucket holding the resource.
+	bucket string
+
+	// kind names the resource in error messages, e.g. "container".
+	kind string
+
+	// prepare, if set, runs on a decoded resource before defaults and
+	// validation are applied on create. It typically resets server-owned
+	// fields such as status.
+	prepare func(P)
+
+	// apply, if set, copies the updatable fields of a decoded resource onto
+	// the stored one, and enables PUT /{name}.
+	apply func(cur, in P)
+
+	// redact, if set, strips what the API never returns from a resource
+	// before delete or finalizers respond with it.
+	redact func(P)
+
+	// checkDelete, if set, runs before the resource named in the request
+	// is deleted, and refuses the deletion with the *conflictError it
+	// returns. For namespaced resources the check and the deletion run
+	// with admissions held off, so that nothing admitted in between can
+	// invalidate the check.
+	checkDelete func(r *http.Request) error
+}
+
+// routes mounts the standard endpoints.
+func (h *resourceHandler[T, P]) routes(r chi.Router) {
+	r.Get("/", h.list)
+	r.Post("/", h.create)
+	r.Get("/{name}", h.get)
+	r.Delete("/{name}", h.delete)
+	r.Put("/{name}/finalizers", h.finalizers)
+	if h.apply != nil {
+		r.Put("/{name}", h.update)
+	}
+}
+
+func (h *resourceHandler[T, P]) list(w http.ResponseWriter, r *http.Request) {
+	page, perPage, err := pagination(r)
+	if err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeBadRequest)
+		return
+	}
+	q, err := parseListQuery(r)
+	if err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeBadRequest)
+		return
+	}
+
+	items, err := listItems[T](h.store, h.bucket, prefix(r), q)
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	Paginated(w, paginate(items, page, perPage), len(items), page, perPage)
+}
+
+func (h *resourceHandler[T, P]) create(w http.ResponseWriter, r *http.Request) {
+	v := P(new(T))
+	if err := decodeJSON(r, v); err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeBadRequest)
+		return
+	}
+
+	if !scope(w, r, v.Meta()) {
+		return
+	}
+	if h.prepare != nil {
+		h.prepare(v)
+	}
+	v.Meta().DeletionTimestamp = nil
+	v.SetDefaults()
+	if err := v.Validate(); err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeValidationFailed)
+		return
+	}
+	if err := v.Meta().ValidateOwnerReferences(); err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeValidationFailed)
+		return
+	}
+	if err := v.Meta().ValidateFinalizers(); err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeValidationFailed)
+		return
+	}
+	if err := v.Meta().Initialize(); err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	meta := P(v).Meta()
+	err := h.admit(v, func() error {
+		return store.CreateJSON(h.store, h.bucket, meta.Key(), v)
+	})
+	if errors.Is(err, store.ErrAlreadyExists) {
+		Error(w, http.StatusConflict, h.kind+" "+meta.Name+" already exists", CodeAlreadyExists)
+		return
+	}
+	if err != nil {
+		h.respond(w, meta.Name, nil, err)
+		return
+	}
+
+	JSON(w, http.StatusCreated, v)
+}
+
+func (h *resourceHandler[T, P]) get(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	v, err := store.GetJSON[T](h.store, h.bucket, key(r))
+	if store.IsNotFound(err) {
+		h.notFound(w, name)
+		return
+	}
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, v)
+}
+
+// update replaces the updatable fields of a resource, as chosen by apply.
+func (h *resourceHandler[T, P]) update(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	v := P(new(T))
+	if err := decodeJSON(r, v); err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeBadRequest)
+		return
+	}
+	meta := P(v).Meta()
+	if meta.Name == "" {
+		meta.Name = name
+	}
+	if meta.Name != name {
+		Error(w, http.StatusBadRequest, "metadata.name does not match the URL", CodeBadRequest)
+		return
+	}
+	if !scope(w, r, meta) {
+		return
+	}
+
+	v.SetDefaults()
+	if err := v.Validate(); err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeValidationFailed)
+		return
+	}
+
+	// The quota is checked against the resource as it will be stored.
+	cur, err := store.GetJSON[T](h.store, h.bucket, meta.Key())
+	if err != nil {
+		h.respond(w, name, nil, err)
+		return
+	}
+	h.apply(cur, v)
+
+	var updated P
+	err = h.admit(cur, func() error {
+		var err error
+		updated, err = store.UpdateJSON(h.store, h.bucket, meta.Key(), func(cur *T) error {
+			h.apply(cur, v)
+			return nil
+		})
+		return err
+	})
+	h.respond(w, name, updated, err)
+}
+
+// delete deletes a resource. One without finalizers is removed at once;
+// one with finalizers is marked as terminating and returned with 202
+// Accepted, and is removed once its finalizers have been cleared.
+//
+// The propagation_policy parameter says what becomes of the dependents of
+// a namespaced resource: with Background, the default, the garbage
+// collector deletes them once the resource is gone; Foreground and Orphan
+// hold the resource with a finalizer of the collector until it has
+// deleted or orphaned them. Cluster-wide resources have no dependents.
+func (h *resourceHandler[T, P]) delete(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	policy, err := model.ParsePropagationPolicy(r.URL.Query().Get("propagation_policy"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeBadRequest)
+		return
+	}
+	if h.namespaces == nil {
+		policy = model.PropagationBackground
+	}
+
+	// A resource already terminating keeps the finalizers it was given.
+	now := time.Now().UTC()
+	var v P
+	err = h.exclusive(func() error {
+		if h.checkDelete != nil {
+			if err := h.checkDelete(r); err != nil {
+				return err
+			}
+		}
+		var err error
+		v, err = store.UpdateJSON(h.store, h.bucket, key(r), func(cur *T) error {
+			meta := P(cur).Meta()
+			if meta.IsTerminating() {
+				return nil
+			}
+			if f := policy.Finalizer(); f != "" {
+				meta.Finalizers = append(meta.Finalizers, f)
+			}
+			if len(meta.Finalizers) == 0 {
+				return errNoFinalizers
+			}
+			meta.DeletionTimestamp = &now
+			return nil
+		})
+		if errors.Is(err, errNoFinalizers) {
+			v = nil
+			err = h.store.Delete(h.bucket, key(r))
+		}
+		return err
+	})
+	if err == nil && v == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err == nil && h.redact != nil {
+		h.redact(v)
+	}
+	var cerr *conflictError
+	if errors.As(err, &cerr) {
+		Error(w, http.StatusConflict, err.Error(), CodeConflict)
+		return
+	}
+	if store.IsNotFound(err) {
+		h.notFound(w, name)
+		return
+	}
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	JSON(w, http.StatusAccepted, v)
+}
+
+// finalizersRequest is the body of PUT /{name}/finalizers.
+type finalizersRequest struct {
+	Finalizers []string `json:"finalizers"`
+}
+
+// finalizers replaces the finalizers of a resource, as the controllers
+// responsible for them do once their cleanup is done. A terminating
+// resource may only lose finalizers, and is removed when it has none
+// left.
+func (h *resourceHandler[T, P]) finalizers(w http.ResponseWriter, r *http.Request) {
+	h.replaceFinalizers(w, r, chi.URLParam(r, "name"), key(r))
+}
+
+// replaceFinalizers serves finalizers for the resource named name and
+// stored under key.
+func (h *resourceHandler[T, P]) replaceFinalizers(w http.ResponseWriter, r *http.Request, name, key string) {
+	var req finalizersRequest
+	if err := decodeJSON(r, &req); err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeBadRequest)
+		return
+	}
+	if err := (&model.ObjectMeta{Finalizers: req.Finalizers}).ValidateFinalizers(); err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeValidationFailed)
+		return
+	}
+
+	v, err := store.UpdateJSON(h.store, h.bucket, key, func(cur *T) error {
+		meta := P(cur).Meta()
+		if meta.IsTerminating() {
+			for _, f := range req.Finalizers {
+				if !meta.HasFinalizer(f) {
+					return errFinalizerAdded
+				}
+			}
+		}
+		meta.Finalizers = req.Finalizers
+		return nil
+	})
+	switch {
+	case errors.Is(err, errFinalizerAdded):
+		Error(w, http.StatusConflict, h.kind+" "+name+" is being deleted; finalizers can only be removed", CodeConflict)
+		return
+	case store.IsNotFound(err):
+		h.notFound(w, name)
+		return
+	case err != nil:
+		h.internalError(w, err)
+		return
+	}
+
+	meta := P(v).Meta()
+	if meta.IsTerminating() && len(meta.Finalizers) == 0 {
+		if err := h.store.Delete(h.bucket, meta.Key()); err != nil && !store.IsNotFound(err) {
+			h.internalError(w, err)
+			return
+		}
+	}
+	if h.redact != nil {
+		h.redact(v)
+	}
+	JSON(w, http.StatusOK, v)
+}
+
+// admit runs write, which stores v, once the namespace registry admits v.
+// Cluster-wide resources are written directly.
+func (h *resourceHandler[T, P]) admit(v P, write func() error) error {
+	if h.namespaces == nil {
+		return write()
+	}
+	return h.namespaces.Admit(h.bucket, v, write)
+}
+
+// exclusive runs fn with admissions into namespaces held off. Cluster-wide
+// resources run fn directly.
+func (h *resourceHandler[T, P]) exclusive(fn func() error) error {
+	if h.namespaces == nil {
+		return fn()
+	}
+	return h.namespaces.Exclusive(fn)
+}
+
+// respond writes the result of an update.
+func (h *resourceHandler[T, P]) respond(w http.ResponseWriter, name string, v P, err error) {
+	var verr *model.ValidationError
+	var qerr *namespace.ExceededError
+	switch {
+	case errors.As(err, &verr):
+		Error(w, http.StatusBadRequest, err.Error(), CodeValidationFailed)
+	case errors.As(err, &qerr):
+		Error(w, http.StatusForbidden, err.Error(), CodeQuotaExceeded)
+	case errors.Is(err, namespace.ErrNotFound):
+		Error(w, http.StatusNotFound, err.Error(), CodeNotFound)
+	case errors.Is(err, namespace.ErrTerminating):
+		Error(w, http.StatusConflict, err.Error(), CodeConflict)
+	case store.IsNotFound(err):
+		h.notFound(w, name)
+	case err != nil:
+		h.internalError(w, err)
+	default:
+		JSON(w, http.StatusOK, v)
+	}
+}
+
+func (h *resourceHandler[T, P]) notFound(w http.ResponseWriter, name string) {
+	Error(w, http.StatusNotFound, h.kind+" "+name+" not found", CodeNotFound)
+}
+
+func (h *resourceHandler[T, P]) internalError(w http.ResponseWriter, err error) {
+	h.logger.Error().Err(err).Str("kind", h.kind).Msg("request failed")
+	Error(w, http.StatusInternalServerError, "internal error", CodeInternal)
+}
+
+// key returns the store key of the resource named in the request URL.
+func key(r *http.Request) string {
+	return model.Key(chi.URLParam(r, "namespace"), chi.URLParam(r, "name"))
+}
+
+// prefix returns the key prefix of the resources listed by the request:
+// those of the namespace in the URL, if any.
+func prefix(r *http.Request) string {
+	return model.NamespacePrefix(chi.URLParam(r, "namespace"))
+}
+
+// scope places a decoded resource in the namespace of the request URL. It
+// writes an error response and returns false if the resource names a
+// different namespace.
+func scope(w http.ResponseWriter, r *http.Request, meta *model.ObjectMeta) bool {
+	ns := chi.URLParam(r, "namespace")
+	if meta.Namespace != "" && meta.Namespace != ns {
+		Error(w, http.StatusBadRequest, "metadata.namespace does not match the URL", CodeBadRequest)
+		return false
+	}
+	meta.Namespace = ns
+	return true
+}
@@ -4,10 +4,37 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 )
 
+// Standard error codes returned in ErrorResponse.Code.
+const (
+	CodeUnauthorized     = "UNAUTHORIZED"
+	CodeForbidden        = "FORBIDDEN"
+	CodeBadRequest       = "BAD_REQUEST"
+	CodeValidationFailed = "VALIDATION_FAILED"
+	CodeNotFound         = "NOT_FOUND"
+	CodeAlreadyExists    = "ALREADY_EXISTS"
+	CodeConflict         = "CONFLICT"
+	CodeQuotaExceeded    = "QUOTA_EXCEEDED"
+	CodeDisruptionBudget = "DISRUPTION_BUDGET_VIOLATED"
+	CodeInternal         = "INTERNAL"
+)
+
+// Pagination defaults for list endpoints.
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// maxRequestBodySize bounds request bodies. It leaves room for the largest
+// secret or config map after JSON encoding.
+const maxRequestBodySize = 4 << 20
+
 // ErrorResponse is the standard error response body.
 type ErrorResponse struct {
 	Error string `json:"error"`
@@ -57,6 +84,52 @@ func Paginated(w http.ResponseWriter, items any, total, page, perPage int) {
 	})
 }
 
+// pagination parses the page and per_page query parameters, applying
+// defaults and clamping per_page to maxPerPage.
+func pagination(r *http.Request) (page, perPage int, err error) {
+	page, perPage = 1, defaultPerPage
+
+	if v := r.URL.Query().Get("page"); v != "" {
+		page, err = strconv.Atoi(v)
+		if err != nil || page < 1 {
+			return 0, 0, fmt.Errorf("page must be a positive integer, got %q", v)
+		}
+	}
+	if v := r.URL.Query().Get("per_page"); v != "" {
+		perPage, err = strconv.Atoi(v)
+		if err != nil || perPage < 1 {
+			return 0, 0, fmt.Errorf("per_page must be a positive integer, got %q", v)
+		}
+	}
+	perPage = min(perPage, maxPerPage)
+
+	return page, perPage, nil
+}
+
+// paginate returns the slice of items on the given page.
+func paginate[T any](items []T, page, perPage int) []T {
+	start := (page - 1) * perPage
+	if start >= len(items) {
+		return []T{}
+	}
+	end := min(start+perPage, len(items))
+	return items[start:end]
+}
+
+// decodeJSON decodes a request body into v, rejecting unknown fields.
+func decodeJSON(r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxRequestBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return fmt.Errorf("request body exceeds %d bytes", tooLarge.Limit)
+		}
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
 // writeBody writes bytes to the response writer, logging on failure.
 func writeBody(w http.ResponseWriter, b []byte) {
 	if _, err := w.Write(b); err != nil {
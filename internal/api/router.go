@@ -11,19 +11,42 @@ import (
 	"github.com/rs/zerolog"
 
 	"github.com/github-builder/container-orchestrator/internal/api/handlers"
+	"github.com/github-builder/container-orchestrator/internal/events"
+	"github.com/github-builder/container-orchestrator/internal/eviction"
+	"github.com/github-builder/container-orchestrator/internal/namespace"
+	"github.com/github-builder/container-orchestrator/internal/node"
+	"github.com/github-builder/container-orchestrator/internal/secret"
 	"github.com/github-builder/container-orchestrator/internal/store"
 )
 
 // RouterConfig holds dependencies for constructing the API router.
 type RouterConfig struct {
 	Store        store.Store
+	Nodes        *node.Registry
+	Namespaces   *namespace.Registry
+	Evictor      *eviction.Evictor
 	Logger       zerolog.Logger
 	DashboardURL string
 	APIKey       string `json:"-"` //nolint:gosec // Not a hardcoded credential, populated from env.
+
+	// Secrets seals secret values before they are stored.
+	Secrets *secret.Cipher
+
+	// SecretReaderKey must be presented to read secret values. Values
+	// cannot be read through the API when it is empty.
+	SecretReaderKey string `json:"-"` //nolint:gosec // Not a hardcoded credential, populated from env.
+
+	// Events is watched for the events it records. Defaults to a recorder
+	// on Store, which only sees events recorded through it.
+	Events *events.Recorder
 }
 
 // NewRouter creates a Chi router with middleware and all API routes mounted.
 func NewRouter(cfg *RouterConfig) http.Handler {
+	if cfg.Events == nil {
+		cfg.Events = events.NewRecorder(&events.Config{Store: cfg.Store, Logger: cfg.Logger})
+	}
+
 	r := chi.NewRouter()
 
 	// Middleware chain.
@@ -34,7 +57,7 @@ func NewRouter(cfg *RouterConfig) http.Handler {
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{cfg.DashboardURL},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-API-Key", "X-Request-ID"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-API-Key", "X-Request-ID", "X-Secret-Reader-Key"},
 		ExposedHeaders:   []string{"X-Request-ID"},
 		AllowCredentials: false,
 		MaxAge:           300,
@@ -46,7 +69,28 @@ func NewRouter(cfg *RouterConfig) http.Handler {
 	// API v1 routes — auth required.
 	r.Route("/api/v1", func(r chi.Router) {
 		r.Use(apiKeyAuth(cfg.APIKey))
-		// Future route groups will be mounted here.
+
+		r.Route("/nodes", newNodeHandler(cfg).routes)
+		r.Route("/priorityclasses", newPriorityClassHandler(cfg).routes)
+		r.Route("/events", newEventHandler(cfg).routes)
+
+		r.Route("/namespaces", func(r chi.Router) {
+			newNamespaceHandler(cfg).routes(r, func(r chi.Router) {
+				r.Route("/containers", newContainerHandler(cfg).routes)
+				r.Route("/deployments", newDeploymentHandler(cfg).routes)
+				r.Route("/daemonsets", newDaemonSetHandler(cfg).routes)
+				r.Route("/statefulsets", newStatefulSetHandler(cfg).routes)
+				r.Route("/groups", newGroupHandler(cfg).routes)
+				r.Route("/jobs", newJobHandler(cfg).routes)
+				r.Route("/cronjobs", newCronJobHandler(cfg).routes)
+				r.Route("/secrets", newSecretHandler(cfg).routes)
+				r.Route("/configmaps", newConfigMapHandler(cfg).routes)
+				r.Route("/volumes", newVolumeHandler(cfg).routes)
+				r.Route("/disruptionbudgets", newDisruptionBudgetHandler(cfg).routes)
+				r.Route("/autoscalers", newAutoscalerHandler(cfg).routes)
+				r.Route("/events", newEventHandler(cfg).routes)
+			})
+		})
 	})
 
 	return r
@@ -81,7 +125,7 @@ func apiKeyAuth(apiKey string) func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			key := r.Header.Get("X-API-Key")
 			if key == "" || subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) != 1 {
-				Error(w, http.StatusUnauthorized, "invalid or missing API key", "UNAUTHORIZED")
+				Error(w, http.StatusUnauthorized, "invalid or missing API key", CodeUnauthorized)
 				return
 			}
 			next.ServeHTTP(w, r)
@@ -1,26 +1,59 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/github-builder/container-orchestrator/internal/api/handlers"
+	"github.com/github-builder/container-orchestrator/internal/eviction"
+	"github.com/github-builder/container-orchestrator/internal/namespace"
+	"github.com/github-builder/container-orchestrator/internal/node"
+	"github.com/github-builder/container-orchestrator/internal/secret"
 	"github.com/github-builder/container-orchestrator/internal/store"
 )
 
 func newTestRouter() http.Handler {
+	router, _ := newTestRouterWithStore()
+	return router
+}
+
+// newTestRouterWithStore returns a router and the store behind it, for
+// tests that also run controllers.
+func newTestRouterWithStore() (http.Handler, store.Store) {
+	s := store.NewMemoryStore()
+	secrets, err := secret.NewCipher(bytes.Repeat([]byte{1}, secret.KeySize))
+	if err != nil {
+		panic(err)
+	}
+	namespaces := namespace.NewRegistry(&namespace.Config{Store: s, Logger: zerolog.Nop()})
+	if err := namespaces.EnsureDefault(); err != nil {
+		panic(err)
+	}
 	return NewRouter(&RouterConfig{
-		Store:        store.NewMemoryStore(),
+		Store:      s,
+		Namespaces: namespaces,
+		Evictor:    eviction.New(&eviction.Config{Store: s, Logger: zerolog.Nop()}),
+		Nodes: node.NewRegistry(&node.Config{
+			Store:             s,
+			Logger:            zerolog.Nop(),
+			HeartbeatInterval: 10 * time.Second,
+			HeartbeatTimeout:  30 * time.Second,
+		}),
 		Logger:       zerolog.Nop(),
 		DashboardURL: "http://localhost:3000",
 		APIKey:       "test-api-key",
-	})
+
+		Secrets:         secrets,
+		SecretReaderKey: "test-reader-key",
+	}), s
 }
 
 func TestHealthEndpoint(t *testing.T) {
@@ -0,0 +1,236 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"maps"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/secret"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// secretHandler serves the /secrets endpoints. Values are sealed before they
+// are stored and redacted from responses unless the request sets
+// reveal=true and carries the secret reader key in X-Secret-Reader-Key.
+type secretHandler struct {
+	*resourceHandler[model.Secret, *model.Secret]
+	cipher    *secret.Cipher
+	readerKey string
+}
+
+// newSecretHandler creates the /secrets handler.
+func newSecretHandler(cfg *RouterConfig) *secretHandler {
+	return &secretHandler{
+		resourceHandler: &resourceHandler[model.Secret, *model.Secret]{
+			store:      cfg.Store,
+			logger:     cfg.Logger,
+			namespaces: cfg.Namespaces,
+			bucket:     model.SecretsBucket,
+			kind:       "secret",
+			redact:     (*model.Secret).Redact,
+		},
+		cipher:    cfg.Secrets,
+		readerKey: cfg.SecretReaderKey,
+	}
+}
+
+// routes mounts the secret endpoints.
+func (h *secretHandler) routes(r chi.Router) {
+	r.Get("/", h.list)
+	r.Post("/", h.create)
+	r.Get("/{name}", h.get)
+	r.Put("/{name}", h.update)
+	r.Delete("/{name}", h.delete)
+	r.Put("/{name}/finalizers", h.finalizers)
+}
+
+func (h *secretHandler) list(w http.ResponseWriter, r *http.Request) {
+	reveal, ok := h.reveal(w, r)
+	if !ok {
+		return
+	}
+	page, perPage, err := pagination(r)
+	if err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeBadRequest)
+		return
+	}
+	q, err := parseListQuery(r)
+	if err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeBadRequest)
+		return
+	}
+
+	items, err := listItems[model.Secret](h.store, h.bucket, prefix(r), q)
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	out := paginate(items, page, perPage)
+	for _, s := range out {
+		if err := h.present(s, reveal); err != nil {
+			h.internalError(w, err)
+			return
+		}
+	}
+	Paginated(w, out, len(items), page, perPage)
+}
+
+func (h *secretHandler) create(w http.ResponseWriter, r *http.Request) {
+	var s model.Secret
+	if err := decodeJSON(r, &s); err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeBadRequest)
+		return
+	}
+
+	if !scope(w, r, &s.ObjectMeta) {
+		return
+	}
+	s.Sealed = nil
+	s.Version = 1
+	s.DeletionTimestamp = nil
+	s.SetDefaults()
+	if err := s.Validate(); err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeValidationFailed)
+		return
+	}
+	if err := s.ValidateFinalizers(); err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeValidationFailed)
+		return
+	}
+	if err := s.Initialize(); err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	data := s.Data
+	if err := h.cipher.Seal(&s); err != nil {
+		h.internalError(w, err)
+		return
+	}
+	err := h.admit(&s, func() error {
+		return store.CreateJSON(h.store, h.bucket, s.Key(), &s)
+	})
+	if errors.Is(err, store.ErrAlreadyExists) {
+		Error(w, http.StatusConflict, "secret "+s.Name+" already exists", CodeAlreadyExists)
+		return
+	}
+	if err != nil {
+		h.respond(w, s.Name, nil, err)
+		return
+	}
+
+	s.Data = data
+	s.Redact()
+	JSON(w, http.StatusCreated, &s)
+}
+
+func (h *secretHandler) get(w http.ResponseWriter, r *http.Request) {
+	reveal, ok := h.reveal(w, r)
+	if !ok {
+		return
+	}
+	name := chi.URLParam(r, "name")
+
+	s, err := store.GetJSON[model.Secret](h.store, h.bucket, key(r))
+	if store.IsNotFound(err) {
+		h.notFound(w, name)
+		return
+	}
+	if err == nil {
+		err = h.present(s, reveal)
+	}
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, s)
+}
+
+// update replaces a secret's labels, annotations and data. The version
+// only moves when the data changed, so relabeling does not roll containers.
+func (h *secretHandler) update(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	var in model.Secret
+	if err := decodeJSON(r, &in); err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeBadRequest)
+		return
+	}
+	if in.Name == "" {
+		in.Name = name
+	}
+	if in.Name != name {
+		Error(w, http.StatusBadRequest, "metadata.name does not match the URL", CodeBadRequest)
+		return
+	}
+	if !scope(w, r, &in.ObjectMeta) {
+		return
+	}
+	in.SetDefaults()
+	if err := in.Validate(); err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), CodeValidationFailed)
+		return
+	}
+
+	updated, err := store.UpdateJSON(h.store, h.bucket, in.Key(), func(cur *model.Secret) error {
+		if err := h.cipher.Open(cur); err != nil {
+			return err
+		}
+		if !maps.Equal(cur.Data, in.Data) {
+			cur.Version++
+		}
+		cur.Labels = in.Labels
+		cur.Annotations = in.Annotations
+		cur.Data = in.Data
+		return h.cipher.Seal(cur)
+	})
+	if err == nil {
+		updated.Data = in.Data
+		updated.Redact()
+	}
+	h.respond(w, name, updated, err)
+}
+
+// reveal reports whether the request asked for secret values and may read
+// them. It writes an error response and returns ok=false otherwise.
+func (h *secretHandler) reveal(w http.ResponseWriter, r *http.Request) (reveal, ok bool) {
+	v := r.URL.Query().Get("reveal")
+	if v == "" {
+		return false, true
+	}
+	reveal, err := strconv.ParseBool(v)
+	if err != nil {
+		Error(w, http.StatusBadRequest, "reveal must be a boolean", CodeBadRequest)
+		return false, false
+	}
+	if !reveal {
+		return false, true
+	}
+
+	key := r.Header.Get("X-Secret-Reader-Key")
+	if h.readerKey == "" || key == "" || subtle.ConstantTimeCompare([]byte(key), []byte(h.readerKey)) != 1 {
+		Error(w, http.StatusForbidden, "reading secret values requires the secret reader key", CodeForbidden)
+		return false, false
+	}
+	return true, true
+}
+
+// present opens a stored secret for a response, redacting its values unless
+// reveal is set.
+func (h *secretHandler) present(s *model.Secret, reveal bool) error {
+	if err := h.cipher.Open(s); err != nil {
+		return err
+	}
+	s.Sealed = nil
+	if !reveal {
+		s.Redact()
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// readSecret fetches a secret, revealing values with the given reader key.
+func readSecret(t *testing.T, router http.Handler, path, readerKey string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("X-API-Key", "test-api-key")
+	if readerKey != "" {
+		req.Header.Set("X-Secret-Reader-Key", readerKey)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSecrets_StoredEncryptedAndRedacted(t *testing.T) {
+	router, s := newTestRouterWithStore()
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/secrets", `{
+		"metadata": {"name": "db"},
+		"data": {"password": "hunter2", "user": "app"},
+		"version": 7
+	}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.NotContains(t, rec.Body.String(), "hunter2")
+
+	var sec model.Secret
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&sec))
+	assert.Equal(t, map[string]string{"password": model.RedactedValue, "user": model.RedactedValue}, sec.Data)
+	assert.Equal(t, int64(1), sec.Version)
+
+	stored, err := store.GetJSON[model.Secret](s, model.SecretsBucket, model.Key(model.DefaultNamespace, "db"))
+	require.NoError(t, err)
+	assert.Empty(t, stored.Data)
+	assert.NotContains(t, string(stored.Sealed), "hunter2")
+
+	for _, path := range []string{"/api/v1/namespaces/default/secrets/db", "/api/v1/namespaces/default/secrets"} {
+		rec = readSecret(t, router, path, "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "hunter2")
+		assert.NotContains(t, rec.Body.String(), "sealed")
+		assert.Contains(t, rec.Body.String(), model.RedactedValue)
+	}
+}
+
+func TestSecrets_Reveal(t *testing.T) {
+	router := newTestRouter()
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/secrets", `{
+		"metadata": {"name": "db"},
+		"data": {"password": "hunter2"}
+	}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = readSecret(t, router, "/api/v1/namespaces/default/secrets/db?reveal=true", "test-reader-key")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var sec model.Secret
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&sec))
+	assert.Equal(t, map[string]string{"password": "hunter2"}, sec.Data)
+	assert.Empty(t, sec.Sealed)
+
+	rec = readSecret(t, router, "/api/v1/namespaces/default/secrets?reveal=true", "test-reader-key")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "hunter2")
+
+	for _, key := range []string{"", "wrong"} {
+		rec = readSecret(t, router, "/api/v1/namespaces/default/secrets/db?reveal=true", key)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, CodeForbidden, decodeError(t, rec).Code)
+	}
+
+	rec = readSecret(t, router, "/api/v1/namespaces/default/secrets/db?reveal=maybe", "test-reader-key")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestSecrets_UpdateBumpsVersion(t *testing.T) {
+	router, s := newTestRouterWithStore()
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/secrets", `{
+		"metadata": {"name": "db"},
+		"data": {"password": "hunter2"}
+	}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	version := func() int64 {
+		sec, err := store.GetJSON[model.Secret](s, model.SecretsBucket, model.Key(model.DefaultNamespace, "db"))
+		require.NoError(t, err)
+		return sec.Version
+	}
+
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/default/secrets/db", `{
+		"metadata": {"labels": {"team": "data"}},
+		"data": {"password": "hunter2"}
+	}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, int64(1), version())
+
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/default/secrets/db", `{"data": {"password": "correct-horse"}}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.NotContains(t, rec.Body.String(), "correct-horse")
+	assert.Equal(t, int64(2), version())
+
+	rec = readSecret(t, router, "/api/v1/namespaces/default/secrets/db?reveal=1", "test-reader-key")
+	assert.Contains(t, rec.Body.String(), "correct-horse")
+
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/default/secrets/db", `{"data": {"bad/key": "x"}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, CodeValidationFailed, decodeError(t, rec).Code)
+
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/default/secrets/missing", `{"data": {"k": "v"}}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = doRequest(t, router, http.MethodDelete, "/api/v1/namespaces/default/secrets/db", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+// newStatefulSetHandler serves the /statefulsets endpoints.
+func newStatefulSetHandler(cfg *RouterConfig) *resourceHandler[model.StatefulSet, *model.StatefulSet] {
+	return &resourceHandler[model.StatefulSet, *model.StatefulSet]{
+		store:      cfg.Store,
+		logger:     cfg.Logger,
+		namespaces: cfg.Namespaces,
+		bucket:     model.StatefulSetsBucket,
+		kind:       "stateful set",
+		prepare: func(s *model.StatefulSet) {
+			// Status is owned by the stateful set controller.
+			s.Status = model.StatefulSetStatus{}
+		},
+		// Scaling and template changes are applied in ordinal order; the
+		// revisions in the status are kept so the partition holds.
+		apply: func(cur, in *model.StatefulSet) {
+			cur.Labels = in.Labels
+			cur.Annotations = in.Annotations
+			cur.Spec = in.Spec
+		},
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+func TestStatefulSets_CreateAndUpdate(t *testing.T) {
+	router := newTestRouter()
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/statefulsets", `{
+		"metadata": {"name": "db"},
+		"spec": {
+			"replicas": 3,
+			"template": {"spec": {"image": "postgres:15"}},
+			"volume_claim_templates": [{"name": "data", "mount_path": "/var/lib/db"}]
+		},
+		"status": {"current_revision": "bogus"}
+	}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var set model.StatefulSet
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&set))
+	assert.Equal(t, model.StatefulSetRollingUpdate, set.Spec.UpdateStrategy.Type)
+	assert.Empty(t, set.Status.CurrentRevision)
+
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/default/statefulsets/db", `{
+		"spec": {
+			"replicas": 3,
+			"template": {"spec": {"image": "postgres:16"}},
+			"volume_claim_templates": [{"name": "data", "mount_path": "/var/lib/db"}],
+			"update_strategy": {"partition": 2}
+		}
+	}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&set))
+	assert.Equal(t, 2, set.Spec.UpdateStrategy.Partition)
+
+	rec = doRequest(t, router, http.MethodPut, "/api/v1/namespaces/default/statefulsets/db", `{
+		"spec": {"replicas": 3, "template": {"spec": {"image": "postgres:16"}}, "update_strategy": {"partition": -1}}
+	}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, CodeValidationFailed, decodeError(t, rec).Code)
+}
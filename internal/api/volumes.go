@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// volumeHandler serves the /volumes endpoints. Volumes mounted by
+// containers that have not finished, or are still being deleted, cannot
+// be deleted.
+type volumeHandler struct {
+	*resourceHandler[model.Volume, *model.Volume]
+}
+
+// newVolumeHandler creates the /volumes handler.
+func newVolumeHandler(cfg *RouterConfig) *volumeHandler {
+	h := &volumeHandler{
+		resourceHandler: &resourceHandler[model.Volume, *model.Volume]{
+			store:      cfg.Store,
+			logger:     cfg.Logger,
+			namespaces: cfg.Namespaces,
+			bucket:     model.VolumesBucket,
+			kind:       "volume",
+			prepare: func(v *model.Volume) {
+				// Status is owned by the scheduler and the agent.
+				v.Status = model.VolumeStatus{}
+			},
+			// Where and how the data is stored cannot change.
+			apply: func(cur, in *model.Volume) {
+				cur.Labels = in.Labels
+				cur.Annotations = in.Annotations
+				cur.Spec.CapacityBytes = in.Spec.CapacityBytes
+			},
+		},
+	}
+	h.checkDelete = h.inUse
+	return h
+}
+
+// routes mounts the volume endpoints.
+func (h *volumeHandler) routes(r chi.Router) {
+	r.Get("/", h.list)
+	r.Post("/", h.create)
+	r.Get("/{name}", h.get)
+	r.Put("/{name}", h.update)
+	r.Delete("/{name}", h.delete)
+	r.Put("/{name}/finalizers", h.finalizers)
+}
+
+// inUse refuses to delete a volume that containers use.
+func (h *volumeHandler) inUse(r *http.Request) error {
+	name := chi.URLParam(r, "name")
+	users, err := h.users(chi.URLParam(r, "namespace"), name)
+	if err != nil {
+		return err
+	}
+	if len(users) > 0 {
+		return &conflictError{"volume " + name + " is in use by containers " + strings.Join(users, ", ")}
+	}
+	return nil
+}
+
+// users returns the containers that mount the volume and have not finished,
+// or are being deleted and may not have stopped yet. Containers only mount
+// volumes of their own namespace.
+func (h *volumeHandler) users(namespace, name string) ([]string, error) {
+	containers, err := store.ListJSON[model.Container](h.store, model.ContainersBucket, model.NamespacePrefix(namespace))
+	if err != nil {
+		return nil, err
+	}
+	var users []string
+	for _, c := range containers {
+		if c.Status.IsTerminal() && !c.IsTerminating() {
+			continue
+		}
+		for _, vm := range c.Spec.VolumeMounts {
+			if vm.Name == name {
+				users = append(users, c.Name)
+				break
+			}
+		}
+	}
+	sort.Strings(users)
+	return users, nil
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+func TestVolumes_Create(t *testing.T) {
+	router := newTestRouter()
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/volumes", `{
+		"metadata": {"name": "data"},
+		"spec": {"capacity_bytes": 1048576},
+		"status": {"node_name": "node-9"}
+	}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var v model.Volume
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&v))
+	assert.Equal(t, model.VolumeDriverLocal, v.Spec.Driver)
+	assert.Empty(t, v.Status.NodeName)
+
+	// Host paths live on a specific node.
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/volumes", `{
+		"metadata": {"name": "host"},
+		"spec": {"driver": "hostpath", "host_path": "/srv/data"}
+	}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, CodeValidationFailed, decodeError(t, rec).Code)
+
+	rec = doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/volumes", `{
+		"metadata": {"name": "host"},
+		"spec": {"driver": "hostpath", "host_path": "/srv/data", "node_name": "node-1"}
+	}`)
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+}
+
+func TestVolumes_DeleteInUse(t *testing.T) {
+	router, s := newTestRouterWithStore()
+
+	rec := doRequest(t, router, http.MethodPost, "/api/v1/namespaces/default/volumes", `{"metadata": {"name": "data"}}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	mounting := func(ns, name string, phase model.ContainerPhase) *model.Container {
+		return &model.Container{
+			ObjectMeta: model.ObjectMeta{Name: name, Namespace: ns},
+			Spec: model.ContainerSpec{
+				Image:        "db",
+				VolumeMounts: []model.VolumeMount{{Name: "data", MountPath: "/data"}},
+			},
+			Status: model.ContainerStatus{Phase: phase},
+		}
+	}
+	// Only unfinished containers of the volume's namespace use it.
+	for _, c := range []*model.Container{
+		mounting("default", "db", model.PhaseRunning),
+		mounting("default", "old", model.PhaseSucceeded),
+		mounting("other", "db", model.PhaseRunning),
+	} {
+		require.NoError(t, store.PutJSON(s, model.ContainersBucket, c.Key(), c))
+	}
+
+	rec = doRequest(t, router, http.MethodDelete, "/api/v1/namespaces/default/volumes/data", "")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	resp := decodeError(t, rec)
+	assert.Equal(t, CodeConflict, resp.Code)
+	assert.Equal(t, "volume data is in use by containers db", resp.Error)
+
+	// A finished container being deleted may still hold the volume.
+	require.NoError(t, s.Delete(model.ContainersBucket, model.Key(model.DefaultNamespace, "db")))
+	stopping := mounting("default", "stopping", model.PhaseSucceeded)
+	now := time.Now().UTC()
+	stopping.Finalizers = []string{model.FinalizerRuntimeCleanup}
+	stopping.DeletionTimestamp = &now
+	require.NoError(t, store.PutJSON(s, model.ContainersBucket, stopping.Key(), stopping))
+	rec = doRequest(t, router, http.MethodDelete, "/api/v1/namespaces/default/volumes/data", "")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "volume data is in use by containers stopping", decodeError(t, rec).Error)
+
+	require.NoError(t, s.Delete(model.ContainersBucket, stopping.Key()))
+	rec = doRequest(t, router, http.MethodDelete, "/api/v1/namespaces/default/volumes/data", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = doRequest(t, router, http.MethodDelete, "/api/v1/namespaces/default/volumes/data", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
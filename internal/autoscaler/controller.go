@@ -0,0 +1,303 @@
+// Package autoscaler implements the autoscaler controller. Each pass it
+// measures the metrics of every autoscaler over the running replicas of its
+// deployment, from the usage the agents record in their status, and sets
+// the deployment's replica count to what the metrics call for. The count
+// stays between the autoscaler's bounds, and moves only as far as its
+// stabilization windows and rate limits and the namespace's quota allow. Every change is recorded in
+// the autoscaler's status, with the metric values behind it, and as an
+// event.
+package autoscaler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/github-builder/container-orchestrator/internal/events"
+	"github.com/github-builder/container-orchestrator/internal/gc"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/namespace"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// errStale is returned when an autoscaler or its deployment changed while
+// being reconciled.
+var errStale = errors.New("autoscaler changed during reconciliation")
+
+// Config holds dependencies for the autoscaler controller.
+type Config struct {
+	Store  store.Store
+	Logger zerolog.Logger
+
+	// Interval is how often autoscalers are evaluated.
+	Interval time.Duration
+
+	// Events records the replica counts set. Defaults to a recorder on
+	// Store.
+	Events *events.Recorder
+
+	// Namespaces admits scale-ups against the quotas of their namespaces.
+	// Scale-ups are not checked when it is nil.
+	Namespaces *namespace.Registry
+}
+
+// Controller evaluates autoscalers and scales their deployments.
+type Controller struct {
+	store      store.Store
+	logger     zerolog.Logger
+	interval   time.Duration
+	events     *events.Recorder
+	namespaces *namespace.Registry
+
+	// now is replaceable for tests.
+	now func() time.Time
+}
+
+// NewController creates an autoscaler controller.
+func NewController(cfg *Config) *Controller {
+	recorder := cfg.Events
+	if recorder == nil {
+		recorder = events.NewRecorder(&events.Config{Store: cfg.Store, Logger: cfg.Logger})
+	}
+
+	return &Controller{
+		store:      cfg.Store,
+		logger:     cfg.Logger,
+		interval:   cfg.Interval,
+		events:     recorder.WithSource("autoscalers"),
+		namespaces: cfg.Namespaces,
+		now:        func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run evaluates autoscalers every interval until ctx is canceled.
+func (c *Controller) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Sync(ctx); err != nil {
+			c.logger.Error().Err(err).Msg("autoscaling failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync evaluates every autoscaler once. Autoscalers being deleted are not
+// evaluated.
+func (c *Controller) Sync(ctx context.Context) error {
+	autoscalers, err := store.ListJSON[model.Autoscaler](c.store, model.AutoscalersBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing autoscalers: %w", err)
+	}
+	if len(autoscalers) == 0 {
+		return nil
+	}
+	containers, err := store.ListJSON[model.Container](c.store, model.ContainersBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing containers: %w", err)
+	}
+
+	replicas := make(map[string][]*model.Container)
+	for _, ctr := range containers {
+		if owner, ok := ctr.Labels[model.LabelDeployment]; ok {
+			key := model.Key(ctr.Namespace, owner)
+			replicas[key] = append(replicas[key], ctr)
+		}
+	}
+
+	var errs []error
+	for _, as := range autoscalers {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if as.IsTerminating() {
+			continue
+		}
+		key := model.Key(as.Namespace, as.Spec.Deployment)
+		if err := c.reconcile(as, replicas[key]); err != nil && !errors.Is(err, errStale) {
+			errs = append(errs, fmt.Errorf("autoscaler %s: %w", as.Key(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// reconcile evaluates one autoscaler over the replicas labelled with its
+// deployment's name, scales the deployment if called for and records the
+// autoscaler's status.
+func (c *Controller) reconcile(as *model.Autoscaler, containers []*model.Container) error {
+	now := c.now()
+	status := as.Status
+	status.LastEvaluated = &now
+	status.Message = ""
+
+	d, err := store.GetJSON[model.Deployment](c.store, model.DeploymentsBucket, model.Key(as.Namespace, as.Spec.Deployment))
+	if errors.Is(err, store.ErrNotFound) || errors.Is(err, store.ErrBucketNotFound) || (err == nil && d.IsTerminating()) {
+		status.CurrentMetrics = nil
+		status.Message = fmt.Sprintf("deployment %s not found", as.Spec.Deployment)
+		return c.writeStatus(as, &status)
+	}
+	if err != nil {
+		return err
+	}
+
+	current := d.Spec.Replicas
+	status.CurrentReplicas = current
+	status.DesiredReplicas = current
+
+	var replicas []*model.Container
+	for _, r := range containers {
+		if r.IsControlledBy(&d.ObjectMeta) {
+			replicas = append(replicas, r)
+		}
+	}
+	metrics, recommended, err := evaluate(as.Spec.Metrics, replicas, current)
+	status.CurrentMetrics = metrics
+	if err != nil {
+		status.Message = err.Error()
+	}
+
+	var desired int
+	var reason string
+	switch {
+	case current < as.Spec.MinReplicas:
+		desired = as.Spec.MinReplicas
+		reason = fmt.Sprintf("below the minimum of %d replicas", as.Spec.MinReplicas)
+	case current > as.Spec.MaxReplicas:
+		desired = as.Spec.MaxReplicas
+		reason = fmt.Sprintf("above the maximum of %d replicas", as.Spec.MaxReplicas)
+	case len(metrics) == 0:
+		return c.writeStatus(as, &status)
+	default:
+		var notes string
+		desired, notes = plan(&as.Spec, &status, current, recommended, now)
+		reason = explain(metrics, current)
+		if notes != "" {
+			reason += "; " + notes
+		}
+	}
+	status.DesiredReplicas = desired
+	if desired == current {
+		return c.writeStatus(as, &status)
+	}
+
+	var errs []error
+	set, err := c.scale(d, desired)
+	var qerr *namespace.ExceededError
+	if errors.As(err, &qerr) {
+		// The quota refused the scale-up, or all but set replicas of it.
+		c.events.Eventf(model.AutoscalersBucket, &as.ObjectMeta, model.EventWarning, model.EventFailedRescale,
+			"Quota allows %d of the %d replicas wanted for deployment %s: %v", set, desired, d.Name, err)
+		status.Message = err.Error()
+		reason += fmt.Sprintf("; capped at %d by the quota", set)
+		desired, err = set, nil
+		status.DesiredReplicas = set
+	}
+	switch {
+	case err != nil:
+		c.events.Eventf(model.AutoscalersBucket, &as.ObjectMeta, model.EventWarning, model.EventFailedRescale,
+			"Failed to set the replicas of deployment %s to %d: %v", d.Name, desired, err)
+		errs = append(errs, err)
+		status.DesiredReplicas = current
+	case desired == current:
+	default:
+		status.LastScaleTime = &now
+		status.Decisions = append(status.Decisions, model.ScalingDecision{
+			Time: now, From: current, To: desired, Reason: reason, Metrics: metrics,
+		})
+		if n := len(status.Decisions) - model.MaxScalingDecisions; n > 0 {
+			status.Decisions = status.Decisions[n:]
+		}
+		c.logger.Info().Str("autoscaler", as.Key()).Str("deployment", d.Name).
+			Int("from", current).Int("to", desired).Str("reason", reason).Msg("deployment rescaled")
+		c.events.Eventf(model.AutoscalersBucket, &as.ObjectMeta, model.EventNormal, model.EventRescaled,
+			"New size: %d; reason: %s", desired, reason)
+	}
+
+	if err := c.writeStatus(as, &status); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// scale sets the replica count of the deployment the controller read as d
+// and returns the count set. A scale-up is admitted by the namespace
+// registry, and one the namespace's quota does not allow in full is capped
+// at what it does, or refused if not one more replica fits; either way the
+// *namespace.ExceededError is returned with the count set.
+func (c *Controller) scale(d *model.Deployment, replicas int) (int, error) {
+	var refused *namespace.ExceededError
+	for {
+		err := c.setReplicas(d, replicas)
+		var qerr *namespace.ExceededError
+		if !errors.As(err, &qerr) {
+			if err == nil && refused != nil {
+				return replicas, refused
+			}
+			return replicas, err
+		}
+		refused = qerr
+		fit := quotaFit(qerr, replicas)
+		if fit <= d.Spec.Replicas {
+			return d.Spec.Replicas, err
+		}
+		replicas = fit
+	}
+}
+
+// quotaFit returns the largest replica count below replicas that the quota
+// refusing them with qerr leaves room for.
+func quotaFit(qerr *namespace.ExceededError, replicas int) int {
+	perReplica := qerr.Requested / int64(replicas)
+	if perReplica <= 0 {
+		return 0
+	}
+	return min(int((qerr.Limit-qerr.Used)/perReplica), replicas-1)
+}
+
+// setReplicas writes the replica count of the deployment the controller
+// read as d, through the namespace registry when it grows.
+func (c *Controller) setReplicas(d *model.Deployment, replicas int) error {
+	write := func() error {
+		_, err := store.UpdateJSON(c.store, model.DeploymentsBucket, d.Key(), func(cur *model.Deployment) error {
+			if cur.UID != d.UID || cur.IsTerminating() {
+				return errStale
+			}
+			cur.Spec.Replicas = replicas
+			return nil
+		})
+		if err != nil && !errors.Is(err, errStale) {
+			return fmt.Errorf("scaling deployment %s: %w", d.Key(), err)
+		}
+		return err
+	}
+	if c.namespaces == nil || replicas <= d.Spec.Replicas {
+		// Scaling down is allowed even over the quota.
+		return write()
+	}
+
+	scaled := *d
+	scaled.Spec.Replicas = replicas
+	return c.namespaces.Admit(model.DeploymentsBucket, &scaled, write)
+}
+
+// writeStatus stores the autoscaler's status if the autoscaler was not
+// replaced in the meantime.
+func (c *Controller) writeStatus(as *model.Autoscaler, status *model.AutoscalerStatus) error {
+	_, err := gc.Update(c.store, model.AutoscalersBucket, &as.ObjectMeta, func(cur *model.Autoscaler) error {
+		cur.Status = *status
+		return nil
+	})
+	if errors.Is(err, gc.ErrStale) {
+		return errStale
+	}
+	return err
+}
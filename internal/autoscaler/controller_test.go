@@ -0,0 +1,266 @@
+package autoscaler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/namespace"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+var t0 = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestController(t *testing.T) (*Controller, store.Store, *time.Time) {
+	t.Helper()
+	s := store.NewMemoryStore()
+	c := NewController(&Config{Store: s, Logger: zerolog.Nop(), Interval: time.Hour})
+	now := t0
+	c.now = func() time.Time { return now }
+	return c, s, &now
+}
+
+func putDeployment(t *testing.T, s store.Store, replicas int) *model.Deployment {
+	t.Helper()
+	d := &model.Deployment{
+		ObjectMeta: model.ObjectMeta{Name: "web", Namespace: model.DefaultNamespace, UID: "web-uid"},
+		Spec:       model.DeploymentSpec{Replicas: replicas},
+	}
+	require.NoError(t, store.PutJSON(s, model.DeploymentsBucket, d.Key(), d))
+	return d
+}
+
+func putAutoscaler(t *testing.T, s store.Store, mutate func(a *model.Autoscaler)) {
+	t.Helper()
+	a := &model.Autoscaler{
+		ObjectMeta: model.ObjectMeta{Name: "web", Namespace: model.DefaultNamespace, UID: "as-uid"},
+		Spec: model.AutoscalerSpec{
+			Deployment:  "web",
+			MaxReplicas: 10,
+			Metrics:     []model.MetricTarget{{Type: model.MetricCPU, TargetUtilization: 50}},
+		},
+	}
+	if mutate != nil {
+		mutate(a)
+	}
+	a.SetDefaults()
+	require.NoError(t, a.Validate())
+	require.NoError(t, store.PutJSON(s, model.AutoscalersBucket, a.Key(), a))
+}
+
+// putReplicas stores running replicas of d, each allotted 500 CPU
+// millicores and reporting the usage usage(i) returns.
+func putReplicas(t *testing.T, s store.Store, d *model.Deployment, n int, usage func(i int) *model.ResourceUsage) {
+	t.Helper()
+	for i := range n {
+		c := &model.Container{
+			ObjectMeta: model.ObjectMeta{
+				Name:            fmt.Sprintf("web-%d", i),
+				Namespace:       d.Namespace,
+				Labels:          map[string]string{model.LabelDeployment: d.Name},
+				OwnerReferences: []model.OwnerReference{model.NewControllerRef(model.DeploymentsBucket, &d.ObjectMeta)},
+			},
+			Spec:   model.ContainerSpec{Image: "web:v1", Resources: model.Resources{CPUMillis: 500}},
+			Status: model.ContainerStatus{Phase: model.PhaseRunning, Usage: usage(i)},
+		}
+		require.NoError(t, store.PutJSON(s, model.ContainersBucket, c.Key(), c))
+	}
+}
+
+func cpu(millis int64) func(int) *model.ResourceUsage {
+	return func(int) *model.ResourceUsage { return &model.ResourceUsage{CPUMillis: millis} }
+}
+
+func getAutoscaler(t *testing.T, s store.Store) *model.Autoscaler {
+	t.Helper()
+	a, err := store.GetJSON[model.Autoscaler](s, model.AutoscalersBucket, model.Key(model.DefaultNamespace, "web"))
+	require.NoError(t, err)
+	return a
+}
+
+func replicas(t *testing.T, s store.Store) int {
+	t.Helper()
+	d, err := store.GetJSON[model.Deployment](s, model.DeploymentsBucket, model.Key(model.DefaultNamespace, "web"))
+	require.NoError(t, err)
+	return d.Spec.Replicas
+}
+
+func TestController_ScalesUpOnCPU(t *testing.T) {
+	c, s, _ := newTestController(t)
+	d := putDeployment(t, s, 2)
+	putReplicas(t, s, d, 2, cpu(450))
+	putAutoscaler(t, s, nil)
+
+	require.NoError(t, c.Sync(context.Background()))
+	assert.Equal(t, 4, replicas(t, s), "90% against a 50% target calls for 1.8 times the replicas")
+
+	a := getAutoscaler(t, s)
+	assert.Equal(t, 2, a.Status.CurrentReplicas)
+	assert.Equal(t, 4, a.Status.DesiredReplicas)
+	require.Len(t, a.Status.Decisions, 1)
+	decision := a.Status.Decisions[0]
+	assert.Equal(t, 2, decision.From)
+	assert.Equal(t, 4, decision.To)
+	assert.Equal(t, "CPU utilization 90% above target 50%", decision.Reason)
+	require.Len(t, decision.Metrics, 1)
+	assert.InDelta(t, 90, decision.Metrics[0].Current, 0.01)
+	assert.Equal(t, 2, decision.Metrics[0].Replicas)
+	assert.Equal(t, 4, decision.Metrics[0].Recommended)
+
+	events, err := store.ListJSON[model.Event](s, model.EventsBucket, "")
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, model.EventRescaled, events[0].Reason)
+	assert.Equal(t, "New size: 4; reason: CPU utilization 90% above target 50%", events[0].Message)
+}
+
+func TestController_ScaleUpCappedByQuota(t *testing.T) {
+	c, s, now := newTestController(t)
+	c.namespaces = namespace.NewRegistry(&namespace.Config{Store: s, Logger: zerolog.Nop()})
+	ns := &model.Namespace{
+		ObjectMeta: model.ObjectMeta{Name: model.DefaultNamespace},
+		Spec:       model.NamespaceSpec{Quota: &model.Quota{Requests: model.Resources{CPUMillis: 1500}}},
+	}
+	require.NoError(t, store.PutJSON(s, model.NamespacesBucket, ns.Name, ns))
+	d := putDeployment(t, s, 2)
+	d.Spec.Template.Spec.Resources.CPUMillis = 500
+	require.NoError(t, store.PutJSON(s, model.DeploymentsBucket, d.Key(), d))
+	putReplicas(t, s, d, 2, cpu(450))
+	putAutoscaler(t, s, nil)
+
+	require.NoError(t, c.Sync(context.Background()))
+	assert.Equal(t, 3, replicas(t, s), "the quota leaves room for 3 of the 4 replicas called for")
+	a := getAutoscaler(t, s)
+	assert.Equal(t, 3, a.Status.DesiredReplicas)
+	assert.Contains(t, a.Status.Message, "quota exceeded")
+	require.Len(t, a.Status.Decisions, 1)
+	assert.Equal(t, "CPU utilization 90% above target 50%; capped at 3 by the quota", a.Status.Decisions[0].Reason)
+
+	// With no room left, the scale-up is refused.
+	*now = now.Add(10 * time.Minute)
+	require.NoError(t, c.Sync(context.Background()))
+	assert.Equal(t, 3, replicas(t, s))
+	assert.Len(t, getAutoscaler(t, s).Status.Decisions, 1)
+
+	events, err := store.ListJSON[model.Event](s, model.EventsBucket, "")
+	require.NoError(t, err)
+	var reasons []string
+	for _, e := range events {
+		reasons = append(reasons, e.Reason)
+	}
+	assert.Contains(t, reasons, model.EventFailedRescale)
+}
+
+func TestController_WithinTolerance(t *testing.T) {
+	c, s, _ := newTestController(t)
+	d := putDeployment(t, s, 3)
+	putReplicas(t, s, d, 3, cpu(270))
+	putAutoscaler(t, s, nil)
+
+	require.NoError(t, c.Sync(context.Background()))
+	assert.Equal(t, 3, replicas(t, s), "54% is within tolerance of 50%")
+	assert.Empty(t, getAutoscaler(t, s).Status.Decisions)
+}
+
+func TestController_ScaleDownStabilization(t *testing.T) {
+	c, s, now := newTestController(t)
+	d := putDeployment(t, s, 4)
+	putReplicas(t, s, d, 4, cpu(250))
+	putAutoscaler(t, s, nil)
+
+	require.NoError(t, c.Sync(context.Background()))
+	assert.Equal(t, 4, replicas(t, s))
+
+	// The load drops, but the window still holds the recommendation of four.
+	putReplicas(t, s, d, 4, cpu(50))
+	*now = t0.Add(time.Minute)
+	require.NoError(t, c.Sync(context.Background()))
+	assert.Equal(t, 4, replicas(t, s))
+	a := getAutoscaler(t, s)
+	assert.Equal(t, 4, a.Status.DesiredReplicas)
+	assert.Equal(t, 1, a.Status.CurrentMetrics[0].Recommended)
+
+	*now = t0.Add(5*time.Minute + time.Second)
+	require.NoError(t, c.Sync(context.Background()))
+	assert.Equal(t, 1, replicas(t, s))
+	a = getAutoscaler(t, s)
+	require.Len(t, a.Status.Decisions, 1)
+	assert.Equal(t, "CPU utilization 10% below target 50%", a.Status.Decisions[0].Reason)
+}
+
+func TestController_ScaleUpRateLimit(t *testing.T) {
+	c, s, now := newTestController(t)
+	d := putDeployment(t, s, 2)
+	putReplicas(t, s, d, 2, cpu(500))
+	putAutoscaler(t, s, func(a *model.Autoscaler) {
+		none := 0
+		a.Spec.Metrics[0].TargetUtilization = 25
+		a.Spec.ScaleUp = &model.ScalingRules{MaxChange: model.FromInt(1), MinChange: &none, PeriodSeconds: 60}
+	})
+
+	require.NoError(t, c.Sync(context.Background()))
+	assert.Equal(t, 3, replicas(t, s))
+	a := getAutoscaler(t, s)
+	assert.Equal(t, "CPU utilization 100% above target 25%; limited to 3 by the scale-up rate", a.Status.Decisions[0].Reason)
+
+	// The replica added this period uses up the change allowed.
+	*now = t0.Add(30 * time.Second)
+	require.NoError(t, c.Sync(context.Background()))
+	assert.Equal(t, 3, replicas(t, s))
+
+	*now = t0.Add(time.Minute)
+	require.NoError(t, c.Sync(context.Background()))
+	assert.Equal(t, 4, replicas(t, s))
+	assert.Len(t, getAutoscaler(t, s).Status.Decisions, 2)
+}
+
+func TestController_CustomMetric(t *testing.T) {
+	c, s, _ := newTestController(t)
+	d := putDeployment(t, s, 2)
+	putReplicas(t, s, d, 2, func(i int) *model.ResourceUsage {
+		return &model.ResourceUsage{Metrics: map[string]float64{"queue_depth": float64(90 - 60*i)}}
+	})
+	putAutoscaler(t, s, func(a *model.Autoscaler) {
+		a.Spec.MaxReplicas = 5
+		a.Spec.Metrics = []model.MetricTarget{{Type: model.MetricCustom, Name: "queue_depth", TargetAverageValue: 20}}
+	})
+
+	require.NoError(t, c.Sync(context.Background()))
+	assert.Equal(t, 5, replicas(t, s))
+	a := getAutoscaler(t, s)
+	assert.Equal(t, 6, a.Status.CurrentMetrics[0].Recommended)
+	assert.Equal(t, "metric queue_depth 60 above target 20; capped at the maximum of 5", a.Status.Decisions[0].Reason)
+}
+
+func TestController_Bounds(t *testing.T) {
+	c, s, _ := newTestController(t)
+	d := putDeployment(t, s, 1)
+	putReplicas(t, s, d, 1, cpu(250))
+	putAutoscaler(t, s, func(a *model.Autoscaler) { a.Spec.MinReplicas = 2 })
+
+	require.NoError(t, c.Sync(context.Background()))
+	assert.Equal(t, 2, replicas(t, s))
+	assert.Equal(t, "below the minimum of 2 replicas", getAutoscaler(t, s).Status.Decisions[0].Reason)
+}
+
+func TestController_Unmeasurable(t *testing.T) {
+	c, s, _ := newTestController(t)
+	putAutoscaler(t, s, nil)
+
+	require.NoError(t, c.Sync(context.Background()))
+	assert.Equal(t, "deployment web not found", getAutoscaler(t, s).Status.Message)
+
+	d := putDeployment(t, s, 2)
+	putReplicas(t, s, d, 2, func(int) *model.ResourceUsage { return nil })
+	require.NoError(t, c.Sync(context.Background()))
+	a := getAutoscaler(t, s)
+	assert.Equal(t, "CPU utilization: no running replica reports it", a.Status.Message)
+	assert.Equal(t, 2, a.Status.DesiredReplicas)
+	assert.Equal(t, 2, replicas(t, s))
+}
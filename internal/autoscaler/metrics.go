@@ -0,0 +1,136 @@
+package autoscaler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+// tolerance is how far a metric may stray from its target, as a fraction
+// of it, before the replica count changes.
+const tolerance = 0.1
+
+// evaluate measures every metric target over the replicas and returns the
+// values that could be measured along with the largest replica count they
+// recommend. A metric that cannot be measured might call for more
+// replicas, so it keeps the count from going below current.
+func evaluate(targets []model.MetricTarget, replicas []*model.Container, current int) ([]model.MetricValue, int, error) {
+	var values []model.MetricValue
+	var errs []error
+	recommended := 0
+	for i := range targets {
+		v, err := measure(&targets[i], replicas, current)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		values = append(values, *v)
+		recommended = max(recommended, v.Recommended)
+	}
+	if len(errs) > 0 {
+		recommended = max(recommended, current)
+	}
+	return values, recommended, errors.Join(errs...)
+}
+
+// measure computes a metric over the running replicas that have reported
+// usage, and the replica count that would bring it to its target. Of the
+// current replicas, those that have not reported yet are assumed to be
+// idle when the metric calls for scaling up, and at target when it calls
+// for scaling down, so that they can only damp the change.
+func measure(t *model.MetricTarget, replicas []*model.Container, current int) (*model.MetricValue, error) {
+	var sum, allotted float64
+	sampled := 0
+	for _, r := range replicas {
+		u := r.Status.Usage
+		if r.Status.Phase != model.PhaseRunning || r.IsTerminating() || u == nil {
+			continue
+		}
+		switch t.Type {
+		case model.MetricCPU:
+			if r.Spec.Resources.CPUMillis <= 0 {
+				return nil, fmt.Errorf("%s: replica %s sets no resources.cpu_millis", describe(t), r.Name)
+			}
+			sum += float64(u.CPUMillis)
+			allotted += float64(r.Spec.Resources.CPUMillis)
+		case model.MetricMemory:
+			if r.Spec.Resources.MemoryBytes <= 0 {
+				return nil, fmt.Errorf("%s: replica %s sets no resources.memory_bytes", describe(t), r.Name)
+			}
+			sum += float64(u.MemoryBytes)
+			allotted += float64(r.Spec.Resources.MemoryBytes)
+		default:
+			v, ok := u.Metrics[t.Name]
+			if !ok {
+				continue
+			}
+			sum += v
+			allotted++
+		}
+		sampled++
+	}
+	if sampled == 0 {
+		return nil, fmt.Errorf("%s: no running replica reports it", describe(t))
+	}
+
+	v := &model.MetricValue{Type: t.Type, Name: t.Name, Replicas: sampled, Recommended: current}
+	v.Current = sum / allotted
+	v.Target = t.TargetAverageValue
+	if t.Type != model.MetricCustom {
+		v.Current *= 100
+		v.Target = float64(t.TargetUtilization)
+	}
+
+	ratio := v.Current / v.Target
+	n := max(current, sampled)
+	if missing := float64(n - sampled); missing > 0 {
+		adjusted := ratio * float64(sampled)
+		if ratio < 1 {
+			adjusted += missing
+		}
+		adjusted /= float64(n)
+		if (adjusted > 1) != (ratio > 1) {
+			return v, nil
+		}
+		ratio = adjusted
+	}
+	if math.Abs(ratio-1) > tolerance {
+		v.Recommended = int(math.Ceil(ratio * float64(n)))
+	}
+	return v, nil
+}
+
+// describe names the metric of t for messages.
+func describe(t *model.MetricTarget) string {
+	switch t.Type {
+	case model.MetricCPU:
+		return "CPU utilization"
+	case model.MetricMemory:
+		return "memory utilization"
+	default:
+		return "metric " + t.Name
+	}
+}
+
+// explain says which of the values drove a change from current replicas:
+// the one recommending the most.
+func explain(values []model.MetricValue, current int) string {
+	top := &values[0]
+	for i := range values {
+		if values[i].Recommended > top.Recommended {
+			top = &values[i]
+		}
+	}
+
+	dir := "above"
+	if top.Recommended < current {
+		dir = "below"
+	}
+	t := &model.MetricTarget{Type: top.Type, Name: top.Name}
+	if top.Type == model.MetricCustom {
+		return fmt.Sprintf("%s %g %s target %g", describe(t), top.Current, dir, top.Target)
+	}
+	return fmt.Sprintf("%s %.0f%% %s target %.0f%%", describe(t), top.Current, dir, top.Target)
+}
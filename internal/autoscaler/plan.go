@@ -0,0 +1,96 @@
+package autoscaler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+// plan turns the replica count the metrics recommend into the count to
+// scale to from current, which is within the autoscaler's bounds. The
+// recommendation is capped by the bounds, stabilized against the earlier
+// ones kept in status and rate limited by the decisions made within the
+// last period. It returns the count and, if one of these held it back,
+// how.
+func plan(spec *model.AutoscalerSpec, status *model.AutoscalerStatus, current, recommended int, now time.Time) (int, string) {
+	var notes []string
+	switch {
+	case recommended < spec.MinReplicas:
+		recommended = spec.MinReplicas
+		notes = append(notes, fmt.Sprintf("raised to the minimum of %d", spec.MinReplicas))
+	case recommended > spec.MaxReplicas:
+		recommended = spec.MaxReplicas
+		notes = append(notes, fmt.Sprintf("capped at the maximum of %d", spec.MaxReplicas))
+	}
+
+	// Scaling up goes no higher than the lowest recommendation within its
+	// window, and scaling down no lower than the highest within its own.
+	upWindow, downWindow := spec.ScaleUp.StabilizationWindow(), spec.ScaleDown.StabilizationWindow()
+	keep := max(upWindow, downWindow)
+	recs := make([]model.Recommendation, 0, len(status.Recommendations)+1)
+	for _, r := range status.Recommendations {
+		if now.Sub(r.Time) < keep {
+			recs = append(recs, r)
+		}
+	}
+	recs = append(recs, model.Recommendation{Replicas: recommended, Time: now})
+	status.Recommendations = recs
+
+	up, down := recommended, recommended
+	for _, r := range recs {
+		age := now.Sub(r.Time)
+		if age <= upWindow {
+			up = min(up, r.Replicas)
+		}
+		if age <= downWindow {
+			down = max(down, r.Replicas)
+		}
+	}
+	desired := current
+	switch {
+	case up > current:
+		desired = up
+	case down < current:
+		desired = down
+	}
+	if desired != recommended {
+		dir := "up"
+		if recommended < current {
+			dir = "down"
+		}
+		notes = append(notes, fmt.Sprintf("held at %d by the scale-%s stabilization window", desired, dir))
+	}
+
+	switch {
+	case desired > current:
+		start := current - changed(status.Decisions, now, spec.ScaleUp.Period(), 1)
+		if limit := start + spec.ScaleUp.Limit(start); desired > limit {
+			desired = max(limit, current)
+			notes = append(notes, fmt.Sprintf("limited to %d by the scale-up rate", desired))
+		}
+	case desired < current:
+		start := current + changed(status.Decisions, now, spec.ScaleDown.Period(), -1)
+		if limit := start - spec.ScaleDown.Limit(start); desired < limit {
+			desired = min(limit, current)
+			notes = append(notes, fmt.Sprintf("limited to %d by the scale-down rate", desired))
+		}
+	}
+	return desired, strings.Join(notes, "; ")
+}
+
+// changed returns how many replicas the decisions made within period
+// before now added, for sign 1, or removed, for sign -1.
+func changed(decisions []model.ScalingDecision, now time.Time, period time.Duration, sign int) int {
+	n := 0
+	for _, d := range decisions {
+		if now.Sub(d.Time) >= period {
+			continue
+		}
+		if delta := (d.To - d.From) * sign; delta > 0 {
+			n += delta
+		}
+	}
+	return n
+}
@@ -2,6 +2,7 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
 	"time"
 
@@ -13,30 +14,88 @@ type Config struct {
 	// DataDir is the directory for bbolt database files.
 	DataDir string `env:"ORCHESTRATOR_DATA_DIR" envDefault:"./data"`
 
+	// ContainerRuntime selects how containers are run: "process" runs them
+	// as local processes, "docker" on the Docker daemon at DockerHost.
+	ContainerRuntime string `env:"CONTAINER_RUNTIME" envDefault:"process"`
+
 	// DockerHost is the Docker daemon socket address.
 	DockerHost string `env:"DOCKER_HOST" envDefault:"unix:///var/run/docker.sock"`
 
+	// CgroupRoot is the cgroup v2 directory under which the process runtime
+	// creates per-container groups. Empty disables cgroup resource limits.
+	CgroupRoot string `env:"CGROUP_ROOT"`
+
 	// LogLevel controls the logging verbosity (debug, info, warn, error).
 	LogLevel string `env:"LOG_LEVEL" envDefault:"info"`
 
 	// APIKey is the required API key for authentication.
 	APIKey string `env:"API_KEY,required"` //nolint:gosec // Not a hardcoded credential, populated from env.
 
+	// SecretEncryptionKey is the base64-encoded 32-byte key secrets are
+	// encrypted with in the store. When empty, a key is generated and kept
+	// in DataDir/secret.key.
+	SecretEncryptionKey string `env:"SECRET_ENCRYPTION_KEY"` //nolint:gosec // Not a hardcoded credential, populated from env.
+
+	// SecretReaderKey must be sent in X-Secret-Reader-Key to read secret
+	// values through the API. When empty, values are never returned.
+	SecretReaderKey string `env:"SECRET_READER_KEY"` //nolint:gosec // Not a hardcoded credential, populated from env.
+
 	// DashboardURL is the dashboard origin for CORS configuration.
 	DashboardURL string `env:"DASHBOARD_URL" envDefault:"http://localhost:3000"`
 
+	// NodeName is the name under which this host registers as a node.
+	NodeName string `env:"NODE_NAME" envDefault:"local"`
+
 	// NodeHeartbeatInterval is how often nodes send heartbeats.
 	NodeHeartbeatInterval time.Duration `env:"NODE_HEARTBEAT_INTERVAL" envDefault:"10s"`
 
 	// NodeHeartbeatTimeout is the time before a node is marked NotReady.
 	NodeHeartbeatTimeout time.Duration `env:"NODE_HEARTBEAT_TIMEOUT" envDefault:"30s"`
 
+	// NodeDrainTimeout is how long a node drain may take unless the
+	// request sets its own timeout.
+	NodeDrainTimeout time.Duration `env:"NODE_DRAIN_TIMEOUT" envDefault:"5m"`
+
 	// HealthCheckInterval is the default health check probe interval.
 	HealthCheckInterval time.Duration `env:"HEALTH_CHECK_INTERVAL" envDefault:"10s"`
 
-	// ReconcileInterval is the deployment reconciliation loop interval.
+	// AgentSyncInterval is how often container records are resynced with the runtime.
+	AgentSyncInterval time.Duration `env:"AGENT_SYNC_INTERVAL" envDefault:"2s"`
+
+	// ContainerStopTimeout is the grace period before a stopping container is killed.
+	ContainerStopTimeout time.Duration `env:"CONTAINER_STOP_TIMEOUT" envDefault:"10s"`
+
+	// ImagePullTimeout bounds a single image pull.
+	ImagePullTimeout time.Duration `env:"IMAGE_PULL_TIMEOUT" envDefault:"10m"`
+
+	// CrashLoopBackoffInitial is the delay before the second consecutive
+	// restart of a crashing container. It doubles with every further crash.
+	CrashLoopBackoffInitial time.Duration `env:"CRASHLOOP_BACKOFF_INITIAL" envDefault:"10s"`
+
+	// CrashLoopBackoffMax caps the crash-loop back-off.
+	CrashLoopBackoffMax time.Duration `env:"CRASHLOOP_BACKOFF_MAX" envDefault:"5m"`
+
+	// CrashLoopBackoffReset is how long a container must run before its
+	// crash-loop back-off is reset.
+	CrashLoopBackoffReset time.Duration `env:"CRASHLOOP_BACKOFF_RESET" envDefault:"10m"`
+
+	// SchedulerInterval is how often pending containers are scheduled.
+	SchedulerInterval time.Duration `env:"SCHEDULER_INTERVAL" envDefault:"1s"`
+
+	// SchedulerStrategy selects how feasible nodes are scored
+	// (least-allocated, most-allocated, spread).
+	SchedulerStrategy string `env:"SCHEDULER_STRATEGY" envDefault:"least-allocated"`
+
+	// ReconcileInterval is the reconciliation loop interval of the workload
+	// controllers: deployments, daemon sets, stateful sets, jobs and cron jobs.
 	ReconcileInterval time.Duration `env:"RECONCILE_INTERVAL" envDefault:"10s"`
 
+	// AutoscalerInterval is how often autoscalers evaluate their metrics.
+	AutoscalerInterval time.Duration `env:"AUTOSCALER_INTERVAL" envDefault:"15s"`
+
+	// EventTTL is how long an event is kept after it was last seen.
+	EventTTL time.Duration `env:"EVENT_TTL" envDefault:"1h"`
+
 	// Port is the API server listen port.
 	Port int `env:"ORCHESTRATOR_PORT" envDefault:"8080"`
 }
@@ -71,10 +130,82 @@ func validate(cfg *Config) error {
 		return fmt.Errorf("LOG_LEVEL must be one of debug, info, warn, error; got %q", cfg.LogLevel)
 	}
 
+	if cfg.ContainerRuntime != "process" && cfg.ContainerRuntime != "docker" {
+		return fmt.Errorf("CONTAINER_RUNTIME must be process or docker; got %q", cfg.ContainerRuntime)
+	}
+
 	if cfg.NodeHeartbeatTimeout <= cfg.NodeHeartbeatInterval {
 		return fmt.Errorf("NODE_HEARTBEAT_TIMEOUT (%s) must be greater than NODE_HEARTBEAT_INTERVAL (%s)",
 			cfg.NodeHeartbeatTimeout, cfg.NodeHeartbeatInterval)
 	}
 
+	if cfg.NodeDrainTimeout <= 0 {
+		return fmt.Errorf("NODE_DRAIN_TIMEOUT must be positive, got %s", cfg.NodeDrainTimeout)
+	}
+
+	if cfg.NodeName == "" {
+		return fmt.Errorf("NODE_NAME must not be empty")
+	}
+
+	// The names match the scheduler's strategies; main resolves them with
+	// scheduler.ScorersFor.
+	validStrategies := map[string]bool{
+		"least-allocated": true,
+		"most-allocated":  true,
+		"spread":          true,
+	}
+	if !validStrategies[cfg.SchedulerStrategy] {
+		return fmt.Errorf("SCHEDULER_STRATEGY must be one of least-allocated, most-allocated, spread; got %q",
+			cfg.SchedulerStrategy)
+	}
+
+	if cfg.SchedulerInterval <= 0 {
+		return fmt.Errorf("SCHEDULER_INTERVAL must be positive, got %s", cfg.SchedulerInterval)
+	}
+
+	if cfg.SecretEncryptionKey != "" {
+		key, err := base64.StdEncoding.DecodeString(cfg.SecretEncryptionKey)
+		if err != nil || len(key) != 32 {
+			return fmt.Errorf("SECRET_ENCRYPTION_KEY must be 32 base64-encoded bytes")
+		}
+	}
+
+	if cfg.ImagePullTimeout <= 0 {
+		return fmt.Errorf("IMAGE_PULL_TIMEOUT must be positive, got %s", cfg.ImagePullTimeout)
+	}
+
+	if cfg.HealthCheckInterval <= 0 {
+		return fmt.Errorf("HEALTH_CHECK_INTERVAL must be positive, got %s", cfg.HealthCheckInterval)
+	}
+
+	if cfg.ReconcileInterval <= 0 {
+		return fmt.Errorf("RECONCILE_INTERVAL must be positive, got %s", cfg.ReconcileInterval)
+	}
+
+	if cfg.AutoscalerInterval <= 0 {
+		return fmt.Errorf("AUTOSCALER_INTERVAL must be positive, got %s", cfg.AutoscalerInterval)
+	}
+
+	if cfg.EventTTL <= 0 {
+		return fmt.Errorf("EVENT_TTL must be positive, got %s", cfg.EventTTL)
+	}
+
+	if cfg.AgentSyncInterval <= 0 {
+		return fmt.Errorf("AGENT_SYNC_INTERVAL must be positive, got %s", cfg.AgentSyncInterval)
+	}
+
+	if cfg.CrashLoopBackoffInitial <= 0 {
+		return fmt.Errorf("CRASHLOOP_BACKOFF_INITIAL must be positive, got %s", cfg.CrashLoopBackoffInitial)
+	}
+
+	if cfg.CrashLoopBackoffMax < cfg.CrashLoopBackoffInitial {
+		return fmt.Errorf("CRASHLOOP_BACKOFF_MAX (%s) must not be less than CRASHLOOP_BACKOFF_INITIAL (%s)",
+			cfg.CrashLoopBackoffMax, cfg.CrashLoopBackoffInitial)
+	}
+
+	if cfg.CrashLoopBackoffReset <= 0 {
+		return fmt.Errorf("CRASHLOOP_BACKOFF_RESET must be positive, got %s", cfg.CrashLoopBackoffReset)
+	}
+
 	return nil
 }
@@ -25,14 +25,29 @@ func TestLoad_Defaults(t *testing.T) {
 
 	assert.Equal(t, 8080, cfg.Port)
 	assert.Equal(t, "./data", cfg.DataDir)
+	assert.Equal(t, "process", cfg.ContainerRuntime)
 	assert.Equal(t, "unix:///var/run/docker.sock", cfg.DockerHost)
 	assert.Equal(t, "info", cfg.LogLevel)
 	assert.Equal(t, "test-key", cfg.APIKey)
 	assert.Equal(t, "http://localhost:3000", cfg.DashboardURL)
 	assert.Equal(t, 10*time.Second, cfg.NodeHeartbeatInterval)
 	assert.Equal(t, 30*time.Second, cfg.NodeHeartbeatTimeout)
+	assert.Equal(t, 5*time.Minute, cfg.NodeDrainTimeout)
 	assert.Equal(t, 10*time.Second, cfg.HealthCheckInterval)
 	assert.Equal(t, 10*time.Second, cfg.ReconcileInterval)
+	assert.Equal(t, 15*time.Second, cfg.AutoscalerInterval)
+	assert.Equal(t, time.Hour, cfg.EventTTL)
+	assert.Equal(t, 2*time.Second, cfg.AgentSyncInterval)
+	assert.Equal(t, 10*time.Second, cfg.ContainerStopTimeout)
+	assert.Equal(t, 10*time.Second, cfg.CrashLoopBackoffInitial)
+	assert.Equal(t, 5*time.Minute, cfg.CrashLoopBackoffMax)
+	assert.Equal(t, 10*time.Minute, cfg.CrashLoopBackoffReset)
+	assert.Empty(t, cfg.CgroupRoot)
+	assert.Empty(t, cfg.SecretEncryptionKey)
+	assert.Empty(t, cfg.SecretReaderKey)
+	assert.Equal(t, "local", cfg.NodeName)
+	assert.Equal(t, time.Second, cfg.SchedulerInterval)
+	assert.Equal(t, "least-allocated", cfg.SchedulerStrategy)
 }
 
 func TestLoad_CustomValues(t *testing.T) {
@@ -40,12 +55,22 @@ func TestLoad_CustomValues(t *testing.T) {
 		"API_KEY":                 "custom-key",
 		"ORCHESTRATOR_PORT":       "9090",
 		"ORCHESTRATOR_DATA_DIR":   "/tmp/data",
+		"CONTAINER_RUNTIME":       "docker",
+		"DOCKER_HOST":             "tcp://127.0.0.1:2375",
 		"LOG_LEVEL":               "debug",
 		"DASHBOARD_URL":           "http://example.com",
 		"NODE_HEARTBEAT_INTERVAL": "5s",
 		"NODE_HEARTBEAT_TIMEOUT":  "15s",
 		"HEALTH_CHECK_INTERVAL":   "30s",
 		"RECONCILE_INTERVAL":      "20s",
+		"AUTOSCALER_INTERVAL":     "30s",
+		"EVENT_TTL":               "15m",
+		"AGENT_SYNC_INTERVAL":     "1s",
+		"CONTAINER_STOP_TIMEOUT":  "3s",
+		"CGROUP_ROOT":             "/sys/fs/cgroup/orchestrator",
+		"NODE_NAME":               "worker-1",
+		"SCHEDULER_INTERVAL":      "5s",
+		"SCHEDULER_STRATEGY":      "spread",
 	})
 
 	cfg, err := Load()
@@ -53,6 +78,8 @@ func TestLoad_CustomValues(t *testing.T) {
 
 	assert.Equal(t, 9090, cfg.Port)
 	assert.Equal(t, "/tmp/data", cfg.DataDir)
+	assert.Equal(t, "docker", cfg.ContainerRuntime)
+	assert.Equal(t, "tcp://127.0.0.1:2375", cfg.DockerHost)
 	assert.Equal(t, "debug", cfg.LogLevel)
 	assert.Equal(t, "custom-key", cfg.APIKey)
 	assert.Equal(t, "http://example.com", cfg.DashboardURL)
@@ -60,6 +87,14 @@ func TestLoad_CustomValues(t *testing.T) {
 	assert.Equal(t, 15*time.Second, cfg.NodeHeartbeatTimeout)
 	assert.Equal(t, 30*time.Second, cfg.HealthCheckInterval)
 	assert.Equal(t, 20*time.Second, cfg.ReconcileInterval)
+	assert.Equal(t, 30*time.Second, cfg.AutoscalerInterval)
+	assert.Equal(t, 15*time.Minute, cfg.EventTTL)
+	assert.Equal(t, time.Second, cfg.AgentSyncInterval)
+	assert.Equal(t, 3*time.Second, cfg.ContainerStopTimeout)
+	assert.Equal(t, "/sys/fs/cgroup/orchestrator", cfg.CgroupRoot)
+	assert.Equal(t, "worker-1", cfg.NodeName)
+	assert.Equal(t, 5*time.Second, cfg.SchedulerInterval)
+	assert.Equal(t, "spread", cfg.SchedulerStrategy)
 }
 
 func TestLoad_MissingAPIKey(t *testing.T) {
@@ -94,6 +129,18 @@ func TestLoad_InvalidLogLevel(t *testing.T) {
 	assert.Contains(t, err.Error(), "LOG_LEVEL")
 }
 
+func TestLoad_InvalidContainerRuntime(t *testing.T) {
+	setEnv(t, map[string]string{
+		"API_KEY":           "test-key",
+		"CONTAINER_RUNTIME": "containerd",
+	})
+
+	cfg, err := Load()
+	assert.Nil(t, cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "CONTAINER_RUNTIME")
+}
+
 func TestLoad_HeartbeatTimeoutMustExceedInterval(t *testing.T) {
 	setEnv(t, map[string]string{
 		"API_KEY":                 "test-key",
@@ -106,3 +153,88 @@ func TestLoad_HeartbeatTimeoutMustExceedInterval(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "NODE_HEARTBEAT_TIMEOUT")
 }
+
+func TestLoad_InvalidHealthCheckInterval(t *testing.T) {
+	setEnv(t, map[string]string{
+		"API_KEY":               "test-key",
+		"HEALTH_CHECK_INTERVAL": "0s",
+	})
+
+	cfg, err := Load()
+	assert.Nil(t, cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "HEALTH_CHECK_INTERVAL")
+}
+
+func TestLoad_InvalidReconcileInterval(t *testing.T) {
+	setEnv(t, map[string]string{
+		"API_KEY":            "test-key",
+		"RECONCILE_INTERVAL": "0s",
+	})
+
+	cfg, err := Load()
+	assert.Nil(t, cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "RECONCILE_INTERVAL")
+}
+
+func TestLoad_InvalidEventTTL(t *testing.T) {
+	setEnv(t, map[string]string{
+		"API_KEY":   "test-key",
+		"EVENT_TTL": "0s",
+	})
+
+	cfg, err := Load()
+	assert.Nil(t, cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "EVENT_TTL")
+}
+
+func TestLoad_InvalidAutoscalerInterval(t *testing.T) {
+	setEnv(t, map[string]string{
+		"API_KEY":             "test-key",
+		"AUTOSCALER_INTERVAL": "-1s",
+	})
+
+	cfg, err := Load()
+	assert.Nil(t, cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "AUTOSCALER_INTERVAL")
+}
+
+func TestLoad_InvalidCrashLoopBackoff(t *testing.T) {
+	setEnv(t, map[string]string{
+		"API_KEY":                   "test-key",
+		"CRASHLOOP_BACKOFF_INITIAL": "1m",
+		"CRASHLOOP_BACKOFF_MAX":     "30s",
+	})
+
+	cfg, err := Load()
+	assert.Nil(t, cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "CRASHLOOP_BACKOFF_MAX")
+}
+
+func TestLoad_InvalidSchedulerStrategy(t *testing.T) {
+	setEnv(t, map[string]string{
+		"API_KEY":            "test-key",
+		"SCHEDULER_STRATEGY": "random",
+	})
+
+	cfg, err := Load()
+	assert.Nil(t, cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "SCHEDULER_STRATEGY")
+}
+
+func TestLoad_InvalidSecretEncryptionKey(t *testing.T) {
+	setEnv(t, map[string]string{
+		"API_KEY":               "test-key",
+		"SECRET_ENCRYPTION_KEY": "c2hvcnQ=",
+	})
+
+	cfg, err := Load()
+	assert.Nil(t, cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "SECRET_ENCRYPTION_KEY")
+}
@@ -0,0 +1,372 @@
+// Package daemonset implements the daemon set controller. Each pass it
+// makes sure every Ready node admitted by a daemon set's template runs
+// exactly one of its daemons, removes daemons from nodes that are gone or
+// no longer admit them, and replaces daemons node by node when the
+// template changes.
+package daemonset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/github-builder/container-orchestrator/internal/events"
+	"github.com/github-builder/container-orchestrator/internal/eviction"
+	"github.com/github-builder/container-orchestrator/internal/gc"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// errStale is returned when a daemon set changed while being reconciled.
+var errStale = errors.New("daemon set changed during reconciliation")
+
+// Config holds dependencies for the daemon set controller.
+type Config struct {
+	Store  store.Store
+	Logger zerolog.Logger
+
+	// Interval is how often daemon sets are reconciled.
+	Interval time.Duration
+
+	// Evictor removes ready daemons during rolling updates, subject to
+	// disruption budgets. Defaults to an evictor on Store.
+	Evictor *eviction.Evictor
+
+	// Events records the daemons created and deleted. Defaults to a
+	// recorder on Store.
+	Events *events.Recorder
+}
+
+// Controller reconciles daemon sets with nodes and their daemons.
+type Controller struct {
+	store    store.Store
+	logger   zerolog.Logger
+	interval time.Duration
+	events   *events.Recorder
+	evictor  *eviction.Evictor
+}
+
+// NewController creates a daemon set controller.
+func NewController(cfg *Config) *Controller {
+	recorder := cfg.Events
+	if recorder == nil {
+		recorder = events.NewRecorder(&events.Config{Store: cfg.Store, Logger: cfg.Logger})
+	}
+	evictor := cfg.Evictor
+	if evictor == nil {
+		evictor = eviction.New(&eviction.Config{Store: cfg.Store, Logger: cfg.Logger, Events: recorder})
+	}
+
+	return &Controller{
+		store:    cfg.Store,
+		logger:   cfg.Logger,
+		interval: cfg.Interval,
+		events:   recorder.WithSource("daemonsets"),
+		evictor:  evictor,
+	}
+}
+
+// Run reconciles daemon sets every interval until ctx is canceled.
+func (c *Controller) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Sync(ctx); err != nil {
+			c.logger.Error().Err(err).Msg("daemon set reconciliation failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync reconciles every daemon set once. Daemons of deleted daemon sets
+// are left to the garbage collector, and daemon sets being deleted are not
+// reconciled.
+func (c *Controller) Sync(ctx context.Context) error {
+	daemonSets, err := store.ListJSON[model.DaemonSet](c.store, model.DaemonSetsBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing daemon sets: %w", err)
+	}
+	nodes, err := store.ListJSON[model.Node](c.store, model.NodesBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing nodes: %w", err)
+	}
+	containers, err := store.ListJSON[model.Container](c.store, model.ContainersBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing containers: %w", err)
+	}
+
+	daemons := make(map[string][]*model.Container)
+	for _, ctr := range containers {
+		if owner, ok := ctr.Labels[model.LabelDaemonSet]; ok {
+			key := model.Key(ctr.Namespace, owner)
+			daemons[key] = append(daemons[key], ctr)
+		}
+	}
+
+	var errs []error
+	for _, ds := range daemonSets {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if ds.IsTerminating() {
+			continue
+		}
+		owned, err := gc.Claim(c.store, model.ContainersBucket, model.DaemonSetsBucket, &ds.ObjectMeta, daemons[ds.Key()])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("daemon set %s: %w", ds.Key(), err))
+			continue
+		}
+		if err := c.reconcile(ds, nodes, owned); err != nil && !errors.Is(err, errStale) {
+			errs = append(errs, fmt.Errorf("daemon set %s: %w", ds.Key(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// reconcile places, removes and updates the daemons of one daemon set and
+// records its status.
+func (c *Controller) reconcile(ds *model.DaemonSet, nodes []*model.Node, daemons []*model.Container) error {
+	hash := ds.Spec.Template.Hash()
+	tmpl := &ds.Spec.Template.Spec
+
+	nodeByName := make(map[string]*model.Node, len(nodes))
+	for _, n := range nodes {
+		nodeByName[n.Name] = n
+	}
+
+	var errs []error
+	byNode := make(map[string][]*model.Container)
+	for _, d := range daemons {
+		n := nodeByName[d.Spec.NodeName]
+		switch {
+		case d.Status.IsTerminal(), n == nil, !admits(tmpl, n, true):
+			// Daemons always restart, so a terminal one failed for good
+			// and is replaced. The others are on nodes that left or no
+			// longer admit them.
+			if err := c.deleteDaemon(d); err != nil {
+				errs = append(errs, err)
+			}
+		default:
+			byNode[n.Name] = append(byNode[n.Name], d)
+		}
+	}
+
+	status := model.DaemonSetStatus{TemplateHash: hash}
+	var outdated []*model.Container
+	for _, n := range nodes {
+		d, extra := pick(byNode[n.Name], hash)
+		for _, e := range extra {
+			if err := c.deleteDaemon(e); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		if d == nil {
+			if !n.IsReady() || !admits(tmpl, n, false) {
+				continue
+			}
+			var err error
+			if d, err = c.createDaemon(ds, hash, n.Name); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+
+		status.DesiredNumberScheduled++
+		status.CurrentNumberScheduled++
+		if d.Labels[model.LabelTemplateHash] == hash {
+			status.UpdatedNumberScheduled++
+		} else {
+			outdated = append(outdated, d)
+		}
+		if d.Status.IsReady() {
+			status.NumberReady++
+		} else {
+			status.NumberUnavailable++
+		}
+	}
+
+	if ds.Spec.UpdateStrategy.Type == model.DaemonSetRollingUpdate {
+		if err := c.rollingUpdate(ds, hash, outdated, &status); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := c.writeStatus(ds, &status); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// rollingUpdate replaces outdated daemons. Unready ones go first since
+// replacing them costs no availability; ready ones are replaced only while
+// fewer than MaxUnavailable nodes are without a ready daemon, and are
+// evicted, so disruption budgets can hold the update back further.
+func (c *Controller) rollingUpdate(ds *model.DaemonSet, hash string, outdated []*model.Container, s *model.DaemonSetStatus) error {
+	maxUnavailable := max(1, ds.Spec.UpdateStrategy.MaxUnavailable.Scaled(s.DesiredNumberScheduled, false))
+
+	sort.SliceStable(outdated, func(i, j int) bool {
+		return !outdated[i].Status.IsReady() && outdated[j].Status.IsReady()
+	})
+
+	for _, old := range outdated {
+		if old.Status.IsReady() {
+			if s.NumberUnavailable >= maxUnavailable {
+				return nil
+			}
+			ok, err := c.evictDaemon(old)
+			if err != nil || !ok {
+				return err
+			}
+			s.NumberReady--
+			s.NumberUnavailable++
+		} else if err := c.deleteDaemon(old); err != nil {
+			return err
+		}
+		if _, err := c.createDaemon(ds, hash, old.Spec.NodeName); err != nil {
+			return err
+		}
+		s.UpdatedNumberScheduled++
+	}
+	return nil
+}
+
+// pick chooses the daemon to keep on a node, preferring ready daemons on
+// the current template, and returns the rest.
+func pick(daemons []*model.Container, hash string) (*model.Container, []*model.Container) {
+	if len(daemons) == 0 {
+		return nil, nil
+	}
+	rank := func(d *model.Container) int {
+		r := 0
+		if d.Labels[model.LabelTemplateHash] == hash {
+			r += 2
+		}
+		if d.Status.IsReady() {
+			r++
+		}
+		return r
+	}
+	sort.SliceStable(daemons, func(i, j int) bool {
+		return rank(daemons[i]) > rank(daemons[j])
+	})
+	return daemons[0], daemons[1:]
+}
+
+// admits reports whether a daemon with spec may run on n. NoSchedule taints
+// only keep new daemons off a node; running ones are left in place.
+func admits(spec *model.ContainerSpec, n *model.Node, running bool) bool {
+	for k, v := range spec.NodeSelector {
+		if n.Labels[k] != v {
+			return false
+		}
+	}
+	if spec.Affinity != nil && spec.Affinity.NodeAffinity != nil &&
+		!spec.Affinity.NodeAffinity.MatchesRequired(n.Labels) {
+		return false
+	}
+	for i := range n.Spec.Taints {
+		t := &n.Spec.Taints[i]
+		switch {
+		case t.Effect == model.TaintPreferNoSchedule:
+			continue
+		case t.Effect == model.TaintNoSchedule && running:
+			continue
+		}
+		if model.FindToleration(spec.Tolerations, t) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// createDaemon stores a new daemon bound to node.
+func (c *Controller) createDaemon(ds *model.DaemonSet, hash, node string) (*model.Container, error) {
+	spec, err := ds.Spec.Template.Spec.Clone()
+	if err != nil {
+		return nil, err
+	}
+	spec.NodeName = node
+
+	labels := make(map[string]string, len(ds.Spec.Template.Labels)+2)
+	for k, v := range ds.Spec.Template.Labels {
+		labels[k] = v
+	}
+	labels[model.LabelDaemonSet] = ds.Name
+	labels[model.LabelTemplateHash] = hash
+
+	d := &model.Container{
+		ObjectMeta: model.ObjectMeta{
+			Namespace:       ds.Namespace,
+			Labels:          labels,
+			OwnerReferences: []model.OwnerReference{model.NewControllerRef(model.DaemonSetsBucket, &ds.ObjectMeta)},
+		},
+		Spec: *spec,
+	}
+	d.SetDefaults()
+
+	if err := gc.Create(c.store, model.ContainersBucket, ds.Name, d); err != nil {
+		return nil, fmt.Errorf("creating daemon: %w", err)
+	}
+	c.logger.Info().Str("daemonset", ds.Name).Str("container", d.Name).Str("node", node).Msg("daemon created")
+	c.events.Eventf(model.DaemonSetsBucket, &ds.ObjectMeta, model.EventNormal, model.EventCreated, "Created container %s", d.Name)
+	return d, nil
+}
+
+// deleteDaemon deletes a daemon; the agent on its node stops the runtime
+// container.
+func (c *Controller) deleteDaemon(d *model.Container) error {
+	if _, err := gc.Delete(c.store, model.ContainersBucket, &d.ObjectMeta); err != nil {
+		return fmt.Errorf("deleting daemon %s: %w", d.Key(), err)
+	}
+	c.logger.Info().Str("container", d.Name).Str("daemonset", d.Labels[model.LabelDaemonSet]).
+		Str("node", d.Spec.NodeName).Msg("daemon deleted")
+	c.events.OwnerEventf(&d.ObjectMeta, model.EventNormal, model.EventDeleted, "Deleted container %s", d.Name)
+	return nil
+}
+
+// evictDaemon removes a ready daemon through the evictor. It reports
+// whether the daemon is gone; a disruption budget may keep it for now.
+func (c *Controller) evictDaemon(d *model.Container) (bool, error) {
+	err := c.evictor.Delete(d, "replaced by rolling update")
+	var berr *eviction.BudgetError
+	switch {
+	case err == nil:
+		c.events.OwnerEventf(&d.ObjectMeta, model.EventNormal, model.EventDeleted, "Deleted container %s", d.Name)
+		return true, nil
+	case errors.Is(err, eviction.ErrStale):
+		return true, nil
+	case errors.As(err, &berr):
+		c.logger.Debug().Err(err).Str("daemonset", d.Labels[model.LabelDaemonSet]).Msg("rolling update held by disruption budget")
+		return false, nil
+	default:
+		return false, fmt.Errorf("deleting daemon %s: %w", d.Key(), err)
+	}
+}
+
+// writeStatus persists status if it changed, provided the daemon set has
+// not been replaced since it was read.
+func (c *Controller) writeStatus(ds *model.DaemonSet, status *model.DaemonSetStatus) error {
+	if reflect.DeepEqual(&ds.Status, status) {
+		return nil
+	}
+	_, err := gc.Update(c.store, model.DaemonSetsBucket, &ds.ObjectMeta, func(cur *model.DaemonSet) error {
+		cur.Status = *status
+		return nil
+	})
+	if errors.Is(err, gc.ErrStale) {
+		return errStale
+	}
+	return err
+}
@@ -0,0 +1,287 @@
+package daemonset
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/gc"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+func newTestController(t *testing.T) (*Controller, store.Store) {
+	t.Helper()
+	s := store.NewMemoryStore()
+	return NewController(&Config{Store: s, Logger: zerolog.Nop(), Interval: time.Hour}), s
+}
+
+func testDaemonSet(name string) *model.DaemonSet {
+	ds := &model.DaemonSet{
+		ObjectMeta: model.ObjectMeta{Name: name, UID: name + "-uid"},
+		Spec: model.DaemonSetSpec{
+			Template: model.ContainerTemplate{
+				Labels: map[string]string{"app": name},
+				Spec:   model.ContainerSpec{Image: "shipper:v1"},
+			},
+		},
+	}
+	ds.SetDefaults()
+	return ds
+}
+
+func putDaemonSet(t *testing.T, s store.Store, ds *model.DaemonSet) {
+	t.Helper()
+	require.NoError(t, ds.Validate())
+	require.NoError(t, store.PutJSON(s, model.DaemonSetsBucket, ds.Name, ds))
+}
+
+func getDaemonSet(t *testing.T, s store.Store, name string) *model.DaemonSet {
+	t.Helper()
+	ds, err := store.GetJSON[model.DaemonSet](s, model.DaemonSetsBucket, name)
+	require.NoError(t, err)
+	return ds
+}
+
+func putNode(t *testing.T, s store.Store, name string, labels map[string]string, mutate func(n *model.Node)) {
+	t.Helper()
+	n := &model.Node{
+		ObjectMeta: model.ObjectMeta{Name: name, Labels: labels},
+		Spec:       model.NodeSpec{Capacity: model.Resources{CPUMillis: 1000, MemoryBytes: 1 << 30}},
+		Status:     model.NodeStatus{Phase: model.NodeReady},
+	}
+	if mutate != nil {
+		mutate(n)
+	}
+	require.NoError(t, store.PutJSON(s, model.NodesBucket, name, n))
+}
+
+// daemonsByNode maps node names to the images of the daemons on them.
+func daemonsByNode(t *testing.T, s store.Store, ds string) map[string][]string {
+	t.Helper()
+	all, err := store.ListJSON[model.Container](s, model.ContainersBucket, "")
+	require.NoError(t, err)
+	out := make(map[string][]string)
+	for _, c := range all {
+		if c.Labels[model.LabelDaemonSet] == ds {
+			out[c.Spec.NodeName] = append(out[c.Spec.NodeName], c.Spec.Image)
+		}
+	}
+	return out
+}
+
+// markAllReady simulates the agents bringing every daemon up.
+func markAllReady(t *testing.T, s store.Store) {
+	t.Helper()
+	all, err := store.ListJSON[model.Container](s, model.ContainersBucket, "")
+	require.NoError(t, err)
+	for _, c := range all {
+		_, err := store.UpdateJSON(s, model.ContainersBucket, c.Name, func(c *model.Container) error {
+			c.Status.Phase = model.PhaseRunning
+			c.Status.Ready = true
+			return nil
+		})
+		require.NoError(t, err)
+	}
+}
+
+func syncOnce(t *testing.T, c *Controller) {
+	t.Helper()
+	require.NoError(t, c.Sync(context.Background()))
+}
+
+func TestSync_OneDaemonPerEligibleNode(t *testing.T) {
+	c, s := newTestController(t)
+	ds := testDaemonSet("logs")
+	ds.Spec.Template.Spec.NodeSelector = map[string]string{"role": "worker"}
+	putDaemonSet(t, s, ds)
+
+	putNode(t, s, "worker-1", map[string]string{"role": "worker"}, nil)
+	putNode(t, s, "worker-2", map[string]string{"role": "worker"}, nil)
+	putNode(t, s, "control", map[string]string{"role": "control"}, nil)
+	putNode(t, s, "down", map[string]string{"role": "worker"}, func(n *model.Node) {
+		n.Status.Phase = model.NodeNotReady
+	})
+	putNode(t, s, "tainted", map[string]string{"role": "worker"}, func(n *model.Node) {
+		n.Spec.Taints = []model.Taint{{Key: "gpu", Effect: model.TaintNoSchedule}}
+	})
+
+	syncOnce(t, c)
+	syncOnce(t, c)
+
+	assert.Equal(t, map[string][]string{
+		"worker-1": {"shipper:v1"},
+		"worker-2": {"shipper:v1"},
+	}, daemonsByNode(t, s, "logs"))
+
+	got := getDaemonSet(t, s, "logs")
+	assert.Equal(t, 2, got.Status.DesiredNumberScheduled)
+	assert.Equal(t, 2, got.Status.CurrentNumberScheduled)
+	assert.Equal(t, 2, got.Status.NumberUnavailable)
+
+	markAllReady(t, s)
+	syncOnce(t, c)
+	assert.Equal(t, 2, getDaemonSet(t, s, "logs").Status.NumberReady)
+}
+
+func TestSync_FollowsNodes(t *testing.T) {
+	c, s := newTestController(t)
+	ds := testDaemonSet("logs")
+	ds.Spec.Template.Spec.NodeSelector = map[string]string{"role": "worker"}
+	putDaemonSet(t, s, ds)
+	putNode(t, s, "worker-1", map[string]string{"role": "worker"}, nil)
+	syncOnce(t, c)
+
+	// A node registers, another becomes eligible.
+	putNode(t, s, "worker-2", map[string]string{"role": "worker"}, nil)
+	putNode(t, s, "spare", nil, nil)
+	syncOnce(t, c)
+	putNode(t, s, "spare", map[string]string{"role": "worker"}, nil)
+	syncOnce(t, c)
+	assert.Len(t, daemonsByNode(t, s, "logs"), 3)
+
+	// One leaves, another stops matching, a third is tainted NoSchedule,
+	// which keeps its running daemon.
+	require.NoError(t, s.Delete(model.NodesBucket, "worker-2"))
+	putNode(t, s, "spare", map[string]string{"role": "spare"}, nil)
+	putNode(t, s, "worker-1", map[string]string{"role": "worker"}, func(n *model.Node) {
+		n.Spec.Taints = []model.Taint{{Key: "maintenance", Effect: model.TaintNoSchedule}}
+	})
+	syncOnce(t, c)
+	assert.Equal(t, map[string][]string{"worker-1": {"shipper:v1"}}, daemonsByNode(t, s, "logs"))
+
+	// NoExecute removes it.
+	putNode(t, s, "worker-1", map[string]string{"role": "worker"}, func(n *model.Node) {
+		n.Spec.Taints = []model.Taint{{Key: "maintenance", Effect: model.TaintNoExecute}}
+	})
+	syncOnce(t, c)
+	assert.Empty(t, daemonsByNode(t, s, "logs"))
+}
+
+func TestSync_Tolerations(t *testing.T) {
+	c, s := newTestController(t)
+	ds := testDaemonSet("exporter")
+	ds.Spec.Template.Spec.Tolerations = []model.Toleration{{Operator: model.TolerationExists}}
+	putDaemonSet(t, s, ds)
+	putNode(t, s, "control", nil, func(n *model.Node) {
+		n.Spec.Taints = []model.Taint{{Key: "control-plane", Effect: model.TaintNoSchedule}}
+	})
+
+	syncOnce(t, c)
+
+	assert.Len(t, daemonsByNode(t, s, "exporter")["control"], 1)
+}
+
+func TestSync_RollingUpdateOneNodeAtATime(t *testing.T) {
+	c, s := newTestController(t)
+	putDaemonSet(t, s, testDaemonSet("logs"))
+	for _, n := range []string{"a", "b", "c"} {
+		putNode(t, s, n, nil, nil)
+	}
+	syncOnce(t, c)
+	markAllReady(t, s)
+
+	ds := getDaemonSet(t, s, "logs")
+	ds.Spec.Template.Spec.Image = "shipper:v2"
+	putDaemonSet(t, s, ds)
+
+	countImages := func() map[string]int {
+		out := make(map[string]int)
+		for _, images := range daemonsByNode(t, s, "logs") {
+			require.Len(t, images, 1)
+			out[images[0]]++
+		}
+		return out
+	}
+
+	syncOnce(t, c)
+	assert.Equal(t, map[string]int{"shipper:v1": 2, "shipper:v2": 1}, countImages())
+
+	// Nothing more happens until the new daemon is ready.
+	syncOnce(t, c)
+	assert.Equal(t, map[string]int{"shipper:v1": 2, "shipper:v2": 1}, countImages())
+
+	for _, want := range []int{2, 3} {
+		markAllReady(t, s)
+		syncOnce(t, c)
+		assert.Equal(t, want, countImages()["shipper:v2"])
+	}
+
+	markAllReady(t, s)
+	syncOnce(t, c)
+	got := getDaemonSet(t, s, "logs")
+	assert.Equal(t, 3, got.Status.UpdatedNumberScheduled)
+	assert.Equal(t, 3, got.Status.NumberReady)
+}
+
+func TestSync_RollingUpdateRespectsDisruptionBudget(t *testing.T) {
+	c, s := newTestController(t)
+	putDaemonSet(t, s, testDaemonSet("logs"))
+	putNode(t, s, "a", nil, nil)
+	syncOnce(t, c)
+	markAllReady(t, s)
+	b := &model.DisruptionBudget{
+		ObjectMeta: model.ObjectMeta{Name: "logs"},
+		Spec: model.DisruptionBudgetSpec{
+			Selector:       model.LabelSelector{MatchLabels: map[string]string{model.LabelDaemonSet: "logs"}},
+			MaxUnavailable: model.FromInt(0),
+		},
+	}
+	require.NoError(t, store.PutJSON(s, model.DisruptionBudgetsBucket, b.Key(), b))
+
+	ds := getDaemonSet(t, s, "logs")
+	ds.Spec.Template.Spec.Image = "shipper:v2"
+	putDaemonSet(t, s, ds)
+
+	syncOnce(t, c)
+	assert.Equal(t, []string{"shipper:v1"}, daemonsByNode(t, s, "logs")["a"], "budget bypassed")
+
+	require.NoError(t, s.Delete(model.DisruptionBudgetsBucket, b.Key()))
+	syncOnce(t, c)
+	assert.Equal(t, []string{"shipper:v2"}, daemonsByNode(t, s, "logs")["a"])
+}
+
+func TestSync_OnDelete(t *testing.T) {
+	c, s := newTestController(t)
+	ds := testDaemonSet("logs")
+	ds.Spec.UpdateStrategy = model.DaemonSetUpdateStrategy{Type: model.DaemonSetOnDelete}
+	putDaemonSet(t, s, ds)
+	putNode(t, s, "a", nil, nil)
+	syncOnce(t, c)
+	markAllReady(t, s)
+
+	ds.Spec.Template.Spec.Image = "shipper:v2"
+	putDaemonSet(t, s, ds)
+	syncOnce(t, c)
+	assert.Equal(t, []string{"shipper:v1"}, daemonsByNode(t, s, "logs")["a"])
+	assert.Zero(t, getDaemonSet(t, s, "logs").Status.UpdatedNumberScheduled)
+}
+
+func TestSync_DaemonsAreCollectedWithTheirDaemonSet(t *testing.T) {
+	c, s := newTestController(t)
+	putDaemonSet(t, s, testDaemonSet("logs"))
+	putNode(t, s, "a", nil, nil)
+	syncOnce(t, c)
+	require.Len(t, daemonsByNode(t, s, "logs"), 1)
+	containers, err := store.ListJSON[model.Container](s, model.ContainersBucket, "")
+	require.NoError(t, err)
+	assert.Equal(t, &model.OwnerReference{
+		Kind: model.DaemonSetsBucket, Name: "logs", UID: "logs-uid", Controller: true, BlockOwnerDeletion: true,
+	}, containers[0].ControllerRef())
+
+	require.NoError(t, s.Delete(model.DaemonSetsBucket, "logs"))
+	syncOnce(t, c)
+	collect(t, s)
+
+	assert.Empty(t, daemonsByNode(t, s, "logs"))
+}
+
+// collect runs a garbage collection pass.
+func collect(t *testing.T, s store.Store) {
+	t.Helper()
+	require.NoError(t, gc.NewCollector(&gc.Config{Store: s, Logger: zerolog.Nop()}).Sync(context.Background()))
+}
@@ -0,0 +1,370 @@
+// Package deployment implements the deployment controller. Each pass it
+// compares every deployment with the containers carrying its label and
+// creates or deletes replicas to move toward the desired state, rolling
+// out template changes according to the deployment's strategy.
+package deployment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/github-builder/container-orchestrator/internal/events"
+	"github.com/github-builder/container-orchestrator/internal/eviction"
+	"github.com/github-builder/container-orchestrator/internal/gc"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// errStale is returned when a deployment changed while being reconciled.
+var errStale = errors.New("deployment changed during reconciliation")
+
+// Config holds dependencies for the deployment controller.
+type Config struct {
+	Store  store.Store
+	Logger zerolog.Logger
+
+	// Interval is how often deployments are reconciled.
+	Interval time.Duration
+
+	// Evictor removes ready replicas during rollouts and scale-downs,
+	// subject to disruption budgets. Defaults to an evictor on Store.
+	Evictor *eviction.Evictor
+
+	// Events records the replicas created and deleted. Defaults to a
+	// recorder on Store.
+	Events *events.Recorder
+}
+
+// Controller reconciles deployments with their replicas.
+type Controller struct {
+	store    store.Store
+	logger   zerolog.Logger
+	interval time.Duration
+	events   *events.Recorder
+	evictor  *eviction.Evictor
+
+	// now is replaceable for tests.
+	now func() time.Time
+}
+
+// NewController creates a deployment controller.
+func NewController(cfg *Config) *Controller {
+	recorder := cfg.Events
+	if recorder == nil {
+		recorder = events.NewRecorder(&events.Config{Store: cfg.Store, Logger: cfg.Logger})
+	}
+	evictor := cfg.Evictor
+	if evictor == nil {
+		evictor = eviction.New(&eviction.Config{Store: cfg.Store, Logger: cfg.Logger, Events: recorder})
+	}
+
+	return &Controller{
+		store:    cfg.Store,
+		logger:   cfg.Logger,
+		interval: cfg.Interval,
+		events:   recorder.WithSource("deployments"),
+		evictor:  evictor,
+		now:      func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run reconciles deployments every interval until ctx is canceled.
+func (c *Controller) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Sync(ctx); err != nil {
+			c.logger.Error().Err(err).Msg("deployment reconciliation failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync reconciles every deployment once and deletes revisions whose
+// deployment no longer exists. Replicas of deleted deployments are left to
+// the garbage collector, and deployments being deleted are not reconciled.
+func (c *Controller) Sync(ctx context.Context) error {
+	deployments, err := store.ListJSON[model.Deployment](c.store, model.DeploymentsBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing deployments: %w", err)
+	}
+	containers, err := store.ListJSON[model.Container](c.store, model.ContainersBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing containers: %w", err)
+	}
+
+	replicas := make(map[string][]*model.Container)
+	for _, ctr := range containers {
+		if owner, ok := ctr.Labels[model.LabelDeployment]; ok {
+			key := model.Key(ctr.Namespace, owner)
+			replicas[key] = append(replicas[key], ctr)
+		}
+	}
+
+	var errs []error
+	for _, d := range deployments {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsTerminating() {
+			continue
+		}
+		owned, err := gc.Claim(c.store, model.ContainersBucket, model.DeploymentsBucket, &d.ObjectMeta, replicas[d.Key()])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("deployment %s: %w", d.Key(), err))
+			continue
+		}
+		if err := c.reconcile(d, owned); err != nil && !errors.Is(err, errStale) {
+			errs = append(errs, fmt.Errorf("deployment %s: %w", d.Key(), err))
+		}
+	}
+	if err := c.deleteOrphanedRevisions(deployments); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// reconcile drives one deployment toward its spec and records its status.
+func (c *Controller) reconcile(d *model.Deployment, replicas []*model.Container) error {
+	hash := d.Spec.Template.Hash()
+	now := c.now()
+
+	var current, old []*model.Container
+	var errs []error
+	for _, r := range replicas {
+		switch {
+		case r.Status.IsTerminal():
+			// Replicas always restart, so a terminal one failed for good;
+			// remove it and let it be replaced.
+			if err := c.deleteReplica(r); err != nil {
+				errs = append(errs, err)
+			}
+		case r.Labels[model.LabelTemplateHash] == hash:
+			current = append(current, r)
+		default:
+			old = append(old, r)
+		}
+	}
+
+	status := d.Status
+	if status.TemplateHash != hash {
+		status.Revision++
+		status.TemplateHash = hash
+		status.Rollout = model.RolloutStatus{
+			Phase:          model.RolloutProgressing,
+			Reason:         model.ReasonNewTemplate,
+			Message:        fmt.Sprintf("rolling out revision %d", status.Revision),
+			StartedAt:      &now,
+			LastProgressAt: &now,
+		}
+		status.Canary = nil
+		status.BlueGreen = nil
+		status.Promote = false
+		status.Abort = false
+		c.logger.Info().Str("deployment", d.Name).Int64("revision", status.Revision).Msg("rollout started")
+	}
+
+	var o outcome
+	if !d.Spec.Paused {
+		var err error
+		current, old, o, err = c.rollout(d, &status, hash, current, old, now)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	c.updateRollout(d, &status, current, old, o, now)
+
+	if err := c.writeStatus(d, hash, &status); err != nil {
+		errs = append(errs, err)
+		return errors.Join(errs...)
+	}
+	d.Status = status
+	if err := c.recordRevision(d); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// outcome is what a strategy reports back about the rollout.
+type outcome struct {
+	// waiting explains why the rollout is holding for promotion or a
+	// timer, and reason says what it holds for. Holding does not count
+	// against the progress deadline.
+	waiting string
+	reason  string
+
+	// aborted is set when the rollout was aborted and the stable template
+	// restored.
+	aborted bool
+}
+
+// rollout runs one step of the deployment's strategy. Canary and blue-green
+// only apply once there is a stable template to move away from; the first
+// rollout of a deployment and a return to the stable template simply scale
+// the current template.
+func (c *Controller) rollout(d *model.Deployment, s *model.DeploymentStatus, hash string, current, old []*model.Container, now time.Time) ([]*model.Container, []*model.Container, outcome, error) {
+	if s.StableTemplateHash != "" && s.StableTemplateHash != hash {
+		switch d.Spec.Strategy.Type {
+		case model.StrategyCanary:
+			return c.canary(d, s, hash, current, old, now)
+		case model.StrategyBlueGreen:
+			return c.blueGreen(d, s, hash, current, old, now)
+		}
+	}
+	current, old, err := c.rollingUpdate(d, hash, current, old)
+	return current, old, outcome{}, err
+}
+
+// updateRollout computes replica counts and advances the rollout phase.
+func (c *Controller) updateRollout(d *model.Deployment, s *model.DeploymentStatus, current, old []*model.Container, o outcome, now time.Time) {
+	desired := d.Spec.Replicas
+	readyCurrent := countReady(current)
+
+	prev := *s
+	s.Replicas = len(current) + len(old)
+	s.UpdatedReplicas = len(current)
+	s.ReadyReplicas = readyCurrent + countReady(old)
+	s.UnavailableReplicas = max(desired-s.ReadyReplicas, 0)
+
+	progressed := s.Replicas != prev.Replicas ||
+		s.UpdatedReplicas != prev.UpdatedReplicas ||
+		s.ReadyReplicas != prev.ReadyReplicas
+	complete := len(current) == desired && len(old) == 0 && readyCurrent == desired
+
+	r := &s.Rollout
+	switch {
+	case d.Spec.Paused:
+		if r.Phase != model.RolloutPaused {
+			*r = model.RolloutStatus{
+				Phase:     model.RolloutPaused,
+				Message:   "rollout paused",
+				StartedAt: r.StartedAt,
+			}
+		}
+
+	case o.aborted:
+		if r.Phase != model.RolloutAborted {
+			*r = model.RolloutStatus{
+				Phase:     model.RolloutAborted,
+				Reason:    model.ReasonAborted,
+				StartedAt: r.StartedAt,
+			}
+			c.logger.Warn().Str("deployment", d.Name).Int64("revision", s.Revision).Msg("rollout aborted")
+		}
+		r.Message = fmt.Sprintf("rollout of revision %d aborted; %d of %d stable replicas are ready",
+			s.Revision, countReady(old), desired)
+
+	case complete:
+		s.StableTemplateHash = s.TemplateHash
+		if d.Spec.Strategy.Type == model.StrategyBlueGreen {
+			s.ActiveTemplateHash = s.TemplateHash
+		} else {
+			s.ActiveTemplateHash = ""
+		}
+		s.Canary = nil
+		s.BlueGreen = nil
+		s.Promote = false
+		if r.Phase != model.RolloutComplete {
+			*r = model.RolloutStatus{
+				Phase:          model.RolloutComplete,
+				Message:        fmt.Sprintf("revision %d rolled out to %d replicas", s.Revision, desired),
+				StartedAt:      r.StartedAt,
+				LastProgressAt: &now,
+				CompletedAt:    &now,
+			}
+			c.logger.Info().Str("deployment", d.Name).Int64("revision", s.Revision).Msg("rollout complete")
+		}
+
+	case o.waiting != "":
+		r.Phase = model.RolloutProgressing
+		r.Reason = o.reason
+		r.Message = o.waiting
+		r.CompletedAt = nil
+		r.LastProgressAt = &now
+
+	default:
+		// Resuming, scaling after completion, or recovering after a missed
+		// deadline all restart the progress clock.
+		if r.Phase != model.RolloutProgressing && (r.Phase != model.RolloutFailed || progressed) {
+			r.Phase = model.RolloutProgressing
+			r.CompletedAt = nil
+			r.LastProgressAt = &now
+			if r.StartedAt == nil {
+				r.StartedAt = &now
+			}
+		}
+		if r.Phase == model.RolloutProgressing && r.Reason != model.ReasonNewTemplate {
+			r.Reason = ""
+		}
+		if progressed || r.LastProgressAt == nil {
+			r.LastProgressAt = &now
+		}
+		if r.Phase == model.RolloutProgressing {
+			r.Message = progressMessage(desired, len(current), readyCurrent, len(old))
+
+			deadline := d.Spec.ProgressDeadline()
+			if deadline > 0 && now.Sub(*r.LastProgressAt) > deadline {
+				r.Phase = model.RolloutFailed
+				r.Reason = model.ReasonProgressDeadlineExceeded
+				r.Message = fmt.Sprintf("revision %d made no progress for %s: %s",
+					s.Revision, deadline, r.Message)
+				c.logger.Warn().Str("deployment", d.Name).Int64("revision", s.Revision).
+					Msg("rollout exceeded its progress deadline")
+			}
+		}
+	}
+}
+
+func progressMessage(desired, updated, readyUpdated, old int) string {
+	if old > 0 {
+		return fmt.Sprintf("%d of %d updated replicas are ready, %d old replicas pending termination",
+			readyUpdated, desired, old)
+	}
+	if updated < desired {
+		return fmt.Sprintf("%d of %d updated replicas created", updated, desired)
+	}
+	return fmt.Sprintf("%d of %d updated replicas are ready", readyUpdated, desired)
+}
+
+// writeStatus persists status if it changed, provided the deployment still
+// has the template it was reconciled against and no promote or abort
+// request arrived in the meantime.
+func (c *Controller) writeStatus(d *model.Deployment, hash string, status *model.DeploymentStatus) error {
+	if reflect.DeepEqual(&d.Status, status) {
+		return nil
+	}
+	_, err := gc.Update(c.store, model.DeploymentsBucket, &d.ObjectMeta, func(cur *model.Deployment) error {
+		if cur.Spec.Template.Hash() != hash ||
+			cur.Status.Promote != d.Status.Promote || cur.Status.Abort != d.Status.Abort {
+			return errStale
+		}
+		cur.Status = *status
+		return nil
+	})
+	if errors.Is(err, gc.ErrStale) {
+		return errStale
+	}
+	return err
+}
+
+func countReady(cs []*model.Container) int {
+	n := 0
+	for _, c := range cs {
+		if c.Status.IsReady() {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,341 @@
+package deployment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/gc"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+func newTestController(t *testing.T) (*Controller, store.Store, *time.Time) {
+	t.Helper()
+	s := store.NewMemoryStore()
+	c := NewController(&Config{Store: s, Logger: zerolog.Nop(), Interval: time.Hour})
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	return c, s, &now
+}
+
+func testDeployment(name string, replicas int) *model.Deployment {
+	d := &model.Deployment{
+		ObjectMeta: model.ObjectMeta{Name: name, Namespace: model.DefaultNamespace, UID: name + "-uid"},
+		Spec: model.DeploymentSpec{
+			Replicas: replicas,
+			Template: model.ContainerTemplate{
+				Labels: map[string]string{"app": name},
+				Spec:   model.ContainerSpec{Image: "app:v1"},
+			},
+		},
+	}
+	d.SetDefaults()
+	return d
+}
+
+func putDeployment(t *testing.T, s store.Store, d *model.Deployment) {
+	t.Helper()
+	require.NoError(t, d.Validate())
+	require.NoError(t, store.PutJSON(s, model.DeploymentsBucket, d.Key(), d))
+}
+
+func getDeployment(t *testing.T, s store.Store, name string) *model.Deployment {
+	t.Helper()
+	d, err := store.GetJSON[model.Deployment](s, model.DeploymentsBucket, model.Key(model.DefaultNamespace, name))
+	require.NoError(t, err)
+	return d
+}
+
+// updateDeployment applies fn to the stored deployment.
+func updateDeployment(t *testing.T, s store.Store, name string, fn func(d *model.Deployment)) {
+	t.Helper()
+	_, err := store.UpdateJSON(s, model.DeploymentsBucket, model.Key(model.DefaultNamespace, name), func(d *model.Deployment) error {
+		fn(d)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func listReplicas(t *testing.T, s store.Store, deployment string) []*model.Container {
+	t.Helper()
+	all, err := store.ListJSON[model.Container](s, model.ContainersBucket, "")
+	require.NoError(t, err)
+	var out []*model.Container
+	for _, c := range all {
+		if c.Labels[model.LabelDeployment] == deployment {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// markAllReady simulates the agent and prober bringing every replica up.
+func markAllReady(t *testing.T, s store.Store, deployment string) {
+	t.Helper()
+	for _, c := range listReplicas(t, s, deployment) {
+		_, err := store.UpdateJSON(s, model.ContainersBucket, c.Key(), func(c *model.Container) error {
+			c.Status.Phase = model.PhaseRunning
+			c.Status.Ready = true
+			return nil
+		})
+		require.NoError(t, err)
+	}
+}
+
+func syncOnce(t *testing.T, c *Controller) {
+	t.Helper()
+	require.NoError(t, c.Sync(context.Background()))
+}
+
+func TestSync_CreatesReplicas(t *testing.T) {
+	c, s, _ := newTestController(t)
+	putDeployment(t, s, testDeployment("web", 3))
+
+	syncOnce(t, c)
+
+	replicas := listReplicas(t, s, "web")
+	require.Len(t, replicas, 3)
+	d := getDeployment(t, s, "web")
+	for _, r := range replicas {
+		assert.Equal(t, "web", r.Labels["app"])
+		assert.Equal(t, d.Status.TemplateHash, r.Labels[model.LabelTemplateHash])
+		assert.Equal(t, "app:v1", r.Spec.Image)
+		assert.Equal(t, model.PhasePending, r.Status.Phase)
+		assert.NoError(t, r.Validate())
+	}
+	assert.Equal(t, int64(1), d.Status.Revision)
+	assert.Equal(t, model.RolloutProgressing, d.Status.Rollout.Phase)
+	assert.Equal(t, 3, d.Status.UnavailableReplicas)
+
+	markAllReady(t, s, "web")
+	syncOnce(t, c)
+
+	d = getDeployment(t, s, "web")
+	assert.Equal(t, model.RolloutComplete, d.Status.Rollout.Phase)
+	assert.Equal(t, 3, d.Status.ReadyReplicas)
+	assert.NotNil(t, d.Status.Rollout.CompletedAt)
+}
+
+func TestSync_RollingUpdateRespectsBounds(t *testing.T) {
+	c, s, _ := newTestController(t)
+	d := testDeployment("web", 4)
+	d.Spec.Strategy.RollingUpdate.MaxSurge = model.FromInt(1)
+	d.Spec.Strategy.RollingUpdate.MaxUnavailable = model.FromInt(1)
+	putDeployment(t, s, d)
+
+	syncOnce(t, c)
+	markAllReady(t, s, "web")
+	syncOnce(t, c)
+
+	updateDeployment(t, s, "web", func(d *model.Deployment) { d.Spec.Template.Spec.Image = "app:v2" })
+
+	for i := 0; ; i++ {
+		require.Less(t, i, 20, "rollout did not converge")
+		syncOnce(t, c)
+
+		replicas := listReplicas(t, s, "web")
+		ready := 0
+		for _, r := range replicas {
+			if r.Status.Ready {
+				ready++
+			}
+		}
+		assert.LessOrEqual(t, len(replicas), 5, "max surge exceeded")
+		assert.GreaterOrEqual(t, ready, 3, "max unavailable exceeded")
+
+		if getDeployment(t, s, "web").Status.Rollout.Phase == model.RolloutComplete {
+			break
+		}
+		markAllReady(t, s, "web")
+	}
+
+	d = getDeployment(t, s, "web")
+	assert.Equal(t, int64(2), d.Status.Revision)
+	for _, r := range listReplicas(t, s, "web") {
+		assert.Equal(t, "app:v2", r.Spec.Image)
+	}
+}
+
+func TestSync_RollingUpdateRespectsDisruptionBudget(t *testing.T) {
+	c, s, _ := newTestController(t)
+	d := testDeployment("web", 4)
+	d.Spec.Strategy.RollingUpdate.MaxSurge = model.FromInt(0)
+	d.Spec.Strategy.RollingUpdate.MaxUnavailable = model.FromInt(2)
+	putDeployment(t, s, d)
+	b := &model.DisruptionBudget{
+		ObjectMeta: model.ObjectMeta{Name: "web", Namespace: model.DefaultNamespace},
+		Spec: model.DisruptionBudgetSpec{
+			Selector:       model.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			MaxUnavailable: model.FromInt(1),
+		},
+	}
+	require.NoError(t, store.PutJSON(s, model.DisruptionBudgetsBucket, b.Key(), b))
+
+	syncOnce(t, c)
+	markAllReady(t, s, "web")
+	syncOnce(t, c)
+
+	updateDeployment(t, s, "web", func(d *model.Deployment) { d.Spec.Template.Spec.Image = "app:v2" })
+
+	// The strategy would take two replicas down at once; the budget one.
+	for i := 0; ; i++ {
+		require.Less(t, i, 20, "rollout did not converge")
+		syncOnce(t, c)
+
+		ready := 0
+		for _, r := range listReplicas(t, s, "web") {
+			if r.Status.Ready {
+				ready++
+			}
+		}
+		assert.GreaterOrEqual(t, ready, 3, "disruption budget exceeded")
+
+		if getDeployment(t, s, "web").Status.Rollout.Phase == model.RolloutComplete {
+			break
+		}
+		markAllReady(t, s, "web")
+	}
+}
+
+func TestSync_RollingUpdateWaitsForReadiness(t *testing.T) {
+	c, s, _ := newTestController(t)
+	d := testDeployment("web", 2)
+	d.Spec.Strategy.RollingUpdate.MaxSurge = model.FromInt(1)
+	d.Spec.Strategy.RollingUpdate.MaxUnavailable = model.FromInt(0)
+	putDeployment(t, s, d)
+	syncOnce(t, c)
+	markAllReady(t, s, "web")
+	syncOnce(t, c)
+
+	updateDeployment(t, s, "web", func(d *model.Deployment) { d.Spec.Template.Spec.Image = "app:v2" })
+
+	// The new replica never becomes ready, so no old replica is removed.
+	for range 3 {
+		syncOnce(t, c)
+	}
+	d = getDeployment(t, s, "web")
+	assert.Equal(t, 3, d.Status.Replicas)
+	assert.Equal(t, 1, d.Status.UpdatedReplicas)
+	assert.Equal(t, 2, d.Status.ReadyReplicas)
+	assert.Equal(t, model.RolloutProgressing, d.Status.Rollout.Phase)
+}
+
+func TestSync_ProgressDeadline(t *testing.T) {
+	c, s, now := newTestController(t)
+	d := testDeployment("web", 1)
+	d.Spec.ProgressDeadlineSeconds = 60
+	putDeployment(t, s, d)
+
+	syncOnce(t, c)
+	*now = now.Add(61 * time.Second)
+	syncOnce(t, c)
+
+	d = getDeployment(t, s, "web")
+	assert.Equal(t, model.RolloutFailed, d.Status.Rollout.Phase)
+	assert.Equal(t, model.ReasonProgressDeadlineExceeded, d.Status.Rollout.Reason)
+
+	// Progress after the deadline resumes the rollout.
+	markAllReady(t, s, "web")
+	syncOnce(t, c)
+	assert.Equal(t, model.RolloutComplete, getDeployment(t, s, "web").Status.Rollout.Phase)
+}
+
+func TestSync_PauseAndResume(t *testing.T) {
+	c, s, now := newTestController(t)
+	d := testDeployment("web", 2)
+	d.Spec.ProgressDeadlineSeconds = 60
+	putDeployment(t, s, d)
+	syncOnce(t, c)
+	markAllReady(t, s, "web")
+	syncOnce(t, c)
+
+	updateDeployment(t, s, "web", func(d *model.Deployment) {
+		d.Spec.Paused = true
+		d.Spec.Template.Spec.Image = "app:v2"
+	})
+	syncOnce(t, c)
+
+	d = getDeployment(t, s, "web")
+	assert.Equal(t, model.RolloutPaused, d.Status.Rollout.Phase)
+	assert.Equal(t, int64(2), d.Status.Revision)
+	assert.Equal(t, 0, d.Status.UpdatedReplicas)
+
+	// Time spent paused does not count against the progress deadline.
+	*now = now.Add(time.Hour)
+	updateDeployment(t, s, "web", func(d *model.Deployment) { d.Spec.Paused = false })
+	syncOnce(t, c)
+
+	d = getDeployment(t, s, "web")
+	assert.Equal(t, model.RolloutProgressing, d.Status.Rollout.Phase)
+	assert.Equal(t, 1, d.Status.UpdatedReplicas)
+}
+
+func TestSync_ScaleDownRemovesUnreadyFirst(t *testing.T) {
+	c, s, _ := newTestController(t)
+	putDeployment(t, s, testDeployment("web", 3))
+	syncOnce(t, c)
+
+	replicas := listReplicas(t, s, "web")
+	_, err := store.UpdateJSON(s, model.ContainersBucket, replicas[0].Key(), func(c *model.Container) error {
+		c.Status.Phase = model.PhaseRunning
+		c.Status.Ready = true
+		return nil
+	})
+	require.NoError(t, err)
+
+	updateDeployment(t, s, "web", func(d *model.Deployment) { d.Spec.Replicas = 1 })
+	syncOnce(t, c)
+
+	remaining := listReplicas(t, s, "web")
+	require.Len(t, remaining, 1)
+	assert.Equal(t, replicas[0].Name, remaining[0].Name)
+}
+
+func TestSync_ReplacesFailedReplicas(t *testing.T) {
+	c, s, _ := newTestController(t)
+	putDeployment(t, s, testDeployment("web", 1))
+	syncOnce(t, c)
+
+	failed := listReplicas(t, s, "web")[0]
+	_, err := store.UpdateJSON(s, model.ContainersBucket, failed.Key(), func(c *model.Container) error {
+		c.Status.Phase = model.PhaseFailed
+		return nil
+	})
+	require.NoError(t, err)
+
+	syncOnce(t, c)
+
+	replicas := listReplicas(t, s, "web")
+	require.Len(t, replicas, 1)
+	assert.NotEqual(t, failed.Name, replicas[0].Name)
+}
+
+func TestSync_ReplicasAreCollectedWithTheirDeployment(t *testing.T) {
+	c, s, _ := newTestController(t)
+	d := testDeployment("web", 2)
+	putDeployment(t, s, d)
+	syncOnce(t, c)
+	replicas := listReplicas(t, s, "web")
+	require.Len(t, replicas, 2)
+	for _, r := range replicas {
+		assert.Equal(t, model.DeploymentsBucket, r.ControllerRef().Kind)
+		assert.True(t, r.IsControlledBy(&d.ObjectMeta))
+	}
+
+	require.NoError(t, s.Delete(model.DeploymentsBucket, model.Key(model.DefaultNamespace, "web")))
+	syncOnce(t, c)
+	collect(t, s)
+
+	assert.Empty(t, listReplicas(t, s, "web"))
+}
+
+// collect runs a garbage collection pass.
+func collect(t *testing.T, s store.Store) {
+	t.Helper()
+	require.NoError(t, gc.NewCollector(&gc.Config{Store: s, Logger: zerolog.Nop()}).Sync(context.Background()))
+}
@@ -0,0 +1,189 @@
+package deployment
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/namespace"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// Rollback errors.
+var (
+	// ErrRevisionNotFound is returned when the requested revision does not
+	// exist or has been pruned from the history.
+	ErrRevisionNotFound = errors.New("revision not found")
+
+	// ErrNoPreviousRevision is returned when rolling back to the previous
+	// revision of a deployment that has none.
+	ErrNoPreviousRevision = errors.New("no previous revision")
+)
+
+// Revisions returns the retained revisions of a deployment, oldest first.
+func Revisions(s store.Store, d *model.Deployment) ([]*model.Revision, error) {
+	all, err := store.ListJSON[model.Revision](s, model.RevisionsBucket, model.RevisionPrefix(d.Namespace, d.Name))
+	if err != nil {
+		return nil, fmt.Errorf("listing revisions: %w", err)
+	}
+
+	// Skip leftovers from a deleted deployment of the same name that have
+	// not been cleaned up yet.
+	revisions := all[:0]
+	for _, r := range all {
+		if r.DeploymentUID == d.UID {
+			revisions = append(revisions, r)
+		}
+	}
+	return revisions, nil
+}
+
+// Rollback replaces the template of the deployment stored under key with
+// the one from revision to, or from the revision before the current one if
+// to is 0. The controller then rolls the template out as a new revision.
+// If namespaces is not nil, the rolled back deployment must be admitted to
+// its namespace first; an older template may request more than the quota
+// has left.
+func Rollback(s store.Store, namespaces *namespace.Registry, key string, to int64) (*model.Deployment, error) {
+	d, err := store.GetJSON[model.Deployment](s, model.DeploymentsBucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := findRevision(s, d, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated *model.Deployment
+	write := func() error {
+		var err error
+		updated, err = store.UpdateJSON(s, model.DeploymentsBucket, key, func(cur *model.Deployment) error {
+			if cur.UID != d.UID {
+				return store.ErrNotFound
+			}
+			cur.Spec.Template = target.Template
+			return nil
+		})
+		return err
+	}
+	if namespaces == nil {
+		err = write()
+	} else {
+		rolledBack := *d
+		rolledBack.Spec.Template = target.Template
+		err = namespaces.Admit(model.DeploymentsBucket, &rolledBack, write)
+	}
+	return updated, err
+}
+
+func findRevision(s store.Store, d *model.Deployment, to int64) (*model.Revision, error) {
+	if to > 0 {
+		r, err := store.GetJSON[model.Revision](s, model.RevisionsBucket, model.RevisionKey(d.Namespace, d.Name, to))
+		if errors.Is(err, store.ErrNotFound) || errors.Is(err, store.ErrBucketNotFound) ||
+			(err == nil && r.DeploymentUID != d.UID) {
+			return nil, fmt.Errorf("%w: %d", ErrRevisionNotFound, to)
+		}
+		return r, err
+	}
+
+	revisions, err := Revisions(s, d)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(revisions) - 1; i >= 0; i-- {
+		if revisions[i].Revision < d.Status.Revision {
+			return revisions[i], nil
+		}
+	}
+	return nil, ErrNoPreviousRevision
+}
+
+// recordRevision stores the current template as an immutable revision if
+// it has not been stored yet, then prunes revisions beyond the history
+// limit. It runs every pass so that a failed write is retried.
+func (c *Controller) recordRevision(d *model.Deployment) error {
+	key := model.RevisionKey(d.Namespace, d.Name, d.Status.Revision)
+	existing, err := store.GetJSON[model.Revision](c.store, model.RevisionsBucket, key)
+	switch {
+	case err == nil && existing.DeploymentUID == d.UID:
+		// Already recorded.
+	case err == nil || errors.Is(err, store.ErrNotFound) || errors.Is(err, store.ErrBucketNotFound):
+		r := &model.Revision{
+			ObjectMeta: model.ObjectMeta{
+				Name:      fmt.Sprintf("%s-%d", d.Name, d.Status.Revision),
+				Namespace: d.Namespace,
+			},
+			Deployment:    d.Name,
+			DeploymentUID: d.UID,
+			Revision:      d.Status.Revision,
+			TemplateHash:  d.Status.TemplateHash,
+			Template:      d.Spec.Template,
+		}
+		if err := r.Initialize(); err != nil {
+			return err
+		}
+		// A record from a deleted deployment of the same name is replaced.
+		if err := store.PutJSON(c.store, model.RevisionsBucket, key, r); err != nil {
+			return fmt.Errorf("recording revision %d: %w", r.Revision, err)
+		}
+	default:
+		return fmt.Errorf("reading revision %d: %w", d.Status.Revision, err)
+	}
+
+	return c.pruneRevisions(d)
+}
+
+// pruneRevisions deletes the oldest revisions beyond the history limit.
+// Revisions of the current and stable templates are kept, since canary and
+// blue-green rollouts recreate stable replicas from the history.
+func (c *Controller) pruneRevisions(d *model.Deployment) error {
+	revisions, err := Revisions(c.store, d)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, r := range revisions[:max(len(revisions)-d.Spec.RevisionHistoryLimit, 0)] {
+		if r.Revision == d.Status.Revision || r.TemplateHash == d.Status.StableTemplateHash {
+			continue
+		}
+		if err := c.deleteRevision(r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deleteOrphanedRevisions removes revisions whose deployment is gone or
+// has been replaced by a new one of the same name.
+func (c *Controller) deleteOrphanedRevisions(deployments []*model.Deployment) error {
+	revisions, err := store.ListJSON[model.Revision](c.store, model.RevisionsBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing revisions: %w", err)
+	}
+
+	uids := make(map[string]string, len(deployments))
+	for _, d := range deployments {
+		uids[d.Key()] = d.UID
+	}
+
+	var errs []error
+	for _, r := range revisions {
+		if uids[model.Key(r.Namespace, r.Deployment)] == r.DeploymentUID {
+			continue
+		}
+		if err := c.deleteRevision(r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Controller) deleteRevision(r *model.Revision) error {
+	err := c.store.Delete(model.RevisionsBucket, model.RevisionKey(r.Namespace, r.Deployment, r.Revision))
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return fmt.Errorf("deleting revision %d: %w", r.Revision, err)
+	}
+	return nil
+}
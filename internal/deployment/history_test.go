@@ -0,0 +1,96 @@
+package deployment
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+func revisionNumbers(t *testing.T, s store.Store, d *model.Deployment) []int64 {
+	t.Helper()
+	revisions, err := Revisions(s, d)
+	require.NoError(t, err)
+	out := make([]int64, len(revisions))
+	for i, r := range revisions {
+		out[i] = r.Revision
+	}
+	return out
+}
+
+func TestRevisions_RecordedAndPruned(t *testing.T) {
+	c, s, _ := newTestController(t)
+	d := testDeployment("web", 1)
+	d.Spec.RevisionHistoryLimit = 3
+	putDeployment(t, s, d)
+
+	for i := 1; i <= 5; i++ {
+		updateDeployment(t, s, "web", func(d *model.Deployment) {
+			d.Spec.Template.Spec.Image = fmt.Sprintf("app:v%d", i)
+		})
+		syncOnce(t, c)
+		// A pass without template changes records nothing new.
+		syncOnce(t, c)
+	}
+
+	d = getDeployment(t, s, "web")
+	assert.Equal(t, int64(5), d.Status.Revision)
+	assert.Equal(t, []int64{3, 4, 5}, revisionNumbers(t, s, d))
+
+	latest, err := store.GetJSON[model.Revision](s, model.RevisionsBucket, model.RevisionKey(model.DefaultNamespace, "web", 5))
+	require.NoError(t, err)
+	assert.Equal(t, "app:v5", latest.Template.Spec.Image)
+	assert.Equal(t, d.Status.TemplateHash, latest.TemplateHash)
+}
+
+func TestRollback(t *testing.T) {
+	c, s, _ := newTestController(t)
+	putDeployment(t, s, testDeployment("web", 1))
+	syncOnce(t, c)
+
+	_, err := Rollback(s, nil, model.Key(model.DefaultNamespace, "web"), 0)
+	assert.ErrorIs(t, err, ErrNoPreviousRevision)
+
+	updateDeployment(t, s, "web", func(d *model.Deployment) { d.Spec.Template.Spec.Image = "app:v2" })
+	syncOnce(t, c)
+
+	d, err := Rollback(s, nil, model.Key(model.DefaultNamespace, "web"), 0)
+	require.NoError(t, err)
+	assert.Equal(t, "app:v1", d.Spec.Template.Spec.Image)
+	syncOnce(t, c)
+
+	d = getDeployment(t, s, "web")
+	assert.Equal(t, int64(3), d.Status.Revision)
+	for _, r := range listReplicas(t, s, "web") {
+		assert.Equal(t, "app:v1", r.Spec.Image)
+	}
+
+	_, err = Rollback(s, nil, model.Key(model.DefaultNamespace, "web"), 9)
+	assert.ErrorIs(t, err, ErrRevisionNotFound)
+	_, err = Rollback(s, nil, model.Key(model.DefaultNamespace, "missing"), 1)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func TestRevisions_DeletedWithDeployment(t *testing.T) {
+	c, s, _ := newTestController(t)
+	putDeployment(t, s, testDeployment("web", 1))
+	syncOnce(t, c)
+
+	require.NoError(t, s.Delete(model.DeploymentsBucket, model.Key(model.DefaultNamespace, "web")))
+	syncOnce(t, c)
+
+	revisions, err := store.ListJSON[model.Revision](s, model.RevisionsBucket, "")
+	require.NoError(t, err)
+	assert.Empty(t, revisions)
+
+	// A new deployment of the same name starts a fresh history.
+	d := testDeployment("web", 1)
+	d.UID = "web-uid-2"
+	putDeployment(t, s, d)
+	syncOnce(t, c)
+	assert.Equal(t, []int64{1}, revisionNumbers(t, s, d))
+}
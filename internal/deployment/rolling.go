@@ -0,0 +1,212 @@
+package deployment
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/github-builder/container-orchestrator/internal/eviction"
+	"github.com/github-builder/container-orchestrator/internal/gc"
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+// rollingUpdate creates replicas from the current template and deletes old
+// ones, keeping the total within replicas+maxSurge and the ready count at or
+// above replicas-maxUnavailable. Ready old replicas are evicted, so
+// disruption budgets can hold the rollout back further. Replicas only
+// become ready through the agent and prober, so a rollout advances as
+// readiness probes pass. It returns the replica sets after its changes.
+func (c *Controller) rollingUpdate(d *model.Deployment, hash string, current, old []*model.Container) ([]*model.Container, []*model.Container, error) {
+	desired := d.Spec.Replicas
+	ru := d.Spec.Strategy.RollingUpdate
+	if ru == nil {
+		// Canary and blue-green deployments scale and return to their
+		// stable template with the default rolling bounds.
+		ru = &model.RollingUpdateStrategy{}
+	}
+	surge := orDefault(ru.MaxSurge, model.DefaultMaxSurge).Scaled(desired, true)
+	unavailable := orDefault(ru.MaxUnavailable, model.DefaultMaxUnavailable).Scaled(desired, false)
+	if surge == 0 && unavailable == 0 {
+		// Percentages can both round to zero; allow one replica to be
+		// replaced at a time so the rollout can make progress.
+		unavailable = 1
+	}
+
+	var errs []error
+
+	total := len(current) + len(old)
+	for range min(desired-len(current), desired+surge-total) {
+		r, err := c.createReplica(d, &d.Spec.Template, hash)
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
+		current = append(current, r)
+	}
+
+	if len(old) > 0 {
+		// Unready old replicas go first, since removing them costs no
+		// availability; ready ones only while enough others are ready.
+		sortForRemoval(old)
+		removable := countReady(current) + countReady(old) - (desired - unavailable)
+
+		kept := old[:0]
+		for _, r := range old {
+			if !r.Status.IsReady() {
+				if err := c.deleteReplica(r); err != nil {
+					errs = append(errs, err)
+					kept = append(kept, r)
+				}
+				continue
+			}
+			if removable <= 0 {
+				kept = append(kept, r)
+				continue
+			}
+			ok, err := c.evictReplica(r)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			if !ok {
+				kept = append(kept, r)
+				continue
+			}
+			removable--
+		}
+		old = kept
+	} else if len(current) > desired {
+		var err error
+		if current, err = c.scale(d, nil, "", current, desired); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return current, old, errors.Join(errs...)
+}
+
+// scale creates or removes replicas of one template until there are n.
+// Surplus replicas are removed in sortForRemoval order, ready ones through
+// the evictor, so a disruption budget can leave more than n for now. A nil
+// tmpl only removes replicas; the caller reports why none can be created.
+func (c *Controller) scale(d *model.Deployment, tmpl *model.ContainerTemplate, hash string, replicas []*model.Container, n int) ([]*model.Container, error) {
+	var errs []error
+	for tmpl != nil && len(replicas) < n {
+		r, err := c.createReplica(d, tmpl, hash)
+		if err != nil {
+			return replicas, err
+		}
+		replicas = append(replicas, r)
+	}
+
+	if surplus := len(replicas) - n; surplus > 0 {
+		sortForRemoval(replicas)
+		kept := replicas[:0]
+		for i, r := range replicas {
+			if i < surplus {
+				gone, err := c.removeReplica(r)
+				if err != nil {
+					errs = append(errs, err)
+				}
+				if gone {
+					continue
+				}
+			}
+			kept = append(kept, r)
+		}
+		replicas = kept
+	}
+	return replicas, errors.Join(errs...)
+}
+
+func orDefault(v *model.IntOrPercent, def model.IntOrPercent) *model.IntOrPercent {
+	if v == nil {
+		return &def
+	}
+	return v
+}
+
+// sortForRemoval orders replicas so the cheapest to remove come first:
+// unready before ready, then newest before oldest.
+func sortForRemoval(cs []*model.Container) {
+	sort.SliceStable(cs, func(i, j int) bool {
+		if ri, rj := cs[i].Status.IsReady(), cs[j].Status.IsReady(); ri != rj {
+			return !ri
+		}
+		return cs[i].CreatedAt.After(cs[j].CreatedAt)
+	})
+}
+
+// createReplica stores a new container from tmpl, whose hash is hash.
+func (c *Controller) createReplica(d *model.Deployment, tmpl *model.ContainerTemplate, hash string) (*model.Container, error) {
+	spec, err := tmpl.Spec.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	labels := make(map[string]string, len(tmpl.Labels)+2)
+	for k, v := range tmpl.Labels {
+		labels[k] = v
+	}
+	labels[model.LabelDeployment] = d.Name
+	labels[model.LabelTemplateHash] = hash
+
+	r := &model.Container{
+		ObjectMeta: model.ObjectMeta{
+			Namespace:       d.Namespace,
+			Labels:          labels,
+			OwnerReferences: []model.OwnerReference{model.NewControllerRef(model.DeploymentsBucket, &d.ObjectMeta)},
+		},
+		Spec: *spec,
+	}
+	r.SetDefaults()
+
+	if err := gc.Create(c.store, model.ContainersBucket, d.Name+"-"+hash, r); err != nil {
+		return nil, fmt.Errorf("creating replica: %w", err)
+	}
+	c.logger.Info().Str("deployment", d.Name).Str("container", r.Name).Msg("replica created")
+	c.events.Eventf(model.DeploymentsBucket, &d.ObjectMeta, model.EventNormal, model.EventCreated, "Created container %s", r.Name)
+	return r, nil
+}
+
+// removeReplica deletes an unready replica and evicts a ready one. It
+// reports whether the replica is gone.
+func (c *Controller) removeReplica(r *model.Container) (bool, error) {
+	if r.Status.IsReady() {
+		return c.evictReplica(r)
+	}
+	if err := c.deleteReplica(r); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// evictReplica removes a ready replica through the evictor. It reports
+// whether the replica is gone; a disruption budget may keep it for now.
+func (c *Controller) evictReplica(r *model.Container) (bool, error) {
+	err := c.evictor.Delete(r, "replaced by rollout")
+	var berr *eviction.BudgetError
+	switch {
+	case err == nil:
+		c.events.OwnerEventf(&r.ObjectMeta, model.EventNormal, model.EventDeleted, "Deleted container %s", r.Name)
+		return true, nil
+	case errors.Is(err, eviction.ErrStale):
+		return true, nil
+	case errors.As(err, &berr):
+		c.logger.Debug().Err(err).Str("deployment", r.Labels[model.LabelDeployment]).Msg("rollout held by disruption budget")
+		return false, nil
+	default:
+		return false, fmt.Errorf("deleting replica %s: %w", r.Key(), err)
+	}
+}
+
+// deleteReplica deletes a replica; the agent on its node stops the
+// runtime container.
+func (c *Controller) deleteReplica(r *model.Container) error {
+	if _, err := gc.Delete(c.store, model.ContainersBucket, &r.ObjectMeta); err != nil {
+		return fmt.Errorf("deleting replica %s: %w", r.Key(), err)
+	}
+	c.logger.Info().Str("container", r.Name).Str("deployment", r.Labels[model.LabelDeployment]).
+		Msg("replica deleted")
+	c.events.OwnerEventf(&r.ObjectMeta, model.EventNormal, model.EventDeleted, "Deleted container %s", r.Name)
+	return nil
+}
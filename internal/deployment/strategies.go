@@ -0,0 +1,223 @@
+package deployment
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+// canary runs one pass of a canary rollout. The current step's share of
+// replicas runs the new template and the rest the stable template; stable
+// replicas are only removed as canaries become ready. Once a step's
+// replicas are all ready the rollout holds until promoted or until the
+// step's pause elapses. After the last step the new template takes every
+// replica and the rollout completes as the stable replicas are removed.
+// On abort the canaries are removed and the stable set restored.
+func (c *Controller) canary(d *model.Deployment, s *model.DeploymentStatus, hash string, current, old []*model.Container, now time.Time) ([]*model.Container, []*model.Container, outcome, error) {
+	desired := d.Spec.Replicas
+	steps := d.Spec.Strategy.Canary.Steps
+	if s.Canary == nil {
+		s.Canary = &model.CanaryStatus{}
+	}
+	cs := s.Canary
+
+	stable, held, errs := c.dropStale(s.StableTemplateHash, old)
+
+	target := desired
+	switch {
+	case s.Abort:
+		target = 0
+	case cs.Step < len(steps):
+		target = min(steps[cs.Step].Replicas.Scaled(desired, true), desired)
+	}
+
+	current, err := c.scale(d, &d.Spec.Template, hash, current, target)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	// Keep enough stable replicas to cover canaries that are not ready yet.
+	stableTmpl, err := c.stableTemplate(d, s, len(stable))
+	if err != nil {
+		errs = append(errs, err)
+	}
+	stable, err = c.scale(d, stableTmpl, s.StableTemplateHash, stable, desired-min(countReady(current), target))
+	if err != nil {
+		errs = append(errs, err)
+	}
+	settled := len(held) == 0 && len(current) == target && countReady(current) == target && len(stable) == desired-target
+	stable = append(stable, held...)
+
+	if s.Abort {
+		return current, stable, outcome{aborted: true}, errors.Join(errs...)
+	}
+	if cs.Step >= len(steps) {
+		s.Promote = false
+		return current, stable, outcome{}, errors.Join(errs...)
+	}
+
+	if !settled {
+		cs.StepReadyAt = nil
+		return current, stable, outcome{}, errors.Join(errs...)
+	}
+	if cs.StepReadyAt == nil {
+		cs.StepReadyAt = &now
+	}
+
+	step := steps[cs.Step]
+	pause := time.Duration(step.PauseSeconds) * time.Second
+	if s.Promote || (pause > 0 && !now.Before(cs.StepReadyAt.Add(pause))) {
+		c.logger.Info().Str("deployment", d.Name).Int("step", cs.Step+1).Bool("manual", s.Promote).
+			Msg("canary step promoted")
+		cs.Step++
+		cs.StepReadyAt = nil
+		s.Promote = false
+		return current, stable, outcome{}, errors.Join(errs...)
+	}
+
+	waiting := fmt.Sprintf("canary step %d/%d: %d of %d replicas on revision %d",
+		cs.Step+1, len(steps), target, desired, s.Revision)
+	if pause > 0 {
+		waiting += fmt.Sprintf("; promoting in %s", cs.StepReadyAt.Add(pause).Sub(now).Round(time.Second))
+	} else {
+		waiting += "; waiting for promotion"
+	}
+	return current, stable, outcome{reason: model.ReasonAwaitingPromotion, waiting: waiting}, errors.Join(errs...)
+}
+
+// blueGreen runs one pass of a blue-green rollout. A full preview set of
+// the new template is brought up next to the active set. When every
+// preview replica is ready and the rollout is promoted, manually or after
+// AutoPromotionSeconds, ActiveTemplateHash switches to the new template in
+// a single status update. The previous set is removed after
+// ScaleDownDelaySeconds. On abort before the switch the preview set is
+// removed.
+func (c *Controller) blueGreen(d *model.Deployment, s *model.DeploymentStatus, hash string, current, old []*model.Container, now time.Time) ([]*model.Container, []*model.Container, outcome, error) {
+	desired := d.Spec.Replicas
+	cfg := d.Spec.Strategy.BlueGreen
+	if s.BlueGreen == nil {
+		s.BlueGreen = &model.BlueGreenStatus{}
+	}
+	bg := s.BlueGreen
+
+	if bg.SwitchedAt != nil {
+		// Traffic has moved; retire the previous set once the delay passes.
+		s.Promote = false
+		var errs []error
+		current, err := c.scale(d, &d.Spec.Template, hash, current, desired)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		delay := time.Duration(cfg.ScaleDownDelaySeconds) * time.Second
+		if deadline := bg.SwitchedAt.Add(delay); now.Before(deadline) && len(old) > 0 {
+			return current, old, outcome{
+				reason: model.ReasonTrafficSwitched,
+				waiting: fmt.Sprintf("traffic switched to revision %d; removing previous replicas in %s",
+					s.Revision, deadline.Sub(now).Round(time.Second)),
+			}, errors.Join(errs...)
+		}
+		old, err = c.scale(d, nil, "", old, 0)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		return current, old, outcome{}, errors.Join(errs...)
+	}
+
+	s.ActiveTemplateHash = s.StableTemplateHash
+	active, held, errs := c.dropStale(s.StableTemplateHash, old)
+
+	activeTmpl, err := c.stableTemplate(d, s, len(active))
+	if err != nil {
+		errs = append(errs, err)
+	}
+	active, err = c.scale(d, activeTmpl, s.StableTemplateHash, active, desired)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	active = append(active, held...)
+
+	preview := desired
+	if s.Abort {
+		preview = 0
+	}
+	current, err = c.scale(d, &d.Spec.Template, hash, current, preview)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	if s.Abort {
+		return current, active, outcome{aborted: true}, errors.Join(errs...)
+	}
+	if countReady(current) < desired {
+		bg.PreviewReadyAt = nil
+		return current, active, outcome{}, errors.Join(errs...)
+	}
+	if bg.PreviewReadyAt == nil {
+		bg.PreviewReadyAt = &now
+	}
+
+	auto := cfg.AutoPromotionSeconds
+	if s.Promote || (auto != nil && !now.Before(bg.PreviewReadyAt.Add(time.Duration(*auto)*time.Second))) {
+		bg.SwitchedAt = &now
+		s.ActiveTemplateHash = hash
+		s.Promote = false
+		c.logger.Info().Str("deployment", d.Name).Int64("revision", s.Revision).Msg("traffic switched to preview replicas")
+		return current, active, outcome{
+			reason:  model.ReasonTrafficSwitched,
+			waiting: fmt.Sprintf("traffic switched to revision %d", s.Revision),
+		}, errors.Join(errs...)
+	}
+
+	waiting := fmt.Sprintf("preview of revision %d is ready", s.Revision)
+	if auto != nil {
+		waiting += fmt.Sprintf("; promoting in %s",
+			bg.PreviewReadyAt.Add(time.Duration(*auto)*time.Second).Sub(now).Round(time.Second))
+	} else {
+		waiting += "; waiting for promotion"
+	}
+	return current, active, outcome{reason: model.ReasonAwaitingPromotion, waiting: waiting}, errors.Join(errs...)
+}
+
+// dropStale removes replicas that belong neither to the current nor to the
+// stable template, such as those of an abandoned canary, and returns the
+// stable ones. Ready replicas are evicted; those a disruption budget keeps
+// for now are returned apart and removed on a later pass.
+func (c *Controller) dropStale(stableHash string, old []*model.Container) ([]*model.Container, []*model.Container, []error) {
+	var stable, held []*model.Container
+	var errs []error
+	for _, r := range old {
+		if r.Labels[model.LabelTemplateHash] == stableHash {
+			stable = append(stable, r)
+			continue
+		}
+		gone, err := c.removeReplica(r)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if !gone {
+			held = append(held, r)
+		}
+	}
+	return stable, held, errs
+}
+
+// stableTemplate looks up the stable template in the revision history. It
+// is only needed to create stable replicas, so the lookup is skipped while
+// the stable set is complete.
+func (c *Controller) stableTemplate(d *model.Deployment, s *model.DeploymentStatus, have int) (*model.ContainerTemplate, error) {
+	if have >= d.Spec.Replicas {
+		return nil, nil
+	}
+	revisions, err := Revisions(c.store, d)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(revisions) - 1; i >= 0; i-- {
+		if revisions[i].TemplateHash == s.StableTemplateHash {
+			return &revisions[i].Template, nil
+		}
+	}
+	return nil, fmt.Errorf("stable template %s is no longer in the revision history", s.StableTemplateHash)
+}
@@ -0,0 +1,224 @@
+package deployment
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// countByImage returns how many replicas of the deployment run each image.
+func countByImage(t *testing.T, s store.Store, deployment string) map[string]int {
+	t.Helper()
+	counts := make(map[string]int)
+	for _, r := range listReplicas(t, s, deployment) {
+		counts[r.Spec.Image]++
+	}
+	return counts
+}
+
+// rolledOut creates the deployment and brings its first revision to Complete.
+func rolledOut(t *testing.T, c *Controller, s store.Store, d *model.Deployment) {
+	t.Helper()
+	putDeployment(t, s, d)
+	syncOnce(t, c)
+	markAllReady(t, s, d.Name)
+	syncOnce(t, c)
+	require.Equal(t, model.RolloutComplete, getDeployment(t, s, d.Name).Status.Rollout.Phase)
+}
+
+func canaryDeployment() *model.Deployment {
+	d := testDeployment("web", 4)
+	d.Spec.Strategy = model.DeploymentStrategy{
+		Type: model.StrategyCanary,
+		Canary: &model.CanaryStrategy{Steps: []model.CanaryStep{
+			{Replicas: *model.FromInt(1)},
+			{Replicas: *model.FromPercent(50), PauseSeconds: 60},
+		}},
+	}
+	return d
+}
+
+func TestCanary_Steps(t *testing.T) {
+	c, s, now := newTestController(t)
+	rolledOut(t, c, s, canaryDeployment())
+	updateDeployment(t, s, "web", func(d *model.Deployment) { d.Spec.Template.Spec.Image = "app:v2" })
+
+	// Step 1: one canary; stable replicas stay until it is ready.
+	syncOnce(t, c)
+	assert.Equal(t, map[string]int{"app:v1": 4, "app:v2": 1}, countByImage(t, s, "web"))
+	markAllReady(t, s, "web")
+	syncOnce(t, c)
+	assert.Equal(t, map[string]int{"app:v1": 3, "app:v2": 1}, countByImage(t, s, "web"))
+
+	syncOnce(t, c)
+	d := getDeployment(t, s, "web")
+	assert.Equal(t, model.ReasonAwaitingPromotion, d.Status.Rollout.Reason)
+	assert.Equal(t, 0, d.Status.Canary.Step)
+
+	// Manual promotion to step 2, which promotes itself after its pause.
+	updateDeployment(t, s, "web", func(d *model.Deployment) { d.Status.Promote = true })
+	syncOnce(t, c)
+	d = getDeployment(t, s, "web")
+	assert.Equal(t, 1, d.Status.Canary.Step)
+	assert.False(t, d.Status.Promote)
+
+	syncOnce(t, c)
+	markAllReady(t, s, "web")
+	syncOnce(t, c)
+	assert.Equal(t, map[string]int{"app:v1": 2, "app:v2": 2}, countByImage(t, s, "web"))
+	syncOnce(t, c)
+	assert.Contains(t, getDeployment(t, s, "web").Status.Rollout.Message, "promoting in")
+
+	*now = now.Add(61 * time.Second)
+	syncOnce(t, c)
+	assert.Equal(t, 2, getDeployment(t, s, "web").Status.Canary.Step)
+
+	// After the last step every replica moves to the new template.
+	syncOnce(t, c)
+	markAllReady(t, s, "web")
+	syncOnce(t, c)
+	assert.Equal(t, map[string]int{"app:v2": 4}, countByImage(t, s, "web"))
+
+	d = getDeployment(t, s, "web")
+	assert.Equal(t, model.RolloutComplete, d.Status.Rollout.Phase)
+	assert.Equal(t, d.Status.TemplateHash, d.Status.StableTemplateHash)
+	assert.Nil(t, d.Status.Canary)
+}
+
+func TestCanary_RespectsDisruptionBudget(t *testing.T) {
+	c, s, _ := newTestController(t)
+	rolledOut(t, c, s, canaryDeployment())
+	b := &model.DisruptionBudget{
+		ObjectMeta: model.ObjectMeta{Name: "web", Namespace: model.DefaultNamespace},
+		Spec: model.DisruptionBudgetSpec{
+			Selector:       model.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			MaxUnavailable: model.FromInt(0),
+		},
+	}
+	require.NoError(t, store.PutJSON(s, model.DisruptionBudgetsBucket, b.Key(), b))
+	updateDeployment(t, s, "web", func(d *model.Deployment) { d.Spec.Template.Spec.Image = "app:v2" })
+
+	syncOnce(t, c)
+	markAllReady(t, s, "web")
+	syncOnce(t, c)
+	assert.Equal(t, map[string]int{"app:v1": 4, "app:v2": 1}, countByImage(t, s, "web"), "budget bypassed")
+	assert.NotEqual(t, model.ReasonAwaitingPromotion, getDeployment(t, s, "web").Status.Rollout.Reason)
+
+	require.NoError(t, s.Delete(model.DisruptionBudgetsBucket, b.Key()))
+	syncOnce(t, c)
+	assert.Equal(t, map[string]int{"app:v1": 3, "app:v2": 1}, countByImage(t, s, "web"))
+}
+
+func TestCanary_AbortAndRollback(t *testing.T) {
+	c, s, _ := newTestController(t)
+	rolledOut(t, c, s, canaryDeployment())
+	stable := getDeployment(t, s, "web").Status.TemplateHash
+	updateDeployment(t, s, "web", func(d *model.Deployment) { d.Spec.Template.Spec.Image = "app:v2" })
+
+	syncOnce(t, c)
+	markAllReady(t, s, "web")
+	syncOnce(t, c)
+	require.Equal(t, map[string]int{"app:v1": 3, "app:v2": 1}, countByImage(t, s, "web"))
+
+	updateDeployment(t, s, "web", func(d *model.Deployment) { d.Status.Abort = true })
+	syncOnce(t, c)
+
+	// The canary is gone and the stable set is restored from history.
+	assert.Equal(t, map[string]int{"app:v1": 4}, countByImage(t, s, "web"))
+	d := getDeployment(t, s, "web")
+	assert.Equal(t, model.RolloutAborted, d.Status.Rollout.Phase)
+	assert.Equal(t, stable, d.Status.StableTemplateHash)
+
+	_, err := Rollback(s, nil, model.Key(model.DefaultNamespace, "web"), 0)
+	require.NoError(t, err)
+	markAllReady(t, s, "web")
+	syncOnce(t, c)
+
+	d = getDeployment(t, s, "web")
+	assert.Equal(t, model.RolloutComplete, d.Status.Rollout.Phase)
+	assert.Equal(t, int64(3), d.Status.Revision)
+	assert.Equal(t, stable, d.Status.TemplateHash)
+	assert.Equal(t, map[string]int{"app:v1": 4}, countByImage(t, s, "web"))
+}
+
+func TestBlueGreen_ManualPromotion(t *testing.T) {
+	c, s, now := newTestController(t)
+	d := testDeployment("web", 2)
+	d.Spec.Strategy = model.DeploymentStrategy{
+		Type:      model.StrategyBlueGreen,
+		BlueGreen: &model.BlueGreenStrategy{ScaleDownDelaySeconds: 30},
+	}
+	rolledOut(t, c, s, d)
+	blue := getDeployment(t, s, "web").Status.TemplateHash
+	assert.Equal(t, blue, getDeployment(t, s, "web").Status.ActiveTemplateHash)
+
+	updateDeployment(t, s, "web", func(d *model.Deployment) { d.Spec.Template.Spec.Image = "app:v2" })
+	syncOnce(t, c)
+
+	// A full preview set runs next to the active one.
+	assert.Equal(t, map[string]int{"app:v1": 2, "app:v2": 2}, countByImage(t, s, "web"))
+	markAllReady(t, s, "web")
+	syncOnce(t, c)
+
+	d = getDeployment(t, s, "web")
+	assert.Equal(t, blue, d.Status.ActiveTemplateHash)
+	assert.Equal(t, model.ReasonAwaitingPromotion, d.Status.Rollout.Reason)
+
+	updateDeployment(t, s, "web", func(d *model.Deployment) { d.Status.Promote = true })
+	syncOnce(t, c)
+
+	d = getDeployment(t, s, "web")
+	green := d.Status.TemplateHash
+	assert.Equal(t, green, d.Status.ActiveTemplateHash)
+	assert.Equal(t, model.ReasonTrafficSwitched, d.Status.Rollout.Reason)
+
+	// The previous set stays around for the scale-down delay.
+	syncOnce(t, c)
+	assert.Equal(t, map[string]int{"app:v1": 2, "app:v2": 2}, countByImage(t, s, "web"))
+
+	*now = now.Add(31 * time.Second)
+	syncOnce(t, c)
+	assert.Equal(t, map[string]int{"app:v2": 2}, countByImage(t, s, "web"))
+
+	d = getDeployment(t, s, "web")
+	assert.Equal(t, model.RolloutComplete, d.Status.Rollout.Phase)
+	assert.Equal(t, green, d.Status.StableTemplateHash)
+	assert.Equal(t, green, d.Status.ActiveTemplateHash)
+}
+
+func TestBlueGreen_AutoPromotionAndAbort(t *testing.T) {
+	c, s, now := newTestController(t)
+	auto := 10
+	d := testDeployment("web", 2)
+	d.Spec.Strategy = model.DeploymentStrategy{
+		Type:      model.StrategyBlueGreen,
+		BlueGreen: &model.BlueGreenStrategy{AutoPromotionSeconds: &auto},
+	}
+	rolledOut(t, c, s, d)
+
+	updateDeployment(t, s, "web", func(d *model.Deployment) { d.Spec.Template.Spec.Image = "app:v2" })
+	syncOnce(t, c)
+	markAllReady(t, s, "web")
+	syncOnce(t, c)
+	assert.Contains(t, getDeployment(t, s, "web").Status.Rollout.Message, "promoting in 10s")
+
+	*now = now.Add(11 * time.Second)
+	syncOnce(t, c)
+	syncOnce(t, c)
+	assert.Equal(t, map[string]int{"app:v2": 2}, countByImage(t, s, "web"))
+	assert.Equal(t, model.RolloutComplete, getDeployment(t, s, "web").Status.Rollout.Phase)
+
+	// Abort before the switch removes the preview set.
+	updateDeployment(t, s, "web", func(d *model.Deployment) { d.Spec.Template.Spec.Image = "app:v3" })
+	syncOnce(t, c)
+	updateDeployment(t, s, "web", func(d *model.Deployment) { d.Status.Abort = true })
+	syncOnce(t, c)
+
+	assert.Equal(t, map[string]int{"app:v2": 2}, countByImage(t, s, "web"))
+	assert.Equal(t, model.RolloutAborted, getDeployment(t, s, "web").Status.Rollout.Phase)
+}
@@ -0,0 +1,249 @@
+// Package events records what happens to resources as Events. Repeats of
+// an event about the same object are folded into one record whose count
+// and last-seen time go up. Events are deleted once they have not been
+// seen for a TTL, and watchers are told of every event as it is recorded.
+package events
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// Defaults for Config.
+const (
+	DefaultTTL           = time.Hour
+	DefaultPruneInterval = time.Minute
+)
+
+// watchBuffer is how many events a watcher may fall behind by before it
+// misses some.
+const watchBuffer = 64
+
+// Config holds dependencies for a recorder.
+type Config struct {
+	Store  store.Store
+	Logger zerolog.Logger
+
+	// TTL is how long an event is kept after it was last seen. Defaults
+	// to DefaultTTL.
+	TTL time.Duration
+
+	// PruneInterval is how often expired events are deleted. Defaults to
+	// DefaultPruneInterval.
+	PruneInterval time.Duration
+}
+
+// Recorder stores events and passes them on to watchers. Recorders made
+// with WithSource share the store and the watchers of their parent.
+type Recorder struct {
+	*hub
+	source string
+}
+
+// hub is the state shared by a recorder and those derived from it.
+type hub struct {
+	store    store.Store
+	logger   zerolog.Logger
+	ttl      time.Duration
+	interval time.Duration
+
+	// now is replaceable for tests.
+	now func() time.Time
+
+	mu       sync.Mutex
+	watchers map[chan *model.Event]struct{}
+}
+
+// NewRecorder creates a recorder whose events have no source.
+func NewRecorder(cfg *Config) *Recorder {
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	interval := cfg.PruneInterval
+	if interval <= 0 {
+		interval = DefaultPruneInterval
+	}
+	return &Recorder{hub: &hub{
+		store:    cfg.Store,
+		logger:   cfg.Logger,
+		ttl:      ttl,
+		interval: interval,
+		now:      func() time.Time { return time.Now().UTC() },
+		watchers: make(map[chan *model.Event]struct{}),
+	}}
+}
+
+// WithSource returns a recorder that marks its events as coming from
+// source, e.g. "scheduler".
+func (r *Recorder) WithSource(source string) *Recorder {
+	return &Recorder{hub: r.hub, source: source}
+}
+
+// Event records an event about the resource of kind described by meta.
+// Recording is best effort: failures are logged, not returned.
+func (r *Recorder) Event(kind string, meta *model.ObjectMeta, typ model.EventType, reason, message string) {
+	ref := model.ObjectReference{Kind: kind, Namespace: meta.Namespace, Name: meta.Name, UID: meta.UID}
+	e, err := r.record(ref, typ, reason, message)
+	if err != nil {
+		r.logger.Error().Err(err).Str("kind", kind).Str("name", meta.Key()).Str("reason", reason).
+			Msg("failed to record event")
+		return
+	}
+	r.publish(e)
+}
+
+// Eventf records an event whose message is formatted from format and args.
+func (r *Recorder) Eventf(kind string, meta *model.ObjectMeta, typ model.EventType, reason, format string, args ...any) {
+	r.Event(kind, meta, typ, reason, fmt.Sprintf(format, args...))
+}
+
+// OwnerEventf records an event about the controller of the resource
+// described by meta, as controllers do about the resources they manage. It
+// does nothing if the resource has no controller.
+func (r *Recorder) OwnerEventf(meta *model.ObjectMeta, typ model.EventType, reason, format string, args ...any) {
+	ref := meta.ControllerRef()
+	if ref == nil {
+		return
+	}
+	owner := &model.ObjectMeta{Name: ref.Name, Namespace: meta.Namespace, UID: ref.UID}
+	r.Eventf(ref.Kind, owner, typ, reason, format, args...)
+}
+
+// record stores an event, or counts a repeat of one seen within the TTL.
+func (r *Recorder) record(ref model.ObjectReference, typ model.EventType, reason, message string) (*model.Event, error) {
+	name := eventName(ref, typ, reason, message, r.source)
+	key := model.Key(ref.Namespace, name)
+	now := r.now()
+
+	for range 3 {
+		e, err := store.UpdateJSON(r.store, model.EventsBucket, key, func(e *model.Event) error {
+			if now.Sub(e.LastSeen) > r.ttl {
+				// Expired but not pruned yet; start over.
+				e.Count = 0
+				e.FirstSeen = now
+			}
+			e.Count++
+			e.LastSeen = now
+			return nil
+		})
+		if !errors.Is(err, store.ErrNotFound) && !errors.Is(err, store.ErrBucketNotFound) {
+			return e, err
+		}
+
+		e = &model.Event{
+			ObjectMeta:     model.ObjectMeta{Name: name, Namespace: ref.Namespace},
+			InvolvedObject: ref,
+			Type:           typ,
+			Reason:         reason,
+			Message:        message,
+			Source:         r.source,
+			Count:          1,
+			FirstSeen:      now,
+			LastSeen:       now,
+		}
+		if err := e.Initialize(); err != nil {
+			return nil, err
+		}
+		e.CreatedAt = now
+		err = store.CreateJSON(r.store, model.EventsBucket, key, e)
+		if !errors.Is(err, store.ErrAlreadyExists) {
+			return e, err
+		}
+		// Recorded concurrently; count this one as a repeat.
+	}
+	return nil, fmt.Errorf("recording event %s: %w", key, store.ErrAlreadyExists)
+}
+
+// eventName derives the name of an event from what makes it distinct, so
+// that repeats land on the same record: the object's name followed by a
+// hash of the rest.
+func eventName(ref model.ObjectReference, typ model.EventType, reason, message, source string) string {
+	h := sha256.New()
+	for _, s := range []string{ref.Kind, ref.UID, string(typ), reason, message, source} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return ref.Name + "." + hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// Watch returns a channel receiving every event recorded from now until
+// ctx is done, when it is closed. A watcher that falls behind misses
+// events rather than holding up those who record them.
+func (r *Recorder) Watch(ctx context.Context) <-chan *model.Event {
+	ch := make(chan *model.Event, watchBuffer)
+	r.mu.Lock()
+	r.watchers[ch] = struct{}{}
+	r.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		r.mu.Lock()
+		delete(r.watchers, ch)
+		close(ch)
+		r.mu.Unlock()
+	}()
+	return ch
+}
+
+// publish passes an event on to the watchers.
+func (r *Recorder) publish(e *model.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for ch := range r.watchers {
+		cp := *e
+		select {
+		case ch <- &cp:
+		default:
+			r.logger.Warn().Str("event", e.Key()).Msg("event watcher is behind, event dropped")
+		}
+	}
+}
+
+// Run deletes expired events every prune interval until ctx is canceled.
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Prune(); err != nil {
+			r.logger.Error().Err(err).Msg("event pruning failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Prune deletes the events last seen more than the TTL ago.
+func (r *Recorder) Prune() error {
+	events, err := store.ListJSON[model.Event](r.store, model.EventsBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing events: %w", err)
+	}
+
+	now := r.now()
+	var errs []error
+	for _, e := range events {
+		if now.Sub(e.LastSeen) <= r.ttl {
+			continue
+		}
+		if err := r.store.Delete(model.EventsBucket, e.Key()); err != nil && !errors.Is(err, store.ErrNotFound) {
+			errs = append(errs, fmt.Errorf("deleting event %s: %w", e.Key(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
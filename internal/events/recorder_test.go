@@ -0,0 +1,122 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+func newTestRecorder(t *testing.T) (*Recorder, store.Store, *time.Time) {
+	t.Helper()
+	s := store.NewMemoryStore()
+	r := NewRecorder(&Config{Store: s, Logger: zerolog.Nop(), TTL: time.Hour})
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	return r, s, &now
+}
+
+func listEvents(t *testing.T, s store.Store) []*model.Event {
+	t.Helper()
+	events, err := store.ListJSON[model.Event](s, model.EventsBucket, "")
+	require.NoError(t, err)
+	return events
+}
+
+var web = &model.ObjectMeta{Name: "web", Namespace: model.DefaultNamespace, UID: "web-uid"}
+
+func TestRecorder_FoldsRepeats(t *testing.T) {
+	r, s, now := newTestRecorder(t)
+	sched := r.WithSource("scheduler")
+
+	sched.Event(model.ContainersBucket, web, model.EventWarning, model.EventFailedScheduling, "0/1 nodes are available")
+	*now = now.Add(time.Minute)
+	sched.Event(model.ContainersBucket, web, model.EventWarning, model.EventFailedScheduling, "0/1 nodes are available")
+	sched.Event(model.ContainersBucket, web, model.EventNormal, model.EventScheduled, "assigned to node node-1")
+
+	events := listEvents(t, s)
+	require.Len(t, events, 2)
+	byReason := map[string]*model.Event{}
+	for _, e := range events {
+		byReason[e.Reason] = e
+	}
+
+	failed := byReason[model.EventFailedScheduling]
+	require.NotNil(t, failed)
+	assert.Equal(t, 2, failed.Count)
+	assert.Equal(t, now.Add(-time.Minute), failed.FirstSeen)
+	assert.Equal(t, *now, failed.LastSeen)
+	assert.Equal(t, "scheduler", failed.Source)
+	assert.Equal(t, model.ObjectReference{
+		Kind: model.ContainersBucket, Namespace: model.DefaultNamespace, Name: "web", UID: "web-uid",
+	}, failed.InvolvedObject)
+	assert.Equal(t, model.DefaultNamespace, failed.Namespace)
+
+	assert.Equal(t, 1, byReason[model.EventScheduled].Count)
+}
+
+func TestRecorder_OwnerEvent(t *testing.T) {
+	r, s, _ := newTestRecorder(t)
+	d := &model.ObjectMeta{Name: "app", Namespace: model.DefaultNamespace, UID: "app-uid"}
+	replica := &model.ObjectMeta{
+		Name: "app-1", Namespace: model.DefaultNamespace,
+		OwnerReferences: []model.OwnerReference{model.NewControllerRef(model.DeploymentsBucket, d)},
+	}
+
+	r.OwnerEventf(replica, model.EventNormal, model.EventDeleted, "Deleted container %s", replica.Name)
+	r.OwnerEventf(web, model.EventNormal, model.EventDeleted, "Deleted container %s", web.Name)
+
+	events := listEvents(t, s)
+	require.Len(t, events, 1)
+	assert.Equal(t, model.ObjectReference{
+		Kind: model.DeploymentsBucket, Namespace: model.DefaultNamespace, Name: "app", UID: "app-uid",
+	}, events[0].InvolvedObject)
+	assert.Equal(t, "Deleted container app-1", events[0].Message)
+}
+
+func TestRecorder_Prune(t *testing.T) {
+	r, s, now := newTestRecorder(t)
+	r.Event(model.ContainersBucket, web, model.EventNormal, model.EventStarted, "old")
+	*now = now.Add(30 * time.Minute)
+	r.Event(model.ContainersBucket, web, model.EventNormal, model.EventStarted, "recent")
+
+	*now = now.Add(45 * time.Minute)
+	require.NoError(t, r.Prune())
+	events := listEvents(t, s)
+	require.Len(t, events, 1)
+	assert.Equal(t, "recent", events[0].Message)
+
+	// A repeat of an expired event that was not pruned yet starts over.
+	*now = now.Add(2 * time.Hour)
+	r.Event(model.ContainersBucket, web, model.EventNormal, model.EventStarted, "recent")
+	events = listEvents(t, s)
+	require.Len(t, events, 1)
+	assert.Equal(t, 1, events[0].Count)
+	assert.Equal(t, *now, events[0].FirstSeen)
+}
+
+func TestRecorder_Watch(t *testing.T) {
+	r, _, _ := newTestRecorder(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := r.Watch(ctx)
+
+	r.WithSource("agent").Event(model.ContainersBucket, web, model.EventNormal, model.EventStarted, "started")
+	r.WithSource("agent").Event(model.ContainersBucket, web, model.EventNormal, model.EventStarted, "started")
+
+	first := <-ch
+	assert.Equal(t, 1, first.Count)
+	assert.Equal(t, "agent", first.Source)
+	second := <-ch
+	assert.Equal(t, 2, second.Count)
+	assert.Equal(t, first.Key(), second.Key())
+
+	cancel()
+	for range ch {
+	}
+}
@@ -0,0 +1,319 @@
+// Package eviction removes containers from the nodes they are bound to.
+// An evicted container returns to Pending with its restart history intact;
+// the agent on the old node stops it within its stop timeout and the
+// scheduler places it again, so replicas owned by a controller come back
+// on another node.
+//
+// Drains, rolling updates, rebalancing and preemption are voluntary
+// disruptions and all go through an Evictor, which refuses them with a
+// *BudgetError when they would leave a DisruptionBudget short of healthy
+// containers.
+package eviction
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/rs/zerolog"
+
+	"github.com/github-builder/container-orchestrator/internal/events"
+	"github.com/github-builder/container-orchestrator/internal/gc"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// ErrStale is returned when the container was deleted, replaced or moved
+// to another node since it was read.
+var ErrStale = errors.New("container changed before eviction")
+
+// BudgetError is returned when evicting a container would violate a
+// disruption budget. The eviction can be retried once replacements are
+// healthy.
+type BudgetError struct {
+	// Container and Budget are the keys of the refused container and of
+	// the budget it would violate.
+	Container string
+	Budget    string
+
+	// Status is the budget's status when the eviction was refused.
+	Status model.DisruptionBudgetStatus
+}
+
+// Error implements error.
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("evicting %s would violate disruption budget %s: %d of %d healthy, %d required",
+		e.Container, e.Budget, e.Status.CurrentHealthy, e.Status.ExpectedCount, e.Status.DesiredHealthy)
+}
+
+// Config holds dependencies for the evictor.
+type Config struct {
+	Store  store.Store
+	Logger zerolog.Logger
+
+	// Events records evictions. Defaults to a recorder on Store.
+	Events *events.Recorder
+}
+
+// Evictor evicts containers from their nodes.
+type Evictor struct {
+	store  store.Store
+	logger zerolog.Logger
+	events *events.Recorder
+
+	// mu serializes evictions, so that two cannot both take the last
+	// disruption a budget allows.
+	mu sync.Mutex
+}
+
+// New creates an evictor.
+func New(cfg *Config) *Evictor {
+	recorder := cfg.Events
+	if recorder == nil {
+		recorder = events.NewRecorder(&events.Config{Store: cfg.Store, Logger: cfg.Logger})
+	}
+
+	return &Evictor{
+		store:  cfg.Store,
+		logger: cfg.Logger,
+		events: recorder.WithSource("eviction"),
+	}
+}
+
+// Evict unbinds c from its node and resets it to Pending so that it is
+// scheduled again. The reason is recorded on the container's status. It
+// returns a *BudgetError if a disruption budget does not allow it.
+func (e *Evictor) Evict(c *model.Container, reason string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.checkBudgets([]*model.Container{c}); err != nil {
+		return err
+	}
+	return e.unbind(c, model.ReasonEvicted, reason)
+}
+
+// ForceEvict evicts c like Evict, without consulting disruption budgets.
+// It is for evictions that cannot wait, such as from a NoExecute taint.
+func (e *Evictor) ForceEvict(c *model.Container, reason string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.unbind(c, model.ReasonEvicted, reason)
+}
+
+// Preempt evicts all of cs to make room for a container of higher
+// priority, or none of them if together they would violate a disruption
+// budget. The reason is recorded on their status.
+func (e *Evictor) Preempt(cs []*model.Container, reason string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.checkBudgets(cs); err != nil {
+		return err
+	}
+	for _, c := range cs {
+		if err := e.unbind(c, model.ReasonPreempted, reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CanEvict returns a *BudgetError if evicting all of cs would violate a
+// disruption budget. It changes nothing.
+func (e *Evictor) CanEvict(cs []*model.Container) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.checkBudgets(cs)
+}
+
+// Delete deletes c with gc.Delete, as a controller replacing it does; the
+// agent on its node stops the runtime container. It returns a *BudgetError
+// if a disruption budget does not allow it.
+func (e *Evictor) Delete(c *model.Container, reason string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.checkBudgets([]*model.Container{c}); err != nil {
+		return err
+	}
+	cur, err := store.GetJSON[model.Container](e.store, model.ContainersBucket, c.Key())
+	if store.IsNotFound(err) {
+		return ErrStale
+	}
+	if err != nil {
+		return err
+	}
+	if cur.UID != c.UID || cur.IsTerminating() {
+		return ErrStale
+	}
+	if _, err := gc.Delete(e.store, model.ContainersBucket, &cur.ObjectMeta); err != nil {
+		return err
+	}
+
+	e.logger.Info().Str("container", c.Key()).Str("reason", reason).Msg("container deleted")
+	return nil
+}
+
+// unbind resets c to Pending without a node, with the given status reason
+// and message. The event recorded for it has the same reason. A container
+// being deleted is left on its node for the agent to stop and release.
+func (e *Evictor) unbind(c *model.Container, reason, message string) error {
+	_, err := store.UpdateJSON(e.store, model.ContainersBucket, c.Key(), func(cur *model.Container) error {
+		if cur.UID != c.UID || cur.Spec.NodeName != c.Spec.NodeName || cur.Spec.NodeName == "" || cur.IsTerminating() {
+			return ErrStale
+		}
+		cur.Spec.NodeName = ""
+		cur.Status = model.ContainerStatus{
+			Phase:           model.PhasePending,
+			RestartCount:    cur.Status.RestartCount,
+			LastTermination: cur.Status.LastTermination,
+			Reason:          reason,
+			Message:         message,
+		}
+		return nil
+	})
+	if store.IsNotFound(err) {
+		return ErrStale
+	}
+	if err != nil {
+		return err
+	}
+
+	e.logger.Warn().Str("container", c.Key()).Str("node", c.Spec.NodeName).Str("reason", message).
+		Msg("container evicted")
+	e.events.Eventf(model.ContainersBucket, &c.ObjectMeta, model.EventWarning, reason,
+		"Removed from node %s: %s", c.Spec.NodeName, message)
+	return nil
+}
+
+// checkBudgets returns a *BudgetError if removing all of cs would leave a
+// budget selecting them with fewer healthy containers than it requires.
+// Removing containers that are not healthy costs no availability and is
+// always allowed.
+func (e *Evictor) checkBudgets(cs []*model.Container) error {
+	byNamespace := make(map[string][]*model.Container)
+	var namespaces []string
+	for _, c := range cs {
+		if _, ok := byNamespace[c.Namespace]; !ok {
+			namespaces = append(namespaces, c.Namespace)
+		}
+		byNamespace[c.Namespace] = append(byNamespace[c.Namespace], c)
+	}
+
+	for _, ns := range namespaces {
+		if err := e.checkNamespace(ns, byNamespace[ns]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkNamespace checks the budgets of one namespace against removing cs,
+// which are in it.
+func (e *Evictor) checkNamespace(ns string, cs []*model.Container) error {
+	prefix := model.NamespacePrefix(ns)
+	budgets, err := store.ListJSON[model.DisruptionBudget](e.store, model.DisruptionBudgetsBucket, prefix)
+	if err != nil {
+		return fmt.Errorf("listing disruption budgets: %w", err)
+	}
+	if len(budgets) == 0 {
+		return nil
+	}
+
+	containers, err := store.ListJSON[model.Container](e.store, model.ContainersBucket, prefix)
+	if err != nil {
+		return fmt.Errorf("listing containers: %w", err)
+	}
+	byName := make(map[string]*model.Container, len(containers))
+	for _, c := range containers {
+		byName[c.Name] = c
+	}
+
+	// Budgets are checked against the stored state of the containers.
+	var healthy []*model.Container
+	for _, c := range cs {
+		cur := byName[c.Name]
+		if cur == nil || cur.UID != c.UID {
+			return ErrStale
+		}
+		if model.Healthy(cur) {
+			healthy = append(healthy, cur)
+		}
+	}
+
+	for _, b := range budgets {
+		var selected []*model.Container
+		for _, c := range healthy {
+			if b.Selects(c) {
+				selected = append(selected, c)
+			}
+		}
+		if len(selected) == 0 {
+			continue
+		}
+		status, err := e.status(b, containers)
+		if err != nil {
+			return err
+		}
+		if len(selected) > status.DisruptionsAllowed {
+			return &BudgetError{Container: selected[status.DisruptionsAllowed].Key(), Budget: b.Key(), Status: status}
+		}
+	}
+	return nil
+}
+
+// Status computes the current status of a disruption budget.
+func (e *Evictor) Status(b *model.DisruptionBudget) (model.DisruptionBudgetStatus, error) {
+	containers, err := store.ListJSON[model.Container](e.store, model.ContainersBucket, model.NamespacePrefix(b.Namespace))
+	if err != nil {
+		return model.DisruptionBudgetStatus{}, fmt.Errorf("listing containers: %w", err)
+	}
+	return e.status(b, containers)
+}
+
+// status computes b's status over containers, those in its namespace. The
+// deployments and stateful sets owning selected containers contribute
+// their desired replicas, so that replicas a rollout deleted and has not
+// replaced yet still count as expected.
+func (e *Evictor) status(b *model.DisruptionBudget, containers []*model.Container) (model.DisruptionBudgetStatus, error) {
+	owners := make(map[string]string)
+	for _, c := range containers {
+		if !b.Selects(c) {
+			continue
+		}
+		if name, ok := c.Labels[model.LabelDeployment]; ok {
+			owners[model.Key(c.Namespace, name)] = model.DeploymentsBucket
+		}
+		if name, ok := c.Labels[model.LabelStatefulSet]; ok {
+			owners[model.Key(c.Namespace, name)] = model.StatefulSetsBucket
+		}
+	}
+
+	desired := 0
+	for key, bucket := range owners {
+		var (
+			replicas int
+			err      error
+		)
+		switch bucket {
+		case model.DeploymentsBucket:
+			var d *model.Deployment
+			if d, err = store.GetJSON[model.Deployment](e.store, bucket, key); err == nil {
+				replicas = d.Spec.Replicas
+			}
+		case model.StatefulSetsBucket:
+			var ss *model.StatefulSet
+			if ss, err = store.GetJSON[model.StatefulSet](e.store, bucket, key); err == nil {
+				replicas = ss.Spec.Replicas
+			}
+		}
+		if err != nil && !store.IsNotFound(err) {
+			return model.DisruptionBudgetStatus{}, err
+		}
+		desired += replicas
+	}
+	return b.Compute(containers, desired), nil
+}
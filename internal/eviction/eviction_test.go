@@ -0,0 +1,122 @@
+package eviction
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+func newTestEvictor(t *testing.T) (*Evictor, store.Store) {
+	t.Helper()
+	s := store.NewMemoryStore()
+	return New(&Config{Store: s, Logger: zerolog.Nop()}), s
+}
+
+func putReplica(t *testing.T, s store.Store, name, nodeName string, ready bool) *model.Container {
+	t.Helper()
+	c := &model.Container{
+		ObjectMeta: model.ObjectMeta{Name: name, Namespace: model.DefaultNamespace, Labels: map[string]string{"app": "web"}},
+		Spec:       model.ContainerSpec{Image: "nginx", NodeName: nodeName},
+		Status:     model.ContainerStatus{Phase: model.PhaseRunning, Ready: ready, RestartCount: 1},
+	}
+	require.NoError(t, c.Initialize())
+	require.NoError(t, store.PutJSON(s, model.ContainersBucket, c.Key(), c))
+	return c
+}
+
+func putBudget(t *testing.T, s store.Store, minAvailable int) {
+	t.Helper()
+	b := &model.DisruptionBudget{
+		ObjectMeta: model.ObjectMeta{Name: "web", Namespace: model.DefaultNamespace},
+		Spec: model.DisruptionBudgetSpec{
+			Selector:     model.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			MinAvailable: model.FromInt(minAvailable),
+		},
+	}
+	require.NoError(t, store.PutJSON(s, model.DisruptionBudgetsBucket, b.Key(), b))
+}
+
+func get(t *testing.T, s store.Store, c *model.Container) *model.Container {
+	t.Helper()
+	cur, err := store.GetJSON[model.Container](s, model.ContainersBucket, c.Key())
+	require.NoError(t, err)
+	return cur
+}
+
+func TestEvict(t *testing.T) {
+	e, s := newTestEvictor(t)
+	c := putReplica(t, s, "web-1", "node-1", true)
+
+	require.NoError(t, e.Evict(c, "node node-1 is draining"))
+
+	cur := get(t, s, c)
+	assert.Empty(t, cur.Spec.NodeName)
+	assert.Equal(t, model.PhasePending, cur.Status.Phase)
+	assert.Equal(t, model.ReasonEvicted, cur.Status.Reason)
+	assert.Equal(t, "node node-1 is draining", cur.Status.Message)
+	assert.Equal(t, 1, cur.Status.RestartCount)
+
+	// The container is no longer on the node it was read on.
+	assert.ErrorIs(t, e.Evict(c, "again"), ErrStale)
+
+	gone := &model.Container{ObjectMeta: model.ObjectMeta{Name: "gone", Namespace: model.DefaultNamespace}}
+	assert.ErrorIs(t, e.Evict(gone, "missing"), ErrStale)
+}
+
+func TestEvict_RespectsBudget(t *testing.T) {
+	e, s := newTestEvictor(t)
+	putBudget(t, s, 2)
+	web1 := putReplica(t, s, "web-1", "node-1", true)
+	web2 := putReplica(t, s, "web-2", "node-1", true)
+	web3 := putReplica(t, s, "web-3", "node-2", true)
+	unready := putReplica(t, s, "web-4", "node-1", false)
+
+	require.NoError(t, e.Evict(web1, "drain"))
+
+	// Two of four are healthy now, which is all the budget allows.
+	err := e.Evict(web2, "drain")
+	var berr *BudgetError
+	require.ErrorAs(t, err, &berr)
+	assert.Equal(t, "default/web-2", berr.Container)
+	assert.Equal(t, "default/web", berr.Budget)
+	assert.Equal(t, model.DisruptionBudgetStatus{
+		ExpectedCount: 4, CurrentHealthy: 2, DesiredHealthy: 2,
+	}, berr.Status)
+	assert.Equal(t, "node-1", get(t, s, web2).Spec.NodeName)
+	assert.ErrorAs(t, e.Delete(web3, "rollout"), &berr)
+
+	// Unhealthy containers cost nothing and may always go.
+	require.NoError(t, e.Evict(unready, "drain"))
+
+	// Forced evictions ignore budgets.
+	require.NoError(t, e.ForceEvict(web2, "NoExecute taint"))
+	assert.Empty(t, get(t, s, web2).Spec.NodeName)
+
+	// Once a replacement is healthy, the next eviction is allowed.
+	putReplica(t, s, "web-5", "node-2", true)
+	putReplica(t, s, "web-6", "node-2", true)
+	require.NoError(t, e.Delete(web3, "rollout"))
+	_, err = store.GetJSON[model.Container](s, model.ContainersBucket, web3.Key())
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	assert.ErrorIs(t, e.Delete(web3, "rollout"), ErrStale)
+}
+
+func TestEvict_BudgetsAreNamespaced(t *testing.T) {
+	e, s := newTestEvictor(t)
+	putBudget(t, s, 1)
+
+	c := &model.Container{
+		ObjectMeta: model.ObjectMeta{Name: "web-1", Namespace: "team-a", Labels: map[string]string{"app": "web"}},
+		Spec:       model.ContainerSpec{Image: "nginx", NodeName: "node-1"},
+		Status:     model.ContainerStatus{Phase: model.PhaseRunning, Ready: true},
+	}
+	require.NoError(t, c.Initialize())
+	require.NoError(t, store.PutJSON(s, model.ContainersBucket, c.Key(), c))
+
+	assert.NoError(t, e.Evict(c, "drain"))
+}
@@ -0,0 +1,55 @@
+package gc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// Claim returns the candidates owner, of kind, controls. Candidates are the
+// resources in bucket carrying the owner's label. Those whose controller
+// reference points at owner are its own, and those without a controller,
+// orphaned or created before owner references, are adopted by adding one.
+// Candidates controlled by another resource, such as a deleted owner of
+// the same name awaiting collection, are left out, and so are those
+// waiting for their finalizers: they are on their way out and are not
+// counted.
+func Claim[T any, P interface {
+	*T
+	model.Object
+}](s store.Store, bucket, kind string, owner *model.ObjectMeta, candidates []P) ([]P, error) {
+	owned := make([]P, 0, len(candidates))
+	var errs []error
+	for _, c := range candidates {
+		meta := c.Meta()
+		if meta.IsTerminating() {
+			continue
+		}
+		if ref := meta.ControllerRef(); ref != nil {
+			if ref.UID == owner.UID {
+				owned = append(owned, c)
+			}
+			continue
+		}
+
+		adopted, err := store.UpdateJSON(s, bucket, meta.Key(), func(cur *T) error {
+			m := P(cur).Meta()
+			if m.UID != meta.UID || m.ControllerRef() != nil {
+				return errStale
+			}
+			m.OwnerReferences = append(m.OwnerReferences, model.NewControllerRef(kind, owner))
+			return nil
+		})
+		if errors.Is(err, errStale) || errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("adopting %s: %w", meta.Key(), err))
+			continue
+		}
+		owned = append(owned, P(adopted))
+	}
+	return owned, errors.Join(errs...)
+}
@@ -0,0 +1,330 @@
+// Package gc implements the garbage collector. Resources name the resources
+// they depend on in owner references, and each pass the collector:
+//
+//   - deletes resources whose owners are all gone,
+//   - deletes the dependents of owners being deleted in the foreground,
+//     and drops the references to owners being deleted with orphaning,
+//   - clears the finalizers of such owners once no dependent refers to
+//     them,
+//   - removes the records of terminating resources whose finalizers have
+//     all been cleared.
+//
+// Controllers create resources with Create, claim them with Claim, update
+// them with Update and delete them with Delete, which respects their
+// finalizers. Those that hold resources with finalizers of their own
+// release them with RemoveFinalizer.
+package gc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// errStale is returned when a resource changed while being collected.
+var errStale = errors.New("resource changed during collection")
+
+// Config holds dependencies for the garbage collector.
+type Config struct {
+	Store  store.Store
+	Logger zerolog.Logger
+
+	// Interval is how often the collector runs.
+	Interval time.Duration
+}
+
+// Collector deletes resources whose owners are gone.
+type Collector struct {
+	store    store.Store
+	logger   zerolog.Logger
+	interval time.Duration
+	now      func() time.Time
+}
+
+// NewCollector creates a garbage collector.
+func NewCollector(cfg *Config) *Collector {
+	return &Collector{
+		store:    cfg.Store,
+		logger:   cfg.Logger,
+		interval: cfg.Interval,
+		now:      func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run collects garbage every interval until ctx is canceled.
+func (c *Collector) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Sync(ctx); err != nil {
+			c.logger.Error().Err(err).Msg("garbage collection failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// object is the metadata of a resource of any kind.
+type object struct {
+	model.ObjectMeta `json:"metadata"`
+
+	kind string
+}
+
+// ref returns the lookup key of the resource of kind named name in
+// namespace.
+func ref(kind, namespace, name string) string {
+	return kind + ":" + model.Key(namespace, name)
+}
+
+// graph is what one pass knows of the namespaced resources.
+type graph struct {
+	objects map[string]*object
+
+	// blocking and referrers count the references to each owner UID: those
+	// that hold up its foreground deletion, and all of them.
+	blocking  map[string]int
+	referrers map[string]int
+
+	// mounted holds the keys of the volumes that containers mount.
+	mounted map[string]bool
+}
+
+// load reads the metadata of every namespaced resource, and the volumes
+// containers mount.
+func (c *Collector) load() (*graph, []*object, error) {
+	g := &graph{
+		objects:   make(map[string]*object),
+		blocking:  make(map[string]int),
+		referrers: make(map[string]int),
+		mounted:   make(map[string]bool),
+	}
+	var all []*object
+	for _, kind := range model.NamespacedBuckets {
+		objects, err := store.ListJSON[object](c.store, kind, "")
+		if err != nil {
+			return nil, nil, fmt.Errorf("listing %s: %w", kind, err)
+		}
+		for _, o := range objects {
+			o.kind = kind
+			g.objects[ref(kind, o.Namespace, o.Name)] = o
+			for _, r := range o.OwnerReferences {
+				g.referrers[r.UID]++
+				if r.BlockOwnerDeletion {
+					g.blocking[r.UID]++
+				}
+			}
+		}
+		all = append(all, objects...)
+	}
+
+	containers, err := store.ListJSON[model.Container](c.store, model.ContainersBucket, "")
+	if err != nil {
+		return nil, nil, fmt.Errorf("listing containers: %w", err)
+	}
+	for _, ctr := range containers {
+		for _, vm := range ctr.Spec.VolumeMounts {
+			g.mounted[model.Key(ctr.Namespace, vm.Name)] = true
+		}
+	}
+	return g, all, nil
+}
+
+// owner returns the live owner r refers to from namespace, or nil if it is
+// gone or has been replaced by another of the same name.
+func (g *graph) owner(namespace string, r model.OwnerReference) *object {
+	o := g.objects[ref(r.Kind, namespace, r.Name)]
+	if o == nil || o.UID != r.UID {
+		return nil
+	}
+	return o
+}
+
+// Sync runs one collection pass.
+func (c *Collector) Sync(ctx context.Context) error {
+	g, all, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, o := range all {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := c.collect(g, o); err != nil && !errors.Is(err, errStale) {
+			errs = append(errs, fmt.Errorf("%s %s: %w", o.kind, o.Key(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// collect handles one resource: as a dependent, according to the state of
+// its owners, and as an owner being deleted, once its dependents are
+// dealt with.
+func (c *Collector) collect(g *graph, o *object) error {
+	if o.IsTerminating() {
+		return c.finalize(g, o)
+	}
+	if len(o.OwnerReferences) == 0 {
+		return nil
+	}
+
+	var live, foreground, orphaning int
+	keep := make([]string, 0, len(o.OwnerReferences))
+	for _, r := range o.OwnerReferences {
+		owner := g.owner(o.Namespace, r)
+		switch {
+		case owner == nil:
+		case owner.HasFinalizer(model.FinalizerOrphan):
+			orphaning++
+		case owner.HasFinalizer(model.FinalizerForeground):
+			foreground++
+		default:
+			live++
+			keep = append(keep, r.UID)
+		}
+	}
+
+	switch {
+	case live > 0:
+		if live < len(o.OwnerReferences) {
+			return c.keepOwners(o, keep)
+		}
+		return nil
+	case foreground > 0:
+		// A dependent with dependents of its own is deleted in the
+		// foreground too, so the owner waits for the whole tree.
+		if g.referrers[o.UID] > 0 {
+			return c.terminate(o)
+		}
+		return c.delete(g, o, "owner deleted in foreground")
+	case orphaning > 0:
+		return c.keepOwners(o, nil)
+	default:
+		return c.delete(g, o, "owners gone")
+	}
+}
+
+// finalize clears the finalizers of the collector from a terminating
+// resource once its dependents are dealt with, and removes its record when
+// no finalizers are left.
+func (c *Collector) finalize(g *graph, o *object) error {
+	var done []string
+	if o.HasFinalizer(model.FinalizerForeground) && g.blocking[o.UID] == 0 {
+		done = append(done, model.FinalizerForeground)
+	}
+	if o.HasFinalizer(model.FinalizerOrphan) && g.referrers[o.UID] == 0 {
+		done = append(done, model.FinalizerOrphan)
+	}
+	if len(done) > 0 {
+		err := updateMeta(c.store, o.kind, &o.ObjectMeta, func(m *model.ObjectMeta) error {
+			for _, f := range done {
+				m.RemoveFinalizer(f)
+			}
+			o.Finalizers = m.Finalizers
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if len(o.Finalizers) > 0 {
+		return nil
+	}
+	return c.delete(g, o, "finalized")
+}
+
+// delete deletes a resource with Delete. Volumes stay while a container
+// mounts them, so the agent stops the container before pruning the data.
+func (c *Collector) delete(g *graph, o *object, why string) error {
+	if o.kind == model.VolumesBucket && g.mounted[o.Key()] {
+		return nil
+	}
+	gone, err := Delete(c.store, o.kind, &o.ObjectMeta)
+	if err != nil {
+		return fmt.Errorf("deleting: %w", err)
+	}
+	if gone {
+		c.logger.Info().Str("kind", o.kind).Str("name", o.Key()).Str("reason", why).Msg("deleted")
+	}
+	return nil
+}
+
+// terminate starts a foreground deletion of a resource.
+func (c *Collector) terminate(o *object) error {
+	if o.IsTerminating() {
+		return nil
+	}
+	now := c.now()
+	return updateMeta(c.store, o.kind, &o.ObjectMeta, func(m *model.ObjectMeta) error {
+		if !m.HasFinalizer(model.FinalizerForeground) {
+			m.Finalizers = append(m.Finalizers, model.FinalizerForeground)
+		}
+		if m.DeletionTimestamp == nil {
+			m.DeletionTimestamp = &now
+		}
+		return nil
+	})
+}
+
+// keepOwners drops the owner references of a resource other than those to
+// the owners in uids.
+func (c *Collector) keepOwners(o *object, uids []string) error {
+	err := updateMeta(c.store, o.kind, &o.ObjectMeta, func(m *model.ObjectMeta) error {
+		m.OwnerReferences = slices.DeleteFunc(m.OwnerReferences, func(r model.OwnerReference) bool {
+			return !slices.Contains(uids, r.UID)
+		})
+		return nil
+	})
+	if err == nil && len(uids) == 0 {
+		c.logger.Info().Str("kind", o.kind).Str("name", o.Key()).Msg("orphaned")
+	}
+	return err
+}
+
+// updateMeta changes the metadata of the resource of kind described by
+// meta, leaving the rest of its record as it is. It fails with a stale
+// error if the resource has been replaced since meta was read, and with
+// any error fn returns, in which case nothing changes.
+func updateMeta(s store.Store, kind string, meta *model.ObjectMeta, fn func(m *model.ObjectMeta) error) error {
+	err := s.Update(kind, meta.Key(), func(cur []byte) ([]byte, error) {
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(cur, &raw); err != nil {
+			return nil, err
+		}
+		var m model.ObjectMeta
+		if err := json.Unmarshal(raw["metadata"], &m); err != nil {
+			return nil, err
+		}
+		if m.UID != meta.UID {
+			return nil, errStale
+		}
+		if err := fn(&m); err != nil {
+			return nil, err
+		}
+		b, err := json.Marshal(&m)
+		if err != nil {
+			return nil, err
+		}
+		raw["metadata"] = b
+		return json.Marshal(raw)
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		return errStale
+	}
+	return err
+}
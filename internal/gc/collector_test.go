@@ -0,0 +1,229 @@
+package gc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+func newTestCollector(t *testing.T) (*Collector, store.Store) {
+	t.Helper()
+	s := store.NewMemoryStore()
+	return NewCollector(&Config{Store: s, Logger: zerolog.Nop(), Interval: time.Hour}), s
+}
+
+func putJob(t *testing.T, s store.Store, name string, owners ...model.OwnerReference) *model.Job {
+	t.Helper()
+	j := &model.Job{ObjectMeta: model.ObjectMeta{
+		Name: name, Namespace: model.DefaultNamespace, UID: name + "-uid", OwnerReferences: owners,
+	}}
+	require.NoError(t, store.PutJSON(s, model.JobsBucket, j.Key(), j))
+	return j
+}
+
+func putContainer(t *testing.T, s store.Store, name string, owners ...model.OwnerReference) *model.Container {
+	t.Helper()
+	c := &model.Container{
+		ObjectMeta: model.ObjectMeta{
+			Name: name, Namespace: model.DefaultNamespace, UID: name + "-uid", OwnerReferences: owners,
+		},
+		Spec: model.ContainerSpec{Image: "app"},
+	}
+	require.NoError(t, store.PutJSON(s, model.ContainersBucket, c.Key(), c))
+	return c
+}
+
+func putCronJob(t *testing.T, s store.Store, name string) *model.CronJob {
+	t.Helper()
+	cj := &model.CronJob{ObjectMeta: model.ObjectMeta{Name: name, Namespace: model.DefaultNamespace, UID: name + "-uid"}}
+	require.NoError(t, store.PutJSON(s, model.CronJobsBucket, cj.Key(), cj))
+	return cj
+}
+
+// terminate starts the deletion of a resource with policy, as the API does.
+func terminate(t *testing.T, s store.Store, bucket, name string, policy model.PropagationPolicy) {
+	t.Helper()
+	now := time.Now().UTC()
+	require.NoError(t, updateMeta(s, bucket, &model.ObjectMeta{
+		Name: name, Namespace: model.DefaultNamespace, UID: name + "-uid",
+	}, func(m *model.ObjectMeta) error {
+		if f := policy.Finalizer(); f != "" {
+			m.Finalizers = append(m.Finalizers, f)
+		}
+		m.DeletionTimestamp = &now
+		return nil
+	}))
+}
+
+func exists(t *testing.T, s store.Store, bucket, name string) bool {
+	t.Helper()
+	_, err := s.Get(bucket, model.Key(model.DefaultNamespace, name))
+	if err != nil {
+		require.ErrorIs(t, err, store.ErrNotFound)
+		return false
+	}
+	return true
+}
+
+func syncOnce(t *testing.T, c *Collector) {
+	t.Helper()
+	require.NoError(t, c.Sync(context.Background()))
+}
+
+func TestSync_Background(t *testing.T) {
+	c, s := newTestCollector(t)
+	j := putJob(t, s, "report")
+	putContainer(t, s, "report-1", model.NewControllerRef(model.JobsBucket, &j.ObjectMeta))
+	putContainer(t, s, "standalone")
+
+	// A replacement of the same name does not own the old one's dependents.
+	putContainer(t, s, "stale", model.OwnerReference{Kind: model.JobsBucket, Name: "report", UID: "old-uid"})
+
+	syncOnce(t, c)
+	assert.True(t, exists(t, s, model.ContainersBucket, "report-1"))
+	assert.True(t, exists(t, s, model.ContainersBucket, "standalone"))
+	assert.False(t, exists(t, s, model.ContainersBucket, "stale"))
+
+	require.NoError(t, s.Delete(model.JobsBucket, j.Key()))
+	syncOnce(t, c)
+	assert.False(t, exists(t, s, model.ContainersBucket, "report-1"))
+	assert.True(t, exists(t, s, model.ContainersBucket, "standalone"))
+}
+
+func TestSync_DropsReferencesToGoneOwners(t *testing.T) {
+	c, s := newTestCollector(t)
+	a := putJob(t, s, "a")
+	putContainer(t, s, "shared",
+		model.OwnerReference{Kind: model.JobsBucket, Name: "a", UID: a.UID},
+		model.OwnerReference{Kind: model.JobsBucket, Name: "b", UID: "b-uid"})
+
+	syncOnce(t, c)
+	ctr, err := store.GetJSON[model.Container](s, model.ContainersBucket, model.Key(model.DefaultNamespace, "shared"))
+	require.NoError(t, err)
+	assert.Equal(t, []model.OwnerReference{{Kind: model.JobsBucket, Name: "a", UID: a.UID}}, ctr.OwnerReferences)
+	assert.Equal(t, "app", ctr.Spec.Image, "the rest of the record is kept")
+}
+
+func TestSync_Foreground(t *testing.T) {
+	c, s := newTestCollector(t)
+	cj := putCronJob(t, s, "nightly")
+	j := putJob(t, s, "nightly-1", model.NewControllerRef(model.CronJobsBucket, &cj.ObjectMeta))
+	putContainer(t, s, "nightly-1-a", model.NewControllerRef(model.JobsBucket, &j.ObjectMeta))
+	terminate(t, s, model.CronJobsBucket, "nightly", model.PropagationForeground)
+
+	// The job has dependents of its own, so it is deleted in the
+	// foreground too and the cron job waits for the whole tree.
+	syncOnce(t, c)
+	job, err := store.GetJSON[model.Job](s, model.JobsBucket, j.Key())
+	require.NoError(t, err)
+	assert.True(t, job.HasFinalizer(model.FinalizerForeground))
+	assert.True(t, exists(t, s, model.CronJobsBucket, "nightly"))
+
+	syncOnce(t, c)
+	assert.False(t, exists(t, s, model.ContainersBucket, "nightly-1-a"))
+	assert.True(t, exists(t, s, model.JobsBucket, "nightly-1"))
+
+	syncOnce(t, c)
+	assert.False(t, exists(t, s, model.JobsBucket, "nightly-1"))
+	assert.True(t, exists(t, s, model.CronJobsBucket, "nightly"))
+
+	syncOnce(t, c)
+	assert.False(t, exists(t, s, model.CronJobsBucket, "nightly"))
+}
+
+func TestSync_Orphan(t *testing.T) {
+	c, s := newTestCollector(t)
+	j := putJob(t, s, "report")
+	putContainer(t, s, "report-1", model.NewControllerRef(model.JobsBucket, &j.ObjectMeta))
+	terminate(t, s, model.JobsBucket, "report", model.PropagationOrphan)
+
+	syncOnce(t, c)
+	ctr, err := store.GetJSON[model.Container](s, model.ContainersBucket, model.Key(model.DefaultNamespace, "report-1"))
+	require.NoError(t, err)
+	assert.Empty(t, ctr.OwnerReferences)
+	assert.True(t, exists(t, s, model.JobsBucket, "report"))
+
+	syncOnce(t, c)
+	assert.False(t, exists(t, s, model.JobsBucket, "report"))
+	assert.True(t, exists(t, s, model.ContainersBucket, "report-1"))
+}
+
+func TestSync_KeepsMountedVolumes(t *testing.T) {
+	c, s := newTestCollector(t)
+	ref := model.OwnerReference{Kind: model.GroupsBucket, Name: "shop", UID: "shop-uid"}
+	v := &model.Volume{ObjectMeta: model.ObjectMeta{
+		Name: "shop-scratch", Namespace: model.DefaultNamespace, UID: "v-uid", OwnerReferences: []model.OwnerReference{ref},
+	}}
+	require.NoError(t, store.PutJSON(s, model.VolumesBucket, v.Key(), v))
+	ctr := putContainer(t, s, "shop-app", ref)
+	_, err := store.UpdateJSON(s, model.ContainersBucket, ctr.Key(), func(c *model.Container) error {
+		c.Spec.VolumeMounts = []model.VolumeMount{{Name: "shop-scratch", MountPath: "/tmp"}}
+		return nil
+	})
+	require.NoError(t, err)
+
+	syncOnce(t, c)
+	assert.False(t, exists(t, s, model.ContainersBucket, "shop-app"))
+	assert.True(t, exists(t, s, model.VolumesBucket, "shop-scratch"))
+
+	syncOnce(t, c)
+	assert.False(t, exists(t, s, model.VolumesBucket, "shop-scratch"))
+}
+
+func TestClaim(t *testing.T) {
+	s := store.NewMemoryStore()
+	j := putJob(t, s, "report")
+	mine := putContainer(t, s, "mine", model.NewControllerRef(model.JobsBucket, &j.ObjectMeta))
+	orphan := putContainer(t, s, "orphan")
+	other := putContainer(t, s, "other", model.OwnerReference{Kind: model.JobsBucket, Name: "report", UID: "old-uid", Controller: true})
+
+	owned, err := Claim(s, model.ContainersBucket, model.JobsBucket, &j.ObjectMeta, []*model.Container{mine, orphan, other})
+	require.NoError(t, err)
+	require.Len(t, owned, 2)
+	assert.Equal(t, "mine", owned[0].Name)
+	assert.Equal(t, "orphan", owned[1].Name)
+	assert.True(t, owned[1].IsControlledBy(&j.ObjectMeta))
+
+	adopted, err := store.GetJSON[model.Container](s, model.ContainersBucket, orphan.Key())
+	require.NoError(t, err)
+	assert.True(t, adopted.IsControlledBy(&j.ObjectMeta))
+}
+
+func TestDelete_WaitsForFinalizers(t *testing.T) {
+	c, s := newTestCollector(t)
+	plain := putJob(t, s, "plain")
+	gone, err := Delete(s, model.JobsBucket, &plain.ObjectMeta)
+	require.NoError(t, err)
+	assert.True(t, gone)
+	assert.False(t, exists(t, s, model.JobsBucket, "plain"))
+
+	held := putJob(t, s, "held")
+	require.NoError(t, updateMeta(s, model.JobsBucket, &held.ObjectMeta, func(m *model.ObjectMeta) error {
+		m.Finalizers = []string{"dns.example.com/record"}
+		return nil
+	}))
+	gone, err = Delete(s, model.JobsBucket, &held.ObjectMeta)
+	require.NoError(t, err)
+	assert.False(t, gone)
+	job, err := store.GetJSON[model.Job](s, model.JobsBucket, held.Key())
+	require.NoError(t, err)
+	assert.True(t, job.IsTerminating())
+
+	// The collector leaves finalizers it does not own alone.
+	syncOnce(t, c)
+	assert.True(t, exists(t, s, model.JobsBucket, "held"))
+
+	require.NoError(t, updateMeta(s, model.JobsBucket, &held.ObjectMeta, func(m *model.ObjectMeta) error {
+		m.RemoveFinalizer("dns.example.com/record")
+		return nil
+	}))
+	syncOnce(t, c)
+	assert.False(t, exists(t, s, model.JobsBucket, "held"))
+}
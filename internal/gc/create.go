@@ -0,0 +1,57 @@
+package gc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// createAttempts is how many names Create tries before giving up.
+const createAttempts = 3
+
+// ErrStale is returned by Update when the resource has been deleted or
+// replaced by another of the same name since it was read.
+var ErrStale = errors.New("resource was deleted or replaced")
+
+// Create stores the new resource obj in bucket under a name made of prefix
+// and a random suffix, e.g. "web-3f2a1". Each attempt gives obj a new UID,
+// the suffix is taken from, and a creation time; random suffixes can
+// collide, so a few are tried before giving up with store.ErrAlreadyExists.
+func Create[P model.Object](s store.Store, bucket, prefix string, obj P) error {
+	meta := obj.Meta()
+	for range createAttempts {
+		if err := meta.Initialize(); err != nil {
+			return err
+		}
+		meta.Name = fmt.Sprintf("%s-%s", prefix, meta.UID[:5])
+
+		err := store.CreateJSON(s, bucket, meta.Key(), obj)
+		if !errors.Is(err, store.ErrAlreadyExists) {
+			return err
+		}
+	}
+	return store.ErrAlreadyExists
+}
+
+// Update applies fn to the stored record of the resource in bucket that
+// meta describes, like store.UpdateJSON, provided it is still the same
+// resource. It fails with ErrStale if the record is gone or belongs to a
+// resource created since meta was read, and with any error fn returns, in
+// which case nothing changes.
+func Update[T any, P interface {
+	*T
+	model.Object
+}](s store.Store, bucket string, meta *model.ObjectMeta, fn func(cur P) error) (P, error) {
+	updated, err := store.UpdateJSON(s, bucket, meta.Key(), func(cur *T) error {
+		if P(cur).Meta().UID != meta.UID {
+			return ErrStale
+		}
+		return fn(cur)
+	})
+	if store.IsNotFound(err) {
+		return nil, ErrStale
+	}
+	return updated, err
+}
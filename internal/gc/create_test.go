@@ -0,0 +1,47 @@
+package gc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+func TestCreate_SuffixesPrefix(t *testing.T) {
+	s := store.NewMemoryStore()
+
+	c := &model.Container{ObjectMeta: model.ObjectMeta{Namespace: model.DefaultNamespace}}
+	require.NoError(t, Create(s, model.ContainersBucket, "web", c))
+	assert.True(t, strings.HasPrefix(c.Name, "web-"+c.UID[:5]), c.Name)
+	assert.False(t, c.CreatedAt.IsZero())
+
+	got, err := store.GetJSON[model.Container](s, model.ContainersBucket, c.Key())
+	require.NoError(t, err)
+	assert.Equal(t, c.UID, got.UID)
+}
+
+func TestUpdate_Stale(t *testing.T) {
+	s := store.NewMemoryStore()
+	j := putJob(t, s, "backup")
+
+	updated, err := Update(s, model.JobsBucket, &j.ObjectMeta, func(cur *model.Job) error {
+		cur.Status.Succeeded = 1
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, updated.Status.Succeeded)
+
+	replaced := j.ObjectMeta
+	replaced.UID = "other-uid"
+	_, err = Update(s, model.JobsBucket, &replaced, func(*model.Job) error { return nil })
+	assert.ErrorIs(t, err, ErrStale)
+
+	gone := j.ObjectMeta
+	gone.Name = "gone"
+	_, err = Update(s, model.JobsBucket, &gone, func(*model.Job) error { return nil })
+	assert.ErrorIs(t, err, ErrStale)
+}
@@ -0,0 +1,70 @@
+package gc
+
+import (
+	"errors"
+	"time"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// errNoFinalizers is returned by the update of a resource that Delete can
+// remove at once.
+var errNoFinalizers = errors.New("resource has no finalizers")
+
+// Delete deletes the resource of kind described by meta. A resource
+// without finalizers is removed at once; one with finalizers is marked as
+// terminating and removed by the collector once they have been cleared.
+// It reports whether the record is gone, which it also is when the
+// resource was deleted or replaced by another of the same name since meta
+// was read.
+func Delete(s store.Store, kind string, meta *model.ObjectMeta) (bool, error) {
+	now := time.Now().UTC()
+	err := updateMeta(s, kind, meta, func(m *model.ObjectMeta) error {
+		if len(m.Finalizers) == 0 {
+			return errNoFinalizers
+		}
+		if m.DeletionTimestamp == nil {
+			m.DeletionTimestamp = &now
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errStale):
+		return true, nil
+	case errors.Is(err, errNoFinalizers):
+		err = s.Delete(kind, meta.Key())
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return false, err
+		}
+		return true, nil
+	case err != nil:
+		return false, err
+	}
+	return false, nil
+}
+
+// RemoveFinalizer removes finalizer f from the resource of kind described
+// by meta, as the controller responsible for it does once its cleanup is
+// done. A terminating resource left without finalizers is removed. A
+// resource deleted or replaced since meta was read is left alone.
+func RemoveFinalizer(s store.Store, kind string, meta *model.ObjectMeta, f string) error {
+	var done bool
+	err := updateMeta(s, kind, meta, func(m *model.ObjectMeta) error {
+		if !m.RemoveFinalizer(f) {
+			return errNoFinalizers
+		}
+		done = m.IsTerminating() && len(m.Finalizers) == 0
+		return nil
+	})
+	if errors.Is(err, errStale) || errors.Is(err, errNoFinalizers) {
+		return nil
+	}
+	if err != nil || !done {
+		return err
+	}
+	if err := s.Delete(kind, meta.Key()); err != nil && !store.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+// Package group implements the group controller. Each pass it creates the
+// member containers and scratch volumes of every group and aggregates the
+// members' status into the group's. Those of deleted groups are left to
+// the garbage collector.
+// Scheduling the members as one unit and starting them in order is left to
+// the scheduler and the agent.
+package group
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/github-builder/container-orchestrator/internal/events"
+	"github.com/github-builder/container-orchestrator/internal/gc"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// errStale is returned when a group changed while being reconciled.
+var errStale = errors.New("group changed during reconciliation")
+
+// Config holds dependencies for the group controller.
+type Config struct {
+	Store  store.Store
+	Logger zerolog.Logger
+
+	// Interval is how often groups are reconciled.
+	Interval time.Duration
+
+	// Events records the members and volumes created and the members
+	// deleted. Defaults to a recorder on Store.
+	Events *events.Recorder
+}
+
+// Controller reconciles groups with their members and volumes.
+type Controller struct {
+	store    store.Store
+	logger   zerolog.Logger
+	interval time.Duration
+	events   *events.Recorder
+}
+
+// NewController creates a group controller.
+func NewController(cfg *Config) *Controller {
+	recorder := cfg.Events
+	if recorder == nil {
+		recorder = events.NewRecorder(&events.Config{Store: cfg.Store, Logger: cfg.Logger})
+	}
+
+	return &Controller{
+		store:    cfg.Store,
+		logger:   cfg.Logger,
+		interval: cfg.Interval,
+		events:   recorder.WithSource("groups"),
+	}
+}
+
+// Run reconciles groups every interval until ctx is canceled.
+func (c *Controller) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Sync(ctx); err != nil {
+			c.logger.Error().Err(err).Msg("group reconciliation failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync reconciles every group once. Groups being deleted are not
+// reconciled.
+func (c *Controller) Sync(ctx context.Context) error {
+	groups, err := store.ListJSON[model.Group](c.store, model.GroupsBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing groups: %w", err)
+	}
+	containers, err := store.ListJSON[model.Container](c.store, model.ContainersBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing containers: %w", err)
+	}
+	volumes, err := store.ListJSON[model.Volume](c.store, model.VolumesBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing volumes: %w", err)
+	}
+
+	members := make(map[string][]*model.Container)
+	for _, ctr := range containers {
+		if owner, ok := ctr.Labels[model.LabelGroup]; ok {
+			key := model.Key(ctr.Namespace, owner)
+			members[key] = append(members[key], ctr)
+		}
+	}
+	groupVolumes := make(map[string][]*model.Volume)
+	for _, v := range volumes {
+		if owner, ok := v.Labels[model.LabelGroup]; ok {
+			key := model.Key(v.Namespace, owner)
+			groupVolumes[key] = append(groupVolumes[key], v)
+		}
+	}
+
+	var errs []error
+	for _, g := range groups {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if g.IsTerminating() {
+			continue
+		}
+		owned, err := gc.Claim(c.store, model.ContainersBucket, model.GroupsBucket, &g.ObjectMeta, members[g.Key()])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("group %s: %w", g.Key(), err))
+			continue
+		}
+		ownedVolumes, err := gc.Claim(c.store, model.VolumesBucket, model.GroupsBucket, &g.ObjectMeta, groupVolumes[g.Key()])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("group %s: %w", g.Key(), err))
+			continue
+		}
+		if err := c.reconcile(g, owned, ownedVolumes); err != nil && !errors.Is(err, errStale) {
+			errs = append(errs, fmt.Errorf("group %s: %w", g.Key(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// reconcile creates the missing volumes and members of a group, removes
+// members it does not have, and updates its status. Members of a finished
+// group are not recreated.
+func (c *Controller) reconcile(g *model.Group, members []*model.Container, volumes []*model.Volume) error {
+	var errs []error
+
+	byName := make(map[string]*model.Container, len(members))
+	for _, m := range members {
+		byName[m.Name] = m
+	}
+	current := make(map[string]*model.Container, len(members))
+	for _, m := range g.Members() {
+		if r, ok := byName[g.MemberName(m.Name)]; ok {
+			current[m.Name] = r
+			delete(byName, g.MemberName(m.Name))
+		}
+	}
+	for _, r := range byName {
+		// Not a member this group would create; remove it.
+		if err := c.deleteMember(r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if !g.Status.IsTerminal() {
+		haveVolumes := make(map[string]bool, len(volumes))
+		for _, v := range volumes {
+			haveVolumes[v.Name] = true
+		}
+		for _, v := range g.Spec.Volumes {
+			if !haveVolumes[g.VolumeName(v.Name)] {
+				if err := c.createVolume(g, &v); err != nil {
+					return errors.Join(append(errs, err)...)
+				}
+			}
+		}
+
+		for _, m := range g.Members() {
+			if _, ok := current[m.Name]; ok {
+				continue
+			}
+			r, err := c.createMember(g, m)
+			if errors.Is(err, store.ErrAlreadyExists) {
+				// The previous container of the member is still
+				// terminating.
+				continue
+			}
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			current[m.Name] = r
+		}
+	}
+
+	if err := c.writeStatus(g, g.Aggregate(current)); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// createMember stores the container of a member. It carries the group's
+// labels and scheduling constraints, and mounts the group's volumes under
+// their record names.
+func (c *Controller) createMember(g *model.Group, m model.GroupMember) (*model.Container, error) {
+	spec, err := m.Spec.Clone()
+	if err != nil {
+		return nil, err
+	}
+	spec.NodeName = g.Spec.NodeName
+	spec.NodeSelector = g.Spec.NodeSelector
+	spec.Affinity = g.Spec.Affinity
+	spec.Tolerations = g.Spec.Tolerations
+	spec.PriorityClassName = g.Spec.PriorityClassName
+
+	scratch := make(map[string]bool, len(g.Spec.Volumes))
+	for _, v := range g.Spec.Volumes {
+		scratch[v.Name] = true
+	}
+	for i := range spec.VolumeMounts {
+		if name := spec.VolumeMounts[i].Name; scratch[name] {
+			spec.VolumeMounts[i].Name = g.VolumeName(name)
+		}
+	}
+
+	labels := make(map[string]string, len(g.Labels)+2)
+	maps.Copy(labels, g.Labels)
+	labels[model.LabelGroup] = g.Name
+	labels[model.LabelGroupRole] = string(m.Role)
+
+	r := &model.Container{
+		ObjectMeta: model.ObjectMeta{
+			Name:            g.MemberName(m.Name),
+			Namespace:       g.Namespace,
+			Labels:          labels,
+			OwnerReferences: []model.OwnerReference{model.NewControllerRef(model.GroupsBucket, &g.ObjectMeta)},
+		},
+		Spec: *spec,
+	}
+	r.SetDefaults()
+	if err := r.Initialize(); err != nil {
+		return nil, err
+	}
+	if err := store.CreateJSON(c.store, model.ContainersBucket, r.Key(), r); err != nil {
+		return nil, fmt.Errorf("creating member %s: %w", r.Key(), err)
+	}
+	c.logger.Info().Str("group", g.Key()).Str("container", r.Name).Str("role", string(m.Role)).Msg("member created")
+	c.events.Eventf(model.GroupsBucket, &g.ObjectMeta, model.EventNormal, model.EventCreated, "Created container %s", r.Name)
+	return r, nil
+}
+
+// createVolume stores a scratch volume of the group. It is bound to the
+// group's node when the members are scheduled.
+func (c *Controller) createVolume(g *model.Group, gv *model.GroupVolume) error {
+	v := &model.Volume{
+		ObjectMeta: model.ObjectMeta{
+			Name:            g.VolumeName(gv.Name),
+			Namespace:       g.Namespace,
+			Labels:          map[string]string{model.LabelGroup: g.Name},
+			OwnerReferences: []model.OwnerReference{model.NewControllerRef(model.GroupsBucket, &g.ObjectMeta)},
+		},
+		Spec: model.VolumeSpec{CapacityBytes: gv.CapacityBytes},
+	}
+	v.SetDefaults()
+	if err := v.Initialize(); err != nil {
+		return err
+	}
+	err := store.CreateJSON(c.store, model.VolumesBucket, v.Key(), v)
+	if errors.Is(err, store.ErrAlreadyExists) {
+		return fmt.Errorf("volume %s exists and does not belong to the group", v.Key())
+	}
+	if err != nil {
+		return fmt.Errorf("creating volume %s: %w", v.Key(), err)
+	}
+	c.logger.Info().Str("group", g.Key()).Str("volume", v.Name).Msg("volume created")
+	c.events.Eventf(model.GroupsBucket, &g.ObjectMeta, model.EventNormal, model.EventCreated, "Created volume %s", v.Name)
+	return nil
+}
+
+// deleteMember deletes a member; the agent on its node stops the
+// runtime container.
+func (c *Controller) deleteMember(r *model.Container) error {
+	if _, err := gc.Delete(c.store, model.ContainersBucket, &r.ObjectMeta); err != nil {
+		return fmt.Errorf("deleting member %s: %w", r.Key(), err)
+	}
+	c.logger.Info().Str("container", r.Key()).Str("group", r.Labels[model.LabelGroup]).Msg("member deleted")
+	c.events.OwnerEventf(&r.ObjectMeta, model.EventNormal, model.EventDeleted, "Deleted container %s", r.Name)
+	return nil
+}
+
+// writeStatus persists status if it changed.
+func (c *Controller) writeStatus(g *model.Group, status model.GroupStatus) error {
+	if reflect.DeepEqual(g.Status, status) {
+		return nil
+	}
+	_, err := gc.Update(c.store, model.GroupsBucket, &g.ObjectMeta, func(cur *model.Group) error {
+		cur.Status = status
+		return nil
+	})
+	if errors.Is(err, gc.ErrStale) {
+		return errStale
+	}
+	if err == nil && status.Phase != g.Status.Phase {
+		c.logger.Info().Str("group", g.Key()).Str("phase", string(status.Phase)).Msg("group phase changed")
+	}
+	return err
+}
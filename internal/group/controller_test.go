@@ -0,0 +1,161 @@
+package group
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/gc"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+func newTestController(t *testing.T) (*Controller, store.Store) {
+	t.Helper()
+	s := store.NewMemoryStore()
+	return NewController(&Config{Store: s, Logger: zerolog.Nop(), Interval: time.Hour}), s
+}
+
+func putGroup(t *testing.T, s store.Store) *model.Group {
+	t.Helper()
+	g := &model.Group{
+		ObjectMeta: model.ObjectMeta{Name: "shop", Namespace: model.DefaultNamespace, UID: "shop-uid",
+			Labels: map[string]string{"app": "shop"}},
+		Spec: model.GroupSpec{
+			InitContainers: []model.GroupContainer{{Name: "migrate", Spec: model.ContainerSpec{Image: "migrate"}}},
+			Sidecars:       []model.GroupContainer{{Name: "proxy", Spec: model.ContainerSpec{Image: "envoy"}}},
+			Containers: []model.GroupContainer{{Name: "app", Spec: model.ContainerSpec{
+				Image:        "shop",
+				VolumeMounts: []model.VolumeMount{{Name: "scratch", MountPath: "/tmp"}, {Name: "shared", MountPath: "/data"}},
+			}}},
+			Volumes:      []model.GroupVolume{{Name: "scratch", CapacityBytes: 1 << 20}},
+			NodeSelector: map[string]string{"disk": "ssd"},
+		},
+	}
+	g.SetDefaults()
+	require.NoError(t, g.Validate())
+	require.NoError(t, store.PutJSON(s, model.GroupsBucket, g.Key(), g))
+	return g
+}
+
+func getGroup(t *testing.T, s store.Store) *model.Group {
+	t.Helper()
+	g, err := store.GetJSON[model.Group](s, model.GroupsBucket, model.Key(model.DefaultNamespace, "shop"))
+	require.NoError(t, err)
+	return g
+}
+
+func getContainer(t *testing.T, s store.Store, name string) *model.Container {
+	t.Helper()
+	c, err := store.GetJSON[model.Container](s, model.ContainersBucket, model.Key(model.DefaultNamespace, name))
+	require.NoError(t, err)
+	return c
+}
+
+func setPhase(t *testing.T, s store.Store, name string, phase model.ContainerPhase, ready bool) {
+	t.Helper()
+	_, err := store.UpdateJSON(s, model.ContainersBucket, model.Key(model.DefaultNamespace, name), func(c *model.Container) error {
+		c.Spec.NodeName = "node-1"
+		c.Status.Phase = phase
+		c.Status.Ready = ready
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestSync_CreatesMembersAndVolumes(t *testing.T) {
+	ctrl, s := newTestController(t)
+	putGroup(t, s)
+
+	require.NoError(t, ctrl.Sync(context.Background()))
+
+	containers, err := store.ListJSON[model.Container](s, model.ContainersBucket, "")
+	require.NoError(t, err)
+	require.Len(t, containers, 3)
+
+	init := getContainer(t, s, "shop-migrate")
+	assert.Equal(t, map[string]string{"app": "shop", model.LabelGroup: "shop", model.LabelGroupRole: "init"}, init.Labels)
+	assert.Equal(t, model.RestartOnFailure, init.Spec.RestartPolicy)
+	assert.Equal(t, map[string]string{"disk": "ssd"}, init.Spec.NodeSelector)
+	assert.Equal(t, model.PhasePending, init.Status.Phase)
+	assert.Equal(t, "sidecar", getContainer(t, s, "shop-proxy").Labels[model.LabelGroupRole])
+
+	// Scratch volumes are mounted under their record names; other volumes
+	// are left alone.
+	app := getContainer(t, s, "shop-app")
+	assert.Equal(t, []model.VolumeMount{
+		{Name: "shop-scratch", MountPath: "/tmp"},
+		{Name: "shared", MountPath: "/data"},
+	}, app.Spec.VolumeMounts)
+
+	v, err := store.GetJSON[model.Volume](s, model.VolumesBucket, model.Key(model.DefaultNamespace, "shop-scratch"))
+	require.NoError(t, err)
+	assert.Equal(t, "shop", v.Labels[model.LabelGroup])
+	assert.Equal(t, int64(1<<20), v.Spec.CapacityBytes)
+
+	g := getGroup(t, s)
+	assert.Equal(t, model.PhasePending, g.Status.Phase)
+	assert.Equal(t, model.ReasonInitializing, g.Status.Reason)
+	assert.Len(t, g.Status.Members, 3)
+
+	// A deleted member is recreated.
+	require.NoError(t, s.Delete(model.ContainersBucket, app.Key()))
+	require.NoError(t, ctrl.Sync(context.Background()))
+	assert.NotEqual(t, app.UID, getContainer(t, s, "shop-app").UID)
+}
+
+func TestSync_AggregatesStatus(t *testing.T) {
+	ctrl, s := newTestController(t)
+	putGroup(t, s)
+	require.NoError(t, ctrl.Sync(context.Background()))
+
+	setPhase(t, s, "shop-migrate", model.PhaseSucceeded, false)
+	setPhase(t, s, "shop-proxy", model.PhaseRunning, true)
+	setPhase(t, s, "shop-app", model.PhaseRunning, true)
+	require.NoError(t, ctrl.Sync(context.Background()))
+
+	g := getGroup(t, s)
+	assert.Equal(t, model.PhaseRunning, g.Status.Phase)
+	assert.True(t, g.Status.Ready)
+	assert.Equal(t, "node-1", g.Status.NodeName)
+
+	// Members of a finished group are not recreated.
+	setPhase(t, s, "shop-app", model.PhaseSucceeded, false)
+	require.NoError(t, ctrl.Sync(context.Background()))
+	require.Equal(t, model.PhaseSucceeded, getGroup(t, s).Status.Phase)
+
+	require.NoError(t, s.Delete(model.ContainersBucket, model.Key(model.DefaultNamespace, "shop-migrate")))
+	require.NoError(t, ctrl.Sync(context.Background()))
+	_, err := store.GetJSON[model.Container](s, model.ContainersBucket, model.Key(model.DefaultNamespace, "shop-migrate"))
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func TestSync_MembersAreCollectedWithTheirGroup(t *testing.T) {
+	ctrl, s := newTestController(t)
+	g := putGroup(t, s)
+	require.NoError(t, ctrl.Sync(context.Background()))
+	assert.True(t, getContainer(t, s, "shop-app").IsControlledBy(&g.ObjectMeta))
+
+	collector := gc.NewCollector(&gc.Config{Store: s, Logger: zerolog.Nop()})
+	require.NoError(t, s.Delete(model.GroupsBucket, g.Key()))
+	require.NoError(t, ctrl.Sync(context.Background()))
+	require.NoError(t, collector.Sync(context.Background()))
+
+	containers, err := store.ListJSON[model.Container](s, model.ContainersBucket, "")
+	require.NoError(t, err)
+	assert.Empty(t, containers)
+
+	// The volumes go on the next pass, once nothing mounts them.
+	volumes, err := store.ListJSON[model.Volume](s, model.VolumesBucket, "")
+	require.NoError(t, err)
+	assert.Len(t, volumes, 1)
+
+	require.NoError(t, collector.Sync(context.Background()))
+	volumes, err = store.ListJSON[model.Volume](s, model.VolumesBucket, "")
+	require.NoError(t, err)
+	assert.Empty(t, volumes)
+}
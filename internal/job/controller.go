@@ -0,0 +1,303 @@
+// Package job implements the job and cron job controllers. Each pass it
+// starts the runs of cron jobs that are due, then runs containers for every
+// job until enough of them succeed, the job exhausts its retries or it
+// exceeds its deadline.
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/github-builder/container-orchestrator/internal/events"
+	"github.com/github-builder/container-orchestrator/internal/gc"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// errStale is returned when a job or cron job changed while being reconciled.
+var errStale = errors.New("record changed during reconciliation")
+
+// Config holds dependencies for the job controller.
+type Config struct {
+	Store  store.Store
+	Logger zerolog.Logger
+
+	// Interval is how often jobs and cron jobs are reconciled.
+	Interval time.Duration
+
+	// Events records the containers and jobs created and deleted, and
+	// jobs finishing. Defaults to a recorder on Store.
+	Events *events.Recorder
+}
+
+// Controller reconciles jobs with their containers and cron jobs with
+// their jobs.
+type Controller struct {
+	store    store.Store
+	logger   zerolog.Logger
+	interval time.Duration
+	events   *events.Recorder
+
+	// now is replaceable for tests.
+	now func() time.Time
+}
+
+// NewController creates a job controller.
+func NewController(cfg *Config) *Controller {
+	recorder := cfg.Events
+	if recorder == nil {
+		recorder = events.NewRecorder(&events.Config{Store: cfg.Store, Logger: cfg.Logger})
+	}
+
+	return &Controller{
+		store:    cfg.Store,
+		logger:   cfg.Logger,
+		interval: cfg.Interval,
+		events:   recorder.WithSource("jobs"),
+		now:      func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run reconciles jobs and cron jobs every interval until ctx is canceled.
+func (c *Controller) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Sync(ctx); err != nil {
+			c.logger.Error().Err(err).Msg("job reconciliation failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync reconciles every cron job and then every job once. Jobs whose cron
+// job and containers whose job no longer exist are left to the garbage
+// collector, and cron jobs and jobs being deleted are not reconciled.
+func (c *Controller) Sync(ctx context.Context) error {
+	var errs []error
+	if err := c.syncCronJobs(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.syncJobs(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Controller) syncJobs(ctx context.Context) error {
+	jobs, err := store.ListJSON[model.Job](c.store, model.JobsBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing jobs: %w", err)
+	}
+	containers, err := store.ListJSON[model.Container](c.store, model.ContainersBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing containers: %w", err)
+	}
+
+	owned := make(map[string][]*model.Container)
+	for _, ctr := range containers {
+		if owner, ok := ctr.Labels[model.LabelJob]; ok {
+			key := model.Key(ctr.Namespace, owner)
+			owned[key] = append(owned[key], ctr)
+		}
+	}
+
+	var errs []error
+	for _, j := range jobs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if j.IsTerminating() {
+			continue
+		}
+		containers, err := gc.Claim(c.store, model.ContainersBucket, model.JobsBucket, &j.ObjectMeta, owned[j.Key()])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("job %s: %w", j.Key(), err))
+			continue
+		}
+		if err := c.reconcile(j, containers); err != nil && !errors.Is(err, errStale) {
+			errs = append(errs, fmt.Errorf("job %s: %w", j.Key(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// reconcile drives one job toward completion and records its status.
+func (c *Controller) reconcile(j *model.Job, containers []*model.Container) error {
+	now := c.now()
+	status := j.Status
+
+	var active []*model.Container
+	succeeded, failed, restarts := 0, 0, 0
+	for _, ctr := range containers {
+		restarts += ctr.Status.RestartCount
+		switch ctr.Status.Phase {
+		case model.PhaseSucceeded:
+			succeeded++
+		case model.PhaseFailed:
+			failed++
+		default:
+			active = append(active, ctr)
+		}
+	}
+	status.Succeeded = succeeded
+	status.Failed = failed
+
+	var errs []error
+	if !status.IsFinished() {
+		if status.StartedAt == nil {
+			status.StartedAt = &now
+			status.Phase = model.JobRunning
+		}
+
+		deadline := j.Spec.ActiveDeadline()
+		switch {
+		case deadline > 0 && now.Sub(*status.StartedAt) >= deadline:
+			finish(&status, model.JobFailed, model.ReasonDeadlineExceeded,
+				fmt.Sprintf("job was active longer than %s", deadline), now)
+		case failed+restarts > *j.Spec.BackoffLimit:
+			finish(&status, model.JobFailed, model.ReasonBackoffLimitExceeded,
+				fmt.Sprintf("job failed %d times, more than the backoff limit of %d", failed+restarts, *j.Spec.BackoffLimit), now)
+		case succeeded >= j.Spec.Completions:
+			finish(&status, model.JobComplete, model.ReasonCompleted,
+				fmt.Sprintf("%d of %d completions succeeded", succeeded, j.Spec.Completions), now)
+		default:
+			var err error
+			active, err = c.scale(j, active, min(j.Spec.Parallelism, j.Spec.Completions-succeeded))
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if status.IsFinished() {
+			c.logger.Info().Str("job", j.Name).Str("phase", string(status.Phase)).Str("reason", status.Reason).
+				Msg("job finished")
+			if status.Phase == model.JobComplete {
+				c.events.Event(model.JobsBucket, &j.ObjectMeta, model.EventNormal, model.EventCompleted, status.Message)
+			} else {
+				c.events.Event(model.JobsBucket, &j.ObjectMeta, model.EventWarning, status.Reason, status.Message)
+			}
+		}
+	}
+
+	// A finished job stops whatever is still running.
+	if status.IsFinished() {
+		for _, ctr := range active {
+			if err := c.deleteContainer(ctr); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		active = nil
+	}
+	status.Active = len(active)
+
+	if err := c.writeStatus(j, &status); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// finish marks a job as complete or failed.
+func finish(s *model.JobStatus, phase model.JobPhase, reason, message string, now time.Time) {
+	s.Phase = phase
+	s.Reason = reason
+	s.Message = message
+	s.CompletedAt = &now
+}
+
+// scale creates or deletes containers so that n are active, and returns
+// the active containers.
+func (c *Controller) scale(j *model.Job, active []*model.Container, n int) ([]*model.Container, error) {
+	var errs []error
+	for len(active) < n {
+		ctr, err := c.createContainer(j)
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
+		active = append(active, ctr)
+	}
+	// Parallelism was lowered; the newest containers have done the least work.
+	for len(active) > n {
+		newest := 0
+		for i, ctr := range active {
+			if ctr.CreatedAt.After(active[newest].CreatedAt) {
+				newest = i
+			}
+		}
+		if err := c.deleteContainer(active[newest]); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		active = append(active[:newest], active[newest+1:]...)
+	}
+	return active, errors.Join(errs...)
+}
+
+// createContainer stores a new container from the job's template.
+func (c *Controller) createContainer(j *model.Job) (*model.Container, error) {
+	spec, err := j.Spec.Template.Spec.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	labels := make(map[string]string, len(j.Spec.Template.Labels)+1)
+	for k, v := range j.Spec.Template.Labels {
+		labels[k] = v
+	}
+	labels[model.LabelJob] = j.Name
+
+	ctr := &model.Container{
+		ObjectMeta: model.ObjectMeta{
+			Namespace:       j.Namespace,
+			Labels:          labels,
+			OwnerReferences: []model.OwnerReference{model.NewControllerRef(model.JobsBucket, &j.ObjectMeta)},
+		},
+		Spec: *spec,
+	}
+	ctr.SetDefaults()
+
+	if err := gc.Create(c.store, model.ContainersBucket, j.Name, ctr); err != nil {
+		return nil, fmt.Errorf("creating container: %w", err)
+	}
+	c.logger.Info().Str("job", j.Name).Str("container", ctr.Name).Msg("job container created")
+	c.events.Eventf(model.JobsBucket, &j.ObjectMeta, model.EventNormal, model.EventCreated, "Created container %s", ctr.Name)
+	return ctr, nil
+}
+
+// deleteContainer deletes a job container; the agent on its node stops
+// the runtime container.
+func (c *Controller) deleteContainer(ctr *model.Container) error {
+	if _, err := gc.Delete(c.store, model.ContainersBucket, &ctr.ObjectMeta); err != nil {
+		return fmt.Errorf("deleting container %s: %w", ctr.Key(), err)
+	}
+	c.logger.Info().Str("container", ctr.Name).Str("job", ctr.Labels[model.LabelJob]).Msg("job container deleted")
+	c.events.OwnerEventf(&ctr.ObjectMeta, model.EventNormal, model.EventDeleted, "Deleted container %s", ctr.Name)
+	return nil
+}
+
+// writeStatus persists status if it changed, provided the job has not been
+// replaced since it was read.
+func (c *Controller) writeStatus(j *model.Job, status *model.JobStatus) error {
+	if reflect.DeepEqual(&j.Status, status) {
+		return nil
+	}
+	_, err := gc.Update(c.store, model.JobsBucket, &j.ObjectMeta, func(cur *model.Job) error {
+		cur.Status = *status
+		return nil
+	})
+	if errors.Is(err, gc.ErrStale) {
+		return errStale
+	}
+	return err
+}
@@ -0,0 +1,217 @@
+package job
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/gc"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+func newTestController(t *testing.T) (*Controller, store.Store, *time.Time) {
+	t.Helper()
+	s := store.NewMemoryStore()
+	c := NewController(&Config{Store: s, Logger: zerolog.Nop(), Interval: time.Hour})
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	return c, s, &now
+}
+
+func testJob(name string) *model.Job {
+	j := &model.Job{
+		ObjectMeta: model.ObjectMeta{Name: name, UID: name + "-uid"},
+		Spec: model.JobSpec{
+			Template: model.ContainerTemplate{
+				Labels: map[string]string{"app": name},
+				Spec:   model.ContainerSpec{Image: "app:v1"},
+			},
+		},
+	}
+	j.SetDefaults()
+	return j
+}
+
+func putJob(t *testing.T, s store.Store, j *model.Job) {
+	t.Helper()
+	require.NoError(t, j.Validate())
+	require.NoError(t, store.PutJSON(s, model.JobsBucket, j.Name, j))
+}
+
+func getJob(t *testing.T, s store.Store, name string) *model.Job {
+	t.Helper()
+	j, err := store.GetJSON[model.Job](s, model.JobsBucket, name)
+	require.NoError(t, err)
+	return j
+}
+
+func listContainers(t *testing.T, s store.Store, job string) []*model.Container {
+	t.Helper()
+	all, err := store.ListJSON[model.Container](s, model.ContainersBucket, "")
+	require.NoError(t, err)
+	var out []*model.Container
+	for _, c := range all {
+		if c.Labels[model.LabelJob] == job {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// finishContainers simulates the agent running n active containers of job
+// to the given phase.
+func finishContainers(t *testing.T, s store.Store, job string, n int, phase model.ContainerPhase) {
+	t.Helper()
+	for _, c := range listContainers(t, s, job) {
+		if n == 0 {
+			return
+		}
+		if c.Status.IsTerminal() {
+			continue
+		}
+		_, err := store.UpdateJSON(s, model.ContainersBucket, c.Name, func(c *model.Container) error {
+			c.Status.Phase = phase
+			return nil
+		})
+		require.NoError(t, err)
+		n--
+	}
+}
+
+func syncOnce(t *testing.T, c *Controller) {
+	t.Helper()
+	require.NoError(t, c.Sync(context.Background()))
+}
+
+func TestJob_RunsToCompletion(t *testing.T) {
+	c, s, _ := newTestController(t)
+	j := testJob("report")
+	j.Spec.Completions = 5
+	j.Spec.Parallelism = 2
+	putJob(t, s, j)
+
+	syncOnce(t, c)
+	containers := listContainers(t, s, "report")
+	require.Len(t, containers, 2)
+	assert.Equal(t, model.RestartNever, containers[0].Spec.RestartPolicy)
+	assert.Equal(t, "report", containers[0].Labels["app"])
+	got := getJob(t, s, "report")
+	assert.Equal(t, model.JobRunning, got.Status.Phase)
+	assert.Equal(t, 2, got.Status.Active)
+	assert.NotNil(t, got.Status.StartedAt)
+
+	// Completed containers are replaced until five have succeeded, never
+	// running more than needed.
+	for _, want := range []int{4, 5} {
+		finishContainers(t, s, "report", 2, model.PhaseSucceeded)
+		syncOnce(t, c)
+		assert.Len(t, listContainers(t, s, "report"), want)
+	}
+
+	finishContainers(t, s, "report", 1, model.PhaseSucceeded)
+	syncOnce(t, c)
+
+	got = getJob(t, s, "report")
+	assert.Equal(t, model.JobComplete, got.Status.Phase)
+	assert.Equal(t, 5, got.Status.Succeeded)
+	assert.Zero(t, got.Status.Active)
+	assert.NotNil(t, got.Status.CompletedAt)
+
+	// A finished job creates nothing more.
+	syncOnce(t, c)
+	assert.Len(t, listContainers(t, s, "report"), 5)
+}
+
+func TestJob_BackoffLimit(t *testing.T) {
+	c, s, _ := newTestController(t)
+	j := testJob("migrate")
+	limit := 1
+	j.Spec.BackoffLimit = &limit
+	putJob(t, s, j)
+
+	syncOnce(t, c)
+	finishContainers(t, s, "migrate", 1, model.PhaseFailed)
+	syncOnce(t, c)
+
+	// One retry is allowed.
+	assert.Len(t, listContainers(t, s, "migrate"), 2)
+	assert.Equal(t, model.JobRunning, getJob(t, s, "migrate").Status.Phase)
+
+	finishContainers(t, s, "migrate", 1, model.PhaseFailed)
+	syncOnce(t, c)
+
+	got := getJob(t, s, "migrate")
+	assert.Equal(t, model.JobFailed, got.Status.Phase)
+	assert.Equal(t, model.ReasonBackoffLimitExceeded, got.Status.Reason)
+	assert.Equal(t, 2, got.Status.Failed)
+	assert.Len(t, listContainers(t, s, "migrate"), 2)
+}
+
+func TestJob_BackoffLimitCountsRestarts(t *testing.T) {
+	c, s, _ := newTestController(t)
+	j := testJob("migrate")
+	j.Spec.Template.Spec.RestartPolicy = model.RestartOnFailure
+	limit := 2
+	j.Spec.BackoffLimit = &limit
+	putJob(t, s, j)
+
+	syncOnce(t, c)
+	ctr := listContainers(t, s, "migrate")[0]
+	_, err := store.UpdateJSON(s, model.ContainersBucket, ctr.Name, func(c *model.Container) error {
+		c.Status.Phase = model.PhaseRunning
+		c.Status.RestartCount = 3
+		return nil
+	})
+	require.NoError(t, err)
+	syncOnce(t, c)
+
+	got := getJob(t, s, "migrate")
+	assert.Equal(t, model.JobFailed, got.Status.Phase)
+	assert.Equal(t, model.ReasonBackoffLimitExceeded, got.Status.Reason)
+	// The crash-looping container is stopped.
+	assert.Empty(t, listContainers(t, s, "migrate"))
+}
+
+func TestJob_ActiveDeadline(t *testing.T) {
+	c, s, now := newTestController(t)
+	j := testJob("slow")
+	j.Spec.ActiveDeadlineSeconds = 60
+	putJob(t, s, j)
+
+	syncOnce(t, c)
+	require.Len(t, listContainers(t, s, "slow"), 1)
+
+	*now = now.Add(time.Minute)
+	syncOnce(t, c)
+
+	got := getJob(t, s, "slow")
+	assert.Equal(t, model.JobFailed, got.Status.Phase)
+	assert.Equal(t, model.ReasonDeadlineExceeded, got.Status.Reason)
+	assert.Empty(t, listContainers(t, s, "slow"))
+}
+
+func TestJob_ContainersAreCollectedWithTheirJob(t *testing.T) {
+	c, s, _ := newTestController(t)
+	putJob(t, s, testJob("report"))
+	syncOnce(t, c)
+	containers := listContainers(t, s, "report")
+	require.Len(t, containers, 1)
+	assert.Equal(t, "report-uid", containers[0].ControllerRef().UID)
+
+	require.NoError(t, s.Delete(model.JobsBucket, "report"))
+	syncOnce(t, c)
+	collect(t, s)
+
+	assert.Empty(t, listContainers(t, s, "report"))
+}
+
+// collect runs a garbage collection pass.
+func collect(t *testing.T, s store.Store) {
+	t.Helper()
+	require.NoError(t, gc.NewCollector(&gc.Config{Store: s, Logger: zerolog.Nop()}).Sync(context.Background()))
+}
@@ -0,0 +1,314 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/github-builder/container-orchestrator/internal/cron"
+	"github.com/github-builder/container-orchestrator/internal/gc"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+func (c *Controller) syncCronJobs(ctx context.Context) error {
+	cronJobs, err := store.ListJSON[model.CronJob](c.store, model.CronJobsBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing cron jobs: %w", err)
+	}
+	jobs, err := store.ListJSON[model.Job](c.store, model.JobsBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing jobs: %w", err)
+	}
+
+	owned := make(map[string][]*model.Job)
+	for _, j := range jobs {
+		if owner, ok := j.Labels[model.LabelCronJob]; ok {
+			key := model.Key(j.Namespace, owner)
+			owned[key] = append(owned[key], j)
+		}
+	}
+
+	var errs []error
+	for _, cj := range cronJobs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if cj.IsTerminating() {
+			continue
+		}
+		jobs, err := gc.Claim(c.store, model.JobsBucket, model.CronJobsBucket, &cj.ObjectMeta, owned[cj.Key()])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cron job %s: %w", cj.Key(), err))
+			continue
+		}
+		if err := c.reconcileCronJob(cj, jobs); err != nil && !errors.Is(err, errStale) {
+			errs = append(errs, fmt.Errorf("cron job %s: %w", cj.Key(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// reconcileCronJob starts the run of a cron job that is due, if any, prunes
+// its finished jobs and records its status.
+func (c *Controller) reconcileCronJob(cj *model.CronJob, jobs []*model.Job) error {
+	now := c.now()
+	status := cj.Status
+
+	var active, succeeded, failed []*model.Job
+	for _, j := range jobs {
+		switch j.Status.Phase {
+		case model.JobComplete:
+			succeeded = append(succeeded, j)
+			if t := j.Status.CompletedAt; t != nil && (status.LastSuccessfulTime == nil || t.After(*status.LastSuccessfulTime)) {
+				status.LastSuccessfulTime = t
+			}
+		case model.JobFailed:
+			failed = append(failed, j)
+		default:
+			active = append(active, j)
+		}
+	}
+
+	var errs []error
+	if err := c.pruneHistory(succeeded, *cj.Spec.SuccessfulJobsHistoryLimit); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.pruneHistory(failed, *cj.Spec.FailedJobsHistoryLimit); err != nil {
+		errs = append(errs, err)
+	}
+
+	if !cj.Spec.Suspend {
+		var err error
+		active, err = c.runDue(cj, &status, active, now)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	status.Active = nil
+	for _, j := range active {
+		status.Active = append(status.Active, j.Name)
+	}
+
+	if err := c.writeCronJobStatus(cj, &status); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// runDue creates a job for the most recent scheduled time that has passed
+// since the last run, applying the concurrency policy, and returns the
+// active jobs. Earlier runs missed while the orchestrator was down or the
+// cron job was suspended are skipped, as is the most recent one if it is
+// past the starting deadline.
+func (c *Controller) runDue(cj *model.CronJob, s *model.CronJobStatus, active []*model.Job, now time.Time) ([]*model.Job, error) {
+	sched, err := cron.Parse(cj.Spec.Schedule)
+	if err != nil {
+		return active, fmt.Errorf("parsing schedule: %w", err)
+	}
+
+	since := cj.CreatedAt
+	if s.LastScheduleTime != nil {
+		since = *s.LastScheduleTime
+	}
+
+	due, runs := lastScheduled(sched, since, now)
+	if due.IsZero() {
+		return active, nil
+	}
+
+	log := c.logger.With().Str("cronjob", cj.Name).Time("scheduled", due).Logger()
+	if runs > 1 {
+		log.Warn().Int("missed", runs-1).Msg("skipping missed runs")
+	}
+
+	if deadline := cj.Spec.StartingDeadline(); deadline > 0 && now.Sub(due) > deadline {
+		s.LastScheduleTime = &due
+		s.Message = fmt.Sprintf("missed run at %s: starting deadline of %s exceeded", due.Format(time.RFC3339), deadline)
+		log.Warn().Msg("missed run past its starting deadline")
+		return active, nil
+	}
+
+	if len(active) > 0 {
+		switch cj.Spec.ConcurrencyPolicy {
+		case model.ConcurrencyForbid:
+			// The run is retried once the active job finishes, provided it
+			// is still within the starting deadline.
+			s.Message = fmt.Sprintf("run at %s postponed: job %s is still active", due.Format(time.RFC3339), active[0].Name)
+			return active, nil
+		case model.ConcurrencyReplace:
+			for _, j := range active {
+				if err := c.deleteJob(j); err != nil {
+					return active, err
+				}
+			}
+			active = nil
+		}
+	}
+
+	j, err := c.createJob(cj, due)
+	if err != nil {
+		return active, err
+	}
+	s.LastScheduleTime = &due
+	s.Message = ""
+	switch {
+	case runs > maxMissedRuns:
+		s.Message = fmt.Sprintf("skipped at least %d missed runs", runs-1)
+	case runs > 1:
+		s.Message = fmt.Sprintf("skipped %d missed runs", runs-1)
+	}
+	return append(active, j), nil
+}
+
+// maxMissedRuns bounds how many scheduled times runDue walks through, so
+// that a cron job suspended or down for long does not stall a reconcile.
+const maxMissedRuns = 100
+
+// lastScheduled returns the most recent time sched fires in (since, now]
+// and how many times it fires there. It counts at most maxMissedRuns+1
+// times; past that it finds the most recent one by looking back from now.
+func lastScheduled(sched *cron.Schedule, since, now time.Time) (time.Time, int) {
+	var last time.Time
+	runs := 0
+	for t := sched.Next(since); !t.IsZero() && !t.After(now); t = sched.Next(t) {
+		last = t
+		if runs++; runs > maxMissedRuns {
+			return lastSince(sched, last, now), runs
+		}
+	}
+	return last, runs
+}
+
+// lastSince returns the most recent time sched fires in [from, now], given
+// that it fires at from. It looks for it in windows ending now that double
+// in length, so that only the times in one window are walked through.
+func lastSince(sched *cron.Schedule, from, now time.Time) time.Time {
+	for window := time.Minute; ; window *= 2 {
+		start := now.Add(-window)
+		if !start.After(from) {
+			start = from
+		}
+		last := from
+		for t := sched.Next(start); !t.IsZero() && !t.After(now); t = sched.Next(t) {
+			last = t
+		}
+		if last.After(from) || start.Equal(from) {
+			return last
+		}
+	}
+}
+
+// createJob stores the job for the run of cj scheduled at t. Job names are
+// derived from the scheduled time, so a run is created at most once.
+func (c *Controller) createJob(cj *model.CronJob, t time.Time) (*model.Job, error) {
+	spec, err := cloneJobSpec(&cj.Spec.JobTemplate.Spec)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := make(map[string]string, len(cj.Spec.JobTemplate.Labels)+1)
+	for k, v := range cj.Spec.JobTemplate.Labels {
+		labels[k] = v
+	}
+	labels[model.LabelCronJob] = cj.Name
+
+	j := &model.Job{
+		ObjectMeta: model.ObjectMeta{
+			Name:            fmt.Sprintf("%s-%d", cj.Name, t.Unix()/60),
+			Namespace:       cj.Namespace,
+			Labels:          labels,
+			OwnerReferences: []model.OwnerReference{model.NewControllerRef(model.CronJobsBucket, &cj.ObjectMeta)},
+		},
+		Spec: *spec,
+	}
+	j.SetDefaults()
+	if err := j.Initialize(); err != nil {
+		return nil, err
+	}
+
+	err = store.CreateJSON(c.store, model.JobsBucket, j.Key(), j)
+	if errors.Is(err, store.ErrAlreadyExists) {
+		return store.GetJSON[model.Job](c.store, model.JobsBucket, j.Key())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating job: %w", err)
+	}
+	c.logger.Info().Str("cronjob", cj.Name).Str("job", j.Name).Msg("job created")
+	c.events.Eventf(model.CronJobsBucket, &cj.ObjectMeta, model.EventNormal, model.EventCreated, "Created job %s", j.Name)
+	return j, nil
+}
+
+// cloneJobSpec deep-copies a job spec so created jobs share nothing with
+// the template.
+func cloneJobSpec(s *model.JobSpec) (*model.JobSpec, error) {
+	out := *s
+	spec, err := s.Template.Spec.Clone()
+	if err != nil {
+		return nil, err
+	}
+	out.Template.Spec = *spec
+	out.Template.Labels = make(map[string]string, len(s.Template.Labels))
+	for k, v := range s.Template.Labels {
+		out.Template.Labels[k] = v
+	}
+	if s.BackoffLimit != nil {
+		limit := *s.BackoffLimit
+		out.BackoffLimit = &limit
+	}
+	return &out, nil
+}
+
+// pruneHistory deletes the oldest of the finished jobs beyond limit.
+func (c *Controller) pruneHistory(finished []*model.Job, limit int) error {
+	if len(finished) <= limit {
+		return nil
+	}
+	sort.Slice(finished, func(i, k int) bool {
+		if !finished[i].CreatedAt.Equal(finished[k].CreatedAt) {
+			return finished[i].CreatedAt.Before(finished[k].CreatedAt)
+		}
+		return finished[i].Name < finished[k].Name
+	})
+
+	var errs []error
+	for _, j := range finished[:len(finished)-limit] {
+		if err := c.deleteJob(j); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deleteJob deletes a job; the garbage collector deletes its containers.
+func (c *Controller) deleteJob(j *model.Job) error {
+	if _, err := gc.Delete(c.store, model.JobsBucket, &j.ObjectMeta); err != nil {
+		return fmt.Errorf("deleting job %s: %w", j.Key(), err)
+	}
+	c.logger.Info().Str("job", j.Name).Str("cronjob", j.Labels[model.LabelCronJob]).Msg("job deleted")
+	c.events.OwnerEventf(&j.ObjectMeta, model.EventNormal, model.EventDeleted, "Deleted job %s", j.Name)
+	return nil
+}
+
+// writeCronJobStatus persists status if it changed, provided the cron job
+// has not been replaced since it was read.
+func (c *Controller) writeCronJobStatus(cj *model.CronJob, status *model.CronJobStatus) error {
+	if reflect.DeepEqual(&cj.Status, status) {
+		return nil
+	}
+	_, err := store.UpdateJSON(c.store, model.CronJobsBucket, cj.Key(), func(cur *model.CronJob) error {
+		if cur.UID != cj.UID {
+			return errStale
+		}
+		cur.Status = *status
+		return nil
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		return errStale
+	}
+	return err
+}
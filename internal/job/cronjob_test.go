@@ -0,0 +1,278 @@
+package job
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/cron"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// testCronJob returns an hourly cron job created at now.
+func testCronJob(name string, now time.Time) *model.CronJob {
+	cj := &model.CronJob{
+		ObjectMeta: model.ObjectMeta{Name: name, UID: name + "-uid", CreatedAt: now},
+		Spec: model.CronJobSpec{
+			Schedule: "0 * * * *",
+			JobTemplate: model.JobTemplate{
+				Labels: map[string]string{"team": "data"},
+				Spec: model.JobSpec{
+					Template: model.ContainerTemplate{Spec: model.ContainerSpec{Image: "report:v1"}},
+				},
+			},
+		},
+	}
+	cj.SetDefaults()
+	return cj
+}
+
+func putCronJob(t *testing.T, s store.Store, cj *model.CronJob) {
+	t.Helper()
+	require.NoError(t, cj.Validate())
+	require.NoError(t, store.PutJSON(s, model.CronJobsBucket, cj.Name, cj))
+}
+
+func getCronJob(t *testing.T, s store.Store, name string) *model.CronJob {
+	t.Helper()
+	cj, err := store.GetJSON[model.CronJob](s, model.CronJobsBucket, name)
+	require.NoError(t, err)
+	return cj
+}
+
+func listJobs(t *testing.T, s store.Store, cronJob string) []*model.Job {
+	t.Helper()
+	all, err := store.ListJSON[model.Job](s, model.JobsBucket, "")
+	require.NoError(t, err)
+	var out []*model.Job
+	for _, j := range all {
+		if j.Labels[model.LabelCronJob] == cronJob {
+			out = append(out, j)
+		}
+	}
+	return out
+}
+
+// finishJob marks a job as finished, as the job controller would.
+func finishJob(t *testing.T, s store.Store, name string, phase model.JobPhase, at time.Time) {
+	t.Helper()
+	_, err := store.UpdateJSON(s, model.JobsBucket, name, func(j *model.Job) error {
+		j.Status.Phase = phase
+		j.Status.CompletedAt = &at
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestCronJob_CreatesJobsOnSchedule(t *testing.T) {
+	c, s, now := newTestController(t)
+	putCronJob(t, s, testCronJob("report", *now))
+
+	syncOnce(t, c)
+	assert.Empty(t, listJobs(t, s, "report"))
+
+	*now = now.Add(time.Hour)
+	syncOnce(t, c)
+
+	jobs := listJobs(t, s, "report")
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "report-29453820", jobs[0].Name)
+	assert.Equal(t, "data", jobs[0].Labels["team"])
+	assert.Equal(t, "report:v1", jobs[0].Spec.Template.Spec.Image)
+	// The job controller started it in the same pass.
+	assert.Len(t, listContainers(t, s, jobs[0].Name), 1)
+
+	cj := getCronJob(t, s, "report")
+	assert.Equal(t, *now, *cj.Status.LastScheduleTime)
+	assert.Equal(t, []string{"report-29453820"}, cj.Status.Active)
+
+	// Syncing again within the hour creates nothing new.
+	*now = now.Add(30 * time.Minute)
+	syncOnce(t, c)
+	assert.Len(t, listJobs(t, s, "report"), 1)
+
+	finishJob(t, s, "report-29453820", model.JobComplete, *now)
+	syncOnce(t, c)
+	cj = getCronJob(t, s, "report")
+	assert.Empty(t, cj.Status.Active)
+	assert.Equal(t, *now, *cj.Status.LastSuccessfulTime)
+}
+
+func TestCronJob_ConcurrencyPolicies(t *testing.T) {
+	tests := []struct {
+		policy model.ConcurrencyPolicy
+		want   []string
+	}{
+		{model.ConcurrencyAllow, []string{"report-29453820", "report-29453880"}},
+		{model.ConcurrencyForbid, []string{"report-29453820"}},
+		{model.ConcurrencyReplace, []string{"report-29453880"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			c, s, now := newTestController(t)
+			cj := testCronJob("report", *now)
+			cj.Spec.ConcurrencyPolicy = tt.policy
+			putCronJob(t, s, cj)
+
+			*now = now.Add(time.Hour)
+			syncOnce(t, c)
+			*now = now.Add(time.Hour)
+			syncOnce(t, c)
+
+			var names []string
+			for _, j := range listJobs(t, s, "report") {
+				names = append(names, j.Name)
+			}
+			assert.Equal(t, tt.want, names)
+			assert.Equal(t, tt.want, getCronJob(t, s, "report").Status.Active)
+		})
+	}
+}
+
+func TestCronJob_ForbidRunsPostponedJob(t *testing.T) {
+	c, s, now := newTestController(t)
+	cj := testCronJob("report", *now)
+	cj.Spec.ConcurrencyPolicy = model.ConcurrencyForbid
+	putCronJob(t, s, cj)
+
+	*now = now.Add(time.Hour)
+	syncOnce(t, c)
+	*now = now.Add(time.Hour)
+	syncOnce(t, c)
+	assert.Contains(t, getCronJob(t, s, "report").Status.Message, "still active")
+
+	finishJob(t, s, "report-29453820", model.JobComplete, *now)
+	syncOnce(t, c)
+
+	assert.Len(t, listJobs(t, s, "report"), 2)
+	assert.Empty(t, getCronJob(t, s, "report").Status.Message)
+}
+
+func TestCronJob_MissedRuns(t *testing.T) {
+	c, s, now := newTestController(t)
+	putCronJob(t, s, testCronJob("report", *now))
+
+	// The orchestrator was down for five hours; only the latest run starts.
+	*now = now.Add(5*time.Hour + 10*time.Minute)
+	syncOnce(t, c)
+
+	jobs := listJobs(t, s, "report")
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "report-29454060", jobs[0].Name)
+	cj := getCronJob(t, s, "report")
+	assert.Equal(t, "skipped 4 missed runs", cj.Status.Message)
+	assert.Equal(t, time.Date(2026, 1, 1, 5, 0, 0, 0, time.UTC), *cj.Status.LastScheduleTime)
+}
+
+func TestCronJob_ManyMissedRuns(t *testing.T) {
+	c, s, now := newTestController(t)
+	cj := testCronJob("report", *now)
+	cj.Spec.Schedule = "*/2 * * * *"
+	putCronJob(t, s, cj)
+
+	// A month of runs is not walked through; the latest one starts.
+	*now = now.Add(30*24*time.Hour + 3*time.Minute)
+	syncOnce(t, c)
+
+	jobs := listJobs(t, s, "report")
+	require.Len(t, jobs, 1)
+	got := getCronJob(t, s, "report")
+	assert.Equal(t, "skipped at least 100 missed runs", got.Status.Message)
+	assert.Equal(t, time.Date(2026, 1, 31, 0, 2, 0, 0, time.UTC), *got.Status.LastScheduleTime)
+}
+
+func TestLastScheduled(t *testing.T) {
+	sched, err := cron.Parse("0 9 * * 1-5")
+	require.NoError(t, err)
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC) // a Thursday
+
+	last, runs := lastScheduled(sched, since, time.Date(2026, 1, 6, 12, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2026, 1, 6, 9, 0, 0, 0, time.UTC), last)
+	assert.Equal(t, 4, runs)
+
+	// Past the limit the most recent time is still found, over a weekend.
+	last, runs = lastScheduled(sched, since, time.Date(2027, 1, 3, 12, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2027, 1, 1, 9, 0, 0, 0, time.UTC), last)
+	assert.Equal(t, maxMissedRuns+1, runs)
+
+	last, runs = lastScheduled(sched, since, since.Add(time.Hour))
+	assert.True(t, last.IsZero())
+	assert.Equal(t, 0, runs)
+}
+
+func TestCronJob_StartingDeadline(t *testing.T) {
+	c, s, now := newTestController(t)
+	cj := testCronJob("report", *now)
+	cj.Spec.StartingDeadlineSeconds = 300
+	putCronJob(t, s, cj)
+
+	*now = now.Add(time.Hour + 10*time.Minute)
+	syncOnce(t, c)
+
+	assert.Empty(t, listJobs(t, s, "report"))
+	got := getCronJob(t, s, "report")
+	assert.Contains(t, got.Status.Message, "starting deadline")
+	require.NotNil(t, got.Status.LastScheduleTime)
+
+	// The next run starts on time.
+	*now = time.Date(2026, 1, 1, 2, 1, 0, 0, time.UTC)
+	syncOnce(t, c)
+	assert.Len(t, listJobs(t, s, "report"), 1)
+}
+
+func TestCronJob_Suspend(t *testing.T) {
+	c, s, now := newTestController(t)
+	cj := testCronJob("report", *now)
+	cj.Spec.Suspend = true
+	putCronJob(t, s, cj)
+
+	*now = now.Add(time.Hour)
+	syncOnce(t, c)
+
+	assert.Empty(t, listJobs(t, s, "report"))
+}
+
+func TestCronJob_HistoryLimits(t *testing.T) {
+	c, s, now := newTestController(t)
+	cj := testCronJob("report", *now)
+	one := 1
+	cj.Spec.SuccessfulJobsHistoryLimit = &one
+	putCronJob(t, s, cj)
+
+	var created []string
+	for range 3 {
+		*now = now.Add(time.Hour)
+		syncOnce(t, c)
+		jobs := listJobs(t, s, "report")
+		name := jobs[len(jobs)-1].Name
+		created = append(created, name)
+		finishJob(t, s, name, model.JobComplete, *now)
+	}
+	syncOnce(t, c)
+
+	jobs := listJobs(t, s, "report")
+	require.Len(t, jobs, 1)
+	assert.Equal(t, created[2], jobs[0].Name)
+	// Containers of pruned jobs are removed with them.
+	assert.Empty(t, listContainers(t, s, created[0]))
+}
+
+func TestCronJob_JobsAreCollectedWithTheirCronJob(t *testing.T) {
+	c, s, now := newTestController(t)
+	putCronJob(t, s, testCronJob("report", *now))
+	*now = now.Add(time.Hour)
+	syncOnce(t, c)
+	jobs := listJobs(t, s, "report")
+	require.Len(t, jobs, 1)
+	assert.Equal(t, model.CronJobsBucket, jobs[0].ControllerRef().Kind)
+
+	require.NoError(t, s.Delete(model.CronJobsBucket, "report"))
+	syncOnce(t, c)
+	collect(t, s)
+
+	assert.Empty(t, listJobs(t, s, "report"))
+}
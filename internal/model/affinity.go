@@ -0,0 +1,240 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// TaintEffect says what happens to containers that do not tolerate a taint.
+type TaintEffect string
+
+// Taint effects.
+const (
+	// TaintNoSchedule keeps new containers off the node.
+	TaintNoSchedule TaintEffect = "NoSchedule"
+
+	// TaintPreferNoSchedule makes the scheduler avoid the node if it can.
+	TaintPreferNoSchedule TaintEffect = "PreferNoSchedule"
+
+	// TaintNoExecute keeps new containers off the node and evicts running
+	// ones, after their toleration period if they have one.
+	TaintNoExecute TaintEffect = "NoExecute"
+)
+
+// TolerationOperator compares a toleration with a taint.
+type TolerationOperator string
+
+// Toleration operators.
+const (
+	TolerationEqual  TolerationOperator = "Equal"
+	TolerationExists TolerationOperator = "Exists"
+)
+
+// Taint repels containers that do not tolerate it.
+type Taint struct {
+	Key    string      `json:"key"`
+	Value  string      `json:"value,omitempty"`
+	Effect TaintEffect `json:"effect"`
+
+	// AddedAt is when the taint was first applied. It is set by the
+	// orchestrator and used to time NoExecute evictions.
+	AddedAt *time.Time `json:"added_at,omitempty"`
+}
+
+// Toleration allows a container onto nodes with a matching taint.
+type Toleration struct {
+	// Key is the taint key. Empty with operator Exists matches every taint.
+	Key      string             `json:"key,omitempty"`
+	Operator TolerationOperator `json:"operator,omitempty"`
+	Value    string             `json:"value,omitempty"`
+
+	// Effect restricts the toleration to one effect; empty matches all.
+	Effect TaintEffect `json:"effect,omitempty"`
+
+	// TolerationSeconds bounds how long a NoExecute taint is tolerated.
+	// Nil tolerates it forever.
+	TolerationSeconds *int64 `json:"toleration_seconds,omitempty"`
+}
+
+// Affinity holds the placement preferences of a container.
+type Affinity struct {
+	NodeAffinity          *NodeAffinity          `json:"node_affinity,omitempty"`
+	ContainerAntiAffinity *ContainerAntiAffinity `json:"container_anti_affinity,omitempty"`
+}
+
+// NodeAffinity constrains which nodes a container may run on by node labels.
+type NodeAffinity struct {
+	// Required terms must be met for the container to run on a node.
+	// Terms are ORed; the requirements within a term are ANDed.
+	Required []NodeSelectorTerm `json:"required,omitempty"`
+
+	// Preferred terms add their weight to nodes that match them.
+	Preferred []PreferredNodeTerm `json:"preferred,omitempty"`
+}
+
+// NodeSelectorTerm is a set of requirements on node labels, all of which must hold.
+type NodeSelectorTerm struct {
+	MatchExpressions []SelectorRequirement `json:"match_expressions"`
+}
+
+// PreferredNodeTerm is a weighted soft node affinity term.
+type PreferredNodeTerm struct {
+	// Weight is between 1 and 100.
+	Weight int              `json:"weight"`
+	Term   NodeSelectorTerm `json:"term"`
+}
+
+// ContainerAntiAffinity keeps a container away from nodes already running
+// containers that match a label selector, spreading replicas across nodes.
+type ContainerAntiAffinity struct {
+	// Required selectors forbid sharing a node with matching containers.
+	Required []LabelSelector `json:"required,omitempty"`
+
+	// Preferred selectors penalize nodes with matching containers.
+	Preferred []PreferredAntiAffinityTerm `json:"preferred,omitempty"`
+}
+
+// PreferredAntiAffinityTerm is a weighted soft anti-affinity term.
+type PreferredAntiAffinityTerm struct {
+	// Weight is between 1 and 100.
+	Weight   int           `json:"weight"`
+	Selector LabelSelector `json:"selector"`
+}
+
+// Matches reports whether the node labels satisfy every requirement in the term.
+func (t *NodeSelectorTerm) Matches(labels map[string]string) bool {
+	for i := range t.MatchExpressions {
+		if !t.MatchExpressions[i].Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesRequired reports whether node labels satisfy the required terms.
+// No required terms match every node.
+func (a *NodeAffinity) MatchesRequired(labels map[string]string) bool {
+	if len(a.Required) == 0 {
+		return true
+	}
+	for i := range a.Required {
+		if a.Required[i].Matches(labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// Tolerates reports whether the toleration matches the taint.
+func (t *Toleration) Tolerates(taint *Taint) bool {
+	if t.Effect != "" && t.Effect != taint.Effect {
+		return false
+	}
+	if t.Operator == TolerationExists {
+		return t.Key == "" || t.Key == taint.Key
+	}
+	return t.Key == taint.Key && t.Value == taint.Value
+}
+
+// FindToleration returns the first toleration in tolerations that matches
+// the taint, or nil if none does.
+func FindToleration(tolerations []Toleration, taint *Taint) *Toleration {
+	for i := range tolerations {
+		if tolerations[i].Tolerates(taint) {
+			return &tolerations[i]
+		}
+	}
+	return nil
+}
+
+// Validate checks the taint.
+func (t *Taint) Validate(field string) error {
+	if t.Key == "" {
+		return invalid(field+".key", "is required")
+	}
+	switch t.Effect {
+	case TaintNoSchedule, TaintPreferNoSchedule, TaintNoExecute:
+	default:
+		return invalid(field+".effect", "must be one of NoSchedule, PreferNoSchedule, NoExecute")
+	}
+	return nil
+}
+
+// Validate checks the toleration.
+func (t *Toleration) Validate(field string) error {
+	switch t.Operator {
+	case TolerationEqual:
+		if t.Key == "" {
+			return invalid(field+".key", "is required for operator Equal")
+		}
+	case TolerationExists:
+		if t.Value != "" {
+			return invalid(field+".value", "must be empty for operator Exists")
+		}
+	default:
+		return invalid(field+".operator", "must be Equal or Exists")
+	}
+	switch t.Effect {
+	case "", TaintNoSchedule, TaintPreferNoSchedule, TaintNoExecute:
+	default:
+		return invalid(field+".effect", "must be one of NoSchedule, PreferNoSchedule, NoExecute")
+	}
+	if t.TolerationSeconds != nil && t.Effect != TaintNoExecute {
+		return invalid(field+".toleration_seconds", "is only valid with effect NoExecute")
+	}
+	return nil
+}
+
+// Validate checks the affinity.
+func (a *Affinity) Validate(field string) error {
+	if na := a.NodeAffinity; na != nil {
+		for i, term := range na.Required {
+			if err := validateNodeTerm(fmt.Sprintf("%s.node_affinity.required[%d]", field, i), &term); err != nil {
+				return err
+			}
+		}
+		for i, p := range na.Preferred {
+			pf := fmt.Sprintf("%s.node_affinity.preferred[%d]", field, i)
+			if p.Weight < 1 || p.Weight > 100 {
+				return invalid(pf+".weight", "must be between 1 and 100")
+			}
+			if err := validateNodeTerm(pf+".term", &p.Term); err != nil {
+				return err
+			}
+		}
+	}
+
+	if aa := a.ContainerAntiAffinity; aa != nil {
+		for i := range aa.Required {
+			rf := fmt.Sprintf("%s.container_anti_affinity.required[%d]", field, i)
+			if aa.Required[i].IsEmpty() {
+				return invalid(rf, "must not be empty")
+			}
+			if err := aa.Required[i].Validate(rf); err != nil {
+				return err
+			}
+		}
+		for i := range aa.Preferred {
+			pf := fmt.Sprintf("%s.container_anti_affinity.preferred[%d]", field, i)
+			if w := aa.Preferred[i].Weight; w < 1 || w > 100 {
+				return invalid(pf+".weight", "must be between 1 and 100")
+			}
+			if err := aa.Preferred[i].Selector.Validate(pf + ".selector"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateNodeTerm(field string, t *NodeSelectorTerm) error {
+	if len(t.MatchExpressions) == 0 {
+		return invalid(field+".match_expressions", "must not be empty")
+	}
+	for i := range t.MatchExpressions {
+		if err := t.MatchExpressions[i].Validate(fmt.Sprintf("%s.match_expressions[%d]", field, i), true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
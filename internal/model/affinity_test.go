@@ -0,0 +1,40 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectorRequirement_Matches(t *testing.T) {
+	labels := map[string]string{"zone": "a", "cores": "8"}
+
+	tests := []struct {
+		req  SelectorRequirement
+		want bool
+	}{
+		{SelectorRequirement{Key: "zone", Operator: OpIn, Values: []string{"a", "b"}}, true},
+		{SelectorRequirement{Key: "zone", Operator: OpIn, Values: []string{"b"}}, false},
+		{SelectorRequirement{Key: "zone", Operator: OpNotIn, Values: []string{"b"}}, true},
+		{SelectorRequirement{Key: "rack", Operator: OpNotIn, Values: []string{"b"}}, true},
+		{SelectorRequirement{Key: "zone", Operator: OpExists}, true},
+		{SelectorRequirement{Key: "zone", Operator: OpDoesNotExist}, false},
+		{SelectorRequirement{Key: "cores", Operator: OpGt, Values: []string{"4"}}, true},
+		{SelectorRequirement{Key: "cores", Operator: OpLt, Values: []string{"4"}}, false},
+		{SelectorRequirement{Key: "zone", Operator: OpGt, Values: []string{"4"}}, false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.req.Matches(labels), "%s %s %v", tt.req.Key, tt.req.Operator, tt.req.Values)
+	}
+}
+
+func TestToleration_Tolerates(t *testing.T) {
+	taint := &Taint{Key: "gpu", Value: "true", Effect: TaintNoSchedule}
+
+	assert.True(t, (&Toleration{Key: "gpu", Operator: TolerationEqual, Value: "true"}).Tolerates(taint))
+	assert.False(t, (&Toleration{Key: "gpu", Operator: TolerationEqual, Value: "false"}).Tolerates(taint))
+	assert.True(t, (&Toleration{Key: "gpu", Operator: TolerationExists}).Tolerates(taint))
+	assert.True(t, (&Toleration{Operator: TolerationExists}).Tolerates(taint))
+	assert.False(t, (&Toleration{Operator: TolerationExists, Effect: TaintNoExecute}).Tolerates(taint))
+}
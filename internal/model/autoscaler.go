@@ -0,0 +1,303 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// AutoscalersBucket holds Autoscaler records keyed by namespace and name.
+const AutoscalersBucket = "autoscalers"
+
+// MetricType selects what a metric target measures.
+type MetricType string
+
+// Metric types.
+const (
+	// MetricCPU is the CPU the replicas use as a percentage of what they
+	// are allotted in resources.cpu_millis.
+	MetricCPU MetricType = "CPU"
+
+	// MetricMemory is the memory the replicas use as a percentage of what
+	// they are allotted in resources.memory_bytes.
+	MetricMemory MetricType = "Memory"
+
+	// MetricCustom is an application metric the replicas publish, averaged
+	// over the replicas.
+	MetricCustom MetricType = "Custom"
+)
+
+// Autoscaler defaults. By default the replica count may double, or grow by
+// four if that is more, and shrink to any size once per period, and it
+// only shrinks to the highest count recommended in the last five minutes.
+const (
+	DefaultAutoscalerMinReplicas         = 1
+	DefaultScaleUpStabilizationSeconds   = 0
+	DefaultScaleDownStabilizationSeconds = 300
+	DefaultScaleUpMinChange              = 4
+	DefaultScalingPeriodSeconds          = 15
+)
+
+// Default rate limits.
+var (
+	DefaultScaleUpMaxChange   = IntOrPercent{Value: 100, Percent: true}
+	DefaultScaleDownMaxChange = IntOrPercent{Value: 100, Percent: true}
+)
+
+// MaxScalingDecisions is how many of its most recent decisions an
+// autoscaler keeps in its status.
+const MaxScalingDecisions = 20
+
+// Autoscaler sets the replica count of a deployment in its namespace from
+// the resource usage of the deployment's replicas, as sampled by the
+// agents, keeping it between a minimum and a maximum.
+type Autoscaler struct {
+	ObjectMeta `json:"metadata"`
+	Spec       AutoscalerSpec   `json:"spec"`
+	Status     AutoscalerStatus `json:"status"`
+}
+
+// AutoscalerSpec is the desired behavior of an autoscaler.
+type AutoscalerSpec struct {
+	// Deployment names the deployment to scale.
+	Deployment string `json:"deployment"`
+
+	// MinReplicas is the fewest replicas to scale down to. Defaults to 1.
+	MinReplicas int `json:"min_replicas,omitempty"`
+
+	// MaxReplicas is the most replicas to scale up to.
+	MaxReplicas int `json:"max_replicas"`
+
+	// Metrics are the targets to scale on. Each recommends a replica count
+	// and the largest recommendation wins.
+	Metrics []MetricTarget `json:"metrics"`
+
+	// ScaleUp and ScaleDown limit how fast the replica count changes in
+	// each direction.
+	ScaleUp   *ScalingRules `json:"scale_up,omitempty"`
+	ScaleDown *ScalingRules `json:"scale_down,omitempty"`
+}
+
+// MetricTarget is the value an autoscaler keeps a metric at.
+type MetricTarget struct {
+	Type MetricType `json:"type"`
+
+	// Name is the name of a Custom metric.
+	Name string `json:"name,omitempty"`
+
+	// TargetUtilization is the percentage of their allotment the replicas
+	// should use on average, for CPU and Memory.
+	TargetUtilization int `json:"target_utilization,omitempty"`
+
+	// TargetAverageValue is the value a Custom metric should have on
+	// average across the replicas.
+	TargetAverageValue float64 `json:"target_average_value,omitempty"`
+}
+
+// ScalingRules dampen the changes an autoscaler makes in one direction.
+type ScalingRules struct {
+	// StabilizationWindowSeconds is how far back recommendations are
+	// considered: scaling up goes no higher than the lowest of them, and
+	// scaling down no lower than the highest, so that a brief spike or dip
+	// does not move the replica count. Defaults to 0 for scaling up and
+	// 300 for scaling down.
+	StabilizationWindowSeconds *int `json:"stabilization_window_seconds,omitempty"`
+
+	// MaxChange is how many replicas may be added or removed within a
+	// period. Percentages are of the count at the start of the period and
+	// round up. Defaults to 100%.
+	MaxChange *IntOrPercent `json:"max_change,omitempty"`
+
+	// MinChange is how many replicas may always be added or removed within
+	// a period, whatever MaxChange allows. Defaults to 4 for scaling up
+	// and 0 for scaling down.
+	MinChange *int `json:"min_change,omitempty"`
+
+	// PeriodSeconds is the length of the period MaxChange applies to.
+	// Defaults to 15.
+	PeriodSeconds int `json:"period_seconds,omitempty"`
+}
+
+// AutoscalerStatus is the observed state of an autoscaler.
+type AutoscalerStatus struct {
+	// CurrentReplicas and DesiredReplicas are the replica counts of the
+	// deployment before and after the last evaluation.
+	CurrentReplicas int `json:"current_replicas"`
+	DesiredReplicas int `json:"desired_replicas"`
+
+	// CurrentMetrics are the metric values of the last evaluation.
+	CurrentMetrics []MetricValue `json:"current_metrics,omitempty"`
+
+	// LastScaleTime is when the replica count was last changed.
+	LastScaleTime *time.Time `json:"last_scale_time,omitempty"`
+
+	// Message explains why the last evaluation did not scale, if it could
+	// not.
+	Message string `json:"message,omitempty"`
+
+	// Recommendations are the replica counts recommended by recent
+	// evaluations, kept for the stabilization windows.
+	Recommendations []Recommendation `json:"recommendations,omitempty"`
+
+	// Decisions are the most recent changes to the replica count, oldest
+	// first. They also bound the rate of change.
+	Decisions []ScalingDecision `json:"decisions,omitempty"`
+
+	LastEvaluated *time.Time `json:"last_evaluated,omitempty"`
+}
+
+// MetricValue is the value of a metric target at one evaluation.
+type MetricValue struct {
+	Type MetricType `json:"type"`
+	Name string     `json:"name,omitempty"`
+
+	// Current is the utilization percentage for CPU and Memory, or the
+	// average value of a Custom metric.
+	Current float64 `json:"current"`
+
+	// Target is the value the autoscaler aims for.
+	Target float64 `json:"target"`
+
+	// Replicas is the number of replicas the value was measured over.
+	Replicas int `json:"replicas"`
+
+	// Recommended is the replica count that brings the metric to its
+	// target.
+	Recommended int `json:"recommended"`
+}
+
+// Recommendation is the replica count one evaluation arrived at before
+// stabilization and rate limits.
+type Recommendation struct {
+	Replicas int       `json:"replicas"`
+	Time     time.Time `json:"time"`
+}
+
+// ScalingDecision records a change of the replica count and the metric
+// values behind it.
+type ScalingDecision struct {
+	Time time.Time `json:"time"`
+	From int       `json:"from"`
+	To   int       `json:"to"`
+
+	// Reason says what limited the change, or which metric drove it.
+	Reason string `json:"reason"`
+
+	Metrics []MetricValue `json:"metrics,omitempty"`
+}
+
+// SetDefaults fills in unset fields.
+func (a *Autoscaler) SetDefaults() {
+	if a.Spec.MinReplicas == 0 {
+		a.Spec.MinReplicas = DefaultAutoscalerMinReplicas
+	}
+	if a.Spec.ScaleUp == nil {
+		a.Spec.ScaleUp = &ScalingRules{}
+	}
+	a.Spec.ScaleUp.setDefaults(DefaultScaleUpStabilizationSeconds, DefaultScaleUpMaxChange, DefaultScaleUpMinChange)
+	if a.Spec.ScaleDown == nil {
+		a.Spec.ScaleDown = &ScalingRules{}
+	}
+	a.Spec.ScaleDown.setDefaults(DefaultScaleDownStabilizationSeconds, DefaultScaleDownMaxChange, 0)
+}
+
+func (r *ScalingRules) setDefaults(window int, maxChange IntOrPercent, minChange int) {
+	if r.StabilizationWindowSeconds == nil {
+		r.StabilizationWindowSeconds = &window
+	}
+	if r.MaxChange == nil {
+		r.MaxChange = &maxChange
+	}
+	if r.MinChange == nil {
+		r.MinChange = &minChange
+	}
+	if r.PeriodSeconds == 0 {
+		r.PeriodSeconds = DefaultScalingPeriodSeconds
+	}
+}
+
+// Validate checks the autoscaler for errors.
+func (a *Autoscaler) Validate() error {
+	if err := ValidateName("metadata.name", a.Name); err != nil {
+		return err
+	}
+	if err := ValidateName("spec.deployment", a.Spec.Deployment); err != nil {
+		return err
+	}
+	if a.Spec.MinReplicas < 1 {
+		return invalid("spec.min_replicas", "must be at least 1")
+	}
+	if a.Spec.MaxReplicas < a.Spec.MinReplicas {
+		return invalid("spec.max_replicas", "must be at least min_replicas (%d)", a.Spec.MinReplicas)
+	}
+
+	if len(a.Spec.Metrics) == 0 {
+		return invalid("spec.metrics", "at least one metric is required")
+	}
+	for i, m := range a.Spec.Metrics {
+		if err := m.validate(fmt.Sprintf("spec.metrics[%d]", i)); err != nil {
+			return err
+		}
+	}
+
+	if err := a.Spec.ScaleUp.validate("spec.scale_up"); err != nil {
+		return err
+	}
+	return a.Spec.ScaleDown.validate("spec.scale_down")
+}
+
+func (m *MetricTarget) validate(field string) error {
+	switch m.Type {
+	case MetricCPU, MetricMemory:
+		if m.TargetUtilization <= 0 {
+			return invalid(field+".target_utilization", "must be positive")
+		}
+		if m.Name != "" || m.TargetAverageValue != 0 {
+			return invalid(field, "%s metrics take only target_utilization", m.Type)
+		}
+	case MetricCustom:
+		if m.Name == "" {
+			return invalid(field+".name", "is required")
+		}
+		if m.TargetAverageValue <= 0 {
+			return invalid(field+".target_average_value", "must be positive")
+		}
+		if m.TargetUtilization != 0 {
+			return invalid(field, "Custom metrics take only target_average_value")
+		}
+	default:
+		return invalid(field+".type", "must be one of %s, %s, %s", MetricCPU, MetricMemory, MetricCustom)
+	}
+	return nil
+}
+
+func (r *ScalingRules) validate(field string) error {
+	if *r.StabilizationWindowSeconds < 0 {
+		return invalid(field+".stabilization_window_seconds", "must not be negative")
+	}
+	if r.MaxChange.Value < 1 {
+		return invalid(field+".max_change", "must be positive")
+	}
+	if *r.MinChange < 0 {
+		return invalid(field+".min_change", "must not be negative")
+	}
+	if r.PeriodSeconds < 0 {
+		return invalid(field+".period_seconds", "must not be negative")
+	}
+	return nil
+}
+
+// StabilizationWindow returns StabilizationWindowSeconds as a duration.
+func (r *ScalingRules) StabilizationWindow() time.Duration {
+	return time.Duration(*r.StabilizationWindowSeconds) * time.Second
+}
+
+// Period returns PeriodSeconds as a duration.
+func (r *ScalingRules) Period() time.Duration {
+	return time.Duration(r.PeriodSeconds) * time.Second
+}
+
+// Limit returns how many replicas may be added or removed within a period
+// that started with replicas.
+func (r *ScalingRules) Limit(replicas int) int {
+	return max(r.MaxChange.Scaled(replicas, true), *r.MinChange)
+}
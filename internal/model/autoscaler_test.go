@@ -0,0 +1,80 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoscaler_Defaults(t *testing.T) {
+	a := &Autoscaler{Spec: AutoscalerSpec{Deployment: "web", MaxReplicas: 10}}
+	a.SetDefaults()
+
+	assert.Equal(t, 1, a.Spec.MinReplicas)
+	assert.Zero(t, a.Spec.ScaleUp.StabilizationWindow())
+	assert.Equal(t, 300, *a.Spec.ScaleDown.StabilizationWindowSeconds)
+	assert.Equal(t, 15, a.Spec.ScaleUp.PeriodSeconds)
+
+	// Scaling up may double the count or add four, whichever is more.
+	assert.Equal(t, 4, a.Spec.ScaleUp.Limit(1))
+	assert.Equal(t, 10, a.Spec.ScaleUp.Limit(10))
+	// Scaling down may remove everything.
+	assert.Equal(t, 10, a.Spec.ScaleDown.Limit(10))
+}
+
+func TestAutoscaler_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(a *Autoscaler)
+		field  string
+	}{
+		{"valid", func(*Autoscaler) {}, ""},
+		{"custom metric", func(a *Autoscaler) {
+			a.Spec.Metrics = []MetricTarget{{Type: MetricCustom, Name: "queue_depth", TargetAverageValue: 30}}
+		}, ""},
+		{"no deployment", func(a *Autoscaler) { a.Spec.Deployment = "" }, "spec.deployment"},
+		{"max below min", func(a *Autoscaler) { a.Spec.MinReplicas = 5; a.Spec.MaxReplicas = 3 }, "spec.max_replicas"},
+		{"no metrics", func(a *Autoscaler) { a.Spec.Metrics = nil }, "spec.metrics"},
+		{"unknown type", func(a *Autoscaler) { a.Spec.Metrics[0].Type = "Disk" }, "spec.metrics[0].type"},
+		{"no utilization", func(a *Autoscaler) { a.Spec.Metrics[0].TargetUtilization = 0 }, "spec.metrics[0].target_utilization"},
+		{"cpu with name", func(a *Autoscaler) { a.Spec.Metrics[0].Name = "cpu" }, "spec.metrics[0]"},
+		{"custom without name", func(a *Autoscaler) {
+			a.Spec.Metrics = []MetricTarget{{Type: MetricCustom, TargetAverageValue: 30}}
+		}, "spec.metrics[0].name"},
+		{"custom without value", func(a *Autoscaler) {
+			a.Spec.Metrics = []MetricTarget{{Type: MetricCustom, Name: "queue_depth"}}
+		}, "spec.metrics[0].target_average_value"},
+		{"negative window", func(a *Autoscaler) {
+			w := -1
+			a.Spec.ScaleDown = &ScalingRules{StabilizationWindowSeconds: &w}
+		}, "spec.scale_down.stabilization_window_seconds"},
+		{"zero max change", func(a *Autoscaler) {
+			a.Spec.ScaleUp = &ScalingRules{MaxChange: FromInt(0)}
+		}, "spec.scale_up.max_change"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Autoscaler{
+				ObjectMeta: ObjectMeta{Name: "web", Namespace: DefaultNamespace},
+				Spec: AutoscalerSpec{
+					Deployment:  "web",
+					MaxReplicas: 10,
+					Metrics:     []MetricTarget{{Type: MetricCPU, TargetUtilization: 60}},
+				},
+			}
+			tt.mutate(a)
+			a.SetDefaults()
+			err := a.Validate()
+			if tt.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, tt.field, verr.Field)
+		})
+	}
+}
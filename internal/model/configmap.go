@@ -0,0 +1,93 @@
+package model
+
+import (
+	"maps"
+	"sort"
+)
+
+// ConfigMapsBucket holds ConfigMap records keyed by namespace and name.
+const ConfigMapsBucket = "configmaps"
+
+// MaxConfigMapSize bounds the total size of a config map's keys and values,
+// text and binary together.
+const MaxConfigMapSize = 1 << 20
+
+// ConfigMap holds non-sensitive configuration: key/value pairs or whole
+// files. Containers project it as environment variables or files.
+type ConfigMap struct {
+	ObjectMeta `json:"metadata"`
+
+	// Data holds UTF-8 values, usable both as environment variables and as
+	// file contents.
+	Data map[string]string `json:"data,omitempty"`
+
+	// BinaryData holds file contents that are not UTF-8 text. It can only
+	// be projected as files.
+	BinaryData map[string][]byte `json:"binary_data,omitempty"`
+
+	// Version increases whenever the data changes. Containers using the
+	// config map are restarted or have their files rewritten once it moves
+	// past the version they last received.
+	Version int64 `json:"version"`
+}
+
+// SetDefaults fills in unset fields.
+func (m *ConfigMap) SetDefaults() {}
+
+// Validate checks the config map for errors.
+func (m *ConfigMap) Validate() error {
+	if err := ValidateName("metadata.name", m.Name); err != nil {
+		return err
+	}
+	if err := validateData("data", m.Data, MaxConfigMapSize); err != nil {
+		return err
+	}
+
+	size := 0
+	for k, v := range m.Data {
+		size += len(k) + len(v)
+	}
+	for k, v := range m.BinaryData {
+		if err := validateKey("binary_data", k); err != nil {
+			return err
+		}
+		if _, ok := m.Data[k]; ok {
+			return invalid("binary_data", "key %q is also set in data", k)
+		}
+		size += len(k) + len(v)
+	}
+	if size > MaxConfigMapSize {
+		return invalid("binary_data", "data and binary_data must be at most %d bytes in total; got %d",
+			MaxConfigMapSize, size)
+	}
+	return nil
+}
+
+// Keys returns the keys of Data and BinaryData in order.
+func (m *ConfigMap) Keys() []string {
+	keys := make([]string, 0, len(m.Data)+len(m.BinaryData))
+	for k := range m.Data {
+		keys = append(keys, k)
+	}
+	for k := range m.BinaryData {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// File returns the contents of key as a file.
+func (m *ConfigMap) File(key string) ([]byte, bool) {
+	if v, ok := m.Data[key]; ok {
+		return []byte(v), true
+	}
+	v, ok := m.BinaryData[key]
+	return v, ok
+}
+
+// SameData reports whether m and o hold the same data.
+func (m *ConfigMap) SameData(o *ConfigMap) bool {
+	return maps.Equal(m.Data, o.Data) && maps.EqualFunc(m.BinaryData, o.BinaryData, func(a, b []byte) bool {
+		return string(a) == string(b)
+	})
+}
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigMap_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   map[string]string
+		binary map[string][]byte
+		field  string
+	}{
+		{"valid", map[string]string{"app.conf": "x"}, map[string][]byte{"logo.png": {0x89, 0x50}}, ""},
+		{"empty", nil, nil, ""},
+		{"bad data key", map[string]string{"a/b": "x"}, nil, "data"},
+		{"bad binary key", nil, map[string][]byte{"..": {1}}, "binary_data"},
+		{"key in both", map[string]string{"a": "x"}, map[string][]byte{"a": {1}}, "binary_data"},
+		{"data too large", map[string]string{"blob": strings.Repeat("x", MaxConfigMapSize)}, nil, "data"},
+		{
+			"total too large",
+			map[string]string{"a": strings.Repeat("x", MaxConfigMapSize/2)},
+			map[string][]byte{"b": make([]byte, MaxConfigMapSize/2)},
+			"binary_data",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &ConfigMap{ObjectMeta: ObjectMeta{Name: "app"}, Data: tt.data, BinaryData: tt.binary}
+			err := m.Validate()
+			if tt.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, tt.field, verr.Field)
+		})
+	}
+}
+
+func TestConfigMap_Files(t *testing.T) {
+	m := &ConfigMap{
+		Data:       map[string]string{"b.conf": "text"},
+		BinaryData: map[string][]byte{"a.bin": {0, 1}},
+	}
+	assert.Equal(t, []string{"a.bin", "b.conf"}, m.Keys())
+
+	data, ok := m.File("b.conf")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("text"), data)
+	data, ok = m.File("a.bin")
+	assert.True(t, ok)
+	assert.Equal(t, []byte{0, 1}, data)
+	_, ok = m.File("missing")
+	assert.False(t, ok)
+
+	o := &ConfigMap{
+		Data:       map[string]string{"b.conf": "text"},
+		BinaryData: map[string][]byte{"a.bin": {0, 1}},
+	}
+	assert.True(t, m.SameData(o))
+	o.BinaryData["a.bin"] = []byte{0, 2}
+	assert.False(t, m.SameData(o))
+}
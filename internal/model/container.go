@@ -0,0 +1,446 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ContainersBucket is the store bucket holding Container records keyed by
+// namespace and name.
+const ContainersBucket = "containers"
+
+// RestartPolicy controls whether a container is restarted after it exits.
+type RestartPolicy string
+
+// Restart policies.
+const (
+	RestartAlways    RestartPolicy = "Always"
+	RestartOnFailure RestartPolicy = "OnFailure"
+	RestartNever     RestartPolicy = "Never"
+)
+
+// ContainerPhase is the high-level lifecycle phase of a container.
+type ContainerPhase string
+
+// Container phases.
+const (
+	PhasePending   ContainerPhase = "Pending"
+	PhaseRunning   ContainerPhase = "Running"
+	PhaseSucceeded ContainerPhase = "Succeeded"
+	PhaseFailed    ContainerPhase = "Failed"
+)
+
+// HealthStatus reflects the outcome of a container's liveness probe.
+type HealthStatus string
+
+// Health statuses. A container without a liveness probe has no health status.
+const (
+	HealthStarting  HealthStatus = "Starting"
+	HealthHealthy   HealthStatus = "Healthy"
+	HealthUnhealthy HealthStatus = "Unhealthy"
+)
+
+// Container is a single runnable unit managed by the orchestrator.
+type Container struct {
+	ObjectMeta `json:"metadata"`
+	Spec       ContainerSpec   `json:"spec"`
+	Status     ContainerStatus `json:"status"`
+}
+
+// ContainerSpec is the desired state of a container.
+type ContainerSpec struct {
+	Image string `json:"image"`
+
+	// ImagePullPolicy says when the image is pulled. Defaults to Always for
+	// images tagged latest or not tagged, and IfNotPresent otherwise.
+	ImagePullPolicy PullPolicy `json:"image_pull_policy,omitempty"`
+
+	// ImagePullSecrets name secrets in the container's namespace holding
+	// registry credentials. The one whose server is the image's registry
+	// is used to pull it.
+	ImagePullSecrets []string `json:"image_pull_secrets,omitempty"`
+
+	Command       []string          `json:"command,omitempty"`
+	Env           map[string]string `json:"env,omitempty"`
+	WorkingDir    string            `json:"working_dir,omitempty"`
+	Ports         []ContainerPort   `json:"ports,omitempty"`
+	Resources     Resources         `json:"resources,omitempty"`
+	RestartPolicy RestartPolicy     `json:"restart_policy,omitempty"`
+
+	// Rlimits are POSIX resource limits set on the container's processes.
+	Rlimits []Rlimit `json:"rlimits,omitempty"`
+
+	// NodeName is the node the container runs on. It is normally set by
+	// the scheduler; setting it at creation bypasses scheduling.
+	NodeName string `json:"node_name,omitempty"`
+
+	// NodeSelector restricts scheduling to nodes carrying all these labels.
+	NodeSelector map[string]string `json:"node_selector,omitempty"`
+
+	// Affinity holds node affinity and container anti-affinity rules.
+	Affinity *Affinity `json:"affinity,omitempty"`
+
+	// Tolerations allow the container onto nodes with matching taints.
+	Tolerations []Toleration `json:"tolerations,omitempty"`
+
+	// PriorityClassName names the PriorityClass giving the container its
+	// scheduling priority. Empty means the global default class, if any.
+	PriorityClassName string `json:"priority_class_name,omitempty"`
+
+	// EnvFrom sets environment variables from secrets and config maps.
+	// Names must not repeat keys of Env.
+	EnvFrom []EnvVarSource `json:"env_from,omitempty"`
+
+	// VolumeMounts mounts volumes into the container.
+	VolumeMounts []VolumeMount `json:"volume_mounts,omitempty"`
+
+	// FileMounts projects secrets and config maps into the container as
+	// files.
+	FileMounts []FileMount `json:"file_mounts,omitempty"`
+
+	// LivenessProbe restarts the container when it fails.
+	LivenessProbe *Probe `json:"liveness_probe,omitempty"`
+
+	// ReadinessProbe gates whether the container is reported as ready.
+	ReadinessProbe *Probe `json:"readiness_probe,omitempty"`
+}
+
+// ContainerPort is a port exposed by a container.
+type ContainerPort struct {
+	Name          string `json:"name,omitempty"`
+	ContainerPort int    `json:"container_port"`
+	HostPort      int    `json:"host_port,omitempty"`
+	Protocol      string `json:"protocol,omitempty"`
+}
+
+// Resources are the CPU and memory a container is allotted.
+type Resources struct {
+	// CPUMillis is in thousandths of a core.
+	CPUMillis int64 `json:"cpu_millis,omitempty"`
+
+	// MemoryBytes is in bytes.
+	MemoryBytes int64 `json:"memory_bytes,omitempty"`
+}
+
+// Rlimit is a POSIX resource limit such as "nofile" or "nproc".
+type Rlimit struct {
+	Name string `json:"name"`
+	Soft uint64 `json:"soft"`
+	Hard uint64 `json:"hard"`
+}
+
+// RlimitNames are the resource limits a container may set.
+var RlimitNames = map[string]bool{
+	"as": true, "core": true, "cpu": true, "data": true, "fsize": true,
+	"memlock": true, "nofile": true, "nproc": true, "stack": true,
+}
+
+// ContainerStatus is the observed state of a container.
+type ContainerStatus struct {
+	Phase     ContainerPhase `json:"phase"`
+	RuntimeID string         `json:"runtime_id,omitempty"`
+	Ready     bool           `json:"ready"`
+	Health    HealthStatus   `json:"health,omitempty"`
+
+	RestartCount int        `json:"restart_count"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+
+	// ConsecutiveCrashes counts restarts since the container last ran
+	// stably. It drives the crash-loop back-off.
+	ConsecutiveCrashes int `json:"consecutive_crashes,omitempty"`
+
+	// BackoffUntil is when a crash-looping container may next be restarted,
+	// or the pull of its image next be tried.
+	BackoffUntil *time.Time `json:"backoff_until,omitempty"`
+
+	// SecretVersions records the version of each secret whose data the
+	// container last received.
+	SecretVersions map[string]int64 `json:"secret_versions,omitempty"`
+
+	// ConfigMapVersions records the version of each config map whose data
+	// the container last received.
+	ConfigMapVersions map[string]int64 `json:"config_map_versions,omitempty"`
+
+	// LastTermination describes the most recent exit of the container.
+	LastTermination *Termination `json:"last_termination,omitempty"`
+
+	// Reason is a short CamelCase explanation of the current state.
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+
+	Liveness  *ProbeResult `json:"liveness,omitempty"`
+	Readiness *ProbeResult `json:"readiness,omitempty"`
+
+	// Scheduling explains the most recent placement decision.
+	Scheduling *SchedulingStatus `json:"scheduling,omitempty"`
+
+	// Usage is the most recent sample of the resources the container uses,
+	// taken by the agent while it runs.
+	Usage *ResourceUsage `json:"usage,omitempty"`
+
+	// ImagePull reports the pull of the image while it is in progress or
+	// failing. It is cleared once the image has been pulled.
+	ImagePull *ImagePullStatus `json:"image_pull,omitempty"`
+}
+
+// ResourceUsage is a sample of what a running container uses.
+type ResourceUsage struct {
+	// CPUMillis is the CPU used in thousandths of a core, averaged since
+	// the previous sample.
+	CPUMillis int64 `json:"cpu_millis"`
+
+	MemoryBytes int64 `json:"memory_bytes"`
+
+	// Metrics are the application metrics the container publishes.
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+
+	SampledAt time.Time `json:"sampled_at"`
+}
+
+// SchedulingStatus records why a container was or was not placed on a node.
+type SchedulingStatus struct {
+	// NodeName is the chosen node, empty while unschedulable.
+	NodeName string `json:"node_name,omitempty"`
+
+	// Message summarizes the decision, e.g. "0/3 nodes are available: ...".
+	Message string `json:"message"`
+
+	// FilteredNodes maps each rejected node to the reason it was rejected.
+	FilteredNodes map[string]string `json:"filtered_nodes,omitempty"`
+
+	// Scores maps each feasible node to its final score.
+	Scores map[string]int64 `json:"scores,omitempty"`
+
+	// Priority is the container's priority when the decision was made.
+	Priority int `json:"priority,omitempty"`
+
+	// Preempted lists the containers evicted to make room, by key.
+	Preempted []string `json:"preempted,omitempty"`
+
+	AttemptedAt time.Time `json:"attempted_at"`
+}
+
+// Termination records how a container exited.
+type Termination struct {
+	ExitCode   int       `json:"exit_code"`
+	Reason     string    `json:"reason"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// Reasons reported in ContainerStatus and Termination.
+const (
+	ReasonCompleted           = "Completed"
+	ReasonError               = "Error"
+	ReasonStartError          = "StartError"
+	ReasonLivenessProbeFailed = "LivenessProbeFailed"
+	ReasonUnschedulable       = "Unschedulable"
+	ReasonEvicted             = "Evicted"
+	ReasonPreempted           = "Preempted"
+	ReasonCrashLoopBackOff    = "CrashLoopBackOff"
+	ReasonVolumeUnavailable   = "VolumeUnavailable"
+	ReasonConfigUnavailable   = "ConfigUnavailable"
+	ReasonPullingImage        = "PullingImage"
+	ReasonImagePullBackOff    = "ImagePullBackOff"
+	ReasonErrImageNeverPull   = "ErrImageNeverPull"
+)
+
+// IsTerminal reports whether the container has finished for good.
+func (s *ContainerStatus) IsTerminal() bool {
+	return s.Phase == PhaseSucceeded || s.Phase == PhaseFailed
+}
+
+// IsReady reports whether the container is running and passing its
+// readiness probe.
+func (s *ContainerStatus) IsReady() bool {
+	return s.Phase == PhaseRunning && s.Ready
+}
+
+// SetDefaults fills in optional fields.
+func (c *Container) SetDefaults() {
+	c.Spec.SetDefaults()
+	if c.Status.Phase == "" {
+		c.Status.Phase = PhasePending
+	}
+}
+
+// SetDefaults fills in unset spec fields.
+func (s *ContainerSpec) SetDefaults() {
+	if s.RestartPolicy == "" {
+		s.RestartPolicy = RestartAlways
+	}
+	if s.ImagePullPolicy == "" {
+		s.ImagePullPolicy = DefaultPullPolicy(s.Image)
+	}
+	for i := range s.Ports {
+		if s.Ports[i].Protocol == "" {
+			s.Ports[i].Protocol = "TCP"
+		}
+	}
+	for i := range s.Tolerations {
+		if s.Tolerations[i].Operator == "" {
+			s.Tolerations[i].Operator = TolerationEqual
+		}
+	}
+	for i := range s.FileMounts {
+		s.FileMounts[i].SetDefaults()
+	}
+}
+
+// Validate checks the container for errors. Call SetDefaults first.
+func (c *Container) Validate() error {
+	if err := ValidateName("metadata.name", c.Name); err != nil {
+		return err
+	}
+	return c.Spec.Validate("spec")
+}
+
+// Validate checks the spec for errors. field prefixes reported field names.
+func (s *ContainerSpec) Validate(field string) error {
+	if s.Image == "" && len(s.Command) == 0 {
+		return invalid(field+".image", "is required when no command is given")
+	}
+
+	switch s.RestartPolicy {
+	case RestartAlways, RestartOnFailure, RestartNever:
+	default:
+		return invalid(field+".restart_policy", "must be one of Always, OnFailure, Never; got %q", s.RestartPolicy)
+	}
+	if err := s.validateImagePull(field); err != nil {
+		return err
+	}
+
+	if s.NodeName != "" {
+		if err := ValidateName(field+".node_name", s.NodeName); err != nil {
+			return err
+		}
+	}
+	if s.PriorityClassName != "" {
+		if err := ValidateName(field+".priority_class_name", s.PriorityClassName); err != nil {
+			return err
+		}
+	}
+
+	if s.Resources.CPUMillis < 0 {
+		return invalid(field+".resources.cpu_millis", "must not be negative")
+	}
+	if s.Resources.MemoryBytes < 0 {
+		return invalid(field+".resources.memory_bytes", "must not be negative")
+	}
+
+	rlimits := make(map[string]bool, len(s.Rlimits))
+	for i, l := range s.Rlimits {
+		lf := fmt.Sprintf("%s.rlimits[%d]", field, i)
+		if !RlimitNames[l.Name] {
+			return invalid(lf+".name", "unknown rlimit %q", l.Name)
+		}
+		if rlimits[l.Name] {
+			return invalid(lf+".name", "%s is set more than once", l.Name)
+		}
+		rlimits[l.Name] = true
+		if l.Soft > l.Hard {
+			return invalid(lf+".soft", "must not exceed the hard limit")
+		}
+	}
+
+	for i, p := range s.Ports {
+		pf := fmt.Sprintf("%s.ports[%d]", field, i)
+		if p.ContainerPort < 1 || p.ContainerPort > 65535 {
+			return invalid(pf+".container_port", "must be between 1 and 65535")
+		}
+		if p.HostPort < 0 || p.HostPort > 65535 {
+			return invalid(pf+".host_port", "must be between 0 and 65535")
+		}
+		if p.Protocol != "TCP" && p.Protocol != "UDP" {
+			return invalid(pf+".protocol", "must be TCP or UDP")
+		}
+	}
+
+	for i := range s.EnvFrom {
+		e := &s.EnvFrom[i]
+		ef := fmt.Sprintf("%s.env_from[%d]", field, i)
+		if err := e.Validate(ef); err != nil {
+			return err
+		}
+		if _, ok := s.Env[e.Name]; ok {
+			return invalid(ef+".name", "%s is also set in env", e.Name)
+		}
+	}
+
+	mountPaths := make(map[string]bool, len(s.VolumeMounts)+len(s.FileMounts))
+	for i := range s.VolumeMounts {
+		m := &s.VolumeMounts[i]
+		mf := fmt.Sprintf("%s.volume_mounts[%d]", field, i)
+		if err := m.Validate(mf); err != nil {
+			return err
+		}
+		if mountPaths[m.MountPath] {
+			return invalid(mf+".mount_path", "duplicate mount path %s", m.MountPath)
+		}
+		mountPaths[m.MountPath] = true
+	}
+	for i := range s.FileMounts {
+		m := &s.FileMounts[i]
+		mf := fmt.Sprintf("%s.file_mounts[%d]", field, i)
+		if err := m.Validate(mf); err != nil {
+			return err
+		}
+		if mountPaths[m.MountPath] {
+			return invalid(mf+".mount_path", "duplicate mount path %s", m.MountPath)
+		}
+		mountPaths[m.MountPath] = true
+	}
+
+	if s.Affinity != nil {
+		if err := s.Affinity.Validate(field + ".affinity"); err != nil {
+			return err
+		}
+	}
+	for i := range s.Tolerations {
+		if err := s.Tolerations[i].Validate(fmt.Sprintf("%s.tolerations[%d]", field, i)); err != nil {
+			return err
+		}
+	}
+
+	if s.LivenessProbe != nil {
+		if err := s.LivenessProbe.Validate(field + ".liveness_probe"); err != nil {
+			return err
+		}
+		if s.LivenessProbe.SuccessThreshold > 1 {
+			return invalid(field+".liveness_probe.success_threshold", "must be 1")
+		}
+	}
+	if s.ReadinessProbe != nil {
+		if err := s.ReadinessProbe.Validate(field + ".readiness_probe"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Clone deep-copies the spec so the copy shares no maps or slices with it.
+func (s *ContainerSpec) Clone() (*ContainerSpec, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("copying container spec: %w", err)
+	}
+	var out ContainerSpec
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("copying container spec: %w", err)
+	}
+	return &out, nil
+}
+
+// ShouldRestart reports whether a container that exited with code must be
+// restarted under policy.
+func ShouldRestart(policy RestartPolicy, code int) bool {
+	switch policy {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return code != 0
+	default:
+		return false
+	}
+}
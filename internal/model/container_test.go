@@ -0,0 +1,219 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validContainer() *Container {
+	c := &Container{
+		ObjectMeta: ObjectMeta{Name: "web"},
+		Spec: ContainerSpec{
+			Image: "nginx",
+			Ports: []ContainerPort{{ContainerPort: 80}},
+		},
+	}
+	c.SetDefaults()
+	return c
+}
+
+func TestContainer_SetDefaults(t *testing.T) {
+	c := validContainer()
+
+	assert.Equal(t, RestartAlways, c.Spec.RestartPolicy)
+	assert.Equal(t, "TCP", c.Spec.Ports[0].Protocol)
+	assert.Equal(t, PhasePending, c.Status.Phase)
+}
+
+func TestContainer_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(c *Container)
+		field  string
+	}{
+		{"valid", func(*Container) {}, ""},
+		{"missing name", func(c *Container) { c.Name = "" }, "metadata.name"},
+		{"uppercase name", func(c *Container) { c.Name = "Web" }, "metadata.name"},
+		{"missing image", func(c *Container) { c.Spec.Image = "" }, "spec.image"},
+		{"command without image", func(c *Container) { c.Spec.Image = ""; c.Spec.Command = []string{"sleep"} }, ""},
+		{"bad restart policy", func(c *Container) { c.Spec.RestartPolicy = "Sometimes" }, "spec.restart_policy"},
+		{"negative cpu", func(c *Container) { c.Spec.Resources.CPUMillis = -1 }, "spec.resources.cpu_millis"},
+		{"bad port", func(c *Container) { c.Spec.Ports[0].ContainerPort = 0 }, "spec.ports[0].container_port"},
+		{"bad protocol", func(c *Container) { c.Spec.Ports[0].Protocol = "SCTP" }, "spec.ports[0].protocol"},
+		{"rlimit", func(c *Container) { c.Spec.Rlimits = []Rlimit{{Name: "nofile", Soft: 1024, Hard: 4096}} }, ""},
+		{"unknown rlimit", func(c *Container) { c.Spec.Rlimits = []Rlimit{{Name: "files", Soft: 1, Hard: 1}} }, "spec.rlimits[0].name"},
+		{
+			"repeated rlimit",
+			func(c *Container) {
+				c.Spec.Rlimits = []Rlimit{{Name: "nproc", Soft: 1, Hard: 1}, {Name: "nproc", Soft: 2, Hard: 2}}
+			},
+			"spec.rlimits[1].name",
+		},
+		{"soft rlimit over hard", func(c *Container) { c.Spec.Rlimits = []Rlimit{{Name: "nofile", Soft: 2, Hard: 1}} }, "spec.rlimits[0].soft"},
+		{
+			"probe without handler",
+			func(c *Container) { c.Spec.LivenessProbe = &Probe{} },
+			"spec.liveness_probe",
+		},
+		{
+			"probe with two handlers",
+			func(c *Container) {
+				c.Spec.ReadinessProbe = &Probe{
+					TCPSocket: &TCPSocketAction{Port: 80},
+					Exec:      &ExecAction{Command: []string{"true"}},
+				}
+			},
+			"spec.readiness_probe",
+		},
+		{
+			"liveness success threshold",
+			func(c *Container) {
+				c.Spec.LivenessProbe = &Probe{TCPSocket: &TCPSocketAction{Port: 80}, SuccessThreshold: 2}
+			},
+			"spec.liveness_probe.success_threshold",
+		},
+		{
+			"readiness success threshold",
+			func(c *Container) {
+				c.Spec.ReadinessProbe = &Probe{TCPSocket: &TCPSocketAction{Port: 80}, SuccessThreshold: 2}
+			},
+			"",
+		},
+		{
+			"bad http scheme",
+			func(c *Container) {
+				c.Spec.LivenessProbe = &Probe{HTTPGet: &HTTPGetAction{Port: 80, Scheme: "FTP"}}
+			},
+			"spec.liveness_probe.http_get.scheme",
+		},
+		{
+			"empty exec command",
+			func(c *Container) { c.Spec.LivenessProbe = &Probe{Exec: &ExecAction{}} },
+			"spec.liveness_probe.exec.command",
+		},
+		{
+			"negative period",
+			func(c *Container) {
+				c.Spec.LivenessProbe = &Probe{TCPSocket: &TCPSocketAction{Port: 80}, PeriodSeconds: -1}
+			},
+			"spec.liveness_probe.period_seconds",
+		},
+		{
+			"toleration seconds without NoExecute",
+			func(c *Container) {
+				secs := int64(10)
+				c.Spec.Tolerations = []Toleration{{Key: "k", Operator: TolerationExists, TolerationSeconds: &secs}}
+			},
+			"spec.tolerations[0].toleration_seconds",
+		},
+		{
+			"empty node affinity term",
+			func(c *Container) {
+				c.Spec.Affinity = &Affinity{NodeAffinity: &NodeAffinity{Required: []NodeSelectorTerm{{}}}}
+			},
+			"spec.affinity.node_affinity.required[0].match_expressions",
+		},
+		{
+			"relative mount path",
+			func(c *Container) { c.Spec.VolumeMounts = []VolumeMount{{Name: "data", MountPath: "data"}} },
+			"spec.volume_mounts[0].mount_path",
+		},
+		{
+			"duplicate mount path",
+			func(c *Container) {
+				c.Spec.VolumeMounts = []VolumeMount{{Name: "a", MountPath: "/data"}, {Name: "b", MountPath: "/data"}}
+			},
+			"spec.volume_mounts[1].mount_path",
+		},
+		{
+			"env from without source",
+			func(c *Container) { c.Spec.EnvFrom = []EnvVarSource{{Name: "TOKEN"}} },
+			"spec.env_from[0]",
+		},
+		{
+			"env from shadowing env",
+			func(c *Container) {
+				c.Spec.Env = map[string]string{"TOKEN": "x"}
+				c.Spec.EnvFrom = []EnvVarSource{{Name: "TOKEN", SecretKeyRef: &KeySelector{Name: "api", Key: "token"}}}
+			},
+			"spec.env_from[0].name",
+		},
+		{
+			"file mount bad key",
+			func(c *Container) {
+				c.Spec.FileMounts = []FileMount{{MountPath: "/etc/api", Secret: "api", Keys: []string{"../token"}}}
+			},
+			"spec.file_mounts[0].keys[0]",
+		},
+		{
+			"file mount with two sources",
+			func(c *Container) {
+				c.Spec.FileMounts = []FileMount{{MountPath: "/etc/api", Secret: "api", ConfigMap: "api"}}
+			},
+			"spec.file_mounts[0]",
+		},
+		{
+			"file mount update policy",
+			func(c *Container) {
+				c.Spec.FileMounts = []FileMount{{MountPath: "/etc/api", ConfigMap: "api", UpdatePolicy: "Never"}}
+			},
+			"spec.file_mounts[0].update_policy",
+		},
+		{
+			"file mount over volume mount",
+			func(c *Container) {
+				c.Spec.VolumeMounts = []VolumeMount{{Name: "data", MountPath: "/data"}}
+				c.Spec.FileMounts = []FileMount{{MountPath: "/data", Secret: "api"}}
+			},
+			"spec.file_mounts[0].mount_path",
+		},
+		{
+			"numeric operator in anti-affinity",
+			func(c *Container) {
+				c.Spec.Affinity = &Affinity{ContainerAntiAffinity: &ContainerAntiAffinity{
+					Required: []LabelSelector{{MatchExpressions: []SelectorRequirement{
+						{Key: "rank", Operator: OpGt, Values: []string{"1"}},
+					}}},
+				}}
+			},
+			"spec.affinity.container_anti_affinity.required[0].match_expressions[0].operator",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validContainer()
+			tt.mutate(c)
+
+			err := c.Validate()
+			if tt.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, tt.field, verr.Field)
+		})
+	}
+}
+
+func TestShouldRestart(t *testing.T) {
+	assert.True(t, ShouldRestart(RestartAlways, 0))
+	assert.True(t, ShouldRestart(RestartAlways, 1))
+	assert.False(t, ShouldRestart(RestartOnFailure, 0))
+	assert.True(t, ShouldRestart(RestartOnFailure, 1))
+	assert.False(t, ShouldRestart(RestartNever, 1))
+}
+
+func TestNewUID(t *testing.T) {
+	a, err := NewUID()
+	require.NoError(t, err)
+	b, err := NewUID()
+	require.NoError(t, err)
+
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, a)
+	assert.NotEqual(t, a, b)
+}
@@ -0,0 +1,145 @@
+package model
+
+import (
+	"time"
+
+	"github.com/github-builder/container-orchestrator/internal/cron"
+)
+
+// CronJobsBucket holds CronJob records keyed by namespace and name.
+const CronJobsBucket = "cronjobs"
+
+// LabelCronJob names the cron job that created a job.
+const LabelCronJob = "orchestrator.cronjob"
+
+// maxCronJobNameLength leaves room in job names for the scheduled time.
+const maxCronJobNameLength = 48
+
+// Default history limits.
+const (
+	DefaultSuccessfulJobsHistoryLimit = 3
+	DefaultFailedJobsHistoryLimit     = 1
+)
+
+// ConcurrencyPolicy decides what happens when a cron job is due while a
+// job it created earlier is still running.
+type ConcurrencyPolicy string
+
+// Concurrency policies.
+const (
+	// ConcurrencyAllow runs jobs side by side.
+	ConcurrencyAllow ConcurrencyPolicy = "Allow"
+
+	// ConcurrencyForbid skips the new run until the running job finishes.
+	ConcurrencyForbid ConcurrencyPolicy = "Forbid"
+
+	// ConcurrencyReplace deletes the running job and starts the new one.
+	ConcurrencyReplace ConcurrencyPolicy = "Replace"
+)
+
+// CronJob creates jobs on a cron schedule.
+type CronJob struct {
+	ObjectMeta `json:"metadata"`
+	Spec       CronJobSpec   `json:"spec"`
+	Status     CronJobStatus `json:"status"`
+}
+
+// CronJobSpec is the desired state of a cron job.
+type CronJobSpec struct {
+	// Schedule is a five-field cron expression, evaluated in UTC.
+	Schedule string `json:"schedule"`
+
+	// ConcurrencyPolicy defaults to Allow.
+	ConcurrencyPolicy ConcurrencyPolicy `json:"concurrency_policy,omitempty"`
+
+	// Suspend stops new jobs from being created. Running jobs continue.
+	Suspend bool `json:"suspend,omitempty"`
+
+	// StartingDeadlineSeconds is how late a run may start, e.g. after the
+	// orchestrator was down. Runs missed by more are skipped. Zero means
+	// the most recent missed run always starts.
+	StartingDeadlineSeconds int `json:"starting_deadline_seconds,omitempty"`
+
+	// SuccessfulJobsHistoryLimit and FailedJobsHistoryLimit are how many
+	// finished jobs are kept. They default to 3 and 1.
+	SuccessfulJobsHistoryLimit *int `json:"successful_jobs_history_limit,omitempty"`
+	FailedJobsHistoryLimit     *int `json:"failed_jobs_history_limit,omitempty"`
+
+	JobTemplate JobTemplate `json:"job_template"`
+}
+
+// JobTemplate describes the jobs a cron job creates.
+type JobTemplate struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	Spec   JobSpec           `json:"spec"`
+}
+
+// CronJobStatus is the observed state of a cron job.
+type CronJobStatus struct {
+	// Active names the jobs that are still running.
+	Active []string `json:"active,omitempty"`
+
+	// LastScheduleTime is the scheduled time of the most recent run that
+	// was started or skipped.
+	LastScheduleTime *time.Time `json:"last_schedule_time,omitempty"`
+
+	// LastSuccessfulTime is when the most recent successful job completed.
+	LastSuccessfulTime *time.Time `json:"last_successful_time,omitempty"`
+
+	// Message explains the most recent skipped or missed run.
+	Message string `json:"message,omitempty"`
+}
+
+// SetDefaults fills in unset fields.
+func (c *CronJob) SetDefaults() {
+	if c.Spec.ConcurrencyPolicy == "" {
+		c.Spec.ConcurrencyPolicy = ConcurrencyAllow
+	}
+	if c.Spec.SuccessfulJobsHistoryLimit == nil {
+		limit := DefaultSuccessfulJobsHistoryLimit
+		c.Spec.SuccessfulJobsHistoryLimit = &limit
+	}
+	if c.Spec.FailedJobsHistoryLimit == nil {
+		limit := DefaultFailedJobsHistoryLimit
+		c.Spec.FailedJobsHistoryLimit = &limit
+	}
+	c.Spec.JobTemplate.Spec.SetDefaults()
+}
+
+// Validate checks the cron job for errors.
+func (c *CronJob) Validate() error {
+	if err := ValidateName("metadata.name", c.Name); err != nil {
+		return err
+	}
+	if len(c.Name) > maxCronJobNameLength {
+		return invalid("metadata.name", "must be at most %d characters", maxCronJobNameLength)
+	}
+	return c.Spec.Validate("spec")
+}
+
+// Validate checks the spec for errors.
+func (s *CronJobSpec) Validate(field string) error {
+	if _, err := cron.Parse(s.Schedule); err != nil {
+		return invalid(field+".schedule", "%v", err)
+	}
+	switch s.ConcurrencyPolicy {
+	case ConcurrencyAllow, ConcurrencyForbid, ConcurrencyReplace:
+	default:
+		return invalid(field+".concurrency_policy", "must be one of Allow, Forbid, Replace; got %q", s.ConcurrencyPolicy)
+	}
+	if s.StartingDeadlineSeconds < 0 {
+		return invalid(field+".starting_deadline_seconds", "must not be negative")
+	}
+	if s.SuccessfulJobsHistoryLimit == nil || *s.SuccessfulJobsHistoryLimit < 0 {
+		return invalid(field+".successful_jobs_history_limit", "must not be negative")
+	}
+	if s.FailedJobsHistoryLimit == nil || *s.FailedJobsHistoryLimit < 0 {
+		return invalid(field+".failed_jobs_history_limit", "must not be negative")
+	}
+	return s.JobTemplate.Spec.Validate(field + ".job_template.spec")
+}
+
+// StartingDeadline returns the starting deadline as a duration, zero if unset.
+func (s *CronJobSpec) StartingDeadline() time.Duration {
+	return time.Duration(s.StartingDeadlineSeconds) * time.Second
+}
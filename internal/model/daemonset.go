@@ -0,0 +1,113 @@
+package model
+
+// DaemonSetsBucket holds DaemonSet records keyed by namespace and name.
+const DaemonSetsBucket = "daemonsets"
+
+// LabelDaemonSet names the daemon set that created a container.
+const LabelDaemonSet = "orchestrator.daemonset"
+
+// maxDaemonSetNameLength leaves room in container names for a random suffix.
+const maxDaemonSetNameLength = 57
+
+// DaemonSetUpdateStrategyType selects how daemons are replaced when the
+// template changes.
+type DaemonSetUpdateStrategyType string
+
+// Daemon set update strategies.
+const (
+	// DaemonSetRollingUpdate replaces daemons node by node, bounded by
+	// MaxUnavailable.
+	DaemonSetRollingUpdate DaemonSetUpdateStrategyType = "RollingUpdate"
+
+	// DaemonSetOnDelete only uses the new template for daemons created
+	// after the old ones are deleted by hand.
+	DaemonSetOnDelete DaemonSetUpdateStrategyType = "OnDelete"
+)
+
+// DaemonSet runs one copy of a container on every Ready node that the
+// template's node selector, node affinity and tolerations admit.
+type DaemonSet struct {
+	ObjectMeta `json:"metadata"`
+	Spec       DaemonSetSpec   `json:"spec"`
+	Status     DaemonSetStatus `json:"status"`
+}
+
+// DaemonSetSpec is the desired state of a daemon set.
+type DaemonSetSpec struct {
+	// Template describes the daemons. Its node selector, node affinity and
+	// tolerations pick the nodes they run on.
+	Template       ContainerTemplate       `json:"template"`
+	UpdateStrategy DaemonSetUpdateStrategy `json:"update_strategy"`
+}
+
+// DaemonSetUpdateStrategy configures how a template change is rolled out.
+type DaemonSetUpdateStrategy struct {
+	Type DaemonSetUpdateStrategyType `json:"type,omitempty"`
+
+	// MaxUnavailable is how many nodes may be without a ready daemon during
+	// a rolling update. Percentages round down, but at least one node is
+	// updated at a time. Defaults to 1.
+	MaxUnavailable *IntOrPercent `json:"max_unavailable,omitempty"`
+}
+
+// DaemonSetStatus is the observed state of a daemon set.
+type DaemonSetStatus struct {
+	// TemplateHash identifies the current template.
+	TemplateHash string `json:"template_hash,omitempty"`
+
+	// DesiredNumberScheduled is the number of nodes that should run a daemon.
+	DesiredNumberScheduled int `json:"desired_number_scheduled"`
+
+	// CurrentNumberScheduled is the number of those nodes running one.
+	CurrentNumberScheduled int `json:"current_number_scheduled"`
+
+	// UpdatedNumberScheduled counts daemons on the current template.
+	UpdatedNumberScheduled int `json:"updated_number_scheduled"`
+
+	NumberReady       int `json:"number_ready"`
+	NumberUnavailable int `json:"number_unavailable"`
+}
+
+// SetDefaults fills in unset fields.
+func (d *DaemonSet) SetDefaults() {
+	if d.Spec.UpdateStrategy.Type == "" {
+		d.Spec.UpdateStrategy.Type = DaemonSetRollingUpdate
+	}
+	if d.Spec.UpdateStrategy.Type == DaemonSetRollingUpdate && d.Spec.UpdateStrategy.MaxUnavailable == nil {
+		d.Spec.UpdateStrategy.MaxUnavailable = FromInt(1)
+	}
+	d.Spec.Template.Spec.SetDefaults()
+}
+
+// Validate checks the daemon set for errors.
+func (d *DaemonSet) Validate() error {
+	if err := ValidateName("metadata.name", d.Name); err != nil {
+		return err
+	}
+	if len(d.Name) > maxDaemonSetNameLength {
+		return invalid("metadata.name", "must be at most %d characters", maxDaemonSetNameLength)
+	}
+	return d.Spec.Validate("spec")
+}
+
+// Validate checks the spec for errors.
+func (s *DaemonSetSpec) Validate(field string) error {
+	if err := s.Template.Spec.Validate(field + ".template.spec"); err != nil {
+		return err
+	}
+	if s.Template.Spec.RestartPolicy != RestartAlways {
+		return invalid(field+".template.spec.restart_policy", "must be Always for daemon sets")
+	}
+	if s.Template.Spec.NodeName != "" {
+		return invalid(field+".template.spec.node_name", "must not be set for daemon sets")
+	}
+
+	switch s.UpdateStrategy.Type {
+	case DaemonSetRollingUpdate:
+		return validateIntOrPercent(field+".update_strategy.max_unavailable", s.UpdateStrategy.MaxUnavailable)
+	case DaemonSetOnDelete:
+		return nil
+	default:
+		return invalid(field+".update_strategy.type", "must be RollingUpdate or OnDelete; got %q", s.UpdateStrategy.Type)
+	}
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDaemonSet_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(d *DaemonSet)
+		field  string
+	}{
+		{"valid", func(*DaemonSet) {}, ""},
+		{"on delete", func(d *DaemonSet) { d.Spec.UpdateStrategy = DaemonSetUpdateStrategy{Type: DaemonSetOnDelete} }, ""},
+		{
+			"restart policy",
+			func(d *DaemonSet) { d.Spec.Template.Spec.RestartPolicy = RestartOnFailure },
+			"spec.template.spec.restart_policy",
+		},
+		{"node name", func(d *DaemonSet) { d.Spec.Template.Spec.NodeName = "node-1" }, "spec.template.spec.node_name"},
+		{
+			"negative max unavailable",
+			func(d *DaemonSet) { d.Spec.UpdateStrategy.MaxUnavailable = FromInt(-1) },
+			"spec.update_strategy.max_unavailable",
+		},
+		{"unknown strategy", func(d *DaemonSet) { d.Spec.UpdateStrategy.Type = "Recreate" }, "spec.update_strategy.type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &DaemonSet{
+				ObjectMeta: ObjectMeta{Name: "logs"},
+				Spec:       DaemonSetSpec{Template: ContainerTemplate{Spec: ContainerSpec{Image: "shipper"}}},
+			}
+			d.SetDefaults()
+			tt.mutate(d)
+
+			err := d.Validate()
+			if tt.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, tt.field, verr.Field)
+		})
+	}
+}
@@ -0,0 +1,374 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"time"
+)
+
+// DeploymentsBucket holds Deployment records keyed by namespace and name.
+const DeploymentsBucket = "deployments"
+
+// Labels the deployment controller applies to the containers it creates.
+const (
+	// LabelDeployment names the owning deployment.
+	LabelDeployment = "orchestrator.deployment"
+
+	// LabelTemplateHash identifies the template a replica was created from.
+	LabelTemplateHash = "orchestrator.template-hash"
+)
+
+// maxDeploymentNameLength leaves room in replica names for the template
+// hash and a random suffix.
+const maxDeploymentNameLength = 46
+
+// DeploymentStrategyType selects how replicas are replaced when the
+// template changes.
+type DeploymentStrategyType string
+
+// Deployment strategies.
+const (
+	// StrategyRollingUpdate replaces replicas gradually, bounded by
+	// MaxSurge and MaxUnavailable.
+	StrategyRollingUpdate DeploymentStrategyType = "RollingUpdate"
+
+	// StrategyCanary shifts replicas to the new template in steps, pausing
+	// after each step for promotion.
+	StrategyCanary DeploymentStrategyType = "Canary"
+
+	// StrategyBlueGreen brings up a full set of new replicas alongside the
+	// old ones and switches traffic over in one step.
+	StrategyBlueGreen DeploymentStrategyType = "BlueGreen"
+)
+
+// RolloutPhase is the state of a deployment's most recent rollout.
+type RolloutPhase string
+
+// Rollout phases.
+const (
+	RolloutProgressing RolloutPhase = "Progressing"
+	RolloutComplete    RolloutPhase = "Complete"
+	RolloutPaused      RolloutPhase = "Paused"
+	RolloutFailed      RolloutPhase = "Failed"
+	RolloutAborted     RolloutPhase = "Aborted"
+)
+
+// Rollout reasons.
+const (
+	ReasonNewTemplate              = "NewTemplate"
+	ReasonProgressDeadlineExceeded = "ProgressDeadlineExceeded"
+	ReasonAwaitingPromotion        = "AwaitingPromotion"
+	ReasonAborted                  = "Aborted"
+	ReasonTrafficSwitched          = "TrafficSwitched"
+)
+
+// DefaultProgressDeadlineSeconds is the progress deadline of deployments
+// that do not set one.
+const DefaultProgressDeadlineSeconds = 600
+
+// Default rolling update bounds.
+var (
+	DefaultMaxSurge       = IntOrPercent{Value: 25, Percent: true}
+	DefaultMaxUnavailable = IntOrPercent{Value: 25, Percent: true}
+)
+
+// Deployment keeps a number of identical containers running and rolls
+// them out gradually when the template changes.
+type Deployment struct {
+	ObjectMeta `json:"metadata"`
+	Spec       DeploymentSpec   `json:"spec"`
+	Status     DeploymentStatus `json:"status"`
+}
+
+// DeploymentSpec is the desired state of a deployment.
+type DeploymentSpec struct {
+	Replicas int                `json:"replicas"`
+	Template ContainerTemplate  `json:"template"`
+	Strategy DeploymentStrategy `json:"strategy"`
+
+	// Paused stops the rollout where it is until resumed.
+	Paused bool `json:"paused,omitempty"`
+
+	// ProgressDeadlineSeconds is how long a rollout may go without making
+	// progress before it is marked Failed. Defaults to 600.
+	ProgressDeadlineSeconds int `json:"progress_deadline_seconds,omitempty"`
+
+	// RevisionHistoryLimit is how many revisions are retained, including
+	// the current one. Defaults to 10.
+	RevisionHistoryLimit int `json:"revision_history_limit,omitempty"`
+}
+
+// ContainerTemplate describes the containers a controller creates.
+type ContainerTemplate struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	Spec   ContainerSpec     `json:"spec"`
+}
+
+// DeploymentStrategy configures how a rollout replaces replicas. Only the
+// settings for the selected type are used.
+type DeploymentStrategy struct {
+	Type          DeploymentStrategyType `json:"type,omitempty"`
+	RollingUpdate *RollingUpdateStrategy `json:"rolling_update,omitempty"`
+	Canary        *CanaryStrategy        `json:"canary,omitempty"`
+	BlueGreen     *BlueGreenStrategy     `json:"blue_green,omitempty"`
+}
+
+// RollingUpdateStrategy bounds how far a rolling update may deviate from
+// the desired replica count.
+type RollingUpdateStrategy struct {
+	// MaxSurge is how many replicas may exist above the desired count.
+	// Percentages round up. Defaults to 25%.
+	MaxSurge *IntOrPercent `json:"max_surge,omitempty"`
+
+	// MaxUnavailable is how many replicas may be unready below the desired
+	// count. Percentages round down. Defaults to 25%.
+	MaxUnavailable *IntOrPercent `json:"max_unavailable,omitempty"`
+}
+
+// CanaryStrategy moves a deployment to a new template through a series of
+// steps. After the last step every replica runs the new template.
+type CanaryStrategy struct {
+	Steps []CanaryStep `json:"steps"`
+}
+
+// CanaryStep runs a number of replicas on the new template, with the rest
+// on the stable template, then holds.
+type CanaryStep struct {
+	// Replicas is how many of the desired replicas run the new template.
+	// Percentages round up.
+	Replicas IntOrPercent `json:"replicas"`
+
+	// PauseSeconds is how long to hold once the step's replicas are ready
+	// before moving on. Zero holds until the rollout is promoted.
+	PauseSeconds int `json:"pause_seconds,omitempty"`
+}
+
+// BlueGreenStrategy runs the new template as a full preview set and moves
+// traffic to it by switching the deployment's active template.
+type BlueGreenStrategy struct {
+	// AutoPromotionSeconds promotes the preview set this long after all of
+	// its replicas are ready. Nil waits for the rollout to be promoted.
+	AutoPromotionSeconds *int `json:"auto_promotion_seconds,omitempty"`
+
+	// ScaleDownDelaySeconds keeps the previous set running this long
+	// after the switch.
+	ScaleDownDelaySeconds int `json:"scale_down_delay_seconds,omitempty"`
+}
+
+// DeploymentStatus is the observed state of a deployment.
+type DeploymentStatus struct {
+	// Revision counts template changes, starting at 1.
+	Revision int64 `json:"revision"`
+
+	// TemplateHash identifies the current template.
+	TemplateHash string `json:"template_hash,omitempty"`
+
+	// StableTemplateHash identifies the last template that was fully
+	// rolled out. Canary and blue-green rollouts fall back to it on abort.
+	StableTemplateHash string `json:"stable_template_hash,omitempty"`
+
+	// ActiveTemplateHash identifies the template whose replicas receive
+	// traffic. It is set by the blue-green strategy and changes in a single
+	// update when the preview set is promoted.
+	ActiveTemplateHash string `json:"active_template_hash,omitempty"`
+
+	Replicas            int `json:"replicas"`
+	UpdatedReplicas     int `json:"updated_replicas"`
+	ReadyReplicas       int `json:"ready_replicas"`
+	UnavailableReplicas int `json:"unavailable_replicas"`
+
+	Rollout RolloutStatus `json:"rollout"`
+
+	// Canary and BlueGreen hold the progress of a rollout using those
+	// strategies.
+	Canary    *CanaryStatus    `json:"canary,omitempty"`
+	BlueGreen *BlueGreenStatus `json:"blue_green,omitempty"`
+
+	// Promote and Abort are requests made through the API for the current
+	// rollout. Promote is cleared once acted on; both are cleared when a
+	// new rollout starts.
+	Promote bool `json:"promote,omitempty"`
+	Abort   bool `json:"abort,omitempty"`
+}
+
+// CanaryStatus tracks a canary rollout.
+type CanaryStatus struct {
+	// Step is the index of the current step. It equals the number of
+	// steps once all steps have been passed.
+	Step int `json:"step"`
+
+	// StepReadyAt is when the current step's replicas all became ready.
+	StepReadyAt *time.Time `json:"step_ready_at,omitempty"`
+}
+
+// BlueGreenStatus tracks a blue-green rollout.
+type BlueGreenStatus struct {
+	// PreviewReadyAt is when every preview replica became ready.
+	PreviewReadyAt *time.Time `json:"preview_ready_at,omitempty"`
+
+	// SwitchedAt is when traffic moved to the new template.
+	SwitchedAt *time.Time `json:"switched_at,omitempty"`
+}
+
+// RolloutStatus describes the most recent rollout.
+type RolloutStatus struct {
+	Phase   RolloutPhase `json:"phase,omitempty"`
+	Reason  string       `json:"reason,omitempty"`
+	Message string       `json:"message,omitempty"`
+
+	StartedAt      *time.Time `json:"started_at,omitempty"`
+	LastProgressAt *time.Time `json:"last_progress_at,omitempty"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+}
+
+// SetDefaults fills in unset fields.
+func (d *Deployment) SetDefaults() {
+	if d.Spec.Strategy.Type == "" {
+		d.Spec.Strategy.Type = StrategyRollingUpdate
+	}
+	if d.Spec.Strategy.Type == StrategyBlueGreen && d.Spec.Strategy.BlueGreen == nil {
+		d.Spec.Strategy.BlueGreen = &BlueGreenStrategy{}
+	}
+	if d.Spec.Strategy.Type == StrategyRollingUpdate {
+		if d.Spec.Strategy.RollingUpdate == nil {
+			d.Spec.Strategy.RollingUpdate = &RollingUpdateStrategy{}
+		}
+		ru := d.Spec.Strategy.RollingUpdate
+		if ru.MaxSurge == nil {
+			surge := DefaultMaxSurge
+			ru.MaxSurge = &surge
+		}
+		if ru.MaxUnavailable == nil {
+			unavailable := DefaultMaxUnavailable
+			ru.MaxUnavailable = &unavailable
+		}
+	}
+	if d.Spec.ProgressDeadlineSeconds == 0 {
+		d.Spec.ProgressDeadlineSeconds = DefaultProgressDeadlineSeconds
+	}
+	if d.Spec.RevisionHistoryLimit == 0 {
+		d.Spec.RevisionHistoryLimit = DefaultRevisionHistoryLimit
+	}
+	d.Spec.Template.Spec.SetDefaults()
+}
+
+// Validate checks the deployment for errors.
+func (d *Deployment) Validate() error {
+	if err := ValidateName("metadata.name", d.Name); err != nil {
+		return err
+	}
+	if len(d.Name) > maxDeploymentNameLength {
+		return invalid("metadata.name", "must be at most %d characters", maxDeploymentNameLength)
+	}
+	return d.Spec.Validate("spec")
+}
+
+// Validate checks the spec for errors.
+func (s *DeploymentSpec) Validate(field string) error {
+	if s.Replicas < 0 {
+		return invalid(field+".replicas", "must not be negative")
+	}
+	if s.ProgressDeadlineSeconds < 0 {
+		return invalid(field+".progress_deadline_seconds", "must not be negative")
+	}
+	if s.RevisionHistoryLimit < 1 {
+		return invalid(field+".revision_history_limit", "must be at least 1")
+	}
+
+	if err := s.Template.Spec.Validate(field + ".template.spec"); err != nil {
+		return err
+	}
+	if s.Template.Spec.RestartPolicy != RestartAlways {
+		return invalid(field+".template.spec.restart_policy", "must be Always for deployments")
+	}
+
+	switch s.Strategy.Type {
+	case StrategyCanary:
+		if err := s.Strategy.Canary.validate(field + ".strategy.canary"); err != nil {
+			return err
+		}
+	case StrategyBlueGreen:
+		if err := s.Strategy.BlueGreen.validate(field + ".strategy.blue_green"); err != nil {
+			return err
+		}
+	case StrategyRollingUpdate:
+		ru := s.Strategy.RollingUpdate
+		if ru == nil {
+			return invalid(field+".strategy.rolling_update", "is required")
+		}
+		if err := validateIntOrPercent(field+".strategy.rolling_update.max_surge", ru.MaxSurge); err != nil {
+			return err
+		}
+		if err := validateIntOrPercent(field+".strategy.rolling_update.max_unavailable", ru.MaxUnavailable); err != nil {
+			return err
+		}
+		if ru.MaxSurge.Value == 0 && ru.MaxUnavailable.Value == 0 {
+			return invalid(field+".strategy.rolling_update", "max_surge and max_unavailable must not both be zero")
+		}
+	default:
+		return invalid(field+".strategy.type", "unknown strategy %q", s.Strategy.Type)
+	}
+	return nil
+}
+
+func (c *CanaryStrategy) validate(field string) error {
+	if c == nil || len(c.Steps) == 0 {
+		return invalid(field+".steps", "must not be empty")
+	}
+	for i := range c.Steps {
+		step := &c.Steps[i]
+		sf := fmt.Sprintf("%s.steps[%d]", field, i)
+		if err := validateIntOrPercent(sf+".replicas", &step.Replicas); err != nil {
+			return err
+		}
+		if step.PauseSeconds < 0 {
+			return invalid(sf+".pause_seconds", "must not be negative")
+		}
+	}
+	return nil
+}
+
+func (b *BlueGreenStrategy) validate(field string) error {
+	if b == nil {
+		return nil
+	}
+	if b.AutoPromotionSeconds != nil && *b.AutoPromotionSeconds < 0 {
+		return invalid(field+".auto_promotion_seconds", "must not be negative")
+	}
+	if b.ScaleDownDelaySeconds < 0 {
+		return invalid(field+".scale_down_delay_seconds", "must not be negative")
+	}
+	return nil
+}
+
+func validateIntOrPercent(field string, v *IntOrPercent) error {
+	if v == nil {
+		return invalid(field, "is required")
+	}
+	if v.Value < 0 {
+		return invalid(field, "must not be negative")
+	}
+	if v.Percent && v.Value > 100 {
+		return invalid(field, "must not exceed 100%%")
+	}
+	return nil
+}
+
+// Hash returns a short stable digest of the template. Replicas carry it in
+// LabelTemplateHash so the controller can tell old replicas from new.
+func (t *ContainerTemplate) Hash() string {
+	data, err := json.Marshal(t)
+	if err != nil {
+		// A template decoded from JSON always re-encodes.
+		panic(fmt.Sprintf("encoding template: %v", err))
+	}
+	h := fnv.New64a()
+	_, _ = h.Write(data)
+	return fmt.Sprintf("%010x", h.Sum64())[:10]
+}
+
+// ProgressDeadline returns the progress deadline as a duration.
+func (s *DeploymentSpec) ProgressDeadline() time.Duration {
+	return time.Duration(s.ProgressDeadlineSeconds) * time.Second
+}
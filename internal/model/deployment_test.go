@@ -0,0 +1,128 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntOrPercent_JSON(t *testing.T) {
+	var v struct {
+		A *IntOrPercent `json:"a"`
+		B *IntOrPercent `json:"b"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"a": 2, "b": "25%"}`), &v))
+	assert.Equal(t, FromInt(2), v.A)
+	assert.Equal(t, FromPercent(25), v.B)
+
+	out, err := json.Marshal(v)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a": 2, "b": "25%"}`, string(out))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"a": "lots"}`), &v))
+}
+
+func TestIntOrPercent_Scaled(t *testing.T) {
+	assert.Equal(t, 3, FromInt(3).Scaled(10, true))
+	assert.Equal(t, 1, FromPercent(25).Scaled(3, true))
+	assert.Equal(t, 0, FromPercent(25).Scaled(3, false))
+	assert.Equal(t, 5, FromPercent(50).Scaled(10, false))
+}
+
+func TestDeployment_Validate(t *testing.T) {
+	valid := func() *Deployment {
+		d := &Deployment{
+			ObjectMeta: ObjectMeta{Name: "web"},
+			Spec: DeploymentSpec{
+				Replicas: 3,
+				Template: ContainerTemplate{Spec: ContainerSpec{Image: "nginx"}},
+			},
+		}
+		d.SetDefaults()
+		return d
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(d *Deployment)
+		field  string
+	}{
+		{"valid", func(*Deployment) {}, ""},
+		{"long name", func(d *Deployment) { d.Name = "a123456789012345678901234567890123456789012345678" }, "metadata.name"},
+		{"negative replicas", func(d *Deployment) { d.Spec.Replicas = -1 }, "spec.replicas"},
+		{"invalid template", func(d *Deployment) { d.Spec.Template.Spec.Image = "" }, "spec.template.spec.image"},
+		{
+			"restart policy",
+			func(d *Deployment) { d.Spec.Template.Spec.RestartPolicy = RestartNever },
+			"spec.template.spec.restart_policy",
+		},
+		{
+			"zero surge and unavailable",
+			func(d *Deployment) {
+				d.Spec.Strategy.RollingUpdate.MaxSurge = FromInt(0)
+				d.Spec.Strategy.RollingUpdate.MaxUnavailable = FromPercent(0)
+			},
+			"spec.strategy.rolling_update",
+		},
+		{
+			"percent over 100",
+			func(d *Deployment) { d.Spec.Strategy.RollingUpdate.MaxSurge = FromPercent(150) },
+			"spec.strategy.rolling_update.max_surge",
+		},
+		{"zero history", func(d *Deployment) { d.Spec.RevisionHistoryLimit = -1 }, "spec.revision_history_limit"},
+		{
+			"canary without steps",
+			func(d *Deployment) { d.Spec.Strategy = DeploymentStrategy{Type: StrategyCanary} },
+			"spec.strategy.canary.steps",
+		},
+		{
+			"canary negative pause",
+			func(d *Deployment) {
+				d.Spec.Strategy = DeploymentStrategy{Type: StrategyCanary, Canary: &CanaryStrategy{
+					Steps: []CanaryStep{{Replicas: *FromInt(1), PauseSeconds: -1}},
+				}}
+			},
+			"spec.strategy.canary.steps[0].pause_seconds",
+		},
+		{
+			"blue-green negative delay",
+			func(d *Deployment) {
+				d.Spec.Strategy = DeploymentStrategy{
+					Type:      StrategyBlueGreen,
+					BlueGreen: &BlueGreenStrategy{ScaleDownDelaySeconds: -1},
+				}
+			},
+			"spec.strategy.blue_green.scale_down_delay_seconds",
+		},
+		{"unknown strategy", func(d *Deployment) { d.Spec.Strategy.Type = "BigBang" }, "spec.strategy.type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := valid()
+			tt.mutate(d)
+
+			err := d.Validate()
+			if tt.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, tt.field, verr.Field)
+		})
+	}
+}
+
+func TestContainerTemplate_Hash(t *testing.T) {
+	a := &ContainerTemplate{Spec: ContainerSpec{Image: "app:v1"}}
+	b := &ContainerTemplate{Spec: ContainerSpec{Image: "app:v1"}}
+	assert.Equal(t, a.Hash(), b.Hash())
+	assert.Len(t, a.Hash(), 10)
+
+	b.Spec.Image = "app:v2"
+	assert.NotEqual(t, a.Hash(), b.Hash())
+}
@@ -0,0 +1,118 @@
+package model
+
+// DisruptionBudgetsBucket holds DisruptionBudget records keyed by namespace
+// and name.
+const DisruptionBudgetsBucket = "disruptionbudgets"
+
+// DisruptionBudget limits how many of the containers it selects voluntary
+// evictions may take down at once. Drains, rolling updates and rebalancing
+// evict through the eviction API, which refuses evictions that would leave
+// fewer healthy containers than the budget requires. Crashes and failed
+// nodes are not voluntary and are not limited.
+type DisruptionBudget struct {
+	ObjectMeta `json:"metadata"`
+	Spec       DisruptionBudgetSpec   `json:"spec"`
+	Status     DisruptionBudgetStatus `json:"status"`
+}
+
+// DisruptionBudgetSpec is the desired state of a disruption budget. Exactly
+// one of MinAvailable and MaxUnavailable is set. Percentages are of the
+// expected count: the selected containers that have not finished, or the
+// desired replicas of the workloads they belong to if that is more.
+type DisruptionBudgetSpec struct {
+	// Selector chooses the containers in the budget's namespace that the
+	// budget protects.
+	Selector LabelSelector `json:"selector"`
+
+	// MinAvailable is how many selected containers must stay healthy.
+	// Percentages round up.
+	MinAvailable *IntOrPercent `json:"min_available,omitempty"`
+
+	// MaxUnavailable is how many selected containers may be unhealthy.
+	// Percentages round down.
+	MaxUnavailable *IntOrPercent `json:"max_unavailable,omitempty"`
+}
+
+// DisruptionBudgetStatus is the observed state of a disruption budget. It
+// is computed when the budget is read.
+type DisruptionBudgetStatus struct {
+	// ExpectedCount is the number of selected containers, or of the
+	// desired replicas of their workloads if that is more.
+	ExpectedCount int `json:"expected_count"`
+
+	// CurrentHealthy is the number of those running and ready.
+	CurrentHealthy int `json:"current_healthy"`
+
+	// DesiredHealthy is the number that must stay healthy.
+	DesiredHealthy int `json:"desired_healthy"`
+
+	// DisruptionsAllowed is how many more may be evicted now.
+	DisruptionsAllowed int `json:"disruptions_allowed"`
+}
+
+// SetDefaults fills in unset fields.
+func (b *DisruptionBudget) SetDefaults() {}
+
+// Validate checks the disruption budget for errors.
+func (b *DisruptionBudget) Validate() error {
+	if err := ValidateName("metadata.name", b.Name); err != nil {
+		return err
+	}
+	if b.Spec.Selector.IsEmpty() {
+		return invalid("spec.selector", "is required")
+	}
+	if err := b.Spec.Selector.Validate("spec.selector"); err != nil {
+		return err
+	}
+
+	switch {
+	case b.Spec.MinAvailable == nil && b.Spec.MaxUnavailable == nil:
+		return invalid("spec", "one of min_available and max_unavailable is required")
+	case b.Spec.MinAvailable != nil && b.Spec.MaxUnavailable != nil:
+		return invalid("spec", "min_available and max_unavailable are mutually exclusive")
+	case b.Spec.MinAvailable != nil:
+		return validateIntOrPercent("spec.min_available", b.Spec.MinAvailable)
+	default:
+		return validateIntOrPercent("spec.max_unavailable", b.Spec.MaxUnavailable)
+	}
+}
+
+// Healthy reports whether a container counts as available to a budget:
+// running, passing its readiness probe and not being deleted. A deleted
+// container may stay ready until its finalizers are cleared, but it is
+// already on its way out.
+func Healthy(c *Container) bool {
+	return c.Status.IsReady() && !c.IsTerminating()
+}
+
+// Selects reports whether c counts toward the budget: a container in the
+// budget's namespace that matches its selector and has not finished.
+func (b *DisruptionBudget) Selects(c *Container) bool {
+	return c.Namespace == b.Namespace && !c.Status.IsTerminal() && b.Spec.Selector.Matches(c.Labels)
+}
+
+// Compute returns the budget's status over containers. At least desired
+// containers are expected, typically the replica count of the workloads
+// they belong to, so that a replica deleted by a rollout still counts
+// until it is replaced.
+func (b *DisruptionBudget) Compute(containers []*Container, desired int) DisruptionBudgetStatus {
+	var s DisruptionBudgetStatus
+	for _, c := range containers {
+		if !b.Selects(c) {
+			continue
+		}
+		s.ExpectedCount++
+		if Healthy(c) {
+			s.CurrentHealthy++
+		}
+	}
+	s.ExpectedCount = max(s.ExpectedCount, desired)
+
+	if b.Spec.MinAvailable != nil {
+		s.DesiredHealthy = b.Spec.MinAvailable.Scaled(s.ExpectedCount, true)
+	} else {
+		s.DesiredHealthy = max(s.ExpectedCount-b.Spec.MaxUnavailable.Scaled(s.ExpectedCount, false), 0)
+	}
+	s.DisruptionsAllowed = max(s.CurrentHealthy-s.DesiredHealthy, 0)
+	return s
+}
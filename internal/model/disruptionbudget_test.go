@@ -0,0 +1,131 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisruptionBudget_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(b *DisruptionBudget)
+		field  string
+	}{
+		{"valid", func(*DisruptionBudget) {}, ""},
+		{"max unavailable", func(b *DisruptionBudget) {
+			b.Spec.MinAvailable = nil
+			b.Spec.MaxUnavailable = FromPercent(25)
+		}, ""},
+		{"no selector", func(b *DisruptionBudget) { b.Spec.Selector = LabelSelector{} }, "spec.selector"},
+		{"bad expression", func(b *DisruptionBudget) {
+			b.Spec.Selector.MatchExpressions = []SelectorRequirement{{Key: "tier", Operator: OpIn}}
+		}, "spec.selector.match_expressions[0].values"},
+		{"neither bound", func(b *DisruptionBudget) { b.Spec.MinAvailable = nil }, "spec"},
+		{"both bounds", func(b *DisruptionBudget) { b.Spec.MaxUnavailable = FromInt(1) }, "spec"},
+		{"negative", func(b *DisruptionBudget) { b.Spec.MinAvailable = FromInt(-1) }, "spec.min_available"},
+		{"over 100%", func(b *DisruptionBudget) {
+			b.Spec.MinAvailable = nil
+			b.Spec.MaxUnavailable = FromPercent(101)
+		}, "spec.max_unavailable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &DisruptionBudget{
+				ObjectMeta: ObjectMeta{Name: "web", Namespace: DefaultNamespace},
+				Spec: DisruptionBudgetSpec{
+					Selector:     LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+					MinAvailable: FromInt(2),
+				},
+			}
+			tt.mutate(b)
+			err := b.Validate()
+			if tt.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, tt.field, verr.Field)
+		})
+	}
+}
+
+func TestDisruptionBudget_Compute(t *testing.T) {
+	container := func(name, ns, app string, phase ContainerPhase, ready bool) *Container {
+		return &Container{
+			ObjectMeta: ObjectMeta{Name: name, Namespace: ns, Labels: map[string]string{"app": app}},
+			Status:     ContainerStatus{Phase: phase, Ready: ready},
+		}
+	}
+	containers := []*Container{
+		container("web-1", DefaultNamespace, "web", PhaseRunning, true),
+		container("web-2", DefaultNamespace, "web", PhaseRunning, true),
+		container("web-3", DefaultNamespace, "web", PhaseRunning, false),
+		container("web-4", DefaultNamespace, "web", PhasePending, false),
+		container("web-5", DefaultNamespace, "web", PhaseSucceeded, false),
+		container("db-1", DefaultNamespace, "db", PhaseRunning, true),
+		container("web-1", "team-a", "web", PhaseRunning, true),
+	}
+
+	b := &DisruptionBudget{
+		ObjectMeta: ObjectMeta{Name: "web", Namespace: DefaultNamespace},
+		Spec: DisruptionBudgetSpec{
+			Selector:     LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			MinAvailable: FromPercent(25),
+		},
+	}
+	assert.Equal(t, DisruptionBudgetStatus{
+		ExpectedCount: 4, CurrentHealthy: 2, DesiredHealthy: 1, DisruptionsAllowed: 1,
+	}, b.Compute(containers, 0))
+
+	// Replicas the workload wants but has not created yet are expected.
+	assert.Equal(t, DisruptionBudgetStatus{
+		ExpectedCount: 8, CurrentHealthy: 2, DesiredHealthy: 2, DisruptionsAllowed: 0,
+	}, b.Compute(containers, 8))
+
+	b.Spec.MinAvailable = nil
+	b.Spec.MaxUnavailable = FromInt(1)
+	assert.Equal(t, DisruptionBudgetStatus{
+		ExpectedCount: 4, CurrentHealthy: 2, DesiredHealthy: 3, DisruptionsAllowed: 0,
+	}, b.Compute(containers, 0))
+
+	b.Spec.MaxUnavailable = FromInt(10)
+	assert.Equal(t, 0, b.Compute(containers, 0).DesiredHealthy)
+	assert.Equal(t, 2, b.Compute(containers, 0).DisruptionsAllowed)
+}
+
+func TestDisruptionBudget_ComputeTerminating(t *testing.T) {
+	deleted := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	containers := []*Container{
+		{
+			ObjectMeta: ObjectMeta{Name: "web-1", Namespace: DefaultNamespace, Labels: map[string]string{"app": "web"}},
+			Status:     ContainerStatus{Phase: PhaseRunning, Ready: true},
+		},
+		{
+			ObjectMeta: ObjectMeta{
+				Name: "web-2", Namespace: DefaultNamespace, Labels: map[string]string{"app": "web"},
+				DeletionTimestamp: &deleted, Finalizers: []string{FinalizerRuntimeCleanup},
+			},
+			Status: ContainerStatus{Phase: PhaseRunning, Ready: true},
+		},
+	}
+	b := &DisruptionBudget{
+		ObjectMeta: ObjectMeta{Name: "web", Namespace: DefaultNamespace},
+		Spec: DisruptionBudgetSpec{
+			Selector:     LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			MinAvailable: FromInt(1),
+		},
+	}
+
+	// The deleted replica is still expected but no longer available, so
+	// the other one may not be disrupted.
+	assert.False(t, Healthy(containers[1]))
+	assert.Equal(t, DisruptionBudgetStatus{
+		ExpectedCount: 2, CurrentHealthy: 1, DesiredHealthy: 1, DisruptionsAllowed: 0,
+	}, b.Compute(containers, 0))
+}
@@ -0,0 +1,70 @@
+package model
+
+import "time"
+
+// EventsBucket holds Event records keyed by the namespace of the object
+// they are about and the event name. Events about cluster-wide objects,
+// such as nodes, are keyed by name alone.
+const EventsBucket = "events"
+
+// EventType says whether an event is routine or calls for attention.
+type EventType string
+
+// Event types.
+const (
+	EventNormal  EventType = "Normal"
+	EventWarning EventType = "Warning"
+)
+
+// Event reasons, as recorded by the components of the orchestrator.
+const (
+	EventScheduled        = "Scheduled"
+	EventFailedScheduling = "FailedScheduling"
+	EventPreempted        = "Preempted"
+	EventPreempting       = "Preempting"
+	EventEvicted          = "Evicted"
+	EventPulling          = "Pulling"
+	EventPulled           = "Pulled"
+	EventStarted          = "Started"
+	EventFailed           = "Failed"
+	EventBackOff          = "BackOff"
+	EventKilling          = "Killing"
+	EventUnhealthy        = "Unhealthy"
+	EventCreated          = "SuccessfulCreate"
+	EventDeleted          = "SuccessfulDelete"
+	EventCompleted        = "Completed"
+	EventNodeReady        = "NodeReady"
+	EventNodeNotReady     = "NodeNotReady"
+	EventRescaled         = "SuccessfulRescale"
+	EventFailedRescale    = "FailedRescale"
+)
+
+// ObjectReference identifies the resource an event is about. Kind is its
+// bucket, e.g. "containers".
+type ObjectReference struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	UID       string `json:"uid,omitempty"`
+}
+
+// Event records something that happened to a resource. Repeats of the same
+// event are folded into one record whose count goes up.
+type Event struct {
+	ObjectMeta `json:"metadata"`
+
+	InvolvedObject ObjectReference `json:"involved_object"`
+	Type           EventType       `json:"type"`
+
+	// Reason is a short CamelCase word saying what happened, e.g.
+	// "FailedScheduling"; Message says it for people.
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+
+	// Source is the component that recorded the event, e.g. "scheduler".
+	Source string `json:"source"`
+
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
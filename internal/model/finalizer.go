@@ -0,0 +1,58 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// Finalizers of the garbage collector, which clears them once it has dealt
+// with the dependents of a resource deleted with the Foreground or Orphan
+// propagation policy.
+const (
+	FinalizerForeground = "orchestrator.foreground-deletion"
+	FinalizerOrphan     = "orchestrator.orphan"
+)
+
+// FinalizerRuntimeCleanup holds a deleted container until the agent of its
+// node has stopped and removed the runtime container behind it, for
+// controllers that must not replace a container while it still runs.
+const FinalizerRuntimeCleanup = "orchestrator.runtime-cleanup"
+
+// FinalizerNamespaceContents holds a deleted namespace until everything in
+// it has been removed.
+const FinalizerNamespaceContents = "orchestrator.namespace-contents"
+
+// finalizerRE matches finalizer names: a DNS-1123 label, optionally
+// qualified by a dotted prefix and a '/', e.g. "dns.example.com/record".
+var finalizerRE = regexp.MustCompile(`^([a-z0-9]([-a-z0-9.]*[a-z0-9])?/)?[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+
+// maxFinalizerLength is the longest finalizer name accepted.
+const maxFinalizerLength = 253
+
+// HasFinalizer reports whether the resource has finalizer f.
+func (m *ObjectMeta) HasFinalizer(f string) bool {
+	return slices.Contains(m.Finalizers, f)
+}
+
+// RemoveFinalizer removes finalizer f and reports whether it was present.
+func (m *ObjectMeta) RemoveFinalizer(f string) bool {
+	n := len(m.Finalizers)
+	m.Finalizers = slices.DeleteFunc(m.Finalizers, func(s string) bool { return s == f })
+	return len(m.Finalizers) != n
+}
+
+// ValidateFinalizers checks that the finalizers are well-formed names and
+// appear once each.
+func (m *ObjectMeta) ValidateFinalizers() error {
+	for i, f := range m.Finalizers {
+		field := fmt.Sprintf("metadata.finalizers[%d]", i)
+		if len(f) > maxFinalizerLength || !finalizerRE.MatchString(f) {
+			return invalid(field, "must be a lowercase name, optionally prefixed with a domain and '/'")
+		}
+		if slices.Contains(m.Finalizers[:i], f) {
+			return invalid(field, "duplicate finalizer %q", f)
+		}
+	}
+	return nil
+}
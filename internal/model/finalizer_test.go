@@ -0,0 +1,49 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObjectMeta_ValidateFinalizers(t *testing.T) {
+	tests := []struct {
+		name       string
+		finalizers []string
+		field      string
+	}{
+		{"none", nil, ""},
+		{"valid", []string{FinalizerForeground, "dns.example.com/record", "cleanup"}, ""},
+		{"uppercase", []string{"Cleanup"}, "metadata.finalizers[0]"},
+		{"empty", []string{"cleanup", ""}, "metadata.finalizers[1]"},
+		{"two slashes", []string{"a/b/c"}, "metadata.finalizers[0]"},
+		{"too long", []string{strings.Repeat("a", 254)}, "metadata.finalizers[0]"},
+		{"duplicate", []string{"cleanup", "cleanup"}, "metadata.finalizers[1]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &ObjectMeta{Finalizers: tt.finalizers}
+			err := m.ValidateFinalizers()
+			if tt.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, tt.field, verr.Field)
+		})
+	}
+}
+
+func TestObjectMeta_RemoveFinalizer(t *testing.T) {
+	m := &ObjectMeta{Finalizers: []string{"a", FinalizerOrphan, "b"}}
+	assert.True(t, m.RemoveFinalizer(FinalizerOrphan))
+	assert.False(t, m.RemoveFinalizer(FinalizerOrphan))
+	assert.Equal(t, []string{"a", "b"}, m.Finalizers)
+	assert.False(t, m.HasFinalizer(FinalizerOrphan))
+	assert.True(t, m.HasFinalizer("b"))
+}
@@ -0,0 +1,466 @@
+package model
+
+import "fmt"
+
+// GroupsBucket holds Group records keyed by namespace and name.
+const GroupsBucket = "groups"
+
+const (
+	// LabelGroup names the group a container or volume belongs to.
+	LabelGroup = "orchestrator.group"
+
+	// LabelGroupRole holds the GroupRole of a group member.
+	LabelGroupRole = "orchestrator.group-role"
+)
+
+// GroupRole is the part a member plays in its group.
+type GroupRole string
+
+// Group roles, in the order members start.
+const (
+	// RoleInit members run to completion one after the other before any
+	// other member starts.
+	RoleInit GroupRole = "init"
+
+	// RoleSidecar members start in order once the init members are done,
+	// and run alongside the main members until those finish.
+	RoleSidecar GroupRole = "sidecar"
+
+	// RoleMain members start once every sidecar is running and ready.
+	RoleMain GroupRole = "main"
+)
+
+// ReasonInitializing is reported on a group, and on its members, while
+// members wait for the ones before them.
+const ReasonInitializing = "Initializing"
+
+// Group runs several containers together on one node, like a pod: init
+// containers prepare the group one at a time, sidecars such as proxies and
+// log forwarders run alongside the main containers, and all of them share
+// the group's scratch volumes and, where the runtime supports it, a network
+// namespace so they reach each other on localhost.
+//
+// Member <m> of group <g> is stored as container <g>-<m>, and scratch volume
+// <v> as volume <g>-<v>. Members carry the group's labels and are scheduled
+// as one unit with the group's scheduling constraints. A group's containers
+// cannot change; replace the group instead.
+type Group struct {
+	ObjectMeta `json:"metadata"`
+	Spec       GroupSpec   `json:"spec"`
+	Status     GroupStatus `json:"status"`
+}
+
+// GroupSpec is the desired state of a group.
+type GroupSpec struct {
+	// InitContainers run in order, each to successful completion, before
+	// the other members start. They restart on failure unless the group's
+	// restart policy is Never, in which case one failure fails the group.
+	InitContainers []GroupContainer `json:"init_containers,omitempty"`
+
+	// Sidecars start in order after the init containers and always
+	// restart. They are stopped once every main container has finished.
+	Sidecars []GroupContainer `json:"sidecars,omitempty"`
+
+	// Containers are the main containers of the group.
+	Containers []GroupContainer `json:"containers"`
+
+	// Volumes are scratch volumes created with the group and deleted with
+	// it. Members mount them by name like any other volume.
+	Volumes []GroupVolume `json:"volumes,omitempty"`
+
+	// RestartPolicy applies to the main containers. Defaults to Always.
+	RestartPolicy RestartPolicy `json:"restart_policy,omitempty"`
+
+	// The scheduling constraints of the group apply to every member.
+	// Members must not set their own.
+	NodeName          string            `json:"node_name,omitempty"`
+	NodeSelector      map[string]string `json:"node_selector,omitempty"`
+	Affinity          *Affinity         `json:"affinity,omitempty"`
+	Tolerations       []Toleration      `json:"tolerations,omitempty"`
+	PriorityClassName string            `json:"priority_class_name,omitempty"`
+}
+
+// GroupContainer is a member of a group.
+type GroupContainer struct {
+	// Name is unique among the members of the group.
+	Name string        `json:"name"`
+	Spec ContainerSpec `json:"spec"`
+}
+
+// GroupVolume is a scratch volume shared by the members of a group.
+type GroupVolume struct {
+	Name          string `json:"name"`
+	CapacityBytes int64  `json:"capacity_bytes,omitempty"`
+}
+
+// GroupStatus aggregates the status of a group's members.
+type GroupStatus struct {
+	// Phase is Pending until the init containers are done and a member is
+	// running, Succeeded once every main container has succeeded and
+	// Failed once an init container or, with every main container
+	// finished, a main container has failed.
+	Phase ContainerPhase `json:"phase"`
+
+	// Ready is set while the group runs and every sidecar and main
+	// container is ready.
+	Ready bool `json:"ready"`
+
+	// NodeName is the node the group is scheduled to.
+	NodeName string `json:"node_name,omitempty"`
+
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+
+	// Members lists the members in start order.
+	Members []GroupMemberStatus `json:"members,omitempty"`
+}
+
+// GroupMemberStatus summarizes one member of a group.
+type GroupMemberStatus struct {
+	Name         string         `json:"name"`
+	Role         GroupRole      `json:"role"`
+	Phase        ContainerPhase `json:"phase"`
+	Ready        bool           `json:"ready"`
+	RestartCount int            `json:"restart_count"`
+	Reason       string         `json:"reason,omitempty"`
+	Message      string         `json:"message,omitempty"`
+}
+
+// GroupMember is a member of a group together with its role.
+type GroupMember struct {
+	*GroupContainer
+	Role GroupRole
+}
+
+// Members returns the members of the group in start order.
+func (g *Group) Members() []GroupMember {
+	s := &g.Spec
+	out := make([]GroupMember, 0, len(s.InitContainers)+len(s.Sidecars)+len(s.Containers))
+	for i := range s.InitContainers {
+		out = append(out, GroupMember{&s.InitContainers[i], RoleInit})
+	}
+	for i := range s.Sidecars {
+		out = append(out, GroupMember{&s.Sidecars[i], RoleSidecar})
+	}
+	for i := range s.Containers {
+		out = append(out, GroupMember{&s.Containers[i], RoleMain})
+	}
+	return out
+}
+
+// MemberName returns the name of the container record of a member.
+func (g *Group) MemberName(member string) string {
+	return g.Name + "-" + member
+}
+
+// VolumeName returns the name of the volume record of a scratch volume.
+func (g *Group) VolumeName(volume string) string {
+	return g.Name + "-" + volume
+}
+
+// RestartPolicyFor returns the restart policy of members with a role.
+func (g *Group) RestartPolicyFor(role GroupRole) RestartPolicy {
+	switch role {
+	case RoleInit:
+		if g.Spec.RestartPolicy == RestartNever {
+			return RestartNever
+		}
+		return RestartOnFailure
+	case RoleSidecar:
+		return RestartAlways
+	default:
+		return g.Spec.RestartPolicy
+	}
+}
+
+// SetDefaults fills in unset fields.
+func (g *Group) SetDefaults() {
+	if g.Spec.RestartPolicy == "" {
+		g.Spec.RestartPolicy = RestartAlways
+	}
+	for i := range g.Spec.Tolerations {
+		if g.Spec.Tolerations[i].Operator == "" {
+			g.Spec.Tolerations[i].Operator = TolerationEqual
+		}
+	}
+	for _, m := range g.Members() {
+		if m.Spec.RestartPolicy == "" {
+			m.Spec.RestartPolicy = g.RestartPolicyFor(m.Role)
+		}
+		m.Spec.SetDefaults()
+	}
+}
+
+// Validate checks the group for errors. Call SetDefaults first.
+func (g *Group) Validate() error {
+	if err := ValidateName("metadata.name", g.Name); err != nil {
+		return err
+	}
+	return g.validateSpec("spec")
+}
+
+// validateSpec checks the spec for errors. Member and volume names are
+// checked together with the group name they are prefixed with.
+func (g *Group) validateSpec(field string) error {
+	s := &g.Spec
+	switch s.RestartPolicy {
+	case RestartAlways, RestartOnFailure, RestartNever:
+	default:
+		return invalid(field+".restart_policy", "must be one of Always, OnFailure, Never; got %q", s.RestartPolicy)
+	}
+	if len(s.Containers) == 0 {
+		return invalid(field+".containers", "must have at least one container")
+	}
+
+	if s.NodeName != "" {
+		if err := ValidateName(field+".node_name", s.NodeName); err != nil {
+			return err
+		}
+	}
+	if s.PriorityClassName != "" {
+		if err := ValidateName(field+".priority_class_name", s.PriorityClassName); err != nil {
+			return err
+		}
+	}
+	if s.Affinity != nil {
+		if err := s.Affinity.Validate(field + ".affinity"); err != nil {
+			return err
+		}
+	}
+	for i := range s.Tolerations {
+		if err := s.Tolerations[i].Validate(fmt.Sprintf("%s.tolerations[%d]", field, i)); err != nil {
+			return err
+		}
+	}
+
+	volumes := make(map[string]bool, len(s.Volumes))
+	for i, v := range s.Volumes {
+		vf := fmt.Sprintf("%s.volumes[%d]", field, i)
+		if err := ValidateName(vf+".name", v.Name); err != nil {
+			return err
+		}
+		if volumes[v.Name] {
+			return invalid(vf+".name", "duplicate volume %s", v.Name)
+		}
+		volumes[v.Name] = true
+		if len(g.VolumeName(v.Name)) > maxNameLength {
+			return invalid(vf+".name", "combined with the group name must be at most %d characters",
+				maxNameLength-1)
+		}
+		if v.CapacityBytes < 0 {
+			return invalid(vf+".capacity_bytes", "must not be negative")
+		}
+	}
+
+	names := make(map[string]bool)
+	// Members share a network namespace, so their ports must not clash.
+	ports := make(map[string]string)
+	for _, m := range g.Members() {
+		mf := memberField(field, g, m)
+		if err := ValidateName(mf+".name", m.Name); err != nil {
+			return err
+		}
+		if names[m.Name] {
+			return invalid(mf+".name", "duplicate member %s", m.Name)
+		}
+		names[m.Name] = true
+		if len(g.MemberName(m.Name)) > maxNameLength {
+			return invalid(mf+".name", "combined with the group name must be at most %d characters",
+				maxNameLength-1)
+		}
+
+		if err := m.Spec.Validate(mf + ".spec"); err != nil {
+			return err
+		}
+		if err := validateMemberSpec(mf+".spec", &m.Spec); err != nil {
+			return err
+		}
+		if want := g.RestartPolicyFor(m.Role); m.Spec.RestartPolicy != want {
+			return invalid(mf+".spec.restart_policy", "must be %s for %s containers of this group", want, m.Role)
+		}
+		for i, p := range m.Spec.Ports {
+			port := fmt.Sprintf("%d/%s", p.ContainerPort, p.Protocol)
+			if other, ok := ports[port]; ok {
+				return invalid(fmt.Sprintf("%s.spec.ports[%d].container_port", mf, i),
+					"port %s is also used by member %s", port, other)
+			}
+			ports[port] = m.Name
+		}
+	}
+	return nil
+}
+
+// validateMemberSpec rejects the fields of a member that are set on the
+// group instead.
+func validateMemberSpec(field string, s *ContainerSpec) error {
+	switch {
+	case s.NodeName != "":
+		return invalid(field+".node_name", "must not be set for group members; set it on the group")
+	case len(s.NodeSelector) > 0:
+		return invalid(field+".node_selector", "must not be set for group members; set it on the group")
+	case s.Affinity != nil:
+		return invalid(field+".affinity", "must not be set for group members; set it on the group")
+	case len(s.Tolerations) > 0:
+		return invalid(field+".tolerations", "must not be set for group members; set it on the group")
+	case s.PriorityClassName != "":
+		return invalid(field+".priority_class_name", "must not be set for group members; set it on the group")
+	}
+	return nil
+}
+
+// memberField returns the field path of a member, e.g. spec.sidecars[1].
+func memberField(field string, g *Group, m GroupMember) string {
+	var list []GroupContainer
+	name := "containers"
+	switch m.Role {
+	case RoleInit:
+		list, name = g.Spec.InitContainers, "init_containers"
+	case RoleSidecar:
+		list, name = g.Spec.Sidecars, "sidecars"
+	default:
+		list = g.Spec.Containers
+	}
+	for i := range list {
+		if &list[i] == m.GroupContainer {
+			return fmt.Sprintf("%s.%s[%d]", field, name, i)
+		}
+	}
+	return field + "." + name
+}
+
+// WaitingFor returns the member that member must wait for before it
+// starts, or "" if it may start. members maps member names to their
+// container records; missing members have not been created yet.
+//
+// Init containers wait for the ones before them to succeed, sidecars for
+// every init container to succeed and the sidecars before them to run,
+// and main containers for every sidecar to run and be ready.
+func (g *Group) WaitingFor(member string, members map[string]*Container) string {
+	role := GroupRole("")
+	for _, m := range g.Members() {
+		if m.Name == member {
+			role = m.Role
+			break
+		}
+	}
+
+	for _, m := range g.Members() {
+		if m.Name == member {
+			return ""
+		}
+		c := members[m.Name]
+		switch m.Role {
+		case RoleInit:
+			if c == nil || c.Status.Phase != PhaseSucceeded {
+				return m.Name
+			}
+		case RoleSidecar:
+			if c == nil || c.Status.Phase != PhaseRunning {
+				return m.Name
+			}
+			if role == RoleMain && !c.Status.Ready {
+				return m.Name
+			}
+		case RoleMain:
+			// Main containers start together.
+			return ""
+		}
+	}
+	return ""
+}
+
+// Aggregate computes the status of the group from its members. members
+// maps member names to their container records.
+func (g *Group) Aggregate(members map[string]*Container) GroupStatus {
+	status := GroupStatus{Phase: PhasePending}
+
+	var (
+		initDone      = true
+		waiting       string
+		running       bool
+		ready         = true
+		mains         int
+		mainsDone     int
+		mainsFailed   int
+		failed        string
+		unschedulable string
+	)
+	for _, m := range g.Members() {
+		ms := GroupMemberStatus{Name: m.Name, Role: m.Role, Phase: PhasePending}
+		c := members[m.Name]
+		if c != nil {
+			ms.Phase = c.Status.Phase
+			ms.Ready = c.Status.Ready
+			ms.RestartCount = c.Status.RestartCount
+			ms.Reason = c.Status.Reason
+			ms.Message = c.Status.Message
+			if c.Spec.NodeName != "" {
+				status.NodeName = c.Spec.NodeName
+			} else if c.Status.Reason == ReasonUnschedulable && unschedulable == "" {
+				unschedulable = c.Status.Message
+			}
+		}
+		status.Members = append(status.Members, ms)
+
+		switch m.Role {
+		case RoleInit:
+			if ms.Phase == PhaseFailed && failed == "" {
+				failed = m.Name
+			}
+			if ms.Phase != PhaseSucceeded {
+				if initDone {
+					waiting = m.Name
+				}
+				initDone = false
+			}
+		case RoleSidecar:
+			if ms.Phase == PhaseRunning {
+				running = true
+			}
+			ready = ready && ms.Phase == PhaseRunning && ms.Ready
+		case RoleMain:
+			mains++
+			switch ms.Phase {
+			case PhaseRunning:
+				running = true
+			case PhaseSucceeded:
+				mainsDone++
+			case PhaseFailed:
+				mainsDone++
+				mainsFailed++
+				if failed == "" {
+					failed = m.Name
+				}
+			}
+			ready = ready && ms.Phase == PhaseRunning && ms.Ready
+		}
+	}
+
+	switch {
+	case failed != "" && (!initDone || mainsDone == mains):
+		status.Phase = PhaseFailed
+		status.Reason = ReasonError
+		status.Message = fmt.Sprintf("member %s failed", failed)
+	case mains > 0 && mainsDone == mains:
+		status.Phase = PhaseSucceeded
+		status.Reason = ReasonCompleted
+	case unschedulable != "":
+		status.Reason = ReasonUnschedulable
+		status.Message = unschedulable
+	case !initDone:
+		status.Reason = ReasonInitializing
+		status.Message = fmt.Sprintf("waiting for init container %s", waiting)
+	case running:
+		status.Phase = PhaseRunning
+		status.Ready = ready
+		if mainsFailed > 0 {
+			status.Message = fmt.Sprintf("%d of %d containers failed", mainsFailed, mains)
+		}
+	}
+	return status
+}
+
+// IsTerminal reports whether the group has finished for good.
+func (s *GroupStatus) IsTerminal() bool {
+	return s.Phase == PhaseSucceeded || s.Phase == PhaseFailed
+}
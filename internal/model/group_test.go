@@ -0,0 +1,180 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testGroup() *Group {
+	return &Group{
+		ObjectMeta: ObjectMeta{Name: "shop"},
+		Spec: GroupSpec{
+			InitContainers: []GroupContainer{
+				{Name: "migrate", Spec: ContainerSpec{Image: "migrate"}},
+				{Name: "seed", Spec: ContainerSpec{Image: "seed"}},
+			},
+			Sidecars: []GroupContainer{
+				{Name: "proxy", Spec: ContainerSpec{Image: "envoy", Ports: []ContainerPort{{ContainerPort: 15001}}}},
+				{Name: "logs", Spec: ContainerSpec{Image: "fluentbit"}},
+			},
+			Containers: []GroupContainer{
+				{Name: "app", Spec: ContainerSpec{Image: "shop", Ports: []ContainerPort{{ContainerPort: 8080}}}},
+			},
+			Volumes: []GroupVolume{{Name: "scratch"}},
+		},
+	}
+}
+
+func TestGroup_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(g *Group)
+		field  string
+	}{
+		{"valid", func(*Group) {}, ""},
+		{"no containers", func(g *Group) { g.Spec.Containers = nil }, "spec.containers"},
+		{"duplicate member", func(g *Group) { g.Spec.Sidecars[1].Name = "migrate" }, "spec.sidecars[1].name"},
+		{"long member name", func(g *Group) {
+			g.Name = "a-group-name-that-is-rather-long-already"
+			g.Spec.Containers[0].Name = "and-a-member-name-that-is-long-too"
+		}, "spec.containers[0].name"},
+		{"member restart policy", func(g *Group) {
+			g.Spec.InitContainers[0].Spec.RestartPolicy = RestartAlways
+		}, "spec.init_containers[0].spec.restart_policy"},
+		{"member node selector", func(g *Group) {
+			g.Spec.Containers[0].Spec.NodeSelector = map[string]string{"disk": "ssd"}
+		}, "spec.containers[0].spec.node_selector"},
+		{"port clash", func(g *Group) {
+			g.Spec.Containers[0].Spec.Ports[0].ContainerPort = 15001
+		}, "spec.containers[0].spec.ports[0].container_port"},
+		{"duplicate volume", func(g *Group) {
+			g.Spec.Volumes = append(g.Spec.Volumes, GroupVolume{Name: "scratch"})
+		}, "spec.volumes[1].name"},
+		{"bad restart policy", func(g *Group) { g.Spec.RestartPolicy = "Sometimes" }, "spec.restart_policy"},
+		{"bad toleration", func(g *Group) {
+			g.Spec.Tolerations = []Toleration{{Operator: TolerationExists, Value: "x"}}
+		}, "spec.tolerations[0].value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := testGroup()
+			tt.mutate(g)
+			g.SetDefaults()
+			err := g.Validate()
+			if tt.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, tt.field, verr.Field)
+		})
+	}
+}
+
+func TestGroup_SetDefaults(t *testing.T) {
+	g := testGroup()
+	g.Spec.RestartPolicy = RestartNever
+	g.SetDefaults()
+	require.NoError(t, g.Validate())
+
+	assert.Equal(t, RestartNever, g.Spec.InitContainers[0].Spec.RestartPolicy)
+	assert.Equal(t, RestartAlways, g.Spec.Sidecars[0].Spec.RestartPolicy)
+	assert.Equal(t, RestartNever, g.Spec.Containers[0].Spec.RestartPolicy)
+	assert.Equal(t, "shop-proxy", g.MemberName("proxy"))
+}
+
+func TestGroup_WaitingFor(t *testing.T) {
+	g := testGroup()
+	members := map[string]*Container{}
+	set := func(name string, phase ContainerPhase, ready bool) {
+		members[name] = &Container{Status: ContainerStatus{Phase: phase, Ready: ready}}
+	}
+
+	assert.Empty(t, g.WaitingFor("migrate", members))
+	assert.Equal(t, "migrate", g.WaitingFor("seed", members))
+	assert.Equal(t, "migrate", g.WaitingFor("app", members))
+
+	set("migrate", PhaseSucceeded, false)
+	set("seed", PhaseRunning, true)
+	assert.Empty(t, g.WaitingFor("seed", members))
+	assert.Equal(t, "seed", g.WaitingFor("proxy", members))
+
+	set("seed", PhaseSucceeded, false)
+	assert.Empty(t, g.WaitingFor("proxy", members))
+	assert.Equal(t, "proxy", g.WaitingFor("logs", members))
+
+	// Sidecars start once the ones before run; main containers wait for
+	// them to be ready as well.
+	set("proxy", PhaseRunning, false)
+	set("logs", PhaseRunning, true)
+	assert.Empty(t, g.WaitingFor("logs", members))
+	assert.Equal(t, "proxy", g.WaitingFor("app", members))
+
+	set("proxy", PhaseRunning, true)
+	assert.Empty(t, g.WaitingFor("app", members))
+}
+
+func TestGroup_Aggregate(t *testing.T) {
+	g := testGroup()
+	members := map[string]*Container{}
+	set := func(name string, phase ContainerPhase, ready bool) {
+		members[name] = &Container{
+			Spec:   ContainerSpec{NodeName: "node-1"},
+			Status: ContainerStatus{Phase: phase, Ready: ready},
+		}
+	}
+
+	s := g.Aggregate(members)
+	assert.Equal(t, PhasePending, s.Phase)
+	assert.Len(t, s.Members, 5)
+	assert.Equal(t, GroupMemberStatus{Name: "proxy", Role: RoleSidecar, Phase: PhasePending}, s.Members[2])
+
+	members["migrate"] = &Container{Status: ContainerStatus{
+		Phase: PhasePending, Reason: ReasonUnschedulable, Message: "0/1 nodes are available: 1 insufficient cpu",
+	}}
+	s = g.Aggregate(members)
+	assert.Equal(t, ReasonUnschedulable, s.Reason)
+	assert.Equal(t, "0/1 nodes are available: 1 insufficient cpu", s.Message)
+
+	set("migrate", PhaseSucceeded, false)
+	set("seed", PhaseRunning, true)
+	s = g.Aggregate(members)
+	assert.Equal(t, PhasePending, s.Phase)
+	assert.Equal(t, ReasonInitializing, s.Reason)
+	assert.Equal(t, "waiting for init container seed", s.Message)
+	assert.Equal(t, "node-1", s.NodeName)
+
+	set("seed", PhaseSucceeded, false)
+	set("proxy", PhaseRunning, true)
+	set("logs", PhaseRunning, true)
+	set("app", PhaseRunning, false)
+	s = g.Aggregate(members)
+	assert.Equal(t, PhaseRunning, s.Phase)
+	assert.False(t, s.Ready)
+
+	set("app", PhaseRunning, true)
+	s = g.Aggregate(members)
+	assert.True(t, s.Ready)
+	assert.Empty(t, s.Reason)
+
+	set("app", PhaseSucceeded, false)
+	s = g.Aggregate(members)
+	assert.Equal(t, PhaseSucceeded, s.Phase)
+
+	set("app", PhaseFailed, false)
+	s = g.Aggregate(members)
+	assert.Equal(t, PhaseFailed, s.Phase)
+	assert.Equal(t, "member app failed", s.Message)
+
+	// A failed init container fails the group straight away.
+	delete(members, "app")
+	set("seed", PhaseFailed, false)
+	s = g.Aggregate(members)
+	assert.Equal(t, PhaseFailed, s.Phase)
+	assert.Equal(t, "member seed failed", s.Message)
+}
@@ -0,0 +1,122 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PullPolicy says when the agent pulls a container's image before
+// creating it.
+type PullPolicy string
+
+// Image pull policies.
+const (
+	// PullAlways pulls the image every time a runtime container is
+	// created for it.
+	PullAlways PullPolicy = "Always"
+
+	// PullIfNotPresent pulls the image only when the runtime does not have
+	// it yet.
+	PullIfNotPresent PullPolicy = "IfNotPresent"
+
+	// PullNever never pulls the image; the container waits until the image
+	// is put on the node some other way.
+	PullNever PullPolicy = "Never"
+)
+
+// DockerHubRegistry is the registry of images whose name does not start
+// with a registry host.
+const DockerHubRegistry = "docker.io"
+
+// Keys of a secret holding registry credentials, as named in a container's
+// image pull secrets. Server is the registry host the credentials are for,
+// e.g. "registry.example.com:5000".
+const (
+	RegistrySecretServer   = "server"
+	RegistrySecretUsername = "username"
+	RegistrySecretPassword = "password"
+)
+
+// ImagePullStatus reports the pull of a container's image while it is in
+// progress or failing.
+type ImagePullStatus struct {
+	Image string `json:"image"`
+
+	// Progress is the step the runtime last reported, e.g. "Downloading".
+	Progress string `json:"progress,omitempty"`
+
+	// CurrentBytes and TotalBytes are how much of the image has been
+	// downloaded and its size, when the runtime reports them.
+	CurrentBytes int64 `json:"current_bytes,omitempty"`
+	TotalBytes   int64 `json:"total_bytes,omitempty"`
+
+	StartedAt time.Time `json:"started_at"`
+
+	// Failures counts the pulls that failed in a row. Each failure backs
+	// off the next attempt like a crashing container.
+	Failures int `json:"failures,omitempty"`
+
+	// Error is why the last pull failed.
+	Error string `json:"error,omitempty"`
+}
+
+// DefaultPullPolicy returns the pull policy of image when the spec sets
+// none: Always for images tagged latest or not tagged at all, whose content
+// may change under the same name, and IfNotPresent for the others.
+func DefaultPullPolicy(image string) PullPolicy {
+	if image == "" || strings.Contains(image, "@") {
+		return PullIfNotPresent
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	_, tag, ok := strings.Cut(name, ":")
+	if !ok || tag == "latest" {
+		return PullAlways
+	}
+	return PullIfNotPresent
+}
+
+// ImageRegistry returns the host of the registry image is pulled from.
+// Like Docker, it takes the first component of the name for a host if it
+// contains a '.' or a ':', or is "localhost".
+func ImageRegistry(image string) string {
+	host, _, ok := strings.Cut(image, "/")
+	if !ok || (!strings.ContainsAny(host, ".:") && host != "localhost") {
+		return DockerHubRegistry
+	}
+	return NormalizeRegistry(host)
+}
+
+// NormalizeRegistry returns the registry host of server as ImageRegistry
+// would, so that credentials given for a URL or an alias of Docker Hub
+// match the images of that registry.
+func NormalizeRegistry(server string) string {
+	host := strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+	switch host {
+	case "index.docker.io", "registry-1.docker.io":
+		return DockerHubRegistry
+	}
+	return host
+}
+
+// validateImagePull checks the pull policy and pull secrets of a spec.
+func (s *ContainerSpec) validateImagePull(field string) error {
+	switch s.ImagePullPolicy {
+	case PullAlways, PullIfNotPresent, PullNever:
+	default:
+		return invalid(field+".image_pull_policy", "must be one of Always, IfNotPresent, Never; got %q", s.ImagePullPolicy)
+	}
+	for i, name := range s.ImagePullSecrets {
+		sf := fmt.Sprintf("%s.image_pull_secrets[%d]", field, i)
+		if err := ValidateName(sf, name); err != nil {
+			return err
+		}
+		for _, prev := range s.ImagePullSecrets[:i] {
+			if prev == name {
+				return invalid(sf, "duplicate secret %q", name)
+			}
+		}
+	}
+	return nil
+}
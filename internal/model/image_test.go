@@ -0,0 +1,57 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultPullPolicy(t *testing.T) {
+	tests := map[string]PullPolicy{
+		"nginx":                            PullAlways,
+		"nginx:latest":                     PullAlways,
+		"nginx:1.25":                       PullIfNotPresent,
+		"localhost:5000/app":               PullAlways,
+		"localhost:5000/app:v2":            PullIfNotPresent,
+		"app@sha256:0123456789abcdef":      PullIfNotPresent,
+		"registry.example.com/team/app:v1": PullIfNotPresent,
+	}
+	for image, want := range tests {
+		assert.Equal(t, want, DefaultPullPolicy(image), image)
+	}
+}
+
+func TestImageRegistry(t *testing.T) {
+	tests := map[string]string{
+		"nginx":                             DockerHubRegistry,
+		"library/nginx:1.25":                DockerHubRegistry,
+		"localhost/app":                     "localhost",
+		"localhost:5000/app:v1":             "localhost:5000",
+		"registry.example.com/team/app:v1":  "registry.example.com",
+		"index.docker.io/library/nginx:1.2": DockerHubRegistry,
+	}
+	for image, want := range tests {
+		assert.Equal(t, want, ImageRegistry(image), image)
+	}
+
+	assert.Equal(t, "registry.example.com", NormalizeRegistry("https://registry.example.com/v2/"))
+	assert.Equal(t, DockerHubRegistry, NormalizeRegistry("https://index.docker.io/v1/"))
+}
+
+func TestContainerSpec_ValidateImagePull(t *testing.T) {
+	spec := ContainerSpec{Image: "app:v1", ImagePullSecrets: []string{"regcred"}}
+	spec.SetDefaults()
+	require.NoError(t, spec.Validate("spec"))
+	assert.Equal(t, PullIfNotPresent, spec.ImagePullPolicy)
+
+	spec.ImagePullPolicy = "Sometimes"
+	var verr *ValidationError
+	require.ErrorAs(t, spec.Validate("spec"), &verr)
+	assert.Equal(t, "spec.image_pull_policy", verr.Field)
+
+	spec.ImagePullPolicy = PullNever
+	spec.ImagePullSecrets = []string{"regcred", "regcred"}
+	require.ErrorAs(t, spec.Validate("spec"), &verr)
+	assert.Equal(t, "spec.image_pull_secrets[1]", verr.Field)
+}
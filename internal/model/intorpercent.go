@@ -0,0 +1,76 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// IntOrPercent is an absolute count or a percentage of a total. It is
+// encoded in JSON as a number (3) or a string ("25%").
+type IntOrPercent struct {
+	Value   int
+	Percent bool
+}
+
+// FromInt returns an absolute IntOrPercent.
+func FromInt(v int) *IntOrPercent {
+	return &IntOrPercent{Value: v}
+}
+
+// FromPercent returns a percentage IntOrPercent.
+func FromPercent(v int) *IntOrPercent {
+	return &IntOrPercent{Value: v, Percent: true}
+}
+
+// Scaled resolves the value against total. Percentages are rounded up or
+// down as requested.
+func (v *IntOrPercent) Scaled(total int, roundUp bool) int {
+	if !v.Percent {
+		return v.Value
+	}
+	f := float64(v.Value*total) / 100
+	if roundUp {
+		return int(math.Ceil(f))
+	}
+	return int(math.Floor(f))
+}
+
+// String implements fmt.Stringer.
+func (v IntOrPercent) String() string {
+	if v.Percent {
+		return strconv.Itoa(v.Value) + "%"
+	}
+	return strconv.Itoa(v.Value)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (v IntOrPercent) MarshalJSON() ([]byte, error) {
+	if v.Percent {
+		return json.Marshal(v.String())
+	}
+	return json.Marshal(v.Value)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (v *IntOrPercent) UnmarshalJSON(data []byte) error {
+	var n int
+	if err := json.Unmarshal(data, &n); err == nil {
+		*v = IntOrPercent{Value: n}
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("must be an integer or a percentage string")
+	}
+	percent := strings.HasSuffix(s, "%")
+	n, err := strconv.Atoi(strings.TrimSuffix(s, "%"))
+	if err != nil {
+		return fmt.Errorf("invalid value %q: must be an integer or a percentage", s)
+	}
+	*v = IntOrPercent{Value: n, Percent: percent}
+	return nil
+}
@@ -0,0 +1,143 @@
+package model
+
+import "time"
+
+// JobsBucket holds Job records keyed by namespace and name.
+const JobsBucket = "jobs"
+
+// LabelJob names the job that created a container.
+const LabelJob = "orchestrator.job"
+
+// maxJobNameLength leaves room in container names for a random suffix.
+const maxJobNameLength = 57
+
+// DefaultBackoffLimit is the number of retries of jobs that do not set one.
+const DefaultBackoffLimit = 6
+
+// JobPhase is the state of a job.
+type JobPhase string
+
+// Job phases.
+const (
+	JobRunning  JobPhase = "Running"
+	JobComplete JobPhase = "Complete"
+	JobFailed   JobPhase = "Failed"
+)
+
+// Job reasons.
+const (
+	ReasonBackoffLimitExceeded = "BackoffLimitExceeded"
+	ReasonDeadlineExceeded     = "DeadlineExceeded"
+)
+
+// Job runs containers until a number of them complete successfully.
+type Job struct {
+	ObjectMeta `json:"metadata"`
+	Spec       JobSpec   `json:"spec"`
+	Status     JobStatus `json:"status"`
+}
+
+// JobSpec is the desired state of a job.
+type JobSpec struct {
+	// Template describes the containers the job runs. Its restart policy
+	// must be OnFailure or Never and defaults to Never.
+	Template ContainerTemplate `json:"template"`
+
+	// Completions is how many containers must succeed. Defaults to 1.
+	Completions int `json:"completions,omitempty"`
+
+	// Parallelism is how many containers may run at once. Defaults to 1.
+	Parallelism int `json:"parallelism,omitempty"`
+
+	// BackoffLimit is how many times containers may fail, counting both
+	// failed containers and restarts, before the job is marked Failed.
+	// Defaults to 6.
+	BackoffLimit *int `json:"backoff_limit,omitempty"`
+
+	// ActiveDeadlineSeconds bounds how long the job may run before it is
+	// marked Failed and its containers are stopped. Zero means no limit.
+	ActiveDeadlineSeconds int `json:"active_deadline_seconds,omitempty"`
+}
+
+// JobStatus is the observed state of a job.
+type JobStatus struct {
+	Phase   JobPhase `json:"phase,omitempty"`
+	Reason  string   `json:"reason,omitempty"`
+	Message string   `json:"message,omitempty"`
+
+	// Active, Succeeded and Failed count the job's containers by state.
+	Active    int `json:"active"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// IsFinished reports whether the job has completed or failed.
+func (s *JobStatus) IsFinished() bool {
+	return s.Phase == JobComplete || s.Phase == JobFailed
+}
+
+// SetDefaults fills in unset fields.
+func (j *Job) SetDefaults() {
+	j.Spec.SetDefaults()
+}
+
+// SetDefaults fills in unset fields.
+func (s *JobSpec) SetDefaults() {
+	if s.Completions == 0 {
+		s.Completions = 1
+	}
+	if s.Parallelism == 0 {
+		s.Parallelism = 1
+	}
+	if s.BackoffLimit == nil {
+		limit := DefaultBackoffLimit
+		s.BackoffLimit = &limit
+	}
+	if s.Template.Spec.RestartPolicy == "" {
+		s.Template.Spec.RestartPolicy = RestartNever
+	}
+	s.Template.Spec.SetDefaults()
+}
+
+// Validate checks the job for errors.
+func (j *Job) Validate() error {
+	if err := ValidateName("metadata.name", j.Name); err != nil {
+		return err
+	}
+	if len(j.Name) > maxJobNameLength {
+		return invalid("metadata.name", "must be at most %d characters", maxJobNameLength)
+	}
+	return j.Spec.Validate("spec")
+}
+
+// Validate checks the spec for errors.
+func (s *JobSpec) Validate(field string) error {
+	if s.Completions < 1 {
+		return invalid(field+".completions", "must be at least 1")
+	}
+	if s.Parallelism < 1 {
+		return invalid(field+".parallelism", "must be at least 1")
+	}
+	if s.BackoffLimit == nil || *s.BackoffLimit < 0 {
+		return invalid(field+".backoff_limit", "must not be negative")
+	}
+	if s.ActiveDeadlineSeconds < 0 {
+		return invalid(field+".active_deadline_seconds", "must not be negative")
+	}
+
+	if err := s.Template.Spec.Validate(field + ".template.spec"); err != nil {
+		return err
+	}
+	if s.Template.Spec.RestartPolicy == RestartAlways {
+		return invalid(field+".template.spec.restart_policy", "must be OnFailure or Never for jobs")
+	}
+	return nil
+}
+
+// ActiveDeadline returns the active deadline as a duration, zero if unset.
+func (s *JobSpec) ActiveDeadline() time.Duration {
+	return time.Duration(s.ActiveDeadlineSeconds) * time.Second
+}
@@ -0,0 +1,114 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJob_Defaults(t *testing.T) {
+	j := &Job{
+		ObjectMeta: ObjectMeta{Name: "migrate"},
+		Spec:       JobSpec{Template: ContainerTemplate{Spec: ContainerSpec{Image: "app"}}},
+	}
+	j.SetDefaults()
+
+	require.NoError(t, j.Validate())
+	assert.Equal(t, 1, j.Spec.Completions)
+	assert.Equal(t, 1, j.Spec.Parallelism)
+	assert.Equal(t, DefaultBackoffLimit, *j.Spec.BackoffLimit)
+	assert.Equal(t, RestartNever, j.Spec.Template.Spec.RestartPolicy)
+}
+
+func TestJob_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(j *Job)
+		field  string
+	}{
+		{"valid", func(*Job) {}, ""},
+		{"long name", func(j *Job) { j.Name = "a1234567890123456789012345678901234567890123456789012345678" }, "metadata.name"},
+		{"negative completions", func(j *Job) { j.Spec.Completions = -1 }, "spec.completions"},
+		{"negative parallelism", func(j *Job) { j.Spec.Parallelism = -1 }, "spec.parallelism"},
+		{"negative backoff limit", func(j *Job) { *j.Spec.BackoffLimit = -1 }, "spec.backoff_limit"},
+		{"negative deadline", func(j *Job) { j.Spec.ActiveDeadlineSeconds = -1 }, "spec.active_deadline_seconds"},
+		{
+			"restart always",
+			func(j *Job) { j.Spec.Template.Spec.RestartPolicy = RestartAlways },
+			"spec.template.spec.restart_policy",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := &Job{
+				ObjectMeta: ObjectMeta{Name: "migrate"},
+				Spec:       JobSpec{Template: ContainerTemplate{Spec: ContainerSpec{Image: "app"}}},
+			}
+			j.SetDefaults()
+			tt.mutate(j)
+
+			err := j.Validate()
+			if tt.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, tt.field, verr.Field)
+		})
+	}
+}
+
+func TestCronJob_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(c *CronJob)
+		field  string
+	}{
+		{"valid", func(*CronJob) {}, ""},
+		{"macro", func(c *CronJob) { c.Spec.Schedule = "@daily" }, ""},
+		{"long name", func(c *CronJob) { c.Name = "a123456789012345678901234567890123456789012345678" }, "metadata.name"},
+		{"bad schedule", func(c *CronJob) { c.Spec.Schedule = "0 25 * * *" }, "spec.schedule"},
+		{"concurrency policy", func(c *CronJob) { c.Spec.ConcurrencyPolicy = "Sometimes" }, "spec.concurrency_policy"},
+		{"negative deadline", func(c *CronJob) { c.Spec.StartingDeadlineSeconds = -1 }, "spec.starting_deadline_seconds"},
+		{
+			"negative history",
+			func(c *CronJob) { *c.Spec.FailedJobsHistoryLimit = -1 },
+			"spec.failed_jobs_history_limit",
+		},
+		{
+			"invalid job template",
+			func(c *CronJob) { c.Spec.JobTemplate.Spec.Template.Spec.RestartPolicy = RestartAlways },
+			"spec.job_template.spec.template.spec.restart_policy",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &CronJob{
+				ObjectMeta: ObjectMeta{Name: "report"},
+				Spec: CronJobSpec{
+					Schedule: "0 2 * * *",
+					JobTemplate: JobTemplate{
+						Spec: JobSpec{Template: ContainerTemplate{Spec: ContainerSpec{Image: "app"}}},
+					},
+				},
+			}
+			c.SetDefaults()
+			tt.mutate(c)
+
+			err := c.Validate()
+			if tt.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, tt.field, verr.Field)
+		})
+	}
+}
@@ -0,0 +1,141 @@
+// Package model defines the resources managed by the orchestrator and the
+// store buckets they are persisted in. Resources are stored as JSON.
+package model
+
+import (
+	"crypto/rand"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Labels applied to runtime containers so they can be traced back to
+// their store records.
+const (
+	LabelManaged   = "orchestrator.managed"
+	LabelNamespace = "orchestrator.namespace"
+	LabelContainer = "orchestrator.container"
+)
+
+// nameRE matches DNS-1123 labels: lowercase alphanumerics and '-',
+// starting and ending with an alphanumeric.
+var nameRE = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// maxNameLength is the longest name accepted for a resource.
+const maxNameLength = 63
+
+// DefaultNamespace is the namespace created at startup for resources
+// that do not need their own.
+const DefaultNamespace = "default"
+
+// ObjectMeta is metadata common to all persisted resources.
+type ObjectMeta struct {
+	Name string `json:"name"`
+
+	// Namespace scopes the name. It is empty for cluster-wide resources
+	// such as nodes and namespaces themselves.
+	Namespace string `json:"namespace,omitempty"`
+
+	UID         string            `json:"uid"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+
+	// OwnerReferences name the resources this one depends on. Once all of
+	// them are gone, the garbage collector deletes it.
+	OwnerReferences []OwnerReference `json:"owner_references,omitempty"`
+
+	// Finalizers name the cleanups that must happen before the record of
+	// the resource is removed, such as releasing an external resource.
+	// Each is cleared by the controller responsible for it.
+	Finalizers []string `json:"finalizers,omitempty"`
+
+	// DeletionTimestamp is set when a resource with finalizers is deleted.
+	// It stays, terminating, until its finalizers have all been cleared.
+	DeletionTimestamp *time.Time `json:"deletion_timestamp,omitempty"`
+}
+
+// Object is implemented by every resource through its embedded
+// ObjectMeta.
+type Object interface {
+	Meta() *ObjectMeta
+}
+
+// Meta returns the metadata itself. Resources embedding ObjectMeta
+// inherit it, giving generic code access to their metadata.
+func (m *ObjectMeta) Meta() *ObjectMeta {
+	return m
+}
+
+// Key returns the store key of the resource: its name, prefixed with its
+// namespace if it has one.
+func (m *ObjectMeta) Key() string {
+	return Key(m.Namespace, m.Name)
+}
+
+// Key returns the store key of a resource in namespace. Cluster-wide
+// resources have an empty namespace and are keyed by name.
+func Key(namespace, name string) string {
+	return NamespacePrefix(namespace) + name
+}
+
+// NamespacePrefix returns the key prefix shared by the resources of a
+// namespace. It is empty for cluster-wide resources.
+func NamespacePrefix(namespace string) string {
+	if namespace == "" {
+		return ""
+	}
+	return namespace + "/"
+}
+
+// ValidationError describes an invalid field in a resource.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// invalid returns a ValidationError for field.
+func invalid(field, format string, args ...any) *ValidationError {
+	return &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+// ValidateName checks that name is a valid resource name.
+func ValidateName(field, name string) error {
+	if name == "" {
+		return invalid(field, "is required")
+	}
+	if len(name) > maxNameLength {
+		return invalid(field, "must be at most %d characters", maxNameLength)
+	}
+	if !nameRE.MatchString(name) {
+		return invalid(field, "must consist of lowercase alphanumerics and '-', and start and end with an alphanumeric")
+	}
+	return nil
+}
+
+// Initialize assigns a UID and creation time to new metadata.
+func (m *ObjectMeta) Initialize() error {
+	uid, err := NewUID()
+	if err != nil {
+		return err
+	}
+	m.UID = uid
+	m.CreatedAt = time.Now().UTC()
+	return nil
+}
+
+// NewUID returns a random RFC 4122 version 4 UUID.
+func NewUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate UID: %w", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
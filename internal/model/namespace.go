@@ -0,0 +1,101 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// NamespacesBucket holds Namespace records keyed by name.
+const NamespacesBucket = "namespaces"
+
+// NamespacedBuckets lists the buckets of namespaced resources. Their names
+// are also the kinds quotas count, as they appear in API paths.
+var NamespacedBuckets = []string{
+	ContainersBucket,
+	DeploymentsBucket,
+	DaemonSetsBucket,
+	StatefulSetsBucket,
+	GroupsBucket,
+	JobsBucket,
+	CronJobsBucket,
+	SecretsBucket,
+	ConfigMapsBucket,
+	VolumesBucket,
+	DisruptionBudgetsBucket,
+	AutoscalersBucket,
+}
+
+// Namespace groups resources, typically those of one team. Names are
+// unique within a namespace, and a quota can cap what the namespace uses.
+// Deleting a namespace deletes everything in it.
+type Namespace struct {
+	ObjectMeta `json:"metadata"`
+	Spec       NamespaceSpec   `json:"spec"`
+	Status     NamespaceStatus `json:"status"`
+}
+
+// NamespaceSpec is the desired state of a namespace.
+type NamespaceSpec struct {
+	// Quota caps the resources of the namespace. Nothing is capped when
+	// it is nil.
+	Quota *Quota `json:"quota,omitempty"`
+}
+
+// NamespaceStatus is the observed state of a namespace. It is computed
+// when the namespace is read and not stored.
+type NamespaceStatus struct {
+	Used *QuotaUsage `json:"used,omitempty"`
+}
+
+// Quota caps the resources of a namespace.
+type Quota struct {
+	// Counts caps the number of resources of each kind, e.g.
+	// {"containers": 20}. Kinds not listed are unlimited.
+	Counts map[string]int `json:"counts,omitempty"`
+
+	// Requests caps the CPU and memory requested by the namespace's
+	// workloads in total. Zero fields are unlimited.
+	Requests Resources `json:"requests,omitempty"`
+}
+
+// QuotaUsage is what a namespace uses of its quota.
+type QuotaUsage struct {
+	Counts   map[string]int `json:"counts"`
+	Requests Resources      `json:"requests"`
+}
+
+// SetDefaults fills in unset fields.
+func (n *Namespace) SetDefaults() {}
+
+// Validate checks the namespace for errors.
+func (n *Namespace) Validate() error {
+	if err := ValidateName("metadata.name", n.Name); err != nil {
+		return err
+	}
+	if n.Namespace != "" {
+		return invalid("metadata.namespace", "must be empty for a namespace")
+	}
+	if q := n.Spec.Quota; q != nil {
+		kinds := make([]string, 0, len(q.Counts))
+		for kind := range q.Counts {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			if !slices.Contains(NamespacedBuckets, kind) {
+				return invalid(fmt.Sprintf("spec.quota.counts[%s]", kind), "is not a namespaced kind")
+			}
+			if q.Counts[kind] < 0 {
+				return invalid(fmt.Sprintf("spec.quota.counts[%s]", kind), "must not be negative")
+			}
+		}
+		if q.Requests.CPUMillis < 0 {
+			return invalid("spec.quota.requests.cpu_millis", "must not be negative")
+		}
+		if q.Requests.MemoryBytes < 0 {
+			return invalid("spec.quota.requests.memory_bytes", "must not be negative")
+		}
+	}
+	return nil
+}
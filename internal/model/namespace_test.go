@@ -0,0 +1,58 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespace_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(n *Namespace)
+		field  string
+	}{
+		{"valid", func(*Namespace) {}, ""},
+		{"with quota", func(n *Namespace) {
+			n.Spec.Quota = &Quota{
+				Counts:   map[string]int{ContainersBucket: 10, SecretsBucket: 0},
+				Requests: Resources{CPUMillis: 4000},
+			}
+		}, ""},
+		{"bad name", func(n *Namespace) { n.Name = "Team_A" }, "metadata.name"},
+		{"namespaced", func(n *Namespace) { n.Namespace = DefaultNamespace }, "metadata.namespace"},
+		{"unknown kind", func(n *Namespace) {
+			n.Spec.Quota = &Quota{Counts: map[string]int{"nodes": 1}}
+		}, "spec.quota.counts[nodes]"},
+		{"negative count", func(n *Namespace) {
+			n.Spec.Quota = &Quota{Counts: map[string]int{JobsBucket: -1}}
+		}, "spec.quota.counts[jobs]"},
+		{"negative memory", func(n *Namespace) {
+			n.Spec.Quota = &Quota{Requests: Resources{MemoryBytes: -1}}
+		}, "spec.quota.requests.memory_bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &Namespace{ObjectMeta: ObjectMeta{Name: "team-a"}}
+			tt.mutate(n)
+			err := n.Validate()
+			if tt.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, tt.field, verr.Field)
+		})
+	}
+}
+
+func TestObjectMeta_Key(t *testing.T) {
+	assert.Equal(t, "node-1", (&ObjectMeta{Name: "node-1"}).Key())
+	assert.Equal(t, "team-a/web", (&ObjectMeta{Name: "web", Namespace: "team-a"}).Key())
+	assert.Equal(t, "team-a/", NamespacePrefix("team-a"))
+	assert.Empty(t, NamespacePrefix(""))
+}
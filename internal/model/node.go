@@ -0,0 +1,119 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// NodesBucket is the store bucket holding Node records keyed by name.
+const NodesBucket = "nodes"
+
+// NodePhase reports whether a node is able to run containers.
+type NodePhase string
+
+// Node phases.
+const (
+	NodeReady    NodePhase = "Ready"
+	NodeNotReady NodePhase = "NotReady"
+)
+
+// Node is a host that runs containers.
+type Node struct {
+	ObjectMeta `json:"metadata"`
+	Spec       NodeSpec   `json:"spec"`
+	Status     NodeStatus `json:"status"`
+}
+
+// NodeSpec is the declared configuration of a node.
+type NodeSpec struct {
+	// Address is where the node can be reached.
+	Address string `json:"address,omitempty"`
+
+	// Capacity is the total CPU and memory available to containers.
+	Capacity Resources `json:"capacity"`
+
+	// Taints repel containers that do not tolerate them.
+	Taints []Taint `json:"taints,omitempty"`
+
+	// Unschedulable marks a cordoned node: the scheduler places no new
+	// containers on it, but those already there keep running.
+	Unschedulable bool `json:"unschedulable,omitempty"`
+}
+
+// NodeStatus is the observed state of a node.
+type NodeStatus struct {
+	Phase           NodePhase `json:"phase"`
+	LastHeartbeatAt time.Time `json:"last_heartbeat_at"`
+
+	// Drain reports the progress of the latest drain, if any.
+	Drain *DrainStatus `json:"drain,omitempty"`
+}
+
+// DrainPhase is the state of a node drain.
+type DrainPhase string
+
+// Drain phases.
+const (
+	DrainInProgress DrainPhase = "Draining"
+	DrainComplete   DrainPhase = "Drained"
+	DrainFailed     DrainPhase = "Failed"
+)
+
+// DrainStatus reports the progress of evicting containers from a node.
+type DrainStatus struct {
+	Phase   DrainPhase `json:"phase"`
+	Message string     `json:"message,omitempty"`
+
+	// StartedAt is when the drain was requested, and Deadline when it
+	// fails if containers remain.
+	StartedAt time.Time `json:"started_at"`
+	Deadline  time.Time `json:"deadline"`
+
+	// CompletedAt is when the drain finished or failed.
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+
+	// Evicted counts the containers evicted so far.
+	Evicted int `json:"evicted"`
+
+	// Remaining lists the containers still to be evicted, by key.
+	Remaining []string `json:"remaining,omitempty"`
+}
+
+// SetDefaults fills in optional fields.
+func (n *Node) SetDefaults() {
+	if n.Status.Phase == "" {
+		n.Status.Phase = NodeNotReady
+	}
+}
+
+// Validate checks the node for errors.
+func (n *Node) Validate() error {
+	if err := ValidateName("metadata.name", n.Name); err != nil {
+		return err
+	}
+	if n.Spec.Capacity.CPUMillis <= 0 {
+		return invalid("spec.capacity.cpu_millis", "must be positive")
+	}
+	if n.Spec.Capacity.MemoryBytes <= 0 {
+		return invalid("spec.capacity.memory_bytes", "must be positive")
+	}
+	seen := make(map[string]bool, len(n.Spec.Taints))
+	for i := range n.Spec.Taints {
+		t := &n.Spec.Taints[i]
+		field := fmt.Sprintf("spec.taints[%d]", i)
+		if err := t.Validate(field); err != nil {
+			return err
+		}
+		id := t.Key + ":" + string(t.Effect)
+		if seen[id] {
+			return invalid(field, "duplicate taint %s", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// IsReady reports whether the node is accepting heartbeats.
+func (n *Node) IsReady() bool {
+	return n.Status.Phase == NodeReady
+}
@@ -0,0 +1,130 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+)
+
+// OwnerReference points at a resource in the same namespace that another
+// depends on, typically the controller that created it. Kind is the
+// owner's bucket, e.g. "deployments".
+type OwnerReference struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	UID  string `json:"uid"`
+
+	// Controller marks the owner that manages the resource. A resource has
+	// at most one.
+	Controller bool `json:"controller,omitempty"`
+
+	// BlockOwnerDeletion keeps a foreground deletion of the owner waiting
+	// until this resource is gone.
+	BlockOwnerDeletion bool `json:"block_owner_deletion,omitempty"`
+}
+
+// PropagationPolicy says what happens to the dependents of a deleted
+// resource.
+type PropagationPolicy string
+
+// Propagation policies.
+const (
+	// PropagationBackground deletes the owner at once and leaves its
+	// dependents to the garbage collector.
+	PropagationBackground PropagationPolicy = "Background"
+
+	// PropagationForeground marks the owner as terminating with
+	// FinalizerForeground, which the garbage collector clears once it has
+	// deleted the owner's dependents.
+	PropagationForeground PropagationPolicy = "Foreground"
+
+	// PropagationOrphan marks the owner as terminating with
+	// FinalizerOrphan, which the garbage collector clears once it has
+	// dropped its dependents' references to it, keeping the dependents.
+	PropagationOrphan PropagationPolicy = "Orphan"
+)
+
+// ParsePropagationPolicy parses a propagation policy; "" is Background.
+func ParsePropagationPolicy(s string) (PropagationPolicy, error) {
+	switch p := PropagationPolicy(s); p {
+	case "":
+		return PropagationBackground, nil
+	case PropagationBackground, PropagationForeground, PropagationOrphan:
+		return p, nil
+	default:
+		return "", invalid("propagation_policy", "must be %s, %s or %s",
+			PropagationBackground, PropagationForeground, PropagationOrphan)
+	}
+}
+
+// Finalizer returns the finalizer that holds up a deletion with the
+// policy, or "" for Background.
+func (p PropagationPolicy) Finalizer() string {
+	switch p {
+	case PropagationForeground:
+		return FinalizerForeground
+	case PropagationOrphan:
+		return FinalizerOrphan
+	default:
+		return ""
+	}
+}
+
+// NewControllerRef returns the reference a controller of kind puts on the
+// resources it creates.
+func NewControllerRef(kind string, owner *ObjectMeta) OwnerReference {
+	return OwnerReference{
+		Kind:               kind,
+		Name:               owner.Name,
+		UID:                owner.UID,
+		Controller:         true,
+		BlockOwnerDeletion: true,
+	}
+}
+
+// ControllerRef returns the reference to the resource's controller, or nil
+// if it has none.
+func (m *ObjectMeta) ControllerRef() *OwnerReference {
+	for i := range m.OwnerReferences {
+		if m.OwnerReferences[i].Controller {
+			return &m.OwnerReferences[i]
+		}
+	}
+	return nil
+}
+
+// IsControlledBy reports whether owner is the resource's controller.
+func (m *ObjectMeta) IsControlledBy(owner *ObjectMeta) bool {
+	ref := m.ControllerRef()
+	return ref != nil && ref.UID == owner.UID
+}
+
+// IsTerminating reports whether the resource has been deleted and waits
+// for its finalizers.
+func (m *ObjectMeta) IsTerminating() bool {
+	return m.DeletionTimestamp != nil
+}
+
+// ValidateOwnerReferences checks that the owner references name known
+// kinds of owner and that at most one is the controller.
+func (m *ObjectMeta) ValidateOwnerReferences() error {
+	controllers := 0
+	for i, ref := range m.OwnerReferences {
+		field := fmt.Sprintf("metadata.owner_references[%d]", i)
+		if !slices.Contains(NamespacedBuckets, ref.Kind) {
+			return invalid(field+".kind", "unknown kind %q", ref.Kind)
+		}
+		if err := ValidateName(field+".name", ref.Name); err != nil {
+			return err
+		}
+		if ref.UID == "" {
+			return invalid(field+".uid", "is required")
+		}
+		if ref.Controller {
+			controllers++
+			if controllers > 1 {
+				return invalid(field+".controller", "only one owner may be the controller")
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObjectMeta_ValidateOwnerReferences(t *testing.T) {
+	ref := OwnerReference{Kind: DeploymentsBucket, Name: "web", UID: "web-uid", Controller: true}
+	tests := []struct {
+		name  string
+		refs  []OwnerReference
+		field string
+	}{
+		{"none", nil, ""},
+		{"valid", []OwnerReference{ref, {Kind: JobsBucket, Name: "report", UID: "report-uid"}}, ""},
+		{"unknown kind", []OwnerReference{{Kind: "widgets", Name: "web", UID: "u"}}, "metadata.owner_references[0].kind"},
+		{"cluster-wide kind", []OwnerReference{{Kind: NodesBucket, Name: "a", UID: "u"}}, "metadata.owner_references[0].kind"},
+		{"bad name", []OwnerReference{{Kind: JobsBucket, Name: "Web", UID: "u"}}, "metadata.owner_references[0].name"},
+		{"no uid", []OwnerReference{{Kind: JobsBucket, Name: "web"}}, "metadata.owner_references[0].uid"},
+		{"two controllers", []OwnerReference{ref, ref}, "metadata.owner_references[1].controller"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &ObjectMeta{OwnerReferences: tt.refs}
+			err := m.ValidateOwnerReferences()
+			if tt.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, tt.field, verr.Field)
+		})
+	}
+}
+
+func TestParsePropagationPolicy(t *testing.T) {
+	p, err := ParsePropagationPolicy("")
+	require.NoError(t, err)
+	assert.Equal(t, PropagationBackground, p)
+
+	p, err = ParsePropagationPolicy("Orphan")
+	require.NoError(t, err)
+	assert.Equal(t, PropagationOrphan, p)
+
+	_, err = ParsePropagationPolicy("orphan")
+	assert.Error(t, err)
+}
+
+func TestObjectMeta_ControllerRef(t *testing.T) {
+	owner := &ObjectMeta{Name: "web", UID: "web-uid"}
+	m := &ObjectMeta{OwnerReferences: []OwnerReference{{Kind: JobsBucket, Name: "other", UID: "other-uid"}}}
+	assert.Nil(t, m.ControllerRef())
+	assert.False(t, m.IsControlledBy(owner))
+
+	m.OwnerReferences = append(m.OwnerReferences, NewControllerRef(DeploymentsBucket, owner))
+	assert.Equal(t, "web", m.ControllerRef().Name)
+	assert.True(t, m.IsControlledBy(owner))
+}
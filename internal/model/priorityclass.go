@@ -0,0 +1,64 @@
+package model
+
+// PriorityClassesBucket is the store bucket holding PriorityClass records
+// keyed by name.
+const PriorityClassesBucket = "priorityclasses"
+
+// MaxPriority bounds the value of a priority class in either direction.
+const MaxPriority = 1_000_000_000
+
+// PreemptionPolicy says whether a container may preempt others.
+type PreemptionPolicy string
+
+// Preemption policies.
+const (
+	PreemptLowerPriority PreemptionPolicy = "PreemptLowerPriority"
+	PreemptNever         PreemptionPolicy = "Never"
+)
+
+// PriorityClass names a scheduling priority. Containers refer to one by
+// name; pending containers are scheduled in order of priority, and one that
+// fits no node may preempt containers of lower priority. Priority classes
+// are cluster-wide.
+type PriorityClass struct {
+	ObjectMeta `json:"metadata"`
+
+	// Value is the priority. Higher values are scheduled first.
+	Value int `json:"value"`
+
+	// GlobalDefault makes this the class of containers that name none.
+	// If several classes are the default, the one with the highest value
+	// wins; without one, such containers have priority 0.
+	GlobalDefault bool `json:"global_default,omitempty"`
+
+	// PreemptionPolicy defaults to PreemptLowerPriority.
+	PreemptionPolicy PreemptionPolicy `json:"preemption_policy,omitempty"`
+
+	Description string `json:"description,omitempty"`
+}
+
+// SetDefaults fills in unset fields.
+func (p *PriorityClass) SetDefaults() {
+	if p.PreemptionPolicy == "" {
+		p.PreemptionPolicy = PreemptLowerPriority
+	}
+}
+
+// Validate checks the priority class for errors.
+func (p *PriorityClass) Validate() error {
+	if err := ValidateName("metadata.name", p.Name); err != nil {
+		return err
+	}
+	if p.Namespace != "" {
+		return invalid("metadata.namespace", "must be empty; priority classes are cluster-wide")
+	}
+	if p.Value < -MaxPriority || p.Value > MaxPriority {
+		return invalid("value", "must be between %d and %d", -MaxPriority, MaxPriority)
+	}
+	switch p.PreemptionPolicy {
+	case PreemptLowerPriority, PreemptNever:
+	default:
+		return invalid("preemption_policy", "must be PreemptLowerPriority or Never; got %q", p.PreemptionPolicy)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriorityClass_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(p *PriorityClass)
+		field  string
+	}{
+		{"valid", func(*PriorityClass) {}, ""},
+		{"never preempts", func(p *PriorityClass) { p.PreemptionPolicy = PreemptNever }, ""},
+		{"negative", func(p *PriorityClass) { p.Value = -MaxPriority }, ""},
+		{"bad name", func(p *PriorityClass) { p.Name = "High_Priority" }, "metadata.name"},
+		{"namespaced", func(p *PriorityClass) { p.Namespace = DefaultNamespace }, "metadata.namespace"},
+		{"too high", func(p *PriorityClass) { p.Value = MaxPriority + 1 }, "value"},
+		{"bad policy", func(p *PriorityClass) { p.PreemptionPolicy = "Sometimes" }, "preemption_policy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &PriorityClass{ObjectMeta: ObjectMeta{Name: "high"}, Value: 1000}
+			p.SetDefaults()
+			tt.mutate(p)
+			err := p.Validate()
+			if tt.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, tt.field, verr.Field)
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package model
+
+import "time"
+
+// Probe describes a periodic health check against a container.
+// Exactly one of HTTPGet, TCPSocket or Exec must be set.
+type Probe struct {
+	HTTPGet   *HTTPGetAction   `json:"http_get,omitempty"`
+	TCPSocket *TCPSocketAction `json:"tcp_socket,omitempty"`
+	Exec      *ExecAction      `json:"exec,omitempty"`
+
+	// InitialDelaySeconds is how long to wait after start before probing.
+	InitialDelaySeconds int `json:"initial_delay_seconds,omitempty"`
+
+	// PeriodSeconds is the probe interval. Zero uses the configured default.
+	PeriodSeconds int `json:"period_seconds,omitempty"`
+
+	// TimeoutSeconds bounds a single probe attempt. Defaults to 1.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+
+	// SuccessThreshold is the consecutive successes needed after a failure.
+	// Defaults to 1.
+	SuccessThreshold int `json:"success_threshold,omitempty"`
+
+	// FailureThreshold is the consecutive failures needed after a success.
+	// Defaults to 3.
+	FailureThreshold int `json:"failure_threshold,omitempty"`
+}
+
+// HTTPGetAction probes by issuing a GET request. Any 2xx or 3xx status
+// counts as success.
+type HTTPGetAction struct {
+	Path   string `json:"path,omitempty"`
+	Port   int    `json:"port"`
+	Host   string `json:"host,omitempty"`
+	Scheme string `json:"scheme,omitempty"`
+}
+
+// TCPSocketAction probes by opening a TCP connection.
+type TCPSocketAction struct {
+	Port int    `json:"port"`
+	Host string `json:"host,omitempty"`
+}
+
+// ExecAction probes by running a command inside the container.
+// A zero exit code counts as success.
+type ExecAction struct {
+	Command []string `json:"command"`
+}
+
+// ProbeResult is the persisted outcome of a container's probe.
+type ProbeResult struct {
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+
+	// LastTransitionAt is when Success last changed.
+	LastTransitionAt time.Time `json:"last_transition_at"`
+}
+
+// Validate checks the probe for errors.
+func (p *Probe) Validate(field string) error {
+	handlers := 0
+	if p.HTTPGet != nil {
+		handlers++
+		if p.HTTPGet.Port < 1 || p.HTTPGet.Port > 65535 {
+			return invalid(field+".http_get.port", "must be between 1 and 65535")
+		}
+		if p.HTTPGet.Scheme != "" && p.HTTPGet.Scheme != "HTTP" && p.HTTPGet.Scheme != "HTTPS" {
+			return invalid(field+".http_get.scheme", "must be HTTP or HTTPS")
+		}
+	}
+	if p.TCPSocket != nil {
+		handlers++
+		if p.TCPSocket.Port < 1 || p.TCPSocket.Port > 65535 {
+			return invalid(field+".tcp_socket.port", "must be between 1 and 65535")
+		}
+	}
+	if p.Exec != nil {
+		handlers++
+		if len(p.Exec.Command) == 0 {
+			return invalid(field+".exec.command", "is required")
+		}
+	}
+	if handlers != 1 {
+		return invalid(field, "must specify exactly one of http_get, tcp_socket, exec")
+	}
+
+	if p.InitialDelaySeconds < 0 {
+		return invalid(field+".initial_delay_seconds", "must not be negative")
+	}
+	if p.PeriodSeconds < 0 {
+		return invalid(field+".period_seconds", "must not be negative")
+	}
+	if p.TimeoutSeconds < 0 {
+		return invalid(field+".timeout_seconds", "must not be negative")
+	}
+	if p.SuccessThreshold < 0 {
+		return invalid(field+".success_threshold", "must not be negative")
+	}
+	if p.FailureThreshold < 0 {
+		return invalid(field+".failure_threshold", "must not be negative")
+	}
+	return nil
+}
+
+// Timeout returns the per-attempt timeout.
+func (p *Probe) Timeout() time.Duration {
+	if p.TimeoutSeconds == 0 {
+		return time.Second
+	}
+	return time.Duration(p.TimeoutSeconds) * time.Second
+}
+
+// Period returns the probe interval, falling back to def.
+func (p *Probe) Period(def time.Duration) time.Duration {
+	if p.PeriodSeconds == 0 {
+		return def
+	}
+	return time.Duration(p.PeriodSeconds) * time.Second
+}
+
+// InitialDelay returns the delay before the first probe.
+func (p *Probe) InitialDelay() time.Duration {
+	return time.Duration(p.InitialDelaySeconds) * time.Second
+}
+
+// Successes returns the success threshold.
+func (p *Probe) Successes() int {
+	if p.SuccessThreshold == 0 {
+		return 1
+	}
+	return p.SuccessThreshold
+}
+
+// Failures returns the failure threshold.
+func (p *Probe) Failures() int {
+	if p.FailureThreshold == 0 {
+		return 3
+	}
+	return p.FailureThreshold
+}
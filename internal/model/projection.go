@@ -0,0 +1,155 @@
+package model
+
+import "fmt"
+
+// FileUpdatePolicy selects how a file mount picks up a changed source.
+type FileUpdatePolicy string
+
+// File update policies.
+const (
+	// FileUpdateRestart recreates the container with the new files.
+	FileUpdateRestart FileUpdatePolicy = "Restart"
+
+	// FileUpdateHot rewrites the files in place; the running process has
+	// to notice the change itself.
+	FileUpdateHot FileUpdatePolicy = "HotUpdate"
+)
+
+// EnvVarSource sets an environment variable from a key of a secret or a
+// config map. Exactly one source must be set.
+type EnvVarSource struct {
+	// Name is the environment variable to set.
+	Name string `json:"name"`
+
+	SecretKeyRef    *KeySelector `json:"secret_key_ref,omitempty"`
+	ConfigMapKeyRef *KeySelector `json:"config_map_key_ref,omitempty"`
+}
+
+// KeySelector picks one key of a named resource.
+type KeySelector struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+// FileMount projects the keys of a secret or a config map into a
+// directory, one read-only file per key. Exactly one source must be set.
+type FileMount struct {
+	// MountPath is the absolute path of the directory inside the container.
+	MountPath string `json:"mount_path"`
+
+	Secret    string `json:"secret,omitempty"`
+	ConfigMap string `json:"config_map,omitempty"`
+
+	// Keys limits the projection to some keys. All keys are projected when
+	// empty.
+	Keys []string `json:"keys,omitempty"`
+
+	// UpdatePolicy says how a change to the source reaches the container.
+	// Defaults to Restart.
+	UpdatePolicy FileUpdatePolicy `json:"update_policy,omitempty"`
+}
+
+// Validate checks the reference for errors.
+func (e *EnvVarSource) Validate(field string) error {
+	if e.Name == "" {
+		return invalid(field+".name", "is required")
+	}
+	switch {
+	case e.SecretKeyRef != nil && e.ConfigMapKeyRef != nil:
+		return invalid(field, "must set only one of secret_key_ref and config_map_key_ref")
+	case e.SecretKeyRef != nil:
+		return e.SecretKeyRef.Validate(field + ".secret_key_ref")
+	case e.ConfigMapKeyRef != nil:
+		return e.ConfigMapKeyRef.Validate(field + ".config_map_key_ref")
+	default:
+		return invalid(field, "must set one of secret_key_ref and config_map_key_ref")
+	}
+}
+
+// Validate checks the selector for errors.
+func (k *KeySelector) Validate(field string) error {
+	if err := ValidateName(field+".name", k.Name); err != nil {
+		return err
+	}
+	return validateKey(field+".key", k.Key)
+}
+
+// Validate checks the mount for errors.
+func (m *FileMount) Validate(field string) error {
+	if err := validateMountPath(field+".mount_path", m.MountPath); err != nil {
+		return err
+	}
+	switch {
+	case m.Secret != "" && m.ConfigMap != "":
+		return invalid(field, "must set only one of secret and config_map")
+	case m.Secret != "":
+		if err := ValidateName(field+".secret", m.Secret); err != nil {
+			return err
+		}
+	case m.ConfigMap != "":
+		if err := ValidateName(field+".config_map", m.ConfigMap); err != nil {
+			return err
+		}
+	default:
+		return invalid(field, "must set one of secret and config_map")
+	}
+	for i, k := range m.Keys {
+		if err := validateKey(fmt.Sprintf("%s.keys[%d]", field, i), k); err != nil {
+			return err
+		}
+	}
+	switch m.UpdatePolicy {
+	case "", FileUpdateRestart, FileUpdateHot:
+	default:
+		return invalid(field+".update_policy", "must be Restart or HotUpdate; got %q", m.UpdatePolicy)
+	}
+	return nil
+}
+
+// SetDefaults fills in unset fields.
+func (m *FileMount) SetDefaults() {
+	if m.UpdatePolicy == "" {
+		m.UpdatePolicy = FileUpdateRestart
+	}
+}
+
+// SecretNames returns the secrets referenced by the spec.
+func (s *ContainerSpec) SecretNames() []string {
+	var names []string
+	for _, e := range s.EnvFrom {
+		if e.SecretKeyRef != nil {
+			names = appendUnique(names, e.SecretKeyRef.Name)
+		}
+	}
+	for _, m := range s.FileMounts {
+		if m.Secret != "" {
+			names = appendUnique(names, m.Secret)
+		}
+	}
+	return names
+}
+
+// ConfigMapNames returns the config maps referenced by the spec.
+func (s *ContainerSpec) ConfigMapNames() []string {
+	var names []string
+	for _, e := range s.EnvFrom {
+		if e.ConfigMapKeyRef != nil {
+			names = appendUnique(names, e.ConfigMapKeyRef.Name)
+		}
+	}
+	for _, m := range s.FileMounts {
+		if m.ConfigMap != "" {
+			names = appendUnique(names, m.ConfigMap)
+		}
+	}
+	return names
+}
+
+func appendUnique(names []string, name string) []string {
+	for _, n := range names {
+		if n == name {
+			return names
+		}
+	}
+	return append(names, name)
+}
@@ -0,0 +1,36 @@
+package model
+
+import "fmt"
+
+// RevisionsBucket holds deployment Revision records keyed by
+// RevisionKey(namespace, deployment, revision).
+const RevisionsBucket = "revisions"
+
+// DefaultRevisionHistoryLimit is how many revisions a deployment retains
+// when its spec does not say.
+const DefaultRevisionHistoryLimit = 10
+
+// Revision is an immutable snapshot of a deployment template. One is
+// recorded each time the template changes, including on rollback.
+type Revision struct {
+	ObjectMeta `json:"metadata"`
+
+	// Deployment and DeploymentUID identify the owning deployment.
+	Deployment    string `json:"deployment"`
+	DeploymentUID string `json:"deployment_uid"`
+
+	Revision     int64             `json:"revision"`
+	TemplateHash string            `json:"template_hash"`
+	Template     ContainerTemplate `json:"template"`
+}
+
+// RevisionKey returns the store key of a revision. Revisions are zero-padded
+// so that keys sort in revision order.
+func RevisionKey(namespace, deployment string, revision int64) string {
+	return fmt.Sprintf("%s%010d", RevisionPrefix(namespace, deployment), revision)
+}
+
+// RevisionPrefix returns the key prefix shared by a deployment's revisions.
+func RevisionPrefix(namespace, deployment string) string {
+	return Key(namespace, deployment) + "/"
+}
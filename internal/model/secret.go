@@ -0,0 +1,87 @@
+package model
+
+import (
+	"regexp"
+	"sort"
+)
+
+// SecretsBucket holds Secret records keyed by namespace and name.
+const SecretsBucket = "secrets"
+
+// MaxSecretSize bounds the total size of a secret's keys and values.
+const MaxSecretSize = 1 << 20
+
+// RedactedValue replaces secret values in API responses.
+const RedactedValue = "REDACTED"
+
+// keyRE matches keys usable both as file names and environment values.
+var keyRE = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+// Secret holds sensitive key/value data such as passwords and tokens.
+// Values are encrypted at rest and only returned by the API on request.
+type Secret struct {
+	ObjectMeta `json:"metadata"`
+
+	// Data maps keys to values. It is empty in the store, where the values
+	// are kept encrypted in Sealed.
+	Data map[string]string `json:"data,omitempty"`
+
+	// Sealed is Data encrypted at rest. The API never returns it.
+	Sealed []byte `json:"sealed,omitempty"`
+
+	// Version increases whenever Data changes. Containers using the secret
+	// are recreated once it moves past the version they started with.
+	Version int64 `json:"version"`
+}
+
+// SetDefaults fills in unset fields.
+func (s *Secret) SetDefaults() {}
+
+// Validate checks the secret for errors.
+func (s *Secret) Validate() error {
+	if err := ValidateName("metadata.name", s.Name); err != nil {
+		return err
+	}
+	return validateData("data", s.Data, MaxSecretSize)
+}
+
+// Keys returns the keys of Data in order.
+func (s *Secret) Keys() []string {
+	keys := make([]string, 0, len(s.Data))
+	for k := range s.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Redact replaces every value with RedactedValue and drops the sealed data.
+func (s *Secret) Redact() {
+	for k := range s.Data {
+		s.Data[k] = RedactedValue
+	}
+	s.Sealed = nil
+}
+
+// validateData checks keys and the total size of a key/value map.
+func validateData(field string, data map[string]string, limit int) error {
+	size := 0
+	for k, v := range data {
+		if !keyRE.MatchString(k) || k == "." || k == ".." {
+			return invalid(field, "key %q must consist of alphanumerics, '-', '_' and '.'", k)
+		}
+		size += len(k) + len(v)
+	}
+	if size > limit {
+		return invalid(field, "must be at most %d bytes in total; got %d", limit, size)
+	}
+	return nil
+}
+
+// validateKey checks a reference to a single key.
+func validateKey(field, key string) error {
+	if !keyRE.MatchString(key) || key == "." || key == ".." {
+		return invalid(field, "must consist of alphanumerics, '-', '_' and '.'")
+	}
+	return nil
+}
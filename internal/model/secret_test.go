@@ -0,0 +1,47 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecret_Validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  map[string]string
+		field string
+	}{
+		{"valid", map[string]string{"password": "x", "tls.crt": "y", "API_TOKEN": "z"}, ""},
+		{"empty", nil, ""},
+		{"slash in key", map[string]string{"a/b": "x"}, "data"},
+		{"dot key", map[string]string{"..": "x"}, "data"},
+		{"too large", map[string]string{"blob": strings.Repeat("x", MaxSecretSize)}, "data"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Secret{ObjectMeta: ObjectMeta{Name: "db"}, Data: tt.data}
+			err := s.Validate()
+			if tt.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, tt.field, verr.Field)
+		})
+	}
+}
+
+func TestSecret_Redact(t *testing.T) {
+	s := &Secret{Data: map[string]string{"b": "2", "a": "1"}, Sealed: []byte("x")}
+	assert.Equal(t, []string{"a", "b"}, s.Keys())
+
+	s.Redact()
+	assert.Equal(t, map[string]string{"a": RedactedValue, "b": RedactedValue}, s.Data)
+	assert.Nil(t, s.Sealed)
+}
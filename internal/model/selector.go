@@ -0,0 +1,235 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// setTermRE matches set-based selector terms such as "env in (a, b)".
+var setTermRE = regexp.MustCompile(`^([^\s!=(),]+)\s+(in|notin)\s*\(([^()]*)\)$`)
+
+// SelectorOperator relates a label key to a set of values.
+type SelectorOperator string
+
+// Selector operators. Gt and Lt compare integer values and are only valid
+// in node affinity.
+const (
+	OpIn           SelectorOperator = "In"
+	OpNotIn        SelectorOperator = "NotIn"
+	OpExists       SelectorOperator = "Exists"
+	OpDoesNotExist SelectorOperator = "DoesNotExist"
+	OpGt           SelectorOperator = "Gt"
+	OpLt           SelectorOperator = "Lt"
+)
+
+// SelectorRequirement is a single condition on a label.
+type SelectorRequirement struct {
+	Key      string           `json:"key"`
+	Operator SelectorOperator `json:"operator"`
+	Values   []string         `json:"values,omitempty"`
+}
+
+// LabelSelector matches labels that satisfy every MatchLabels entry and
+// every MatchExpressions requirement. An empty selector matches everything.
+type LabelSelector struct {
+	MatchLabels      map[string]string     `json:"match_labels,omitempty"`
+	MatchExpressions []SelectorRequirement `json:"match_expressions,omitempty"`
+}
+
+// Matches reports whether labels satisfy the requirement.
+func (r *SelectorRequirement) Matches(labels map[string]string) bool {
+	v, ok := labels[r.Key]
+	switch r.Operator {
+	case OpIn:
+		return ok && contains(r.Values, v)
+	case OpNotIn:
+		return !ok || !contains(r.Values, v)
+	case OpExists:
+		return ok
+	case OpDoesNotExist:
+		return !ok
+	case OpGt, OpLt:
+		if !ok || len(r.Values) != 1 {
+			return false
+		}
+		have, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return false
+		}
+		want, err := strconv.ParseInt(r.Values[0], 10, 64)
+		if err != nil {
+			return false
+		}
+		if r.Operator == OpGt {
+			return have > want
+		}
+		return have < want
+	default:
+		return false
+	}
+}
+
+// Validate checks the requirement. allowNumeric permits Gt and Lt.
+func (r *SelectorRequirement) Validate(field string, allowNumeric bool) error {
+	if r.Key == "" {
+		return invalid(field+".key", "is required")
+	}
+	switch r.Operator {
+	case OpIn, OpNotIn:
+		if len(r.Values) == 0 {
+			return invalid(field+".values", "must not be empty for operator %s", r.Operator)
+		}
+	case OpExists, OpDoesNotExist:
+		if len(r.Values) != 0 {
+			return invalid(field+".values", "must be empty for operator %s", r.Operator)
+		}
+	case OpGt, OpLt:
+		if !allowNumeric {
+			return invalid(field+".operator", "%s is only allowed in node affinity", r.Operator)
+		}
+		if len(r.Values) != 1 {
+			return invalid(field+".values", "must have exactly one value for operator %s", r.Operator)
+		}
+		if _, err := strconv.ParseInt(r.Values[0], 10, 64); err != nil {
+			return invalid(field+".values", "must be an integer for operator %s", r.Operator)
+		}
+	default:
+		return invalid(field+".operator", "unknown operator %q", r.Operator)
+	}
+	return nil
+}
+
+// Matches reports whether labels satisfy the selector.
+func (s *LabelSelector) Matches(labels map[string]string) bool {
+	for k, v := range s.MatchLabels {
+		if labels[k] != v {
+			return false
+		}
+	}
+	for i := range s.MatchExpressions {
+		if !s.MatchExpressions[i].Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsEmpty reports whether the selector has no conditions.
+func (s *LabelSelector) IsEmpty() bool {
+	return len(s.MatchLabels) == 0 && len(s.MatchExpressions) == 0
+}
+
+// Validate checks the selector for errors.
+func (s *LabelSelector) Validate(field string) error {
+	for i := range s.MatchExpressions {
+		if err := s.MatchExpressions[i].Validate(fmt.Sprintf("%s.match_expressions[%d]", field, i), false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseSelector parses the selector language of list queries into
+// requirements that must all hold. Terms are separated by commas:
+//
+//	key=value, key==value  the key has the value
+//	key!=value             the key is missing or has another value
+//	key in (v1, v2)        the key has one of the values
+//	key notin (v1, v2)     the key is missing or has none of the values
+//	key                    the key exists
+//	!key                   the key does not exist
+//
+// An empty string yields no requirements.
+func ParseSelector(s string) ([]SelectorRequirement, error) {
+	var reqs []SelectorRequirement
+	for _, term := range splitTerms(s) {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			return nil, fmt.Errorf("empty term in selector %q", s)
+		}
+		req, err := parseTerm(term)
+		if err != nil {
+			return nil, err
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs, nil
+}
+
+func parseTerm(term string) (SelectorRequirement, error) {
+	if m := setTermRE.FindStringSubmatch(term); m != nil {
+		op := OpIn
+		if m[2] == "notin" {
+			op = OpNotIn
+		}
+		var values []string
+		for _, v := range strings.Split(m[3], ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		if len(values) == 0 {
+			return SelectorRequirement{}, fmt.Errorf("selector term %q has no values", term)
+		}
+		return SelectorRequirement{Key: m[1], Operator: op, Values: values}, nil
+	}
+
+	var req SelectorRequirement
+	switch {
+	case strings.HasPrefix(term, "!") && !strings.Contains(term, "="):
+		req = SelectorRequirement{Key: strings.TrimSpace(term[1:]), Operator: OpDoesNotExist}
+	case strings.Contains(term, "!="):
+		k, v, _ := strings.Cut(term, "!=")
+		req = SelectorRequirement{Key: strings.TrimSpace(k), Operator: OpNotIn, Values: []string{strings.TrimSpace(v)}}
+	case strings.Contains(term, "="):
+		k, v, _ := strings.Cut(term, "=")
+		v = strings.TrimPrefix(v, "=")
+		req = SelectorRequirement{Key: strings.TrimSpace(k), Operator: OpIn, Values: []string{strings.TrimSpace(v)}}
+	default:
+		req = SelectorRequirement{Key: term, Operator: OpExists}
+	}
+	if req.Key == "" || strings.ContainsAny(req.Key, " \t!=(),") {
+		return SelectorRequirement{}, fmt.Errorf("invalid selector term %q", term)
+	}
+	for _, v := range req.Values {
+		if strings.ContainsAny(v, "!=()") {
+			return SelectorRequirement{}, fmt.Errorf("invalid selector term %q", term)
+		}
+	}
+	return req, nil
+}
+
+// splitTerms splits a selector on the commas that are not inside a value
+// list.
+func splitTerms(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	var terms []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(terms, s[start:])
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSelector(t *testing.T) {
+	reqs, err := ParseSelector("app=web, tier==api,env!=dev,zone in (a, b),track notin (canary),gpu,!legacy")
+	require.NoError(t, err)
+	assert.Equal(t, []SelectorRequirement{
+		{Key: "app", Operator: OpIn, Values: []string{"web"}},
+		{Key: "tier", Operator: OpIn, Values: []string{"api"}},
+		{Key: "env", Operator: OpNotIn, Values: []string{"dev"}},
+		{Key: "zone", Operator: OpIn, Values: []string{"a", "b"}},
+		{Key: "track", Operator: OpNotIn, Values: []string{"canary"}},
+		{Key: "gpu", Operator: OpExists},
+		{Key: "legacy", Operator: OpDoesNotExist},
+	}, reqs)
+
+	// An empty value matches a label set to the empty string.
+	reqs, err = ParseSelector("status.reason=")
+	require.NoError(t, err)
+	assert.Equal(t, []SelectorRequirement{{Key: "status.reason", Operator: OpIn, Values: []string{""}}}, reqs)
+
+	reqs, err = ParseSelector("")
+	require.NoError(t, err)
+	assert.Empty(t, reqs)
+
+	for _, bad := range []string{"app=web,", "=web", "zone in ()", "zone in (a", "a b", "app=x=y", "!"} {
+		_, err := ParseSelector(bad)
+		assert.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,194 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// StatefulSetsBucket holds StatefulSet records keyed by namespace and name.
+const StatefulSetsBucket = "statefulsets"
+
+const (
+	// LabelStatefulSet names the stateful set that created a container or
+	// volume.
+	LabelStatefulSet = "orchestrator.statefulset"
+
+	// LabelOrdinal holds the ordinal of a stateful set replica.
+	LabelOrdinal = "orchestrator.ordinal"
+)
+
+// maxStatefulSetNameLength leaves room in replica and volume names for the
+// ordinal suffix.
+const maxStatefulSetNameLength = 52
+
+// StatefulSetUpdateStrategyType selects how replicas are replaced when the
+// template changes.
+type StatefulSetUpdateStrategyType string
+
+// Stateful set update strategies.
+const (
+	// StatefulSetRollingUpdate replaces replicas one at a time from the
+	// highest ordinal down to the partition, waiting for each to be ready.
+	StatefulSetRollingUpdate StatefulSetUpdateStrategyType = "RollingUpdate"
+
+	// StatefulSetOnDelete only uses the new template for replicas created
+	// after the old ones are deleted by hand.
+	StatefulSetOnDelete StatefulSetUpdateStrategyType = "OnDelete"
+)
+
+// StatefulSet runs replicas with stable identities. Replica i is always
+// named <name>-<i> and mounts its own volumes, created from the volume claim
+// templates and kept across restarts, rescheduling and scale-down. Replicas
+// are started in ordinal order, each once all lower ones are ready, and
+// removed in reverse order.
+type StatefulSet struct {
+	ObjectMeta `json:"metadata"`
+	Spec       StatefulSetSpec   `json:"spec"`
+	Status     StatefulSetStatus `json:"status"`
+}
+
+// StatefulSetSpec is the desired state of a stateful set.
+type StatefulSetSpec struct {
+	Replicas int               `json:"replicas"`
+	Template ContainerTemplate `json:"template"`
+
+	// VolumeClaimTemplates describe the volumes each replica gets. Replica
+	// i of set web mounts volume <claim>-web-<i>.
+	VolumeClaimTemplates []VolumeClaimTemplate `json:"volume_claim_templates,omitempty"`
+
+	UpdateStrategy StatefulSetUpdateStrategy `json:"update_strategy"`
+}
+
+// VolumeClaimTemplate describes a per-replica volume.
+type VolumeClaimTemplate struct {
+	Name          string `json:"name"`
+	MountPath     string `json:"mount_path"`
+	CapacityBytes int64  `json:"capacity_bytes,omitempty"`
+}
+
+// StatefulSetUpdateStrategy configures how a template change is rolled out.
+type StatefulSetUpdateStrategy struct {
+	Type StatefulSetUpdateStrategyType `json:"type,omitempty"`
+
+	// Partition holds back a rolling update: only replicas with an ordinal
+	// at or above it are updated, and lower ones are recreated from the
+	// current revision. Zero updates every replica.
+	Partition int `json:"partition,omitempty"`
+}
+
+// StatefulSetStatus is the observed state of a stateful set.
+type StatefulSetStatus struct {
+	Replicas      int `json:"replicas"`
+	ReadyReplicas int `json:"ready_replicas"`
+
+	// CurrentReplicas counts replicas on CurrentRevision and
+	// UpdatedReplicas those on UpdateRevision.
+	CurrentReplicas int `json:"current_replicas"`
+	UpdatedReplicas int `json:"updated_replicas"`
+
+	// CurrentRevision is the template hash every replica last ran, and
+	// UpdateRevision the hash of the spec's template. They differ while an
+	// update is in progress or held back by the partition.
+	CurrentRevision string `json:"current_revision,omitempty"`
+	UpdateRevision  string `json:"update_revision,omitempty"`
+
+	// CurrentTemplate is the template of CurrentRevision, used to recreate
+	// replicas below the partition.
+	CurrentTemplate *ContainerTemplate `json:"current_template,omitempty"`
+}
+
+// ReplicaName returns the name of the replica with the given ordinal.
+func (s *StatefulSet) ReplicaName(ordinal int) string {
+	return s.Name + "-" + strconv.Itoa(ordinal)
+}
+
+// VolumeName returns the name of the claim's volume for the replica with
+// the given ordinal.
+func (s *StatefulSet) VolumeName(claim string, ordinal int) string {
+	return fmt.Sprintf("%s-%s-%d", claim, s.Name, ordinal)
+}
+
+// SetDefaults fills in unset fields.
+func (s *StatefulSet) SetDefaults() {
+	if s.Spec.UpdateStrategy.Type == "" {
+		s.Spec.UpdateStrategy.Type = StatefulSetRollingUpdate
+	}
+	s.Spec.Template.Spec.SetDefaults()
+}
+
+// Validate checks the stateful set for errors.
+func (s *StatefulSet) Validate() error {
+	if err := ValidateName("metadata.name", s.Name); err != nil {
+		return err
+	}
+	if len(s.Name) > maxStatefulSetNameLength {
+		return invalid("metadata.name", "must be at most %d characters", maxStatefulSetNameLength)
+	}
+	if err := s.Spec.Validate("spec"); err != nil {
+		return err
+	}
+	for i, c := range s.Spec.VolumeClaimTemplates {
+		if len(c.Name)+1+len(s.Name) > maxStatefulSetNameLength {
+			return invalid(fmt.Sprintf("spec.volume_claim_templates[%d].name", i),
+				"combined with the set name must be at most %d characters", maxStatefulSetNameLength-1)
+		}
+	}
+	return nil
+}
+
+// Validate checks the spec for errors.
+func (s *StatefulSetSpec) Validate(field string) error {
+	if s.Replicas < 0 {
+		return invalid(field+".replicas", "must not be negative")
+	}
+	if err := s.Template.Spec.Validate(field + ".template.spec"); err != nil {
+		return err
+	}
+	if s.Template.Spec.RestartPolicy != RestartAlways {
+		return invalid(field+".template.spec.restart_policy", "must be Always for stateful sets")
+	}
+	if s.Template.Spec.NodeName != "" {
+		return invalid(field+".template.spec.node_name", "must not be set for stateful sets")
+	}
+
+	mountPaths := make(map[string]bool)
+	for _, m := range s.Template.Spec.VolumeMounts {
+		mountPaths[m.MountPath] = true
+	}
+	for _, m := range s.Template.Spec.FileMounts {
+		mountPaths[m.MountPath] = true
+	}
+	claims := make(map[string]bool, len(s.VolumeClaimTemplates))
+	for i, c := range s.VolumeClaimTemplates {
+		cf := fmt.Sprintf("%s.volume_claim_templates[%d]", field, i)
+		if err := ValidateName(cf+".name", c.Name); err != nil {
+			return err
+		}
+		if claims[c.Name] {
+			return invalid(cf+".name", "duplicate claim %s", c.Name)
+		}
+		claims[c.Name] = true
+		if err := validateMountPath(cf+".mount_path", c.MountPath); err != nil {
+			return err
+		}
+		if mountPaths[c.MountPath] {
+			return invalid(cf+".mount_path", "duplicate mount path %s", c.MountPath)
+		}
+		mountPaths[c.MountPath] = true
+		if c.CapacityBytes < 0 {
+			return invalid(cf+".capacity_bytes", "must not be negative")
+		}
+	}
+
+	switch s.UpdateStrategy.Type {
+	case StatefulSetRollingUpdate:
+		if s.UpdateStrategy.Partition < 0 {
+			return invalid(field+".update_strategy.partition", "must not be negative")
+		}
+		return nil
+	case StatefulSetOnDelete:
+		return nil
+	default:
+		return invalid(field+".update_strategy.type", "must be RollingUpdate or OnDelete; got %q", s.UpdateStrategy.Type)
+	}
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatefulSet_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(s *StatefulSet)
+		field  string
+	}{
+		{"valid", func(*StatefulSet) {}, ""},
+		{"on delete", func(s *StatefulSet) { s.Spec.UpdateStrategy = StatefulSetUpdateStrategy{Type: StatefulSetOnDelete} }, ""},
+		{"long name", func(s *StatefulSet) { s.Name = strings.Repeat("a", 53) }, "metadata.name"},
+		{"negative replicas", func(s *StatefulSet) { s.Spec.Replicas = -1 }, "spec.replicas"},
+		{
+			"restart policy",
+			func(s *StatefulSet) { s.Spec.Template.Spec.RestartPolicy = RestartNever },
+			"spec.template.spec.restart_policy",
+		},
+		{"node name", func(s *StatefulSet) { s.Spec.Template.Spec.NodeName = "node-1" }, "spec.template.spec.node_name"},
+		{
+			"duplicate claim",
+			func(s *StatefulSet) {
+				s.Spec.VolumeClaimTemplates = append(s.Spec.VolumeClaimTemplates,
+					VolumeClaimTemplate{Name: "data", MountPath: "/logs"})
+			},
+			"spec.volume_claim_templates[1].name",
+		},
+		{
+			"relative claim mount path",
+			func(s *StatefulSet) { s.Spec.VolumeClaimTemplates[0].MountPath = "data" },
+			"spec.volume_claim_templates[0].mount_path",
+		},
+		{
+			"claim mount path used by template",
+			func(s *StatefulSet) {
+				s.Spec.Template.Spec.VolumeMounts = []VolumeMount{{Name: "shared", MountPath: "/var/lib/db"}}
+			},
+			"spec.volume_claim_templates[0].mount_path",
+		},
+		{
+			"long claim name",
+			func(s *StatefulSet) { s.Spec.VolumeClaimTemplates[0].Name = strings.Repeat("d", 50) },
+			"spec.volume_claim_templates[0].name",
+		},
+		{
+			"negative partition",
+			func(s *StatefulSet) { s.Spec.UpdateStrategy.Partition = -1 },
+			"spec.update_strategy.partition",
+		},
+		{"unknown strategy", func(s *StatefulSet) { s.Spec.UpdateStrategy.Type = "Recreate" }, "spec.update_strategy.type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &StatefulSet{
+				ObjectMeta: ObjectMeta{Name: "db"},
+				Spec: StatefulSetSpec{
+					Replicas:             3,
+					Template:             ContainerTemplate{Spec: ContainerSpec{Image: "postgres"}},
+					VolumeClaimTemplates: []VolumeClaimTemplate{{Name: "data", MountPath: "/var/lib/db"}},
+				},
+			}
+			s.SetDefaults()
+			tt.mutate(s)
+
+			err := s.Validate()
+			if tt.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, tt.field, verr.Field)
+		})
+	}
+}
+
+func TestStatefulSet_Names(t *testing.T) {
+	s := &StatefulSet{ObjectMeta: ObjectMeta{Name: "db"}}
+	assert.Equal(t, "db-0", s.ReplicaName(0))
+	assert.Equal(t, "data-db-2", s.VolumeName("data", 2))
+}
@@ -0,0 +1,149 @@
+package model
+
+import (
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// VolumesBucket holds Volume records keyed by namespace and name.
+const VolumesBucket = "volumes"
+
+var driverRE = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// Volume drivers built into the orchestrator.
+const (
+	// VolumeDriverLocal keeps the data in a directory managed by the node.
+	VolumeDriverLocal = "local"
+
+	// VolumeDriverHostPath mounts an existing directory of the node.
+	VolumeDriverHostPath = "hostpath"
+)
+
+// Volume is persistent storage that outlives the containers mounting it.
+// A volume lives on one node, either the one it is pinned to or the one
+// where it was first scheduled; containers that mount it must run there.
+type Volume struct {
+	ObjectMeta `json:"metadata"`
+	Spec       VolumeSpec   `json:"spec"`
+	Status     VolumeStatus `json:"status"`
+}
+
+// VolumeSpec is the desired state of a volume.
+type VolumeSpec struct {
+	// Driver provides the storage. Defaults to local.
+	Driver string `json:"driver,omitempty"`
+
+	// HostPath is the directory a hostpath volume mounts. It must exist on
+	// the node.
+	HostPath string `json:"host_path,omitempty"`
+
+	// NodeName pins the volume to a node. Unpinned volumes are bound to the
+	// node of the first container scheduled with them.
+	NodeName string `json:"node_name,omitempty"`
+
+	// CapacityBytes is the requested size. Zero means unbounded. It is
+	// reported against usage but not enforced.
+	CapacityBytes int64 `json:"capacity_bytes,omitempty"`
+}
+
+// VolumeStatus is the observed state of a volume.
+type VolumeStatus struct {
+	// NodeName is the node holding the volume's data, empty until first
+	// mounted.
+	NodeName string     `json:"node_name,omitempty"`
+	BoundAt  *time.Time `json:"bound_at,omitempty"`
+
+	// Usage is measured periodically by the node holding the volume.
+	Usage *VolumeUsage `json:"usage,omitempty"`
+}
+
+// VolumeUsage reports the space a volume takes up.
+type VolumeUsage struct {
+	// UsedBytes is the size of the files in the volume.
+	UsedBytes int64 `json:"used_bytes"`
+
+	// CapacityBytes is the requested capacity, or the size of the
+	// filesystem holding an unbounded volume.
+	CapacityBytes int64 `json:"capacity_bytes"`
+
+	// AvailableBytes is what is left of the capacity, bounded by the free
+	// space of the filesystem.
+	AvailableBytes int64 `json:"available_bytes"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// VolumeMount mounts a volume into a container.
+type VolumeMount struct {
+	// Name is the volume to mount.
+	Name string `json:"name"`
+
+	// MountPath is the absolute path of the mount inside the container.
+	MountPath string `json:"mount_path"`
+
+	ReadOnly bool `json:"read_only,omitempty"`
+}
+
+// SetDefaults fills in unset fields.
+func (v *Volume) SetDefaults() {
+	if v.Spec.Driver == "" {
+		v.Spec.Driver = VolumeDriverLocal
+	}
+}
+
+// Validate checks the volume for errors.
+func (v *Volume) Validate() error {
+	if err := ValidateName("metadata.name", v.Name); err != nil {
+		return err
+	}
+	if v.Spec.CapacityBytes < 0 {
+		return invalid("spec.capacity_bytes", "must not be negative")
+	}
+	if v.Spec.NodeName != "" {
+		if err := ValidateName("spec.node_name", v.Spec.NodeName); err != nil {
+			return err
+		}
+	}
+	if v.Spec.Driver == VolumeDriverHostPath {
+		if err := validateMountPath("spec.host_path", v.Spec.HostPath); err != nil {
+			return err
+		}
+		if v.Spec.NodeName == "" {
+			return invalid("spec.node_name", "is required for hostpath volumes")
+		}
+	} else if v.Spec.HostPath != "" {
+		return invalid("spec.host_path", "is only allowed for hostpath volumes")
+	}
+	if v.Spec.Driver == "" || !driverRE.MatchString(v.Spec.Driver) {
+		return invalid("spec.driver", "must be a lowercase driver name; got %q", v.Spec.Driver)
+	}
+	return nil
+}
+
+// BoundNode returns the node holding the volume, empty if it is not bound
+// yet.
+func (v *Volume) BoundNode() string {
+	if v.Status.NodeName != "" {
+		return v.Status.NodeName
+	}
+	return v.Spec.NodeName
+}
+
+// Validate checks the mount for errors.
+func (m *VolumeMount) Validate(field string) error {
+	if err := ValidateName(field+".name", m.Name); err != nil {
+		return err
+	}
+	return validateMountPath(field+".mount_path", m.MountPath)
+}
+
+func validateMountPath(field, path string) error {
+	if !filepath.IsAbs(path) {
+		return invalid(field, "must be an absolute path")
+	}
+	if filepath.Clean(path) != path || path == "/" {
+		return invalid(field, "must be a clean path below /")
+	}
+	return nil
+}
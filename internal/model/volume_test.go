@@ -0,0 +1,51 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVolume_Validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		spec  VolumeSpec
+		field string
+	}{
+		{"local", VolumeSpec{}, ""},
+		{"pinned local", VolumeSpec{NodeName: "node-1", CapacityBytes: 1 << 20}, ""},
+		{"hostpath", VolumeSpec{Driver: VolumeDriverHostPath, HostPath: "/srv/data", NodeName: "node-1"}, ""},
+		{"custom driver", VolumeSpec{Driver: "nfs"}, ""},
+		{"negative capacity", VolumeSpec{CapacityBytes: -1}, "spec.capacity_bytes"},
+		{"bad driver", VolumeSpec{Driver: "NFS"}, "spec.driver"},
+		{"hostpath without node", VolumeSpec{Driver: VolumeDriverHostPath, HostPath: "/srv"}, "spec.node_name"},
+		{"relative host path", VolumeSpec{Driver: VolumeDriverHostPath, HostPath: "srv", NodeName: "node-1"}, "spec.host_path"},
+		{"host path on local", VolumeSpec{HostPath: "/srv"}, "spec.host_path"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &Volume{ObjectMeta: ObjectMeta{Name: "data"}, Spec: tt.spec}
+			v.SetDefaults()
+			err := v.Validate()
+			if tt.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, tt.field, verr.Field)
+		})
+	}
+}
+
+func TestVolume_BoundNode(t *testing.T) {
+	v := &Volume{}
+	assert.Empty(t, v.BoundNode())
+	v.Spec.NodeName = "node-1"
+	assert.Equal(t, "node-1", v.BoundNode())
+	v.Status.NodeName = "node-2"
+	assert.Equal(t, "node-2", v.BoundNode())
+}
@@ -0,0 +1,144 @@
+package namespace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/github-builder/container-orchestrator/internal/gc"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// object is the metadata of a namespaced resource of any kind.
+type object struct {
+	model.ObjectMeta `json:"metadata"`
+}
+
+// Delete starts deleting the namespace: it marks it as terminating, which
+// stops new resources from being admitted into it, and deletes everything
+// in it. The namespace is removed once its contents are gone; while
+// finalizers hold some of them, it stays terminating and Run finishes it
+// later. Delete returns the terminating namespace, or nil if it is
+// already gone.
+func (r *Registry) Delete(name string) (*model.Namespace, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// A namespace already terminating keeps the finalizers it was given.
+	now := time.Now().UTC()
+	ns, err := store.UpdateJSON(r.store, model.NamespacesBucket, name, func(cur *model.Namespace) error {
+		if cur.IsTerminating() {
+			return nil
+		}
+		cur.Finalizers = append(cur.Finalizers, model.FinalizerNamespaceContents)
+		cur.DeletionTimestamp = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	gone, err := r.finish(ns)
+	if err != nil || gone {
+		return nil, err
+	}
+	return ns, nil
+}
+
+// Run finishes deleting terminating namespaces every interval until ctx
+// is canceled.
+func (r *Registry) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Sync(ctx); err != nil {
+			r.logger.Error().Err(err).Msg("namespace sync failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync deletes what is left in terminating namespaces, and removes those
+// left empty.
+func (r *Registry) Sync(ctx context.Context) error {
+	namespaces, err := store.ListJSON[model.Namespace](r.store, model.NamespacesBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing namespaces: %w", err)
+	}
+
+	var errs []error
+	for _, ns := range namespaces {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !ns.IsTerminating() {
+			continue
+		}
+		if _, err := r.finish(ns); err != nil {
+			errs = append(errs, fmt.Errorf("namespace %s: %w", ns.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// finish deletes the resources left in the terminating namespace ns with
+// gc.Delete and, once none are left, clears its finalizer, which removes
+// it unless finalizers of others still hold it. Volumes are deleted only
+// once no containers are left, so that agents stop the containers that
+// mount them before their data is pruned. It reports whether the
+// namespace is gone.
+func (r *Registry) finish(ns *model.Namespace) (bool, error) {
+	left := make(map[string]int, len(model.NamespacedBuckets))
+	for _, b := range model.NamespacedBuckets {
+		objects, err := store.ListJSON[object](r.store, b, model.NamespacePrefix(ns.Name))
+		if err != nil {
+			return false, fmt.Errorf("listing %s: %w", b, err)
+		}
+		if b == model.VolumesBucket && left[model.ContainersBucket] > 0 {
+			left[b] = len(objects)
+			continue
+		}
+
+		deleted := 0
+		for _, o := range objects {
+			gone, err := gc.Delete(r.store, b, &o.ObjectMeta)
+			if err != nil {
+				return false, fmt.Errorf("deleting %s %s: %w", b, o.Key(), err)
+			}
+			if !gone {
+				left[b]++
+			} else if !o.IsTerminating() {
+				deleted++
+			}
+		}
+		if deleted > 0 {
+			r.logger.Info().Str("namespace", ns.Name).Str("kind", b).Int("count", deleted).Msg("namespace resources deleted")
+		}
+	}
+
+	for _, n := range left {
+		if n > 0 {
+			return false, nil
+		}
+	}
+	if !ns.HasFinalizer(model.FinalizerNamespaceContents) {
+		return false, nil
+	}
+	if err := gc.RemoveFinalizer(r.store, model.NamespacesBucket, &ns.ObjectMeta, model.FinalizerNamespaceContents); err != nil {
+		return false, err
+	}
+	ok, err := r.Exists(ns.Name)
+	if err != nil || ok {
+		return false, err
+	}
+	r.logger.Info().Str("namespace", ns.Name).Msg("namespace deleted")
+	return true, nil
+}
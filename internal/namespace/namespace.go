@@ -0,0 +1,235 @@
+// Package namespace manages namespaces: it admits resources only into
+// namespaces that exist and have room in their quota, reports quota usage
+// and deletes namespaces together with everything in them.
+//
+// A deleted namespace is terminating until its contents are gone: it
+// admits no new resources, its resources are deleted with gc.Delete, so
+// that their finalizers are respected, and the registry removes it once
+// the last of them has been removed.
+package namespace
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// ErrNotFound is returned when a resource is admitted into a namespace
+// that does not exist.
+var ErrNotFound = errors.New("namespace not found")
+
+// ErrTerminating is returned when a new resource is admitted into a
+// namespace being deleted.
+var ErrTerminating = errors.New("namespace is being deleted")
+
+// ExceededError reports a change that would take a namespace over its
+// quota.
+type ExceededError struct {
+	Namespace string
+
+	// Resource is the capped resource: a kind such as "containers", or
+	// "cpu_millis" or "memory_bytes".
+	Resource string
+
+	// Requested is what the change adds, Used what the namespace uses
+	// without it and Limit the cap.
+	Requested, Used, Limit int64
+}
+
+// Error implements the error interface.
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("quota exceeded in namespace %s: %s requested %d, used %d, limited to %d",
+		e.Namespace, e.Resource, e.Requested, e.Used, e.Limit)
+}
+
+// Config holds dependencies for the namespace registry.
+type Config struct {
+	Store  store.Store
+	Logger zerolog.Logger
+
+	// Interval is how often Run finishes deleting terminating namespaces.
+	Interval time.Duration
+}
+
+// Registry guards namespaces and their quotas. Admissions and deletions
+// are serialized so that concurrent requests cannot jointly exceed a quota
+// or create resources in a namespace being deleted.
+type Registry struct {
+	store    store.Store
+	logger   zerolog.Logger
+	interval time.Duration
+
+	mu sync.Mutex
+}
+
+// NewRegistry creates a namespace registry.
+func NewRegistry(cfg *Config) *Registry {
+	return &Registry{store: cfg.Store, logger: cfg.Logger, interval: cfg.Interval}
+}
+
+// EnsureDefault creates the default namespace if it does not exist.
+func (r *Registry) EnsureDefault() error {
+	ns := &model.Namespace{ObjectMeta: model.ObjectMeta{Name: model.DefaultNamespace}}
+	if err := ns.Initialize(); err != nil {
+		return err
+	}
+	err := store.CreateJSON(r.store, model.NamespacesBucket, ns.Name, ns)
+	if errors.Is(err, store.ErrAlreadyExists) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("creating namespace %s: %w", ns.Name, err)
+	}
+	r.logger.Info().Str("namespace", ns.Name).Msg("namespace created")
+	return nil
+}
+
+// Exists reports whether the namespace exists.
+func (r *Registry) Exists(name string) (bool, error) {
+	_, err := r.store.Get(model.NamespacesBucket, name)
+	if store.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Admit runs write, which stores obj in bucket, if the namespace of obj
+// exists and obj keeps it within its quota. A stored resource with the
+// same key is being replaced and does not count towards the usage, and is
+// the only kind admitted into a terminating namespace. Otherwise it
+// returns an error wrapping ErrNotFound or ErrTerminating, or an
+// *ExceededError, without calling write.
+func (r *Registry) Admit(bucket string, obj model.Object, write func() error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	meta := obj.Meta()
+	ns, err := store.GetJSON[model.Namespace](r.store, model.NamespacesBucket, meta.Namespace)
+	if store.IsNotFound(err) {
+		return fmt.Errorf("%w: %s", ErrNotFound, meta.Namespace)
+	}
+	if err != nil {
+		return err
+	}
+	if ns.IsTerminating() {
+		_, err := r.store.Get(bucket, meta.Key())
+		if store.IsNotFound(err) {
+			return fmt.Errorf("%w: %s", ErrTerminating, ns.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if q := ns.Spec.Quota; q != nil {
+		if err := r.check(ns.Name, q, bucket, obj); err != nil {
+			return err
+		}
+	}
+	return write()
+}
+
+// Exclusive runs fn while no resource is admitted, for changes that must
+// not interleave with admissions, such as deleting a volume once no
+// container mounts it.
+func (r *Registry) Exclusive(fn func() error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return fn()
+}
+
+// check returns an *ExceededError if storing obj in bucket would exceed q.
+func (r *Registry) check(namespace string, q *model.Quota, bucket string, obj model.Object) error {
+	key := obj.Meta().Key()
+	used, err := r.usage(namespace, bucket, key)
+	if err != nil {
+		return err
+	}
+
+	if limit, ok := q.Counts[bucket]; ok {
+		// Replacing a resource leaves the count unchanged.
+		_, err := r.store.Get(bucket, key)
+		switch {
+		case err == nil:
+			used.Counts[bucket]--
+		case !store.IsNotFound(err):
+			return err
+		}
+		if n := used.Counts[bucket]; n+1 > limit {
+			return &ExceededError{Namespace: namespace, Resource: bucket, Requested: 1, Used: int64(n), Limit: int64(limit)}
+		}
+	}
+
+	nodes, err := r.nodeCount()
+	if err != nil {
+		return err
+	}
+	req := Requests(obj, nodes)
+	if limit := q.Requests.CPUMillis; limit > 0 && req.CPUMillis > 0 && used.Requests.CPUMillis+req.CPUMillis > limit {
+		return &ExceededError{Namespace: namespace, Resource: "cpu_millis", Requested: req.CPUMillis, Used: used.Requests.CPUMillis, Limit: limit}
+	}
+	if limit := q.Requests.MemoryBytes; limit > 0 && req.MemoryBytes > 0 && used.Requests.MemoryBytes+req.MemoryBytes > limit {
+		return &ExceededError{Namespace: namespace, Resource: "memory_bytes", Requested: req.MemoryBytes, Used: used.Requests.MemoryBytes, Limit: limit}
+	}
+	return nil
+}
+
+// Usage reports what the namespace uses of its quota.
+func (r *Registry) Usage(namespace string) (*model.QuotaUsage, error) {
+	return r.usage(namespace, "", "")
+}
+
+// usage computes the usage of a namespace, leaving out the requests of
+// the resource stored under key in bucket.
+func (r *Registry) usage(namespace, bucket, key string) (*model.QuotaUsage, error) {
+	nodes, err := r.nodeCount()
+	if err != nil {
+		return nil, err
+	}
+
+	used := &model.QuotaUsage{Counts: make(map[string]int, len(model.NamespacedBuckets))}
+	for _, b := range model.NamespacedBuckets {
+		kvs, err := r.store.List(b, model.NamespacePrefix(namespace))
+		if store.IsNotFound(err) {
+			used.Counts[b] = 0
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		used.Counts[b] = len(kvs)
+
+		for _, kv := range kvs {
+			if b == bucket && kv.Key == key {
+				continue
+			}
+			obj, err := decode(b, kv.Value)
+			if err != nil {
+				return nil, fmt.Errorf("decoding %s %s: %w", b, kv.Key, err)
+			}
+			if obj == nil {
+				continue
+			}
+			req := Requests(obj, nodes)
+			used.Requests.CPUMillis += req.CPUMillis
+			used.Requests.MemoryBytes += req.MemoryBytes
+		}
+	}
+	return used, nil
+}
+
+// nodeCount returns the number of registered nodes, which bounds how many
+// replicas a daemon set runs.
+func (r *Registry) nodeCount() (int, error) {
+	kvs, err := r.store.List(model.NodesBucket, "")
+	if store.IsNotFound(err) {
+		return 0, nil
+	}
+	return len(kvs), err
+}
@@ -0,0 +1,197 @@
+package namespace
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/gc"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+func newTestRegistry(t *testing.T) (*Registry, store.Store) {
+	t.Helper()
+	s := store.NewMemoryStore()
+	return NewRegistry(&Config{Store: s, Logger: zerolog.Nop()}), s
+}
+
+func putNamespace(t *testing.T, s store.Store, name string, quota *model.Quota) {
+	t.Helper()
+	ns := &model.Namespace{ObjectMeta: model.ObjectMeta{Name: name}, Spec: model.NamespaceSpec{Quota: quota}}
+	require.NoError(t, ns.Validate())
+	require.NoError(t, store.PutJSON(s, model.NamespacesBucket, name, ns))
+}
+
+func testContainer(ns, name string, cpu int64) *model.Container {
+	return &model.Container{
+		ObjectMeta: model.ObjectMeta{Name: name, Namespace: ns},
+		Spec:       model.ContainerSpec{Image: "app", Resources: model.Resources{CPUMillis: cpu}},
+	}
+}
+
+// create admits obj and stores it, as the API does.
+func create(r *Registry, s store.Store, bucket string, obj model.Object) error {
+	return r.Admit(bucket, obj, func() error {
+		return store.PutJSON(s, bucket, obj.Meta().Key(), obj)
+	})
+}
+
+func TestEnsureDefault(t *testing.T) {
+	r, s := newTestRegistry(t)
+	require.NoError(t, r.EnsureDefault())
+	first, err := store.GetJSON[model.Namespace](s, model.NamespacesBucket, model.DefaultNamespace)
+	require.NoError(t, err)
+
+	// An existing default namespace is kept.
+	require.NoError(t, r.EnsureDefault())
+	again, err := store.GetJSON[model.Namespace](s, model.NamespacesBucket, model.DefaultNamespace)
+	require.NoError(t, err)
+	assert.Equal(t, first.UID, again.UID)
+
+	ok, err := r.Exists(model.DefaultNamespace)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = r.Exists("missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestAdmit_MissingNamespace(t *testing.T) {
+	r, s := newTestRegistry(t)
+	err := create(r, s, model.ContainersBucket, testContainer("missing", "web", 0))
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.EqualError(t, err, "namespace not found: missing")
+}
+
+func TestAdmit_CountQuota(t *testing.T) {
+	r, s := newTestRegistry(t)
+	putNamespace(t, s, "team-a", &model.Quota{Counts: map[string]int{model.ContainersBucket: 2}})
+	putNamespace(t, s, "team-b", nil)
+
+	require.NoError(t, create(r, s, model.ContainersBucket, testContainer("team-a", "web-1", 0)))
+	require.NoError(t, create(r, s, model.ContainersBucket, testContainer("team-a", "web-2", 0)))
+	err := create(r, s, model.ContainersBucket, testContainer("team-a", "web-3", 0))
+	var qerr *ExceededError
+	require.ErrorAs(t, err, &qerr)
+	assert.EqualError(t, err, "quota exceeded in namespace team-a: containers requested 1, used 2, limited to 2")
+
+	// Replacing a container does not count it twice, and other namespaces
+	// are unaffected.
+	require.NoError(t, create(r, s, model.ContainersBucket, testContainer("team-a", "web-2", 0)))
+	require.NoError(t, create(r, s, model.ContainersBucket, testContainer("team-b", "web-3", 0)))
+}
+
+func TestAdmit_RequestQuota(t *testing.T) {
+	r, s := newTestRegistry(t)
+	putNamespace(t, s, "team-a", &model.Quota{Requests: model.Resources{CPUMillis: 1000}})
+	for _, n := range []string{"node-1", "node-2"} {
+		require.NoError(t, store.PutJSON(s, model.NodesBucket, n, &model.Node{ObjectMeta: model.ObjectMeta{Name: n}}))
+	}
+
+	require.NoError(t, create(r, s, model.ContainersBucket, testContainer("team-a", "web", 300)))
+
+	d := &model.Deployment{
+		ObjectMeta: model.ObjectMeta{Name: "api", Namespace: "team-a"},
+		Spec: model.DeploymentSpec{
+			Replicas: 2,
+			Template: model.ContainerTemplate{Spec: testContainer("", "", 200).Spec},
+		},
+	}
+	require.NoError(t, create(r, s, model.DeploymentsBucket, d))
+
+	// The deployment's replicas count through the deployment only.
+	replica := testContainer("team-a", "api-1", 200)
+	replica.Labels = map[string]string{model.LabelDeployment: "api"}
+	require.NoError(t, create(r, s, model.ContainersBucket, replica))
+
+	// A daemon set runs on every node.
+	ds := &model.DaemonSet{
+		ObjectMeta: model.ObjectMeta{Name: "agent", Namespace: "team-a"},
+		Spec:       model.DaemonSetSpec{Template: d.Spec.Template},
+	}
+	err := create(r, s, model.DaemonSetsBucket, ds)
+	assert.EqualError(t, err, "quota exceeded in namespace team-a: cpu_millis requested 400, used 700, limited to 1000")
+
+	// Scaling the deployment is checked against the usage without it.
+	d.Spec.Replicas = 3
+	require.NoError(t, create(r, s, model.DeploymentsBucket, d))
+	d.Spec.Replicas = 4
+	require.Error(t, create(r, s, model.DeploymentsBucket, d))
+
+	used, err := r.Usage("team-a")
+	require.NoError(t, err)
+	assert.Equal(t, int64(900), used.Requests.CPUMillis)
+	assert.Equal(t, 2, used.Counts[model.ContainersBucket])
+	assert.Equal(t, 1, used.Counts[model.DeploymentsBucket])
+	assert.Equal(t, 0, used.Counts[model.SecretsBucket])
+}
+
+func TestDelete_Cascades(t *testing.T) {
+	r, s := newTestRegistry(t)
+	putNamespace(t, s, "team-a", nil)
+	putNamespace(t, s, "team-b", nil)
+	for _, ns := range []string{"team-a", "team-b"} {
+		require.NoError(t, create(r, s, model.ContainersBucket, testContainer(ns, "web", 0)))
+		require.NoError(t, create(r, s, model.SecretsBucket, &model.Secret{ObjectMeta: model.ObjectMeta{Name: "db", Namespace: ns}}))
+	}
+
+	ns, err := r.Delete("team-a")
+	require.NoError(t, err)
+	assert.Nil(t, ns)
+
+	ok, err := r.Exists("team-a")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	for b, key := range map[string]string{model.ContainersBucket: "team-b/web", model.SecretsBucket: "team-b/db"} {
+		kvs, err := s.List(b, "")
+		require.NoError(t, err)
+		require.Len(t, kvs, 1)
+		assert.Equal(t, key, kvs[0].Key)
+	}
+
+	_, err = r.Delete("team-a")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func TestDelete_WaitsForFinalizers(t *testing.T) {
+	r, s := newTestRegistry(t)
+	putNamespace(t, s, "team-a", nil)
+	c := testContainer("team-a", "web", 0)
+	c.Finalizers = []string{model.FinalizerRuntimeCleanup}
+	require.NoError(t, create(r, s, model.ContainersBucket, c))
+	require.NoError(t, create(r, s, model.VolumesBucket, &model.Volume{ObjectMeta: model.ObjectMeta{Name: "data", Namespace: "team-a"}}))
+
+	ns, err := r.Delete("team-a")
+	require.NoError(t, err)
+	require.NotNil(t, ns)
+	assert.True(t, ns.IsTerminating())
+
+	// The container is terminating, and the volume waits for it.
+	got, err := store.GetJSON[model.Container](s, model.ContainersBucket, c.Key())
+	require.NoError(t, err)
+	assert.True(t, got.IsTerminating())
+	_, err = s.Get(model.VolumesBucket, "team-a/data")
+	require.NoError(t, err)
+
+	// Nothing new is admitted into a terminating namespace.
+	err = create(r, s, model.SecretsBucket, &model.Secret{ObjectMeta: model.ObjectMeta{Name: "db", Namespace: "team-a"}})
+	assert.ErrorIs(t, err, ErrTerminating)
+
+	require.NoError(t, r.Sync(context.Background()))
+	ok, err := r.Exists("team-a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, gc.RemoveFinalizer(s, model.ContainersBucket, &got.ObjectMeta, model.FinalizerRuntimeCleanup))
+	require.NoError(t, r.Sync(context.Background()))
+	ok, err = r.Exists("team-a")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	kvs, err := s.List(model.VolumesBucket, "")
+	require.NoError(t, err)
+	assert.Empty(t, kvs)
+}
@@ -0,0 +1,96 @@
+package namespace
+
+import (
+	"encoding/json"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+// Requests returns the CPU and memory a resource requests for its
+// containers when fully scaled. Workloads count for the containers they
+// create, so those containers count for nothing themselves, and finished
+// containers and jobs count for nothing either. nodes is the number of
+// nodes a daemon set may run on.
+func Requests(obj model.Object, nodes int) model.Resources {
+	switch v := obj.(type) {
+	case *model.Container:
+		if v.Status.IsTerminal() || owned(v.Labels) {
+			return model.Resources{}
+		}
+		return v.Spec.Resources
+	case *model.Deployment:
+		return times(v.Spec.Template.Spec.Resources, v.Spec.Replicas)
+	case *model.StatefulSet:
+		return times(v.Spec.Template.Spec.Resources, v.Spec.Replicas)
+	case *model.Group:
+		// Members count until the group finishes, init containers
+		// included.
+		var r model.Resources
+		if v.Status.IsTerminal() {
+			return r
+		}
+		for _, m := range v.Members() {
+			r.CPUMillis += m.Spec.Resources.CPUMillis
+			r.MemoryBytes += m.Spec.Resources.MemoryBytes
+		}
+		return r
+	case *model.DaemonSet:
+		return times(v.Spec.Template.Spec.Resources, nodes)
+	case *model.Job:
+		// A cron job counts for the jobs it creates.
+		if v.Status.IsFinished() || v.Labels[model.LabelCronJob] != "" {
+			return model.Resources{}
+		}
+		return times(v.Spec.Template.Spec.Resources, v.Spec.Parallelism)
+	case *model.CronJob:
+		spec := &v.Spec.JobTemplate.Spec
+		return times(spec.Template.Spec.Resources, max(spec.Parallelism, 1))
+	default:
+		return model.Resources{}
+	}
+}
+
+// owned reports whether labels mark a container as created by a workload.
+func owned(labels map[string]string) bool {
+	for _, l := range []string{model.LabelDeployment, model.LabelDaemonSet, model.LabelStatefulSet, model.LabelGroup, model.LabelJob} {
+		if labels[l] != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func times(r model.Resources, n int) model.Resources {
+	return model.Resources{
+		CPUMillis:   r.CPUMillis * int64(n),
+		MemoryBytes: r.MemoryBytes * int64(n),
+	}
+}
+
+// decode decodes a stored resource of a kind that requests resources. It
+// returns nil for other kinds.
+func decode(bucket string, data []byte) (model.Object, error) {
+	var obj model.Object
+	switch bucket {
+	case model.ContainersBucket:
+		obj = new(model.Container)
+	case model.DeploymentsBucket:
+		obj = new(model.Deployment)
+	case model.StatefulSetsBucket:
+		obj = new(model.StatefulSet)
+	case model.GroupsBucket:
+		obj = new(model.Group)
+	case model.DaemonSetsBucket:
+		obj = new(model.DaemonSet)
+	case model.JobsBucket:
+		obj = new(model.Job)
+	case model.CronJobsBucket:
+		obj = new(model.CronJob)
+	default:
+		return nil, nil
+	}
+	if err := json.Unmarshal(data, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
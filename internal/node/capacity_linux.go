@@ -0,0 +1,24 @@
+//go:build linux
+
+package node
+
+import (
+	goruntime "runtime"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+// LocalCapacity reports the CPU and memory of the host the orchestrator runs on.
+func LocalCapacity() (model.Resources, error) {
+	var info unix.Sysinfo_t
+	if err := unix.Sysinfo(&info); err != nil {
+		return model.Resources{}, err
+	}
+
+	return model.Resources{
+		CPUMillis:   int64(goruntime.NumCPU()) * 1000,
+		MemoryBytes: int64(info.Totalram) * int64(info.Unit), //nolint:gosec,unconvert // Field widths vary by architecture.
+	}, nil
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package node
+
+import (
+	goruntime "runtime"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+// fallbackMemoryBytes is assumed where total memory cannot be queried.
+const fallbackMemoryBytes = 4 << 30
+
+// LocalCapacity reports the CPU of the host the orchestrator runs on and
+// a fixed memory estimate.
+func LocalCapacity() (model.Resources, error) {
+	return model.Resources{
+		CPUMillis:   int64(goruntime.NumCPU()) * 1000,
+		MemoryBytes: fallbackMemoryBytes,
+	}, nil
+}
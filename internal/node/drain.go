@@ -0,0 +1,210 @@
+package node
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/github-builder/container-orchestrator/internal/eviction"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// errDrainChanged is returned when a drain was canceled or restarted while
+// an eviction pass ran.
+var errDrainChanged = errors.New("drain changed during eviction pass")
+
+// Cordon marks a node unschedulable. Containers already on it keep running.
+func (r *Registry) Cordon(name string) (*model.Node, error) {
+	n, err := store.UpdateJSON(r.store, model.NodesBucket, name, func(n *model.Node) error {
+		n.Spec.Unschedulable = true
+		return nil
+	})
+	if err == nil {
+		r.logger.Info().Str("node", name).Msg("node cordoned")
+	}
+	return n, err
+}
+
+// Uncordon makes a node schedulable again and cancels any drain in
+// progress. Evicted containers stay where they were rescheduled.
+func (r *Registry) Uncordon(name string) (*model.Node, error) {
+	n, err := store.UpdateJSON(r.store, model.NodesBucket, name, func(n *model.Node) error {
+		n.Spec.Unschedulable = false
+		n.Status.Drain = nil
+		return nil
+	})
+	if err == nil {
+		r.logger.Info().Str("node", name).Msg("node uncordoned")
+	}
+	return n, err
+}
+
+// Drain cordons a node and evicts the containers on it, except daemon set
+// containers, which belong to the node. Evicted containers are stopped by
+// the agent within its stop timeout and scheduled elsewhere. Containers
+// that cannot be evicted yet, typically because a disruption budget waits
+// for their replacements to become healthy, are retried every heartbeat
+// interval until none remain or the timeout, or the registry's drain
+// timeout if zero, elapses. The first eviction pass runs before Drain
+// returns.
+func (r *Registry) Drain(name string, timeout time.Duration) (*model.Node, error) {
+	if timeout <= 0 {
+		timeout = r.drainTimeout
+	}
+
+	now := r.now()
+	n, err := store.UpdateJSON(r.store, model.NodesBucket, name, func(n *model.Node) error {
+		n.Spec.Unschedulable = true
+		n.Status.Drain = &model.DrainStatus{
+			Phase:     model.DrainInProgress,
+			StartedAt: now,
+			Deadline:  now.Add(timeout),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.logger.Info().Str("node", name).Dur("timeout", timeout).Msg("draining node")
+
+	containers, err := store.ListJSON[model.Container](r.store, model.ContainersBucket, "")
+	if err != nil {
+		return nil, fmt.Errorf("listing containers: %w", err)
+	}
+	updated, err := r.progress(n, containers)
+	switch {
+	case errors.Is(err, errDrainChanged):
+		return store.GetJSON[model.Node](r.store, model.NodesBucket, name)
+	case updated == nil:
+		return nil, err
+	case err != nil:
+		// Reported in the drain status and retried on the next pass.
+		r.logger.Error().Err(err).Str("node", name).Msg("drain eviction pass failed")
+	}
+	return updated, nil
+}
+
+// ProgressDrains runs an eviction pass on every node being drained, and
+// completes or fails the drains that are done or out of time.
+func (r *Registry) ProgressDrains() error {
+	nodes, err := store.ListJSON[model.Node](r.store, model.NodesBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing nodes: %w", err)
+	}
+
+	var draining []*model.Node
+	for _, n := range nodes {
+		if n.Status.Drain != nil && n.Status.Drain.Phase == model.DrainInProgress {
+			draining = append(draining, n)
+		}
+	}
+	if len(draining) == 0 {
+		return nil
+	}
+
+	containers, err := store.ListJSON[model.Container](r.store, model.ContainersBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing containers: %w", err)
+	}
+
+	var errs []error
+	for _, n := range draining {
+		if _, err := r.progress(n, containers); err != nil && !errors.Is(err, errDrainChanged) {
+			errs = append(errs, fmt.Errorf("node %s: %w", n.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// progress evicts the drainable containers on n and records the outcome
+// on the drain status. Evictions refused by a disruption budget are
+// reported in the status message and retried on the next pass. It returns
+// the updated node along with any other eviction errors, which are also
+// reported in the status message.
+func (r *Registry) progress(n *model.Node, containers []*model.Container) (*model.Node, error) {
+	reason := fmt.Sprintf("node %s is draining", n.Name)
+
+	var (
+		evicted   int
+		remaining []string
+		blocked   string
+		errs      []error
+	)
+	for _, c := range containers {
+		if !drainable(c, n.Name) {
+			continue
+		}
+		err := r.evictor.Evict(c, reason)
+		var berr *eviction.BudgetError
+		switch {
+		case err == nil:
+			evicted++
+		case errors.Is(err, eviction.ErrStale):
+			// Deleted or moved since it was listed.
+		case errors.As(err, &berr):
+			// Retried once replacements elsewhere are healthy.
+			remaining = append(remaining, c.Key())
+			if blocked == "" {
+				blocked = err.Error()
+			}
+		default:
+			remaining = append(remaining, c.Key())
+			errs = append(errs, fmt.Errorf("evicting %s: %w", c.Key(), err))
+		}
+	}
+
+	now := r.now()
+	started := n.Status.Drain.StartedAt
+	updated, err := store.UpdateJSON(r.store, model.NodesBucket, n.Name, func(cur *model.Node) error {
+		d := cur.Status.Drain
+		if d == nil || d.Phase != model.DrainInProgress || !d.StartedAt.Equal(started) {
+			return errDrainChanged
+		}
+		d.Evicted += evicted
+		d.Remaining = remaining
+		d.Message = blocked
+		if len(errs) > 0 {
+			d.Message = errs[0].Error()
+		}
+		switch {
+		case len(remaining) == 0:
+			d.Phase = model.DrainComplete
+			d.CompletedAt = &now
+		case !now.Before(d.Deadline):
+			msg := fmt.Sprintf("timed out with %d containers remaining", len(remaining))
+			if d.Message != "" {
+				msg += ": " + d.Message
+			}
+			d.Phase = model.DrainFailed
+			d.Message = msg
+			d.CompletedAt = &now
+		}
+		return nil
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		err = errDrainChanged
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch d := updated.Status.Drain; d.Phase {
+	case model.DrainComplete:
+		r.logger.Info().Str("node", n.Name).Int("evicted", d.Evicted).Msg("node drained")
+	case model.DrainFailed:
+		r.logger.Warn().Str("node", n.Name).Strs("remaining", d.Remaining).Msg("node drain timed out")
+	}
+	return updated, errors.Join(errs...)
+}
+
+// drainable reports whether c is running on the named node and must be
+// evicted to drain it. Daemon set containers stay with their node, and
+// containers being deleted are already leaving it.
+func drainable(c *model.Container, nodeName string) bool {
+	if c.Spec.NodeName != nodeName || c.Status.IsTerminal() || c.IsTerminating() {
+		return false
+	}
+	_, daemon := c.Labels[model.LabelDaemonSet]
+	return !daemon
+}
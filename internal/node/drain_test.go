@@ -0,0 +1,207 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/eviction"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+func putContainer(t *testing.T, r *Registry, name, nodeName string, labels map[string]string) *model.Container {
+	t.Helper()
+	c := &model.Container{
+		ObjectMeta: model.ObjectMeta{Name: name, Namespace: model.DefaultNamespace, Labels: labels},
+		Spec:       model.ContainerSpec{Image: "nginx", NodeName: nodeName},
+		Status:     model.ContainerStatus{Phase: model.PhaseRunning, RestartCount: 2},
+	}
+	require.NoError(t, c.Initialize())
+	require.NoError(t, store.PutJSON(r.store, model.ContainersBucket, c.Key(), c))
+	return c
+}
+
+func newTestEvictor(s store.Store) *eviction.Evictor {
+	return eviction.New(&eviction.Config{Store: s, Logger: zerolog.Nop()})
+}
+
+func getContainer(t *testing.T, r *Registry, name string) *model.Container {
+	t.Helper()
+	c, err := store.GetJSON[model.Container](r.store, model.ContainersBucket, model.Key(model.DefaultNamespace, name))
+	require.NoError(t, err)
+	return c
+}
+
+func TestCordon(t *testing.T) {
+	r, _ := newTestRegistry(t)
+	_, _, err := r.Register(testNode("node-1"))
+	require.NoError(t, err)
+
+	n, err := r.Cordon("node-1")
+	require.NoError(t, err)
+	assert.True(t, n.Spec.Unschedulable)
+
+	// Registering again does not uncordon.
+	n, _, err = r.Register(testNode("node-1"))
+	require.NoError(t, err)
+	assert.True(t, n.Spec.Unschedulable)
+
+	n, err = r.Uncordon("node-1")
+	require.NoError(t, err)
+	assert.False(t, n.Spec.Unschedulable)
+
+	_, err = r.Cordon("missing")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func TestDrain(t *testing.T) {
+	r, now := newTestRegistry(t)
+	_, _, err := r.Register(testNode("node-1"))
+	require.NoError(t, err)
+	_, _, err = r.Register(testNode("node-2"))
+	require.NoError(t, err)
+
+	putContainer(t, r, "web-1", "node-1", map[string]string{model.LabelDeployment: "web"})
+	putContainer(t, r, "web-2", "node-2", map[string]string{model.LabelDeployment: "web"})
+	putContainer(t, r, "logs-node-1", "node-1", map[string]string{model.LabelDaemonSet: "logs"})
+	done := putContainer(t, r, "done", "node-1", nil)
+	done.Status.Phase = model.PhaseSucceeded
+	require.NoError(t, store.PutJSON(r.store, model.ContainersBucket, done.Key(), done))
+
+	n, err := r.Drain("node-1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, n.Spec.Unschedulable)
+	require.NotNil(t, n.Status.Drain)
+	assert.Equal(t, model.DrainComplete, n.Status.Drain.Phase)
+	assert.Equal(t, 1, n.Status.Drain.Evicted)
+	assert.Empty(t, n.Status.Drain.Remaining)
+	assert.Equal(t, *now, n.Status.Drain.StartedAt)
+	assert.Equal(t, now.Add(time.Minute), n.Status.Drain.Deadline)
+	require.NotNil(t, n.Status.Drain.CompletedAt)
+
+	// The replica is back to Pending for the scheduler, keeping its history.
+	web := getContainer(t, r, "web-1")
+	assert.Empty(t, web.Spec.NodeName)
+	assert.Equal(t, model.PhasePending, web.Status.Phase)
+	assert.Equal(t, model.ReasonEvicted, web.Status.Reason)
+	assert.Equal(t, "node node-1 is draining", web.Status.Message)
+	assert.Equal(t, 2, web.Status.RestartCount)
+
+	// Daemons, finished containers and other nodes are left alone.
+	assert.Equal(t, "node-1", getContainer(t, r, "logs-node-1").Spec.NodeName)
+	assert.Equal(t, "node-1", getContainer(t, r, "done").Spec.NodeName)
+	assert.Equal(t, "node-2", getContainer(t, r, "web-2").Spec.NodeName)
+
+	// Uncordoning clears the drain.
+	n, err = r.Uncordon("node-1")
+	require.NoError(t, err)
+	assert.False(t, n.Spec.Unschedulable)
+	assert.Nil(t, n.Status.Drain)
+
+	_, err = r.Drain("missing", 0)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+// failingStore fails writes to containers, so that evictions fail.
+type failingStore struct {
+	store.Store
+}
+
+func (s failingStore) Update(bucket, key string, fn func([]byte) ([]byte, error)) error {
+	if bucket == model.ContainersBucket {
+		return assert.AnError
+	}
+	return s.Store.Update(bucket, key, fn)
+}
+
+func TestDrain_RetriesUntilTimeout(t *testing.T) {
+	r, now := newTestRegistry(t)
+	mem := r.store
+	r.drainTimeout = time.Minute
+	_, _, err := r.Register(testNode("node-1"))
+	require.NoError(t, err)
+	putContainer(t, r, "web-1", "node-1", nil)
+
+	// Evictions fail while the store rejects container writes.
+	r.store = failingStore{mem}
+	r.evictor = newTestEvictor(r.store)
+	n, err := r.Drain("node-1", 0)
+	require.NoError(t, err)
+	d := n.Status.Drain
+	assert.Equal(t, model.DrainInProgress, d.Phase)
+	assert.Equal(t, []string{"default/web-1"}, d.Remaining)
+	assert.Contains(t, d.Message, assert.AnError.Error())
+	assert.Equal(t, now.Add(time.Minute), d.Deadline)
+
+	*now = now.Add(2 * time.Minute)
+	assert.Error(t, r.ProgressDrains())
+
+	n, err = store.GetJSON[model.Node](mem, model.NodesBucket, "node-1")
+	require.NoError(t, err)
+	d = n.Status.Drain
+	assert.Equal(t, model.DrainFailed, d.Phase)
+	assert.Contains(t, d.Message, "timed out with 1 containers remaining")
+	assert.True(t, n.Spec.Unschedulable, "a failed drain leaves the node cordoned")
+
+	// Further passes leave a failed drain alone.
+	r.store, r.evictor = mem, newTestEvictor(mem)
+	require.NoError(t, r.ProgressDrains())
+	assert.Equal(t, "node-1", getContainer(t, r, "web-1").Spec.NodeName)
+
+	// Draining again starts over and succeeds.
+	n, err = r.Drain("node-1", 0)
+	require.NoError(t, err)
+	assert.Equal(t, model.DrainComplete, n.Status.Drain.Phase)
+	assert.Equal(t, 1, n.Status.Drain.Evicted)
+}
+
+func TestDrain_WaitsForBudget(t *testing.T) {
+	r, _ := newTestRegistry(t)
+	_, _, err := r.Register(testNode("node-1"))
+	require.NoError(t, err)
+
+	b := &model.DisruptionBudget{
+		ObjectMeta: model.ObjectMeta{Name: "web", Namespace: model.DefaultNamespace},
+		Spec: model.DisruptionBudgetSpec{
+			Selector:       model.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			MaxUnavailable: model.FromInt(1),
+		},
+	}
+	require.NoError(t, store.PutJSON(r.store, model.DisruptionBudgetsBucket, b.Key(), b))
+	for _, name := range []string{"web-1", "web-2"} {
+		c := putContainer(t, r, name, "node-1", map[string]string{"app": "web"})
+		c.Status.Ready = true
+		require.NoError(t, store.PutJSON(r.store, model.ContainersBucket, c.Key(), c))
+	}
+
+	// Only one replica may be down at a time.
+	n, err := r.Drain("node-1", time.Minute)
+	require.NoError(t, err)
+	d := n.Status.Drain
+	assert.Equal(t, model.DrainInProgress, d.Phase)
+	assert.Equal(t, 1, d.Evicted)
+	assert.Equal(t, []string{"default/web-2"}, d.Remaining)
+	assert.Contains(t, d.Message, "would violate disruption budget default/web")
+
+	require.NoError(t, r.ProgressDrains())
+	n, err = store.GetJSON[model.Node](r.store, model.NodesBucket, "node-1")
+	require.NoError(t, err)
+	assert.Equal(t, model.DrainInProgress, n.Status.Drain.Phase)
+
+	// The evicted replica runs again elsewhere, so the second may go.
+	web1 := getContainer(t, r, "web-1")
+	web1.Spec.NodeName = "node-2"
+	web1.Status = model.ContainerStatus{Phase: model.PhaseRunning, Ready: true}
+	require.NoError(t, store.PutJSON(r.store, model.ContainersBucket, web1.Key(), web1))
+
+	require.NoError(t, r.ProgressDrains())
+	n, err = store.GetJSON[model.Node](r.store, model.NodesBucket, "node-1")
+	require.NoError(t, err)
+	assert.Equal(t, model.DrainComplete, n.Status.Drain.Phase)
+	assert.Equal(t, 2, n.Status.Drain.Evicted)
+	assert.Empty(t, n.Status.Drain.Message)
+}
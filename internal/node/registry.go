@@ -0,0 +1,280 @@
+// Package node maintains the registry of nodes that can run containers.
+// Nodes register with their capacity and then send periodic heartbeats;
+// a node whose heartbeats stop is marked NotReady. For maintenance, a node
+// can be cordoned to keep new containers off it and drained to evict the
+// ones it runs.
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/github-builder/container-orchestrator/internal/events"
+	"github.com/github-builder/container-orchestrator/internal/eviction"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// Config holds dependencies for the node registry.
+type Config struct {
+	Store  store.Store
+	Logger zerolog.Logger
+
+	// HeartbeatInterval is how often nodes are expected to send heartbeats.
+	HeartbeatInterval time.Duration
+
+	// HeartbeatTimeout is how long without a heartbeat before a node is NotReady.
+	HeartbeatTimeout time.Duration
+
+	// DrainTimeout is how long a drain may take when none is requested.
+	DrainTimeout time.Duration
+
+	// Evictor evicts containers from drained nodes. Defaults to an
+	// evictor on Store.
+	Evictor *eviction.Evictor
+
+	// Events records nodes becoming ready and not ready. Defaults to a
+	// recorder on Store.
+	Events *events.Recorder
+}
+
+// Registry records nodes and tracks their liveness.
+type Registry struct {
+	store    store.Store
+	logger   zerolog.Logger
+	interval time.Duration
+	timeout  time.Duration
+	evictor  *eviction.Evictor
+	events   *events.Recorder
+
+	drainTimeout time.Duration
+
+	// now is replaceable for tests.
+	now func() time.Time
+}
+
+// NewRegistry creates a node registry.
+func NewRegistry(cfg *Config) *Registry {
+	recorder := cfg.Events
+	if recorder == nil {
+		recorder = events.NewRecorder(&events.Config{Store: cfg.Store, Logger: cfg.Logger})
+	}
+	evictor := cfg.Evictor
+	if evictor == nil {
+		evictor = eviction.New(&eviction.Config{Store: cfg.Store, Logger: cfg.Logger, Events: recorder})
+	}
+
+	return &Registry{
+		store:        cfg.Store,
+		logger:       cfg.Logger,
+		interval:     cfg.HeartbeatInterval,
+		timeout:      cfg.HeartbeatTimeout,
+		evictor:      evictor,
+		events:       recorder.WithSource("nodes"),
+		drainTimeout: cfg.DrainTimeout,
+		now:          func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Register adds a node or updates the labels and spec of an existing one.
+// Labels and taints left nil keep their current values, so a node that
+// restarts and registers again does not discard ones set by an operator.
+// Neither does it uncordon the node.
+// A registered node is Ready and counts as having just sent a heartbeat.
+// It reports whether the node was newly created.
+func (r *Registry) Register(n *model.Node) (*model.Node, bool, error) {
+	n.SetDefaults()
+	if err := n.Validate(); err != nil {
+		return nil, false, err
+	}
+
+	now := r.now()
+	updated, err := store.UpdateJSON(r.store, model.NodesBucket, n.Name, func(cur *model.Node) error {
+		if n.Labels != nil {
+			cur.Labels = n.Labels
+		}
+		cur.Annotations = n.Annotations
+		taints := cur.Spec.Taints
+		if n.Spec.Taints != nil {
+			taints = stampTaints(cur.Spec.Taints, n.Spec.Taints, now)
+		}
+		unschedulable := cur.Spec.Unschedulable
+		cur.Spec = n.Spec
+		cur.Spec.Taints = taints
+		cur.Spec.Unschedulable = unschedulable
+		cur.Status.Phase = model.NodeReady
+		cur.Status.LastHeartbeatAt = now
+		return nil
+	})
+	if err == nil {
+		r.logger.Info().Str("node", n.Name).Msg("node re-registered")
+		return updated, false, nil
+	}
+	if !errors.Is(err, store.ErrNotFound) && !errors.Is(err, store.ErrBucketNotFound) {
+		return nil, false, err
+	}
+
+	if err := n.Initialize(); err != nil {
+		return nil, false, err
+	}
+	n.Spec.Taints = stampTaints(nil, n.Spec.Taints, now)
+	n.Status = model.NodeStatus{Phase: model.NodeReady, LastHeartbeatAt: now}
+
+	if err := store.CreateJSON(r.store, model.NodesBucket, n.Name, n); err != nil {
+		if errors.Is(err, store.ErrAlreadyExists) {
+			// Lost a race with a concurrent registration; treat as update.
+			return r.Register(n)
+		}
+		return nil, false, err
+	}
+
+	r.logger.Info().Str("node", n.Name).Msg("node registered")
+	return n, true, nil
+}
+
+// Update describes a change to a node's labels or taints. Nil fields are
+// left unchanged; an empty map or slice clears them.
+type Update struct {
+	Labels map[string]string `json:"labels"`
+	Taints []model.Taint     `json:"taints"`
+}
+
+// Update replaces the labels and taints of a registered node. The scheduler
+// re-evaluates placements against the new values on its next pass.
+func (r *Registry) Update(name string, u *Update) (*model.Node, error) {
+	now := r.now()
+	return store.UpdateJSON(r.store, model.NodesBucket, name, func(n *model.Node) error {
+		if u.Labels != nil {
+			n.Labels = u.Labels
+		}
+		if u.Taints != nil {
+			n.Spec.Taints = stampTaints(n.Spec.Taints, u.Taints, now)
+		}
+		return n.Validate()
+	})
+}
+
+// stampTaints returns next with AddedAt set on every taint: taints already
+// present in prev keep their original time, new ones get now.
+func stampTaints(prev, next []model.Taint, now time.Time) []model.Taint {
+	added := make(map[string]*time.Time, len(prev))
+	for _, t := range prev {
+		added[t.Key+":"+string(t.Effect)] = t.AddedAt
+	}
+
+	out := make([]model.Taint, len(next))
+	for i, t := range next {
+		t.AddedAt = added[t.Key+":"+string(t.Effect)]
+		if t.AddedAt == nil {
+			at := now
+			t.AddedAt = &at
+		}
+		out[i] = t
+	}
+	return out
+}
+
+// Heartbeat records that a node is alive and marks it Ready.
+func (r *Registry) Heartbeat(name string) (*model.Node, error) {
+	now := r.now()
+	became := false
+	n, err := store.UpdateJSON(r.store, model.NodesBucket, name, func(n *model.Node) error {
+		became = n.Status.Phase != model.NodeReady
+		n.Status.Phase = model.NodeReady
+		n.Status.LastHeartbeatAt = now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if became {
+		r.logger.Info().Str("node", name).Msg("node is ready")
+		r.events.Event(model.NodesBucket, &n.ObjectMeta, model.EventNormal, model.EventNodeReady,
+			"Node is sending heartbeats again")
+	}
+	return n, nil
+}
+
+// CheckHeartbeats marks nodes NotReady when their last heartbeat is older
+// than the heartbeat timeout.
+func (r *Registry) CheckHeartbeats() error {
+	nodes, err := store.ListJSON[model.Node](r.store, model.NodesBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing nodes: %w", err)
+	}
+
+	deadline := r.now().Add(-r.timeout)
+	var errs []error
+	for _, n := range nodes {
+		if !n.IsReady() || n.Status.LastHeartbeatAt.After(deadline) {
+			continue
+		}
+
+		marked := false
+		_, err := store.UpdateJSON(r.store, model.NodesBucket, n.Name, func(cur *model.Node) error {
+			// Re-check under the update in case a heartbeat raced in.
+			if cur.Status.LastHeartbeatAt.After(deadline) {
+				return nil
+			}
+			cur.Status.Phase = model.NodeNotReady
+			marked = true
+			return nil
+		})
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			errs = append(errs, fmt.Errorf("node %s: %w", n.Name, err))
+			continue
+		}
+		if !marked {
+			continue
+		}
+
+		r.logger.Warn().Str("node", n.Name).Time("last_heartbeat", n.Status.LastHeartbeatAt).
+			Msg("node missed heartbeats, marking NotReady")
+		r.events.Eventf(model.NodesBucket, &n.ObjectMeta, model.EventWarning, model.EventNodeNotReady,
+			"No heartbeat since %s", n.Status.LastHeartbeatAt.Format(time.RFC3339))
+	}
+	return errors.Join(errs...)
+}
+
+// Run checks heartbeats and progresses drains every heartbeat interval
+// until ctx is canceled.
+func (r *Registry) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.CheckHeartbeats(); err != nil {
+				r.logger.Error().Err(err).Msg("heartbeat check failed")
+			}
+			if err := r.ProgressDrains(); err != nil {
+				r.logger.Error().Err(err).Msg("drain progress failed")
+			}
+		}
+	}
+}
+
+// KeepAlive sends heartbeats for the named node every heartbeat interval
+// until ctx is canceled. It is used by the node the orchestrator runs on.
+func (r *Registry) KeepAlive(ctx context.Context, name string) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.Heartbeat(name); err != nil {
+				r.logger.Error().Err(err).Str("node", name).Msg("failed to send heartbeat")
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+func newTestRegistry(t *testing.T) (*Registry, *time.Time) {
+	t.Helper()
+	r := NewRegistry(&Config{
+		Store:             store.NewMemoryStore(),
+		Logger:            zerolog.Nop(),
+		HeartbeatInterval: 10 * time.Second,
+		HeartbeatTimeout:  30 * time.Second,
+	})
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	return r, &now
+}
+
+func testNode(name string) *model.Node {
+	return &model.Node{
+		ObjectMeta: model.ObjectMeta{Name: name, Labels: map[string]string{"zone": "a"}},
+		Spec:       model.NodeSpec{Capacity: model.Resources{CPUMillis: 2000, MemoryBytes: 1 << 30}},
+	}
+}
+
+func TestRegister(t *testing.T) {
+	r, now := newTestRegistry(t)
+
+	n, created, err := r.Register(testNode("node-1"))
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.NotEmpty(t, n.UID)
+	assert.Equal(t, model.NodeReady, n.Status.Phase)
+	assert.Equal(t, *now, n.Status.LastHeartbeatAt)
+
+	// Re-registering updates labels and capacity but keeps identity.
+	again := testNode("node-1")
+	again.Labels = map[string]string{"zone": "b"}
+	again.Spec.Capacity.CPUMillis = 4000
+
+	updated, created, err := r.Register(again)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, n.UID, updated.UID)
+	assert.Equal(t, "b", updated.Labels["zone"])
+	assert.Equal(t, int64(4000), updated.Spec.Capacity.CPUMillis)
+}
+
+func TestRegister_Invalid(t *testing.T) {
+	r, _ := newTestRegistry(t)
+
+	n := testNode("node-1")
+	n.Spec.Capacity.CPUMillis = 0
+
+	_, _, err := r.Register(n)
+	var verr *model.ValidationError
+	assert.ErrorAs(t, err, &verr)
+}
+
+func TestCheckHeartbeats(t *testing.T) {
+	r, now := newTestRegistry(t)
+	_, _, err := r.Register(testNode("stale"))
+	require.NoError(t, err)
+	_, _, err = r.Register(testNode("fresh"))
+	require.NoError(t, err)
+
+	*now = now.Add(31 * time.Second)
+	_, err = r.Heartbeat("fresh")
+	require.NoError(t, err)
+
+	require.NoError(t, r.CheckHeartbeats())
+
+	stale, err := store.GetJSON[model.Node](r.store, model.NodesBucket, "stale")
+	require.NoError(t, err)
+	assert.Equal(t, model.NodeNotReady, stale.Status.Phase)
+
+	fresh, err := store.GetJSON[model.Node](r.store, model.NodesBucket, "fresh")
+	require.NoError(t, err)
+	assert.Equal(t, model.NodeReady, fresh.Status.Phase)
+
+	// A heartbeat brings a NotReady node back.
+	n, err := r.Heartbeat("stale")
+	require.NoError(t, err)
+	assert.Equal(t, model.NodeReady, n.Status.Phase)
+
+	events, err := store.ListJSON[model.Event](r.store, model.EventsBucket, "")
+	require.NoError(t, err)
+	var reasons []string
+	for _, e := range events {
+		assert.Equal(t, "stale", e.InvolvedObject.Name)
+		reasons = append(reasons, e.Reason)
+	}
+	assert.ElementsMatch(t, []string{model.EventNodeNotReady, model.EventNodeReady}, reasons)
+}
+
+func TestHeartbeat_UnknownNode(t *testing.T) {
+	r, _ := newTestRegistry(t)
+
+	_, err := r.Heartbeat("missing")
+	assert.Error(t, err)
+}
+
+func TestUpdate(t *testing.T) {
+	r, now := newTestRegistry(t)
+	_, _, err := r.Register(testNode("node-1"))
+	require.NoError(t, err)
+	first := *now
+
+	n, err := r.Update("node-1", &Update{
+		Taints: []model.Taint{{Key: "maintenance", Effect: model.TaintNoExecute}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "a", n.Labels["zone"], "labels left unchanged")
+	require.Len(t, n.Spec.Taints, 1)
+	assert.Equal(t, first, *n.Spec.Taints[0].AddedAt)
+
+	// Existing taints keep the time they were added.
+	*now = now.Add(time.Minute)
+	n, err = r.Update("node-1", &Update{
+		Labels: map[string]string{"zone": "b"},
+		Taints: []model.Taint{
+			{Key: "maintenance", Effect: model.TaintNoExecute},
+			{Key: "spot", Effect: model.TaintPreferNoSchedule},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "b", n.Labels["zone"])
+	assert.Equal(t, first, *n.Spec.Taints[0].AddedAt)
+	assert.Equal(t, *now, *n.Spec.Taints[1].AddedAt)
+
+	// Re-registering without taints or labels keeps them.
+	n, _, err = r.Register(&model.Node{
+		ObjectMeta: model.ObjectMeta{Name: "node-1"},
+		Spec:       model.NodeSpec{Capacity: model.Resources{CPUMillis: 2000, MemoryBytes: 1 << 30}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "b", n.Labels["zone"])
+	assert.Len(t, n.Spec.Taints, 2)
+
+	_, err = r.Update("node-1", &Update{Taints: []model.Taint{{Key: "x", Effect: "Sometimes"}}})
+	var verr *model.ValidationError
+	assert.ErrorAs(t, err, &verr)
+
+	_, err = r.Update("missing", &Update{})
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
@@ -0,0 +1,331 @@
+// Package prober runs liveness and readiness probes against running
+// containers. Results are persisted on the container records; a liveness
+// failure marks the container unhealthy and asks the Restarter to restart it,
+// which then applies the container's restart policy.
+package prober
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/github-builder/container-orchestrator/internal/events"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/runtime"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// errStale is returned when a result is for a container instance that no
+// longer exists.
+var errStale = errors.New("container instance changed")
+
+// Restarter restarts containers that failed their liveness probe.
+type Restarter interface {
+	Restart(ctx context.Context, key, reason string) error
+}
+
+// Kind distinguishes liveness from readiness probes.
+type Kind string
+
+// Probe kinds.
+const (
+	Liveness  Kind = "liveness"
+	Readiness Kind = "readiness"
+)
+
+// Config holds dependencies for the probe manager.
+type Config struct {
+	Store     store.Store
+	Runtime   runtime.Runtime
+	Restarter Restarter
+	Logger    zerolog.Logger
+
+	// Interval is how often running containers are rescanned for probes.
+	// It is also the period of probes that do not set one.
+	Interval time.Duration
+
+	// Events records probe failures. Defaults to a recorder on Store.
+	Events *events.Recorder
+}
+
+// title returns the kind's name for the start of a sentence.
+func (k Kind) title() string {
+	return strings.ToUpper(string(k[:1])) + string(k[1:])
+}
+
+// Manager starts and stops a probe worker per running container and probe kind.
+type Manager struct {
+	store     store.Store
+	runtime   runtime.Runtime
+	restarter Restarter
+	logger    zerolog.Logger
+	interval  time.Duration
+	events    *events.Recorder
+
+	mu      sync.Mutex
+	workers map[string]*worker
+}
+
+// worker probes a single container instance.
+type worker struct {
+	kind  Kind
+	probe *model.Probe
+
+	// Identity of the container instance being probed. container is the
+	// store key of its record.
+	container    string
+	uid          string
+	runtimeID    string
+	restartCount int
+
+	// object identifies the container in events.
+	object model.ObjectMeta
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewManager creates a probe manager.
+func NewManager(cfg *Config) *Manager {
+	recorder := cfg.Events
+	if recorder == nil {
+		recorder = events.NewRecorder(&events.Config{Store: cfg.Store, Logger: cfg.Logger})
+	}
+
+	return &Manager{
+		store:     cfg.Store,
+		runtime:   cfg.Runtime,
+		restarter: cfg.Restarter,
+		logger:    cfg.Logger,
+		interval:  cfg.Interval,
+		events:    recorder.WithSource("prober"),
+		workers:   make(map[string]*worker),
+	}
+}
+
+// Run rescans containers every interval until ctx is canceled, then stops
+// all workers.
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	defer m.stopAll()
+
+	for {
+		if err := m.Sync(ctx); err != nil {
+			m.logger.Error().Err(err).Msg("probe sync failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync starts workers for newly running containers and stops workers whose
+// container instance has gone away.
+func (m *Manager) Sync(ctx context.Context) error {
+	containers, err := store.ListJSON[model.Container](m.store, model.ContainersBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing containers: %w", err)
+	}
+
+	desired := make(map[string]*worker)
+	for _, c := range containers {
+		if c.Status.Phase != model.PhaseRunning || c.Status.RuntimeID == "" {
+			continue
+		}
+		if c.Spec.LivenessProbe != nil {
+			w := newWorker(c, Liveness, c.Spec.LivenessProbe)
+			desired[w.key()] = w
+		}
+		if c.Spec.ReadinessProbe != nil {
+			w := newWorker(c, Readiness, c.Spec.ReadinessProbe)
+			desired[w.key()] = w
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, w := range m.workers {
+		if _, ok := desired[key]; !ok {
+			w.cancel()
+			delete(m.workers, key)
+		}
+	}
+
+	for key, w := range desired {
+		if _, ok := m.workers[key]; ok {
+			continue
+		}
+		wctx, cancel := context.WithCancel(ctx)
+		w.cancel = cancel
+		m.workers[key] = w
+		go m.runWorker(wctx, w)
+	}
+
+	return nil
+}
+
+// stopAll cancels every worker and waits for them to exit.
+func (m *Manager) stopAll() {
+	m.mu.Lock()
+	workers := make([]*worker, 0, len(m.workers))
+	for key, w := range m.workers {
+		w.cancel()
+		workers = append(workers, w)
+		delete(m.workers, key)
+	}
+	m.mu.Unlock()
+
+	for _, w := range workers {
+		<-w.done
+	}
+}
+
+func newWorker(c *model.Container, kind Kind, p *model.Probe) *worker {
+	return &worker{
+		kind:         kind,
+		probe:        p,
+		container:    c.Key(),
+		uid:          c.UID,
+		runtimeID:    c.Status.RuntimeID,
+		restartCount: c.Status.RestartCount,
+		object:       model.ObjectMeta{Name: c.Name, Namespace: c.Namespace, UID: c.UID},
+		done:         make(chan struct{}),
+	}
+}
+
+// key identifies the container instance and probe kind. A restart changes
+// the key, so probing starts over with a fresh initial delay.
+func (w *worker) key() string {
+	return fmt.Sprintf("%s/%s/%d/%s", w.uid, w.runtimeID, w.restartCount, w.kind)
+}
+
+// runWorker probes at the configured period and records result transitions.
+func (m *Manager) runWorker(ctx context.Context, w *worker) {
+	defer close(w.done)
+
+	log := m.logger.With().Str("container", w.container).Str("probe", string(w.kind)).Logger()
+
+	if !sleep(ctx, w.probe.InitialDelay()) {
+		return
+	}
+
+	ticker := time.NewTicker(w.probe.Period(m.interval))
+	defer ticker.Stop()
+
+	var (
+		reported  *bool
+		successes int
+		failures  int
+	)
+
+	for {
+		ok, msg := m.runProbe(ctx, w)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if ok {
+			successes++
+			failures = 0
+		} else {
+			failures++
+			successes = 0
+			m.events.Eventf(model.ContainersBucket, &w.object, model.EventWarning, model.EventUnhealthy,
+				"%s probe failed: %s", w.kind.title(), msg)
+		}
+
+		var transition *bool
+		switch {
+		case ok && successes >= w.probe.Successes() && (reported == nil || !*reported):
+			transition = &ok
+		case !ok && failures >= w.probe.Failures() && (reported == nil || *reported):
+			transition = &ok
+		}
+
+		if transition != nil {
+			reported = transition
+			err := m.record(w, *transition, msg)
+			if errors.Is(err, errStale) {
+				return
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to record probe result")
+			}
+
+			if !*transition && w.kind == Liveness {
+				log.Warn().Str("message", msg).Int("failures", failures).Msg("liveness probe failed, restarting container")
+				m.events.Event(model.ContainersBucket, &w.object, model.EventNormal, model.EventKilling,
+					"Container failed its liveness probe and will be restarted")
+				if err := m.restarter.Restart(ctx, w.container, model.ReasonLivenessProbeFailed); err != nil {
+					log.Error().Err(err).Msg("failed to restart container")
+				}
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// record persists a probe result transition on the container record.
+func (m *Manager) record(w *worker, success bool, msg string) error {
+	result := &model.ProbeResult{
+		Success:          success,
+		Message:          msg,
+		LastTransitionAt: time.Now().UTC(),
+	}
+
+	_, err := store.UpdateJSON(m.store, model.ContainersBucket, w.container, func(c *model.Container) error {
+		if c.UID != w.uid || c.Status.RuntimeID != w.runtimeID ||
+			c.Status.RestartCount != w.restartCount || c.Status.Phase != model.PhaseRunning {
+			return errStale
+		}
+
+		switch w.kind {
+		case Liveness:
+			c.Status.Liveness = result
+			c.Status.Health = model.HealthHealthy
+			if !success {
+				c.Status.Health = model.HealthUnhealthy
+			}
+		case Readiness:
+			c.Status.Readiness = result
+			c.Status.Ready = success
+		}
+		return nil
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		return errStale
+	}
+	return err
+}
+
+// sleep waits for d or until ctx is canceled, reporting whether d elapsed.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,241 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/runtime"
+	"github.com/github-builder/container-orchestrator/internal/runtime/fake"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+type fakeRestarter struct {
+	mu      sync.Mutex
+	reasons map[string]string
+}
+
+func (r *fakeRestarter) Restart(_ context.Context, name, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reasons[name] = reason
+	return nil
+}
+
+func (r *fakeRestarter) reason(name string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reasons[name]
+}
+
+type testEnv struct {
+	store     store.Store
+	runtime   *fake.Runtime
+	restarter *fakeRestarter
+	manager   *Manager
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	env := &testEnv{
+		store:     store.NewMemoryStore(),
+		runtime:   fake.New(),
+		restarter: &fakeRestarter{reasons: make(map[string]string)},
+	}
+	env.manager = NewManager(&Config{
+		Store:     env.store,
+		Runtime:   env.runtime,
+		Restarter: env.restarter,
+		Logger:    zerolog.Nop(),
+		Interval:  10 * time.Millisecond,
+	})
+	return env
+}
+
+// runContainer stores a running container backed by a fake runtime container.
+func (e *testEnv) runContainer(t *testing.T, name string, liveness, readiness *model.Probe) {
+	t.Helper()
+	ctx := context.Background()
+
+	id, err := e.runtime.Create(ctx, &runtime.ContainerConfig{Name: name})
+	require.NoError(t, err)
+	require.NoError(t, e.runtime.Start(ctx, id))
+
+	c := &model.Container{
+		ObjectMeta: model.ObjectMeta{Name: name},
+		Spec: model.ContainerSpec{
+			Image:          "app",
+			LivenessProbe:  liveness,
+			ReadinessProbe: readiness,
+		},
+		Status: model.ContainerStatus{
+			Phase:     model.PhaseRunning,
+			RuntimeID: id,
+		},
+	}
+	c.SetDefaults()
+	require.NoError(t, c.Initialize())
+	require.NoError(t, store.PutJSON(e.store, model.ContainersBucket, name, c))
+}
+
+func (e *testEnv) get(t *testing.T, name string) *model.Container {
+	t.Helper()
+	c, err := store.GetJSON[model.Container](e.store, model.ContainersBucket, name)
+	require.NoError(t, err)
+	return c
+}
+
+func (e *testEnv) run(t *testing.T) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		e.manager.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func TestProber_HTTPReadiness(t *testing.T) {
+	var healthy atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/ready", r.URL.Path)
+		if healthy.Load() {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	env := newTestEnv(t)
+	env.runContainer(t, "web", nil, &model.Probe{
+		HTTPGet:          &model.HTTPGetAction{Path: "ready", Port: serverPort(t, srv)},
+		FailureThreshold: 1,
+	})
+	env.run(t)
+
+	require.Eventually(t, func() bool {
+		r := env.get(t, "web").Status.Readiness
+		return r != nil && !r.Success
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Contains(t, env.get(t, "web").Status.Readiness.Message, "503")
+
+	healthy.Store(true)
+	require.Eventually(t, func() bool {
+		return env.get(t, "web").Status.Ready
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// Readiness failures never restart a container.
+	assert.Empty(t, env.restarter.reason("web"))
+}
+
+func TestProber_TCPLiveness(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+
+	env := newTestEnv(t)
+	env.runContainer(t, "db", &model.Probe{
+		TCPSocket: &model.TCPSocketAction{Port: ln.Addr().(*net.TCPAddr).Port},
+	}, nil)
+	env.run(t)
+
+	require.Eventually(t, func() bool {
+		return env.get(t, "db").Status.Health == model.HealthHealthy
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.True(t, env.get(t, "db").Status.Liveness.Success)
+}
+
+func TestProber_LivenessFailureRestarts(t *testing.T) {
+	env := newTestEnv(t)
+	var calls atomic.Int32
+	env.runtime.ExecHandler = func(_ string, cmd []string) (*runtime.ExecResult, error) {
+		calls.Add(1)
+		return &runtime.ExecResult{ExitCode: 1, Output: []byte("not ok")}, nil
+	}
+	env.runContainer(t, "app", &model.Probe{
+		Exec:             &model.ExecAction{Command: []string{"check"}},
+		FailureThreshold: 3,
+	}, nil)
+	env.run(t)
+
+	require.Eventually(t, func() bool {
+		return env.restarter.reason("app") == model.ReasonLivenessProbeFailed
+	}, 2*time.Second, 10*time.Millisecond)
+
+	c := env.get(t, "app")
+	assert.Equal(t, model.HealthUnhealthy, c.Status.Health)
+	require.NotNil(t, c.Status.Liveness)
+	assert.False(t, c.Status.Liveness.Success)
+	assert.Contains(t, c.Status.Liveness.Message, "not ok")
+	assert.GreaterOrEqual(t, calls.Load(), int32(3))
+}
+
+func TestProber_InitialDelay(t *testing.T) {
+	env := newTestEnv(t)
+	var calls atomic.Int32
+	env.runtime.ExecHandler = func(string, []string) (*runtime.ExecResult, error) {
+		calls.Add(1)
+		return &runtime.ExecResult{}, nil
+	}
+	env.runContainer(t, "app", &model.Probe{
+		Exec:                &model.ExecAction{Command: []string{"check"}},
+		InitialDelaySeconds: 60,
+	}, nil)
+	env.run(t)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Zero(t, calls.Load())
+	assert.Nil(t, env.get(t, "app").Status.Liveness)
+}
+
+func TestProber_StopsWorkersForStoppedContainers(t *testing.T) {
+	env := newTestEnv(t)
+	env.runContainer(t, "app", &model.Probe{Exec: &model.ExecAction{Command: []string{"check"}}}, nil)
+	ctx := context.Background()
+
+	require.NoError(t, env.manager.Sync(ctx))
+	assert.Len(t, env.manager.workers, 1)
+
+	require.NoError(t, env.store.Delete(model.ContainersBucket, "app"))
+	require.NoError(t, env.manager.Sync(ctx))
+	assert.Empty(t, env.manager.workers)
+	env.manager.stopAll()
+}
+
+func TestProber_IgnoresStaleInstance(t *testing.T) {
+	env := newTestEnv(t)
+	env.runContainer(t, "app", nil, nil)
+	c := env.get(t, "app")
+
+	w := newWorker(c, Readiness, &model.Probe{})
+	w.restartCount = c.Status.RestartCount + 1
+
+	err := env.manager.record(w, true, "")
+	assert.ErrorIs(t, err, errStale)
+	assert.False(t, env.get(t, "app").Status.Ready)
+}
+
+func serverPort(t *testing.T, srv *httptest.Server) int {
+	t.Helper()
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+	p, err := strconv.Atoi(port)
+	require.NoError(t, err)
+	return p
+}
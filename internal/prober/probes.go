@@ -0,0 +1,116 @@
+package prober
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+// maxExecOutput bounds the exec output kept in a probe message.
+const maxExecOutput = 256
+
+// httpClient is shared by all HTTP probes. Redirects are not followed, so a
+// 3xx response counts as success without reaching another host. Certificates
+// are not verified because containers commonly serve self-signed ones.
+var httpClient = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+	Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true}, //nolint:gosec // Probes check liveness, not identity.
+		DisableKeepAlives: true,
+	},
+}
+
+// runProbe executes a single probe attempt and reports success and a message.
+func (m *Manager) runProbe(ctx context.Context, w *worker) (bool, string) {
+	ctx, cancel := context.WithTimeout(ctx, w.probe.Timeout())
+	defer cancel()
+
+	info, err := m.runtime.Inspect(ctx, w.runtimeID)
+	if err != nil {
+		return false, fmt.Sprintf("inspecting container: %v", err)
+	}
+
+	p := w.probe
+	switch {
+	case p.HTTPGet != nil:
+		return probeHTTP(ctx, info.IPAddress, p.HTTPGet)
+	case p.TCPSocket != nil:
+		return probeTCP(ctx, info.IPAddress, p.TCPSocket)
+	case p.Exec != nil:
+		return m.probeExec(ctx, w.runtimeID, p.Exec)
+	default:
+		return false, "probe has no handler"
+	}
+}
+
+func probeHTTP(ctx context.Context, defaultHost string, action *model.HTTPGetAction) (bool, string) {
+	host := action.Host
+	if host == "" {
+		host = defaultHost
+	}
+	scheme := "http"
+	if action.Scheme == "HTTPS" {
+		scheme = "https"
+	}
+	path := action.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	url := scheme + "://" + net.JoinHostPort(host, strconv.Itoa(action.Port)) + path
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return false, err.Error()
+	}
+	req.Header.Set("User-Agent", "container-orchestrator-probe")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return false, err.Error()
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 400 {
+		return true, ""
+	}
+	return false, fmt.Sprintf("HTTP probe failed with status %d", resp.StatusCode)
+}
+
+func probeTCP(ctx context.Context, defaultHost string, action *model.TCPSocketAction) (bool, string) {
+	host := action.Host
+	if host == "" {
+		host = defaultHost
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(action.Port)))
+	if err != nil {
+		return false, err.Error()
+	}
+	_ = conn.Close()
+	return true, ""
+}
+
+func (m *Manager) probeExec(ctx context.Context, runtimeID string, action *model.ExecAction) (bool, string) {
+	res, err := m.runtime.Exec(ctx, runtimeID, action.Command)
+	if err != nil {
+		return false, err.Error()
+	}
+	if res.ExitCode == 0 {
+		return true, ""
+	}
+
+	out := strings.TrimSpace(string(res.Output))
+	if len(out) > maxExecOutput {
+		out = out[:maxExecOutput]
+	}
+	return false, fmt.Sprintf("command exited with code %d: %s", res.ExitCode, out)
+}
@@ -0,0 +1,539 @@
+// Package docker implements runtime.Runtime on a Docker daemon, through its
+// HTTP API at DOCKER_HOST. Containers are created from images pulled from
+// their registries, with the credentials the orchestrator passes along,
+// and run in the daemon's default bridge network. A sandbox is a pause
+// container whose network namespace the members of a group join. Only
+// plain unix and tcp hosts are supported, not TLS.
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/github-builder/container-orchestrator/internal/runtime"
+)
+
+// apiVersion is the Docker API version requests are made against. 1.41
+// shipped with Docker 20.10.
+const apiVersion = "v1.41"
+
+// Config holds settings for the Docker runtime.
+type Config struct {
+	// Host is the address of the Docker daemon in DOCKER_HOST form, e.g.
+	// "unix:///var/run/docker.sock" or "tcp://127.0.0.1:2375".
+	Host string
+
+	// PauseImage is the image of the containers holding sandboxes. It is
+	// pulled when first needed. Defaults to DefaultPauseImage.
+	PauseImage string
+
+	Logger zerolog.Logger
+}
+
+// Runtime runs containers on a Docker daemon.
+type Runtime struct {
+	client     *http.Client
+	base       string
+	pauseImage string
+	logger     zerolog.Logger
+}
+
+var (
+	_ runtime.Runtime        = (*Runtime)(nil)
+	_ runtime.StatsRuntime   = (*Runtime)(nil)
+	_ runtime.ImageRuntime   = (*Runtime)(nil)
+	_ runtime.SandboxRuntime = (*Runtime)(nil)
+)
+
+// NewRuntime creates a runtime talking to the daemon at cfg.Host. It does
+// not contact the daemon; use Ping for that.
+func NewRuntime(cfg *Config) (*Runtime, error) {
+	u, err := url.Parse(cfg.Host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker host %q: %w", cfg.Host, err)
+	}
+
+	r := &Runtime{client: &http.Client{}, pauseImage: cfg.PauseImage, logger: cfg.Logger}
+	if r.pauseImage == "" {
+		r.pauseImage = DefaultPauseImage
+	}
+	switch u.Scheme {
+	case "unix":
+		if u.Path == "" {
+			return nil, fmt.Errorf("invalid docker host %q: no socket path", cfg.Host)
+		}
+		socket := u.Path
+		r.client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		}
+		// The host name is ignored when dialing the socket.
+		r.base = "http://docker"
+	case "tcp", "http":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid docker host %q: no address", cfg.Host)
+		}
+		r.base = "http://" + u.Host
+	default:
+		return nil, fmt.Errorf("unsupported docker host %q: want unix:// or tcp://", cfg.Host)
+	}
+	return r, nil
+}
+
+// Ping checks that the daemon answers.
+func (r *Runtime) Ping(ctx context.Context) error {
+	resp, err := r.do(ctx, http.MethodGet, "/_ping", nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// createRequest is the body of a container create request.
+type createRequest struct {
+	Image      string
+	Entrypoint []string          `json:",omitempty"`
+	Cmd        []string          `json:",omitempty"`
+	Env        []string          `json:",omitempty"`
+	WorkingDir string            `json:",omitempty"`
+	Labels     map[string]string `json:",omitempty"`
+	HostConfig hostConfig
+}
+
+type hostConfig struct {
+	NanoCPUs    int64    `json:"NanoCpus,omitempty"`
+	Memory      int64    `json:",omitempty"`
+	Ulimits     []ulimit `json:",omitempty"`
+	Binds       []string `json:",omitempty"`
+	NetworkMode string   `json:",omitempty"`
+}
+
+type ulimit struct {
+	Name string
+	Soft uint64
+	Hard uint64
+}
+
+// containerName makes name acceptable to Docker, which allows letters,
+// digits, '_', '.' and '-' after a leading letter or digit. Other
+// characters become '_', and leading punctuation is dropped.
+func containerName(name string) string {
+	name = strings.Map(func(c rune) rune {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '_', c == '.', c == '-':
+			return c
+		}
+		return '_'
+	}, name)
+	return strings.TrimLeft(name, "_.-")
+}
+
+// Create creates a container from an image that has been pulled. A
+// command replaces the image's entrypoint and arguments.
+func (r *Runtime) Create(ctx context.Context, cfg *runtime.ContainerConfig) (string, error) {
+	if cfg.Image == "" {
+		return "", errors.New("the docker runtime needs an image")
+	}
+
+	req := createRequest{
+		Image:      cfg.Image,
+		WorkingDir: cfg.WorkingDir,
+		Labels:     cfg.Labels,
+		HostConfig: hostConfig{
+			NanoCPUs: cfg.Resources.CPUMillis * 1_000_000,
+			Memory:   cfg.Resources.MemoryBytes,
+		},
+	}
+	if len(cfg.Command) > 0 {
+		req.Entrypoint = cfg.Command[:1]
+		req.Cmd = cfg.Command[1:]
+	}
+	for k, v := range cfg.Env {
+		req.Env = append(req.Env, k+"="+v)
+	}
+	sort.Strings(req.Env)
+	for _, l := range cfg.Rlimits {
+		req.HostConfig.Ulimits = append(req.HostConfig.Ulimits, ulimit{Name: l.Name, Soft: l.Soft, Hard: l.Hard})
+	}
+	for _, m := range cfg.Mounts {
+		bind := m.Source + ":" + m.Target
+		if m.ReadOnly {
+			bind += ":ro"
+		}
+		req.HostConfig.Binds = append(req.HostConfig.Binds, bind)
+	}
+	if cfg.Sandbox != "" {
+		req.HostConfig.NetworkMode = sandboxNetwork + cfg.Sandbox
+	}
+
+	query := url.Values{}
+	if cfg.Name != "" {
+		query.Set("name", containerName(cfg.Name))
+	}
+	var created struct {
+		ID string `json:"Id"`
+	}
+	err := r.call(ctx, http.MethodPost, "/containers/create", query, req, &created)
+	switch statusOf(err) {
+	case http.StatusNotFound:
+		return "", fmt.Errorf("%w: %s", runtime.ErrImageNotPresent, cfg.Image)
+	case http.StatusConflict:
+		return "", fmt.Errorf("%w: %s", runtime.ErrAlreadyExists, cfg.Name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("creating container: %w", err)
+	}
+	return created.ID, nil
+}
+
+// Start starts a created or exited container.
+func (r *Runtime) Start(ctx context.Context, id string) error {
+	resp, err := r.do(ctx, http.MethodPost, "/containers/"+id+"/start", nil, nil, nil)
+	if err != nil {
+		return containerError(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return runtime.ErrRunning
+	}
+	return nil
+}
+
+// Stop sends the container SIGTERM and SIGKILL after timeout.
+func (r *Runtime) Stop(ctx context.Context, id string, timeout time.Duration) error {
+	query := url.Values{"t": {strconv.Itoa(int(timeout.Seconds()))}}
+	return containerError(r.call(ctx, http.MethodPost, "/containers/"+id+"/stop", query, nil, nil))
+}
+
+// Remove deletes a stopped container.
+func (r *Runtime) Remove(ctx context.Context, id string) error {
+	err := r.call(ctx, http.MethodDelete, "/containers/"+id, nil, nil, nil)
+	if statusOf(err) == http.StatusConflict {
+		return runtime.ErrRunning
+	}
+	return containerError(err)
+}
+
+// inspectResponse is the part of a container inspection the runtime uses.
+type inspectResponse struct {
+	ID    string `json:"Id"`
+	Name  string
+	State struct {
+		Status     string
+		Pid        int
+		ExitCode   int
+		Error      string
+		StartedAt  time.Time
+		FinishedAt time.Time
+	}
+	Config struct {
+		Labels map[string]string
+	}
+	HostConfig struct {
+		NetworkMode string
+	}
+	NetworkSettings struct {
+		IPAddress string
+	}
+}
+
+// Inspect returns the state of a container.
+func (r *Runtime) Inspect(ctx context.Context, id string) (*runtime.ContainerInfo, error) {
+	var in inspectResponse
+	if err := r.call(ctx, http.MethodGet, "/containers/"+id+"/json", nil, nil, &in); err != nil {
+		return nil, containerError(err)
+	}
+	info := &runtime.ContainerInfo{
+		ID:         in.ID,
+		Name:       strings.TrimPrefix(in.Name, "/"),
+		Labels:     in.Config.Labels,
+		State:      stateOf(in.State.Status),
+		Pid:        in.State.Pid,
+		ExitCode:   in.State.ExitCode,
+		StartedAt:  in.State.StartedAt,
+		FinishedAt: in.State.FinishedAt,
+		IPAddress:  in.NetworkSettings.IPAddress,
+		Error:      in.State.Error,
+	}
+	// Members of a sandbox are reached at its address.
+	if sandbox, ok := strings.CutPrefix(in.HostConfig.NetworkMode, sandboxNetwork); ok && info.IPAddress == "" {
+		var s inspectResponse
+		if err := r.call(ctx, http.MethodGet, "/containers/"+sandbox+"/json", nil, nil, &s); err == nil {
+			info.IPAddress = s.NetworkSettings.IPAddress
+		}
+	}
+	return info, nil
+}
+
+// List returns the containers whose labels include all of labels. Only
+// their ID, name, labels and state are filled in.
+func (r *Runtime) List(ctx context.Context, labels map[string]string) ([]*runtime.ContainerInfo, error) {
+	var filter []string
+	for k, v := range labels {
+		filter = append(filter, k+"="+v)
+	}
+	filters, err := json.Marshal(map[string][]string{"label": filter})
+	if err != nil {
+		return nil, err
+	}
+
+	var list []struct {
+		ID     string `json:"Id"`
+		Names  []string
+		Labels map[string]string
+		State  string
+	}
+	query := url.Values{"all": {"true"}, "filters": {string(filters)}}
+	if err := r.call(ctx, http.MethodGet, "/containers/json", query, nil, &list); err != nil {
+		return nil, fmt.Errorf("listing containers: %w", err)
+	}
+
+	infos := make([]*runtime.ContainerInfo, 0, len(list))
+	for _, c := range list {
+		info := &runtime.ContainerInfo{ID: c.ID, Labels: c.Labels, State: stateOf(c.State)}
+		if len(c.Names) > 0 {
+			info.Name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// Wait blocks until the container exits.
+func (r *Runtime) Wait(ctx context.Context, id string) (int, error) {
+	info, err := r.Inspect(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if info.State == runtime.StateCreated {
+		return 0, runtime.ErrNotRunning
+	}
+
+	var res struct {
+		StatusCode int
+		Error      *struct{ Message string }
+	}
+	if err := r.call(ctx, http.MethodPost, "/containers/"+id+"/wait", nil, nil, &res); err != nil {
+		return 0, containerError(err)
+	}
+	if res.Error != nil && res.Error.Message != "" {
+		return res.StatusCode, fmt.Errorf("waiting for container: %s", res.Error.Message)
+	}
+	return res.StatusCode, nil
+}
+
+// Logs returns the output the container has written so far.
+func (r *Runtime) Logs(ctx context.Context, id string) (io.ReadCloser, error) {
+	query := url.Values{"stdout": {"true"}, "stderr": {"true"}}
+	resp, err := r.do(ctx, http.MethodGet, "/containers/"+id+"/logs", query, nil, nil)
+	if err != nil {
+		return nil, containerError(err)
+	}
+	defer resp.Body.Close()
+
+	out, err := demux(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading logs: %w", err)
+	}
+	return io.NopCloser(bytes.NewReader(out)), nil
+}
+
+// Exec runs a command in a running container and waits for it.
+func (r *Runtime) Exec(ctx context.Context, id string, cmd []string) (*runtime.ExecResult, error) {
+	var exec struct {
+		ID string `json:"Id"`
+	}
+	body := map[string]any{"Cmd": cmd, "AttachStdout": true, "AttachStderr": true}
+	err := r.call(ctx, http.MethodPost, "/containers/"+id+"/exec", nil, body, &exec)
+	if statusOf(err) == http.StatusConflict {
+		return nil, runtime.ErrNotRunning
+	}
+	if err != nil {
+		return nil, containerError(err)
+	}
+
+	resp, err := r.do(ctx, http.MethodPost, "/exec/"+exec.ID+"/start", nil, map[string]any{"Detach": false}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("starting exec: %w", err)
+	}
+	output, err := demux(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading exec output: %w", err)
+	}
+
+	var state struct{ ExitCode int }
+	if err := r.call(ctx, http.MethodGet, "/exec/"+exec.ID+"/json", nil, nil, &state); err != nil {
+		return nil, fmt.Errorf("inspecting exec: %w", err)
+	}
+	return &runtime.ExecResult{ExitCode: state.ExitCode, Output: output}, nil
+}
+
+// Stats samples the CPU time and memory of a running container. Docker
+// does not collect application metrics.
+func (r *Runtime) Stats(ctx context.Context, id string) (*runtime.Stats, error) {
+	info, err := r.Inspect(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if info.State != runtime.StateRunning {
+		return nil, runtime.ErrNotRunning
+	}
+
+	var st struct {
+		Read     time.Time
+		CPUStats struct {
+			CPUUsage struct {
+				TotalUsage uint64 `json:"total_usage"`
+			} `json:"cpu_usage"`
+		} `json:"cpu_stats"`
+		MemoryStats struct {
+			Usage uint64            `json:"usage"`
+			Stats map[string]uint64 `json:"stats"`
+		} `json:"memory_stats"`
+	}
+	query := url.Values{"stream": {"false"}, "one-shot": {"true"}}
+	if err := r.call(ctx, http.MethodGet, "/containers/"+id+"/stats", query, nil, &st); err != nil {
+		return nil, containerError(err)
+	}
+
+	// Like docker stats, leave out the page cache the kernel can reclaim:
+	// inactive_file on cgroup v2, total_inactive_file on v1.
+	mem := st.MemoryStats.Usage
+	inactive, ok := st.MemoryStats.Stats["inactive_file"]
+	if !ok {
+		inactive = st.MemoryStats.Stats["total_inactive_file"]
+	}
+	if inactive < mem {
+		mem -= inactive
+	}
+	return &runtime.Stats{
+		CPUTime:     time.Duration(st.CPUStats.CPUUsage.TotalUsage),
+		MemoryBytes: int64(mem),
+		Time:        st.Read,
+	}, nil
+}
+
+// stateOf maps a Docker container status to a runtime state. Paused and
+// restarting containers still hold their process, so they count as
+// running.
+func stateOf(status string) runtime.State {
+	switch status {
+	case "created":
+		return runtime.StateCreated
+	case "running", "paused", "restarting":
+		return runtime.StateRunning
+	default:
+		return runtime.StateExited
+	}
+}
+
+// apiError is an error response from the daemon.
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("docker: %s (status %d)", e.message, e.status)
+}
+
+// statusOf returns the HTTP status of a daemon error, or 0 for other
+// errors.
+func statusOf(err error) int {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr.status
+	}
+	return 0
+}
+
+// containerError maps a 404 from a container endpoint to
+// runtime.ErrNotFound.
+func containerError(err error) error {
+	if statusOf(err) == http.StatusNotFound {
+		return runtime.ErrNotFound
+	}
+	return err
+}
+
+// call makes a request and decodes the JSON response into out, unless out
+// is nil.
+func (r *Runtime) call(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	resp, err := r.do(ctx, method, path, query, body, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding %s response: %w", path, err)
+	}
+	return nil
+}
+
+// do makes a request with body encoded as JSON, unless it is nil. Error
+// responses are returned as *apiError, with their body closed.
+func (r *Runtime) do(ctx context.Context, method, path string, query url.Values, body any, header http.Header) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	u := r.base + "/" + apiVersion + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("docker: %w", err)
+	}
+	if resp.StatusCode < http.StatusBadRequest {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	apiErr := &apiError{status: resp.StatusCode, message: http.StatusText(resp.StatusCode)}
+	var msg struct{ Message string }
+	if data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10)); err == nil {
+		if json.Unmarshal(data, &msg) == nil && msg.Message != "" {
+			apiErr.message = msg.Message
+		} else if s := strings.TrimSpace(string(data)); s != "" {
+			apiErr.message = s
+		}
+	}
+	return nil, apiErr
+}
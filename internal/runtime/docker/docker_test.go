@@ -0,0 +1,253 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/runtime"
+)
+
+// newTestRuntime returns a runtime talking to a fake daemon serving mux.
+func newTestRuntime(t *testing.T, mux *http.ServeMux) *Runtime {
+	t.Helper()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	r, err := NewRuntime(&Config{Host: "tcp://" + strings.TrimPrefix(srv.URL, "http://"), Logger: zerolog.Nop()})
+	require.NoError(t, err)
+	return r
+}
+
+// frame encodes p as one frame of a multiplexed stream.
+func frame(stream byte, p string) []byte {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(p)))
+	return append(header, p...)
+}
+
+func TestNewRuntime_Hosts(t *testing.T) {
+	for _, host := range []string{"unix:///var/run/docker.sock", "tcp://127.0.0.1:2375"} {
+		_, err := NewRuntime(&Config{Host: host})
+		assert.NoError(t, err, host)
+	}
+	for _, host := range []string{"ssh://user@host", "unix://", "tcp://"} {
+		_, err := NewRuntime(&Config{Host: host})
+		assert.Error(t, err, host)
+	}
+}
+
+func TestRuntime_CreateAndInspect(t *testing.T) {
+	var got createRequest
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1.41/containers/create", func(w http.ResponseWriter, req *http.Request) {
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&got))
+		if got.Image == "missing:v1" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"message": "No such image: missing:v1"}`)
+			return
+		}
+		assert.Equal(t, "default_web", req.URL.Query().Get("name"))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, `{"Id": "abc123"}`)
+	})
+	mux.HandleFunc("GET /v1.41/containers/abc123/json", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, `{
+			"Id": "abc123", "Name": "/web",
+			"State": {"Status": "exited", "ExitCode": 3, "StartedAt": "2024-01-01T12:00:00Z", "FinishedAt": "2024-01-01T12:05:00Z"},
+			"Config": {"Labels": {"app": "web"}},
+			"NetworkSettings": {"IPAddress": "172.17.0.2"}
+		}`)
+	})
+	r := newTestRuntime(t, mux)
+	ctx := context.Background()
+
+	id, err := r.Create(ctx, &runtime.ContainerConfig{
+		Name:      "default/web",
+		Image:     "nginx:1.25",
+		Command:   []string{"nginx", "-g", "daemon off;"},
+		Env:       map[string]string{"B": "2", "A": "1"},
+		Labels:    map[string]string{"app": "web"},
+		Resources: runtime.Resources{CPUMillis: 500, MemoryBytes: 64 << 20},
+		Mounts:    []runtime.Mount{{Source: "/data/web", Target: "/usr/share/nginx/html", ReadOnly: true}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", id)
+	assert.Equal(t, []string{"nginx"}, got.Entrypoint)
+	assert.Equal(t, []string{"-g", "daemon off;"}, got.Cmd)
+	assert.Equal(t, []string{"A=1", "B=2"}, got.Env)
+	assert.Equal(t, int64(500_000_000), got.HostConfig.NanoCPUs)
+	assert.Equal(t, []string{"/data/web:/usr/share/nginx/html:ro"}, got.HostConfig.Binds)
+
+	_, err = r.Create(ctx, &runtime.ContainerConfig{Name: "other", Image: "missing:v1"})
+	assert.ErrorIs(t, err, runtime.ErrImageNotPresent)
+
+	info, err := r.Inspect(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "web", info.Name)
+	assert.Equal(t, runtime.StateExited, info.State)
+	assert.Equal(t, 3, info.ExitCode)
+	assert.Equal(t, "172.17.0.2", info.IPAddress)
+	assert.Equal(t, "web", info.Labels["app"])
+
+	_, err = r.Inspect(ctx, "gone")
+	assert.ErrorIs(t, err, runtime.ErrNotFound)
+}
+
+func TestRuntime_PullImage(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1.41/images/create", func(w http.ResponseWriter, req *http.Request) {
+		data, err := base64.URLEncoding.DecodeString(req.Header.Get("X-Registry-Auth"))
+		require.NoError(t, err)
+		var auth map[string]string
+		require.NoError(t, json.Unmarshal(data, &auth))
+		if auth["password"] != "s3cret" {
+			_, _ = io.WriteString(w, `{"error": "unauthorized: authentication required"}`)
+			return
+		}
+
+		assert.Equal(t, "registry.example.com/app", req.URL.Query().Get("fromImage"))
+		assert.Equal(t, "latest", req.URL.Query().Get("tag"))
+		assert.Equal(t, "registry.example.com", auth["serveraddress"])
+		for _, line := range []string{
+			`{"status": "Pulling from app", "id": "latest"}`,
+			`{"status": "Downloading", "id": "l1", "progressDetail": {"current": 50, "total": 100}}`,
+			`{"status": "Downloading", "id": "l2", "progressDetail": {"current": 100, "total": 300}}`,
+			`{"status": "Download complete", "id": "l1"}`,
+			`{"status": "Status: Downloaded newer image for registry.example.com/app:latest"}`,
+		} {
+			_, _ = io.WriteString(w, line+"\n")
+		}
+	})
+	mux.HandleFunc("GET /v1.41/images/{name...}", func(w http.ResponseWriter, req *http.Request) {
+		if req.PathValue("name") != "nginx:1.25/json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = io.WriteString(w, `{"Id": "sha256:abc"}`)
+	})
+	r := newTestRuntime(t, mux)
+	ctx := context.Background()
+
+	var progress []runtime.PullProgress
+	auth := &runtime.RegistryAuth{Server: "registry.example.com", Username: "ci", Password: "s3cret"}
+	require.NoError(t, r.PullImage(ctx, "registry.example.com/app", auth, func(p runtime.PullProgress) {
+		progress = append(progress, p)
+	}))
+	require.Len(t, progress, 5)
+	assert.Equal(t, runtime.PullProgress{Status: "Downloading", Current: 150, Total: 400}, progress[2])
+	assert.Equal(t, runtime.PullProgress{Status: "Download complete", Current: 200, Total: 400}, progress[3])
+
+	auth.Password = "wrong"
+	err := r.PullImage(ctx, "registry.example.com/app", auth, nil)
+	assert.ErrorIs(t, err, runtime.ErrUnauthorized)
+
+	ok, err := r.HasImage(ctx, "nginx:1.25")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = r.HasImage(ctx, "nginx:1.26")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestRuntime_LogsAndExec(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1.41/containers/abc123/logs", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(append(frame(1, "hello\n"), frame(2, "oops\n")...))
+	})
+	mux.HandleFunc("POST /v1.41/containers/abc123/exec", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, `{"Id": "exec1"}`)
+	})
+	mux.HandleFunc("POST /v1.41/containers/stopped/exec", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		_, _ = io.WriteString(w, `{"message": "container stopped is not running"}`)
+	})
+	mux.HandleFunc("POST /v1.41/exec/exec1/start", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(frame(1, "ok"))
+	})
+	mux.HandleFunc("GET /v1.41/exec/exec1/json", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, `{"ExitCode": 1}`)
+	})
+	r := newTestRuntime(t, mux)
+	ctx := context.Background()
+
+	logs, err := r.Logs(ctx, "abc123")
+	require.NoError(t, err)
+	out, err := io.ReadAll(logs)
+	require.NoError(t, err)
+	assert.Equal(t, "hello\noops\n", string(out))
+
+	res, err := r.Exec(ctx, "abc123", []string{"check"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, res.ExitCode)
+	assert.Equal(t, "ok", string(res.Output))
+
+	_, err = r.Exec(ctx, "stopped", []string{"check"})
+	assert.ErrorIs(t, err, runtime.ErrNotRunning)
+}
+
+func TestRuntime_Sandbox(t *testing.T) {
+	var created []createRequest
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1.41/images/{name...}", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, `{"Id": "sha256:abc"}`)
+	})
+	mux.HandleFunc("POST /v1.41/containers/create", func(w http.ResponseWriter, req *http.Request) {
+		var body createRequest
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+		created = append(created, body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(w, `{"Id": "c%d"}`, len(created))
+	})
+	mux.HandleFunc("POST /v1.41/containers/c1/start", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /v1.41/containers/c1/json", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, `{"Id": "c1", "State": {"Status": "running"}, "NetworkSettings": {"IPAddress": "172.17.0.5"}}`)
+	})
+	mux.HandleFunc("GET /v1.41/containers/c2/json", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, `{"Id": "c2", "State": {"Status": "running"}, "HostConfig": {"NetworkMode": "container:c1"}}`)
+	})
+	mux.HandleFunc("DELETE /v1.41/containers/{id}", func(w http.ResponseWriter, req *http.Request) {
+		if req.PathValue("id") != "c1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		assert.Equal(t, "true", req.URL.Query().Get("force"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	r := newTestRuntime(t, mux)
+	ctx := context.Background()
+
+	sandbox, err := r.CreateSandbox(ctx, "default_shop", map[string]string{"orchestrator.group": "shop"})
+	require.NoError(t, err)
+	assert.Equal(t, "c1", sandbox)
+	id, err := r.Create(ctx, &runtime.ContainerConfig{Name: "default_shop-app", Image: "shop:v1", Sandbox: sandbox})
+	require.NoError(t, err)
+
+	require.Len(t, created, 2)
+	assert.Equal(t, DefaultPauseImage, created[0].Image)
+	assert.Equal(t, "shop", created[0].Labels["orchestrator.group"])
+	assert.Equal(t, "container:c1", created[1].HostConfig.NetworkMode)
+
+	// Members are reached at the sandbox's address.
+	info, err := r.Inspect(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "172.17.0.5", info.IPAddress)
+
+	require.NoError(t, r.RemoveSandbox(ctx, sandbox))
+	assert.ErrorIs(t, r.RemoveSandbox(ctx, "gone"), runtime.ErrNotFound)
+}
@@ -0,0 +1,135 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/github-builder/container-orchestrator/internal/runtime"
+)
+
+// HasImage reports whether the daemon has image.
+func (r *Runtime) HasImage(ctx context.Context, image string) (bool, error) {
+	err := r.call(ctx, http.MethodGet, "/images/"+image+"/json", nil, nil, nil)
+	if statusOf(err) == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("inspecting image %s: %w", image, err)
+	}
+	return true, nil
+}
+
+// pullMessage is one line of the progress stream of a pull.
+type pullMessage struct {
+	ID             string `json:"id"`
+	Status         string `json:"status"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+	Error string `json:"error"`
+}
+
+// PullImage has the daemon pull image, reporting the bytes downloaded
+// across all layers.
+func (r *Runtime) PullImage(ctx context.Context, image string, auth *runtime.RegistryAuth, progress func(runtime.PullProgress)) error {
+	query := url.Values{"fromImage": {image}}
+	// Without a tag the daemon would pull every tag of the repository.
+	if !strings.Contains(image, "@") && !strings.Contains(image[strings.LastIndex(image, "/")+1:], ":") {
+		query.Set("tag", "latest")
+	}
+	header := http.Header{}
+	if auth != nil {
+		data, err := json.Marshal(map[string]string{
+			"username":      auth.Username,
+			"password":      auth.Password,
+			"serveraddress": auth.Server,
+		})
+		if err != nil {
+			return err
+		}
+		header.Set("X-Registry-Auth", base64.URLEncoding.EncodeToString(data))
+	}
+
+	r.logger.Debug().Str("image", image).Bool("auth", auth != nil).Msg("pulling image")
+	resp, err := r.do(ctx, http.MethodPost, "/images/create", query, nil, header)
+	if err != nil {
+		return pullError(image, err)
+	}
+	defer resp.Body.Close()
+
+	type layer struct{ current, total int64 }
+	layers := make(map[string]layer)
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg pullMessage
+		if err := dec.Decode(&msg); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("pulling %s: reading progress: %w", image, err)
+		}
+		if msg.Error != "" {
+			return pullError(image, errors.New(msg.Error))
+		}
+		if progress == nil {
+			continue
+		}
+
+		if msg.ID != "" && msg.ProgressDetail.Total > 0 && msg.Status == "Downloading" {
+			layers[msg.ID] = layer{msg.ProgressDetail.Current, msg.ProgressDetail.Total}
+		} else if l, ok := layers[msg.ID]; ok && msg.Status == "Download complete" {
+			layers[msg.ID] = layer{l.total, l.total}
+		}
+		p := runtime.PullProgress{Status: msg.Status}
+		for _, l := range layers {
+			p.Current += l.current
+			p.Total += l.total
+		}
+		progress(p)
+	}
+}
+
+// pullError maps the daemon's reasons for failing a pull to the runtime's
+// errors.
+func pullError(image string, err error) error {
+	msg := strings.ToLower(err.Error())
+	switch {
+	case statusOf(err) == http.StatusUnauthorized || strings.Contains(msg, "unauthorized") ||
+		strings.Contains(msg, "authentication required") || strings.Contains(msg, "denied"):
+		return fmt.Errorf("pulling %s: %w: %v", image, runtime.ErrUnauthorized, err)
+	case statusOf(err) == http.StatusNotFound || strings.Contains(msg, "not found") ||
+		strings.Contains(msg, "manifest unknown"):
+		return fmt.Errorf("pulling %s: %w: %v", image, runtime.ErrImageNotFound, err)
+	}
+	return fmt.Errorf("pulling %s: %w", image, err)
+}
+
+// demux reads a multiplexed stdout and stderr stream as Docker sends it
+// for containers without a terminal: frames of an 8-byte header, whose
+// last four bytes are the big-endian payload size, and the payload. The
+// payloads are returned in order.
+func demux(r io.Reader) ([]byte, error) {
+	var out []byte
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); errors.Is(err, io.EOF) {
+			return out, nil
+		} else if err != nil {
+			return out, err
+		}
+		size := binary.BigEndian.Uint32(header[4:])
+		start := len(out)
+		out = append(out, make([]byte, size)...)
+		if _, err := io.ReadFull(r, out[start:]); err != nil {
+			return out[:start], err
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/github-builder/container-orchestrator/internal/runtime"
+)
+
+// DefaultPauseImage is the default image of the containers holding
+// sandboxes. It does nothing but wait to be stopped.
+const DefaultPauseImage = "registry.k8s.io/pause:3.9"
+
+// sandboxNetwork prefixes the ID of a sandbox in the network mode of the
+// containers joining it.
+const sandboxNetwork = "container:"
+
+// CreateSandbox creates and starts a pause container, whose network
+// namespace the containers created with its ID as their sandbox join.
+func (r *Runtime) CreateSandbox(ctx context.Context, name string, labels map[string]string) (string, error) {
+	present, err := r.HasImage(ctx, r.pauseImage)
+	if err != nil {
+		return "", err
+	}
+	if !present {
+		if err := r.PullImage(ctx, r.pauseImage, nil, nil); err != nil {
+			return "", err
+		}
+	}
+
+	id, err := r.Create(ctx, &runtime.ContainerConfig{Name: name, Image: r.pauseImage, Labels: labels})
+	if err != nil {
+		return "", fmt.Errorf("creating sandbox %s: %w", name, err)
+	}
+	if err := r.Start(ctx, id); err != nil {
+		_ = r.RemoveSandbox(ctx, id)
+		return "", fmt.Errorf("starting sandbox %s: %w", name, err)
+	}
+	return id, nil
+}
+
+// RemoveSandbox stops and removes the pause container of a sandbox.
+func (r *Runtime) RemoveSandbox(ctx context.Context, id string) error {
+	query := url.Values{"force": {"true"}}
+	return containerError(r.call(ctx, http.MethodDelete, "/containers/"+id, query, nil, nil))
+}
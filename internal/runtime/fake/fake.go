@@ -0,0 +1,372 @@
+// Package fake provides an in-memory runtime.Runtime. Intended for testing.
+package fake
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/github-builder/container-orchestrator/internal/runtime"
+)
+
+// ExecFunc handles Exec calls for a container.
+type ExecFunc func(id string, cmd []string) (*runtime.ExecResult, error)
+
+// Runtime is an in-memory runtime whose containers run until told to exit.
+type Runtime struct {
+	mu         sync.Mutex
+	containers map[string]*Container
+	sandboxes  map[string]string
+	images     map[string]bool
+	nextID     int
+
+	// ExecHandler answers Exec calls. By default every command succeeds.
+	ExecHandler ExecFunc
+
+	// StartErr, when set, is returned by Start.
+	StartErr error
+
+	// Registry, when set, is where images are pulled from, and containers
+	// can only be created from pulled images. Without it every image is
+	// present and pulls succeed at once.
+	Registry *Registry
+}
+
+// Container is the fake state of a single container.
+type Container struct {
+	Config runtime.ContainerConfig
+	Info   runtime.ContainerInfo
+	Starts int
+
+	// Stats is what Stats reports while the container runs.
+	Stats runtime.Stats
+
+	done chan struct{}
+}
+
+var (
+	_ runtime.Runtime        = (*Runtime)(nil)
+	_ runtime.SandboxRuntime = (*Runtime)(nil)
+	_ runtime.StatsRuntime   = (*Runtime)(nil)
+	_ runtime.ImageRuntime   = (*Runtime)(nil)
+)
+
+// New creates an empty fake runtime.
+func New() *Runtime {
+	return &Runtime{
+		containers: make(map[string]*Container),
+		sandboxes:  make(map[string]string),
+		images:     make(map[string]bool),
+	}
+}
+
+// Create records a new container in the created state.
+func (r *Runtime) Create(_ context.Context, cfg *runtime.ContainerConfig) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range r.containers {
+		if cfg.Name != "" && c.Config.Name == cfg.Name {
+			return "", fmt.Errorf("%w: %s", runtime.ErrAlreadyExists, cfg.Name)
+		}
+	}
+	if _, ok := r.sandboxes[cfg.Sandbox]; cfg.Sandbox != "" && !ok {
+		return "", fmt.Errorf("sandbox %s: %w", cfg.Sandbox, runtime.ErrNotFound)
+	}
+	if r.Registry != nil && cfg.Image != "" && !r.images[cfg.Image] {
+		return "", fmt.Errorf("%w: %s", runtime.ErrImageNotPresent, cfg.Image)
+	}
+
+	r.nextID++
+	id := fmt.Sprintf("fake-%d", r.nextID)
+	r.containers[id] = &Container{
+		Config: *cfg,
+		Info: runtime.ContainerInfo{
+			ID:        id,
+			Name:      cfg.Name,
+			Labels:    cfg.Labels,
+			State:     runtime.StateCreated,
+			IPAddress: "127.0.0.1",
+		},
+		done: make(chan struct{}),
+	}
+	return id, nil
+}
+
+// Start marks a container as running.
+func (r *Runtime) Start(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.containers[id]
+	if !ok {
+		return runtime.ErrNotFound
+	}
+	if c.Info.State == runtime.StateRunning {
+		return runtime.ErrRunning
+	}
+	if r.StartErr != nil {
+		return r.StartErr
+	}
+
+	if c.Info.State == runtime.StateExited {
+		c.done = make(chan struct{})
+	}
+	c.Starts++
+	c.Info.State = runtime.StateRunning
+	c.Info.Pid = 1000 + c.Starts
+	c.Info.ExitCode = 0
+	c.Info.StartedAt = time.Now().UTC()
+	c.Info.FinishedAt = time.Time{}
+	return nil
+}
+
+// Stop exits a running container with code 143, as if terminated by SIGTERM.
+func (r *Runtime) Stop(_ context.Context, id string, _ time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.containers[id]
+	if !ok {
+		return runtime.ErrNotFound
+	}
+	if c.Info.State == runtime.StateRunning {
+		r.exitLocked(c, 143)
+	}
+	return nil
+}
+
+// Exit simulates a running container terminating with the given code.
+func (r *Runtime) Exit(id string, code int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if c, ok := r.containers[id]; ok && c.Info.State == runtime.StateRunning {
+		r.exitLocked(c, code)
+	}
+}
+
+func (r *Runtime) exitLocked(c *Container, code int) {
+	c.Info.State = runtime.StateExited
+	c.Info.ExitCode = code
+	c.Info.FinishedAt = time.Now().UTC()
+	close(c.done)
+}
+
+// Remove deletes a stopped container.
+func (r *Runtime) Remove(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.containers[id]
+	if !ok {
+		return runtime.ErrNotFound
+	}
+	if c.Info.State == runtime.StateRunning {
+		return runtime.ErrRunning
+	}
+	delete(r.containers, id)
+	return nil
+}
+
+// Inspect returns a copy of the container's state.
+func (r *Runtime) Inspect(_ context.Context, id string) (*runtime.ContainerInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.containers[id]
+	if !ok {
+		return nil, runtime.ErrNotFound
+	}
+	info := c.Info
+	return &info, nil
+}
+
+// List returns copies of the containers whose labels include all of
+// labels.
+func (r *Runtime) List(_ context.Context, labels map[string]string) ([]*runtime.ContainerInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var infos []*runtime.ContainerInfo
+	for _, c := range r.containers {
+		if c.Info.HasLabels(labels) {
+			info := c.Info
+			infos = append(infos, &info)
+		}
+	}
+	return infos, nil
+}
+
+// Wait blocks until the container exits.
+func (r *Runtime) Wait(ctx context.Context, id string) (int, error) {
+	r.mu.Lock()
+	c, ok := r.containers[id]
+	if !ok {
+		r.mu.Unlock()
+		return 0, runtime.ErrNotFound
+	}
+	if c.Info.State == runtime.StateCreated {
+		r.mu.Unlock()
+		return 0, runtime.ErrNotRunning
+	}
+	done := c.done
+	r.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return c.Info.ExitCode, nil
+}
+
+// Logs returns an empty log stream.
+func (r *Runtime) Logs(_ context.Context, id string) (io.ReadCloser, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.containers[id]; !ok {
+		return nil, runtime.ErrNotFound
+	}
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+// Exec delegates to ExecHandler.
+func (r *Runtime) Exec(_ context.Context, id string, cmd []string) (*runtime.ExecResult, error) {
+	r.mu.Lock()
+	c, ok := r.containers[id]
+	if !ok {
+		r.mu.Unlock()
+		return nil, runtime.ErrNotFound
+	}
+	if c.Info.State != runtime.StateRunning {
+		r.mu.Unlock()
+		return nil, runtime.ErrNotRunning
+	}
+	handler := r.ExecHandler
+	r.mu.Unlock()
+
+	if handler == nil {
+		return &runtime.ExecResult{}, nil
+	}
+	return handler(id, cmd)
+}
+
+// Stats returns the stats set with SetStats, taken now unless they say
+// otherwise.
+func (r *Runtime) Stats(_ context.Context, id string) (*runtime.Stats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.containers[id]
+	if !ok {
+		return nil, runtime.ErrNotFound
+	}
+	if c.Info.State != runtime.StateRunning {
+		return nil, runtime.ErrNotRunning
+	}
+	st := c.Stats
+	st.Metrics = maps.Clone(c.Stats.Metrics)
+	if st.Time.IsZero() {
+		st.Time = time.Now().UTC()
+	}
+	return &st, nil
+}
+
+// SetStats sets the stats of a container.
+func (r *Runtime) SetStats(id string, st runtime.Stats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if c, ok := r.containers[id]; ok {
+		c.Stats = st
+	}
+}
+
+// HasImage reports whether image has been pulled from the registry.
+func (r *Runtime) HasImage(_ context.Context, image string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Registry == nil || r.images[image], nil
+}
+
+// PullImage pulls image from the registry.
+func (r *Runtime) PullImage(ctx context.Context, image string, auth *runtime.RegistryAuth, progress func(runtime.PullProgress)) error {
+	r.mu.Lock()
+	registry := r.Registry
+	r.mu.Unlock()
+	if registry == nil {
+		return nil
+	}
+
+	if err := registry.pull(ctx, image, auth, progress); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.images[image] = true
+	return nil
+}
+
+// CreateSandbox records a new sandbox.
+func (r *Runtime) CreateSandbox(_ context.Context, name string, _ map[string]string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, n := range r.sandboxes {
+		if n == name {
+			return "", fmt.Errorf("%w: %s", runtime.ErrAlreadyExists, name)
+		}
+	}
+	r.nextID++
+	id := fmt.Sprintf("sandbox-%d", r.nextID)
+	r.sandboxes[id] = name
+	return id, nil
+}
+
+// RemoveSandbox deletes a sandbox.
+func (r *Runtime) RemoveSandbox(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.sandboxes[id]; !ok {
+		return runtime.ErrNotFound
+	}
+	delete(r.sandboxes, id)
+	return nil
+}
+
+// Sandboxes returns the names of the sandboxes by ID.
+func (r *Runtime) Sandboxes() map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return maps.Clone(r.sandboxes)
+}
+
+// Get returns a snapshot of a container, or nil if it does not exist.
+func (r *Runtime) Get(id string) *Container {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.containers[id]
+	if !ok {
+		return nil
+	}
+	cp := *c
+	return &cp
+}
+
+// Count returns the number of containers known to the runtime.
+func (r *Runtime) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.containers)
+}
@@ -0,0 +1,106 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/github-builder/container-orchestrator/internal/runtime"
+)
+
+// pullSteps is how many progress reports a pull makes while downloading.
+const pullSteps = 4
+
+// Registry is an in-memory image registry for the fake runtime. It serves
+// the images pushed to it, optionally only to pulls with the right
+// credentials, and records every pull.
+type Registry struct {
+	mu     sync.Mutex
+	images map[string]int64
+	auth   *runtime.RegistryAuth
+	pulls  []Pull
+
+	// Err, when set, fails every pull.
+	Err error
+
+	// Block, when set, holds every pull until it is closed or the pull is
+	// canceled.
+	Block chan struct{}
+}
+
+// Pull is a pull the registry received.
+type Pull struct {
+	Image string
+
+	// Auth is the credentials the pull carried, nil for none.
+	Auth *runtime.RegistryAuth
+}
+
+// NewRegistry creates an empty registry that needs no credentials.
+func NewRegistry() *Registry {
+	return &Registry{images: make(map[string]int64)}
+}
+
+// Push adds image to the registry with the given size in bytes.
+func (r *Registry) Push(image string, size int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.images[image] = size
+}
+
+// RequireAuth makes pulls fail with runtime.ErrUnauthorized unless they
+// carry username and password.
+func (r *Registry) RequireAuth(username, password string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.auth = &runtime.RegistryAuth{Username: username, Password: password}
+}
+
+// Pulls returns the pulls received so far, oldest first.
+func (r *Registry) Pulls() []Pull {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Pull(nil), r.pulls...)
+}
+
+// pull serves image, reporting the download in steps.
+func (r *Registry) pull(ctx context.Context, image string, auth *runtime.RegistryAuth, progress func(runtime.PullProgress)) error {
+	r.mu.Lock()
+	p := Pull{Image: image}
+	if auth != nil {
+		cp := *auth
+		p.Auth = &cp
+	}
+	r.pulls = append(r.pulls, p)
+	if r.Err != nil {
+		r.mu.Unlock()
+		return r.Err
+	}
+	if r.auth != nil && (auth == nil || auth.Username != r.auth.Username || auth.Password != r.auth.Password) {
+		r.mu.Unlock()
+		return fmt.Errorf("pulling %s: %w", image, runtime.ErrUnauthorized)
+	}
+	size, ok := r.images[image]
+	block := r.Block
+	r.mu.Unlock()
+	if block != nil {
+		select {
+		case <-block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if !ok {
+		return fmt.Errorf("pulling %s: %w", image, runtime.ErrImageNotFound)
+	}
+
+	for i := int64(1); i <= pullSteps; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(runtime.PullProgress{Status: "Downloading", Current: size * i / pullSteps, Total: size})
+		}
+	}
+	return nil
+}
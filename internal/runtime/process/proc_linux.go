@@ -0,0 +1,294 @@
+//go:build linux
+
+package process
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/github-builder/container-orchestrator/internal/runtime"
+)
+
+// cpuPeriod is the cgroup CPU accounting period in microseconds.
+const cpuPeriod = 100000
+
+// clockTicks is the unit of CPU times in /proc, USER_HZ, which is 100 on
+// every architecture Linux supports.
+const clockTicks = 100
+
+// rlimitResources maps rlimit names to their resource identifiers.
+var rlimitResources = map[string]int{
+	"as":      unix.RLIMIT_AS,
+	"core":    unix.RLIMIT_CORE,
+	"cpu":     unix.RLIMIT_CPU,
+	"data":    unix.RLIMIT_DATA,
+	"fsize":   unix.RLIMIT_FSIZE,
+	"memlock": unix.RLIMIT_MEMLOCK,
+	"nofile":  unix.RLIMIT_NOFILE,
+	"nproc":   unix.RLIMIT_NPROC,
+	"stack":   unix.RLIMIT_STACK,
+}
+
+// configureProcAttr puts the process in its own process group so that
+// signals reach every descendant.
+func configureProcAttr(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// terminate sends SIGTERM to the process group.
+func terminate(p *os.Process) error {
+	return signalGroup(p, syscall.SIGTERM)
+}
+
+// kill sends SIGKILL to the process group.
+func kill(p *os.Process) error {
+	return signalGroup(p, syscall.SIGKILL)
+}
+
+func signalGroup(p *os.Process, sig syscall.Signal) error {
+	err := syscall.Kill(-p.Pid, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		return nil
+	}
+	return err
+}
+
+// exitCode converts a wait result to a shell-style exit code,
+// reporting death by signal as 128 plus the signal number.
+func exitCode(cmd *exec.Cmd, waitErr error) int {
+	if cmd.ProcessState == nil {
+		if waitErr != nil {
+			return -1
+		}
+		return 0
+	}
+	if ws, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	return cmd.ProcessState.ExitCode()
+}
+
+// rlimitEnv carries the limits for a process started through the rlimit
+// shim, as comma-separated name=soft:hard entries.
+const rlimitEnv = "ORCHESTRATOR_RLIMITS"
+
+// selfExe names the running binary, which serves as the rlimit shim.
+const selfExe = "/proc/self/exe"
+
+// Go cannot set rlimits between fork and exec, so a process that needs them
+// is started through a re-exec of this binary: the shim sets the limits on
+// itself and then execs the real command, which inherits them.
+func init() {
+	if spec, ok := os.LookupEnv(rlimitEnv); ok {
+		runRlimitShim(spec)
+	}
+}
+
+// withRlimits arranges for cmd to run under the given resource limits from
+// its first instruction by starting it through the rlimit shim. Unknown
+// limits are reported and left out; the command still runs under the rest.
+func withRlimits(cmd *exec.Cmd, limits []runtime.Rlimit) error {
+	var (
+		errs []error
+		spec []string
+	)
+	for _, l := range limits {
+		if _, ok := rlimitResources[l.Name]; !ok {
+			errs = append(errs, fmt.Errorf("unknown rlimit %q", l.Name))
+			continue
+		}
+		spec = append(spec, fmt.Sprintf("%s=%d:%d", l.Name, l.Soft, l.Hard))
+	}
+	if len(spec) == 0 {
+		return errors.Join(errs...)
+	}
+
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd.Env = append(env, rlimitEnv+"="+strings.Join(spec, ","))
+	cmd.Args = append([]string{"rlimit-shim", cmd.Path}, cmd.Args...)
+	cmd.Path = selfExe
+	return errors.Join(errs...)
+}
+
+// runRlimitShim applies the limits in spec to the current process and
+// replaces it with the command in os.Args[1:]. It never returns: failures
+// are written to stderr, which is the container log, and exit with 127 as
+// a shell does for a command it cannot run.
+func runRlimitShim(spec string) {
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "rlimit shim: %v\n", err)
+		os.Exit(127)
+	}
+	if len(os.Args) < 3 {
+		fail(errors.New("missing command"))
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		name, limits, _ := strings.Cut(entry, "=")
+		soft, hard, _ := strings.Cut(limits, ":")
+		res, ok := rlimitResources[name]
+		if !ok {
+			fail(fmt.Errorf("unknown rlimit %q", name))
+		}
+		var rl syscall.Rlimit
+		var err error
+		if rl.Cur, err = strconv.ParseUint(soft, 10, 64); err != nil {
+			fail(fmt.Errorf("rlimit %s: %w", name, err))
+		}
+		if rl.Max, err = strconv.ParseUint(hard, 10, 64); err != nil {
+			fail(fmt.Errorf("rlimit %s: %w", name, err))
+		}
+		// syscall.Setrlimit, unlike its x/sys counterpart, stops Exec from
+		// restoring the soft nofile limit Go had at startup.
+		if err := syscall.Setrlimit(res, &rl); err != nil {
+			fail(fmt.Errorf("rlimit %s: %w", name, err))
+		}
+	}
+
+	env := make([]string, 0, len(os.Environ()))
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, rlimitEnv+"=") {
+			env = append(env, kv)
+		}
+	}
+	fail(syscall.Exec(os.Args[1], os.Args[2:], env))
+}
+
+// checkCgroupRoot verifies that dir is a writable cgroup v2 directory.
+func checkCgroupRoot(dir string) error {
+	if _, err := os.Stat(filepath.Join(dir, "cgroup.controllers")); err != nil {
+		return fmt.Errorf("%s is not a cgroup v2 directory: %w", dir, err)
+	}
+	if err := unix.Access(dir, unix.W_OK); err != nil {
+		return fmt.Errorf("%s is not writable: %w", dir, err)
+	}
+
+	// Delegate the cpu and memory controllers to child groups. This fails
+	// harmlessly if they are already enabled or not available.
+	_ = os.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte("+cpu +memory"), 0o600)
+	return nil
+}
+
+// createCgroup creates a child cgroup with the given limits.
+func createCgroup(dir string, res runtime.Resources) error {
+	if err := os.Mkdir(dir, 0o750); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+
+	if res.CPUMillis > 0 {
+		quota := res.CPUMillis * cpuPeriod / 1000
+		value := strconv.FormatInt(quota, 10) + " " + strconv.Itoa(cpuPeriod)
+		if err := os.WriteFile(filepath.Join(dir, "cpu.max"), []byte(value), 0o600); err != nil {
+			return fmt.Errorf("setting cpu.max: %w", err)
+		}
+	}
+
+	if res.MemoryBytes > 0 {
+		value := strconv.FormatInt(res.MemoryBytes, 10)
+		if err := os.WriteFile(filepath.Join(dir, "memory.max"), []byte(value), 0o600); err != nil {
+			return fmt.Errorf("setting memory.max: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// attachCgroup makes the process start directly inside the cgroup at dir,
+// so no child escapes the limits between fork and exec.
+func attachCgroup(cmd *exec.Cmd, dir string) (*os.File, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(f.Fd())
+	return f, nil
+}
+
+// cgroupUsage reads the CPU time and memory use of the cgroup at dir.
+func cgroupUsage(dir string) (time.Duration, int64, error) {
+	stat, err := os.ReadFile(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return 0, 0, err
+	}
+	var cpu time.Duration
+	for _, line := range strings.Split(string(stat), "\n") {
+		if v, ok := strings.CutPrefix(line, "usage_usec "); ok {
+			usec, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return 0, 0, fmt.Errorf("parsing cpu.stat: %w", err)
+			}
+			cpu = time.Duration(usec) * time.Microsecond
+		}
+	}
+
+	current, err := os.ReadFile(filepath.Join(dir, "memory.current"))
+	if err != nil {
+		return 0, 0, err
+	}
+	mem, err := strconv.ParseInt(string(bytes.TrimSpace(current)), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("parsing memory.current: %w", err)
+	}
+	return cpu, mem, nil
+}
+
+// processUsage reads the CPU time and resident memory of the process pid
+// from /proc. Unlike a cgroup, it does not account for its children.
+func processUsage(pid int) (time.Duration, int64, error) {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+	// The command name may contain spaces and parentheses; the fields
+	// after it start with the state, the third field.
+	i := bytes.LastIndexByte(stat, ')')
+	if i < 0 {
+		return 0, 0, errors.New("malformed stat")
+	}
+	fields := strings.Fields(string(stat[i+1:]))
+	if len(fields) < 13 {
+		return 0, 0, errors.New("malformed stat")
+	}
+	var ticks int64
+	for _, f := range fields[11:13] { // utime and stime
+		n, err := strconv.ParseInt(f, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("parsing stat: %w", err)
+		}
+		ticks += n
+	}
+
+	statm, err := os.ReadFile(fmt.Sprintf("/proc/%d/statm", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+	pages := strings.Fields(string(statm))
+	if len(pages) < 2 {
+		return 0, 0, errors.New("malformed statm")
+	}
+	resident, err := strconv.ParseInt(pages[1], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("parsing statm: %w", err)
+	}
+	return time.Duration(ticks) * time.Second / clockTicks, resident * int64(os.Getpagesize()), nil
+}
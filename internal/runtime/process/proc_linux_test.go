@@ -0,0 +1,42 @@
+//go:build linux
+
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/runtime"
+)
+
+func TestCreateCgroup_WritesLimits(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cg")
+
+	err := createCgroup(dir, runtime.Resources{CPUMillis: 250, MemoryBytes: 1 << 20})
+	require.NoError(t, err)
+
+	cpu, err := os.ReadFile(filepath.Join(dir, "cpu.max"))
+	require.NoError(t, err)
+	assert.Equal(t, "25000 100000", string(cpu))
+
+	mem, err := os.ReadFile(filepath.Join(dir, "memory.max"))
+	require.NoError(t, err)
+	assert.Equal(t, "1048576", string(mem))
+}
+
+func TestCgroupUsage(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cpu.stat"),
+		[]byte("usage_usec 1500000\nuser_usec 1000000\nsystem_usec 500000\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "memory.current"), []byte("4096\n"), 0o600))
+
+	cpu, mem, err := cgroupUsage(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 1500*time.Millisecond, cpu)
+	assert.Equal(t, int64(4096), mem)
+}
@@ -0,0 +1,61 @@
+//go:build !linux
+
+package process
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/github-builder/container-orchestrator/internal/runtime"
+)
+
+var errUnsupported = errors.New("not supported on this platform")
+
+func configureProcAttr(*exec.Cmd) {}
+
+func terminate(p *os.Process) error {
+	return p.Signal(os.Interrupt)
+}
+
+func kill(p *os.Process) error {
+	return p.Kill()
+}
+
+func exitCode(cmd *exec.Cmd, waitErr error) int {
+	if cmd.ProcessState == nil {
+		if waitErr != nil {
+			return -1
+		}
+		return 0
+	}
+	return cmd.ProcessState.ExitCode()
+}
+
+func withRlimits(_ *exec.Cmd, limits []runtime.Rlimit) error {
+	if len(limits) > 0 {
+		return errUnsupported
+	}
+	return nil
+}
+
+func checkCgroupRoot(string) error {
+	return errUnsupported
+}
+
+func createCgroup(string, runtime.Resources) error {
+	return errUnsupported
+}
+
+func attachCgroup(*exec.Cmd, string) (*os.File, error) {
+	return nil, errUnsupported
+}
+
+func cgroupUsage(string) (time.Duration, int64, error) {
+	return 0, 0, errUnsupported
+}
+
+func processUsage(int) (time.Duration, int64, error) {
+	return 0, 0, errUnsupported
+}
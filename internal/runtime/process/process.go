@@ -0,0 +1,615 @@
+// Package process implements runtime.Runtime by supervising plain local processes.
+// It is intended for hosts that cannot run Docker: each container gets its own
+// working directory and log file, and on Linux it is optionally placed in a
+// cgroup v2 sub-tree that enforces CPU and memory limits. Rlimits are set
+// before the command runs by starting it through a re-exec of the current
+// binary, and exec'd commands join the same cgroup. Processes share the
+// host filesystem, so mounts are symlinked into the working directory at
+// their target path; read-only mounts are not enforced. A container may
+// publish application metrics by writing "name value" lines to the file
+// named by $ORCHESTRATOR_METRICS_FILE.
+package process
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/github-builder/container-orchestrator/internal/runtime"
+)
+
+const (
+	logFileName     = "container.log"
+	workDirName     = "work"
+	metricsFileName = "metrics"
+	defaultPath     = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+)
+
+// MetricsFileEnv is the environment variable that tells a container where
+// to publish its application metrics.
+const MetricsFileEnv = "ORCHESTRATOR_METRICS_FILE"
+
+// Config holds settings for the process runtime.
+type Config struct {
+	// RootDir holds one sub-directory per container with its working
+	// directory and captured output.
+	RootDir string
+
+	// CgroupRoot is a cgroup v2 directory under which per-container groups
+	// are created. Leave empty to disable cgroup limits. If the directory is
+	// not a writable cgroup v2 hierarchy, limits are skipped with a warning.
+	CgroupRoot string
+
+	Logger zerolog.Logger
+}
+
+// Runtime supervises containers as local child processes.
+type Runtime struct {
+	rootDir    string
+	cgroupRoot string
+	logger     zerolog.Logger
+
+	mu         sync.Mutex
+	containers map[string]*container
+}
+
+// container is the supervised state of a single process.
+type container struct {
+	id     string
+	dir    string
+	cfg    runtime.ContainerConfig
+	cgroup string
+
+	cmd        *exec.Cmd
+	state      runtime.State
+	pid        int
+	exitCode   int
+	startedAt  time.Time
+	finishedAt time.Time
+	err        string
+
+	// done is closed when the process exits.
+	done chan struct{}
+}
+
+var (
+	_ runtime.Runtime      = (*Runtime)(nil)
+	_ runtime.StatsRuntime = (*Runtime)(nil)
+)
+
+// NewRuntime creates a process runtime rooted at cfg.RootDir.
+// The root directory is created if it does not exist.
+func NewRuntime(cfg *Config) (*Runtime, error) {
+	if err := os.MkdirAll(cfg.RootDir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create runtime directory %s: %w", cfg.RootDir, err)
+	}
+
+	r := &Runtime{
+		rootDir:    cfg.RootDir,
+		logger:     cfg.Logger,
+		containers: make(map[string]*container),
+	}
+
+	if cfg.CgroupRoot != "" {
+		if err := checkCgroupRoot(cfg.CgroupRoot); err != nil {
+			r.logger.Warn().Err(err).Str("cgroup_root", cfg.CgroupRoot).
+				Msg("cgroup v2 unavailable, running without resource limits")
+		} else {
+			r.cgroupRoot = cfg.CgroupRoot
+		}
+	}
+
+	return r, nil
+}
+
+// CgroupsEnabled reports whether containers are placed in cgroups.
+func (r *Runtime) CgroupsEnabled() bool {
+	return r.cgroupRoot != ""
+}
+
+// Create prepares the working directory for a container.
+func (r *Runtime) Create(_ context.Context, cfg *runtime.ContainerConfig) (string, error) {
+	if len(cfg.Command) == 0 && cfg.Image == "" {
+		return "", errors.New("container needs a command or image")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range r.containers {
+		if cfg.Name != "" && c.cfg.Name == cfg.Name {
+			return "", fmt.Errorf("%w: %s", runtime.ErrAlreadyExists, cfg.Name)
+		}
+	}
+
+	id, err := newID()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(r.rootDir, id)
+	if err := os.MkdirAll(filepath.Join(dir, workDirName), 0o750); err != nil {
+		return "", fmt.Errorf("failed to create container directory: %w", err)
+	}
+	if err := linkMounts(filepath.Join(dir, workDirName), cfg.Mounts); err != nil {
+		_ = os.RemoveAll(dir)
+		return "", err
+	}
+
+	r.containers[id] = &container{
+		id:    id,
+		dir:   dir,
+		cfg:   *cfg,
+		state: runtime.StateCreated,
+		done:  make(chan struct{}),
+	}
+
+	return id, nil
+}
+
+// Start launches the container process and begins supervising it.
+func (r *Runtime) Start(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.containers[id]
+	if !ok {
+		return runtime.ErrNotFound
+	}
+	if c.state == runtime.StateRunning {
+		return runtime.ErrRunning
+	}
+
+	workDir, err := c.workDir()
+	if err != nil {
+		return err
+	}
+
+	logFile, err := os.OpenFile(filepath.Join(c.dir, logFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	argv := c.cfg.Command
+	if len(argv) == 0 {
+		argv = []string{c.cfg.Image}
+	}
+
+	// The process is supervised beyond the lifetime of any request context.
+	cmd := exec.Command(argv[0], argv[1:]...) //nolint:gosec,noctx // Running user-provided commands is the purpose of this runtime.
+	cmd.Dir = workDir
+	cmd.Env = c.environ()
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+
+	if err := withRlimits(cmd, c.cfg.Rlimits); err != nil {
+		r.logger.Warn().Err(err).Str("container_id", id).Msg("failed to apply rlimits")
+	}
+	cgroupFD, err := r.prepareCgroup(c, cmd)
+	if err != nil {
+		_ = logFile.Close()
+		return err
+	}
+	configureProcAttr(cmd)
+
+	startErr := cmd.Start()
+	if cgroupFD != nil {
+		_ = cgroupFD.Close()
+	}
+	if startErr != nil {
+		_ = logFile.Close()
+		c.err = startErr.Error()
+		return fmt.Errorf("failed to start container %s: %w", id, startErr)
+	}
+
+	// A restarted container needs a fresh exit notification.
+	if c.state == runtime.StateExited {
+		c.done = make(chan struct{})
+	}
+
+	c.cmd = cmd
+	c.state = runtime.StateRunning
+	c.pid = cmd.Process.Pid
+	c.exitCode = 0
+	c.err = ""
+	c.startedAt = time.Now().UTC()
+	c.finishedAt = time.Time{}
+
+	go r.supervise(c, cmd, logFile)
+
+	return nil
+}
+
+// supervise waits for the process to exit and records its exit status.
+func (r *Runtime) supervise(c *container, cmd *exec.Cmd, logFile *os.File) {
+	waitErr := cmd.Wait()
+	code := exitCode(cmd, waitErr)
+
+	if err := logFile.Close(); err != nil {
+		r.logger.Warn().Err(err).Str("container_id", c.id).Msg("failed to close log file")
+	}
+
+	r.mu.Lock()
+	c.state = runtime.StateExited
+	c.exitCode = code
+	c.finishedAt = time.Now().UTC()
+	close(c.done)
+	r.mu.Unlock()
+
+	r.logger.Debug().Str("container_id", c.id).Int("exit_code", code).Msg("container exited")
+}
+
+// Stop sends a termination signal and kills the process after timeout.
+func (r *Runtime) Stop(ctx context.Context, id string, timeout time.Duration) error {
+	r.mu.Lock()
+	c, ok := r.containers[id]
+	if !ok {
+		r.mu.Unlock()
+		return runtime.ErrNotFound
+	}
+	if c.state != runtime.StateRunning {
+		r.mu.Unlock()
+		return nil
+	}
+	proc := c.cmd.Process
+	done := c.done
+	r.mu.Unlock()
+
+	if err := terminate(proc); err != nil {
+		return fmt.Errorf("failed to signal container %s: %w", id, err)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	if err := kill(proc); err != nil {
+		return fmt.Errorf("failed to kill container %s: %w", id, err)
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Remove deletes a stopped container's directory and cgroup.
+func (r *Runtime) Remove(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.containers[id]
+	if !ok {
+		return runtime.ErrNotFound
+	}
+	if c.state == runtime.StateRunning {
+		return runtime.ErrRunning
+	}
+
+	if c.cgroup != "" {
+		if err := os.Remove(c.cgroup); err != nil && !errors.Is(err, os.ErrNotExist) {
+			r.logger.Warn().Err(err).Str("cgroup", c.cgroup).Msg("failed to remove cgroup")
+		}
+	}
+
+	if err := os.RemoveAll(c.dir); err != nil {
+		return fmt.Errorf("failed to remove container directory: %w", err)
+	}
+
+	delete(r.containers, id)
+	return nil
+}
+
+// Inspect returns the current state of a container.
+func (r *Runtime) Inspect(_ context.Context, id string) (*runtime.ContainerInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.containers[id]
+	if !ok {
+		return nil, runtime.ErrNotFound
+	}
+	return c.info(), nil
+}
+
+// List returns the containers whose labels include all of labels.
+func (r *Runtime) List(_ context.Context, labels map[string]string) ([]*runtime.ContainerInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var infos []*runtime.ContainerInfo
+	for _, c := range r.containers {
+		if info := c.info(); info.HasLabels(labels) {
+			infos = append(infos, info)
+		}
+	}
+	return infos, nil
+}
+
+// info returns the state of the container. The runtime's lock must be
+// held.
+func (c *container) info() *runtime.ContainerInfo {
+	return &runtime.ContainerInfo{
+		ID:         c.id,
+		Name:       c.cfg.Name,
+		Labels:     c.cfg.Labels,
+		State:      c.state,
+		Pid:        c.pid,
+		ExitCode:   c.exitCode,
+		StartedAt:  c.startedAt,
+		FinishedAt: c.finishedAt,
+		IPAddress:  "127.0.0.1",
+		Error:      c.err,
+	}
+}
+
+// Wait blocks until the container exits and returns its exit code.
+func (r *Runtime) Wait(ctx context.Context, id string) (int, error) {
+	r.mu.Lock()
+	c, ok := r.containers[id]
+	if !ok {
+		r.mu.Unlock()
+		return 0, runtime.ErrNotFound
+	}
+	if c.state == runtime.StateCreated {
+		r.mu.Unlock()
+		return 0, runtime.ErrNotRunning
+	}
+	done := c.done
+	r.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return c.exitCode, nil
+}
+
+// Logs opens the container's captured output.
+func (r *Runtime) Logs(_ context.Context, id string) (io.ReadCloser, error) {
+	r.mu.Lock()
+	c, ok := r.containers[id]
+	r.mu.Unlock()
+	if !ok {
+		return nil, runtime.ErrNotFound
+	}
+
+	f, err := os.Open(filepath.Join(c.dir, logFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open logs: %w", err)
+	}
+	return f, nil
+}
+
+// Exec runs a command in the container's working directory and environment,
+// under the same rlimits and in the same cgroup as its main process.
+func (r *Runtime) Exec(ctx context.Context, id string, argv []string) (*runtime.ExecResult, error) {
+	if len(argv) == 0 {
+		return nil, errors.New("exec needs a command")
+	}
+
+	r.mu.Lock()
+	c, ok := r.containers[id]
+	if !ok {
+		r.mu.Unlock()
+		return nil, runtime.ErrNotFound
+	}
+	if c.state != runtime.StateRunning {
+		r.mu.Unlock()
+		return nil, runtime.ErrNotRunning
+	}
+	cgroup := c.cgroup
+	r.mu.Unlock()
+
+	workDir, err := c.workDir()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...) //nolint:gosec // Running user-provided commands is the purpose of this runtime.
+	cmd.Dir = workDir
+	cmd.Env = c.environ()
+	if err := withRlimits(cmd, c.cfg.Rlimits); err != nil {
+		r.logger.Warn().Err(err).Str("container_id", id).Msg("failed to apply rlimits")
+	}
+	if cgroup != "" {
+		fd, err := attachCgroup(cmd, cgroup)
+		if err != nil {
+			return nil, fmt.Errorf("failed to attach cgroup: %w", err)
+		}
+		defer fd.Close()
+	}
+
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, fmt.Errorf("failed to exec in container %s: %w", id, err)
+	}
+
+	return &runtime.ExecResult{
+		ExitCode: exitCode(cmd, err),
+		Output:   out,
+	}, nil
+}
+
+// Stats samples a running container: CPU time and memory come from its
+// cgroup, or from its main process when it has none, and metrics from the
+// last contents of its metrics file.
+func (r *Runtime) Stats(_ context.Context, id string) (*runtime.Stats, error) {
+	r.mu.Lock()
+	c, ok := r.containers[id]
+	if !ok {
+		r.mu.Unlock()
+		return nil, runtime.ErrNotFound
+	}
+	if c.state != runtime.StateRunning {
+		r.mu.Unlock()
+		return nil, runtime.ErrNotRunning
+	}
+	pid, cgroup := c.pid, c.cgroup
+	r.mu.Unlock()
+
+	st := &runtime.Stats{Time: time.Now().UTC()}
+	var err error
+	if cgroup != "" {
+		st.CPUTime, st.MemoryBytes, err = cgroupUsage(cgroup)
+	} else {
+		st.CPUTime, st.MemoryBytes, err = processUsage(pid)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage of container %s: %w", id, err)
+	}
+	if st.Metrics, err = readMetrics(filepath.Join(c.dir, metricsFileName)); err != nil {
+		return nil, fmt.Errorf("failed to read metrics of container %s: %w", id, err)
+	}
+	return st, nil
+}
+
+// readMetrics parses a metrics file of "name value" lines. Blank lines,
+// comments starting with '#' and lines that do not parse, such as one
+// being written, are skipped. A missing file holds no metrics.
+func readMetrics(path string) (map[string]float64, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var metrics map[string]float64
+	lines := bufio.NewScanner(f)
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		v, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			continue
+		}
+		if metrics == nil {
+			metrics = make(map[string]float64)
+		}
+		metrics[fields[0]] = v
+	}
+	return metrics, lines.Err()
+}
+
+// workDir resolves the process working directory. Relative paths are
+// resolved inside the container directory and may not escape it.
+func (c *container) workDir() (string, error) {
+	base := filepath.Join(c.dir, workDirName)
+	wd := c.cfg.WorkingDir
+	switch {
+	case wd == "":
+		return base, nil
+	case filepath.IsAbs(wd):
+		return wd, nil
+	case !filepath.IsLocal(wd):
+		return "", fmt.Errorf("working directory %q escapes the container directory", wd)
+	}
+
+	dir := filepath.Join(base, wd)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", fmt.Errorf("failed to create working directory: %w", err)
+	}
+	return dir, nil
+}
+
+// linkMounts links each mount source into base at the mount's target path,
+// so a mount at /data appears as base/data.
+func linkMounts(base string, mounts []runtime.Mount) error {
+	for _, m := range mounts {
+		rel := strings.TrimPrefix(filepath.Clean(m.Target), "/")
+		if !filepath.IsLocal(rel) {
+			return fmt.Errorf("mount target %q is not a path below /", m.Target)
+		}
+		link := filepath.Join(base, rel)
+		if err := os.MkdirAll(filepath.Dir(link), 0o750); err != nil {
+			return fmt.Errorf("failed to create mount point %s: %w", m.Target, err)
+		}
+		if err := os.Symlink(m.Source, link); err != nil {
+			return fmt.Errorf("failed to mount %s at %s: %w", m.Source, m.Target, err)
+		}
+	}
+	return nil
+}
+
+// environ builds a clean environment for the process.
+// Nothing is inherited from the orchestrator.
+func (c *container) environ() []string {
+	env := make([]string, 0, len(c.cfg.Env)+3)
+	if _, ok := c.cfg.Env["PATH"]; !ok {
+		env = append(env, "PATH="+defaultPath)
+	}
+	env = append(env, MetricsFileEnv+"="+filepath.Join(c.dir, metricsFileName))
+	if _, ok := c.cfg.Env["HOSTNAME"]; !ok && c.cfg.Name != "" {
+		env = append(env, "HOSTNAME="+c.cfg.Name)
+	}
+	for k, v := range c.cfg.Env {
+		env = append(env, k+"="+v)
+	}
+	return env
+}
+
+// prepareCgroup creates the container's cgroup and arranges for the process
+// to start inside it. The returned file must be closed after the process starts.
+func (r *Runtime) prepareCgroup(c *container, cmd *exec.Cmd) (*os.File, error) {
+	if r.cgroupRoot == "" {
+		return nil, nil
+	}
+
+	dir := filepath.Join(r.cgroupRoot, "orchestrator-"+c.id)
+	if err := createCgroup(dir, c.cfg.Resources); err != nil {
+		return nil, fmt.Errorf("failed to create cgroup: %w", err)
+	}
+	c.cgroup = dir
+
+	fd, err := attachCgroup(cmd, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach cgroup: %w", err)
+	}
+	return fd, nil
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate container ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
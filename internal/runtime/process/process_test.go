@@ -0,0 +1,350 @@
+package process
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/runtime"
+)
+
+func newTestRuntime(t *testing.T) *Runtime {
+	t.Helper()
+	r, err := NewRuntime(&Config{
+		RootDir: t.TempDir(),
+		Logger:  zerolog.Nop(),
+	})
+	require.NoError(t, err)
+	return r
+}
+
+func runToCompletion(t *testing.T, r *Runtime, cfg *runtime.ContainerConfig) (string, int) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id, err := r.Create(ctx, cfg)
+	require.NoError(t, err)
+	require.NoError(t, r.Start(ctx, id))
+
+	code, err := r.Wait(ctx, id)
+	require.NoError(t, err)
+	return id, code
+}
+
+func readLogs(t *testing.T, r *Runtime, id string) string {
+	t.Helper()
+	rc, err := r.Logs(context.Background(), id)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, rc.Close()) }()
+
+	b, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestRuntime_RunCapturesOutput(t *testing.T) {
+	r := newTestRuntime(t)
+
+	id, code := runToCompletion(t, r, &runtime.ContainerConfig{
+		Name:    "echo",
+		Command: []string{"sh", "-c", "echo out; echo err >&2"},
+	})
+
+	assert.Equal(t, 0, code)
+	logs := readLogs(t, r, id)
+	assert.Contains(t, logs, "out")
+	assert.Contains(t, logs, "err")
+
+	info, err := r.Inspect(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, runtime.StateExited, info.State)
+	assert.False(t, info.StartedAt.IsZero())
+	assert.False(t, info.FinishedAt.IsZero())
+}
+
+func TestRuntime_ExitCode(t *testing.T) {
+	r := newTestRuntime(t)
+
+	_, code := runToCompletion(t, r, &runtime.ContainerConfig{
+		Command: []string{"sh", "-c", "exit 3"},
+	})
+
+	assert.Equal(t, 3, code)
+}
+
+func TestRuntime_EnvironmentIsIsolated(t *testing.T) {
+	t.Setenv("ORCHESTRATOR_LEAK", "leaked")
+	r := newTestRuntime(t)
+
+	id, code := runToCompletion(t, r, &runtime.ContainerConfig{
+		Name:    "env",
+		Command: []string{"sh", "-c", `echo "foo=$FOO leak=$ORCHESTRATOR_LEAK host=$HOSTNAME"`},
+		Env:     map[string]string{"FOO": "bar"},
+	})
+
+	require.Equal(t, 0, code)
+	assert.Contains(t, readLogs(t, r, id), "foo=bar leak= host=env")
+}
+
+func TestRuntime_WorkingDir(t *testing.T) {
+	r := newTestRuntime(t)
+
+	id, code := runToCompletion(t, r, &runtime.ContainerConfig{
+		Command:    []string{"sh", "-c", "pwd"},
+		WorkingDir: "app",
+	})
+
+	require.Equal(t, 0, code)
+	assert.Equal(t, filepath.Join(r.rootDir, id, workDirName, "app"), strings.TrimSpace(readLogs(t, r, id)))
+}
+
+func TestRuntime_WorkingDirEscape(t *testing.T) {
+	r := newTestRuntime(t)
+	ctx := context.Background()
+
+	id, err := r.Create(ctx, &runtime.ContainerConfig{
+		Command:    []string{"true"},
+		WorkingDir: "../outside",
+	})
+	require.NoError(t, err)
+
+	err = r.Start(ctx, id)
+	assert.ErrorContains(t, err, "escapes")
+}
+
+func TestRuntime_Mounts(t *testing.T) {
+	r := newTestRuntime(t)
+	ctx := context.Background()
+	src := t.TempDir()
+
+	id, code := runToCompletion(t, r, &runtime.ContainerConfig{
+		Command: []string{"sh", "-c", "echo hello > var/lib/data/greeting"},
+		Mounts:  []runtime.Mount{{Source: src, Target: "/var/lib/data"}},
+	})
+	require.Equal(t, 0, code)
+
+	b, err := os.ReadFile(filepath.Join(src, "greeting"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(b))
+
+	// Removing the container leaves the volume's data alone.
+	require.NoError(t, r.Remove(ctx, id))
+	assert.FileExists(t, filepath.Join(src, "greeting"))
+}
+
+func TestRuntime_StopTerminatesProcess(t *testing.T) {
+	r := newTestRuntime(t)
+	ctx := context.Background()
+
+	id, err := r.Create(ctx, &runtime.ContainerConfig{Command: []string{"sleep", "30"}})
+	require.NoError(t, err)
+	require.NoError(t, r.Start(ctx, id))
+
+	info, err := r.Inspect(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, runtime.StateRunning, info.State)
+	assert.Positive(t, info.Pid)
+
+	assert.ErrorIs(t, r.Remove(ctx, id), runtime.ErrRunning)
+	require.NoError(t, r.Stop(ctx, id, 5*time.Second))
+
+	info, err = r.Inspect(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, runtime.StateExited, info.State)
+	assert.Equal(t, 128+15, info.ExitCode)
+
+	// Stopping an exited container is a no-op.
+	require.NoError(t, r.Stop(ctx, id, time.Second))
+}
+
+func TestRuntime_StopKillsAfterTimeout(t *testing.T) {
+	r := newTestRuntime(t)
+	ctx := context.Background()
+
+	id, err := r.Create(ctx, &runtime.ContainerConfig{
+		Command: []string{"sh", "-c", "trap '' TERM; while :; do sleep 0.1; done"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, r.Start(ctx, id))
+
+	// Give the shell time to install its trap.
+	time.Sleep(200 * time.Millisecond)
+	require.NoError(t, r.Stop(ctx, id, 200*time.Millisecond))
+
+	info, err := r.Inspect(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, 128+9, info.ExitCode)
+}
+
+func TestRuntime_Restart(t *testing.T) {
+	r := newTestRuntime(t)
+	ctx := context.Background()
+
+	id, code := runToCompletion(t, r, &runtime.ContainerConfig{Command: []string{"sh", "-c", "echo run"}})
+	require.Equal(t, 0, code)
+
+	require.NoError(t, r.Start(ctx, id))
+	code, err := r.Wait(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, 2, strings.Count(readLogs(t, r, id), "run"))
+}
+
+func TestRuntime_Exec(t *testing.T) {
+	r := newTestRuntime(t)
+	ctx := context.Background()
+
+	id, err := r.Create(ctx, &runtime.ContainerConfig{
+		Command: []string{"sleep", "30"},
+		Env:     map[string]string{"GREETING": "hi"},
+	})
+	require.NoError(t, err)
+
+	_, err = r.Exec(ctx, id, []string{"true"})
+	assert.ErrorIs(t, err, runtime.ErrNotRunning)
+
+	require.NoError(t, r.Start(ctx, id))
+	defer func() { require.NoError(t, r.Stop(ctx, id, time.Second)) }()
+
+	res, err := r.Exec(ctx, id, []string{"sh", "-c", "echo $GREETING; exit 2"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, res.ExitCode)
+	assert.Equal(t, "hi\n", string(res.Output))
+
+	_, err = r.Exec(ctx, id, []string{"/nonexistent/binary"})
+	assert.Error(t, err)
+}
+
+func TestRuntime_Remove(t *testing.T) {
+	r := newTestRuntime(t)
+	ctx := context.Background()
+
+	id, _ := runToCompletion(t, r, &runtime.ContainerConfig{Command: []string{"true"}})
+	require.NoError(t, r.Remove(ctx, id))
+
+	_, err := os.Stat(filepath.Join(r.rootDir, id))
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = r.Inspect(ctx, id)
+	assert.ErrorIs(t, err, runtime.ErrNotFound)
+}
+
+func TestRuntime_DuplicateName(t *testing.T) {
+	r := newTestRuntime(t)
+	ctx := context.Background()
+
+	_, err := r.Create(ctx, &runtime.ContainerConfig{Name: "web", Command: []string{"true"}})
+	require.NoError(t, err)
+
+	_, err = r.Create(ctx, &runtime.ContainerConfig{Name: "web", Command: []string{"true"}})
+	assert.ErrorIs(t, err, runtime.ErrAlreadyExists)
+}
+
+func TestRuntime_NotFound(t *testing.T) {
+	r := newTestRuntime(t)
+	ctx := context.Background()
+
+	assert.ErrorIs(t, r.Start(ctx, "missing"), runtime.ErrNotFound)
+	assert.ErrorIs(t, r.Stop(ctx, "missing", time.Second), runtime.ErrNotFound)
+	assert.ErrorIs(t, r.Remove(ctx, "missing"), runtime.ErrNotFound)
+	_, err := r.Wait(ctx, "missing")
+	assert.ErrorIs(t, err, runtime.ErrNotFound)
+	_, err = r.Logs(ctx, "missing")
+	assert.ErrorIs(t, err, runtime.ErrNotFound)
+}
+
+func TestRuntime_WaitBeforeStart(t *testing.T) {
+	r := newTestRuntime(t)
+	ctx := context.Background()
+
+	id, err := r.Create(ctx, &runtime.ContainerConfig{Command: []string{"true"}})
+	require.NoError(t, err)
+
+	_, err = r.Wait(ctx, id)
+	assert.ErrorIs(t, err, runtime.ErrNotRunning)
+}
+
+func TestRuntime_Rlimits(t *testing.T) {
+	r := newTestRuntime(t)
+	ctx := context.Background()
+	limits := []runtime.Rlimit{{Name: "nofile", Soft: 64, Hard: 128}}
+
+	// The limits are in place before the command runs, not applied to it
+	// after it has started.
+	id, code := runToCompletion(t, r, &runtime.ContainerConfig{
+		Command: []string{"sh", "-c", "ulimit -Sn; ulimit -Hn"},
+		Rlimits: limits,
+	})
+	require.Equal(t, 0, code)
+	assert.Equal(t, "64\n128\n", readLogs(t, r, id))
+
+	id, err := r.Create(ctx, &runtime.ContainerConfig{
+		Command: []string{"sleep", "30"},
+		Rlimits: limits,
+	})
+	require.NoError(t, err)
+	require.NoError(t, r.Start(ctx, id))
+	defer func() { require.NoError(t, r.Stop(ctx, id, time.Second)) }()
+
+	res, err := r.Exec(ctx, id, []string{"sh", "-c", "ulimit -Sn; ulimit -Hn"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, res.ExitCode)
+	assert.Equal(t, "64\n128\n", string(res.Output))
+}
+
+func TestRuntime_Stats(t *testing.T) {
+	r := newTestRuntime(t)
+	ctx := context.Background()
+
+	id, err := r.Create(ctx, &runtime.ContainerConfig{
+		Command: []string{"sh", "-c", `printf '# queue\nqueue_depth 12\nbroken\n' > "$` + MetricsFileEnv + `"; sleep 30`},
+	})
+	require.NoError(t, err)
+	_, err = r.Stats(ctx, id)
+	require.ErrorIs(t, err, runtime.ErrNotRunning)
+
+	require.NoError(t, r.Start(ctx, id))
+	defer func() { require.NoError(t, r.Stop(ctx, id, time.Second)) }()
+
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("procfs not available")
+	}
+	require.Eventually(t, func() bool {
+		st, err := r.Stats(ctx, id)
+		require.NoError(t, err)
+		return st.Metrics["queue_depth"] == 12
+	}, 5*time.Second, 20*time.Millisecond)
+
+	st, err := r.Stats(ctx, id)
+	require.NoError(t, err)
+	assert.Positive(t, st.MemoryBytes)
+	assert.Len(t, st.Metrics, 1)
+	assert.False(t, st.Time.IsZero())
+}
+
+func TestRuntime_CgroupRootUnavailable(t *testing.T) {
+	r, err := NewRuntime(&Config{
+		RootDir:    t.TempDir(),
+		CgroupRoot: t.TempDir(),
+		Logger:     zerolog.Nop(),
+	})
+	require.NoError(t, err)
+
+	// A plain directory is not a cgroup hierarchy, so limits are disabled
+	// but containers still run.
+	assert.False(t, r.CgroupsEnabled())
+	_, code := runToCompletion(t, r, &runtime.ContainerConfig{
+		Command:   []string{"true"},
+		Resources: runtime.Resources{CPUMillis: 500, MemoryBytes: 64 << 20},
+	})
+	assert.Equal(t, 0, code)
+}
@@ -0,0 +1,237 @@
+// Package runtime defines the container runtime abstraction used by the orchestrator.
+// Backends (Docker, plain processes) implement Runtime so the rest of the system
+// never depends on how a container is actually executed.
+package runtime
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// Sentinel errors for runtime operations.
+var (
+	ErrNotFound      = errors.New("container not found")
+	ErrRunning       = errors.New("container is running")
+	ErrNotRunning    = errors.New("container is not running")
+	ErrAlreadyExists = errors.New("container already exists")
+
+	ErrImageNotPresent = errors.New("image not present")
+	ErrImageNotFound   = errors.New("image not found in registry")
+	ErrUnauthorized    = errors.New("registry authentication failed")
+)
+
+// State is the lifecycle state of a runtime container.
+type State string
+
+// Runtime container states.
+const (
+	StateCreated State = "created"
+	StateRunning State = "running"
+	StateExited  State = "exited"
+)
+
+// Resources are the compute limits applied to a container.
+// A zero value means unlimited.
+type Resources struct {
+	// CPUMillis is the CPU limit in thousandths of a core.
+	CPUMillis int64 `json:"cpu_millis,omitempty"`
+
+	// MemoryBytes is the memory limit in bytes.
+	MemoryBytes int64 `json:"memory_bytes,omitempty"`
+}
+
+// Rlimit is a POSIX resource limit such as "nofile" or "nproc".
+type Rlimit struct {
+	Name string `json:"name"`
+	Soft uint64 `json:"soft"`
+	Hard uint64 `json:"hard"`
+}
+
+// Mount makes a host directory available inside a container.
+type Mount struct {
+	// Source is the host directory.
+	Source string
+
+	// Target is the absolute path inside the container.
+	Target string
+
+	ReadOnly bool
+}
+
+// ContainerConfig describes a container to create.
+type ContainerConfig struct {
+	// Name is a human-readable name, unique within the runtime.
+	Name string
+
+	// Image identifies what to run. Backends interpret it differently:
+	// Docker runs the image once pulled, the process backend treats it as
+	// the executable when Command is empty.
+	Image string
+
+	// Command overrides the image entrypoint.
+	Command []string
+
+	// Env holds environment variables for the container.
+	Env map[string]string
+
+	// WorkingDir is the initial working directory.
+	WorkingDir string
+
+	// Labels are attached to the container for later lookup.
+	Labels map[string]string
+
+	// Resources are the CPU and memory limits.
+	Resources Resources
+
+	// Rlimits are per-process resource limits.
+	Rlimits []Rlimit
+
+	// Mounts are host directories to expose inside the container.
+	Mounts []Mount
+
+	// Sandbox is the ID of a sandbox whose network namespace the container
+	// joins. Only set for runtimes implementing SandboxRuntime.
+	Sandbox string
+}
+
+// ContainerInfo is a point-in-time view of a runtime container.
+type ContainerInfo struct {
+	ID         string
+	Name       string
+	Labels     map[string]string
+	State      State
+	Pid        int
+	ExitCode   int
+	StartedAt  time.Time
+	FinishedAt time.Time
+
+	// IPAddress is where the container's ports can be reached.
+	IPAddress string
+
+	// Error holds a backend error that prevented the container from running.
+	Error string
+}
+
+// HasLabels reports whether the container's labels include all of labels.
+func (i *ContainerInfo) HasLabels(labels map[string]string) bool {
+	for k, v := range labels {
+		if got, ok := i.Labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ExecResult is the outcome of a command run inside a container.
+type ExecResult struct {
+	ExitCode int
+	Output   []byte
+}
+
+// Runtime creates, runs and observes containers.
+// All methods identify containers by the ID returned from Create.
+type Runtime interface {
+	// Create prepares a container without starting it.
+	Create(ctx context.Context, cfg *ContainerConfig) (string, error)
+
+	// Start runs a created container.
+	// Returns ErrRunning if the container is already running.
+	Start(ctx context.Context, id string) error
+
+	// Stop asks the container to terminate and kills it after timeout.
+	// Stopping an exited container is a no-op.
+	Stop(ctx context.Context, id string, timeout time.Duration) error
+
+	// Remove deletes a container and its resources.
+	// Returns ErrRunning if the container has not been stopped.
+	Remove(ctx context.Context, id string) error
+
+	// Inspect returns the current state of a container.
+	Inspect(ctx context.Context, id string) (*ContainerInfo, error)
+
+	// List returns the containers whose labels include all of labels.
+	List(ctx context.Context, labels map[string]string) ([]*ContainerInfo, error)
+
+	// Wait blocks until the container exits and returns its exit code.
+	Wait(ctx context.Context, id string) (int, error)
+
+	// Logs returns the combined stdout and stderr captured so far.
+	Logs(ctx context.Context, id string) (io.ReadCloser, error)
+
+	// Exec runs a command inside a running container and waits for it.
+	// Returns ErrNotRunning if the container is not running.
+	Exec(ctx context.Context, id string, cmd []string) (*ExecResult, error)
+}
+
+// Stats is a sample of the resources a container is using.
+type Stats struct {
+	// CPUTime is the CPU time the container has used since it started.
+	CPUTime time.Duration
+
+	// MemoryBytes is the memory the container is using.
+	MemoryBytes int64
+
+	// Metrics are the values of application metrics the container
+	// publishes, by name. Backends that cannot collect them leave it nil.
+	Metrics map[string]float64
+
+	// Time is when the sample was taken.
+	Time time.Time
+}
+
+// StatsRuntime is implemented by runtimes that can report the resource
+// usage of their containers.
+type StatsRuntime interface {
+	// Stats samples the usage of a running container.
+	// Returns ErrNotRunning if the container is not running.
+	Stats(ctx context.Context, id string) (*Stats, error)
+}
+
+// RegistryAuth holds the credentials to pull images from a registry.
+type RegistryAuth struct {
+	// Server is the registry host, e.g. "registry.example.com:5000".
+	Server   string
+	Username string
+	Password string
+}
+
+// PullProgress reports how far an image pull has come.
+type PullProgress struct {
+	// Status is the current step, e.g. "Downloading" or "Extracting".
+	Status string
+
+	// Current and Total are the bytes downloaded so far and in all. Total
+	// is zero while unknown.
+	Current int64
+	Total   int64
+}
+
+// ImageRuntime is implemented by runtimes that run containers from images
+// pulled from registries. Their Create returns ErrImageNotPresent for an
+// image that has not been pulled.
+type ImageRuntime interface {
+	// HasImage reports whether image has been pulled.
+	HasImage(ctx context.Context, image string) (bool, error)
+
+	// PullImage pulls image from its registry, with auth unless it is nil,
+	// and calls progress, unless it is nil, as the pull proceeds.
+	// Returns ErrImageNotFound if the registry does not have the image and
+	// ErrUnauthorized if it rejects the credentials.
+	PullImage(ctx context.Context, image string, auth *RegistryAuth, progress func(PullProgress)) error
+}
+
+// SandboxRuntime is implemented by runtimes that can hold a network
+// namespace for a group of containers, so that they reach each other on
+// localhost. A sandbox outlives the containers in it until removed.
+// Runtimes without sandboxes run every container in the network they
+// normally use; the process runtime's containers all share the host's.
+type SandboxRuntime interface {
+	// CreateSandbox creates a sandbox and returns its ID.
+	CreateSandbox(ctx context.Context, name string, labels map[string]string) (string, error)
+
+	// RemoveSandbox deletes a sandbox. Returns ErrNotFound if it does not
+	// exist.
+	RemoveSandbox(ctx context.Context, id string) error
+}
@@ -0,0 +1,183 @@
+package scheduler
+
+import (
+	"errors"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+// Affinity and taint rejection reasons.
+var (
+	ErrAffinityMismatch  = errors.New("node does not match node affinity")
+	ErrUntoleratedTaint  = errors.New("node has a taint the container does not tolerate")
+	ErrAntiAffinityClash = errors.New("node runs a container that conflicts with anti-affinity rules")
+)
+
+// PreferenceScorers returns the score plugins for soft placement rules. They
+// are added to every strategy so that preferences are honored regardless of
+// how resources are balanced.
+func PreferenceScorers() []WeightedScorer {
+	return []WeightedScorer{
+		{Plugin: NodeAffinityPreference{}, Weight: 2},
+		{Plugin: AntiAffinityPreference{}, Weight: 2},
+		{Plugin: TaintPreference{}, Weight: 1},
+	}
+}
+
+// TaintToleration rejects nodes with NoSchedule or NoExecute taints the
+// container does not tolerate.
+type TaintToleration struct{}
+
+// Name implements FilterPlugin.
+func (TaintToleration) Name() string { return "TaintToleration" }
+
+// Filter implements FilterPlugin.
+func (TaintToleration) Filter(c *model.Container, n *NodeInfo) error {
+	for i := range n.Node.Spec.Taints {
+		t := &n.Node.Spec.Taints[i]
+		if t.Effect == model.TaintPreferNoSchedule {
+			continue
+		}
+		if model.FindToleration(c.Spec.Tolerations, t) == nil {
+			return ErrUntoleratedTaint
+		}
+	}
+	return nil
+}
+
+// NodeAffinity rejects nodes that do not satisfy the container's required
+// node affinity terms.
+type NodeAffinity struct{}
+
+// Name implements FilterPlugin.
+func (NodeAffinity) Name() string { return "NodeAffinity" }
+
+// Filter implements FilterPlugin.
+func (NodeAffinity) Filter(c *model.Container, n *NodeInfo) error {
+	if na := nodeAffinity(c); na != nil && !na.MatchesRequired(n.Node.Labels) {
+		return ErrAffinityMismatch
+	}
+	return nil
+}
+
+// ContainerAntiAffinity rejects nodes running a container that matches one
+// of the container's required anti-affinity selectors, or whose own required
+// anti-affinity selectors match the container.
+type ContainerAntiAffinity struct{}
+
+// Name implements FilterPlugin.
+func (ContainerAntiAffinity) Name() string { return "ContainerAntiAffinity" }
+
+// Filter implements FilterPlugin.
+func (ContainerAntiAffinity) Filter(c *model.Container, n *NodeInfo) error {
+	for _, other := range n.Containers {
+		if other.UID == c.UID {
+			continue
+		}
+		if conflicts(c, other) || conflicts(other, c) {
+			return ErrAntiAffinityClash
+		}
+	}
+	return nil
+}
+
+// conflicts reports whether a's required anti-affinity forbids sharing a
+// node with b.
+func conflicts(a, b *model.Container) bool {
+	aa := antiAffinity(a)
+	if aa == nil {
+		return false
+	}
+	for i := range aa.Required {
+		if aa.Required[i].Matches(b.Labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// NodeAffinityPreference favors nodes matching more of the container's
+// preferred node affinity terms, by weight.
+type NodeAffinityPreference struct{}
+
+// Name implements ScorePlugin.
+func (NodeAffinityPreference) Name() string { return "NodeAffinityPreference" }
+
+// Score implements ScorePlugin.
+func (NodeAffinityPreference) Score(c *model.Container, n *NodeInfo) int64 {
+	na := nodeAffinity(c)
+	if na == nil || len(na.Preferred) == 0 {
+		return MaxScore
+	}
+
+	var matched, total int64
+	for i := range na.Preferred {
+		p := &na.Preferred[i]
+		total += int64(p.Weight)
+		if p.Term.Matches(n.Node.Labels) {
+			matched += int64(p.Weight)
+		}
+	}
+	return matched * MaxScore / total
+}
+
+// AntiAffinityPreference penalizes nodes running containers that match the
+// container's preferred anti-affinity selectors, by weight.
+type AntiAffinityPreference struct{}
+
+// Name implements ScorePlugin.
+func (AntiAffinityPreference) Name() string { return "AntiAffinityPreference" }
+
+// Score implements ScorePlugin.
+func (AntiAffinityPreference) Score(c *model.Container, n *NodeInfo) int64 {
+	aa := antiAffinity(c)
+	if aa == nil || len(aa.Preferred) == 0 {
+		return MaxScore
+	}
+
+	var clashed, total int64
+	for i := range aa.Preferred {
+		p := &aa.Preferred[i]
+		total += int64(p.Weight)
+		for _, other := range n.Containers {
+			if other.UID != c.UID && p.Selector.Matches(other.Labels) {
+				clashed += int64(p.Weight)
+				break
+			}
+		}
+	}
+	return MaxScore - clashed*MaxScore/total
+}
+
+// TaintPreference favors nodes with fewer PreferNoSchedule taints the
+// container does not tolerate.
+type TaintPreference struct{}
+
+// Name implements ScorePlugin.
+func (TaintPreference) Name() string { return "TaintPreference" }
+
+// Score implements ScorePlugin.
+func (TaintPreference) Score(c *model.Container, n *NodeInfo) int64 {
+	var untolerated int64
+	for i := range n.Node.Spec.Taints {
+		t := &n.Node.Spec.Taints[i]
+		if t.Effect == model.TaintPreferNoSchedule && model.FindToleration(c.Spec.Tolerations, t) == nil {
+			untolerated++
+		}
+	}
+	return MaxScore / (1 + untolerated)
+}
+
+func nodeAffinity(c *model.Container) *model.NodeAffinity {
+	if c.Spec.Affinity == nil {
+		return nil
+	}
+	return c.Spec.Affinity.NodeAffinity
+}
+
+func antiAffinity(c *model.Container) *model.ContainerAntiAffinity {
+	if c.Spec.Affinity == nil {
+		return nil
+	}
+	return c.Spec.Affinity.ContainerAntiAffinity
+}
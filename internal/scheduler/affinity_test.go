@@ -0,0 +1,172 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+func TestTaintToleration(t *testing.T) {
+	tainted := testNode("tainted", 4000, 8*gib, nil)
+	tainted.Spec.Taints = []model.Taint{{Key: "gpu", Value: "true", Effect: model.TaintNoSchedule}}
+	node := NewNodeInfo(tainted)
+
+	c := testContainer("web", 0, 0)
+	assert.ErrorIs(t, TaintToleration{}.Filter(c, node), ErrUntoleratedTaint)
+
+	c.Spec.Tolerations = []model.Toleration{{Key: "gpu", Operator: model.TolerationEqual, Value: "false"}}
+	assert.ErrorIs(t, TaintToleration{}.Filter(c, node), ErrUntoleratedTaint)
+
+	c.Spec.Tolerations = []model.Toleration{{Key: "gpu", Operator: model.TolerationExists}}
+	assert.NoError(t, TaintToleration{}.Filter(c, node))
+
+	// PreferNoSchedule never filters, only lowers the score.
+	soft := testNode("soft", 4000, 8*gib, nil)
+	soft.Spec.Taints = []model.Taint{{Key: "spot", Effect: model.TaintPreferNoSchedule}}
+	c.Spec.Tolerations = nil
+	assert.NoError(t, TaintToleration{}.Filter(c, NewNodeInfo(soft)))
+
+	sched, _ := newTestScheduler(t, nil)
+	d := sched.Schedule(c, []*NodeInfo{NewNodeInfo(soft), NewNodeInfo(testNode("plain", 4000, 8*gib, nil))})
+	assert.Equal(t, "plain", d.NodeName)
+}
+
+func TestNodeAffinity(t *testing.T) {
+	sched, _ := newTestScheduler(t, nil)
+	nodes := []*NodeInfo{
+		NewNodeInfo(testNode("a", 4000, 8*gib, map[string]string{"zone": "a", "disk": "hdd"})),
+		NewNodeInfo(testNode("b", 4000, 8*gib, map[string]string{"zone": "b", "disk": "ssd"})),
+		NewNodeInfo(testNode("c", 4000, 8*gib, map[string]string{"zone": "c", "disk": "ssd"})),
+	}
+
+	c := testContainer("web", 0, 0)
+	c.Spec.Affinity = &model.Affinity{NodeAffinity: &model.NodeAffinity{
+		Required: []model.NodeSelectorTerm{{MatchExpressions: []model.SelectorRequirement{
+			{Key: "zone", Operator: model.OpNotIn, Values: []string{"a"}},
+		}}},
+		Preferred: []model.PreferredNodeTerm{{Weight: 50, Term: model.NodeSelectorTerm{
+			MatchExpressions: []model.SelectorRequirement{{Key: "zone", Operator: model.OpIn, Values: []string{"c"}}},
+		}}},
+	}}
+
+	d := sched.Schedule(c, nodes)
+	assert.Equal(t, "c", d.NodeName)
+	assert.Equal(t, ErrAffinityMismatch.Error(), d.Filtered["a"])
+	assert.Greater(t, d.Scores["c"], d.Scores["b"])
+}
+
+func TestContainerAntiAffinity(t *testing.T) {
+	sched, _ := newTestScheduler(t, nil)
+	a := NewNodeInfo(testNode("a", 4000, 8*gib, nil))
+	b := NewNodeInfo(testNode("b", 4000, 8*gib, nil))
+
+	replica := func(name string) *model.Container {
+		c := testContainer(name, 0, 0)
+		c.Labels = map[string]string{"app": "db"}
+		c.Spec.Affinity = &model.Affinity{ContainerAntiAffinity: &model.ContainerAntiAffinity{
+			Required: []model.LabelSelector{{MatchLabels: map[string]string{"app": "db"}}},
+		}}
+		return c
+	}
+
+	a.Add(replica("db-0"))
+	d := sched.Schedule(replica("db-1"), []*NodeInfo{a, b})
+	assert.Equal(t, "b", d.NodeName)
+	assert.Equal(t, ErrAntiAffinityClash.Error(), d.Filtered["a"])
+
+	b.Add(replica("db-1"))
+	d = sched.Schedule(replica("db-2"), []*NodeInfo{a, b})
+	assert.Empty(t, d.NodeName)
+
+	// The rule is symmetric: a plain container matching an existing
+	// replica's selector is kept off that replica's node.
+	plain := testContainer("tool", 0, 0)
+	plain.Labels = map[string]string{"app": "db"}
+	assert.ErrorIs(t, ContainerAntiAffinity{}.Filter(plain, a), ErrAntiAffinityClash)
+}
+
+func TestAntiAffinityPreference(t *testing.T) {
+	sched, _ := newTestScheduler(t, nil)
+	a := NewNodeInfo(testNode("a", 4000, 8*gib, nil))
+	b := NewNodeInfo(testNode("b", 8000, 16*gib, nil))
+	existing := testContainer("web-0", 0, 0)
+	existing.Labels = map[string]string{"app": "web"}
+	b.Add(existing)
+
+	c := testContainer("web-1", 0, 0)
+	c.Labels = map[string]string{"app": "web"}
+	c.Spec.Affinity = &model.Affinity{ContainerAntiAffinity: &model.ContainerAntiAffinity{
+		Preferred: []model.PreferredAntiAffinityTerm{{Weight: 100, Selector: model.LabelSelector{
+			MatchLabels: map[string]string{"app": "web"},
+		}}},
+	}}
+
+	d := sched.Schedule(c, []*NodeInfo{a, b})
+	assert.Equal(t, "a", d.NodeName)
+}
+
+func TestEvictViolations(t *testing.T) {
+	sched, s := newTestScheduler(t, nil)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sched.now = func() time.Time { return now }
+
+	added := now.Add(-time.Minute)
+	n := testNode("node-1", 4000, 8*gib, map[string]string{"zone": "a"})
+	n.Spec.Taints = []model.Taint{{Key: "maintenance", Effect: model.TaintNoExecute, AddedAt: &added}}
+	putNode(t, s, n)
+
+	bind := func(c *model.Container) *model.Container {
+		c.Spec.NodeName = "node-1"
+		c.Status.Phase = model.PhaseRunning
+		c.Status.RestartCount = 2
+		putContainer(t, s, c)
+		return c
+	}
+	seconds := func(v int64) *int64 { return &v }
+
+	bind(testContainer("intolerant", 0, 0))
+	tolerant := testContainer("tolerant", 0, 0)
+	tolerant.Spec.Tolerations = []model.Toleration{{Key: "maintenance", Operator: model.TolerationExists}}
+	bind(tolerant)
+	expired := testContainer("expired", 0, 0)
+	expired.Spec.Tolerations = []model.Toleration{{
+		Key: "maintenance", Operator: model.TolerationExists, Effect: model.TaintNoExecute, TolerationSeconds: seconds(30),
+	}}
+	bind(expired)
+	grace := testContainer("grace", 0, 0)
+	grace.Spec.Tolerations = []model.Toleration{{
+		Key: "maintenance", Operator: model.TolerationExists, Effect: model.TaintNoExecute, TolerationSeconds: seconds(300),
+	}}
+	bind(grace)
+	moved := testContainer("moved", 0, 0)
+	moved.Spec.Tolerations = tolerant.Spec.Tolerations
+	moved.Spec.NodeSelector = map[string]string{"zone": "b"}
+	bind(moved)
+	daemon := testContainer("daemon", 0, 0)
+	daemon.Labels = map[string]string{model.LabelDaemonSet: "logs"}
+	bind(daemon)
+
+	require.NoError(t, sched.EvictViolations(context.Background()))
+
+	for _, name := range []string{"intolerant", "expired", "moved"} {
+		c := getContainer(t, s, name)
+		assert.Empty(t, c.Spec.NodeName, name)
+		assert.Equal(t, model.PhasePending, c.Status.Phase, name)
+		assert.Equal(t, model.ReasonEvicted, c.Status.Reason, name)
+		assert.Equal(t, 2, c.Status.RestartCount, name)
+	}
+	for _, name := range []string{"tolerant", "grace", "daemon"} {
+		assert.Equal(t, "node-1", getContainer(t, s, name).Spec.NodeName, name)
+	}
+
+	// Evicted containers cannot return to the tainted node.
+	require.NoError(t, sched.SchedulePending(context.Background()))
+	c := getContainer(t, s, "intolerant")
+	assert.Empty(t, c.Spec.NodeName)
+	assert.Equal(t, model.ReasonUnschedulable, c.Status.Reason)
+}
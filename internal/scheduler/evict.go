@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/github-builder/container-orchestrator/internal/eviction"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// EvictViolations re-evaluates containers already bound to nodes against
+// the nodes' current labels and taints. A container is evicted when its
+// node has a NoExecute taint it does not tolerate, or tolerates only for a
+// period that has elapsed, or when the node no longer satisfies its node
+// selector or required node affinity. Evicted containers return to Pending
+// and are scheduled again; the agent on the old node stops them. Daemon set
+// containers are left to their controller.
+//
+// Taint evictions cannot wait and ignore disruption budgets. Moving a
+// container off a node that no longer matches its selector or affinity is
+// rebalancing, which budgets may defer to a later pass.
+func (s *Scheduler) EvictViolations(ctx context.Context) error {
+	nodes, err := store.ListJSON[model.Node](s.store, model.NodesBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing nodes: %w", err)
+	}
+	containers, err := store.ListJSON[model.Container](s.store, model.ContainersBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing containers: %w", err)
+	}
+
+	byName := make(map[string]*model.Node, len(nodes))
+	for _, n := range nodes {
+		byName[n.Name] = n
+	}
+
+	now := s.now()
+	var errs []error
+	for _, c := range containers {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// Containers being deleted stay on their node until the agent
+		// releases them.
+		if c.Spec.NodeName == "" || c.Status.IsTerminal() || c.IsTerminating() {
+			continue
+		}
+		// Daemons are bound to their node; the daemon set controller
+		// removes them when the node no longer admits them.
+		if _, ok := c.Labels[model.LabelDaemonSet]; ok {
+			continue
+		}
+		n, ok := byName[c.Spec.NodeName]
+		if !ok {
+			continue
+		}
+
+		reason, force := evictionReason(c, n, now)
+		if reason == "" {
+			continue
+		}
+		evict := s.evictor.Evict
+		if force {
+			evict = s.evictor.ForceEvict
+		}
+		err := evict(c, reason)
+		var berr *eviction.BudgetError
+		switch {
+		case errors.As(err, &berr):
+			s.logger.Debug().Err(err).Msg("rebalancing deferred")
+		case err != nil && !errors.Is(err, eviction.ErrStale):
+			errs = append(errs, fmt.Errorf("container %s: %w", c.Key(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// evictionReason explains why c may no longer run on n, or returns "" if it
+// may. It also reports whether the eviction must bypass disruption budgets.
+func evictionReason(c *model.Container, n *model.Node, now time.Time) (string, bool) {
+	for i := range n.Spec.Taints {
+		t := &n.Spec.Taints[i]
+		if t.Effect != model.TaintNoExecute {
+			continue
+		}
+		tol := model.FindToleration(c.Spec.Tolerations, t)
+		if tol == nil {
+			return fmt.Sprintf("node %s has NoExecute taint %s", n.Name, t.Key), true
+		}
+		if tol.TolerationSeconds != nil && t.AddedAt != nil {
+			deadline := t.AddedAt.Add(time.Duration(*tol.TolerationSeconds) * time.Second)
+			if !now.Before(deadline) {
+				return fmt.Sprintf("toleration of NoExecute taint %s on node %s expired", t.Key, n.Name), true
+			}
+		}
+	}
+
+	if !labelsMatch(c.Spec.NodeSelector, n.Labels) {
+		return fmt.Sprintf("node %s no longer matches node selector", n.Name), false
+	}
+	if na := nodeAffinity(c); na != nil && !na.MatchesRequired(n.Labels) {
+		return fmt.Sprintf("node %s no longer matches node affinity", n.Name), false
+	}
+	return "", false
+}
@@ -0,0 +1,55 @@
+package scheduler
+
+import (
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+// unit returns the containers that must be placed together with the
+// pending container c: c alone, or every pending member of its group. The
+// returned target stands in for the unit when deciding: it has the
+// scheduling constraints the members share, the sum of their requests and
+// all their ports and volumes. nodes are the nodes the unit may go to; a
+// group with members already bound keeps the rest on their node.
+func (snap *snapshot) unit(c *model.Container) (members []*model.Container, target *model.Container, nodes []*NodeInfo) {
+	name, ok := c.Labels[model.LabelGroup]
+	if !ok {
+		return []*model.Container{c}, c, snap.nodes
+	}
+
+	bound := ""
+	for _, m := range snap.groups[model.Key(c.Namespace, name)] {
+		if m.Spec.NodeName == "" {
+			members = append(members, m)
+		} else if bound == "" {
+			bound = m.Spec.NodeName
+		}
+	}
+
+	t := *c
+	t.Spec.Resources = model.Resources{}
+	t.Spec.Ports = nil
+	t.Spec.VolumeMounts = nil
+	volumes := make(map[string]bool)
+	for _, m := range members {
+		t.Spec.Resources.CPUMillis += m.Spec.Resources.CPUMillis
+		t.Spec.Resources.MemoryBytes += m.Spec.Resources.MemoryBytes
+		t.Spec.Ports = append(t.Spec.Ports, m.Spec.Ports...)
+		for _, vm := range m.Spec.VolumeMounts {
+			if !volumes[vm.Name] {
+				volumes[vm.Name] = true
+				t.Spec.VolumeMounts = append(t.Spec.VolumeMounts, vm)
+			}
+		}
+	}
+
+	nodes = snap.nodes
+	if bound != "" {
+		nodes = nil
+		for _, n := range snap.nodes {
+			if n.Node.Name == bound {
+				nodes = append(nodes, n)
+			}
+		}
+	}
+	return members, &t, nodes
+}
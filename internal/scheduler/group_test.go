@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+func groupMember(group, name string, cpu int64) *model.Container {
+	c := testContainer(group+"-"+name, cpu, 0)
+	c.Labels = map[string]string{model.LabelGroup: group}
+	return c
+}
+
+func TestSchedulePending_GroupsAreCoScheduled(t *testing.T) {
+	sched, s := newTestScheduler(t, nil)
+	putNode(t, s, testNode("node-1", 1000, gib, nil))
+	putNode(t, s, testNode("node-2", 1000, gib, nil))
+
+	// node-1 is emptier, but only node-2 has room for the whole group.
+	busy := testContainer("busy", 400, 0)
+	busy.Spec.NodeName = "node-2"
+	putContainer(t, s, busy)
+	busier := testContainer("busier", 500, 0)
+	busier.Spec.NodeName = "node-1"
+	putContainer(t, s, busier)
+
+	putContainer(t, s, groupMember("shop", "proxy", 100))
+	putContainer(t, s, groupMember("shop", "app", 400))
+	putContainer(t, s, groupMember("shop", "logs", 50))
+
+	// Each member of this group fits somewhere, the group fits nowhere.
+	putContainer(t, s, groupMember("big", "a", 400))
+	putContainer(t, s, groupMember("big", "b", 400))
+
+	require.NoError(t, sched.SchedulePending(context.Background()))
+
+	for _, name := range []string{"shop-proxy", "shop-app", "shop-logs"} {
+		assert.Equal(t, "node-2", getContainer(t, s, name).Spec.NodeName, name)
+	}
+	for _, name := range []string{"big-a", "big-b"} {
+		c := getContainer(t, s, name)
+		assert.Empty(t, c.Spec.NodeName, name)
+		assert.Equal(t, model.ReasonUnschedulable, c.Status.Reason)
+	}
+}
+
+func TestSchedulePending_GroupMembersFollowTheirGroup(t *testing.T) {
+	sched, s := newTestScheduler(t, nil)
+	putNode(t, s, testNode("node-1", 1000, gib, nil))
+	putNode(t, s, testNode("node-2", 4000, gib, nil))
+
+	running := groupMember("shop", "app", 300)
+	running.Spec.NodeName = "node-1"
+	putContainer(t, s, running)
+	putContainer(t, s, groupMember("shop", "proxy", 100))
+
+	require.NoError(t, sched.SchedulePending(context.Background()))
+	assert.Equal(t, "node-1", getContainer(t, s, "shop-proxy").Spec.NodeName)
+
+	// A member that does not fit next to the others waits.
+	putContainer(t, s, groupMember("shop", "logs", 1000))
+	require.NoError(t, sched.SchedulePending(context.Background()))
+	c := getContainer(t, s, "shop-logs")
+	assert.Empty(t, c.Spec.NodeName)
+	assert.Equal(t, "0/1 nodes are available: 1 insufficient cpu", c.Status.Message)
+}
@@ -0,0 +1,213 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+// MaxScore is the highest score a ScorePlugin may return.
+const MaxScore = 100
+
+// FilterPlugin rejects nodes that cannot run a container.
+type FilterPlugin interface {
+	// Name identifies the plugin in logs and decisions.
+	Name() string
+
+	// Filter returns nil if the container can run on the node, or an error
+	// whose message explains why it cannot.
+	Filter(c *model.Container, node *NodeInfo) error
+}
+
+// ScorePlugin ranks the nodes that passed filtering.
+type ScorePlugin interface {
+	// Name identifies the plugin in logs and decisions.
+	Name() string
+
+	// Score rates a node from 0 (worst) to MaxScore (best).
+	Score(c *model.Container, node *NodeInfo) int64
+}
+
+// WeightedScorer pairs a ScorePlugin with its weight in the final score.
+type WeightedScorer struct {
+	Plugin ScorePlugin
+	Weight int64
+}
+
+// Filter rejection reasons. Nodes rejected for the same reason are counted
+// together in the unschedulable message.
+var (
+	ErrNodeNotReady       = errors.New("node is not ready")
+	ErrNodeUnschedulable  = errors.New("node is cordoned")
+	ErrInsufficientCPU    = errors.New("insufficient cpu")
+	ErrInsufficientMemory = errors.New("insufficient memory")
+	ErrPortConflict       = errors.New("host port already in use")
+	ErrSelectorMismatch   = errors.New("node does not match node selector")
+)
+
+// DefaultFilters returns the built-in filter plugins.
+func DefaultFilters() []FilterPlugin {
+	return []FilterPlugin{
+		NodeReady{},
+		NodeSchedulable{},
+		VolumeBinding{},
+		NodeSelector{},
+		NodeAffinity{},
+		TaintToleration{},
+		ResourcesFit{},
+		HostPorts{},
+		ContainerAntiAffinity{},
+	}
+}
+
+// NodeReady rejects nodes that are not Ready.
+type NodeReady struct{}
+
+// Name implements FilterPlugin.
+func (NodeReady) Name() string { return "NodeReady" }
+
+// Filter implements FilterPlugin.
+func (NodeReady) Filter(_ *model.Container, n *NodeInfo) error {
+	if !n.Node.IsReady() {
+		return ErrNodeNotReady
+	}
+	return nil
+}
+
+// NodeSchedulable rejects cordoned nodes. Containers already on them keep
+// running until they are evicted.
+type NodeSchedulable struct{}
+
+// Name implements FilterPlugin.
+func (NodeSchedulable) Name() string { return "NodeSchedulable" }
+
+// Filter implements FilterPlugin.
+func (NodeSchedulable) Filter(_ *model.Container, n *NodeInfo) error {
+	if n.Node.Spec.Unschedulable {
+		return ErrNodeUnschedulable
+	}
+	return nil
+}
+
+// NodeSelector rejects nodes missing any label in the container's node selector.
+type NodeSelector struct{}
+
+// Name implements FilterPlugin.
+func (NodeSelector) Name() string { return "NodeSelector" }
+
+// Filter implements FilterPlugin.
+func (NodeSelector) Filter(c *model.Container, n *NodeInfo) error {
+	for k, v := range c.Spec.NodeSelector {
+		if n.Node.Labels[k] != v {
+			return ErrSelectorMismatch
+		}
+	}
+	return nil
+}
+
+// ResourcesFit rejects nodes without enough unreserved CPU or memory.
+type ResourcesFit struct{}
+
+// Name implements FilterPlugin.
+func (ResourcesFit) Name() string { return "ResourcesFit" }
+
+// Filter implements FilterPlugin.
+func (ResourcesFit) Filter(c *model.Container, n *NodeInfo) error {
+	free := n.Free()
+	req := c.Spec.Resources
+	if req.CPUMillis > free.CPUMillis {
+		return ErrInsufficientCPU
+	}
+	if req.MemoryBytes > free.MemoryBytes {
+		return ErrInsufficientMemory
+	}
+	return nil
+}
+
+// HostPorts rejects nodes where a requested host port is already taken.
+type HostPorts struct{}
+
+// Name implements FilterPlugin.
+func (HostPorts) Name() string { return "HostPorts" }
+
+// Filter implements FilterPlugin.
+func (HostPorts) Filter(c *model.Container, n *NodeInfo) error {
+	for _, p := range c.Spec.Ports {
+		if p.HostPort == 0 {
+			continue
+		}
+		if n.UsedPorts[hostPortKey(p)] {
+			return fmt.Errorf("%w: %d/%s", ErrPortConflict, p.HostPort, p.Protocol)
+		}
+	}
+	return nil
+}
+
+// LeastAllocated favors nodes with the most free resources, spreading load.
+type LeastAllocated struct{}
+
+// Name implements ScorePlugin.
+func (LeastAllocated) Name() string { return "LeastAllocated" }
+
+// Score implements ScorePlugin.
+func (LeastAllocated) Score(c *model.Container, n *NodeInfo) int64 {
+	return MaxScore - allocatedScore(c, n)
+}
+
+// MostAllocated favors the fullest nodes that still fit, packing containers
+// tightly so that whole nodes stay free.
+type MostAllocated struct{}
+
+// Name implements ScorePlugin.
+func (MostAllocated) Name() string { return "MostAllocated" }
+
+// Score implements ScorePlugin.
+func (MostAllocated) Score(c *model.Container, n *NodeInfo) int64 {
+	return allocatedScore(c, n)
+}
+
+// allocatedScore is the average CPU and memory utilization of the node, in
+// percent, after placing the container.
+func allocatedScore(c *model.Container, n *NodeInfo) int64 {
+	capacity := n.Node.Spec.Capacity
+	cpu := utilization(n.Requested.CPUMillis+c.Spec.Resources.CPUMillis, capacity.CPUMillis)
+	mem := utilization(n.Requested.MemoryBytes+c.Spec.Resources.MemoryBytes, capacity.MemoryBytes)
+	return (cpu + mem) / 2
+}
+
+func utilization(requested, capacity int64) int64 {
+	if capacity <= 0 {
+		return MaxScore
+	}
+	return min(requested*MaxScore/capacity, MaxScore)
+}
+
+// Spread favors nodes running the fewest containers with the same labels,
+// so replicas of one workload land on different nodes. Containers without
+// labels are spread by total container count.
+type Spread struct{}
+
+// Name implements ScorePlugin.
+func (Spread) Name() string { return "Spread" }
+
+// Score implements ScorePlugin.
+func (Spread) Score(c *model.Container, n *NodeInfo) int64 {
+	var peers int64
+	for _, other := range n.Containers {
+		if len(c.Labels) == 0 || labelsMatch(c.Labels, other.Labels) {
+			peers++
+		}
+	}
+	return MaxScore / (1 + peers)
+}
+
+// labelsMatch reports whether have contains every entry in want.
+func labelsMatch(want, have map[string]string) bool {
+	for k, v := range want {
+		if have[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,141 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/github-builder/container-orchestrator/internal/eviction"
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+// preemption is a plan to make room for a container on a node by evicting
+// containers of lower priority.
+type preemption struct {
+	node    *NodeInfo
+	victims []*model.Container
+
+	// highest and total are the highest and the summed priority of the
+	// victims.
+	highest int
+	total   int
+}
+
+// better reports whether p disrupts less than q: its most important victim
+// ranks lower, then its victims rank lower in total, then it has fewer.
+// Ties go to the alphabetically first node.
+func (p *preemption) better(q *preemption) bool {
+	switch {
+	case p.highest != q.highest:
+		return p.highest < q.highest
+	case p.total != q.total:
+		return p.total < q.total
+	case len(p.victims) != len(q.victims):
+		return len(p.victims) < len(q.victims)
+	default:
+		return p.node.Node.Name < q.node.Node.Name
+	}
+}
+
+// preempt tries to place c, which fits none of nodes as things are, by
+// evicting containers of lower priority from the node where that disrupts
+// least. Nodes where the evictions would violate a disruption budget are
+// not considered. On success c is assigned to the node in d, the victims
+// are listed in it and the nodes no longer count them.
+func (s *Scheduler) preempt(c *model.Container, d *Decision, nodes []*NodeInfo, priorities *priorities) error {
+	var best *preemption
+	for _, n := range nodes {
+		p := s.victims(c, d.Priority, n, priorities)
+		if p == nil || (best != nil && !p.better(best)) {
+			continue
+		}
+		if s.evictor.CanEvict(p.victims) != nil {
+			continue
+		}
+		best = p
+	}
+	if best == nil {
+		return nil
+	}
+
+	node := best.node.Node.Name
+	reason := fmt.Sprintf("preempted by %s with priority %d on node %s", c.Key(), d.Priority, node)
+	err := s.evictor.Preempt(best.victims, reason)
+	var berr *eviction.BudgetError
+	if errors.As(err, &berr) || errors.Is(err, eviction.ErrStale) {
+		// Something changed since the snapshot; retry on the next pass.
+		s.logger.Debug().Err(err).Str("container", c.Key()).Msg("preemption abandoned")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("preempting on node %s: %w", node, err)
+	}
+
+	for _, v := range best.victims {
+		best.node.Remove(v)
+		d.Preempted = append(d.Preempted, v.Key())
+	}
+	d.NodeName = node
+	d.Message = fmt.Sprintf("assigned to node %s after preempting %d lower-priority containers", node, len(best.victims))
+	s.logger.Info().Str("container", c.Key()).Int("priority", d.Priority).Str("node", node).
+		Strs("victims", d.Preempted).Msg("containers preempted")
+	s.events.Eventf(model.ContainersBucket, &c.ObjectMeta, model.EventNormal, model.EventPreempting,
+		"Preempted %s on node %s", strings.Join(d.Preempted, ", "), node)
+	return nil
+}
+
+// victims returns the smallest set of containers of priority below prio
+// whose eviction lets c fit on n, or nil if there is none. Containers of
+// the highest priority are spared first. Daemon set containers are bound
+// to their node and never preempted, and containers being deleted cannot
+// be evicted again.
+func (s *Scheduler) victims(c *model.Container, prio int, n *NodeInfo, priorities *priorities) *preemption {
+	var lower []*model.Container
+	for _, v := range n.Containers {
+		if _, daemon := v.Labels[model.LabelDaemonSet]; daemon || v.IsTerminating() {
+			continue
+		}
+		if priorities.of(v) < prio {
+			lower = append(lower, v)
+		}
+	}
+	if len(lower) == 0 {
+		return nil
+	}
+
+	trial := n.clone()
+	for _, v := range lower {
+		trial.Remove(v)
+	}
+	if s.filter(c, trial) != nil {
+		// Evicting everything it may does not make room.
+		return nil
+	}
+
+	sort.SliceStable(lower, func(i, j int) bool {
+		pi, pj := priorities.of(lower[i]), priorities.of(lower[j])
+		if pi != pj {
+			return pi > pj
+		}
+		return lower[i].CreatedAt.Before(lower[j].CreatedAt)
+	})
+
+	p := &preemption{node: n, highest: math.MinInt}
+	for _, v := range lower {
+		trial.Add(v)
+		if s.filter(c, trial) == nil {
+			continue
+		}
+		trial.Remove(v)
+		vp := priorities.of(v)
+		p.victims = append(p.victims, v)
+		p.highest = max(p.highest, vp)
+		p.total += vp
+	}
+	if len(p.victims) == 0 {
+		return nil
+	}
+	return p
+}
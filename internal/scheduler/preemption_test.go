@@ -0,0 +1,146 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+func putPriorityClass(t *testing.T, s store.Store, name string, value int, policy model.PreemptionPolicy) {
+	t.Helper()
+	pc := &model.PriorityClass{ObjectMeta: model.ObjectMeta{Name: name}, Value: value, PreemptionPolicy: policy}
+	pc.SetDefaults()
+	require.NoError(t, pc.Validate())
+	require.NoError(t, store.PutJSON(s, model.PriorityClassesBucket, name, pc))
+}
+
+// running returns a container bound to a node with the given class.
+func running(name, nodeName, class string, cpu int64) *model.Container {
+	c := testContainer(name, cpu, 0)
+	c.Spec.NodeName = nodeName
+	c.Spec.PriorityClassName = class
+	c.Status = model.ContainerStatus{Phase: model.PhaseRunning, Ready: true}
+	return c
+}
+
+func TestSchedulePending_PriorityOrder(t *testing.T) {
+	sched, s := newTestScheduler(t, nil)
+	putPriorityClass(t, s, "high", 1000, model.PreemptNever)
+	putNode(t, s, testNode("node-1", 1000, gib, nil))
+
+	// Both fit alone; the more important one goes first although it is
+	// younger and later in key order.
+	low := testContainer("a-low", 800, 0)
+	low.CreatedAt = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	high := testContainer("b-high", 800, 0)
+	high.Spec.PriorityClassName = "high"
+	high.CreatedAt = low.CreatedAt.Add(time.Hour)
+	putContainer(t, s, low)
+	putContainer(t, s, high)
+
+	unknown := testContainer("c-unknown", 100, 0)
+	unknown.Spec.PriorityClassName = "missing"
+	putContainer(t, s, unknown)
+
+	require.NoError(t, sched.SchedulePending(context.Background()))
+
+	got := getContainer(t, s, "b-high")
+	assert.Equal(t, "node-1", got.Spec.NodeName)
+	assert.Equal(t, 1000, got.Status.Scheduling.Priority)
+	assert.Empty(t, getContainer(t, s, "a-low").Spec.NodeName)
+
+	got = getContainer(t, s, "c-unknown")
+	assert.Empty(t, got.Spec.NodeName)
+	assert.Equal(t, "priority class not found: missing", got.Status.Message)
+}
+
+func TestSchedulePending_Preempts(t *testing.T) {
+	sched, s := newTestScheduler(t, nil)
+	putPriorityClass(t, s, "low", 10, "")
+	putPriorityClass(t, s, "mid", 100, "")
+	putPriorityClass(t, s, "critical", 1000, "")
+	putNode(t, s, testNode("node-1", 1000, gib, nil))
+	putNode(t, s, testNode("node-2", 1000, gib, nil))
+
+	// Evicting one low container from node-1 is enough; node-2 would cost
+	// a mid one.
+	putContainer(t, s, running("n1-low-a", "node-1", "low", 400))
+	putContainer(t, s, running("n1-low-b", "node-1", "low", 400))
+	putContainer(t, s, running("n1-mid", "node-1", "mid", 200))
+	putContainer(t, s, running("n2-mid", "node-2", "mid", 900))
+	putContainer(t, s, running("n2-low", "node-2", "", 100))
+
+	critical := testContainer("critical", 400, 0)
+	critical.Spec.PriorityClassName = "critical"
+	putContainer(t, s, critical)
+
+	require.NoError(t, sched.SchedulePending(context.Background()))
+
+	got := getContainer(t, s, "critical")
+	assert.Equal(t, "node-1", got.Spec.NodeName)
+	require.NotNil(t, got.Status.Scheduling)
+	assert.Len(t, got.Status.Scheduling.Preempted, 1)
+	assert.Contains(t, got.Status.Scheduling.Message, "after preempting 1 lower-priority containers")
+
+	victim := getContainer(t, s, got.Status.Scheduling.Preempted[0])
+	assert.Contains(t, []string{"n1-low-a", "n1-low-b"}, victim.Name)
+	assert.Empty(t, victim.Spec.NodeName)
+	assert.Equal(t, model.PhasePending, victim.Status.Phase)
+	assert.Equal(t, model.ReasonPreempted, victim.Status.Reason)
+	assert.Equal(t, "preempted by critical with priority 1000 on node node-1", victim.Status.Message)
+
+	for _, name := range []string{"n1-mid", "n2-mid", "n2-low"} {
+		assert.NotEmpty(t, getContainer(t, s, name).Spec.NodeName, name)
+	}
+}
+
+func TestSchedulePending_PreemptionLimits(t *testing.T) {
+	sched, s := newTestScheduler(t, nil)
+	putPriorityClass(t, s, "low", 10, "")
+	putPriorityClass(t, s, "polite", 1000, model.PreemptNever)
+	putPriorityClass(t, s, "critical", 1000, "")
+	putNode(t, s, testNode("node-1", 1000, gib, nil))
+
+	// The only victims are protected by a disruption budget.
+	for _, name := range []string{"web-1", "web-2"} {
+		c := running(name, "node-1", "low", 500)
+		c.Labels = map[string]string{"app": "web"}
+		putContainer(t, s, c)
+	}
+	require.NoError(t, store.PutJSON(s, model.DisruptionBudgetsBucket, "web", &model.DisruptionBudget{
+		ObjectMeta: model.ObjectMeta{Name: "web"},
+		Spec: model.DisruptionBudgetSpec{
+			Selector:     model.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			MinAvailable: model.FromInt(2),
+		},
+	}))
+
+	polite := testContainer("polite", 500, 0)
+	polite.Spec.PriorityClassName = "polite"
+	putContainer(t, s, polite)
+	critical := testContainer("critical", 500, 0)
+	critical.Spec.PriorityClassName = "critical"
+	putContainer(t, s, critical)
+
+	require.NoError(t, sched.SchedulePending(context.Background()))
+	assert.Empty(t, getContainer(t, s, "polite").Spec.NodeName)
+	assert.Empty(t, getContainer(t, s, "critical").Spec.NodeName)
+	assert.Equal(t, "node-1", getContainer(t, s, "web-1").Spec.NodeName)
+	assert.Equal(t, "node-1", getContainer(t, s, "web-2").Spec.NodeName)
+
+	// With the budget relaxed, only the container allowed to preempt does.
+	_, err := store.UpdateJSON(s, model.DisruptionBudgetsBucket, "web", func(b *model.DisruptionBudget) error {
+		b.Spec.MinAvailable = model.FromInt(1)
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, sched.SchedulePending(context.Background()))
+	assert.Empty(t, getContainer(t, s, "polite").Spec.NodeName)
+	assert.Equal(t, "node-1", getContainer(t, s, "critical").Spec.NodeName)
+}
@@ -0,0 +1,74 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+// ErrPriorityClassNotFound is the unschedulable reason of a container
+// naming a priority class that does not exist.
+var ErrPriorityClassNotFound = errors.New("priority class not found")
+
+// priorities resolves the priority of containers from priority classes.
+type priorities struct {
+	classes map[string]*model.PriorityClass
+
+	// def is the global default class, nil if there is none.
+	def *model.PriorityClass
+}
+
+func newPriorities(classes []*model.PriorityClass) *priorities {
+	p := &priorities{classes: make(map[string]*model.PriorityClass, len(classes))}
+	for _, pc := range classes {
+		p.classes[pc.Name] = pc
+		if pc.GlobalDefault && (p.def == nil || pc.Value > p.def.Value) {
+			p.def = pc
+		}
+	}
+	return p
+}
+
+// class returns the priority class of c, nil if it names none and there
+// is no default.
+func (p *priorities) class(c *model.Container) (*model.PriorityClass, error) {
+	name := c.Spec.PriorityClassName
+	if name == "" {
+		return p.def, nil
+	}
+	pc, ok := p.classes[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrPriorityClassNotFound, name)
+	}
+	return pc, nil
+}
+
+// of returns the priority of c. Containers whose class is missing rank
+// highest, so that they are never preempted; pending ones are reported
+// unschedulable instead.
+func (p *priorities) of(c *model.Container) int {
+	pc, err := p.class(c)
+	switch {
+	case err != nil:
+		return math.MaxInt
+	case pc == nil:
+		return 0
+	default:
+		return pc.Value
+	}
+}
+
+// sort orders pending containers for scheduling: higher priority first,
+// then oldest first.
+func (p *priorities) sort(pending []*model.Container) {
+	sort.SliceStable(pending, func(i, j int) bool {
+		pi, pj := p.of(pending[i]), p.of(pending[j])
+		if pi != pj {
+			return pi > pj
+		}
+		return pending[i].CreatedAt.Before(pending[j].CreatedAt)
+	})
+}
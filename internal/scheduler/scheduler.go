@@ -0,0 +1,372 @@
+// Package scheduler assigns pending containers to nodes. Each decision runs
+// in two phases: filter plugins drop nodes that cannot run the container,
+// then score plugins rank the remaining nodes and the best one wins. The
+// outcome, including why each rejected node was rejected, is recorded on
+// the container's status.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/github-builder/container-orchestrator/internal/events"
+	"github.com/github-builder/container-orchestrator/internal/eviction"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// errStale is returned when the container changed while being scheduled.
+var errStale = errors.New("container changed during scheduling")
+
+// Scoring strategies selectable by name.
+const (
+	StrategyLeastAllocated = "least-allocated"
+	StrategyMostAllocated  = "most-allocated"
+	StrategySpread         = "spread"
+)
+
+// Strategies lists the valid scoring strategy names.
+var Strategies = []string{StrategyLeastAllocated, StrategyMostAllocated, StrategySpread}
+
+// ScorersFor returns the score plugins for a named strategy, followed by
+// the PreferenceScorers.
+func ScorersFor(strategy string) ([]WeightedScorer, error) {
+	var scorers []WeightedScorer
+	switch strategy {
+	case StrategyLeastAllocated:
+		scorers = []WeightedScorer{{Plugin: LeastAllocated{}, Weight: 1}}
+	case StrategyMostAllocated:
+		scorers = []WeightedScorer{{Plugin: MostAllocated{}, Weight: 1}}
+	case StrategySpread:
+		scorers = []WeightedScorer{{Plugin: Spread{}, Weight: 2}, {Plugin: LeastAllocated{}, Weight: 1}}
+	default:
+		return nil, fmt.Errorf("unknown scheduling strategy %q", strategy)
+	}
+	return append(scorers, PreferenceScorers()...), nil
+}
+
+// Config holds dependencies for the scheduler.
+type Config struct {
+	Store  store.Store
+	Logger zerolog.Logger
+
+	// Interval is how often pending containers are scheduled.
+	Interval time.Duration
+
+	// Filters decide which nodes can run a container. Defaults to DefaultFilters.
+	Filters []FilterPlugin
+
+	// Scorers rank the feasible nodes. Defaults to the least-allocated
+	// strategy.
+	Scorers []WeightedScorer
+
+	// Evictor evicts containers that no longer fit their nodes. Defaults
+	// to an evictor on Store.
+	Evictor *eviction.Evictor
+
+	// Events records scheduling decisions. Defaults to a recorder on Store.
+	Events *events.Recorder
+}
+
+// Scheduler places pending containers on nodes.
+type Scheduler struct {
+	store    store.Store
+	logger   zerolog.Logger
+	interval time.Duration
+	filters  []FilterPlugin
+	scorers  []WeightedScorer
+	evictor  *eviction.Evictor
+	events   *events.Recorder
+
+	// now is replaceable for tests.
+	now func() time.Time
+}
+
+// New creates a scheduler.
+func New(cfg *Config) *Scheduler {
+	filters := cfg.Filters
+	if filters == nil {
+		filters = DefaultFilters()
+	}
+	scorers := cfg.Scorers
+	if scorers == nil {
+		scorers, _ = ScorersFor(StrategyLeastAllocated)
+	}
+	recorder := cfg.Events
+	if recorder == nil {
+		recorder = events.NewRecorder(&events.Config{Store: cfg.Store, Logger: cfg.Logger})
+	}
+	evictor := cfg.Evictor
+	if evictor == nil {
+		evictor = eviction.New(&eviction.Config{Store: cfg.Store, Logger: cfg.Logger, Events: recorder})
+	}
+
+	return &Scheduler{
+		store:    cfg.Store,
+		logger:   cfg.Logger,
+		interval: cfg.Interval,
+		filters:  filters,
+		scorers:  scorers,
+		evictor:  evictor,
+		events:   recorder.WithSource("scheduler"),
+		now:      func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Decision is the outcome of scheduling one container.
+type Decision struct {
+	// NodeName is the chosen node, empty if no node fits.
+	NodeName string
+
+	// Filtered maps each rejected node to the reason it was rejected.
+	Filtered map[string]string
+
+	// Scores maps each feasible node to its weighted score.
+	Scores map[string]int64
+
+	// Message summarizes the decision.
+	Message string
+
+	// Priority is the container's priority.
+	Priority int
+
+	// Preempted lists the keys of containers evicted to make room.
+	Preempted []string
+}
+
+// Run evicts containers that no longer fit their nodes and schedules
+// pending containers every interval until ctx is canceled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.EvictViolations(ctx); err != nil {
+			s.logger.Error().Err(err).Msg("eviction check failed")
+		}
+		if err := s.SchedulePending(ctx); err != nil {
+			s.logger.Error().Err(err).Msg("scheduling failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SchedulePending attempts to place every unscheduled container, in order
+// of priority. A container that fits no node may preempt containers of
+// lower priority. The members of a group are placed together, on one node.
+func (s *Scheduler) SchedulePending(ctx context.Context) error {
+	snap, err := s.snapshot()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	done := make(map[string]bool)
+	for _, c := range snap.pending {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if done[c.Key()] {
+			continue
+		}
+		members, target, nodes := snap.unit(c)
+
+		var d *Decision
+		pc, err := snap.priorities.class(c)
+		if err != nil {
+			d = &Decision{Message: err.Error()}
+		} else {
+			d = s.Schedule(target, nodes)
+			if pc != nil {
+				d.Priority = pc.Value
+			}
+			if d.NodeName == "" && (pc == nil || pc.PreemptionPolicy != model.PreemptNever) {
+				if err := s.preempt(target, d, nodes, snap.priorities); err != nil {
+					errs = append(errs, fmt.Errorf("container %s: %w", c.Key(), err))
+				}
+			}
+		}
+
+		for _, m := range members {
+			done[m.Key()] = true
+			if err := s.record(m, d); err != nil {
+				if !errors.Is(err, errStale) {
+					errs = append(errs, fmt.Errorf("container %s: %w", m.Key(), err))
+				}
+				continue
+			}
+			if d.NodeName == "" {
+				continue
+			}
+
+			// Account for the placement so later containers in this pass
+			// see the node's reduced capacity.
+			for _, n := range snap.nodes {
+				if n.Node.Name == d.NodeName {
+					m.Spec.NodeName = d.NodeName
+					n.Add(m)
+				}
+			}
+			if err := s.bindVolumes(m, snap.volumes); err != nil {
+				errs = append(errs, fmt.Errorf("container %s: %w", m.Key(), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Schedule decides where a container should run. It does not modify the
+// container or the nodes.
+func (s *Scheduler) Schedule(c *model.Container, nodes []*NodeInfo) *Decision {
+	d := &Decision{
+		Filtered: make(map[string]string),
+		Scores:   make(map[string]int64),
+	}
+
+	feasible := make([]*NodeInfo, 0, len(nodes))
+	for _, n := range nodes {
+		if err := s.filter(c, n); err != nil {
+			d.Filtered[n.Node.Name] = err.Error()
+			continue
+		}
+		feasible = append(feasible, n)
+	}
+
+	if len(feasible) == 0 {
+		d.Message = unschedulableMessage(len(nodes), d.Filtered)
+		return d
+	}
+
+	for _, n := range feasible {
+		var total int64
+		for _, ws := range s.scorers {
+			total += ws.Weight * ws.Plugin.Score(c, n)
+		}
+		d.Scores[n.Node.Name] = total
+	}
+
+	// Highest score wins; ties go to the alphabetically first node so that
+	// decisions are deterministic.
+	sort.Slice(feasible, func(i, j int) bool {
+		si, sj := d.Scores[feasible[i].Node.Name], d.Scores[feasible[j].Node.Name]
+		if si != sj {
+			return si > sj
+		}
+		return feasible[i].Node.Name < feasible[j].Node.Name
+	})
+
+	d.NodeName = feasible[0].Node.Name
+	d.Message = fmt.Sprintf("assigned to node %s (%d/%d nodes feasible)", d.NodeName, len(feasible), len(nodes))
+	return d
+}
+
+// filter runs the filter plugins in order and returns the first rejection.
+func (s *Scheduler) filter(c *model.Container, n *NodeInfo) error {
+	for _, f := range s.filters {
+		if err := f.Filter(c, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record binds the container to the chosen node, or records why it could
+// not be scheduled. Each failed attempt is counted on the container's
+// FailedScheduling event, even when its status stays the same.
+func (s *Scheduler) record(c *model.Container, d *Decision) error {
+	status := &model.SchedulingStatus{
+		NodeName:      d.NodeName,
+		Message:       d.Message,
+		FilteredNodes: d.Filtered,
+		Scores:        d.Scores,
+		Priority:      d.Priority,
+		Preempted:     d.Preempted,
+		AttemptedAt:   time.Now().UTC(),
+	}
+
+	unchanged := false
+	_, err := store.UpdateJSON(s.store, model.ContainersBucket, c.Key(), func(cur *model.Container) error {
+		if cur.UID != c.UID || cur.Spec.NodeName != "" {
+			return errStale
+		}
+
+		if d.NodeName == "" {
+			// Avoid rewriting an identical unschedulable verdict every pass.
+			if prev := cur.Status.Scheduling; prev != nil && prev.Message == d.Message {
+				unchanged = true
+				return errStale
+			}
+			cur.Status.Reason = model.ReasonUnschedulable
+			cur.Status.Message = d.Message
+		} else {
+			cur.Spec.NodeName = d.NodeName
+			switch cur.Status.Reason {
+			case model.ReasonUnschedulable, model.ReasonEvicted, model.ReasonPreempted:
+				cur.Status.Reason = ""
+				cur.Status.Message = ""
+			}
+		}
+		cur.Status.Scheduling = status
+		return nil
+	})
+	if unchanged {
+		s.events.Event(model.ContainersBucket, &c.ObjectMeta, model.EventWarning, model.EventFailedScheduling, d.Message)
+		return errStale
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		return errStale
+	}
+	if err != nil {
+		return err
+	}
+
+	if d.NodeName == "" {
+		s.logger.Info().Str("container", c.Name).Str("reason", d.Message).Msg("container unschedulable")
+		s.events.Event(model.ContainersBucket, &c.ObjectMeta, model.EventWarning, model.EventFailedScheduling, d.Message)
+	} else {
+		s.logger.Info().Str("container", c.Name).Str("node", d.NodeName).Msg("container scheduled")
+		s.events.Event(model.ContainersBucket, &c.ObjectMeta, model.EventNormal, model.EventScheduled, d.Message)
+	}
+	return nil
+}
+
+// unschedulableMessage summarizes rejections, e.g.
+// "0/3 nodes are available: 2 insufficient cpu, 1 node is not ready".
+func unschedulableMessage(total int, filtered map[string]string) string {
+	if total == 0 {
+		return "0/0 nodes are available: no nodes registered"
+	}
+
+	counts := make(map[string]int)
+	for _, reason := range filtered {
+		counts[reason]++
+	}
+
+	reasons := make([]string, 0, len(counts))
+	for reason := range counts {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if counts[reasons[i]] != counts[reasons[j]] {
+			return counts[reasons[i]] > counts[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+
+	parts := make([]string, len(reasons))
+	for i, reason := range reasons {
+		parts[i] = fmt.Sprintf("%d %s", counts[reason], reason)
+	}
+	return fmt.Sprintf("0/%d nodes are available: %s", total, strings.Join(parts, ", "))
+}
@@ -0,0 +1,306 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+const gib = 1 << 30
+
+func newTestScheduler(t *testing.T, scorers []WeightedScorer) (*Scheduler, store.Store) {
+	t.Helper()
+	s := store.NewMemoryStore()
+	return New(&Config{
+		Store:    s,
+		Logger:   zerolog.Nop(),
+		Interval: time.Hour,
+		Scorers:  scorers,
+	}), s
+}
+
+func testNode(name string, cpu, mem int64, labels map[string]string) *model.Node {
+	return &model.Node{
+		ObjectMeta: model.ObjectMeta{Name: name, Labels: labels},
+		Spec:       model.NodeSpec{Capacity: model.Resources{CPUMillis: cpu, MemoryBytes: mem}},
+		Status:     model.NodeStatus{Phase: model.NodeReady},
+	}
+}
+
+func testContainer(name string, cpu, mem int64) *model.Container {
+	c := &model.Container{
+		ObjectMeta: model.ObjectMeta{Name: name, UID: name + "-uid"},
+		Spec: model.ContainerSpec{
+			Image:     "app",
+			Resources: model.Resources{CPUMillis: cpu, MemoryBytes: mem},
+		},
+	}
+	c.SetDefaults()
+	return c
+}
+
+func putNode(t *testing.T, s store.Store, n *model.Node) {
+	t.Helper()
+	require.NoError(t, store.PutJSON(s, model.NodesBucket, n.Name, n))
+}
+
+func putContainer(t *testing.T, s store.Store, c *model.Container) {
+	t.Helper()
+	require.NoError(t, store.PutJSON(s, model.ContainersBucket, c.Name, c))
+}
+
+func getContainer(t *testing.T, s store.Store, name string) *model.Container {
+	t.Helper()
+	c, err := store.GetJSON[model.Container](s, model.ContainersBucket, name)
+	require.NoError(t, err)
+	return c
+}
+
+func TestSchedule_Filters(t *testing.T) {
+	sched, _ := newTestScheduler(t, nil)
+
+	notReady := testNode("not-ready", 4000, 8*gib, nil)
+	notReady.Status.Phase = model.NodeNotReady
+	cordoned := testNode("cordoned", 4000, 8*gib, map[string]string{"zone": "a"})
+	cordoned.Spec.Unschedulable = true
+	small := testNode("small", 500, 8*gib, nil)
+	lowMem := testNode("low-mem", 4000, gib/2, nil)
+	wrongZone := testNode("wrong-zone", 4000, 8*gib, map[string]string{"zone": "b"})
+	busyPort := NewNodeInfo(testNode("busy-port", 4000, 8*gib, map[string]string{"zone": "a"}))
+	other := testContainer("other", 0, 0)
+	other.Spec.Ports = []model.ContainerPort{{ContainerPort: 80, HostPort: 8080, Protocol: "TCP"}}
+	busyPort.Add(other)
+	good := testNode("good", 4000, 8*gib, map[string]string{"zone": "a"})
+
+	c := testContainer("web", 1000, gib)
+	c.Spec.NodeSelector = map[string]string{"zone": "a"}
+	c.Spec.Ports = []model.ContainerPort{{ContainerPort: 80, HostPort: 8080, Protocol: "TCP"}}
+
+	nodes := []*NodeInfo{
+		NewNodeInfo(notReady), NewNodeInfo(cordoned), NewNodeInfo(small), NewNodeInfo(lowMem),
+		NewNodeInfo(wrongZone), busyPort, NewNodeInfo(good),
+	}
+	// Give the resource-constrained nodes the right zone so that they are
+	// rejected for resources rather than the selector.
+	small.Labels = map[string]string{"zone": "a"}
+	lowMem.Labels = map[string]string{"zone": "a"}
+
+	d := sched.Schedule(c, nodes)
+
+	assert.Equal(t, "good", d.NodeName)
+	assert.Equal(t, ErrNodeNotReady.Error(), d.Filtered["not-ready"])
+	assert.Equal(t, ErrNodeUnschedulable.Error(), d.Filtered["cordoned"])
+	assert.Equal(t, ErrInsufficientCPU.Error(), d.Filtered["small"])
+	assert.Equal(t, ErrInsufficientMemory.Error(), d.Filtered["low-mem"])
+	assert.Equal(t, ErrSelectorMismatch.Error(), d.Filtered["wrong-zone"])
+	assert.Contains(t, d.Filtered["busy-port"], ErrPortConflict.Error())
+	assert.Len(t, d.Scores, 1)
+}
+
+func TestSchedule_Unschedulable(t *testing.T) {
+	sched, _ := newTestScheduler(t, nil)
+
+	nodes := []*NodeInfo{
+		NewNodeInfo(testNode("a", 500, 8*gib, nil)),
+		NewNodeInfo(testNode("b", 500, 8*gib, nil)),
+		NewNodeInfo(testNode("c", 4000, gib/2, nil)),
+	}
+
+	d := sched.Schedule(testContainer("web", 1000, gib), nodes)
+
+	assert.Empty(t, d.NodeName)
+	assert.Equal(t, "0/3 nodes are available: 2 insufficient cpu, 1 insufficient memory", d.Message)
+}
+
+func TestSchedule_NoNodes(t *testing.T) {
+	sched, _ := newTestScheduler(t, nil)
+
+	d := sched.Schedule(testContainer("web", 0, 0), nil)
+
+	assert.Empty(t, d.NodeName)
+	assert.Contains(t, d.Message, "no nodes registered")
+}
+
+func TestSchedule_Strategies(t *testing.T) {
+	busy := NewNodeInfo(testNode("busy", 4000, 8*gib, nil))
+	busy.Add(testContainer("existing", 2000, 4*gib))
+	idle := NewNodeInfo(testNode("idle", 4000, 8*gib, nil))
+	nodes := []*NodeInfo{busy, idle}
+
+	tests := []struct {
+		strategy string
+		want     string
+	}{
+		{StrategyLeastAllocated, "idle"},
+		{StrategyMostAllocated, "busy"},
+		{StrategySpread, "idle"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			scorers, err := ScorersFor(tt.strategy)
+			require.NoError(t, err)
+			sched, _ := newTestScheduler(t, scorers)
+
+			d := sched.Schedule(testContainer("web", 500, gib), nodes)
+			assert.Equal(t, tt.want, d.NodeName)
+		})
+	}
+
+	_, err := ScorersFor("random")
+	assert.Error(t, err)
+}
+
+func TestSpread_SeparatesReplicas(t *testing.T) {
+	a := NewNodeInfo(testNode("a", 4000, 8*gib, nil))
+	b := NewNodeInfo(testNode("b", 4000, 8*gib, nil))
+
+	replica := testContainer("web-0", 0, 0)
+	replica.Labels = map[string]string{"app": "web"}
+	a.Add(replica)
+	unrelated := testContainer("db-0", 0, 0)
+	unrelated.Labels = map[string]string{"app": "db"}
+	b.Add(unrelated)
+
+	c := testContainer("web-1", 0, 0)
+	c.Labels = map[string]string{"app": "web"}
+
+	assert.Less(t, Spread{}.Score(c, a), Spread{}.Score(c, b))
+}
+
+// rejectAll is a custom filter plugin used to check extensibility.
+type rejectAll struct{}
+
+func (rejectAll) Name() string { return "RejectAll" }
+
+func (rejectAll) Filter(*model.Container, *NodeInfo) error {
+	return assert.AnError
+}
+
+func TestSchedule_CustomFilter(t *testing.T) {
+	s := store.NewMemoryStore()
+	sched := New(&Config{
+		Store:   s,
+		Logger:  zerolog.Nop(),
+		Filters: []FilterPlugin{rejectAll{}},
+	})
+
+	d := sched.Schedule(testContainer("web", 0, 0), []*NodeInfo{NewNodeInfo(testNode("a", 1000, gib, nil))})
+
+	assert.Empty(t, d.NodeName)
+	assert.Equal(t, assert.AnError.Error(), d.Filtered["a"])
+}
+
+func TestSchedulePending_BindsAndRecords(t *testing.T) {
+	sched, s := newTestScheduler(t, nil)
+	putNode(t, s, testNode("node-1", 1000, 2*gib, nil))
+
+	putContainer(t, s, testContainer("first", 600, gib))
+	putContainer(t, s, testContainer("second", 600, gib))
+
+	require.NoError(t, sched.SchedulePending(context.Background()))
+
+	first := getContainer(t, s, "first")
+	assert.Equal(t, "node-1", first.Spec.NodeName)
+	require.NotNil(t, first.Status.Scheduling)
+	assert.Equal(t, "node-1", first.Status.Scheduling.NodeName)
+	assert.Contains(t, first.Status.Scheduling.Scores, "node-1")
+
+	// The first placement consumed most of the node's CPU.
+	second := getContainer(t, s, "second")
+	assert.Empty(t, second.Spec.NodeName)
+	assert.Equal(t, model.ReasonUnschedulable, second.Status.Reason)
+	require.NotNil(t, second.Status.Scheduling)
+	assert.Equal(t, "insufficient cpu", second.Status.Scheduling.FilteredNodes["node-1"])
+	assert.Equal(t, "0/1 nodes are available: 1 insufficient cpu", second.Status.Message)
+}
+
+func TestSchedulePending_BecomesSchedulable(t *testing.T) {
+	sched, s := newTestScheduler(t, nil)
+	putContainer(t, s, testContainer("web", 100, gib))
+
+	require.NoError(t, sched.SchedulePending(context.Background()))
+	require.NoError(t, sched.SchedulePending(context.Background()))
+	assert.Equal(t, model.ReasonUnschedulable, getContainer(t, s, "web").Status.Reason)
+
+	putNode(t, s, testNode("node-1", 1000, 2*gib, nil))
+	require.NoError(t, sched.SchedulePending(context.Background()))
+
+	c := getContainer(t, s, "web")
+	assert.Equal(t, "node-1", c.Spec.NodeName)
+	assert.Empty(t, c.Status.Reason)
+
+	// Both failed attempts count on one event.
+	events, err := store.ListJSON[model.Event](s, model.EventsBucket, "")
+	require.NoError(t, err)
+	counts := map[string]int{}
+	for _, e := range events {
+		assert.Equal(t, "web", e.InvolvedObject.Name)
+		counts[e.Reason] += e.Count
+	}
+	assert.Equal(t, map[string]int{model.EventFailedScheduling: 2, model.EventScheduled: 1}, counts)
+}
+
+func TestSchedulePending_SkipsTerminalAndBound(t *testing.T) {
+	sched, s := newTestScheduler(t, nil)
+	putNode(t, s, testNode("node-1", 1000, gib, nil))
+
+	done := testContainer("done", 1000, gib)
+	done.Status.Phase = model.PhaseSucceeded
+	putContainer(t, s, done)
+
+	bound := testContainer("bound", 0, 0)
+	bound.Spec.NodeName = "node-2"
+	putContainer(t, s, bound)
+
+	require.NoError(t, sched.SchedulePending(context.Background()))
+
+	assert.Empty(t, getContainer(t, s, "done").Spec.NodeName)
+	assert.Nil(t, getContainer(t, s, "done").Status.Scheduling)
+	assert.Equal(t, "node-2", getContainer(t, s, "bound").Spec.NodeName)
+}
+
+func TestSchedulePending_CoLocatesVolumes(t *testing.T) {
+	sched, s := newTestScheduler(t, nil)
+	putNode(t, s, testNode("node-1", 1000, gib, nil))
+	putNode(t, s, testNode("node-2", 4000, 4*gib, nil))
+	require.NoError(t, store.PutJSON(s, model.VolumesBucket, "data", &model.Volume{
+		ObjectMeta: model.ObjectMeta{Name: "data"},
+	}))
+	require.NoError(t, store.PutJSON(s, model.VolumesBucket, "logs", &model.Volume{
+		ObjectMeta: model.ObjectMeta{Name: "logs"},
+		Spec:       model.VolumeSpec{NodeName: "node-1"},
+	}))
+
+	withVolume := func(name, volume string) *model.Container {
+		c := testContainer(name, 100, 0)
+		c.Spec.VolumeMounts = []model.VolumeMount{{Name: volume, MountPath: "/data"}}
+		return c
+	}
+	putContainer(t, s, withVolume("a-writer", "data"))
+	putContainer(t, s, withVolume("b-reader", "data"))
+	putContainer(t, s, withVolume("logger", "logs"))
+	putContainer(t, s, withVolume("orphan", "missing"))
+
+	require.NoError(t, sched.SchedulePending(context.Background()))
+
+	// The unbound volume follows the first container to the emptier node.
+	assert.Equal(t, "node-2", getContainer(t, s, "a-writer").Spec.NodeName)
+	assert.Equal(t, "node-2", getContainer(t, s, "b-reader").Spec.NodeName)
+	v, err := store.GetJSON[model.Volume](s, model.VolumesBucket, "data")
+	require.NoError(t, err)
+	assert.Equal(t, "node-2", v.Status.NodeName)
+
+	assert.Equal(t, "node-1", getContainer(t, s, "logger").Spec.NodeName)
+
+	orphan := getContainer(t, s, "orphan")
+	assert.Empty(t, orphan.Spec.NodeName)
+	assert.Equal(t, "0/2 nodes are available: 2 volume not found: missing", orphan.Status.Message)
+}
@@ -0,0 +1,162 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// NodeInfo is a node together with the containers already placed on it.
+type NodeInfo struct {
+	Node *model.Node
+
+	// Containers are the non-terminal containers bound to the node.
+	Containers []*model.Container
+
+	// Requested is the sum of the containers' resource requests.
+	Requested model.Resources
+
+	// UsedPorts holds host ports in use, keyed by "port/protocol".
+	UsedPorts map[string]bool
+
+	// Volumes maps the key of each volume to the node holding it, empty
+	// while the volume is unbound. It is shared by all nodes of a snapshot; volumes
+	// are not checked when it is nil.
+	Volumes map[string]string
+}
+
+// NewNodeInfo creates a NodeInfo with no containers.
+func NewNodeInfo(n *model.Node) *NodeInfo {
+	return &NodeInfo{
+		Node:      n,
+		UsedPorts: make(map[string]bool),
+	}
+}
+
+// Add accounts for a container placed on the node.
+func (n *NodeInfo) Add(c *model.Container) {
+	n.Containers = append(n.Containers, c)
+	n.Requested.CPUMillis += c.Spec.Resources.CPUMillis
+	n.Requested.MemoryBytes += c.Spec.Resources.MemoryBytes
+	for _, p := range c.Spec.Ports {
+		if p.HostPort != 0 {
+			n.UsedPorts[hostPortKey(p)] = true
+		}
+	}
+}
+
+// Remove undoes Add for a container on the node.
+func (n *NodeInfo) Remove(c *model.Container) {
+	kept := n.Containers[:0:0]
+	for _, other := range n.Containers {
+		if other.Key() != c.Key() {
+			kept = append(kept, other)
+		}
+	}
+	if len(kept) == len(n.Containers) {
+		return
+	}
+
+	n.Containers = nil
+	n.Requested = model.Resources{}
+	n.UsedPorts = make(map[string]bool)
+	for _, other := range kept {
+		n.Add(other)
+	}
+}
+
+// clone returns a copy of the node info that can be changed independently.
+func (n *NodeInfo) clone() *NodeInfo {
+	out := NewNodeInfo(n.Node)
+	out.Volumes = n.Volumes
+	for _, c := range n.Containers {
+		out.Add(c)
+	}
+	return out
+}
+
+// Free returns the capacity not yet requested by containers.
+func (n *NodeInfo) Free() model.Resources {
+	return model.Resources{
+		CPUMillis:   n.Node.Spec.Capacity.CPUMillis - n.Requested.CPUMillis,
+		MemoryBytes: n.Node.Spec.Capacity.MemoryBytes - n.Requested.MemoryBytes,
+	}
+}
+
+func hostPortKey(p model.ContainerPort) string {
+	return strconv.Itoa(p.HostPort) + "/" + p.Protocol
+}
+
+// snapshot is a consistent view of nodes and containers for one pass.
+// Pending containers are in scheduling order.
+type snapshot struct {
+	nodes      []*NodeInfo
+	pending    []*model.Container
+	volumes    map[string]string
+	priorities *priorities
+
+	// groups maps the key of each group to its non-terminal members.
+	groups map[string][]*model.Container
+}
+
+func (s *Scheduler) snapshot() (*snapshot, error) {
+	nodes, err := store.ListJSON[model.Node](s.store, model.NodesBucket, "")
+	if err != nil {
+		return nil, fmt.Errorf("listing nodes: %w", err)
+	}
+	containers, err := store.ListJSON[model.Container](s.store, model.ContainersBucket, "")
+	if err != nil {
+		return nil, fmt.Errorf("listing containers: %w", err)
+	}
+
+	volumes, err := store.ListJSON[model.Volume](s.store, model.VolumesBucket, "")
+	if err != nil {
+		return nil, fmt.Errorf("listing volumes: %w", err)
+	}
+	classes, err := store.ListJSON[model.PriorityClass](s.store, model.PriorityClassesBucket, "")
+	if err != nil {
+		return nil, fmt.Errorf("listing priority classes: %w", err)
+	}
+
+	snap := &snapshot{
+		nodes:      make([]*NodeInfo, 0, len(nodes)),
+		volumes:    make(map[string]string, len(volumes)),
+		priorities: newPriorities(classes),
+		groups:     make(map[string][]*model.Container),
+	}
+	for _, v := range volumes {
+		snap.volumes[v.Key()] = v.BoundNode()
+	}
+	byName := make(map[string]*NodeInfo, len(nodes))
+	for _, n := range nodes {
+		info := NewNodeInfo(n)
+		info.Volumes = snap.volumes
+		snap.nodes = append(snap.nodes, info)
+		byName[n.Name] = info
+	}
+
+	for _, c := range containers {
+		if c.Status.IsTerminal() {
+			continue
+		}
+		if group, ok := c.Labels[model.LabelGroup]; ok {
+			key := model.Key(c.Namespace, group)
+			snap.groups[key] = append(snap.groups[key], c)
+		}
+		if c.Spec.NodeName == "" {
+			// A terminating container is not worth placing.
+			if !c.IsTerminating() {
+				snap.pending = append(snap.pending, c)
+			}
+			continue
+		}
+		if info, ok := byName[c.Spec.NodeName]; ok {
+			info.Add(c)
+		}
+	}
+	snap.priorities.sort(snap.pending)
+
+	return snap, nil
+}
@@ -0,0 +1,70 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// Volume rejection reasons.
+var (
+	ErrVolumeNotFound  = errors.New("volume not found")
+	ErrVolumeElsewhere = errors.New("volume is bound to another node")
+)
+
+// VolumeBinding rejects nodes other than the one holding a volume the
+// container mounts. Volumes not bound yet fit any node.
+type VolumeBinding struct{}
+
+// Name implements FilterPlugin.
+func (VolumeBinding) Name() string { return "VolumeBinding" }
+
+// Filter implements FilterPlugin.
+func (VolumeBinding) Filter(c *model.Container, n *NodeInfo) error {
+	if n.Volumes == nil {
+		return nil
+	}
+	for _, vm := range c.Spec.VolumeMounts {
+		node, ok := n.Volumes[model.Key(c.Namespace, vm.Name)]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrVolumeNotFound, vm.Name)
+		}
+		if node != "" && node != n.Node.Name {
+			return ErrVolumeElsewhere
+		}
+	}
+	return nil
+}
+
+// bindVolumes binds the unbound volumes a scheduled container mounts to its
+// node, so that later containers mounting them follow it there.
+func (s *Scheduler) bindVolumes(c *model.Container, volumes map[string]string) error {
+	var errs []error
+	for _, vm := range c.Spec.VolumeMounts {
+		key := model.Key(c.Namespace, vm.Name)
+		if volumes[key] != "" {
+			continue
+		}
+		_, err := store.UpdateJSON(s.store, model.VolumesBucket, key, func(v *model.Volume) error {
+			if v.BoundNode() != "" {
+				return errStale
+			}
+			now := time.Now().UTC()
+			v.Status.NodeName = c.Spec.NodeName
+			v.Status.BoundAt = &now
+			return nil
+		})
+		if errors.Is(err, errStale) || errors.Is(err, store.ErrNotFound) || errors.Is(err, store.ErrBucketNotFound) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("binding volume %s: %w", vm.Name, err))
+			continue
+		}
+		volumes[key] = c.Spec.NodeName
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,127 @@
+// Package secret encrypts secret data at rest. Secrets are stored with their
+// values sealed under a single AES-256-GCM key; the API seals them on write
+// and the agent opens them when it starts a container.
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// KeySize is the length of an encryption key in bytes.
+const KeySize = 32
+
+// Cipher seals and opens secret data.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher creates a cipher from a KeySize-byte key.
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("secret key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Seal encrypts s.Data into s.Sealed and clears s.Data. The ciphertext is
+// bound to the secret's namespace and name, so it cannot be copied to
+// another secret.
+func (c *Cipher) Seal(s *model.Secret) error {
+	plain, err := json.Marshal(s.Data)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("generating nonce: %w", err)
+	}
+	s.Sealed = c.aead.Seal(nonce, nonce, plain, []byte(s.Key()))
+	s.Data = nil
+	return nil
+}
+
+// Open decrypts s.Sealed into s.Data.
+func (c *Cipher) Open(s *model.Secret) error {
+	n := c.aead.NonceSize()
+	if len(s.Sealed) < n {
+		return fmt.Errorf("secret %s: sealed data is truncated", s.Name)
+	}
+	plain, err := c.aead.Open(nil, s.Sealed[:n], s.Sealed[n:], []byte(s.Key()))
+	if err != nil {
+		return fmt.Errorf("secret %s: decrypting: %w", s.Name, err)
+	}
+	data := make(map[string]string)
+	if err := json.Unmarshal(plain, &data); err != nil {
+		return fmt.Errorf("secret %s: decoding: %w", s.Name, err)
+	}
+	s.Data = data
+	return nil
+}
+
+// Get reads a secret and opens it.
+func (c *Cipher) Get(st store.Store, key string) (*model.Secret, error) {
+	s, err := store.GetJSON[model.Secret](st, model.SecretsBucket, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Open(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ParseKey decodes a base64-encoded key.
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decoding secret key: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("secret key must be %d bytes, got %d", KeySize, len(key))
+	}
+	return key, nil
+}
+
+// LoadOrCreateKey reads the base64-encoded key at path, generating and
+// saving a new one if the file does not exist.
+func LoadOrCreateKey(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err == nil {
+		return ParseKey(string(b))
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("reading secret key: %w", err)
+	}
+
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generating secret key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("creating secret key directory: %w", err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(key)
+	if err := os.WriteFile(path, []byte(encoded), 0o600); err != nil {
+		return nil, fmt.Errorf("writing secret key: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,69 @@
+package secret
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+func newTestCipher(t *testing.T) *Cipher {
+	t.Helper()
+	c, err := NewCipher(bytes.Repeat([]byte{7}, KeySize))
+	require.NoError(t, err)
+	return c
+}
+
+func TestCipher_SealAndOpen(t *testing.T) {
+	c := newTestCipher(t)
+	s := &model.Secret{ObjectMeta: model.ObjectMeta{Name: "db"}, Data: map[string]string{"password": "hunter2"}}
+
+	require.NoError(t, c.Seal(s))
+	assert.Nil(t, s.Data)
+	assert.NotContains(t, string(s.Sealed), "hunter2")
+
+	st := store.NewMemoryStore()
+	require.NoError(t, store.PutJSON(st, model.SecretsBucket, "db", s))
+	got, err := c.Get(st, "db")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"password": "hunter2"}, got.Data)
+
+	// Sealed data is bound to the secret's name and key.
+	moved := &model.Secret{ObjectMeta: model.ObjectMeta{Name: "other"}, Sealed: s.Sealed}
+	assert.Error(t, c.Open(moved))
+
+	other, err := NewCipher(bytes.Repeat([]byte{8}, KeySize))
+	require.NoError(t, err)
+	assert.Error(t, other.Open(&model.Secret{ObjectMeta: s.ObjectMeta, Sealed: s.Sealed}))
+}
+
+func TestNewCipher_KeySize(t *testing.T) {
+	_, err := NewCipher([]byte("short"))
+	assert.Error(t, err)
+}
+
+func TestLoadOrCreateKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "secret.key")
+
+	key, err := LoadOrCreateKey(path)
+	require.NoError(t, err)
+	assert.Len(t, key, KeySize)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	again, err := LoadOrCreateKey(path)
+	require.NoError(t, err)
+	assert.Equal(t, key, again)
+
+	require.NoError(t, os.WriteFile(path, []byte("not base64!"), 0o600))
+	_, err = LoadOrCreateKey(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,471 @@
+// Package statefulset implements the stateful set controller. Each pass it
+// brings up a stateful set's replicas in ordinal order, one at a time and
+// each only once all lower ordinals are ready, removes surplus replicas
+// from the highest ordinal down, and replaces outdated replicas from the
+// highest ordinal down to the update partition. Replica i keeps its name
+// and volumes across replacements, and is placed on the node its volumes
+// are bound to. Replicas carry model.FinalizerRuntimeCleanup, so a deleted
+// replica stays until its runtime container is gone, and no step is taken
+// while one is terminating.
+package statefulset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/github-builder/container-orchestrator/internal/events"
+	"github.com/github-builder/container-orchestrator/internal/eviction"
+	"github.com/github-builder/container-orchestrator/internal/gc"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+// errStale is returned when a stateful set changed while being reconciled.
+var errStale = errors.New("stateful set changed during reconciliation")
+
+// Config holds dependencies for the stateful set controller.
+type Config struct {
+	Store  store.Store
+	Logger zerolog.Logger
+
+	// Interval is how often stateful sets are reconciled.
+	Interval time.Duration
+
+	// Evictor removes ready replicas when scaling down and updating,
+	// subject to disruption budgets. Defaults to an evictor on Store.
+	Evictor *eviction.Evictor
+
+	// Events records the replicas and volumes created and the replicas
+	// deleted. Defaults to a recorder on Store.
+	Events *events.Recorder
+}
+
+// Controller reconciles stateful sets with their replicas and volumes.
+type Controller struct {
+	store    store.Store
+	logger   zerolog.Logger
+	interval time.Duration
+	events   *events.Recorder
+	evictor  *eviction.Evictor
+}
+
+// NewController creates a stateful set controller.
+func NewController(cfg *Config) *Controller {
+	recorder := cfg.Events
+	if recorder == nil {
+		recorder = events.NewRecorder(&events.Config{Store: cfg.Store, Logger: cfg.Logger})
+	}
+	evictor := cfg.Evictor
+	if evictor == nil {
+		evictor = eviction.New(&eviction.Config{Store: cfg.Store, Logger: cfg.Logger, Events: recorder})
+	}
+
+	return &Controller{
+		store:    cfg.Store,
+		logger:   cfg.Logger,
+		interval: cfg.Interval,
+		events:   recorder.WithSource("statefulsets"),
+		evictor:  evictor,
+	}
+}
+
+// Run reconciles stateful sets every interval until ctx is canceled.
+func (c *Controller) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Sync(ctx); err != nil {
+			c.logger.Error().Err(err).Msg("stateful set reconciliation failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync reconciles every stateful set once. Replicas of deleted stateful
+// sets are left to the garbage collector; their volumes have no owner and
+// are kept, since they hold data that outlives the set. Stateful sets
+// being deleted are not reconciled.
+func (c *Controller) Sync(ctx context.Context) error {
+	sets, err := store.ListJSON[model.StatefulSet](c.store, model.StatefulSetsBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing stateful sets: %w", err)
+	}
+	containers, err := store.ListJSON[model.Container](c.store, model.ContainersBucket, "")
+	if err != nil {
+		return fmt.Errorf("listing containers: %w", err)
+	}
+
+	replicas := make(map[string][]*model.Container)
+	for _, ctr := range containers {
+		if owner, ok := ctr.Labels[model.LabelStatefulSet]; ok {
+			key := model.Key(ctr.Namespace, owner)
+			replicas[key] = append(replicas[key], ctr)
+		}
+	}
+
+	var errs []error
+	for _, set := range sets {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if set.IsTerminating() {
+			continue
+		}
+		owned, err := gc.Claim(c.store, model.ContainersBucket, model.StatefulSetsBucket, &set.ObjectMeta, replicas[set.Key()])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("stateful set %s: %w", set.Key(), err))
+			continue
+		}
+		terminating, err := c.terminating(set, replicas[set.Key()])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("stateful set %s: %w", set.Key(), err))
+			continue
+		}
+		if err := c.reconcile(set, owned, terminating); err != nil && !errors.Is(err, errStale) {
+			errs = append(errs, fmt.Errorf("stateful set %s: %w", set.Key(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// terminating reports whether a replica of set is being deleted. Its
+// runtime container may still run, so the next step waits until the agent
+// has removed it. Replicas on no node, or on a node that has been removed,
+// have no agent to do that, so their finalizer is released here.
+func (c *Controller) terminating(set *model.StatefulSet, candidates []*model.Container) (bool, error) {
+	found := false
+	var errs []error
+	for _, r := range candidates {
+		if !r.IsTerminating() || !r.IsControlledBy(&set.ObjectMeta) {
+			continue
+		}
+		found = true
+		if !r.HasFinalizer(model.FinalizerRuntimeCleanup) {
+			continue
+		}
+		if r.Spec.NodeName != "" {
+			_, err := store.GetJSON[model.Node](c.store, model.NodesBucket, r.Spec.NodeName)
+			if err == nil {
+				continue
+			}
+			if !store.IsNotFound(err) {
+				errs = append(errs, fmt.Errorf("reading node %s: %w", r.Spec.NodeName, err))
+				continue
+			}
+		}
+		if err := gc.RemoveFinalizer(c.store, model.ContainersBucket, &r.ObjectMeta, model.FinalizerRuntimeCleanup); err != nil {
+			errs = append(errs, fmt.Errorf("releasing replica %s: %w", r.Key(), err))
+		}
+	}
+	return found, errors.Join(errs...)
+}
+
+// reconcile takes at most one ordered step for a stateful set: creating the
+// lowest missing replica, removing the highest surplus one, or replacing the
+// highest outdated one. Every step waits until all replicas below it are
+// ready and no replica is terminating.
+func (c *Controller) reconcile(set *model.StatefulSet, replicas []*model.Container, terminating bool) error {
+	hash := set.Spec.Template.Hash()
+	status := set.Status
+	if status.CurrentRevision == "" {
+		status.CurrentRevision = hash
+		status.CurrentTemplate = &set.Spec.Template
+	}
+	status.UpdateRevision = hash
+
+	var errs []error
+	byOrdinal := make(map[int]*model.Container, len(replicas))
+	for _, r := range replicas {
+		i, ok := ordinal(set, r)
+		switch {
+		case !ok:
+			// Not a name this set would create; remove it.
+			if _, err := c.removeReplica(r, "not a replica of the stateful set"); err != nil {
+				errs = append(errs, err)
+			}
+		case r.Status.IsTerminal():
+			// Replicas always restart, so a terminal one failed for good;
+			// remove it and recreate it under the same name.
+			if err := c.deleteReplica(r); err != nil {
+				errs = append(errs, err)
+			}
+		default:
+			byOrdinal[i] = r
+		}
+	}
+
+	if !terminating {
+		if err := c.step(set, &status, byOrdinal); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	c.updateStatus(set, &status, byOrdinal)
+	if err := c.writeStatus(set, hash, &status); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// step makes the next ordered change and updates byOrdinal to match.
+func (c *Controller) step(set *model.StatefulSet, s *model.StatefulSetStatus, byOrdinal map[int]*model.Container) error {
+	for i := range set.Spec.Replicas {
+		r, ok := byOrdinal[i]
+		if !ok {
+			r, err := c.createReplica(set, s, i)
+			if errors.Is(err, store.ErrAlreadyExists) {
+				// The previous replica i is still being removed.
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			byOrdinal[i] = r
+			return nil
+		}
+		if !r.Status.IsReady() {
+			return nil
+		}
+	}
+
+	// Surplus replicas go from the highest ordinal down.
+	ordinals := make([]int, 0, len(byOrdinal))
+	for i := range byOrdinal {
+		ordinals = append(ordinals, i)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ordinals)))
+	if len(ordinals) > 0 && ordinals[0] >= set.Spec.Replicas {
+		gone, err := c.removeReplica(byOrdinal[ordinals[0]], "scaled down")
+		if gone {
+			delete(byOrdinal, ordinals[0])
+		}
+		return err
+	}
+
+	if set.Spec.UpdateStrategy.Type != model.StatefulSetRollingUpdate {
+		return nil
+	}
+	for _, i := range ordinals {
+		if i < set.Spec.UpdateStrategy.Partition {
+			break
+		}
+		if r := byOrdinal[i]; r.Labels[model.LabelTemplateHash] != s.UpdateRevision {
+			// The replica is recreated from the new template once the
+			// agent has removed this one's runtime container.
+			gone, err := c.removeReplica(r, "replaced by rolling update")
+			if gone {
+				delete(byOrdinal, i)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// updateStatus counts replicas by revision and promotes the update revision
+// to current once every replica runs it and is ready.
+func (c *Controller) updateStatus(set *model.StatefulSet, s *model.StatefulSetStatus, byOrdinal map[int]*model.Container) {
+	s.Replicas = len(byOrdinal)
+	s.ReadyReplicas = 0
+	s.CurrentReplicas = 0
+	s.UpdatedReplicas = 0
+	for _, r := range byOrdinal {
+		if r.Status.IsReady() {
+			s.ReadyReplicas++
+		}
+		hash := r.Labels[model.LabelTemplateHash]
+		if hash == s.CurrentRevision {
+			s.CurrentReplicas++
+		}
+		if hash == s.UpdateRevision {
+			s.UpdatedReplicas++
+		}
+	}
+
+	if s.CurrentRevision != s.UpdateRevision && s.Replicas == set.Spec.Replicas &&
+		s.UpdatedReplicas == s.Replicas && s.ReadyReplicas == s.Replicas {
+		c.logger.Info().Str("statefulset", set.Name).Str("revision", s.UpdateRevision).Msg("update complete")
+		s.CurrentRevision = s.UpdateRevision
+		s.CurrentTemplate = &set.Spec.Template
+		s.CurrentReplicas = s.UpdatedReplicas
+	}
+}
+
+// ordinal returns the ordinal of a replica, provided its name matches it.
+func ordinal(set *model.StatefulSet, r *model.Container) (int, bool) {
+	i, err := strconv.Atoi(r.Labels[model.LabelOrdinal])
+	if err != nil || i < 0 || set.ReplicaName(i) != r.Name {
+		return 0, false
+	}
+	return i, true
+}
+
+// revisionFor returns the template and hash replica i is created from.
+// Replicas below the partition of a rolling update stay on the current
+// revision; everything else gets the update revision.
+func revisionFor(set *model.StatefulSet, s *model.StatefulSetStatus, i int) (*model.ContainerTemplate, string) {
+	if set.Spec.UpdateStrategy.Type == model.StatefulSetRollingUpdate &&
+		i < set.Spec.UpdateStrategy.Partition && s.CurrentTemplate != nil {
+		return s.CurrentTemplate, s.CurrentRevision
+	}
+	return &set.Spec.Template, s.UpdateRevision
+}
+
+// createReplica stores replica i, creating its volumes first. The replica
+// is pinned to the node its volumes are bound to, if any.
+func (c *Controller) createReplica(set *model.StatefulSet, s *model.StatefulSetStatus, i int) (*model.Container, error) {
+	tmpl, hash := revisionFor(set, s, i)
+	spec, err := tmpl.Spec.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, claim := range set.Spec.VolumeClaimTemplates {
+		v, err := c.ensureVolume(set, &claim, i)
+		if err != nil {
+			return nil, err
+		}
+		if node := v.BoundNode(); node != "" {
+			if spec.NodeName != "" && spec.NodeName != node {
+				return nil, fmt.Errorf("volumes of replica %d are bound to different nodes %s and %s",
+					i, spec.NodeName, node)
+			}
+			spec.NodeName = node
+		}
+		spec.VolumeMounts = append(spec.VolumeMounts, model.VolumeMount{Name: v.Name, MountPath: claim.MountPath})
+	}
+
+	labels := make(map[string]string, len(tmpl.Labels)+3)
+	for k, v := range tmpl.Labels {
+		labels[k] = v
+	}
+	labels[model.LabelStatefulSet] = set.Name
+	labels[model.LabelOrdinal] = strconv.Itoa(i)
+	labels[model.LabelTemplateHash] = hash
+
+	r := &model.Container{
+		ObjectMeta: model.ObjectMeta{
+			Name:            set.ReplicaName(i),
+			Namespace:       set.Namespace,
+			Labels:          labels,
+			OwnerReferences: []model.OwnerReference{model.NewControllerRef(model.StatefulSetsBucket, &set.ObjectMeta)},
+			Finalizers:      []string{model.FinalizerRuntimeCleanup},
+		},
+		Spec: *spec,
+	}
+	r.SetDefaults()
+	if err := r.Initialize(); err != nil {
+		return nil, err
+	}
+	if err := store.CreateJSON(c.store, model.ContainersBucket, r.Key(), r); err != nil {
+		return nil, fmt.Errorf("creating replica %s: %w", r.Key(), err)
+	}
+	c.logger.Info().Str("statefulset", set.Name).Str("container", r.Name).Msg("replica created")
+	c.events.Eventf(model.StatefulSetsBucket, &set.ObjectMeta, model.EventNormal, model.EventCreated, "Created container %s", r.Name)
+	return r, nil
+}
+
+// ensureVolume returns the claim's volume for replica i, creating it if
+// needed.
+func (c *Controller) ensureVolume(set *model.StatefulSet, claim *model.VolumeClaimTemplate, i int) (*model.Volume, error) {
+	name := set.VolumeName(claim.Name, i)
+	key := model.Key(set.Namespace, name)
+	v, err := store.GetJSON[model.Volume](c.store, model.VolumesBucket, key)
+	if err == nil {
+		return v, nil
+	}
+	if !errors.Is(err, store.ErrNotFound) && !errors.Is(err, store.ErrBucketNotFound) {
+		return nil, fmt.Errorf("reading volume %s: %w", name, err)
+	}
+
+	v = &model.Volume{
+		ObjectMeta: model.ObjectMeta{
+			Name:      name,
+			Namespace: set.Namespace,
+			Labels:    map[string]string{model.LabelStatefulSet: set.Name},
+		},
+		Spec: model.VolumeSpec{CapacityBytes: claim.CapacityBytes},
+	}
+	v.SetDefaults()
+	if err := v.Initialize(); err != nil {
+		return nil, err
+	}
+	if err := store.CreateJSON(c.store, model.VolumesBucket, key, v); err != nil {
+		return nil, fmt.Errorf("creating volume %s: %w", name, err)
+	}
+	c.logger.Info().Str("statefulset", set.Name).Str("volume", name).Msg("volume created")
+	c.events.Eventf(model.StatefulSetsBucket, &set.ObjectMeta, model.EventNormal, model.EventCreated, "Created volume %s", name)
+	return v, nil
+}
+
+// deleteReplica deletes a replica; the agent on its node stops the
+// runtime container and then releases the record. Its volumes are kept for
+// its successor.
+func (c *Controller) deleteReplica(r *model.Container) error {
+	if _, err := gc.Delete(c.store, model.ContainersBucket, &r.ObjectMeta); err != nil {
+		return fmt.Errorf("deleting replica %s: %w", r.Key(), err)
+	}
+	c.logger.Info().Str("container", r.Name).Str("statefulset", r.Labels[model.LabelStatefulSet]).
+		Msg("replica deleted")
+	c.events.OwnerEventf(&r.ObjectMeta, model.EventNormal, model.EventDeleted, "Deleted container %s", r.Name)
+	return nil
+}
+
+// removeReplica deletes an unready replica and evicts a ready one for
+// reason. It reports whether the replica is gone; a disruption budget may
+// keep a ready one for now, holding the step back.
+func (c *Controller) removeReplica(r *model.Container, reason string) (bool, error) {
+	if !r.Status.IsReady() {
+		if err := c.deleteReplica(r); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	err := c.evictor.Delete(r, reason)
+	var berr *eviction.BudgetError
+	switch {
+	case err == nil:
+		c.events.OwnerEventf(&r.ObjectMeta, model.EventNormal, model.EventDeleted, "Deleted container %s", r.Name)
+		return true, nil
+	case errors.Is(err, eviction.ErrStale):
+		return true, nil
+	case errors.As(err, &berr):
+		c.logger.Debug().Err(err).Str("statefulset", r.Labels[model.LabelStatefulSet]).Msg("step held by disruption budget")
+		return false, nil
+	default:
+		return false, fmt.Errorf("deleting replica %s: %w", r.Key(), err)
+	}
+}
+
+// writeStatus persists status if it changed, provided the stateful set
+// still has the template it was reconciled against.
+func (c *Controller) writeStatus(set *model.StatefulSet, hash string, status *model.StatefulSetStatus) error {
+	if reflect.DeepEqual(&set.Status, status) {
+		return nil
+	}
+	_, err := gc.Update(c.store, model.StatefulSetsBucket, &set.ObjectMeta, func(cur *model.StatefulSet) error {
+		if cur.Spec.Template.Hash() != hash {
+			return errStale
+		}
+		cur.Status = *status
+		return nil
+	})
+	if errors.Is(err, gc.ErrStale) {
+		return errStale
+	}
+	return err
+}
@@ -0,0 +1,339 @@
+package statefulset
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/gc"
+	"github.com/github-builder/container-orchestrator/internal/model"
+	"github.com/github-builder/container-orchestrator/internal/store"
+)
+
+func newTestController(t *testing.T) (*Controller, store.Store) {
+	t.Helper()
+	s := store.NewMemoryStore()
+	n := &model.Node{ObjectMeta: model.ObjectMeta{Name: "node-1"}}
+	require.NoError(t, store.PutJSON(s, model.NodesBucket, n.Name, n))
+	return NewController(&Config{Store: s, Logger: zerolog.Nop(), Interval: time.Hour}), s
+}
+
+func testStatefulSet(name string, replicas int) *model.StatefulSet {
+	set := &model.StatefulSet{
+		ObjectMeta: model.ObjectMeta{Name: name, UID: name + "-uid"},
+		Spec: model.StatefulSetSpec{
+			Replicas: replicas,
+			Template: model.ContainerTemplate{
+				Labels: map[string]string{"app": name},
+				Spec:   model.ContainerSpec{Image: "postgres:15"},
+			},
+			VolumeClaimTemplates: []model.VolumeClaimTemplate{{Name: "data", MountPath: "/var/lib/db"}},
+		},
+	}
+	set.SetDefaults()
+	return set
+}
+
+func putStatefulSet(t *testing.T, s store.Store, set *model.StatefulSet) {
+	t.Helper()
+	require.NoError(t, set.Validate())
+	require.NoError(t, store.PutJSON(s, model.StatefulSetsBucket, set.Name, set))
+}
+
+func getStatefulSet(t *testing.T, s store.Store, name string) *model.StatefulSet {
+	t.Helper()
+	set, err := store.GetJSON[model.StatefulSet](s, model.StatefulSetsBucket, name)
+	require.NoError(t, err)
+	return set
+}
+
+func updateStatefulSet(t *testing.T, s store.Store, name string, fn func(set *model.StatefulSet)) {
+	t.Helper()
+	_, err := store.UpdateJSON(s, model.StatefulSetsBucket, name, func(set *model.StatefulSet) error {
+		fn(set)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+// replicaImages maps the names of replicas not being deleted to their
+// images.
+func replicaImages(t *testing.T, s store.Store, set string) map[string]string {
+	t.Helper()
+	all, err := store.ListJSON[model.Container](s, model.ContainersBucket, "")
+	require.NoError(t, err)
+	out := make(map[string]string)
+	for _, c := range all {
+		if c.Labels[model.LabelStatefulSet] == set && !c.IsTerminating() {
+			out[c.Name] = c.Spec.Image
+		}
+	}
+	return out
+}
+
+// markAllReady simulates the scheduler and agents bringing every replica
+// up.
+func markAllReady(t *testing.T, s store.Store) {
+	t.Helper()
+	all, err := store.ListJSON[model.Container](s, model.ContainersBucket, "")
+	require.NoError(t, err)
+	for _, c := range all {
+		if c.IsTerminating() {
+			continue
+		}
+		_, err := store.UpdateJSON(s, model.ContainersBucket, c.Name, func(c *model.Container) error {
+			if c.Spec.NodeName == "" {
+				c.Spec.NodeName = "node-1"
+			}
+			c.Status.Phase = model.PhaseRunning
+			c.Status.Ready = true
+			return nil
+		})
+		require.NoError(t, err)
+	}
+}
+
+// removeRuntime simulates the agents removing the runtime containers of
+// deleted replicas and releasing their records.
+func removeRuntime(t *testing.T, s store.Store) {
+	t.Helper()
+	all, err := store.ListJSON[model.Container](s, model.ContainersBucket, "")
+	require.NoError(t, err)
+	for _, c := range all {
+		if c.IsTerminating() {
+			require.NoError(t, gc.RemoveFinalizer(s, model.ContainersBucket, &c.ObjectMeta, model.FinalizerRuntimeCleanup))
+		}
+	}
+}
+
+func syncOnce(t *testing.T, c *Controller) {
+	t.Helper()
+	require.NoError(t, c.Sync(context.Background()))
+}
+
+// converge syncs and marks replicas ready until nothing changes.
+func converge(t *testing.T, c *Controller, s store.Store) {
+	t.Helper()
+	var prev map[string]string
+	for i := 0; ; i++ {
+		require.Less(t, i, 20, "stateful set did not converge")
+		syncOnce(t, c)
+		removeRuntime(t, s)
+		markAllReady(t, s)
+		cur := replicaImages(t, s, "db")
+		if i > 0 && assert.ObjectsAreEqual(prev, cur) {
+			set := getStatefulSet(t, s, "db")
+			if set.Status.ReadyReplicas == set.Spec.Replicas {
+				return
+			}
+		}
+		prev = cur
+	}
+}
+
+func TestSync_OrderedStartup(t *testing.T) {
+	c, s := newTestController(t)
+	putStatefulSet(t, s, testStatefulSet("db", 3))
+
+	// Each replica waits for the one before it to be ready.
+	syncOnce(t, c)
+	syncOnce(t, c)
+	assert.Equal(t, map[string]string{"db-0": "postgres:15"}, replicaImages(t, s, "db"))
+
+	markAllReady(t, s)
+	syncOnce(t, c)
+	assert.Len(t, replicaImages(t, s, "db"), 2)
+
+	converge(t, c, s)
+	assert.Equal(t, map[string]string{"db-0": "postgres:15", "db-1": "postgres:15", "db-2": "postgres:15"},
+		replicaImages(t, s, "db"))
+
+	r, err := store.GetJSON[model.Container](s, model.ContainersBucket, "db-1")
+	require.NoError(t, err)
+	assert.Equal(t, []model.VolumeMount{{Name: "data-db-1", MountPath: "/var/lib/db"}}, r.Spec.VolumeMounts)
+	assert.Equal(t, "1", r.Labels[model.LabelOrdinal])
+	assert.NoError(t, r.Validate())
+
+	volumes, err := store.ListJSON[model.Volume](s, model.VolumesBucket, "")
+	require.NoError(t, err)
+	require.Len(t, volumes, 3)
+	assert.Equal(t, "db", volumes[0].Labels[model.LabelStatefulSet])
+
+	set := getStatefulSet(t, s, "db")
+	assert.Equal(t, 3, set.Status.ReadyReplicas)
+	assert.Equal(t, set.Status.UpdateRevision, set.Status.CurrentRevision)
+}
+
+func TestSync_OrderedScaleDownKeepsVolumes(t *testing.T) {
+	c, s := newTestController(t)
+	putStatefulSet(t, s, testStatefulSet("db", 3))
+	converge(t, c, s)
+
+	updateStatefulSet(t, s, "db", func(set *model.StatefulSet) { set.Spec.Replicas = 1 })
+	syncOnce(t, c)
+	assert.NotContains(t, replicaImages(t, s, "db"), "db-2")
+	assert.Contains(t, replicaImages(t, s, "db"), "db-1")
+
+	// db-1 waits until the runtime container of db-2 is gone.
+	syncOnce(t, c)
+	assert.Contains(t, replicaImages(t, s, "db"), "db-1")
+	_, err := store.GetJSON[model.Container](s, model.ContainersBucket, "db-2")
+	require.NoError(t, err)
+
+	removeRuntime(t, s)
+	syncOnce(t, c)
+	assert.Equal(t, []string{"db-0"}, keys(replicaImages(t, s, "db")))
+
+	volumes, err := store.ListJSON[model.Volume](s, model.VolumesBucket, "")
+	require.NoError(t, err)
+	assert.Len(t, volumes, 3)
+}
+
+func TestSync_ScaleDownRespectsDisruptionBudget(t *testing.T) {
+	c, s := newTestController(t)
+	putStatefulSet(t, s, testStatefulSet("db", 3))
+	converge(t, c, s)
+	b := &model.DisruptionBudget{
+		ObjectMeta: model.ObjectMeta{Name: "db"},
+		Spec: model.DisruptionBudgetSpec{
+			Selector:       model.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+			MaxUnavailable: model.FromInt(0),
+		},
+	}
+	require.NoError(t, store.PutJSON(s, model.DisruptionBudgetsBucket, b.Key(), b))
+
+	updateStatefulSet(t, s, "db", func(set *model.StatefulSet) { set.Spec.Replicas = 2 })
+	syncOnce(t, c)
+	assert.Contains(t, replicaImages(t, s, "db"), "db-2", "budget bypassed")
+
+	require.NoError(t, s.Delete(model.DisruptionBudgetsBucket, b.Key()))
+	syncOnce(t, c)
+	assert.NotContains(t, replicaImages(t, s, "db"), "db-2")
+}
+
+func TestSync_ReplicaFollowsBoundVolume(t *testing.T) {
+	c, s := newTestController(t)
+	putStatefulSet(t, s, testStatefulSet("db", 1))
+	syncOnce(t, c)
+
+	// The agent on node-2 bound the volume when it started the replica.
+	_, err := store.UpdateJSON(s, model.VolumesBucket, "data-db-0", func(v *model.Volume) error {
+		v.Status.NodeName = "node-2"
+		return nil
+	})
+	require.NoError(t, err)
+	_, err = store.UpdateJSON(s, model.ContainersBucket, "db-0", func(r *model.Container) error {
+		r.Status.Phase = model.PhaseFailed
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The failed replica is deleted, released, since it never reached a
+	// node, and recreated.
+	syncOnce(t, c)
+	syncOnce(t, c)
+	syncOnce(t, c)
+
+	r, err := store.GetJSON[model.Container](s, model.ContainersBucket, "db-0")
+	require.NoError(t, err)
+	assert.Equal(t, "node-2", r.Spec.NodeName)
+	assert.Equal(t, model.PhasePending, r.Status.Phase)
+}
+
+func TestSync_PartitionedRollingUpdate(t *testing.T) {
+	c, s := newTestController(t)
+	putStatefulSet(t, s, testStatefulSet("db", 3))
+	converge(t, c, s)
+
+	updateStatefulSet(t, s, "db", func(set *model.StatefulSet) {
+		set.Spec.Template.Spec.Image = "postgres:16"
+		set.Spec.UpdateStrategy.Partition = 1
+	})
+
+	// The highest ordinal goes first and must be ready before the next.
+	syncOnce(t, c)
+	assert.NotContains(t, replicaImages(t, s, "db"), "db-2")
+	removeRuntime(t, s)
+	syncOnce(t, c)
+	syncOnce(t, c)
+	assert.Equal(t, "postgres:16", replicaImages(t, s, "db")["db-2"])
+	assert.Equal(t, "postgres:15", replicaImages(t, s, "db")["db-1"])
+
+	converge(t, c, s)
+	assert.Equal(t, map[string]string{"db-0": "postgres:15", "db-1": "postgres:16", "db-2": "postgres:16"},
+		replicaImages(t, s, "db"))
+	set := getStatefulSet(t, s, "db")
+	assert.Equal(t, 2, set.Status.UpdatedReplicas)
+	assert.Equal(t, 1, set.Status.CurrentReplicas)
+	assert.NotEqual(t, set.Status.CurrentRevision, set.Status.UpdateRevision)
+
+	// A replica below the partition is recreated from the current revision.
+	_, err := store.UpdateJSON(s, model.ContainersBucket, "db-0", func(r *model.Container) error {
+		r.Status.Phase = model.PhaseFailed
+		return nil
+	})
+	require.NoError(t, err)
+	converge(t, c, s)
+	assert.Equal(t, "postgres:15", replicaImages(t, s, "db")["db-0"])
+
+	// Lowering the partition finishes the update.
+	updateStatefulSet(t, s, "db", func(set *model.StatefulSet) { set.Spec.UpdateStrategy.Partition = 0 })
+	converge(t, c, s)
+	assert.Equal(t, map[string]string{"db-0": "postgres:16", "db-1": "postgres:16", "db-2": "postgres:16"},
+		replicaImages(t, s, "db"))
+	set = getStatefulSet(t, s, "db")
+	assert.Equal(t, set.Status.UpdateRevision, set.Status.CurrentRevision)
+	assert.Equal(t, "postgres:16", set.Status.CurrentTemplate.Spec.Image)
+}
+
+func TestSync_OnDeleteLeavesReplicas(t *testing.T) {
+	c, s := newTestController(t)
+	set := testStatefulSet("db", 2)
+	set.Spec.UpdateStrategy.Type = model.StatefulSetOnDelete
+	putStatefulSet(t, s, set)
+	converge(t, c, s)
+
+	updateStatefulSet(t, s, "db", func(set *model.StatefulSet) { set.Spec.Template.Spec.Image = "postgres:16" })
+	converge(t, c, s)
+	assert.Equal(t, map[string]string{"db-0": "postgres:15", "db-1": "postgres:15"}, replicaImages(t, s, "db"))
+
+	require.NoError(t, s.Delete(model.ContainersBucket, "db-1"))
+	converge(t, c, s)
+	assert.Equal(t, "postgres:16", replicaImages(t, s, "db")["db-1"])
+}
+
+func TestSync_ReplicasAreCollectedWithTheirSet(t *testing.T) {
+	c, s := newTestController(t)
+	putStatefulSet(t, s, testStatefulSet("db", 1))
+	syncOnce(t, c)
+	require.Len(t, replicaImages(t, s, "db"), 1)
+	r, err := store.GetJSON[model.Container](s, model.ContainersBucket, "db-0")
+	require.NoError(t, err)
+	assert.Equal(t, "db-uid", r.ControllerRef().UID)
+
+	require.NoError(t, s.Delete(model.StatefulSetsBucket, "db"))
+	syncOnce(t, c)
+	collect(t, s)
+
+	assert.Empty(t, replicaImages(t, s, "db"))
+	_, err = store.GetJSON[model.Volume](s, model.VolumesBucket, "data-db-0")
+	assert.NoError(t, err)
+}
+
+// collect runs a garbage collection pass.
+func collect(t *testing.T, s store.Store) {
+	t.Helper()
+	require.NoError(t, gc.NewCollector(&gc.Config{Store: s, Logger: zerolog.Nop()}).Sync(context.Background()))
+}
+
+func keys(m map[string]string) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
@@ -64,6 +64,46 @@ func (s *BoltStore) Put(bucket, key string, value []byte) error {
 	})
 }
 
+// Create stores a value only if the key does not already exist.
+func (s *BoltStore) Create(bucket, key string, value []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return fmt.Errorf("failed to create bucket %s: %w", bucket, err)
+		}
+		if b.Get([]byte(key)) != nil {
+			return ErrAlreadyExists
+		}
+		return b.Put([]byte(key), value)
+	})
+}
+
+// Update atomically replaces a value with the result of fn.
+func (s *BoltStore) Update(bucket, key string, fn func(current []byte) ([]byte, error)) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return ErrBucketNotFound
+		}
+
+		v := b.Get([]byte(key))
+		if v == nil {
+			return ErrNotFound
+		}
+
+		// Values returned by bbolt are only valid for the transaction and
+		// must not be modified, so hand fn a copy.
+		current := make([]byte, len(v))
+		copy(current, v)
+
+		updated, err := fn(current)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), updated)
+	})
+}
+
 // Delete removes a key from a bucket.
 func (s *BoltStore) Delete(bucket, key string) error {
 	return s.db.Update(func(tx *bolt.Tx) error {
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// GetJSON retrieves a value and decodes it as JSON into a new T.
+func GetJSON[T any](s Store, bucket, key string) (*T, error) {
+	data, err := s.Get(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	v := new(T)
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, fmt.Errorf("failed to decode %s/%s: %w", bucket, key, err)
+	}
+	return v, nil
+}
+
+// PutJSON encodes v as JSON and stores it.
+func PutJSON(s Store, bucket, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s/%s: %w", bucket, key, err)
+	}
+	return s.Put(bucket, key, data)
+}
+
+// CreateJSON encodes v as JSON and stores it if the key does not exist.
+// Returns ErrAlreadyExists if the key is present.
+func CreateJSON(s Store, bucket, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s/%s: %w", bucket, key, err)
+	}
+	return s.Create(bucket, key, data)
+}
+
+// UpdateJSON atomically decodes a value, applies fn and stores the result.
+// An error returned by fn aborts the update. It returns the updated value.
+func UpdateJSON[T any](s Store, bucket, key string, fn func(v *T) error) (*T, error) {
+	v := new(T)
+	err := s.Update(bucket, key, func(current []byte) ([]byte, error) {
+		if err := json.Unmarshal(current, v); err != nil {
+			return nil, fmt.Errorf("failed to decode %s/%s: %w", bucket, key, err)
+		}
+		if err := fn(v); err != nil {
+			return nil, err
+		}
+		return json.Marshal(v)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ListJSON decodes every value in a bucket whose key starts with prefix.
+// A missing bucket yields an empty list rather than ErrBucketNotFound,
+// since typed callers treat "no bucket yet" the same as "no items".
+func ListJSON[T any](s Store, bucket, prefix string) ([]*T, error) {
+	kvs, err := s.List(bucket, prefix)
+	if errors.Is(err, ErrBucketNotFound) {
+		return []*T{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*T, 0, len(kvs))
+	for _, kv := range kvs {
+		v := new(T)
+		if err := json.Unmarshal(kv.Value, v); err != nil {
+			return nil, fmt.Errorf("failed to decode %s/%s: %w", bucket, kv.Key, err)
+		}
+		items = append(items, v)
+	}
+	return items, nil
+}
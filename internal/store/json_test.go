@@ -0,0 +1,71 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testItem struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestJSONHelpers_RoundTrip(t *testing.T) {
+	s := NewMemoryStore()
+
+	require.NoError(t, PutJSON(s, "items", "a", testItem{Name: "a", Count: 1}))
+
+	got, err := GetJSON[testItem](s, "items", "a")
+	require.NoError(t, err)
+	assert.Equal(t, &testItem{Name: "a", Count: 1}, got)
+}
+
+func TestCreateJSON_Conflict(t *testing.T) {
+	s := NewMemoryStore()
+
+	require.NoError(t, CreateJSON(s, "items", "a", testItem{Name: "a"}))
+	assert.ErrorIs(t, CreateJSON(s, "items", "a", testItem{Name: "a"}), ErrAlreadyExists)
+}
+
+func TestUpdateJSON(t *testing.T) {
+	s := NewMemoryStore()
+	require.NoError(t, PutJSON(s, "items", "a", testItem{Name: "a", Count: 1}))
+
+	updated, err := UpdateJSON(s, "items", "a", func(v *testItem) error {
+		v.Count++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, updated.Count)
+
+	errStop := errors.New("stop")
+	_, err = UpdateJSON(s, "items", "a", func(v *testItem) error {
+		v.Count = 100
+		return errStop
+	})
+	assert.ErrorIs(t, err, errStop)
+
+	got, err := GetJSON[testItem](s, "items", "a")
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.Count)
+}
+
+func TestListJSON(t *testing.T) {
+	s := NewMemoryStore()
+
+	items, err := ListJSON[testItem](s, "items", "")
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	require.NoError(t, PutJSON(s, "items", "b", testItem{Name: "b"}))
+	require.NoError(t, PutJSON(s, "items", "a", testItem{Name: "a"}))
+
+	items, err = ListJSON[testItem](s, "items", "")
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "a", items[0].Name)
+	assert.Equal(t, "b", items[1].Name)
+}
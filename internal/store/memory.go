@@ -1,6 +1,7 @@
 package store
 
 import (
+	"sort"
 	"strings"
 	"sync"
 )
@@ -55,6 +56,55 @@ func (s *MemoryStore) Put(bucket, key string, value []byte) error {
 	return nil
 }
 
+// Create stores a value only if the key does not already exist.
+func (s *MemoryStore) Create(bucket, key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[bucket]
+	if !ok {
+		b = make(map[string][]byte)
+		s.buckets[bucket] = b
+	}
+
+	if _, ok := b[key]; ok {
+		return ErrAlreadyExists
+	}
+
+	cp := make([]byte, len(value))
+	copy(cp, value)
+	b[key] = cp
+	return nil
+}
+
+// Update atomically replaces a value with the result of fn.
+func (s *MemoryStore) Update(bucket, key string, fn func(current []byte) ([]byte, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[bucket]
+	if !ok {
+		return ErrBucketNotFound
+	}
+
+	v, ok := b[key]
+	if !ok {
+		return ErrNotFound
+	}
+
+	cp := make([]byte, len(v))
+	copy(cp, v)
+	updated, err := fn(cp)
+	if err != nil {
+		return err
+	}
+
+	cp = make([]byte, len(updated))
+	copy(cp, updated)
+	b[key] = cp
+	return nil
+}
+
 // Delete removes a key from a bucket.
 func (s *MemoryStore) Delete(bucket, key string) error {
 	s.mu.Lock()
@@ -93,6 +143,11 @@ func (s *MemoryStore) List(bucket, prefix string) ([]KV, error) {
 		results = append(results, KV{Key: k, Value: cp})
 	}
 
+	// Match the key order of the bbolt store.
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Key < results[j].Key
+	})
+
 	return results, nil
 }
 
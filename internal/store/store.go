@@ -7,8 +7,14 @@ import "errors"
 var (
 	ErrNotFound       = errors.New("key not found")
 	ErrBucketNotFound = errors.New("bucket not found")
+	ErrAlreadyExists  = errors.New("key already exists")
 )
 
+// IsNotFound reports whether err means the record or its bucket is missing.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, ErrBucketNotFound)
+}
+
 // KV represents a key-value pair returned from list operations.
 type KV struct {
 	Key   string
@@ -27,6 +33,18 @@ type Store interface {
 	// The bucket is created automatically if it does not exist.
 	Put(bucket, key string, value []byte) error
 
+	// Create stores a value only if the key does not already exist.
+	// The bucket is created automatically if it does not exist.
+	// Returns ErrAlreadyExists if the key is present.
+	Create(bucket, key string, value []byte) error
+
+	// Update atomically replaces a value with the result of fn, which
+	// receives the current value. An error returned by fn aborts the update
+	// and is passed through to the caller.
+	// Returns ErrBucketNotFound if the bucket does not exist.
+	// Returns ErrNotFound if the key does not exist.
+	Update(bucket, key string, fn func(current []byte) ([]byte, error)) error
+
 	// Delete removes a key from a bucket.
 	// Returns ErrBucketNotFound if the bucket does not exist.
 	// Returns ErrNotFound if the key does not exist.
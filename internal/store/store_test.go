@@ -1,6 +1,7 @@
 package store
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -115,6 +116,22 @@ func RunStoreTests(t *testing.T, newStore func(t *testing.T) Store) {
 		}
 	})
 
+	t.Run("ListSortedByKey", func(t *testing.T) {
+		s := newStore(t)
+		defer func() { require.NoError(t, s.Close()) }()
+
+		require.NoError(t, s.Put("test", "b", []byte("2")))
+		require.NoError(t, s.Put("test", "c", []byte("3")))
+		require.NoError(t, s.Put("test", "a", []byte("1")))
+
+		results, err := s.List("test", "")
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		assert.Equal(t, "a", results[0].Key)
+		assert.Equal(t, "b", results[1].Key)
+		assert.Equal(t, "c", results[2].Key)
+	})
+
 	t.Run("ListNonexistentBucket", func(t *testing.T) {
 		s := newStore(t)
 		defer func() { require.NoError(t, s.Close()) }()
@@ -123,6 +140,65 @@ func RunStoreTests(t *testing.T, newStore func(t *testing.T) Store) {
 		assert.ErrorIs(t, err, ErrBucketNotFound)
 	})
 
+	t.Run("Create", func(t *testing.T) {
+		s := newStore(t)
+		defer func() { require.NoError(t, s.Close()) }()
+
+		require.NoError(t, s.Create("test", "key1", []byte("v1")))
+
+		err := s.Create("test", "key1", []byte("v2"))
+		assert.ErrorIs(t, err, ErrAlreadyExists)
+
+		val, err := s.Get("test", "key1")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("v1"), val)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		s := newStore(t)
+		defer func() { require.NoError(t, s.Close()) }()
+
+		require.NoError(t, s.Put("test", "key1", []byte("v1")))
+
+		err := s.Update("test", "key1", func(current []byte) ([]byte, error) {
+			return append(current, '+'), nil
+		})
+		require.NoError(t, err)
+
+		val, err := s.Get("test", "key1")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("v1+"), val)
+	})
+
+	t.Run("UpdateAbort", func(t *testing.T) {
+		s := newStore(t)
+		defer func() { require.NoError(t, s.Close()) }()
+
+		require.NoError(t, s.Put("test", "key1", []byte("v1")))
+
+		errAbort := errors.New("abort")
+		err := s.Update("test", "key1", func([]byte) ([]byte, error) {
+			return []byte("v2"), errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+
+		val, err := s.Get("test", "key1")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("v1"), val)
+	})
+
+	t.Run("UpdateMissing", func(t *testing.T) {
+		s := newStore(t)
+		defer func() { require.NoError(t, s.Close()) }()
+
+		noop := func(current []byte) ([]byte, error) { return current, nil }
+
+		assert.ErrorIs(t, s.Update("nonexistent", "key1", noop), ErrBucketNotFound)
+
+		require.NoError(t, s.Put("test", "key1", []byte("v1")))
+		assert.ErrorIs(t, s.Update("test", "missing", noop), ErrNotFound)
+	})
+
 	t.Run("MultipleBuckets", func(t *testing.T) {
 		s := newStore(t)
 		defer func() { require.NoError(t, s.Close()) }()
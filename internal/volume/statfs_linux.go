@@ -0,0 +1,14 @@
+//go:build linux
+
+package volume
+
+import "golang.org/x/sys/unix"
+
+// statfs returns the size and the free space of the filesystem holding path.
+func statfs(path string) (size, free int64, err error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+	return int64(st.Blocks) * st.Bsize, int64(st.Bavail) * st.Bsize, nil
+}
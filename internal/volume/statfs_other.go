@@ -0,0 +1,9 @@
+//go:build !linux
+
+package volume
+
+import "errors"
+
+func statfs(string) (size, free int64, err error) {
+	return 0, 0, errors.New("not supported on this platform")
+}
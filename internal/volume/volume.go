@@ -0,0 +1,206 @@
+// Package volume provides the storage behind volumes. A Driver turns a
+// volume into a directory on the node holding it; the agent mounts that
+// directory into containers and reports how much of it is used. Drivers are
+// looked up by the name in the volume's spec, so new kinds of storage can be
+// added without touching the agent.
+package volume
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+// Driver provides storage for volumes on one node.
+type Driver interface {
+	// Name is the driver name volumes select in spec.driver.
+	Name() string
+
+	// Mount prepares the volume on this node and returns the directory to
+	// mount into containers.
+	Mount(v *model.Volume) (string, error)
+
+	// Usage measures the volume.
+	Usage(v *model.Volume) (*model.VolumeUsage, error)
+
+	// Prune removes data the driver keeps for volumes that no longer exist.
+	// keep holds the keys of the volumes bound to this node that use the
+	// driver.
+	Prune(keep map[string]bool) error
+}
+
+// Registry holds the drivers available on a node.
+type Registry struct {
+	drivers map[string]Driver
+}
+
+// NewRegistry creates a registry of drivers.
+func NewRegistry(drivers ...Driver) *Registry {
+	r := &Registry{drivers: make(map[string]Driver, len(drivers))}
+	for _, d := range drivers {
+		r.drivers[d.Name()] = d
+	}
+	return r
+}
+
+// Get returns the named driver.
+func (r *Registry) Get(name string) (Driver, error) {
+	d, ok := r.drivers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown volume driver %q", name)
+	}
+	return d, nil
+}
+
+// Drivers returns the registered drivers ordered by name.
+func (r *Registry) Drivers() []Driver {
+	out := make([]Driver, 0, len(r.drivers))
+	for _, d := range r.drivers {
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name() < out[j].Name() })
+	return out
+}
+
+// Local keeps each volume in its own directory below Dir, grouped by
+// namespace.
+type Local struct {
+	Dir string
+}
+
+// NewLocal creates a local driver storing volumes below dir.
+func NewLocal(dir string) *Local {
+	return &Local{Dir: dir}
+}
+
+// Name implements Driver.
+func (*Local) Name() string { return model.VolumeDriverLocal }
+
+// Mount implements Driver. The directory is created on first use.
+func (l *Local) Mount(v *model.Volume) (string, error) {
+	dir := l.dir(v)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", fmt.Errorf("creating volume %s: %w", v.Name, err)
+	}
+	return dir, nil
+}
+
+// Usage implements Driver.
+func (l *Local) Usage(v *model.Volume) (*model.VolumeUsage, error) {
+	return usage(l.dir(v), v.Spec.CapacityBytes)
+}
+
+func (l *Local) dir(v *model.Volume) string {
+	return filepath.Join(l.Dir, filepath.FromSlash(v.Key()))
+}
+
+// Prune implements Driver. Directories of deleted volumes are removed, and
+// so are namespace directories left empty.
+func (l *Local) Prune(keep map[string]bool) error {
+	namespaces, err := os.ReadDir(l.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, ns := range namespaces {
+		if !ns.IsDir() {
+			continue
+		}
+		nsDir := filepath.Join(l.Dir, ns.Name())
+		volumes, err := os.ReadDir(nsDir)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		kept := 0
+		for _, v := range volumes {
+			if keep[model.Key(ns.Name(), v.Name())] {
+				kept++
+				continue
+			}
+			if err := os.RemoveAll(filepath.Join(nsDir, v.Name())); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if kept == 0 {
+			if err := os.Remove(nsDir); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// HostPath mounts an existing directory of the node. The directory belongs
+// to the host and is never created or removed.
+type HostPath struct{}
+
+// Name implements Driver.
+func (HostPath) Name() string { return model.VolumeDriverHostPath }
+
+// Mount implements Driver.
+func (HostPath) Mount(v *model.Volume) (string, error) {
+	info, err := os.Stat(v.Spec.HostPath)
+	if err != nil {
+		return "", fmt.Errorf("volume %s: %w", v.Name, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("volume %s: %s is not a directory", v.Name, v.Spec.HostPath)
+	}
+	return v.Spec.HostPath, nil
+}
+
+// Usage implements Driver.
+func (HostPath) Usage(v *model.Volume) (*model.VolumeUsage, error) {
+	return usage(v.Spec.HostPath, v.Spec.CapacityBytes)
+}
+
+// Prune implements Driver. It does nothing.
+func (HostPath) Prune(map[string]bool) error { return nil }
+
+// usage measures the files below dir against capacity, or against the
+// filesystem holding dir when capacity is zero.
+func usage(dir string, capacity int64) (*model.VolumeUsage, error) {
+	var used int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			used += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("measuring %s: %w", dir, err)
+	}
+
+	u := &model.VolumeUsage{UsedBytes: used, CapacityBytes: capacity}
+	size, free, err := statfs(dir)
+	switch {
+	case err != nil:
+		// Without filesystem figures, only a requested capacity bounds
+		// what is available.
+		if capacity > 0 {
+			u.AvailableBytes = max(capacity-used, 0)
+		}
+	case capacity > 0:
+		u.AvailableBytes = max(min(capacity-used, free), 0)
+	default:
+		u.CapacityBytes = size
+		u.AvailableBytes = free
+	}
+	return u, nil
+}
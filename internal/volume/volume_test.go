@@ -0,0 +1,84 @@
+package volume
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github-builder/container-orchestrator/internal/model"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry(NewLocal(t.TempDir()), HostPath{})
+
+	d, err := r.Get(model.VolumeDriverHostPath)
+	require.NoError(t, err)
+	assert.Equal(t, model.VolumeDriverHostPath, d.Name())
+
+	_, err = r.Get("nfs")
+	assert.EqualError(t, err, `unknown volume driver "nfs"`)
+
+	names := []string{}
+	for _, d := range r.Drivers() {
+		names = append(names, d.Name())
+	}
+	assert.Equal(t, []string{"hostpath", "local"}, names)
+}
+
+func TestLocal(t *testing.T) {
+	l := NewLocal(t.TempDir())
+	v := &model.Volume{ObjectMeta: model.ObjectMeta{Name: "data", Namespace: "team-a"}, Spec: model.VolumeSpec{CapacityBytes: 64}}
+
+	dir, err := l.Mount(v)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(l.Dir, "team-a", "data"), dir)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "f"), make([]byte, 40), 0o600))
+
+	u, err := l.Usage(v)
+	require.NoError(t, err)
+	assert.Equal(t, int64(40), u.UsedBytes)
+	assert.Equal(t, int64(64), u.CapacityBytes)
+	assert.LessOrEqual(t, u.AvailableBytes, int64(24))
+
+	// Usage beyond the capacity leaves nothing available.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "g"), make([]byte, 40), 0o600))
+	u, err = l.Usage(v)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), u.AvailableBytes)
+
+	// Pruning removes deleted volumes and namespaces left empty.
+	_, err = l.Mount(&model.Volume{ObjectMeta: model.ObjectMeta{Name: "other", Namespace: "team-a"}})
+	require.NoError(t, err)
+	_, err = l.Mount(&model.Volume{ObjectMeta: model.ObjectMeta{Name: "data", Namespace: "team-b"}})
+	require.NoError(t, err)
+	require.NoError(t, l.Prune(map[string]bool{"team-a/data": true}))
+	assert.DirExists(t, filepath.Join(l.Dir, "team-a", "data"))
+	assert.NoDirExists(t, filepath.Join(l.Dir, "team-a", "other"))
+	assert.NoDirExists(t, filepath.Join(l.Dir, "team-b"))
+}
+
+func TestHostPath(t *testing.T) {
+	dir := t.TempDir()
+	v := &model.Volume{
+		ObjectMeta: model.ObjectMeta{Name: "host"},
+		Spec:       model.VolumeSpec{Driver: model.VolumeDriverHostPath, HostPath: dir},
+	}
+
+	got, err := HostPath{}.Mount(v)
+	require.NoError(t, err)
+	assert.Equal(t, dir, got)
+
+	v.Spec.HostPath = filepath.Join(dir, "missing")
+	_, err = HostPath{}.Mount(v)
+	assert.Error(t, err)
+
+	file := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+	v.Spec.HostPath = file
+	_, err = HostPath{}.Mount(v)
+	assert.ErrorContains(t, err, "is not a directory")
+}